@@ -0,0 +1,142 @@
+// Command admin serves data subject requests from the command line:
+//
+//	admin export -user <id or email> [-out file]
+//	admin delete -user <id or email> -confirm <email>
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"medods-test/internal/auth/repo/postgres"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/internal/config"
+	"medods-test/pkg/db"
+	"os"
+)
+
+const usage = `usage:
+  admin export -user <id or email> [-out file]
+  admin delete -user <id or email> -confirm <email>`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := run(context.Background(), os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	user := flags.String("user", "", "id or email of the user")
+	out := flags.String("out", "", "file to write the export to, stdout by default")
+	confirm := flags.String("confirm", "", "email of the user, confirms the deletion")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *user == "" {
+		return errors.New(usage)
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		return err
+	}
+
+	DB, err := db.OpenDB(ctx, cfg.DBConfig)
+	if err != nil {
+		return err
+	}
+	defer DB.Close()
+
+	userRepo := postgres.NewUserRepo(DB)
+	oauthRepo := postgres.NewOAuthRepo(DB)
+
+	s := service.New(&service.Repository{
+		UserRepo:         userRepo,
+		SessionRepo:      postgres.NewSessionRepo(DB),
+		OAuthRepo:        oauthRepo,
+		ClientRepo:       oauthRepo,
+		ExchangeRepo:     postgres.NewTokenExchangeRepo(DB),
+		IdentityRepo:     postgres.NewIdentityRepo(DB),
+		RBACRepo:         postgres.NewRBACRepo(DB),
+		OrgRepo:          postgres.NewOrganizationRepo(DB),
+		AuditRepo:        postgres.NewAuditRepo(DB),
+		LoginRepo:        postgres.NewLoginHistoryRepo(DB),
+		MFARepo:          postgres.NewMFARepo(DB),
+		KnownDeviceRepo:  postgres.NewKnownDeviceRepo(DB),
+		NotificationRepo: postgres.NewNotificationRepo(DB),
+		WebhookRepo:      postgres.NewWebhookRepo(DB),
+		SignalRepo:       postgres.NewSignalRepo(DB),
+		PhoneRepo:        postgres.NewPhoneRepo(DB),
+	}, nil)
+	privacy := s.Privacy(nil, cfg.AuthConfig.AccountDeletionTTL)
+
+	target, err := findUser(ctx, userRepo, *user)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "export":
+		return exportUser(ctx, privacy, target, *out)
+	case "delete":
+		if *confirm != target.Email {
+			return fmt.Errorf("pass -confirm %s to delete user %s", target.Email, target.UserUUID)
+		}
+		if err = privacy.Erase(ctx, target.UserUUID); err != nil {
+			return err
+		}
+		fmt.Printf("user %s erased\n", target.UserUUID)
+		return nil
+	}
+
+	return fmt.Errorf("unknown command %q\n%s", command, usage)
+}
+
+func findUser(ctx context.Context, userRepo *postgres.UserRepo, idOrEmail string) (*types.User, error) {
+	var (
+		user *types.User
+		err  error
+	)
+	if uuid.Validate(idOrEmail) == nil {
+		user, err = userRepo.GetUserByID(ctx, idOrEmail)
+	} else {
+		user, err = userRepo.GetUserByEmail(ctx, idOrEmail)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, service.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func exportUser(ctx context.Context, privacy *service.Privacy, user *types.User, path string) error {
+	export, err := privacy.Export(ctx, user.UserUUID)
+	if err != nil {
+		return err
+	}
+
+	output := os.Stdout
+	if path != "" {
+		if output, err = os.Create(path); err != nil {
+			return err
+		}
+		defer output.Close()
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
@@ -2,19 +2,34 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
 	"medods-test/internal/auth/repo/postgres"
 	"medods-test/internal/auth/rest"
 	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
 	"medods-test/internal/config"
 	"medods-test/pkg/auth"
 	"medods-test/pkg/db"
 	"medods-test/pkg/email/smtp"
+	"medods-test/pkg/geoip"
+	"medods-test/pkg/ldapauth"
 	"medods-test/pkg/logger"
+	"medods-test/pkg/oidcclient"
+	"medods-test/pkg/passwordpolicy"
+	"medods-test/pkg/risk"
+	"medods-test/pkg/sms"
+	"medods-test/pkg/sms/fake"
+	"medods-test/pkg/ssf"
+	"medods-test/pkg/webhook"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 )
 
 func main() {
@@ -33,15 +48,65 @@ func main() {
 
 	userRepo := postgres.NewUserRepo(DB)
 	sessionRepo := postgres.NewSessionRepo(DB)
+	oauthRepo := postgres.NewOAuthRepo(DB)
+	deviceRepo := postgres.NewDeviceRepo(DB)
+	exchangeRepo := postgres.NewTokenExchangeRepo(DB)
+	identityRepo := postgres.NewIdentityRepo(DB)
+	rbacRepo := postgres.NewRBACRepo(DB)
+	orgRepo := postgres.NewOrganizationRepo(DB)
+	auditRepo := postgres.NewAuditRepo(DB)
+	loginRepo := postgres.NewLoginHistoryRepo(DB)
+	mfaRepo := postgres.NewMFARepo(DB)
+	knownDeviceRepo := postgres.NewKnownDeviceRepo(DB)
+	notificationRepo := postgres.NewNotificationRepo(DB)
+	webhookRepo := postgres.NewWebhookRepo(DB)
+	signalRepo := postgres.NewSignalRepo(DB)
+	phoneRepo := postgres.NewPhoneRepo(DB)
 
 	repo := &service.Repository{
-		UserRepo:    userRepo,
-		SessionRepo: sessionRepo,
+		UserRepo:         userRepo,
+		SessionRepo:      sessionRepo,
+		OAuthRepo:        oauthRepo,
+		ClientRepo:       oauthRepo,
+		DeviceRepo:       deviceRepo,
+		ExchangeRepo:     exchangeRepo,
+		IdentityRepo:     identityRepo,
+		RBACRepo:         rbacRepo,
+		OrgRepo:          orgRepo,
+		AuditRepo:        auditRepo,
+		LoginRepo:        loginRepo,
+		MFARepo:          mfaRepo,
+		KnownDeviceRepo:  knownDeviceRepo,
+		NotificationRepo: notificationRepo,
+		WebhookRepo:      webhookRepo,
+		SignalRepo:       signalRepo,
+		PhoneRepo:        phoneRepo,
 	}
 
-	s := service.New(repo)
+	geoDB, err := loadGeoIP(cfg.GeoIPConfig)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+
+	var locator service.GeoLocator
+	if geoDB != nil {
+		locator = geoDB
+		go geoDB.Watch(watchCtx, cfg.GeoIPConfig.ReloadInterval)
+	}
+
+	s := service.New(repo, locator)
 
-	manager, err := auth.NewManager(cfg.AuthConfig.SigningKey)
+	idTokenKey, err := loadIDTokenKey(cfg.OAuthConfig.PrivateKeyFile)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	manager, err := auth.NewManager(cfg.AuthConfig.SigningKey, idTokenKey)
 	if err != nil {
 		logger.Error(err)
 		return
@@ -58,13 +123,124 @@ func main() {
 		return
 	}
 
+	smsSender, err := loadSMSSender(cfg.SMSConfig)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	credentialVerifiers, err := loadCredentialVerifiers(cfg.LDAPConfig.DirectoriesFile, cfg.LDAPConfig.Timeout)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	passwordPolicy, err := loadPasswordPolicy(cfg.PasswordConfig)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	riskConfig, err := loadRiskConfig(watchCtx, cfg.RiskConfig)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	emailUniqueness := cfg.OrgConfig.EmailUniqueness
+	if emailUniqueness != types.EmailUniquenessGlobal && emailUniqueness != types.EmailUniquenessOrganization {
+		logger.Errorf("unknown EMAIL_UNIQUENESS: %s", emailUniqueness)
+		return
+	}
+
+	user := s.User(
+		manager,
+		smtpSender,
+		smsSender,
+		credentialVerifiers,
+		passwordPolicy,
+		s.Risk(riskConfig),
+		emailUniqueness,
+		cfg.AuthConfig.AccessTokenTTL,
+		cfg.AuthConfig.RefreshTokenTTL,
+		cfg.AuthConfig.EmailChangeTTL,
+		cfg.AuthConfig.DeviceCookieTTL,
+		cfg.AuthConfig.DeviceTrustTTL,
+		cfg.PasswordConfig.HistorySize,
+		cfg.PasswordConfig.HistoryRetention,
+		cfg.AuthConfig.LoginHistoryRetention,
+	)
+
+	oauth := s.OAuth(user, cfg.OAuthConfig)
+
+	socialProviders, err := loadSocialProviders(cfg.SocialConfig.ProvidersFile, cfg.OAuthConfig.Issuer)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	rbac := s.RBAC()
+	if err = rbac.Bootstrap(ctx, cfg.AdminConfig.BootstrapEmail); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	userAdmin := s.UserAdmin(
+		smtpSender,
+		cfg.AuthConfig.PasswordResetTTL,
+		cfg.AuthConfig.UserPurgeDelay,
+		cfg.PasswordConfig.HistoryRetention,
+		cfg.AuthConfig.LoginHistoryRetention,
+		cfg.AuthConfig.DeviceCookieTTL,
+	)
+
+	purgerCtx, stopPurger := context.WithCancel(ctx)
+	defer stopPurger()
+	go userAdmin.RunPurger(purgerCtx, cfg.AuthConfig.UserPurgeInterval)
+
+	notifications := s.Notifications(smtpSender, smsSender)
+	go notifications.RunDigests(purgerCtx, cfg.NotifyConfig.DigestInterval)
+
+	summaryConfig, err := loadActivitySummaryConfig(cfg.NotifyConfig)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	go s.ActivitySummaries(smtpSender, summaryConfig).RunSummaries(purgerCtx, cfg.NotifyConfig.SummaryInterval)
+
+	webhooks := s.Webhooks(webhook.NewClient(cfg.WebhookConfig.Timeout), service.WebhookConfig{
+		Timeout:           cfg.WebhookConfig.Timeout,
+		MaxAttempts:       cfg.WebhookConfig.MaxAttempts,
+		RetryBackoff:      cfg.WebhookConfig.RetryBackoff,
+		MaxBackoff:        cfg.WebhookConfig.MaxBackoff,
+		DeliveryRetention: cfg.WebhookConfig.DeliveryRetention,
+	})
+	go webhooks.RunDispatcher(purgerCtx, cfg.WebhookConfig.PollInterval)
+
+	signals := s.Signals(manager, ssf.NewPusher(cfg.SignalConfig.Timeout), service.SignalConfig{
+		Issuer:       cfg.OAuthConfig.Issuer,
+		Timeout:      cfg.SignalConfig.Timeout,
+		MaxAttempts:  cfg.SignalConfig.MaxAttempts,
+		RetryBackoff: cfg.SignalConfig.RetryBackoff,
+		MaxBackoff:   cfg.SignalConfig.MaxBackoff,
+		Retention:    cfg.SignalConfig.EventRetention,
+	})
+	go signals.RunPusher(purgerCtx, cfg.SignalConfig.PollInterval)
+
 	restUseCase := &rest.UseCase{
-		User: s.User(
-			manager,
-			smtpSender,
-			cfg.AuthConfig.AccessTokenTTL,
-			cfg.AuthConfig.RefreshTokenTTL,
-		),
+		User:     user,
+		OAuth:    oauth,
+		Client:   s.Client(manager, cfg.OAuthConfig.SecretRotationGrace),
+		Social:   s.Social(user, socialProviders, cfg.SocialConfig.StateTTL),
+		RBAC:     rbac,
+		Org:      s.Organization(smtpSender, cfg.OrgConfig.InvitationTTL),
+		Admin:    userAdmin,
+		Privacy:  s.Privacy(smtpSender, cfg.AuthConfig.AccountDeletionTTL),
+		Audit:    s.Audit(),
+		Notify:   notifications,
+		Webhooks: webhooks,
+		Signals:  signals,
+		Keys:     manager,
 	}
 
 	h := rest.New(restUseCase)
@@ -75,8 +251,8 @@ func main() {
 	}
 
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
-			logger.Error("failed to run http server: %s\n", err.Error())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("failed to run http server: %s", err.Error())
 		}
 	}()
 
@@ -85,8 +261,167 @@ func main() {
 	waitForShutdown(server)
 }
 
+func loadIDTokenKey(path string) (*rsa.PrivateKey, error) {
+	if path != "" {
+		return auth.LoadRSAPrivateKey(path)
+	}
+
+	logger.Warn("OIDC_PRIVATE_KEY_FILE is not set, using an ephemeral key for id tokens")
+	return auth.GenerateRSAPrivateKey()
+}
+
+func loadSocialProviders(path string, issuer string) ([]service.SocialProvider, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	configs, err := oidcclient.LoadConfigs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make([]service.SocialProvider, 0, len(configs))
+	for _, providerConfig := range configs {
+		redirectURL := fmt.Sprintf("%s/auth/social/%s/callback", issuer, providerConfig.Name)
+		provider, err := oidcclient.NewProvider(providerConfig, redirectURL)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+func loadCredentialVerifiers(path string, timeout time.Duration) ([]service.CredentialVerifier, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	configs, err := ldapauth.LoadConfigs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	verifiers := make([]service.CredentialVerifier, 0, len(configs))
+	for _, directoryConfig := range configs {
+		directoryConfig.Timeout = timeout
+		verifier, err := ldapauth.NewAuthenticator(directoryConfig)
+		if err != nil {
+			return nil, err
+		}
+		verifiers = append(verifiers, verifier)
+	}
+
+	return verifiers, nil
+}
+
+// loadSMSSender returns the sender of the configured SMS provider, nil if SMS is disabled.
+func loadSMSSender(cfg config.SMSConfig) (sms.Sender, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "fake":
+		return fake.NewFakeSender(cfg.FakeFile), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS_PROVIDER: %s", cfg.Provider)
+	}
+}
+
+func loadActivitySummaryConfig(cfg config.NotificationConfig) (service.ActivitySummaryConfig, error) {
+	if cfg.SummaryHour < 0 || cfg.SummaryHour > 23 {
+		return service.ActivitySummaryConfig{}, fmt.Errorf("ACTIVITY_SUMMARY_HOUR must be from 0 to 23: %d", cfg.SummaryHour)
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(cfg.SummaryWeekday, weekday.String()) {
+			return service.ActivitySummaryConfig{
+				Weekday: weekday,
+				Hour:    cfg.SummaryHour,
+			}, nil
+		}
+	}
+	return service.ActivitySummaryConfig{}, fmt.Errorf("unknown ACTIVITY_SUMMARY_WEEKDAY: %s", cfg.SummaryWeekday)
+}
+
+func loadGeoIP(cfg config.GeoIPConfig) (*geoip.DB, error) {
+	if cfg.CityDB == "" && cfg.ASNDB == "" {
+		return nil, nil
+	}
+
+	return geoip.Open(geoip.Config{
+		CityDB:   cfg.CityDB,
+		ASNDB:    cfg.ASNDB,
+		Language: cfg.Language,
+	})
+}
+
+// loadRiskConfig builds the risk policy and loads the IP lists, which are reloaded until ctx is done.
+func loadRiskConfig(ctx context.Context, cfg config.RiskConfig) (service.RiskConfig, error) {
+	weights, err := risk.ParseWeights(cfg.Weights)
+	if err != nil {
+		return service.RiskConfig{}, err
+	}
+	bands, err := risk.ParseBands(cfg.Bands)
+	if err != nil {
+		return service.RiskConfig{}, err
+	}
+	policy, err := risk.NewPolicy(weights, bands)
+	if err != nil {
+		return service.RiskConfig{}, err
+	}
+
+	riskConfig := service.RiskConfig{
+		Policy:               policy,
+		FailedAttempts:       cfg.FailedAttempts,
+		FailedAttemptsWindow: cfg.FailedAttemptsWindow,
+		MaxTravelSpeed:       cfg.MaxTravelSpeed,
+	}
+
+	if cfg.TorExitList != "" {
+		torExits, err := risk.OpenIPList(cfg.TorExitList)
+		if err != nil {
+			return service.RiskConfig{}, err
+		}
+		go torExits.Watch(ctx, cfg.ListReloadInterval)
+		riskConfig.TorExits = torExits
+	}
+	if cfg.BadIPList != "" {
+		badIPs, err := risk.OpenIPList(cfg.BadIPList)
+		if err != nil {
+			return service.RiskConfig{}, err
+		}
+		go badIPs.Watch(ctx, cfg.ListReloadInterval)
+		riskConfig.BadIPs = badIPs
+	}
+
+	return riskConfig, nil
+}
+
+func loadPasswordPolicy(cfg config.PasswordConfig) (*passwordpolicy.Policy, error) {
+	policyConfig := passwordpolicy.Config{
+		MinLength:     cfg.MinLength,
+		MaxLength:     cfg.MaxLength,
+		RequireLower:  cfg.RequireLower,
+		RequireUpper:  cfg.RequireUpper,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+		MinStrength:   cfg.MinStrength,
+		BannedWords:   cfg.BannedWords,
+	}
+
+	if cfg.BreachedDir == "" {
+		return passwordpolicy.NewPolicy(policyConfig, nil), nil
+	}
+
+	breaches, err := passwordpolicy.NewBreachedPasswords(cfg.BreachedDir)
+	if err != nil {
+		return nil, err
+	}
+	return passwordpolicy.NewPolicy(policyConfig, breaches), nil
+}
+
 func waitForShutdown(server *http.Server) {
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 
 	<-quit
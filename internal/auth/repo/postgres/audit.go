@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"time"
+)
+
+// auditChainLock serializes appends to the audit log, so that every event is chained to the last one.
+const auditChainLock = 7_341_002
+
+// auditEventColumns select the events joined with their details, which are missing once erased.
+const auditEventColumns = `e.id, e.type, e.actor_id, e.user_id,
+						   COALESCE(d.ip, ''), COALESCE(d.user_agent, ''), COALESCE(d.metadata, '{}'), COALESCE(d.salt, ''),
+						   e.details_hash, e.created_at, e.prev_hash, e.hash`
+
+const auditEventTables = `audit_events e LEFT JOIN audit_event_details d ON d.event_id = e.id`
+
+type AuditRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepo(db *pgxpool.Pool) *AuditRepo {
+	return &AuditRepo{
+		pool: db,
+	}
+}
+
+func scanAuditEvent(row pgx.Row, event *types.AuditEvent) error {
+	return row.Scan(
+		&event.Id,
+		&event.Type,
+		&event.ActorId,
+		&event.UserId,
+		&event.IP,
+		&event.UserAgent,
+		&event.Metadata,
+		&event.DetailsSalt,
+		&event.DetailsHash,
+		&event.CreatedAt,
+		&event.PrevHash,
+		&event.Hash,
+	)
+}
+
+// AppendEvent chains the event to the last event of the log, stores it and returns its id.
+func (r *AuditRepo) AppendEvent(ctx context.Context, event types.AuditEvent) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf(`SQL: AppendEvent: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if _, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return 0, fmt.Errorf("SQL: AppendEvent: Exec(): %w", err)
+	}
+
+	event.PrevHash = types.AuditGenesisHash
+	lastQuery := `SELECT id, hash FROM audit_events ORDER BY id DESC LIMIT 1`
+	if err = tx.QueryRow(ctx, lastQuery).Scan(&event.Id, &event.PrevHash); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf(`SQL: AppendEvent: Scan(): %w`, err)
+	}
+	event.Id++
+	if event.Metadata == nil {
+		event.Metadata = map[string]string{}
+	}
+	event.DetailsHash = event.ComputeDetailsHash()
+	event.Hash = event.ComputeHash()
+
+	insertQuery := `INSERT INTO audit_events (id, type, actor_id, user_id, details_hash, created_at, prev_hash, hash)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err = tx.Exec(ctx, insertQuery,
+		event.Id,
+		event.Type,
+		event.ActorId,
+		event.UserId,
+		event.DetailsHash,
+		event.CreatedAt,
+		event.PrevHash,
+		event.Hash,
+	); err != nil {
+		return 0, fmt.Errorf("SQL: AppendEvent: Exec(): %w", err)
+	}
+
+	detailsQuery := `INSERT INTO audit_event_details (event_id, ip, user_agent, metadata, salt)
+					 VALUES ($1, $2, $3, $4, $5)`
+	if _, err = tx.Exec(ctx, detailsQuery, event.Id, event.IP, event.UserAgent, event.Metadata, event.DetailsSalt); err != nil {
+		return 0, fmt.Errorf("SQL: AppendEvent: Exec(): %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf(`SQL: AppendEvent: Commit(): %w`, err)
+	}
+
+	return event.Id, nil
+}
+
+// ListEvents returns a page of the events matching the filter, newest first, and the number of matching events.
+func (r *AuditRepo) ListEvents(ctx context.Context, filter types.AuditFilter) ([]types.AuditEvent, int, error) {
+	where := `($1 = '' OR e.type = $1)
+			  AND ($2 = '' OR e.user_id = $2)
+			  AND ($3 = '' OR e.actor_id = $3)
+			  AND ($4::timestamp IS NULL OR e.created_at >= $4)
+			  AND ($5::timestamp IS NULL OR e.created_at < $5)`
+	args := []any{filter.Type, filter.UserId, filter.ActorId, nullTime(filter.From), nullTime(filter.To)}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT count(*) FROM audit_events e WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf(`SQL: ListEvents: Scan(): %w`, err)
+	}
+
+	query := `SELECT ` + auditEventColumns + `
+			  FROM ` + auditEventTables + `
+			  WHERE ` + where + `
+			  ORDER BY e.id DESC
+			  LIMIT $6 OFFSET $7`
+
+	events, err := r.listEvents(ctx, "ListEvents", query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// ListEventsAfter returns up to limit events with ids greater than afterId in chain order.
+func (r *AuditRepo) ListEventsAfter(ctx context.Context, afterId int64, limit int) ([]types.AuditEvent, error) {
+	query := `SELECT ` + auditEventColumns + `
+			  FROM ` + auditEventTables + `
+			  WHERE e.id > $1
+			  ORDER BY e.id
+			  LIMIT $2`
+
+	return r.listEvents(ctx, "ListEventsAfter", query, afterId, limit)
+}
+
+// ListUserEvents returns the events about the user or performed by the user in chain order.
+func (r *AuditRepo) ListUserEvents(ctx context.Context, userId string) ([]types.AuditEvent, error) {
+	query := `SELECT ` + auditEventColumns + `
+			  FROM ` + auditEventTables + `
+			  WHERE e.user_id = $1 OR e.actor_id = $1
+			  ORDER BY e.id`
+
+	return r.listEvents(ctx, "ListUserEvents", query, userId)
+}
+
+func (r *AuditRepo) listEvents(ctx context.Context, name string, query string, args ...any) ([]types.AuditEvent, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Query(): %w`, name, err)
+	}
+	defer rows.Close()
+
+	events := make([]types.AuditEvent, 0)
+	for rows.Next() {
+		event := types.AuditEvent{}
+		if err = scanAuditEvent(rows, &event); err != nil {
+			return nil, fmt.Errorf(`SQL: %s: Scan(): %w`, name, err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Rows(): %w`, name, err)
+	}
+
+	return events, nil
+}
+
+// nullTime passes a zero time as NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
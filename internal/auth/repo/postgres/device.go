@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"time"
+)
+
+// slowDownStep is added to the polling interval each time a client polls too fast (RFC 8628, section 3.5).
+const slowDownStep = 5 * time.Second
+
+type DeviceRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewDeviceRepo(db *pgxpool.Pool) *DeviceRepo {
+	return &DeviceRepo{
+		pool: db,
+	}
+}
+
+func (r *DeviceRepo) CreateDeviceCode(ctx context.Context, code types.DeviceCode) error {
+	query := `INSERT INTO oauth_device_codes
+				(device_code_hash, user_code, client_id, scope, status, poll_interval, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.pool.Exec(ctx, query,
+		code.DeviceCodeHash,
+		code.UserCode,
+		code.ClientId,
+		code.Scope,
+		code.Status,
+		int(code.Interval.Seconds()),
+		code.ExpiresAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == ErrUniqueViolationCode {
+				return ErrUniqueContraintFailed
+			}
+		}
+		return fmt.Errorf("SQL: CreateDeviceCode: Exec(): %w", err)
+	}
+	return nil
+}
+
+const deviceCodeColumns = `device_code_hash, user_code, client_id, scope, status, COALESCE(user_uuid::text, ''),
+						   poll_interval, last_polled_at, expires_at, created_at`
+
+func scanDeviceCode(row pgx.Row, code *types.DeviceCode) error {
+	var interval int
+	if err := row.Scan(
+		&code.DeviceCodeHash,
+		&code.UserCode,
+		&code.ClientId,
+		&code.Scope,
+		&code.Status,
+		&code.UserId,
+		&interval,
+		&code.LastPolledAt,
+		&code.ExpiresAt,
+		&code.CreatedAt,
+	); err != nil {
+		return err
+	}
+	code.Interval = time.Duration(interval) * time.Second
+	return nil
+}
+
+func (r *DeviceRepo) GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*types.DeviceCode, error) {
+	code := types.DeviceCode{}
+
+	query := `SELECT ` + deviceCodeColumns + `
+			  FROM oauth_device_codes
+			  WHERE user_code = $1`
+
+	if err := scanDeviceCode(r.pool.QueryRow(ctx, query, userCode), &code); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetDeviceCodeByUserCode: Scan(): %w`, err)
+	}
+
+	return &code, nil
+}
+
+// ResolveDeviceCode approves or denies a pending device code. It reports false if
+// the code is not pending anymore.
+func (r *DeviceRepo) ResolveDeviceCode(ctx context.Context, userCode string, status string, userId string) (bool, error) {
+	query := `UPDATE oauth_device_codes
+			  SET status = $2, user_uuid = NULLIF($3, '')::uuid
+			  WHERE user_code = $1 AND status = 'pending'`
+	tag, err := r.pool.Exec(ctx, query, userCode, status, userId)
+	if err != nil {
+		return false, fmt.Errorf("SQL: ResolveDeviceCode: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// PollDeviceCode records a token request for the device code and returns the code as it
+// was before the poll. Polling faster than the interval sets SlowDown and increases the
+// interval. An approved code is consumed by the first poll that is not too fast.
+func (r *DeviceRepo) PollDeviceCode(ctx context.Context, deviceCodeHash string) (*types.DeviceCode, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: PollDeviceCode: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	code := types.DeviceCode{}
+
+	selectQuery := `SELECT ` + deviceCodeColumns + `
+					FROM oauth_device_codes
+					WHERE device_code_hash = $1
+					FOR UPDATE`
+
+	if err = scanDeviceCode(tx.QueryRow(ctx, selectQuery, deviceCodeHash), &code); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: PollDeviceCode: Scan(): %w`, err)
+	}
+
+	now := time.Now()
+	code.SlowDown = code.LastPolledAt != nil && now.Sub(*code.LastPolledAt) < code.Interval
+
+	interval := code.Interval
+	status := code.Status
+	if code.SlowDown {
+		interval += slowDownStep
+	} else if status == types.DeviceCodeApproved {
+		status = types.DeviceCodeConsumed
+	}
+
+	updateQuery := `UPDATE oauth_device_codes
+					SET last_polled_at = $2, poll_interval = $3, status = $4
+					WHERE device_code_hash = $1`
+	if _, err = tx.Exec(ctx, updateQuery, deviceCodeHash, now, int(interval.Seconds()), status); err != nil {
+		return nil, fmt.Errorf(`SQL: PollDeviceCode: Exec(): %w`, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf(`SQL: PollDeviceCode: Commit(): %w`, err)
+	}
+
+	return &code, nil
+}
@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+)
+
+type IdentityRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdentityRepo(db *pgxpool.Pool) *IdentityRepo {
+	return &IdentityRepo{
+		pool: db,
+	}
+}
+
+func (r *IdentityRepo) CreateIdentity(ctx context.Context, identity types.ExternalIdentity) error {
+	query := `INSERT INTO identities (id, provider, subject, user_uuid, email)
+			  VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.pool.Exec(ctx, query, identity.Id, identity.Provider, identity.Subject, identity.UserId, identity.Email)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == ErrUniqueViolationCode {
+				return ErrUniqueContraintFailed
+			}
+		}
+		return fmt.Errorf("SQL: CreateIdentity: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *IdentityRepo) GetIdentity(ctx context.Context, provider string, subject string) (*types.ExternalIdentity, error) {
+	identity := types.ExternalIdentity{}
+
+	query := `SELECT id, provider, subject, user_uuid, email, created_at, last_login_at
+			  FROM identities
+			  WHERE provider = $1 AND subject = $2`
+
+	if err := r.pool.QueryRow(ctx, query, provider, subject).Scan(
+		&identity.Id,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.UserId,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetIdentity: Scan(): %w`, err)
+	}
+
+	return &identity, nil
+}
+
+func (r *IdentityRepo) UpdateIdentityLogin(ctx context.Context, id string, email string) error {
+	query := `UPDATE identities
+			  SET email = $2, last_login_at = now()
+			  WHERE id = $1`
+	if _, err := r.pool.Exec(ctx, query, id, email); err != nil {
+		return fmt.Errorf("SQL: UpdateIdentityLogin: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *IdentityRepo) CreateLoginState(ctx context.Context, state types.SocialLoginState) error {
+	query := `INSERT INTO social_login_states (state_hash, provider, nonce, code_verifier, expires_at)
+			  VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.pool.Exec(ctx, query, state.StateHash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("SQL: CreateLoginState: Exec(): %w", err)
+	}
+	return nil
+}
+
+// ConsumeLoginState deletes the state and returns it, so every state can be used once.
+func (r *IdentityRepo) ConsumeLoginState(ctx context.Context, stateHash string) (*types.SocialLoginState, error) {
+	state := types.SocialLoginState{}
+
+	query := `DELETE FROM social_login_states
+			  WHERE state_hash = $1
+			  RETURNING state_hash, provider, nonce, code_verifier, expires_at`
+
+	if err := r.pool.QueryRow(ctx, query, stateHash).Scan(
+		&state.StateHash,
+		&state.Provider,
+		&state.Nonce,
+		&state.CodeVerifier,
+		&state.ExpiresAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: ConsumeLoginState: Scan(): %w`, err)
+	}
+
+	return &state, nil
+}
+
+func (r *IdentityRepo) ListUserIdentities(ctx context.Context, userId string) ([]types.ExternalIdentity, error) {
+	query := `SELECT id, provider, subject, user_uuid, email, created_at, last_login_at
+			  FROM identities
+			  WHERE user_uuid = $1
+			  ORDER BY created_at`
+
+	rows, err := r.pool.Query(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListUserIdentities: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	identities := make([]types.ExternalIdentity, 0)
+	for rows.Next() {
+		identity := types.ExternalIdentity{}
+		if err = rows.Scan(
+			&identity.Id,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.UserId,
+			&identity.Email,
+			&identity.CreatedAt,
+			&identity.LastLoginAt,
+		); err != nil {
+			return nil, fmt.Errorf(`SQL: ListUserIdentities: Scan(): %w`, err)
+		}
+		identities = append(identities, identity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListUserIdentities: Rows(): %w`, err)
+	}
+
+	return identities, nil
+}
+
+func (r *IdentityRepo) CreateLinkRequest(ctx context.Context, request types.SocialLinkRequest) error {
+	query := `INSERT INTO social_link_requests (token_hash, provider, subject, user_uuid, email, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if _, err := r.pool.Exec(ctx, query,
+		request.TokenHash,
+		request.Provider,
+		request.Subject,
+		request.UserId,
+		request.Email,
+		request.ExpiresAt,
+	); err != nil {
+		return fmt.Errorf("SQL: CreateLinkRequest: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *IdentityRepo) GetLinkRequest(ctx context.Context, tokenHash string) (*types.SocialLinkRequest, error) {
+	request := types.SocialLinkRequest{}
+
+	query := `SELECT token_hash, provider, subject, user_uuid, email, attempts, expires_at
+			  FROM social_link_requests
+			  WHERE token_hash = $1`
+
+	if err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&request.TokenHash,
+		&request.Provider,
+		&request.Subject,
+		&request.UserId,
+		&request.Email,
+		&request.Attempts,
+		&request.ExpiresAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetLinkRequest: Scan(): %w`, err)
+	}
+
+	return &request, nil
+}
+
+// AddLinkRequestAttempt counts a wrong password entered to confirm the link.
+func (r *IdentityRepo) AddLinkRequestAttempt(ctx context.Context, tokenHash string) error {
+	query := `UPDATE social_link_requests SET attempts = attempts + 1 WHERE token_hash = $1`
+
+	if _, err := r.pool.Exec(ctx, query, tokenHash); err != nil {
+		return fmt.Errorf("SQL: AddLinkRequestAttempt: Exec(): %w", err)
+	}
+	return nil
+}
+
+// DeleteLinkRequest removes the request and reports whether it existed, so that only one
+// request can complete it.
+func (r *IdentityRepo) DeleteLinkRequest(ctx context.Context, tokenHash string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM social_link_requests WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return false, fmt.Errorf("SQL: DeleteLinkRequest: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"time"
+)
+
+const knownDeviceColumns = `id, user_uuid, device_hash, name, ip, location, trusted_until, created_at, last_seen_at`
+
+type KnownDeviceRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewKnownDeviceRepo(db *pgxpool.Pool) *KnownDeviceRepo {
+	return &KnownDeviceRepo{
+		pool: db,
+	}
+}
+
+func scanKnownDevice(row pgx.Row, device *types.KnownDevice) error {
+	return row.Scan(
+		&device.Id,
+		&device.UserId,
+		&device.DeviceHash,
+		&device.Name,
+		&device.IP,
+		&device.Location,
+		&device.TrustedUntil,
+		&device.CreatedAt,
+		&device.LastSeenAt,
+	)
+}
+
+func (r *KnownDeviceRepo) GetDevice(ctx context.Context, userId string, deviceHash string) (*types.KnownDevice, error) {
+	query := `SELECT ` + knownDeviceColumns + `
+			  FROM known_devices
+			  WHERE user_uuid = $1 AND device_hash = $2`
+
+	device := &types.KnownDevice{}
+	if err := scanKnownDevice(r.pool.QueryRow(ctx, query, userId, deviceHash), device); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetDevice: Scan(): %w`, err)
+	}
+	return device, nil
+}
+
+func (r *KnownDeviceRepo) HasDevices(ctx context.Context, userId string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM known_devices WHERE user_uuid = $1)`
+	if err := r.pool.QueryRow(ctx, query, userId).Scan(&exists); err != nil {
+		return false, fmt.Errorf(`SQL: HasDevices: Scan(): %w`, err)
+	}
+	return exists, nil
+}
+
+// SaveDevice adds the device or updates when and where it was last seen. The trust of a
+// known device is only extended, a nil TrustedUntil keeps the stored one.
+func (r *KnownDeviceRepo) SaveDevice(ctx context.Context, device types.KnownDevice) error {
+	query := `INSERT INTO known_devices (id, user_uuid, device_hash, name, ip, location, trusted_until)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  ON CONFLICT (user_uuid, device_hash) DO UPDATE
+			  SET name = EXCLUDED.name,
+			      ip = EXCLUDED.ip,
+			      location = EXCLUDED.location,
+			      trusted_until = COALESCE(EXCLUDED.trusted_until, known_devices.trusted_until),
+			      last_seen_at = now()`
+
+	if _, err := r.pool.Exec(ctx, query,
+		device.Id,
+		device.UserId,
+		device.DeviceHash,
+		device.Name,
+		device.IP,
+		device.Location,
+		device.TrustedUntil,
+	); err != nil {
+		return fmt.Errorf("SQL: SaveDevice: Exec(): %w", err)
+	}
+	return nil
+}
+
+// ListDevices returns the devices of the user, most recently seen first.
+func (r *KnownDeviceRepo) ListDevices(ctx context.Context, userId string) ([]types.KnownDevice, error) {
+	query := `SELECT ` + knownDeviceColumns + `
+			  FROM known_devices
+			  WHERE user_uuid = $1
+			  ORDER BY last_seen_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListDevices: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	devices := make([]types.KnownDevice, 0)
+	for rows.Next() {
+		device := types.KnownDevice{}
+		if err = scanKnownDevice(rows, &device); err != nil {
+			return nil, fmt.Errorf(`SQL: ListDevices: Scan(): %w`, err)
+		}
+		devices = append(devices, device)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListDevices: Rows(): %w`, err)
+	}
+
+	return devices, nil
+}
+
+func (r *KnownDeviceRepo) DeleteDevice(ctx context.Context, userId string, deviceId string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM known_devices WHERE user_uuid = $1 AND id = $2`, userId, deviceId)
+	if err != nil {
+		return false, fmt.Errorf("SQL: DeleteDevice: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteStaleDevices removes devices last seen before the given time.
+func (r *KnownDeviceRepo) DeleteStaleDevices(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM known_devices WHERE last_seen_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("SQL: DeleteStaleDevices: Exec(): %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"time"
+)
+
+const loginAttemptColumns = `id, user_uuid, method, success, failure_reason, ip, user_agent, device, location,
+	country, asn, latitude, longitude, created_at`
+
+type LoginHistoryRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewLoginHistoryRepo(db *pgxpool.Pool) *LoginHistoryRepo {
+	return &LoginHistoryRepo{
+		pool: db,
+	}
+}
+
+func scanLoginAttempt(row pgx.Row, attempt *types.LoginAttempt) error {
+	return row.Scan(
+		&attempt.Id,
+		&attempt.UserId,
+		&attempt.Method,
+		&attempt.Success,
+		&attempt.FailureReason,
+		&attempt.IP,
+		&attempt.UserAgent,
+		&attempt.Device,
+		&attempt.Location,
+		&attempt.Country,
+		&attempt.ASN,
+		&attempt.Latitude,
+		&attempt.Longitude,
+		&attempt.CreatedAt,
+	)
+}
+
+func (r *LoginHistoryRepo) AddLoginAttempt(ctx context.Context, attempt types.LoginAttempt) error {
+	query := `INSERT INTO login_attempts (user_uuid, method, success, failure_reason, ip, user_agent, device, location,
+			  	country, asn, latitude, longitude)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	if _, err := r.pool.Exec(ctx, query,
+		attempt.UserId,
+		attempt.Method,
+		attempt.Success,
+		attempt.FailureReason,
+		attempt.IP,
+		attempt.UserAgent,
+		attempt.Device,
+		attempt.Location,
+		attempt.Country,
+		attempt.ASN,
+		attempt.Latitude,
+		attempt.Longitude,
+	); err != nil {
+		return fmt.Errorf("SQL: AddLoginAttempt: Exec(): %w", err)
+	}
+	return nil
+}
+
+// ListLoginAttempts returns up to limit attempts of the user made after since, newest first.
+func (r *LoginHistoryRepo) ListLoginAttempts(ctx context.Context, userId string, limit int, since time.Time) ([]types.LoginAttempt, error) {
+	query := `SELECT ` + loginAttemptColumns + `
+			  FROM login_attempts
+			  WHERE user_uuid = $1 AND created_at > $2
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $3`
+
+	rows, err := r.pool.Query(ctx, query, userId, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListLoginAttempts: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	attempts := make([]types.LoginAttempt, 0)
+	for rows.Next() {
+		attempt := types.LoginAttempt{}
+		if err = scanLoginAttempt(rows, &attempt); err != nil {
+			return nil, fmt.Errorf(`SQL: ListLoginAttempts: Scan(): %w`, err)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListLoginAttempts: Rows(): %w`, err)
+	}
+
+	return attempts, nil
+}
+
+func (r *LoginHistoryRepo) DeleteLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM login_attempts WHERE created_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("SQL: DeleteLoginAttempts: Exec(): %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
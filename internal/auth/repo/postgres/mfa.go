@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+)
+
+const mfaChallengeColumns = `token_hash, user_uuid, COALESCE(organization_id::text, ''), method, channel, code_hash,
+	attempts, expires_at, created_at`
+
+type MFARepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewMFARepo(db *pgxpool.Pool) *MFARepo {
+	return &MFARepo{
+		pool: db,
+	}
+}
+
+func (r *MFARepo) CreateChallenge(ctx context.Context, challenge types.MFAChallenge) error {
+	query := `INSERT INTO mfa_challenges (token_hash, user_uuid, organization_id, method, channel, code_hash, expires_at)
+			  VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, $7)`
+
+	if _, err := r.pool.Exec(ctx, query,
+		challenge.TokenHash,
+		challenge.UserId,
+		challenge.OrganizationId,
+		challenge.Method,
+		challenge.Channel,
+		challenge.CodeHash,
+		challenge.ExpiresAt,
+	); err != nil {
+		return fmt.Errorf("SQL: CreateChallenge: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *MFARepo) GetChallenge(ctx context.Context, tokenHash string) (*types.MFAChallenge, error) {
+	challenge := types.MFAChallenge{}
+	query := `SELECT ` + mfaChallengeColumns + `
+			  FROM mfa_challenges
+			  WHERE token_hash = $1`
+
+	if err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&challenge.TokenHash,
+		&challenge.UserId,
+		&challenge.OrganizationId,
+		&challenge.Method,
+		&challenge.Channel,
+		&challenge.CodeHash,
+		&challenge.Attempts,
+		&challenge.ExpiresAt,
+		&challenge.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetChallenge: Scan(): %w`, err)
+	}
+	return &challenge, nil
+}
+
+// AddChallengeAttempt counts a wrong code entered for the challenge.
+func (r *MFARepo) AddChallengeAttempt(ctx context.Context, tokenHash string) error {
+	query := `UPDATE mfa_challenges SET attempts = attempts + 1 WHERE token_hash = $1`
+
+	if _, err := r.pool.Exec(ctx, query, tokenHash); err != nil {
+		return fmt.Errorf("SQL: AddChallengeAttempt: Exec(): %w", err)
+	}
+	return nil
+}
+
+// DeleteChallenge removes the challenge and reports whether it existed, so that only one
+// request can complete it.
+func (r *MFARepo) DeleteChallenge(ctx context.Context, tokenHash string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM mfa_challenges WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return false, fmt.Errorf("SQL: DeleteChallenge: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *MFARepo) DeleteExpiredChallenges(ctx context.Context) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM mfa_challenges WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("SQL: DeleteExpiredChallenges: Exec(): %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
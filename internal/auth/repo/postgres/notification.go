@@ -0,0 +1,250 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"time"
+)
+
+const pendingNotificationColumns = `id, user_uuid, event, channel, subject, body, created_at`
+
+type NotificationRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewNotificationRepo(db *pgxpool.Pool) *NotificationRepo {
+	return &NotificationRepo{
+		pool: db,
+	}
+}
+
+func scanPendingNotification(row pgx.Row, notification *types.PendingNotification) error {
+	return row.Scan(
+		&notification.Id,
+		&notification.UserId,
+		&notification.Event,
+		&notification.Channel,
+		&notification.Subject,
+		&notification.Body,
+		&notification.CreatedAt,
+	)
+}
+
+// ListPreferences returns the preferences the user has changed, events with default
+// preferences are left out.
+func (r *NotificationRepo) ListPreferences(ctx context.Context, userId string) ([]types.NotificationPreference, error) {
+	query := `SELECT event, channels, delivery
+			  FROM notification_preferences
+			  WHERE user_uuid = $1
+			  ORDER BY event`
+
+	rows, err := r.pool.Query(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListPreferences: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	preferences := make([]types.NotificationPreference, 0)
+	for rows.Next() {
+		preference := types.NotificationPreference{}
+		if err = rows.Scan(&preference.Event, &preference.Channels, &preference.Delivery); err != nil {
+			return nil, fmt.Errorf(`SQL: ListPreferences: Scan(): %w`, err)
+		}
+		preferences = append(preferences, preference)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListPreferences: Rows(): %w`, err)
+	}
+
+	return preferences, nil
+}
+
+func (r *NotificationRepo) SavePreferences(ctx context.Context, userId string, preferences []types.NotificationPreference) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: SavePreferences: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `INSERT INTO notification_preferences (user_uuid, event, channels, delivery)
+			  VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (user_uuid, event) DO UPDATE
+			  SET channels = EXCLUDED.channels,
+			      delivery = EXCLUDED.delivery,
+			      updated_at = now()`
+	for _, preference := range preferences {
+		if _, err = tx.Exec(ctx, query, userId, preference.Event, preference.Channels, preference.Delivery); err != nil {
+			return fmt.Errorf("SQL: SavePreferences: Exec(): %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: SavePreferences: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+func (r *NotificationRepo) EnqueueNotification(ctx context.Context, notification types.PendingNotification) error {
+	query := `INSERT INTO pending_notifications (user_uuid, event, channel, subject, body)
+			  VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := r.pool.Exec(ctx, query,
+		notification.UserId,
+		notification.Event,
+		notification.Channel,
+		notification.Subject,
+		notification.Body,
+	); err != nil {
+		return fmt.Errorf("SQL: EnqueueNotification: Exec(): %w", err)
+	}
+	return nil
+}
+
+// ListPendingUsers returns up to limit users that have notifications waiting for a digest.
+func (r *NotificationRepo) ListPendingUsers(ctx context.Context, limit int) ([]string, error) {
+	query := `SELECT DISTINCT user_uuid::text
+			  FROM pending_notifications
+			  ORDER BY 1
+			  LIMIT $1`
+
+	rows, err := r.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListPendingUsers: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	userIds := make([]string, 0)
+	for rows.Next() {
+		var userId string
+		if err = rows.Scan(&userId); err != nil {
+			return nil, fmt.Errorf(`SQL: ListPendingUsers: Scan(): %w`, err)
+		}
+		userIds = append(userIds, userId)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListPendingUsers: Rows(): %w`, err)
+	}
+
+	return userIds, nil
+}
+
+// TakePendingNotifications removes and returns the waiting notifications of the user in the
+// order they were queued. Notifications taken by a concurrent call are skipped.
+func (r *NotificationRepo) TakePendingNotifications(ctx context.Context, userId string) ([]types.PendingNotification, error) {
+	query := `DELETE FROM pending_notifications
+			  WHERE id IN (
+			  	SELECT id FROM pending_notifications
+			  	WHERE user_uuid = $1
+			  	FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + pendingNotificationColumns
+
+	rows, err := r.pool.Query(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: TakePendingNotifications: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	notifications := make([]types.PendingNotification, 0)
+	for rows.Next() {
+		notification := types.PendingNotification{}
+		if err = scanPendingNotification(rows, &notification); err != nil {
+			return nil, fmt.Errorf(`SQL: TakePendingNotifications: Scan(): %w`, err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: TakePendingNotifications: Rows(): %w`, err)
+	}
+
+	return notifications, nil
+}
+
+// GetTimeZone returns the time zone the user has chosen, empty if the user has not.
+func (r *NotificationRepo) GetTimeZone(ctx context.Context, userId string) (string, error) {
+	query := `SELECT time_zone FROM activity_summaries WHERE user_uuid = $1`
+
+	var timeZone string
+	if err := r.pool.QueryRow(ctx, query, userId).Scan(&timeZone); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf(`SQL: GetTimeZone: Scan(): %w`, err)
+	}
+	return timeZone, nil
+}
+
+// SaveTimeZone sets the time zone of the user and clears the time of the next summary, so
+// that it is scheduled again in the new time zone.
+func (r *NotificationRepo) SaveTimeZone(ctx context.Context, userId string, timeZone string) error {
+	query := `INSERT INTO activity_summaries (user_uuid, time_zone)
+			  VALUES ($1, $2)
+			  ON CONFLICT (user_uuid) DO UPDATE
+			  SET time_zone = EXCLUDED.time_zone,
+			      next_at = NULL,
+			      updated_at = now()`
+
+	if _, err := r.pool.Exec(ctx, query, userId, timeZone); err != nil {
+		return fmt.Errorf("SQL: SaveTimeZone: Exec(): %w", err)
+	}
+	return nil
+}
+
+// ListDueSummaries returns up to limit active users whose summary is due at now or has not
+// been scheduled yet, unscheduled first.
+func (r *NotificationRepo) ListDueSummaries(ctx context.Context, now time.Time, limit int) ([]types.SummarySchedule, error) {
+	query := `SELECT u.user_uuid::text, COALESCE(s.time_zone, 'UTC'), s.next_at
+			  FROM users u
+			  LEFT JOIN activity_summaries s ON s.user_uuid = u.user_uuid
+			  WHERE u.status = $1 AND (s.next_at IS NULL OR s.next_at <= $2)
+			  ORDER BY s.next_at NULLS FIRST, u.user_uuid
+			  LIMIT $3`
+
+	rows, err := r.pool.Query(ctx, query, types.UserStatusActive, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListDueSummaries: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	schedules := make([]types.SummarySchedule, 0)
+	for rows.Next() {
+		schedule := types.SummarySchedule{}
+		if err = rows.Scan(&schedule.UserId, &schedule.TimeZone, &schedule.NextAt); err != nil {
+			return nil, fmt.Errorf(`SQL: ListDueSummaries: Scan(): %w`, err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListDueSummaries: Rows(): %w`, err)
+	}
+
+	return schedules, nil
+}
+
+// ScheduleSummary moves the next summary of the user from previous to next and reports
+// whether it was still at previous, so that only one scheduler sends a due summary.
+func (r *NotificationRepo) ScheduleSummary(ctx context.Context, userId string, previous *time.Time, next time.Time) (bool, error) {
+	query := `INSERT INTO activity_summaries (user_uuid, next_at)
+			  VALUES ($1, $3)
+			  ON CONFLICT (user_uuid) DO UPDATE
+			  SET next_at = EXCLUDED.next_at,
+			      updated_at = now()
+			  WHERE activity_summaries.next_at IS NOT DISTINCT FROM $2`
+
+	tag, err := r.pool.Exec(ctx, query, userId, previous, next)
+	if err != nil {
+		return false, fmt.Errorf("SQL: ScheduleSummary: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
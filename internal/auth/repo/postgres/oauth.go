@@ -0,0 +1,330 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"time"
+)
+
+type OAuthRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewOAuthRepo(db *pgxpool.Pool) *OAuthRepo {
+	return &OAuthRepo{
+		pool: db,
+	}
+}
+
+func (r *OAuthRepo) CreateClient(ctx context.Context, client types.OAuthClient) error {
+	query := `INSERT INTO oauth_clients (client_id, name, client_type, redirect_uris, scopes, grant_types, exchange_audiences)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.pool.Exec(ctx, query,
+		client.ClientId,
+		client.Name,
+		client.Type,
+		client.RedirectURIs,
+		client.Scopes,
+		client.GrantTypes,
+		client.ExchangeAudiences,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == ErrUniqueViolationCode {
+				return ErrUniqueContraintFailed
+			}
+		}
+		return fmt.Errorf("SQL: CreateClient: Exec(): %w", err)
+	}
+	return nil
+}
+
+const clientColumns = `client_id, name, client_type, redirect_uris, scopes, grant_types, exchange_audiences,
+	created_at, updated_at`
+
+func scanClient(row pgx.Row, client *types.OAuthClient) error {
+	return row.Scan(
+		&client.ClientId,
+		&client.Name,
+		&client.Type,
+		&client.RedirectURIs,
+		&client.Scopes,
+		&client.GrantTypes,
+		&client.ExchangeAudiences,
+		&client.CreatedAt,
+		&client.UpdatedAt,
+	)
+}
+
+func (r *OAuthRepo) GetClientByID(ctx context.Context, clientId string) (*types.OAuthClient, error) {
+	client := types.OAuthClient{}
+
+	query := `SELECT ` + clientColumns + `
+			  FROM oauth_clients
+			  WHERE client_id = $1`
+
+	if err := scanClient(r.pool.QueryRow(ctx, query, clientId), &client); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetClientByID: Scan(): %w`, err)
+	}
+
+	return &client, nil
+}
+
+func (r *OAuthRepo) ListClients(ctx context.Context) ([]types.OAuthClient, error) {
+	query := `SELECT ` + clientColumns + `
+			  FROM oauth_clients
+			  ORDER BY created_at`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListClients: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	clients := make([]types.OAuthClient, 0)
+	for rows.Next() {
+		client := types.OAuthClient{}
+		if err = scanClient(rows, &client); err != nil {
+			return nil, fmt.Errorf(`SQL: ListClients: Scan(): %w`, err)
+		}
+		clients = append(clients, client)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListClients: Rows(): %w`, err)
+	}
+
+	return clients, nil
+}
+
+func (r *OAuthRepo) UpdateClient(ctx context.Context, client types.OAuthClient) error {
+	query := `UPDATE oauth_clients
+			  SET name = $2, redirect_uris = $3, scopes = $4, grant_types = $5, exchange_audiences = $6, updated_at = now()
+			  WHERE client_id = $1`
+	_, err := r.pool.Exec(ctx, query,
+		client.ClientId,
+		client.Name,
+		client.RedirectURIs,
+		client.Scopes,
+		client.GrantTypes,
+		client.ExchangeAudiences,
+	)
+	if err != nil {
+		return fmt.Errorf("SQL: UpdateClient: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *OAuthRepo) DeleteClient(ctx context.Context, clientId string) error {
+	query := `DELETE FROM oauth_clients
+			  WHERE client_id = $1`
+	if _, err := r.pool.Exec(ctx, query, clientId); err != nil {
+		return fmt.Errorf("SQL: DeleteClient: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *OAuthRepo) GetActiveClientSecrets(ctx context.Context, clientId string) ([]types.OAuthClientSecret, error) {
+	query := `SELECT id, client_id, secret_hash, created_at, expires_at
+			  FROM oauth_client_secrets
+			  WHERE client_id = $1 AND (expires_at IS NULL OR expires_at > now())
+			  ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, clientId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: GetActiveClientSecrets: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	secrets := make([]types.OAuthClientSecret, 0)
+	for rows.Next() {
+		secret := types.OAuthClientSecret{}
+		if err = rows.Scan(
+			&secret.Id,
+			&secret.ClientId,
+			&secret.SecretHash,
+			&secret.CreatedAt,
+			&secret.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf(`SQL: GetActiveClientSecrets: Scan(): %w`, err)
+		}
+		secrets = append(secrets, secret)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: GetActiveClientSecrets: Rows(): %w`, err)
+	}
+
+	return secrets, nil
+}
+
+// RotateClientSecret adds secret to the client. The newest of the current secrets stays
+// active until previousExpiresAt, all older ones are removed, so at most two secrets
+// are active at any time.
+func (r *OAuthRepo) RotateClientSecret(ctx context.Context, secret types.OAuthClientSecret, previousExpiresAt time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: RotateClientSecret: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	lockQuery := `SELECT client_id FROM oauth_clients WHERE client_id = $1 FOR UPDATE`
+	if _, err = tx.Exec(ctx, lockQuery, secret.ClientId); err != nil {
+		return fmt.Errorf(`SQL: RotateClientSecret: Exec(): %w`, err)
+	}
+
+	deleteQuery := `DELETE FROM oauth_client_secrets
+					WHERE client_id = $1 AND id NOT IN (
+						SELECT id FROM oauth_client_secrets
+						WHERE client_id = $1 AND (expires_at IS NULL OR expires_at > now())
+						ORDER BY created_at DESC
+						LIMIT 1
+					)`
+	if _, err = tx.Exec(ctx, deleteQuery, secret.ClientId); err != nil {
+		return fmt.Errorf(`SQL: RotateClientSecret: Exec(): %w`, err)
+	}
+
+	expireQuery := `UPDATE oauth_client_secrets
+					SET expires_at = LEAST(COALESCE(expires_at, $2), $2)
+					WHERE client_id = $1`
+	if _, err = tx.Exec(ctx, expireQuery, secret.ClientId, previousExpiresAt); err != nil {
+		return fmt.Errorf(`SQL: RotateClientSecret: Exec(): %w`, err)
+	}
+
+	insertQuery := `INSERT INTO oauth_client_secrets (id, client_id, secret_hash, expires_at)
+					VALUES ($1, $2, $3, $4)`
+	if _, err = tx.Exec(ctx, insertQuery, secret.Id, secret.ClientId, secret.SecretHash, secret.ExpiresAt); err != nil {
+		return fmt.Errorf(`SQL: RotateClientSecret: Exec(): %w`, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: RotateClientSecret: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+func (r *OAuthRepo) DeleteClientSecret(ctx context.Context, clientId string, secretId string) (bool, error) {
+	query := `DELETE FROM oauth_client_secrets
+			  WHERE client_id = $1 AND id = $2`
+	tag, err := r.pool.Exec(ctx, query, clientId, secretId)
+	if err != nil {
+		return false, fmt.Errorf("SQL: DeleteClientSecret: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *OAuthRepo) CreateAuthorizationCode(ctx context.Context, code types.AuthorizationCode) error {
+	query := `INSERT INTO oauth_authorization_codes
+    			(code_hash, client_id, user_uuid, redirect_uri, scope, code_challenge, code_challenge_method,
+    			 nonce, auth_time, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := r.pool.Exec(ctx, query,
+		code.CodeHash,
+		code.ClientId,
+		code.UserId,
+		code.RedirectURI,
+		code.Scope,
+		code.CodeChallenge,
+		code.CodeChallengeMethod,
+		code.Nonce,
+		code.AuthTime,
+		code.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("SQL: CreateAuthorizationCode: Exec(): %w", err)
+	}
+	return nil
+}
+
+const authorizationCodeColumns = `code_hash, client_id, user_uuid, redirect_uri, scope,
+			   code_challenge, code_challenge_method, nonce, auth_time, expires_at, used,
+			   COALESCE(session_id::text, '')`
+
+func scanAuthorizationCode(row pgx.Row, code *types.AuthorizationCode) error {
+	return row.Scan(
+		&code.CodeHash,
+		&code.ClientId,
+		&code.UserId,
+		&code.RedirectURI,
+		&code.Scope,
+		&code.CodeChallenge,
+		&code.CodeChallengeMethod,
+		&code.Nonce,
+		&code.AuthTime,
+		&code.ExpiresAt,
+		&code.Used,
+		&code.SessionId,
+	)
+}
+
+// GetAuthorizationCode returns the code without using it, so that the request can be checked
+// against it first.
+func (r *OAuthRepo) GetAuthorizationCode(ctx context.Context, codeHash string) (*types.AuthorizationCode, error) {
+	query := `SELECT ` + authorizationCodeColumns + `
+			  FROM oauth_authorization_codes
+			  WHERE code_hash = $1`
+
+	code := &types.AuthorizationCode{}
+	if err := scanAuthorizationCode(r.pool.QueryRow(ctx, query, codeHash), code); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetAuthorizationCode: Scan(): %w`, err)
+	}
+	return code, nil
+}
+
+// ConsumeAuthorizationCode marks the code as used for the session it is exchanged for and
+// returns it as it was before. A code that was already used is returned with Used set and the
+// session it was exchanged for, so the caller can detect a replay and revoke that session.
+func (r *OAuthRepo) ConsumeAuthorizationCode(ctx context.Context, codeHash string, sessionId string) (*types.AuthorizationCode, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ConsumeAuthorizationCode: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	code := types.AuthorizationCode{}
+
+	selectQuery := `SELECT ` + authorizationCodeColumns + `
+			        FROM oauth_authorization_codes
+			        WHERE code_hash = $1
+			        FOR UPDATE`
+
+	if err = scanAuthorizationCode(tx.QueryRow(ctx, selectQuery, codeHash), &code); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: ConsumeAuthorizationCode: Scan(): %w`, err)
+	}
+	if code.Used {
+		return &code, nil
+	}
+
+	updateQuery := `UPDATE oauth_authorization_codes
+					SET used = true, session_id = $2
+					WHERE code_hash = $1`
+	if _, err = tx.Exec(ctx, updateQuery, codeHash, sessionId); err != nil {
+		return nil, fmt.Errorf(`SQL: ConsumeAuthorizationCode: Exec(): %w`, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf(`SQL: ConsumeAuthorizationCode: Commit(): %w`, err)
+	}
+
+	return &code, nil
+}
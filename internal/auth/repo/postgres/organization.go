@@ -0,0 +1,310 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+)
+
+type OrganizationRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewOrganizationRepo(db *pgxpool.Pool) *OrganizationRepo {
+	return &OrganizationRepo{
+		pool: db,
+	}
+}
+
+func (r *OrganizationRepo) CreateOrganization(ctx context.Context, org types.Organization) error {
+	query := `INSERT INTO organizations (id, name, slug)
+			  VALUES ($1, $2, $3)`
+	if _, err := r.pool.Exec(ctx, query, org.Id, org.Name, org.Slug); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == ErrUniqueViolationCode {
+				return ErrUniqueContraintFailed
+			}
+		}
+		return fmt.Errorf("SQL: CreateOrganization: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *OrganizationRepo) GetOrganizationByID(ctx context.Context, orgId string) (*types.Organization, error) {
+	org := types.Organization{}
+
+	query := `SELECT id, name, slug, created_at
+			  FROM organizations
+			  WHERE id = $1`
+
+	if err := r.pool.QueryRow(ctx, query, orgId).Scan(
+		&org.Id,
+		&org.Name,
+		&org.Slug,
+		&org.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetOrganizationByID: Scan(): %w`, err)
+	}
+
+	return &org, nil
+}
+
+func (r *OrganizationRepo) ListOrganizations(ctx context.Context) ([]types.Organization, error) {
+	query := `SELECT id, name, slug, created_at
+			  FROM organizations
+			  ORDER BY name`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListOrganizations: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	orgs := make([]types.Organization, 0)
+	for rows.Next() {
+		org := types.Organization{}
+		if err = rows.Scan(&org.Id, &org.Name, &org.Slug, &org.CreatedAt); err != nil {
+			return nil, fmt.Errorf(`SQL: ListOrganizations: Scan(): %w`, err)
+		}
+		orgs = append(orgs, org)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListOrganizations: Rows(): %w`, err)
+	}
+
+	return orgs, nil
+}
+
+const membershipColumns = `m.organization_id, o.name, m.user_uuid, u.email, m.created_at,
+	ARRAY(SELECT r.name FROM membership_roles mr JOIN roles r ON r.id = mr.role_id
+		  WHERE mr.organization_id = m.organization_id AND mr.user_uuid = m.user_uuid ORDER BY r.name)`
+
+const membershipJoins = `memberships m
+			  JOIN organizations o ON o.id = m.organization_id
+			  JOIN users u ON u.user_uuid = m.user_uuid`
+
+func scanMembership(row pgx.Row, membership *types.Membership) error {
+	return row.Scan(
+		&membership.OrganizationId,
+		&membership.OrganizationName,
+		&membership.UserId,
+		&membership.Email,
+		&membership.CreatedAt,
+		&membership.Roles,
+	)
+}
+
+func (r *OrganizationRepo) GetMembership(ctx context.Context, orgId string, userId string) (*types.Membership, error) {
+	membership := types.Membership{}
+
+	query := `SELECT ` + membershipColumns + `
+			  FROM ` + membershipJoins + `
+			  WHERE m.organization_id = $1 AND m.user_uuid = $2`
+
+	if err := scanMembership(r.pool.QueryRow(ctx, query, orgId, userId), &membership); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetMembership: Scan(): %w`, err)
+	}
+
+	return &membership, nil
+}
+
+func (r *OrganizationRepo) ListMembers(ctx context.Context, orgId string) ([]types.Membership, error) {
+	query := `SELECT ` + membershipColumns + `
+			  FROM ` + membershipJoins + `
+			  WHERE m.organization_id = $1
+			  ORDER BY u.email`
+
+	return r.listMemberships(ctx, "ListMembers", query, orgId)
+}
+
+func (r *OrganizationRepo) ListUserMemberships(ctx context.Context, userId string) ([]types.Membership, error) {
+	query := `SELECT ` + membershipColumns + `
+			  FROM ` + membershipJoins + `
+			  WHERE m.user_uuid = $1
+			  ORDER BY o.name`
+
+	return r.listMemberships(ctx, "ListUserMemberships", query, userId)
+}
+
+func (r *OrganizationRepo) listMemberships(ctx context.Context, name string, query string, arg string) ([]types.Membership, error) {
+	rows, err := r.pool.Query(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Query(): %w`, name, err)
+	}
+	defer rows.Close()
+
+	memberships := make([]types.Membership, 0)
+	for rows.Next() {
+		membership := types.Membership{}
+		if err = scanMembership(rows, &membership); err != nil {
+			return nil, fmt.Errorf(`SQL: %s: Scan(): %w`, name, err)
+		}
+		memberships = append(memberships, membership)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Rows(): %w`, name, err)
+	}
+
+	return memberships, nil
+}
+
+// SetMembership adds the user to the organization if needed and replaces the user's roles there.
+func (r *OrganizationRepo) SetMembership(ctx context.Context, orgId string, userId string, roleIds []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: SetMembership: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err = setMembership(ctx, tx, orgId, userId, roleIds); err != nil {
+		return fmt.Errorf(`SQL: SetMembership: %w`, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: SetMembership: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+func setMembership(ctx context.Context, tx pgx.Tx, orgId string, userId string, roleIds []string) error {
+	insertQuery := `INSERT INTO memberships (organization_id, user_uuid)
+					VALUES ($1, $2)
+					ON CONFLICT DO NOTHING`
+	if _, err := tx.Exec(ctx, insertQuery, orgId, userId); err != nil {
+		return fmt.Errorf("setMembership: Exec(): %w", err)
+	}
+
+	deleteQuery := `DELETE FROM membership_roles
+					WHERE organization_id = $1 AND user_uuid = $2`
+	if _, err := tx.Exec(ctx, deleteQuery, orgId, userId); err != nil {
+		return fmt.Errorf("setMembership: Exec(): %w", err)
+	}
+
+	rolesQuery := `INSERT INTO membership_roles (organization_id, user_uuid, role_id)
+				   SELECT $1, $2, id FROM roles WHERE id = ANY($3::uuid[])`
+	if _, err := tx.Exec(ctx, rolesQuery, orgId, userId, roleIds); err != nil {
+		return fmt.Errorf("setMembership: Exec(): %w", err)
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepo) DeleteMembership(ctx context.Context, orgId string, userId string) (bool, error) {
+	query := `DELETE FROM memberships
+			  WHERE organization_id = $1 AND user_uuid = $2`
+	tag, err := r.pool.Exec(ctx, query, orgId, userId)
+	if err != nil {
+		return false, fmt.Errorf("SQL: DeleteMembership: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *OrganizationRepo) CreateInvitation(ctx context.Context, invitation types.Invitation) error {
+	query := `INSERT INTO organization_invitations (id, organization_id, email, role_ids, token_hash, invited_by, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::uuid, $7)`
+	_, err := r.pool.Exec(ctx, query,
+		invitation.Id,
+		invitation.OrganizationId,
+		invitation.Email,
+		invitation.RoleIds,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("SQL: CreateInvitation: Exec(): %w", err)
+	}
+	return nil
+}
+
+// AcceptInvitation marks the invitation as accepted and makes userId a member with the
+// invited roles. It returns nil if there is no such invitation or it was already accepted.
+func (r *OrganizationRepo) AcceptInvitation(ctx context.Context, tokenHash string, userId string) (*types.Invitation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: AcceptInvitation: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	invitation := types.Invitation{}
+
+	query := `UPDATE organization_invitations
+			  SET accepted_at = now()
+			  WHERE token_hash = $1 AND accepted_at IS NULL
+			  RETURNING id, organization_id, email, role_ids::text[], token_hash, COALESCE(invited_by::text, ''),
+			  	created_at, expires_at, accepted_at`
+
+	if err = tx.QueryRow(ctx, query, tokenHash).Scan(
+		&invitation.Id,
+		&invitation.OrganizationId,
+		&invitation.Email,
+		&invitation.RoleIds,
+		&invitation.TokenHash,
+		&invitation.InvitedBy,
+		&invitation.CreatedAt,
+		&invitation.ExpiresAt,
+		&invitation.AcceptedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: AcceptInvitation: Scan(): %w`, err)
+	}
+
+	if err = setMembership(ctx, tx, invitation.OrganizationId, userId, invitation.RoleIds); err != nil {
+		return nil, fmt.Errorf(`SQL: AcceptInvitation: %w`, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf(`SQL: AcceptInvitation: Commit(): %w`, err)
+	}
+
+	return &invitation, nil
+}
+
+// GetInvitation returns the invitation by its token hash.
+func (r *OrganizationRepo) GetInvitation(ctx context.Context, tokenHash string) (*types.Invitation, error) {
+	invitation := types.Invitation{}
+
+	query := `SELECT id, organization_id, email, role_ids::text[], token_hash, COALESCE(invited_by::text, ''),
+			  	created_at, expires_at, accepted_at
+			  FROM organization_invitations
+			  WHERE token_hash = $1`
+
+	if err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&invitation.Id,
+		&invitation.OrganizationId,
+		&invitation.Email,
+		&invitation.RoleIds,
+		&invitation.TokenHash,
+		&invitation.InvitedBy,
+		&invitation.CreatedAt,
+		&invitation.ExpiresAt,
+		&invitation.AcceptedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetInvitation: Scan(): %w`, err)
+	}
+
+	return &invitation, nil
+}
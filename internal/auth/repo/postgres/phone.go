@@ -0,0 +1,212 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"time"
+)
+
+const (
+	userPhoneColumns = `user_uuid, phone, verified_at, use_for_mfa, COALESCE(pending_phone, ''), pending_use_for_mfa,
+		created_at, updated_at`
+	smsCodeColumns = `token_hash, user_uuid, purpose, phone, code_hash, attempts, expires_at, created_at`
+)
+
+type PhoneRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPhoneRepo(db *pgxpool.Pool) *PhoneRepo {
+	return &PhoneRepo{
+		pool: db,
+	}
+}
+
+func (r *PhoneRepo) GetPhone(ctx context.Context, userId string) (*types.UserPhone, error) {
+	phone := types.UserPhone{}
+	query := `SELECT ` + userPhoneColumns + `
+			  FROM user_phones
+			  WHERE user_uuid = $1`
+
+	if err := r.pool.QueryRow(ctx, query, userId).Scan(
+		&phone.UserId,
+		&phone.Phone,
+		&phone.VerifiedAt,
+		&phone.UseForMFA,
+		&phone.PendingPhone,
+		&phone.PendingUseForMFA,
+		&phone.CreatedAt,
+		&phone.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetPhone: Scan(): %w`, err)
+	}
+	return &phone, nil
+}
+
+// SavePhone sets the phone of the user. A changed number loses its verification.
+func (r *PhoneRepo) SavePhone(ctx context.Context, phone types.UserPhone) error {
+	query := `INSERT INTO user_phones (user_uuid, phone, use_for_mfa)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT (user_uuid) DO UPDATE
+			  SET verified_at = CASE WHEN user_phones.phone = EXCLUDED.phone THEN user_phones.verified_at END,
+			      phone = EXCLUDED.phone,
+			      use_for_mfa = EXCLUDED.use_for_mfa,
+			      updated_at = now()`
+
+	if _, err := r.pool.Exec(ctx, query, phone.UserId, phone.Phone, phone.UseForMFA); err != nil {
+		return fmt.Errorf("SQL: SavePhone: Exec(): %w", err)
+	}
+	return nil
+}
+
+// SavePendingPhone sets the number that replaces the phone of the user once verified, the
+// phone itself is left as is.
+func (r *PhoneRepo) SavePendingPhone(ctx context.Context, userId string, phone string, useForMFA bool) (bool, error) {
+	query := `UPDATE user_phones
+			  SET pending_phone = $2, pending_use_for_mfa = $3, updated_at = now()
+			  WHERE user_uuid = $1`
+
+	tag, err := r.pool.Exec(ctx, query, userId, phone, useForMFA)
+	if err != nil {
+		return false, fmt.Errorf("SQL: SavePendingPhone: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// VerifyPhone marks the phone of the user verified if it is still the given number. A
+// pending number replaces the phone.
+func (r *PhoneRepo) VerifyPhone(ctx context.Context, userId string, phone string, verifiedAt time.Time) (bool, error) {
+	query := `UPDATE user_phones
+			  SET use_for_mfa = CASE WHEN phone = $2 THEN use_for_mfa ELSE pending_use_for_mfa END,
+			      phone = $2,
+			      pending_phone = NULL,
+			      verified_at = $3,
+			      updated_at = now()
+			  WHERE user_uuid = $1 AND (phone = $2 OR pending_phone = $2)`
+
+	tag, err := r.pool.Exec(ctx, query, userId, phone, verifiedAt)
+	if err != nil {
+		return false, fmt.Errorf("SQL: VerifyPhone: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeletePhone removes the phone of the user together with the codes sent to it.
+func (r *PhoneRepo) DeletePhone(ctx context.Context, userId string) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf(`SQL: DeletePhone: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, `DELETE FROM user_phones WHERE user_uuid = $1`, userId)
+	if err != nil {
+		return false, fmt.Errorf("SQL: DeletePhone: Exec(): %w", err)
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM sms_codes WHERE user_uuid = $1`, userId); err != nil {
+		return false, fmt.Errorf("SQL: DeletePhone: Exec(): %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf(`SQL: DeletePhone: Commit(): %w`, err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *PhoneRepo) CreateSMSCode(ctx context.Context, code types.SMSCode) error {
+	query := `INSERT INTO sms_codes (token_hash, user_uuid, purpose, phone, code_hash, expires_at, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	if _, err := r.pool.Exec(ctx, query,
+		code.TokenHash,
+		code.UserId,
+		code.Purpose,
+		code.Phone,
+		code.CodeHash,
+		code.ExpiresAt,
+		code.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("SQL: CreateSMSCode: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *PhoneRepo) GetSMSCode(ctx context.Context, tokenHash string) (*types.SMSCode, error) {
+	code := types.SMSCode{}
+	query := `SELECT ` + smsCodeColumns + `
+			  FROM sms_codes
+			  WHERE token_hash = $1`
+
+	if err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&code.TokenHash,
+		&code.UserId,
+		&code.Purpose,
+		&code.Phone,
+		&code.CodeHash,
+		&code.Attempts,
+		&code.ExpiresAt,
+		&code.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetSMSCode: Scan(): %w`, err)
+	}
+	return &code, nil
+}
+
+// CountSMSCodes returns how many codes for the purpose were sent to the user since the given time.
+func (r *PhoneRepo) CountSMSCodes(ctx context.Context, userId string, purpose string, since time.Time) (int, error) {
+	query := `SELECT count(*)
+			  FROM sms_codes
+			  WHERE user_uuid = $1 AND purpose = $2 AND created_at > $3`
+
+	var count int
+	if err := r.pool.QueryRow(ctx, query, userId, purpose, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf(`SQL: CountSMSCodes: Scan(): %w`, err)
+	}
+	return count, nil
+}
+
+// AddSMSCodeAttempt counts a wrong code entered for the token.
+func (r *PhoneRepo) AddSMSCodeAttempt(ctx context.Context, tokenHash string) error {
+	query := `UPDATE sms_codes SET attempts = attempts + 1 WHERE token_hash = $1`
+
+	if _, err := r.pool.Exec(ctx, query, tokenHash); err != nil {
+		return fmt.Errorf("SQL: AddSMSCodeAttempt: Exec(): %w", err)
+	}
+	return nil
+}
+
+// UseSMSCode expires the code and reports whether it was still valid, so that only one
+// request can use it. The code is kept until DeleteSMSCodes to count the codes sent.
+func (r *PhoneRepo) UseSMSCode(ctx context.Context, tokenHash string) (bool, error) {
+	query := `UPDATE sms_codes
+			  SET expires_at = now()
+			  WHERE token_hash = $1 AND expires_at > now()`
+
+	tag, err := r.pool.Exec(ctx, query, tokenHash)
+	if err != nil {
+		return false, fmt.Errorf("SQL: UseSMSCode: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteSMSCodes removes codes sent before the given time.
+func (r *PhoneRepo) DeleteSMSCodes(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM sms_codes WHERE created_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("SQL: DeleteSMSCodes: Exec(): %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,355 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+)
+
+type RBACRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewRBACRepo(db *pgxpool.Pool) *RBACRepo {
+	return &RBACRepo{
+		pool: db,
+	}
+}
+
+// EnsurePermissions adds the missing permissions and updates descriptions of the existing ones.
+func (r *RBACRepo) EnsurePermissions(ctx context.Context, permissions []types.Permission) error {
+	query := `INSERT INTO permissions (name, description)
+			  VALUES ($1, $2)
+			  ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description`
+
+	batch := &pgx.Batch{}
+	for _, permission := range permissions {
+		batch.Queue(query, permission.Name, permission.Description)
+	}
+
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("SQL: EnsurePermissions: SendBatch(): %w", err)
+	}
+	return nil
+}
+
+func (r *RBACRepo) ListPermissions(ctx context.Context) ([]types.Permission, error) {
+	query := `SELECT name, description
+			  FROM permissions
+			  ORDER BY name`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListPermissions: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	permissions := make([]types.Permission, 0)
+	for rows.Next() {
+		permission := types.Permission{}
+		if err = rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, fmt.Errorf(`SQL: ListPermissions: Scan(): %w`, err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListPermissions: Rows(): %w`, err)
+	}
+
+	return permissions, nil
+}
+
+func (r *RBACRepo) CreateRole(ctx context.Context, role types.Role) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: CreateRole: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `INSERT INTO roles (id, name, description)
+			  VALUES ($1, $2, $3)`
+	if _, err = tx.Exec(ctx, query, role.Id, role.Name, role.Description); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == ErrUniqueViolationCode {
+				return ErrUniqueContraintFailed
+			}
+		}
+		return fmt.Errorf("SQL: CreateRole: Exec(): %w", err)
+	}
+
+	if err = setRolePermissions(ctx, tx, role.Id, role.Permissions); err != nil {
+		return fmt.Errorf("SQL: CreateRole: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: CreateRole: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+const roleColumns = `r.id, r.name, r.description, r.created_at, r.updated_at,
+	COALESCE(ARRAY(SELECT permission FROM role_permissions WHERE role_id = r.id ORDER BY permission), '{}')`
+
+func scanRole(row pgx.Row, role *types.Role) error {
+	return row.Scan(
+		&role.Id,
+		&role.Name,
+		&role.Description,
+		&role.CreatedAt,
+		&role.UpdatedAt,
+		&role.Permissions,
+	)
+}
+
+func (r *RBACRepo) GetRoleByID(ctx context.Context, roleId string) (*types.Role, error) {
+	role := types.Role{}
+
+	query := `SELECT ` + roleColumns + `
+			  FROM roles r
+			  WHERE r.id = $1`
+
+	if err := scanRole(r.pool.QueryRow(ctx, query, roleId), &role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetRoleByID: Scan(): %w`, err)
+	}
+
+	return &role, nil
+}
+
+func (r *RBACRepo) GetRoleByName(ctx context.Context, name string) (*types.Role, error) {
+	role := types.Role{}
+
+	query := `SELECT ` + roleColumns + `
+			  FROM roles r
+			  WHERE r.name = $1`
+
+	if err := scanRole(r.pool.QueryRow(ctx, query, name), &role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetRoleByName: Scan(): %w`, err)
+	}
+
+	return &role, nil
+}
+
+func (r *RBACRepo) ListRoles(ctx context.Context) ([]types.Role, error) {
+	query := `SELECT ` + roleColumns + `
+			  FROM roles r
+			  ORDER BY r.name`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListRoles: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	roles := make([]types.Role, 0)
+	for rows.Next() {
+		role := types.Role{}
+		if err = scanRole(rows, &role); err != nil {
+			return nil, fmt.Errorf(`SQL: ListRoles: Scan(): %w`, err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListRoles: Rows(): %w`, err)
+	}
+
+	return roles, nil
+}
+
+func (r *RBACRepo) UpdateRole(ctx context.Context, role types.Role) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: UpdateRole: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `UPDATE roles
+			  SET name = $2, description = $3, updated_at = now()
+			  WHERE id = $1`
+	if _, err = tx.Exec(ctx, query, role.Id, role.Name, role.Description); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == ErrUniqueViolationCode {
+				return ErrUniqueContraintFailed
+			}
+		}
+		return fmt.Errorf("SQL: UpdateRole: Exec(): %w", err)
+	}
+
+	deleteQuery := `DELETE FROM role_permissions
+					WHERE role_id = $1`
+	if _, err = tx.Exec(ctx, deleteQuery, role.Id); err != nil {
+		return fmt.Errorf("SQL: UpdateRole: Exec(): %w", err)
+	}
+
+	if err = setRolePermissions(ctx, tx, role.Id, role.Permissions); err != nil {
+		return fmt.Errorf("SQL: UpdateRole: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: UpdateRole: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+// GrantRolePermissions adds permissions to the role and keeps the ones it already has.
+func (r *RBACRepo) GrantRolePermissions(ctx context.Context, roleId string, permissions []string) error {
+	query := `INSERT INTO role_permissions (role_id, permission)
+			  SELECT $1, unnest($2::text[])
+			  ON CONFLICT DO NOTHING`
+	if _, err := r.pool.Exec(ctx, query, roleId, permissions); err != nil {
+		return fmt.Errorf("SQL: GrantRolePermissions: Exec(): %w", err)
+	}
+	return nil
+}
+
+func setRolePermissions(ctx context.Context, tx pgx.Tx, roleId string, permissions []string) error {
+	query := `INSERT INTO role_permissions (role_id, permission)
+			  SELECT $1, unnest($2::text[])`
+	if _, err := tx.Exec(ctx, query, roleId, permissions); err != nil {
+		return fmt.Errorf("setRolePermissions: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *RBACRepo) DeleteRole(ctx context.Context, roleId string) (bool, error) {
+	query := `DELETE FROM roles
+			  WHERE id = $1`
+	tag, err := r.pool.Exec(ctx, query, roleId)
+	if err != nil {
+		return false, fmt.Errorf("SQL: DeleteRole: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *RBACRepo) ListUserRoles(ctx context.Context, userId string) ([]types.UserRole, error) {
+	query := `SELECT ur.user_uuid, ur.role_id, r.name, ur.source, ur.created_at
+			  FROM user_roles ur
+			  JOIN roles r ON r.id = ur.role_id
+			  WHERE ur.user_uuid = $1
+			  ORDER BY r.name, ur.source`
+
+	rows, err := r.pool.Query(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListUserRoles: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	roles := make([]types.UserRole, 0)
+	for rows.Next() {
+		role := types.UserRole{}
+		if err = rows.Scan(
+			&role.UserId,
+			&role.RoleId,
+			&role.RoleName,
+			&role.Source,
+			&role.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf(`SQL: ListUserRoles: Scan(): %w`, err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListUserRoles: Rows(): %w`, err)
+	}
+
+	return roles, nil
+}
+
+func (r *RBACRepo) AssignRole(ctx context.Context, userRole types.UserRole) error {
+	query := `INSERT INTO user_roles (user_uuid, role_id, source)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING`
+	if _, err := r.pool.Exec(ctx, query, userRole.UserId, userRole.RoleId, userRole.Source); err != nil {
+		return fmt.Errorf("SQL: AssignRole: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *RBACRepo) UnassignRole(ctx context.Context, userRole types.UserRole) (bool, error) {
+	query := `DELETE FROM user_roles
+			  WHERE user_uuid = $1 AND role_id = $2 AND source = $3`
+	tag, err := r.pool.Exec(ctx, query, userRole.UserId, userRole.RoleId, userRole.Source)
+	if err != nil {
+		return false, fmt.Errorf("SQL: UnassignRole: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReplaceSourceRoles replaces the roles the source granted to the user. Unknown role names are skipped.
+func (r *RBACRepo) ReplaceSourceRoles(ctx context.Context, userId string, source string, roleNames []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: ReplaceSourceRoles: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	deleteQuery := `DELETE FROM user_roles
+					WHERE user_uuid = $1 AND source = $2`
+	if _, err = tx.Exec(ctx, deleteQuery, userId, source); err != nil {
+		return fmt.Errorf("SQL: ReplaceSourceRoles: Exec(): %w", err)
+	}
+
+	insertQuery := `INSERT INTO user_roles (user_uuid, role_id, source)
+					SELECT $1, id, $2 FROM roles WHERE name = ANY($3)`
+	if _, err = tx.Exec(ctx, insertQuery, userId, source, roleNames); err != nil {
+		return fmt.Errorf("SQL: ReplaceSourceRoles: Exec(): %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: ReplaceSourceRoles: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+// GetRolePermissions returns the distinct permissions of the roles with the given names.
+func (r *RBACRepo) GetRolePermissions(ctx context.Context, roleNames []string) ([]string, error) {
+	query := `SELECT DISTINCT rp.permission
+			  FROM role_permissions rp
+			  JOIN roles r ON r.id = rp.role_id
+			  WHERE r.name = ANY($1)
+			  ORDER BY rp.permission`
+
+	rows, err := r.pool.Query(ctx, query, roleNames)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: GetRolePermissions: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	permissions := make([]string, 0)
+	for rows.Next() {
+		var permission string
+		if err = rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf(`SQL: GetRolePermissions: Scan(): %w`, err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: GetRolePermissions: Rows(): %w`, err)
+	}
+
+	return permissions, nil
+}
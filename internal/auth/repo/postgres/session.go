@@ -20,32 +20,49 @@ func NewSessionRepo(db *pgxpool.Pool) *SessionRepo {
 }
 
 func (s *SessionRepo) CreateSession(ctx context.Context, session types.Session) error {
-	query := `INSERT INTO sessions (id, user_uuid, refresh_token, expires_at, used)
-					VALUES ($1, $2, $3, $4, $5)`
-	_, err := s.pool.Exec(ctx, query, session.SessionId, session.UserId, session.RefreshToken, session.ExpiresAt, session.Used)
+	query := `INSERT INTO sessions (id, user_uuid, refresh_token, expires_at, used, client_id, scope, organization_id, ip, country, city, asn, family_id)
+					VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, '')::uuid, $9, $10, $11, $12, $1)`
+	_, err := s.pool.Exec(ctx, query, session.SessionId, session.UserId, session.RefreshToken, session.ExpiresAt, session.Used,
+		session.ClientId, session.Scope, session.OrganizationId, session.IP, session.Country, session.City, session.ASN)
 	if err != nil {
 		return fmt.Errorf("SQL: CreateSession: Exec(): %w", err)
 	}
 	return nil
 }
 
-func (s *SessionRepo) GetSessionById(ctx context.Context, sessionId string) (*types.Session, error) {
-	session := types.Session{}
-	query := `SELECT id, user_uuid, refresh_token, expires_at, used
-			  FROM sessions
-	          WHERE id = $1`
+const sessionColumns = `id, user_uuid, refresh_token, expires_at, used, COALESCE(client_id, ''), scope,
+	COALESCE(organization_id::text, ''), ip, country, city, asn, created_at, revoked_at`
 
-	if err := s.pool.QueryRow(ctx, query, sessionId).Scan(
+func scanSession(row pgx.Row, session *types.Session) error {
+	return row.Scan(
 		&session.SessionId,
 		&session.UserId,
 		&session.RefreshToken,
 		&session.ExpiresAt,
 		&session.Used,
-	); err != nil {
+		&session.ClientId,
+		&session.Scope,
+		&session.OrganizationId,
+		&session.IP,
+		&session.Country,
+		&session.City,
+		&session.ASN,
+		&session.CreatedAt,
+		&session.RevokedAt,
+	)
+}
+
+func (s *SessionRepo) GetSessionById(ctx context.Context, sessionId string) (*types.Session, error) {
+	session := types.Session{}
+	query := `SELECT ` + sessionColumns + `
+			  FROM sessions
+	          WHERE id = $1`
+
+	if err := scanSession(s.pool.QueryRow(ctx, query, sessionId), &session); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf(`SQL: GetUserByCreds: Scan(): %w`, err)
+		return nil, fmt.Errorf(`SQL: GetSessionById: Scan(): %w`, err)
 	}
 
 	return &session, nil
@@ -82,10 +99,13 @@ func (s *SessionRepo) CreateAndSetUsed(ctx context.Context, session types.Sessio
 		return fmt.Errorf(`SQL: CreateAndSetUsed: Exec(): %w`, err)
 	}
 
-	createQuery := `INSERT INTO sessions (id, user_uuid, refresh_token, expires_at, used)
-					VALUES ($1, $2, $3, $4, $5)`
+	// The rotated session stays in the family of the session it was created from.
+	createQuery := `INSERT INTO sessions (id, user_uuid, refresh_token, expires_at, used, client_id, scope, organization_id, ip, country, city, asn, family_id)
+					VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, '')::uuid, $9, $10, $11, $12,
+					        (SELECT COALESCE(family_id, id) FROM sessions WHERE id = $13))`
 
-	_, err = tx.Exec(ctx, createQuery, session.SessionId, session.UserId, session.RefreshToken, session.ExpiresAt, session.Used)
+	_, err = tx.Exec(ctx, createQuery, session.SessionId, session.UserId, session.RefreshToken, session.ExpiresAt, session.Used,
+		session.ClientId, session.Scope, session.OrganizationId, session.IP, session.Country, session.City, session.ASN, usedSessionId)
 	if err != nil {
 		return fmt.Errorf(`SQL: CreateAndSetUsed: Exec(): %w`, err)
 	}
@@ -96,3 +116,96 @@ func (s *SessionRepo) CreateAndSetUsed(ctx context.Context, session types.Sessio
 
 	return nil
 }
+
+// ListActiveSessions returns the sessions of the user that can still be refreshed.
+func (s *SessionRepo) ListActiveSessions(ctx context.Context, userId string) ([]types.Session, error) {
+	query := `SELECT ` + sessionColumns + `
+			  FROM sessions
+			  WHERE user_uuid = $1 AND NOT used AND revoked_at IS NULL AND expires_at > now()
+			  ORDER BY created_at DESC`
+
+	rows, err := s.pool.Query(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListActiveSessions: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	sessions := make([]types.Session, 0)
+	for rows.Next() {
+		session := types.Session{}
+		if err = scanSession(rows, &session); err != nil {
+			return nil, fmt.Errorf(`SQL: ListActiveSessions: Scan(): %w`, err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListActiveSessions: Rows(): %w`, err)
+	}
+
+	return sessions, nil
+}
+
+// ListUserSessions returns every session of the user, including used, revoked and expired ones.
+func (s *SessionRepo) ListUserSessions(ctx context.Context, userId string) ([]types.Session, error) {
+	query := `SELECT ` + sessionColumns + `
+			  FROM sessions
+			  WHERE user_uuid = $1
+			  ORDER BY created_at DESC`
+
+	rows, err := s.pool.Query(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListUserSessions: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	sessions := make([]types.Session, 0)
+	for rows.Next() {
+		session := types.Session{}
+		if err = scanSession(rows, &session); err != nil {
+			return nil, fmt.Errorf(`SQL: ListUserSessions: Scan(): %w`, err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListUserSessions: Rows(): %w`, err)
+	}
+
+	return sessions, nil
+}
+
+func (s *SessionRepo) RevokeSession(ctx context.Context, userId string, sessionId string) (bool, error) {
+	query := `UPDATE sessions
+			  SET revoked_at = now()
+			  WHERE user_uuid = $1 AND id = $2 AND revoked_at IS NULL`
+	tag, err := s.pool.Exec(ctx, query, userId, sessionId)
+	if err != nil {
+		return false, fmt.Errorf(`SQL: RevokeSession: Exec(): %w`, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeSessionFamily revokes the session and every session rotated from it by refresh.
+func (s *SessionRepo) RevokeSessionFamily(ctx context.Context, sessionId string) (int64, error) {
+	query := `UPDATE sessions
+			  SET revoked_at = now()
+			  WHERE (id = $1 OR family_id = $1) AND revoked_at IS NULL`
+	tag, err := s.pool.Exec(ctx, query, sessionId)
+	if err != nil {
+		return 0, fmt.Errorf(`SQL: RevokeSessionFamily: Exec(): %w`, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+const revokeUserSessionsQuery = `UPDATE sessions
+			  SET revoked_at = now()
+			  WHERE user_uuid = $1 AND revoked_at IS NULL`
+
+func (s *SessionRepo) RevokeUserSessions(ctx context.Context, userId string) (int64, error) {
+	tag, err := s.pool.Exec(ctx, revokeUserSessionsQuery, userId)
+	if err != nil {
+		return 0, fmt.Errorf(`SQL: RevokeUserSessions: Exec(): %w`, err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,291 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"time"
+)
+
+const (
+	signalStreamColumns  = `id, client_id, delivery_method, endpoint_url, authorization_header, events_requested, description, status, status_reason, created_at, updated_at`
+	securityEventColumns = `id, stream_id, event_type, user_id, session_id, txn, payload, status, attempts, next_attempt_at, error, created_at`
+)
+
+type SignalRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSignalRepo(db *pgxpool.Pool) *SignalRepo {
+	return &SignalRepo{
+		pool: db,
+	}
+}
+
+func scanSignalStream(row pgx.Row, stream *types.SignalStream) error {
+	return row.Scan(
+		&stream.Id,
+		&stream.ClientId,
+		&stream.DeliveryMethod,
+		&stream.EndpointURL,
+		&stream.AuthorizationHeader,
+		&stream.EventsRequested,
+		&stream.Description,
+		&stream.Status,
+		&stream.StatusReason,
+		&stream.CreatedAt,
+		&stream.UpdatedAt,
+	)
+}
+
+func scanSecurityEvent(row pgx.Row, event *types.SecurityEvent) error {
+	return row.Scan(
+		&event.Id,
+		&event.StreamId,
+		&event.Type,
+		&event.UserId,
+		&event.SessionId,
+		&event.Txn,
+		&event.Payload,
+		&event.Status,
+		&event.Attempts,
+		&event.NextAttemptAt,
+		&event.Error,
+		&event.CreatedAt,
+	)
+}
+
+func (r *SignalRepo) CreateStream(ctx context.Context, stream types.SignalStream) error {
+	query := `INSERT INTO signal_streams (id, client_id, delivery_method, endpoint_url, authorization_header, events_requested, description, status)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	if _, err := r.pool.Exec(ctx, query,
+		stream.Id,
+		stream.ClientId,
+		stream.DeliveryMethod,
+		stream.EndpointURL,
+		stream.AuthorizationHeader,
+		stream.EventsRequested,
+		stream.Description,
+		stream.Status,
+	); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == ErrUniqueViolationCode {
+			return ErrUniqueContraintFailed
+		}
+		return fmt.Errorf("SQL: CreateStream: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *SignalRepo) GetStream(ctx context.Context, streamId string) (*types.SignalStream, error) {
+	query := `SELECT ` + signalStreamColumns + `
+			  FROM signal_streams
+			  WHERE id = $1`
+
+	return r.getStream(ctx, "GetStream", query, streamId)
+}
+
+func (r *SignalRepo) GetClientStream(ctx context.Context, clientId string) (*types.SignalStream, error) {
+	query := `SELECT ` + signalStreamColumns + `
+			  FROM signal_streams
+			  WHERE client_id = $1`
+
+	return r.getStream(ctx, "GetClientStream", query, clientId)
+}
+
+// ListEventStreams returns the streams that requested the event type and are not disabled.
+func (r *SignalRepo) ListEventStreams(ctx context.Context, eventType string) ([]types.SignalStream, error) {
+	query := `SELECT ` + signalStreamColumns + `
+			  FROM signal_streams
+			  WHERE status <> 'disabled' AND $1 = ANY (events_requested)`
+
+	rows, err := r.pool.Query(ctx, query, eventType)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListEventStreams: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	streams := make([]types.SignalStream, 0)
+	for rows.Next() {
+		stream := types.SignalStream{}
+		if err = scanSignalStream(rows, &stream); err != nil {
+			return nil, fmt.Errorf(`SQL: ListEventStreams: Scan(): %w`, err)
+		}
+		streams = append(streams, stream)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListEventStreams: Rows(): %w`, err)
+	}
+
+	return streams, nil
+}
+
+func (r *SignalRepo) UpdateStream(ctx context.Context, stream types.SignalStream) error {
+	query := `UPDATE signal_streams
+			  SET delivery_method = $2,
+			      endpoint_url = $3,
+			      authorization_header = $4,
+			      events_requested = $5,
+			      description = $6,
+			      status = $7,
+			      status_reason = $8,
+			      updated_at = now()
+			  WHERE id = $1`
+
+	if _, err := r.pool.Exec(ctx, query,
+		stream.Id,
+		stream.DeliveryMethod,
+		stream.EndpointURL,
+		stream.AuthorizationHeader,
+		stream.EventsRequested,
+		stream.Description,
+		stream.Status,
+		stream.StatusReason,
+	); err != nil {
+		return fmt.Errorf("SQL: UpdateStream: Exec(): %w", err)
+	}
+	return nil
+}
+
+// DeleteStream removes the stream together with its queued events.
+func (r *SignalRepo) DeleteStream(ctx context.Context, streamId string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM signal_streams WHERE id = $1`, streamId)
+	if err != nil {
+		return false, fmt.Errorf("SQL: DeleteStream: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *SignalRepo) CreateSecurityEvent(ctx context.Context, event types.SecurityEvent) error {
+	query := `INSERT INTO security_events (id, stream_id, event_type, user_id, session_id, txn, payload, next_attempt_at, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	if _, err := r.pool.Exec(ctx, query,
+		event.Id,
+		event.StreamId,
+		event.Type,
+		event.UserId,
+		event.SessionId,
+		event.Txn,
+		event.Payload,
+		event.NextAttemptAt,
+		event.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("SQL: CreateSecurityEvent: Exec(): %w", err)
+	}
+	return nil
+}
+
+// ClaimPushEvents returns up to limit pending events of enabled push streams that are due at
+// now and postpones them until leaseUntil, so that concurrent pushers do not send them twice.
+func (r *SignalRepo) ClaimPushEvents(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]types.SecurityEvent, error) {
+	query := `UPDATE security_events
+			  SET next_attempt_at = $2
+			  WHERE id IN (
+			  	SELECT e.id FROM security_events e
+			  	JOIN signal_streams s ON s.id = e.stream_id
+			  	WHERE e.status = 'pending' AND e.next_attempt_at <= $1
+			  	  AND s.status = 'enabled' AND s.delivery_method = 'urn:ietf:rfc:8935'
+			  	ORDER BY e.next_attempt_at
+			  	LIMIT $3
+			  	FOR UPDATE OF e SKIP LOCKED
+			  )
+			  RETURNING ` + securityEventColumns
+
+	return r.listSecurityEvents(ctx, "ClaimPushEvents", query, now, leaseUntil, limit)
+}
+
+// ListPollEvents returns up to limit pending events of the stream, oldest first.
+func (r *SignalRepo) ListPollEvents(ctx context.Context, streamId string, limit int) ([]types.SecurityEvent, error) {
+	query := `SELECT ` + securityEventColumns + `
+			  FROM security_events
+			  WHERE stream_id = $1 AND status = 'pending'
+			  ORDER BY created_at, id
+			  LIMIT $2`
+
+	return r.listSecurityEvents(ctx, "ListPollEvents", query, streamId, limit)
+}
+
+// SaveSecurityEvent stores the delivery state of the event.
+func (r *SignalRepo) SaveSecurityEvent(ctx context.Context, event types.SecurityEvent) error {
+	query := `UPDATE security_events
+			  SET status = $2,
+			      attempts = $3,
+			      next_attempt_at = $4,
+			      error = $5
+			  WHERE id = $1`
+
+	if _, err := r.pool.Exec(ctx, query,
+		event.Id,
+		event.Status,
+		event.Attempts,
+		event.NextAttemptAt,
+		event.Error,
+	); err != nil {
+		return fmt.Errorf("SQL: SaveSecurityEvent: Exec(): %w", err)
+	}
+	return nil
+}
+
+// FinishSecurityEvents sets the status of pending events of the stream, events of other
+// streams are left untouched.
+func (r *SignalRepo) FinishSecurityEvents(ctx context.Context, streamId string, eventIds []string, status string, reason string) error {
+	query := `UPDATE security_events
+			  SET status = $3,
+			      error = $4
+			  WHERE stream_id = $1 AND id::text = ANY ($2) AND status = 'pending'`
+
+	if _, err := r.pool.Exec(ctx, query, streamId, eventIds, status, reason); err != nil {
+		return fmt.Errorf("SQL: FinishSecurityEvents: Exec(): %w", err)
+	}
+	return nil
+}
+
+// DeleteSecurityEvents removes events created before the given time whatever their status.
+func (r *SignalRepo) DeleteSecurityEvents(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM security_events WHERE created_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("SQL: DeleteSecurityEvents: Exec(): %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *SignalRepo) getStream(ctx context.Context, name string, query string, args ...any) (*types.SignalStream, error) {
+	stream := &types.SignalStream{}
+	if err := scanSignalStream(r.pool.QueryRow(ctx, query, args...), stream); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: %s: Scan(): %w`, name, err)
+	}
+	return stream, nil
+}
+
+func (r *SignalRepo) listSecurityEvents(ctx context.Context, name string, query string, args ...any) ([]types.SecurityEvent, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Query(): %w`, name, err)
+	}
+	defer rows.Close()
+
+	events := make([]types.SecurityEvent, 0)
+	for rows.Next() {
+		event := types.SecurityEvent{}
+		if err = scanSecurityEvent(rows, &event); err != nil {
+			return nil, fmt.Errorf(`SQL: %s: Scan(): %w`, name, err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Rows(): %w`, name, err)
+	}
+
+	return events, nil
+}
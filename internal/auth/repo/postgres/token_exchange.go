@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+)
+
+type TokenExchangeRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewTokenExchangeRepo(db *pgxpool.Pool) *TokenExchangeRepo {
+	return &TokenExchangeRepo{
+		pool: db,
+	}
+}
+
+func (r *TokenExchangeRepo) CreateTokenExchange(ctx context.Context, exchange types.TokenExchange) error {
+	query := `INSERT INTO token_exchanges
+				(id, client_id, subject, actor, requested_scope, granted_scope, ip, success, error, token_id, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, '')::uuid, $11)`
+	_, err := r.pool.Exec(ctx, query,
+		exchange.Id,
+		exchange.ClientId,
+		exchange.Subject,
+		exchange.Actor,
+		exchange.RequestedScope,
+		exchange.GrantedScope,
+		exchange.IP,
+		exchange.Success,
+		exchange.Error,
+		exchange.TokenId,
+		exchange.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("SQL: CreateTokenExchange: Exec(): %w", err)
+	}
+	return nil
+}
+
+// ListUserTokenExchanges returns the token exchanges where the user was the subject or the actor.
+func (r *TokenExchangeRepo) ListUserTokenExchanges(ctx context.Context, userId string) ([]types.TokenExchange, error) {
+	query := `SELECT id, client_id, subject, actor, requested_scope, granted_scope, ip, success, error,
+				COALESCE(token_id::text, ''), expires_at, created_at
+			  FROM token_exchanges
+			  WHERE subject = $1 OR actor = $1
+			  ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListUserTokenExchanges: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	exchanges := make([]types.TokenExchange, 0)
+	for rows.Next() {
+		exchange := types.TokenExchange{}
+		if err = rows.Scan(
+			&exchange.Id,
+			&exchange.ClientId,
+			&exchange.Subject,
+			&exchange.Actor,
+			&exchange.RequestedScope,
+			&exchange.GrantedScope,
+			&exchange.IP,
+			&exchange.Success,
+			&exchange.Error,
+			&exchange.TokenId,
+			&exchange.ExpiresAt,
+			&exchange.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf(`SQL: ListUserTokenExchanges: Scan(): %w`, err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListUserTokenExchanges: Rows(): %w`, err)
+	}
+
+	return exchanges, nil
+}
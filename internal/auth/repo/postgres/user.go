@@ -8,6 +8,7 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"medods-test/internal/auth/types"
+	"time"
 )
 
 type UserRepo struct {
@@ -21,8 +22,8 @@ func NewUserRepo(db *pgxpool.Pool) *UserRepo {
 }
 
 func (r *UserRepo) Create(ctx context.Context, user types.User) error {
-	query := `INSERT INTO users (user_uuid, email, password) VALUES ($1, $2, $3)`
-	_, err := r.pool.Exec(ctx, query, user.UserUUID, user.Email, user.Password)
+	query := `INSERT INTO users (user_uuid, email, password, organization_id) VALUES ($1, $2, $3, NULLIF($4, '')::uuid)`
+	_, err := r.pool.Exec(ctx, query, user.UserUUID, user.Email, user.Password, user.OrganizationId)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -35,18 +36,36 @@ func (r *UserRepo) Create(ctx context.Context, user types.User) error {
 	return err
 }
 
-func (r *UserRepo) GetUserByCreds(ctx context.Context, email string, password string) (*types.User, error) {
-	user := types.User{}
-
-	query := `SELECT user_uuid, email, password 
-			  FROM users
-	          WHERE email = $1 AND password = $2`
+const userColumns = `user_uuid, email, password, COALESCE(organization_id::text, ''), created_at,
+	password_reset_required, status, status_reason, status_changed_at, purge_at`
 
-	if err := r.pool.QueryRow(ctx, query, email, password).Scan(
+func scanUser(row pgx.Row, user *types.User) error {
+	return row.Scan(
 		&user.UserUUID,
 		&user.Email,
 		&user.Password,
-	); err != nil {
+		&user.OrganizationId,
+		&user.CreatedAt,
+		&user.PasswordResetRequired,
+		&user.Status,
+		&user.StatusReason,
+		&user.StatusChangedAt,
+		&user.PurgeAt,
+	)
+}
+
+// GetUserByCreds looks for the user of the organization first and then for a user without an organization.
+func (r *UserRepo) GetUserByCreds(ctx context.Context, email string, password string, organizationId string) (*types.User, error) {
+	user := types.User{}
+
+	query := `SELECT ` + userColumns + `
+			  FROM users
+	          WHERE email = $1 AND password = $2
+	            AND (organization_id IS NULL OR organization_id = NULLIF($3, '')::uuid)
+	          ORDER BY organization_id NULLS LAST
+	          LIMIT 1`
+
+	if err := scanUser(r.pool.QueryRow(ctx, query, email, password, organizationId), &user); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
@@ -59,15 +78,11 @@ func (r *UserRepo) GetUserByCreds(ctx context.Context, email string, password st
 func (r *UserRepo) GetUserByID(ctx context.Context, userUUID string) (*types.User, error) {
 	user := types.User{}
 
-	query := `SELECT user_uuid, email, password
+	query := `SELECT ` + userColumns + `
               FROM users
               WHERE user_uuid = $1`
 
-	if err := r.pool.QueryRow(ctx, query, userUUID).Scan(
-		&user.UserUUID,
-		&user.Email,
-		&user.Password,
-	); err != nil {
+	if err := scanUser(r.pool.QueryRow(ctx, query, userUUID), &user); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
@@ -75,3 +90,500 @@ func (r *UserRepo) GetUserByID(ctx context.Context, userUUID string) (*types.Use
 	}
 	return &user, nil
 }
+
+func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	user := types.User{}
+
+	query := `SELECT ` + userColumns + `
+              FROM users
+              WHERE lower(email) = lower($1) AND organization_id IS NULL`
+
+	if err := scanUser(r.pool.QueryRow(ctx, query, email), &user); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetUserByEmail: Scan(): %w`, err)
+	}
+	return &user, nil
+}
+
+// GetUserByLogin looks for the user of the organization first and then for a user without an
+// organization, like GetUserByCreds does without the password.
+func (r *UserRepo) GetUserByLogin(ctx context.Context, email string, organizationId string) (*types.User, error) {
+	user := types.User{}
+
+	query := `SELECT ` + userColumns + `
+			  FROM users
+	          WHERE lower(email) = lower($1)
+	            AND (organization_id IS NULL OR organization_id = NULLIF($2, '')::uuid)
+	          ORDER BY organization_id NULLS LAST
+	          LIMIT 1`
+
+	if err := scanUser(r.pool.QueryRow(ctx, query, email, organizationId), &user); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetUserByLogin: Scan(): %w`, err)
+	}
+	return &user, nil
+}
+
+// ListUsers returns a page of users ordered by registration time and the total number of matching users.
+func (r *UserRepo) ListUsers(ctx context.Context, filter types.UserFilter) ([]types.User, int, error) {
+	var total int
+
+	countQuery := `SELECT count(*)
+				   FROM users
+				   WHERE $1 = '' OR email ILIKE '%' || $1 || '%'`
+	if err := r.pool.QueryRow(ctx, countQuery, filter.Query).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf(`SQL: ListUsers: Scan(): %w`, err)
+	}
+
+	query := `SELECT ` + userColumns + `
+			  FROM users
+			  WHERE $1 = '' OR email ILIKE '%' || $1 || '%'
+			  ORDER BY created_at, user_uuid
+			  LIMIT $2 OFFSET $3`
+
+	rows, err := r.pool.Query(ctx, query, filter.Query, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf(`SQL: ListUsers: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	users := make([]types.User, 0)
+	for rows.Next() {
+		user := types.User{}
+		if err = scanUser(rows, &user); err != nil {
+			return nil, 0, fmt.Errorf(`SQL: ListUsers: Scan(): %w`, err)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf(`SQL: ListUsers: Rows(): %w`, err)
+	}
+
+	return users, total, nil
+}
+
+// SetStatus changes the status of the user. Any status but active also revokes all sessions of the user.
+func (r *UserRepo) SetStatus(ctx context.Context, change types.UserStatusChange) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf(`SQL: SetStatus: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `UPDATE users
+			  SET status = $2, status_reason = $3, status_changed_at = now(), purge_at = $4
+			  WHERE user_uuid = $1`
+	tag, err := tx.Exec(ctx, query, change.UserId, change.Status, change.Reason, change.PurgeAt)
+	if err != nil {
+		return false, fmt.Errorf("SQL: SetStatus: Exec(): %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if change.Status != types.UserStatusActive {
+		if _, err = tx.Exec(ctx, revokeUserSessionsQuery, change.UserId); err != nil {
+			return false, fmt.Errorf("SQL: SetStatus: Exec(): %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf(`SQL: SetStatus: Commit(): %w`, err)
+	}
+
+	return true, nil
+}
+
+// eraseUsersQueries remove the users with ids $1 and the personal data kept about them.
+// Rows referencing the users by foreign key are removed by cascade, the token exchange
+// records and the audit events are kept without the personal data. The webhook deliveries
+// and the security events carrying the personal data of the users are removed as well.
+var eraseUsersQueries = []string{
+	`DELETE FROM sessions WHERE user_uuid = ANY($1::uuid[])`,
+	`DELETE FROM oauth_authorization_codes WHERE user_uuid = ANY($1::uuid[])`,
+	`DELETE FROM oauth_device_codes WHERE user_uuid = ANY($1::uuid[])`,
+	`DELETE FROM organization_invitations
+	 WHERE email IN (SELECT email FROM users WHERE user_uuid = ANY($1::uuid[]))`,
+	`UPDATE token_exchanges SET ip = ''
+	 WHERE subject = ANY($1::text[]) OR actor = ANY($1::text[])`,
+	`DELETE FROM webhook_deliveries
+	 WHERE event_id IN (SELECT id FROM audit_events WHERE user_id = ANY($1::text[]) OR actor_id = ANY($1::text[]))`,
+	`DELETE FROM audit_event_details
+	 WHERE event_id IN (SELECT id FROM audit_events WHERE user_id = ANY($1::text[]) OR actor_id = ANY($1::text[]))`,
+	`DELETE FROM security_events WHERE user_id = ANY($1::text[])`,
+	`DELETE FROM users WHERE user_uuid = ANY($1::uuid[])`,
+}
+
+// PurgeUsers removes the users pending deletion whose purge time has come, together
+// with the sessions and grants issued to them, and returns their ids.
+func (r *UserRepo) PurgeUsers(ctx context.Context, limit int) ([]string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: PurgeUsers: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var userIds []string
+
+	selectQuery := `SELECT COALESCE(array_agg(user_uuid::text), '{}')
+					FROM (
+						SELECT user_uuid FROM users
+						WHERE status = $1 AND purge_at <= now()
+						ORDER BY purge_at
+						LIMIT $2
+						FOR UPDATE SKIP LOCKED
+					) due`
+	if err = tx.QueryRow(ctx, selectQuery, types.UserStatusPendingDeletion, limit).Scan(&userIds); err != nil {
+		return nil, fmt.Errorf(`SQL: PurgeUsers: Scan(): %w`, err)
+	}
+	if len(userIds) == 0 {
+		return userIds, nil
+	}
+
+	for _, query := range eraseUsersQueries {
+		if _, err = tx.Exec(ctx, query, userIds); err != nil {
+			return nil, fmt.Errorf("SQL: PurgeUsers: Exec(): %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf(`SQL: PurgeUsers: Commit(): %w`, err)
+	}
+
+	return userIds, nil
+}
+
+// RequirePasswordReset stores the reset token, blocks sign-in with the current password
+// and revokes all sessions of the user.
+func (r *UserRepo) RequirePasswordReset(ctx context.Context, token types.PasswordResetToken) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: RequirePasswordReset: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	updateQuery := `UPDATE users
+					SET password_reset_required = true
+					WHERE user_uuid = $1`
+	if _, err = tx.Exec(ctx, updateQuery, token.UserId); err != nil {
+		return fmt.Errorf("SQL: RequirePasswordReset: Exec(): %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, revokeUserSessionsQuery, token.UserId); err != nil {
+		return fmt.Errorf("SQL: RequirePasswordReset: Exec(): %w", err)
+	}
+
+	insertQuery := `INSERT INTO password_reset_tokens (token_hash, user_uuid, expires_at)
+					VALUES ($1, $2, $3)`
+	if _, err = tx.Exec(ctx, insertQuery, token.TokenHash, token.UserId, token.ExpiresAt); err != nil {
+		return fmt.Errorf("SQL: RequirePasswordReset: Exec(): %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: RequirePasswordReset: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+// GetUserByResetToken returns the user of an unused and not expired password reset token.
+func (r *UserRepo) GetUserByResetToken(ctx context.Context, tokenHash string) (*types.User, error) {
+	user := types.User{}
+
+	query := `SELECT ` + userColumns + `
+			  FROM users
+			  WHERE user_uuid = (
+				  SELECT user_uuid FROM password_reset_tokens
+				  WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+			  )`
+
+	if err := scanUser(r.pool.QueryRow(ctx, query, tokenHash), &user); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetUserByResetToken: Scan(): %w`, err)
+	}
+	return &user, nil
+}
+
+// ResetPassword uses the reset token once and sets the new password. It returns an empty
+// user id if the token is unknown, used or expired.
+func (r *UserRepo) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf(`SQL: ResetPassword: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var userId string
+
+	consumeQuery := `UPDATE password_reset_tokens
+					 SET used_at = now()
+					 WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+					 RETURNING user_uuid`
+	if err = tx.QueryRow(ctx, consumeQuery, tokenHash).Scan(&userId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf(`SQL: ResetPassword: Scan(): %w`, err)
+	}
+
+	updateQuery := `UPDATE users
+					SET password = $2, password_reset_required = false
+					WHERE user_uuid = $1`
+	if _, err = tx.Exec(ctx, updateQuery, userId, passwordHash); err != nil {
+		return "", fmt.Errorf("SQL: ResetPassword: Exec(): %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, revokeUserSessionsQuery, userId); err != nil {
+		return "", fmt.Errorf("SQL: ResetPassword: Exec(): %w", err)
+	}
+
+	invalidateQuery := `UPDATE password_reset_tokens
+						SET used_at = now()
+						WHERE user_uuid = $1 AND used_at IS NULL`
+	if _, err = tx.Exec(ctx, invalidateQuery, userId); err != nil {
+		return "", fmt.Errorf("SQL: ResetPassword: Exec(): %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf(`SQL: ResetPassword: Commit(): %w`, err)
+	}
+
+	return userId, nil
+}
+
+// ChangePassword sets the new password of the user and clears a required password reset.
+func (r *UserRepo) ChangePassword(ctx context.Context, change types.PasswordChange) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: ChangePassword: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	updateQuery := `UPDATE users
+					SET password = $2, password_reset_required = false
+					WHERE user_uuid = $1`
+	if _, err = tx.Exec(ctx, updateQuery, change.UserId, change.PasswordHash); err != nil {
+		return fmt.Errorf("SQL: ChangePassword: Exec(): %w", err)
+	}
+
+	if change.RevokeSessions {
+		revokeQuery := `UPDATE sessions
+						SET revoked_at = now()
+						WHERE user_uuid = $1 AND revoked_at IS NULL AND id::text <> $2`
+		if _, err = tx.Exec(ctx, revokeQuery, change.UserId, change.KeepSessionId); err != nil {
+			return fmt.Errorf("SQL: ChangePassword: Exec(): %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: ChangePassword: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+func (r *UserRepo) CreateEmailChange(ctx context.Context, token types.EmailChangeToken) error {
+	query := `INSERT INTO email_change_tokens (token_hash, user_uuid, new_email, expires_at)
+			  VALUES ($1, $2, $3, $4)`
+
+	if _, err := r.pool.Exec(ctx, query, token.TokenHash, token.UserId, token.NewEmail, token.ExpiresAt); err != nil {
+		return fmt.Errorf("SQL: CreateEmailChange: Exec(): %w", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange consumes the token of the user, sets the new email and invalidates other
+// pending email changes of the user. It returns an empty email if the token is unknown,
+// used or expired.
+func (r *UserRepo) ConfirmEmailChange(ctx context.Context, userId string, tokenHash string) (string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf(`SQL: ConfirmEmailChange: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var newEmail string
+
+	consumeQuery := `UPDATE email_change_tokens
+					 SET used_at = now()
+					 WHERE token_hash = $1 AND user_uuid = $2 AND used_at IS NULL AND expires_at > now()
+					 RETURNING new_email`
+	if err = tx.QueryRow(ctx, consumeQuery, tokenHash, userId).Scan(&newEmail); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf(`SQL: ConfirmEmailChange: Scan(): %w`, err)
+	}
+
+	updateQuery := `UPDATE users
+					SET email = $2
+					WHERE user_uuid = $1`
+	if _, err = tx.Exec(ctx, updateQuery, userId, newEmail); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == ErrUniqueViolationCode {
+			return "", ErrUniqueContraintFailed
+		}
+		return "", fmt.Errorf("SQL: ConfirmEmailChange: Exec(): %w", err)
+	}
+
+	invalidateQuery := `UPDATE email_change_tokens
+						SET used_at = now()
+						WHERE user_uuid = $1 AND used_at IS NULL`
+	if _, err = tx.Exec(ctx, invalidateQuery, userId); err != nil {
+		return "", fmt.Errorf("SQL: ConfirmEmailChange: Exec(): %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf(`SQL: ConfirmEmailChange: Commit(): %w`, err)
+	}
+
+	return newEmail, nil
+}
+
+// ListPasswordHistory returns the latest limit previous password hashes of the user stored after since.
+func (r *UserRepo) ListPasswordHistory(ctx context.Context, userId string, limit int, since time.Time) ([]string, error) {
+	query := `SELECT password_hash
+			  FROM password_history
+			  WHERE user_uuid = $1 AND created_at > $3
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $2`
+
+	rows, err := r.pool.Query(ctx, query, userId, limit, since)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListPasswordHistory: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	hashes := make([]string, 0)
+	for rows.Next() {
+		var passwordHash string
+		if err = rows.Scan(&passwordHash); err != nil {
+			return nil, fmt.Errorf(`SQL: ListPasswordHistory: Scan(): %w`, err)
+		}
+		hashes = append(hashes, passwordHash)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListPasswordHistory: Rows(): %w`, err)
+	}
+
+	return hashes, nil
+}
+
+// AddPasswordHistory stores a previous password hash of the user and keeps only the latest keep hashes.
+func (r *UserRepo) AddPasswordHistory(ctx context.Context, userId string, passwordHash string, keep int) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: AddPasswordHistory: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	insertQuery := `INSERT INTO password_history (user_uuid, password_hash)
+					VALUES ($1, $2)`
+	if _, err = tx.Exec(ctx, insertQuery, userId, passwordHash); err != nil {
+		return fmt.Errorf("SQL: AddPasswordHistory: Exec(): %w", err)
+	}
+
+	pruneQuery := `DELETE FROM password_history
+				   WHERE user_uuid = $1 AND id NOT IN (
+					   SELECT id FROM password_history
+					   WHERE user_uuid = $1
+					   ORDER BY created_at DESC, id DESC
+					   LIMIT $2
+				   )`
+	if _, err = tx.Exec(ctx, pruneQuery, userId, keep); err != nil {
+		return fmt.Errorf("SQL: AddPasswordHistory: Exec(): %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: AddPasswordHistory: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+// DeletePasswordHistory removes password hashes stored before the given time and returns how many were removed.
+func (r *UserRepo) DeletePasswordHistory(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM password_history WHERE created_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("SQL: DeletePasswordHistory: Exec(): %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *UserRepo) CreateDeletionRequest(ctx context.Context, token types.AccountDeletionToken) error {
+	query := `INSERT INTO account_deletion_tokens (token_hash, user_uuid, expires_at)
+			  VALUES ($1, $2, $3)`
+
+	if _, err := r.pool.Exec(ctx, query, token.TokenHash, token.UserId, token.ExpiresAt); err != nil {
+		return fmt.Errorf("SQL: CreateDeletionRequest: Exec(): %w", err)
+	}
+	return nil
+}
+
+// EraseUser removes the user and the personal data kept about the user. With a non-empty
+// tokenHash the user is erased only if it is an unused and not expired deletion token of the user.
+func (r *UserRepo) EraseUser(ctx context.Context, userId string, tokenHash string) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf(`SQL: EraseUser: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if tokenHash != "" {
+		consumeQuery := `UPDATE account_deletion_tokens
+						 SET used_at = now()
+						 WHERE token_hash = $1 AND user_uuid = $2 AND used_at IS NULL AND expires_at > now()`
+		tag, err := tx.Exec(ctx, consumeQuery, tokenHash, userId)
+		if err != nil {
+			return false, fmt.Errorf("SQL: EraseUser: Exec(): %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return false, nil
+		}
+	}
+
+	var lockedId string
+	if err = tx.QueryRow(ctx, `SELECT user_uuid::text FROM users WHERE user_uuid = $1 FOR UPDATE`, userId).Scan(&lockedId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf(`SQL: EraseUser: Scan(): %w`, err)
+	}
+
+	userIds := []string{userId}
+	for _, query := range eraseUsersQueries {
+		if _, err = tx.Exec(ctx, query, userIds); err != nil {
+			return false, fmt.Errorf("SQL: EraseUser: Exec(): %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf(`SQL: EraseUser: Commit(): %w`, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestPool connects to the database of TEST_DATABASE_URL and applies the migrations in
+// a schema of its own, dropped when the test ends. Without TEST_DATABASE_URL the test is skipped.
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	connString := os.Getenv("TEST_DATABASE_URL")
+	if connString == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	admin, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		t.Fatalf("pgxpool.New() error = %v", err)
+	}
+	t.Cleanup(admin.Close)
+	if _, err = admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = admin.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`)
+	})
+
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		t.Fatalf("pgxpool.ParseConfig() error = %v", err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("pgxpool.NewWithConfig() error = %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrations, err := filepath.Glob(filepath.Join("..", "..", "..", "..", "migrations", "*.up.sql"))
+	if err != nil {
+		t.Fatalf("filepath.Glob() error = %v", err)
+	}
+	slices.Sort(migrations)
+	for _, migration := range migrations {
+		query, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatalf("read %s: %v", migration, err)
+		}
+		if _, err = pool.Exec(ctx, string(query)); err != nil {
+			t.Fatalf("apply %s: %v", migration, err)
+		}
+	}
+
+	return pool
+}
+
+const (
+	testOrgId          = "9b8a7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+	testSubscriptionId = "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d"
+	testStreamId       = "2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e"
+)
+
+// seedUser adds the user with the email <userId>@example.com and rows about the user in every
+// table that keeps personal data.
+func seedUser(t *testing.T, pool *pgxpool.Pool, audit *AuditRepo, userId string) {
+	t.Helper()
+
+	ctx := context.Background()
+	email := userId + "@example.com"
+	queries := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO users (user_uuid, email, password) VALUES ($1, $2, 'hash')`, []any{userId, email}},
+		{`INSERT INTO sessions (id, user_uuid, refresh_token, ip) VALUES ($1, $2, $3, '203.0.113.1')`, []any{uuid.NewString(), userId, "refresh-" + userId}},
+		{`INSERT INTO oauth_authorization_codes (code_hash, client_id, user_uuid, redirect_uri, code_challenge, code_challenge_method, expires_at)
+		  VALUES ($1, 'test-client', $2, 'https://app.example.com/callback', 'challenge', 'S256', now() + interval '1 minute')`, []any{"code-" + userId, userId}},
+		{`INSERT INTO oauth_device_codes (device_code_hash, user_code, client_id, status, user_uuid, poll_interval, expires_at)
+		  VALUES ($1, $2, 'test-client', 'approved', $3, 5, now() + interval '10 minutes')`, []any{"device-" + userId, userId[:8], userId}},
+		{`INSERT INTO organization_invitations (id, organization_id, email, token_hash, expires_at)
+		  VALUES ($1, $2, $3, $4, now() + interval '1 day')`, []any{uuid.NewString(), testOrgId, email, "invitation-" + userId}},
+		{`INSERT INTO memberships (organization_id, user_uuid) VALUES ($1, $2)`, []any{testOrgId, userId}},
+		{`INSERT INTO token_exchanges (id, client_id, subject, actor, ip, success) VALUES ($1, 'test-client', $2, $2, '203.0.113.1', true)`, []any{uuid.NewString(), userId}},
+		{`INSERT INTO login_attempts (user_uuid, method, success, ip, user_agent) VALUES ($1, 'password', true, '203.0.113.1', 'curl/8.0')`, []any{userId}},
+		{`INSERT INTO user_phones (user_uuid, phone) VALUES ($1, '+79990000000')`, []any{userId}},
+	}
+	for _, q := range queries {
+		if _, err := pool.Exec(ctx, q.query, q.args...); err != nil {
+			t.Fatalf("seed %s: %v", userId, err)
+		}
+	}
+
+	eventId, err := audit.AppendEvent(ctx, types.AuditEvent{
+		Type:        types.AuditEmailChangeRequested,
+		ActorId:     userId,
+		UserId:      userId,
+		IP:          "203.0.113.1",
+		UserAgent:   "curl/8.0",
+		Metadata:    map[string]string{"new_email": "new-" + email},
+		DetailsSalt: "salt-" + userId,
+		CreatedAt:   time.Now().UTC().Truncate(time.Microsecond),
+	})
+	if err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+
+	payload := fmt.Sprintf(`{"user_id": %q, "metadata": {"new_email": "new-%s"}}`, userId, email)
+	if _, err = pool.Exec(ctx, `INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload) VALUES ($1, $2, $3, $4, $5)`,
+		uuid.NewString(), testSubscriptionId, eventId, types.AuditEmailChangeRequested, payload); err != nil {
+		t.Fatalf("seed %s: %v", userId, err)
+	}
+	if _, err = pool.Exec(ctx, `INSERT INTO security_events (id, stream_id, event_type, user_id, payload) VALUES ($1, $2, 'credential-change', $3, $4)`,
+		uuid.NewString(), testStreamId, userId, payload); err != nil {
+		t.Fatalf("seed %s: %v", userId, err)
+	}
+}
+
+// userRowQueries count the rows that keep personal data of the user $1.
+var userRowQueries = map[string]string{
+	"users":                     `SELECT count(*) FROM users WHERE user_uuid = $1`,
+	"sessions":                  `SELECT count(*) FROM sessions WHERE user_uuid = $1`,
+	"oauth_authorization_codes": `SELECT count(*) FROM oauth_authorization_codes WHERE user_uuid = $1`,
+	"oauth_device_codes":        `SELECT count(*) FROM oauth_device_codes WHERE user_uuid = $1`,
+	"organization_invitations":  `SELECT count(*) FROM organization_invitations WHERE email = $1::text || '@example.com'`,
+	"memberships":               `SELECT count(*) FROM memberships WHERE user_uuid = $1`,
+	"token_exchanges":           `SELECT count(*) FROM token_exchanges WHERE (subject = $1::text OR actor = $1::text) AND ip <> ''`,
+	"login_attempts":            `SELECT count(*) FROM login_attempts WHERE user_uuid = $1`,
+	"user_phones":               `SELECT count(*) FROM user_phones WHERE user_uuid = $1`,
+	"audit_event_details": `SELECT count(*) FROM audit_event_details d JOIN audit_events e ON e.id = d.event_id
+							WHERE e.user_id = $1::text OR e.actor_id = $1::text`,
+	"webhook_deliveries": `SELECT count(*) FROM webhook_deliveries
+						   WHERE event_id IN (SELECT id FROM audit_events WHERE user_id = $1::text) OR payload->>'user_id' = $1::text`,
+	"security_events": `SELECT count(*) FROM security_events WHERE user_id = $1::text OR payload->>'user_id' = $1::text`,
+}
+
+func TestEraseUser(t *testing.T) {
+	pool := newTestPool(t)
+	ctx := context.Background()
+
+	setup := []string{
+		`INSERT INTO oauth_clients (client_id, name) VALUES ('test-client', 'Test')`,
+		`INSERT INTO organizations (id, name, slug) VALUES ('` + testOrgId + `', 'Clinic', 'clinic')`,
+		`INSERT INTO webhook_subscriptions (id, url, secret) VALUES ('` + testSubscriptionId + `', 'https://hooks.example.com', 'secret')`,
+		`INSERT INTO signal_streams (id, client_id, delivery_method) VALUES ('` + testStreamId + `', 'test-client', 'push')`,
+	}
+	for _, query := range setup {
+		if _, err := pool.Exec(ctx, query); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+	}
+
+	erased, kept := "a1b2c3d4-0000-4000-8000-000000000001", "b1c2d3e4-0000-4000-8000-000000000002"
+	audit := NewAuditRepo(pool)
+	seedUser(t, pool, audit, erased)
+	seedUser(t, pool, audit, kept)
+
+	ok, err := NewUserRepo(pool).EraseUser(ctx, erased, "")
+	if err != nil || !ok {
+		t.Fatalf("EraseUser() = %v, %v, want true", ok, err)
+	}
+
+	for table, query := range userRowQueries {
+		var erasedRows, keptRows int
+		if err = pool.QueryRow(ctx, query, erased).Scan(&erasedRows); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		if err = pool.QueryRow(ctx, query, kept).Scan(&keptRows); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		if erasedRows != 0 {
+			t.Errorf("%s keeps %d rows of the erased user", table, erasedRows)
+		}
+		if keptRows == 0 {
+			t.Errorf("%s lost the rows of the other user", table)
+		}
+	}
+
+	// The audit events stay chained without the erased details.
+	events, err := audit.ListEventsAfter(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("ListEventsAfter() error = %v", err)
+	}
+	prevHash := types.AuditGenesisHash
+	for _, event := range events {
+		erasedEvent := event.UserId == erased
+		if event.HasDetails() == erasedEvent {
+			t.Errorf("event %d of %s has details %v", event.Id, event.UserId, event.HasDetails())
+		}
+		if event.HasDetails() && event.ComputeDetailsHash() != event.DetailsHash {
+			t.Errorf("event %d details do not match the details hash", event.Id)
+		}
+		if event.PrevHash != prevHash || event.ComputeHash() != event.Hash {
+			t.Errorf("event %d is not chained", event.Id)
+		}
+		prevHash = event.Hash
+	}
+	if len(events) != 2 {
+		t.Errorf("audit events = %d, want 2", len(events))
+	}
+}
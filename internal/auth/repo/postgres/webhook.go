@@ -0,0 +1,360 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"medods-test/internal/auth/types"
+	"time"
+)
+
+const (
+	webhookSubscriptionColumns = `id, url, description, event_types, secret, active, created_at, updated_at`
+	webhookDeliveryColumns     = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, error, COALESCE(replay_of::text, ''), created_at`
+)
+
+type WebhookRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookRepo(db *pgxpool.Pool) *WebhookRepo {
+	return &WebhookRepo{
+		pool: db,
+	}
+}
+
+func scanWebhookSubscription(row pgx.Row, subscription *types.WebhookSubscription) error {
+	return row.Scan(
+		&subscription.Id,
+		&subscription.URL,
+		&subscription.Description,
+		&subscription.EventTypes,
+		&subscription.Secret,
+		&subscription.Active,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+}
+
+func scanWebhookDelivery(row pgx.Row, delivery *types.WebhookDelivery) error {
+	return row.Scan(
+		&delivery.Id,
+		&delivery.SubscriptionId,
+		&delivery.EventId,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastAttemptAt,
+		&delivery.ResponseStatus,
+		&delivery.Error,
+		&delivery.ReplayOf,
+		&delivery.CreatedAt,
+	)
+}
+
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, subscription types.WebhookSubscription) error {
+	query := `INSERT INTO webhook_subscriptions (id, url, description, event_types, secret, active)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if _, err := r.pool.Exec(ctx, query,
+		subscription.Id,
+		subscription.URL,
+		subscription.Description,
+		subscription.EventTypes,
+		subscription.Secret,
+		subscription.Active,
+	); err != nil {
+		return fmt.Errorf("SQL: CreateSubscription: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) GetSubscription(ctx context.Context, subscriptionId string) (*types.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + `
+			  FROM webhook_subscriptions
+			  WHERE id = $1`
+
+	subscription := &types.WebhookSubscription{}
+	if err := scanWebhookSubscription(r.pool.QueryRow(ctx, query, subscriptionId), subscription); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetSubscription: Scan(): %w`, err)
+	}
+	return subscription, nil
+}
+
+func (r *WebhookRepo) ListSubscriptions(ctx context.Context) ([]types.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + `
+			  FROM webhook_subscriptions
+			  ORDER BY created_at`
+
+	return r.listSubscriptions(ctx, "ListSubscriptions", query)
+}
+
+// ListEventSubscriptions returns the active subscriptions to the event type.
+func (r *WebhookRepo) ListEventSubscriptions(ctx context.Context, eventType string) ([]types.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + `
+			  FROM webhook_subscriptions
+			  WHERE active AND ($1 = ANY (event_types) OR $2 = ANY (event_types))
+			  ORDER BY created_at`
+
+	return r.listSubscriptions(ctx, "ListEventSubscriptions", query, eventType, types.WebhookEventAll)
+}
+
+func (r *WebhookRepo) UpdateSubscription(ctx context.Context, subscription types.WebhookSubscription) error {
+	query := `UPDATE webhook_subscriptions
+			  SET url = $2,
+			      description = $3,
+			      event_types = $4,
+			      active = $5,
+			      updated_at = now()
+			  WHERE id = $1`
+
+	if _, err := r.pool.Exec(ctx, query,
+		subscription.Id,
+		subscription.URL,
+		subscription.Description,
+		subscription.EventTypes,
+		subscription.Active,
+	); err != nil {
+		return fmt.Errorf("SQL: UpdateSubscription: Exec(): %w", err)
+	}
+	return nil
+}
+
+// DeleteSubscription removes the subscription together with its deliveries.
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, subscriptionId string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, subscriptionId)
+	if err != nil {
+		return false, fmt.Errorf("SQL: DeleteSubscription: Exec(): %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *WebhookRepo) CreateDelivery(ctx context.Context, delivery types.WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, next_attempt_at, replay_of)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	var replayOf *string
+	if delivery.ReplayOf != "" {
+		replayOf = &delivery.ReplayOf
+	}
+
+	if _, err := r.pool.Exec(ctx, query,
+		delivery.Id,
+		delivery.SubscriptionId,
+		delivery.EventId,
+		delivery.EventType,
+		delivery.Payload,
+		delivery.NextAttemptAt,
+		replayOf,
+	); err != nil {
+		return fmt.Errorf("SQL: CreateDelivery: Exec(): %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) GetDelivery(ctx context.Context, subscriptionId string, deliveryId string) (*types.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `
+			  FROM webhook_deliveries
+			  WHERE subscription_id = $1 AND id = $2`
+
+	delivery := &types.WebhookDelivery{}
+	if err := scanWebhookDelivery(r.pool.QueryRow(ctx, query, subscriptionId, deliveryId), delivery); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`SQL: GetDelivery: Scan(): %w`, err)
+	}
+	return delivery, nil
+}
+
+// ListDeliveries returns a page of the deliveries matching the filter, newest first, and the number of matching deliveries.
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, filter types.WebhookDeliveryFilter) ([]types.WebhookDelivery, int, error) {
+	where := `subscription_id = $1 AND ($2 = '' OR status = $2)`
+	args := []any{filter.SubscriptionId, filter.Status}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT count(*) FROM webhook_deliveries WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf(`SQL: ListDeliveries: Scan(): %w`, err)
+	}
+
+	query := `SELECT ` + webhookDeliveryColumns + `
+			  FROM webhook_deliveries
+			  WHERE ` + where + `
+			  ORDER BY created_at DESC, event_id DESC
+			  LIMIT $3 OFFSET $4`
+
+	deliveries, err := r.listDeliveries(ctx, "ListDeliveries", query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// ClaimDeliveries returns up to limit pending deliveries that are due at now and postpones
+// them until leaseUntil, so that concurrent dispatchers do not send them twice. Deliveries
+// of inactive subscriptions wait until the subscription is activated again.
+func (r *WebhookRepo) ClaimDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]types.WebhookDelivery, error) {
+	query := `UPDATE webhook_deliveries
+			  SET next_attempt_at = $2
+			  WHERE id IN (
+			  	SELECT d.id FROM webhook_deliveries d
+			  	JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			  	WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND s.active
+			  	ORDER BY d.next_attempt_at
+			  	LIMIT $3
+			  	FOR UPDATE OF d SKIP LOCKED
+			  )
+			  RETURNING ` + webhookDeliveryColumns
+
+	return r.listDeliveries(ctx, "ClaimDeliveries", query, now, leaseUntil, limit)
+}
+
+// SaveAttempt stores the outcome of an attempt to send the delivery and logs the attempt.
+func (r *WebhookRepo) SaveAttempt(ctx context.Context, delivery types.WebhookDelivery, attempt types.WebhookAttempt) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf(`SQL: SaveAttempt: Begin(): %w`, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	updateQuery := `UPDATE webhook_deliveries
+					SET status = $2,
+					    attempts = $3,
+					    next_attempt_at = $4,
+					    last_attempt_at = $5,
+					    response_status = $6,
+					    error = $7
+					WHERE id = $1`
+	if _, err = tx.Exec(ctx, updateQuery,
+		delivery.Id,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastAttemptAt,
+		delivery.ResponseStatus,
+		delivery.Error,
+	); err != nil {
+		return fmt.Errorf("SQL: SaveAttempt: Exec(): %w", err)
+	}
+
+	insertQuery := `INSERT INTO webhook_attempts (delivery_id, attempt, status_code, error, duration_ms, created_at)
+					VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err = tx.Exec(ctx, insertQuery,
+		attempt.DeliveryId,
+		attempt.Attempt,
+		attempt.StatusCode,
+		attempt.Error,
+		attempt.Duration.Milliseconds(),
+		attempt.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("SQL: SaveAttempt: Exec(): %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf(`SQL: SaveAttempt: Commit(): %w`, err)
+	}
+
+	return nil
+}
+
+// ListAttempts returns the attempts to send the delivery in the order they were made.
+func (r *WebhookRepo) ListAttempts(ctx context.Context, deliveryId string) ([]types.WebhookAttempt, error) {
+	query := `SELECT delivery_id, attempt, status_code, error, duration_ms, created_at
+			  FROM webhook_attempts
+			  WHERE delivery_id = $1
+			  ORDER BY attempt, id`
+
+	rows, err := r.pool.Query(ctx, query, deliveryId)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: ListAttempts: Query(): %w`, err)
+	}
+	defer rows.Close()
+
+	attempts := make([]types.WebhookAttempt, 0)
+	for rows.Next() {
+		var durationMs int64
+		attempt := types.WebhookAttempt{}
+		if err = rows.Scan(
+			&attempt.DeliveryId,
+			&attempt.Attempt,
+			&attempt.StatusCode,
+			&attempt.Error,
+			&durationMs,
+			&attempt.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf(`SQL: ListAttempts: Scan(): %w`, err)
+		}
+		attempt.Duration = time.Duration(durationMs) * time.Millisecond
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: ListAttempts: Rows(): %w`, err)
+	}
+
+	return attempts, nil
+}
+
+// DeleteDeliveries removes finished deliveries created before the given time together with their attempts.
+func (r *WebhookRepo) DeleteDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("SQL: DeleteDeliveries: Exec(): %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *WebhookRepo) listSubscriptions(ctx context.Context, name string, query string, args ...any) ([]types.WebhookSubscription, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Query(): %w`, name, err)
+	}
+	defer rows.Close()
+
+	subscriptions := make([]types.WebhookSubscription, 0)
+	for rows.Next() {
+		subscription := types.WebhookSubscription{}
+		if err = scanWebhookSubscription(rows, &subscription); err != nil {
+			return nil, fmt.Errorf(`SQL: %s: Scan(): %w`, name, err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Rows(): %w`, name, err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepo) listDeliveries(ctx context.Context, name string, query string, args ...any) ([]types.WebhookDelivery, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Query(): %w`, name, err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]types.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := types.WebhookDelivery{}
+		if err = scanWebhookDelivery(rows, &delivery); err != nil {
+			return nil, fmt.Errorf(`SQL: %s: Scan(): %w`, name, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(`SQL: %s: Rows(): %w`, name, err)
+	}
+
+	return deliveries, nil
+}
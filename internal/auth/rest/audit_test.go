@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"context"
+	"medods-test/internal/auth/types"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAudit keeps the types of the recorded events and marks the request as audited the way
+// service.Audit does.
+type fakeAudit struct {
+	AuditService
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (a *fakeAudit) Record(ctx context.Context, eventType string, _ string, _ map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, eventType)
+	types.MarkAudited(ctx)
+}
+
+// fakeUserAdmin records a specific event only for status changes.
+type fakeUserAdmin struct {
+	UserAdminService
+
+	audit *fakeAudit
+}
+
+func (a *fakeUserAdmin) SetStatus(ctx context.Context, userId string, _ string, _ string) error {
+	a.audit.Record(ctx, types.AuditUserStatusChanged, userId, nil)
+	return nil
+}
+
+func (a *fakeUserAdmin) RevokeSession(context.Context, string, string) error {
+	return nil
+}
+
+func TestAuditAdminMiddleware(t *testing.T) {
+	const userId = "8c8f8a3e-5d43-4a71-9c55-0a1b0c9d2e11"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantEvents []string
+	}{
+		{
+			name:       "action recorded by the service",
+			method:     http.MethodPut,
+			path:       "/admin/users/" + userId + "/status",
+			body:       `{"status":"blocked","reason":"fraud"}`,
+			wantEvents: []string{types.AuditUserStatusChanged},
+		},
+		{
+			name:       "action without a specific event",
+			method:     http.MethodDelete,
+			path:       "/admin/users/" + userId + "/sessions/5b0c6f0e-2a8d-4f4e-8d8e-3f1a2b3c4d5e",
+			wantEvents: []string{types.AuditAdminAction},
+		},
+		{
+			name:   "read",
+			method: http.MethodGet,
+			path:   "/admin/clients",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &fakeAudit{}
+			handler := New(&UseCase{
+				User: &fakeUsers{identities: map[string]types.Identity{
+					"token": {SessionId: "s1", UserId: userId, Roles: []string{"admin"}},
+				}},
+				RBAC:   fakeRBAC{},
+				Client: fakeClients{},
+				Admin:  &fakeUserAdmin{audit: audit},
+				Audit:  audit,
+			}).Handler()
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code >= http.StatusBadRequest {
+				t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body)
+			}
+			if !slices.Equal(audit.events, tt.wantEvents) {
+				t.Errorf("recorded events = %v, want %v", audit.events, tt.wantEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+func (h *Handler) DeleteClientHandler(c *gin.Context) {
+	if err := h.auth.Client.Delete(c.Request.Context(), c.Param("client_id")); err != nil {
+		logger.Errorf("failed to delete client: %s", err.Error())
+		if errors.Is(err, service.ErrClientNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) RevokeClientSecretHandler(c *gin.Context) {
+	if err := h.auth.Client.RevokeSecret(c.Request.Context(), c.Param("client_id"), c.Param("secret_id")); err != nil {
+		logger.Errorf("failed to revoke client secret: %s", err.Error())
+		if errors.Is(err, service.ErrClientSecretNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+func (h *Handler) DeleteRoleHandler(c *gin.Context) {
+	if err := h.auth.RBAC.DeleteRole(c.Request.Context(), c.Param("role_id")); err != nil {
+		logger.Errorf("failed to delete role: %s", err.Error())
+		if errors.Is(err, service.ErrRoleNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) UnassignRoleHandler(c *gin.Context) {
+	if err := h.auth.RBAC.Unassign(c.Request.Context(), c.Param("user_id"), c.Param("role_id")); err != nil {
+		logger.Errorf("failed to unassign role: %s", err.Error())
+		if errors.Is(err, service.ErrRoleAssignmentMissing) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
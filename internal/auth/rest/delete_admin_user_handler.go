@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type revokedSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+func (h *Handler) DeleteUserHandler(c *gin.Context) {
+	if err := h.auth.Admin.Delete(c.Request.Context(), c.Param("user_id"), c.Query("reason")); err != nil {
+		logger.Errorf("failed to delete user: %s", err.Error())
+		if errors.Is(err, service.ErrUserNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) RevokeUserSessionsHandler(c *gin.Context) {
+	revoked, err := h.auth.Admin.RevokeSessions(c.Request.Context(), c.Param("user_id"))
+	if err != nil {
+		logger.Errorf("failed to revoke sessions: %s", err.Error())
+		if errors.Is(err, service.ErrUserNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, revokedSessionsResponse{Revoked: revoked})
+}
+
+func (h *Handler) RevokeUserSessionHandler(c *gin.Context) {
+	if err := h.auth.Admin.RevokeSession(c.Request.Context(), c.Param("user_id"), c.Param("session_id")); err != nil {
+		logger.Errorf("failed to revoke session: %s", err.Error())
+		if errors.Is(err, service.ErrSessionNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,24 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+func (h *Handler) DeleteWebhookHandler(c *gin.Context) {
+	if err := h.auth.Webhooks.Delete(c.Request.Context(), c.Param("webhook_id")); err != nil {
+		logger.Errorf("failed to delete webhook: %s", err.Error())
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,27 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+// ForgetDeviceHandler removes a device of the caller, so that it is no longer trusted and
+// its next sign-in is treated as a sign-in from a new device.
+func (h *Handler) ForgetDeviceHandler(c *gin.Context) {
+	identity := identityFrom(c)
+	if err := h.auth.User.ForgetDevice(c.Request.Context(), identity, c.Param("device_id")); err != nil {
+		logger.Errorf("failed to forget device (user: %s): %s", identity.UserId, err.Error())
+		if errors.Is(err, service.ErrKnownDeviceNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,24 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+func (h *Handler) RemoveMemberHandler(c *gin.Context) {
+	if err := h.auth.Org.RemoveMember(c.Request.Context(), organizationParam(c), c.Param("user_id")); err != nil {
+		logger.Errorf("failed to remove member: %s", err.Error())
+		if errors.Is(err, service.ErrNotOrganizationMember) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
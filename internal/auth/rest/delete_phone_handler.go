@@ -0,0 +1,26 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+// RemovePhoneHandler removes the phone of the caller, sign-in codes are emailed again.
+func (h *Handler) RemovePhoneHandler(c *gin.Context) {
+	identity := identityFrom(c)
+	if err := h.auth.User.RemovePhone(c.Request.Context(), identity); err != nil {
+		logger.Errorf("failed to remove phone (user: %s): %s", identity.UserId, err.Error())
+		if errors.Is(err, service.ErrPhoneNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
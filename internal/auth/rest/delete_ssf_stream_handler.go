@@ -0,0 +1,26 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+func (h *Handler) DeleteStreamHandler(c *gin.Context) {
+	identity := identityFrom(c)
+
+	if err := h.auth.Signals.DeleteStream(c.Request.Context(), identity.ClientId, c.Query("stream_id")); err != nil {
+		logger.Errorf("failed to delete stream (client: %s): %s", identity.ClientId, err.Error())
+		if errors.Is(err, service.ErrSignalStreamNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,29 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+// ExportAccountHandler returns everything stored about the caller as a JSON file.
+func (h *Handler) ExportAccountHandler(c *gin.Context) {
+	identity := identityFrom(c)
+	export, err := h.auth.Privacy.Export(c.Request.Context(), identity.UserId)
+	if err != nil {
+		logger.Errorf("failed to export account data (user: %s): %s", identity.UserId, err.Error())
+		if errors.Is(err, service.ErrUserNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.json"`, identity.UserId))
+	c.JSON(http.StatusOK, export)
+}
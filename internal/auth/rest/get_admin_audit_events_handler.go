@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type auditEventResponse struct {
+	Id        int64             `json:"id"`
+	Type      string            `json:"type"`
+	ActorId   string            `json:"actor_id,omitempty"`
+	UserId    string            `json:"user_id,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Metadata  map[string]string `json:"metadata"`
+	CreatedAt time.Time         `json:"created_at"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
+
+type auditEventsPageResponse struct {
+	Events []auditEventResponse `json:"events"`
+	Total  int                  `json:"total"`
+}
+
+type auditVerificationResponse struct {
+	Checked  int64 `json:"checked"`
+	Valid    bool  `json:"valid"`
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
+
+type listAuditEventsInput struct {
+	Type    string    `form:"type" binding:"max=100"`
+	UserId  string    `form:"user_id" binding:"max=100"`
+	ActorId string    `form:"actor_id" binding:"max=100"`
+	From    time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To      time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit   int       `form:"limit" binding:"min=0,max=500"`
+	Offset  int       `form:"offset" binding:"min=0"`
+}
+
+func (h *Handler) ListAuditEventsHandler(c *gin.Context) {
+	var input listAuditEventsInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		logger.Errorf("failed to decode query: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid query")
+		return
+	}
+
+	filter := types.AuditFilter{
+		Type:    input.Type,
+		UserId:  input.UserId,
+		ActorId: input.ActorId,
+		From:    input.From,
+		To:      input.To,
+		Limit:   input.Limit,
+		Offset:  input.Offset,
+	}
+	events, total, err := h.auth.Audit.List(c.Request.Context(), filter)
+	if err != nil {
+		logger.Errorf("failed to list audit events: %s", err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := auditEventsPageResponse{
+		Events: make([]auditEventResponse, 0, len(events)),
+		Total:  total,
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, auditEventResponse{
+			Id:        event.Id,
+			Type:      event.Type,
+			ActorId:   event.ActorId,
+			UserId:    event.UserId,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			Metadata:  event.Metadata,
+			CreatedAt: event.CreatedAt,
+			PrevHash:  event.PrevHash,
+			Hash:      event.Hash,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// VerifyAuditLogHandler checks the hash chain of the whole audit log.
+func (h *Handler) VerifyAuditLogHandler(c *gin.Context) {
+	verification, err := h.auth.Audit.Verify(c.Request.Context())
+	if err != nil {
+		logger.Errorf("failed to verify audit log: %s", err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, auditVerificationResponse{
+		Checked:  verification.Checked,
+		Valid:    verification.Valid,
+		BrokenAt: verification.BrokenAt,
+	})
+}
@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type clientResponse struct {
+	ClientId          string    `json:"client_id"`
+	ClientSecret      string    `json:"client_secret,omitempty"`
+	Name              string    `json:"name"`
+	Type              string    `json:"client_type"`
+	RedirectURIs      []string  `json:"redirect_uris"`
+	Scopes            []string  `json:"scopes"`
+	GrantTypes        []string  `json:"grant_types"`
+	ExchangeAudiences []string  `json:"exchange_audiences"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func newClientResponse(client types.OAuthClient) clientResponse {
+	return clientResponse{
+		ClientId:          client.ClientId,
+		Name:              client.Name,
+		Type:              client.Type,
+		RedirectURIs:      client.RedirectURIs,
+		Scopes:            client.Scopes,
+		GrantTypes:        client.GrantTypes,
+		ExchangeAudiences: client.ExchangeAudiences,
+		CreatedAt:         client.CreatedAt,
+		UpdatedAt:         client.UpdatedAt,
+	}
+}
+
+func (h *Handler) ListClientsHandler(c *gin.Context) {
+	clients, err := h.auth.Client.List(c.Request.Context())
+	if err != nil {
+		logger.Errorf("failed to list clients: %s", err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := make([]clientResponse, 0, len(clients))
+	for _, client := range clients {
+		resp = append(resp, newClientResponse(client))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) GetClientHandler(c *gin.Context) {
+	client, err := h.auth.Client.Get(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		logger.Errorf("failed to get client: %s", err.Error())
+		if errors.Is(err, service.ErrClientNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newClientResponse(*client))
+}
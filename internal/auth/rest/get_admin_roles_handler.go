@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type roleResponse struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newRoleResponse(role types.Role) roleResponse {
+	return roleResponse{
+		Id:          role.Id,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.Permissions,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+type permissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (h *Handler) ListPermissionsHandler(c *gin.Context) {
+	permissions, err := h.auth.RBAC.Permissions(c.Request.Context())
+	if err != nil {
+		logger.Errorf("failed to list permissions: %s", err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := make([]permissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		resp = append(resp, permissionResponse{
+			Name:        permission.Name,
+			Description: permission.Description,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) ListRolesHandler(c *gin.Context) {
+	roles, err := h.auth.RBAC.ListRoles(c.Request.Context())
+	if err != nil {
+		logger.Errorf("failed to list roles: %s", err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := make([]roleResponse, 0, len(roles))
+	for _, role := range roles {
+		resp = append(resp, newRoleResponse(role))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) GetRoleHandler(c *gin.Context) {
+	role, err := h.auth.RBAC.GetRole(c.Request.Context(), c.Param("role_id"))
+	if err != nil {
+		logger.Errorf("failed to get role: %s", err.Error())
+		if errors.Is(err, service.ErrRoleNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleResponse(*role))
+}
@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type userRoleResponse struct {
+	RoleId    string    `json:"role_id"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (h *Handler) ListUserRolesHandler(c *gin.Context) {
+	roles, err := h.auth.RBAC.UserRoles(c.Request.Context(), c.Param("user_id"))
+	if err != nil {
+		logger.Errorf("failed to list user roles: %s", err.Error())
+		if errors.Is(err, service.ErrUserNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := make([]userRoleResponse, 0, len(roles))
+	for _, role := range roles {
+		resp = append(resp, userRoleResponse{
+			RoleId:    role.RoleId,
+			Name:      role.RoleName,
+			Source:    role.Source,
+			CreatedAt: role.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
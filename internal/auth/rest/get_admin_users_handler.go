@@ -0,0 +1,132 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type userResponse struct {
+	Id                    string     `json:"id"`
+	Email                 string     `json:"email"`
+	OrganizationId        string     `json:"organization_id,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	Status                string     `json:"status"`
+	StatusReason          string     `json:"status_reason,omitempty"`
+	StatusChangedAt       *time.Time `json:"status_changed_at"`
+	PurgeAt               *time.Time `json:"purge_at,omitempty"`
+}
+
+func newUserResponse(user types.User) userResponse {
+	return userResponse{
+		Id:                    user.UserUUID,
+		Email:                 user.Email,
+		OrganizationId:        user.OrganizationId,
+		CreatedAt:             user.CreatedAt,
+		PasswordResetRequired: user.PasswordResetRequired,
+		Status:                user.Status,
+		StatusReason:          user.StatusReason,
+		StatusChangedAt:       user.StatusChangedAt,
+		PurgeAt:               user.PurgeAt,
+	}
+}
+
+type sessionResponse struct {
+	Id             string    `json:"id"`
+	IP             string    `json:"ip"`
+	Country        string    `json:"country,omitempty"`
+	City           string    `json:"city,omitempty"`
+	ASN            int64     `json:"asn,omitempty"`
+	ClientId       string    `json:"client_id,omitempty"`
+	Scope          string    `json:"scope,omitempty"`
+	OrganizationId string    `json:"organization_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type userDetailsResponse struct {
+	userResponse
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+type usersPageResponse struct {
+	Users []userResponse `json:"users"`
+	Total int            `json:"total"`
+}
+
+type listUsersInput struct {
+	Query  string `form:"query" binding:"max=100"`
+	Limit  int    `form:"limit" binding:"min=0,max=500"`
+	Offset int    `form:"offset" binding:"min=0"`
+}
+
+func (h *Handler) ListUsersHandler(c *gin.Context) {
+	var input listUsersInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		logger.Errorf("failed to decode query: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid query")
+		return
+	}
+
+	filter := types.UserFilter{
+		Query:  input.Query,
+		Limit:  input.Limit,
+		Offset: input.Offset,
+	}
+	users, total, err := h.auth.Admin.List(c.Request.Context(), filter)
+	if err != nil {
+		logger.Errorf("failed to list users: %s", err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := usersPageResponse{
+		Users: make([]userResponse, 0, len(users)),
+		Total: total,
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, newUserResponse(user))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) GetUserHandler(c *gin.Context) {
+	user, sessions, err := h.auth.Admin.Get(c.Request.Context(), c.Param("user_id"))
+	if err != nil {
+		logger.Errorf("failed to get user: %s", err.Error())
+		if errors.Is(err, service.ErrUserNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := userDetailsResponse{
+		userResponse: newUserResponse(*user),
+		Sessions:     make([]sessionResponse, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionResponse{
+			Id:             session.SessionId,
+			IP:             session.IP,
+			Country:        session.Country,
+			City:           session.City,
+			ASN:            session.ASN,
+			ClientId:       session.ClientId,
+			Scope:          session.Scope,
+			OrganizationId: session.OrganizationId,
+			CreatedAt:      session.CreatedAt,
+			ExpiresAt:      session.ExpiresAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type webhookDeliveryResponse struct {
+	Id             string          `json:"id"`
+	WebhookId      string          `json:"webhook_id"`
+	EventId        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	ReplayOf       string          `json:"replay_of,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+
+	AttemptLog []webhookAttemptResponse `json:"attempt_log,omitempty"`
+}
+
+type webhookAttemptResponse struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type webhookDeliveriesPageResponse struct {
+	Deliveries []webhookDeliveryResponse `json:"deliveries"`
+	Total      int                       `json:"total"`
+}
+
+type listWebhookDeliveriesInput struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded failed"`
+	Limit  int    `form:"limit" binding:"min=0,max=500"`
+	Offset int    `form:"offset" binding:"min=0"`
+}
+
+func newWebhookDeliveryResponse(delivery types.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		Id:             delivery.Id,
+		WebhookId:      delivery.SubscriptionId,
+		EventId:        delivery.EventId,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		ResponseStatus: delivery.ResponseStatus,
+		Error:          delivery.Error,
+		ReplayOf:       delivery.ReplayOf,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == types.WebhookDeliveryPending {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return resp
+}
+
+func (h *Handler) ListWebhookDeliveriesHandler(c *gin.Context) {
+	var input listWebhookDeliveriesInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		logger.Errorf("failed to decode query: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid query")
+		return
+	}
+
+	filter := types.WebhookDeliveryFilter{
+		SubscriptionId: c.Param("webhook_id"),
+		Status:         input.Status,
+		Limit:          input.Limit,
+		Offset:         input.Offset,
+	}
+	deliveries, total, err := h.auth.Webhooks.Deliveries(c.Request.Context(), filter)
+	if err != nil {
+		logger.Errorf("failed to list webhook deliveries: %s", err.Error())
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := webhookDeliveriesPageResponse{
+		Deliveries: make([]webhookDeliveryResponse, 0, len(deliveries)),
+		Total:      total,
+	}
+	for _, delivery := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, newWebhookDeliveryResponse(delivery))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetWebhookDeliveryHandler returns a delivery with the log of its attempts.
+func (h *Handler) GetWebhookDeliveryHandler(c *gin.Context) {
+	delivery, attempts, err := h.auth.Webhooks.Delivery(c.Request.Context(), c.Param("webhook_id"), c.Param("delivery_id"))
+	if err != nil {
+		logger.Errorf("failed to get webhook delivery: %s", err.Error())
+		if errors.Is(err, service.ErrWebhookDeliveryNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := newWebhookDeliveryResponse(*delivery)
+	resp.AttemptLog = make([]webhookAttemptResponse, 0, len(attempts))
+	for _, attempt := range attempts {
+		resp.AttemptLog = append(resp.AttemptLog, webhookAttemptResponse{
+			Attempt:    attempt.Attempt,
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMs: attempt.Duration.Milliseconds(),
+			CreatedAt:  attempt.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type webhookResponse struct {
+	Id          string    `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Description string    `json:"description"`
+	EventTypes  []string  `json:"event_types"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newWebhookResponse(subscription types.WebhookSubscription) webhookResponse {
+	return webhookResponse{
+		Id:          subscription.Id,
+		URL:         subscription.URL,
+		Description: subscription.Description,
+		EventTypes:  subscription.EventTypes,
+		Active:      subscription.Active,
+		CreatedAt:   subscription.CreatedAt,
+		UpdatedAt:   subscription.UpdatedAt,
+	}
+}
+
+func (h *Handler) ListWebhooksHandler(c *gin.Context) {
+	subscriptions, err := h.auth.Webhooks.List(c.Request.Context())
+	if err != nil {
+		logger.Errorf("failed to list webhooks: %s", err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := make([]webhookResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		resp = append(resp, newWebhookResponse(subscription))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) GetWebhookHandler(c *gin.Context) {
+	subscription, err := h.auth.Webhooks.Get(c.Request.Context(), c.Param("webhook_id"))
+	if err != nil {
+		logger.Errorf("failed to get webhook: %s", err.Error())
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newWebhookResponse(*subscription))
+}
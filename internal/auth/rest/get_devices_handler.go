@@ -0,0 +1,57 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type knownDeviceResponse struct {
+	Id           string     `json:"id"`
+	Name         string     `json:"name"`
+	IP           string     `json:"ip"`
+	Location     string     `json:"location,omitempty"`
+	Trusted      bool       `json:"trusted"`
+	TrustedUntil *time.Time `json:"trusted_until,omitempty"`
+	Current      bool       `json:"current"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastSeenAt   time.Time  `json:"last_seen_at"`
+}
+
+type knownDevicesResponse struct {
+	Devices []knownDeviceResponse `json:"devices"`
+}
+
+// DevicesHandler returns the devices the caller has signed in from.
+func (h *Handler) DevicesHandler(c *gin.Context) {
+	identity := identityFrom(c)
+	devices, err := h.auth.User.Devices(c.Request.Context(), identity)
+	if err != nil {
+		logger.Errorf("failed to list devices (user: %s): %s", identity.UserId, err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := knownDevicesResponse{
+		Devices: make([]knownDeviceResponse, 0, len(devices)),
+	}
+	for _, device := range devices {
+		item := knownDeviceResponse{
+			Id:         device.Id,
+			Name:       device.Name,
+			IP:         device.IP,
+			Location:   device.Location,
+			Trusted:    device.IsTrusted(),
+			Current:    device.Current,
+			CreatedAt:  device.CreatedAt,
+			LastSeenAt: device.LastSeenAt,
+		}
+		if item.Trusted {
+			item.TrustedUntil = device.TrustedUntil
+		}
+		resp.Devices = append(resp.Devices, item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
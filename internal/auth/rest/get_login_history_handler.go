@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type loginAttemptResponse struct {
+	Method        string    `json:"method"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	IP            string    `json:"ip"`
+	Device        string    `json:"device"`
+	Location      string    `json:"location,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type loginHistoryResponse struct {
+	Attempts []loginAttemptResponse `json:"attempts"`
+}
+
+type loginHistoryInput struct {
+	Limit int `form:"limit" binding:"min=0,max=200"`
+}
+
+// LoginHistoryHandler returns recent sign-in attempts to the account of the caller.
+func (h *Handler) LoginHistoryHandler(c *gin.Context) {
+	var input loginHistoryInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		logger.Errorf("failed to decode query: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid query")
+		return
+	}
+
+	identity := identityFrom(c)
+	attempts, err := h.auth.User.LoginHistory(c.Request.Context(), identity, input.Limit)
+	if err != nil {
+		logger.Errorf("failed to get login history (user: %s): %s", identity.UserId, err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := loginHistoryResponse{
+		Attempts: make([]loginAttemptResponse, 0, len(attempts)),
+	}
+	for _, attempt := range attempts {
+		resp.Attempts = append(resp.Attempts, loginAttemptResponse{
+			Method:        attempt.Method,
+			Success:       attempt.Success,
+			FailureReason: attempt.FailureReason,
+			IP:            attempt.IP,
+			Device:        attempt.Device,
+			Location:      attempt.Location,
+			CreatedAt:     attempt.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,118 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"medods-test/internal/auth/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeLoginHistoryUsers returns attempts as the login history and remembers the requested limit.
+type fakeLoginHistoryUsers struct {
+	*fakeUsers
+
+	attempts []types.LoginAttempt
+	limit    int
+}
+
+func (u *fakeLoginHistoryUsers) LoginHistory(_ context.Context, _ types.Identity, limit int) ([]types.LoginAttempt, error) {
+	u.limit = limit
+	return u.attempts, nil
+}
+
+func TestLoginHistoryHandler(t *testing.T) {
+	const userId = "8c8f8a3e-5d43-4a71-9c55-0a1b0c9d2e11"
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	attempts := []types.LoginAttempt{
+		{UserId: userId, Method: types.LoginMethodPassword, Success: true, IP: "203.0.113.1", UserAgent: "curl/8.0", Device: "curl", Location: "Moscow, Russia", CreatedAt: createdAt},
+		{UserId: userId, Method: types.LoginMethodPassword, FailureReason: "invalid_credentials", IP: "198.51.100.2", Device: "Unknown device", CreatedAt: createdAt.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantLimit  int
+	}{
+		{name: "default limit", wantStatus: http.StatusOK},
+		{name: "limit", query: "?limit=20", wantStatus: http.StatusOK, wantLimit: 20},
+		{name: "limit above the maximum", query: "?limit=201", wantStatus: http.StatusBadRequest},
+		{name: "negative limit", query: "?limit=-1", wantStatus: http.StatusBadRequest},
+		{name: "invalid limit", query: "?limit=ten", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeLoginHistoryUsers{
+				fakeUsers: &fakeUsers{identities: map[string]types.Identity{"token": {SessionId: "s1", UserId: userId}}},
+				attempts:  attempts,
+				limit:     -1,
+			}
+			handler := New(&UseCase{User: users}).Handler()
+
+			req := httptest.NewRequest(http.MethodGet, "/auth/login-history"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer token")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if users.limit != -1 {
+					t.Errorf("LoginHistory() called for an invalid query")
+				}
+				return
+			}
+			if users.limit != tt.wantLimit {
+				t.Errorf("LoginHistory() limit = %d, want %d", users.limit, tt.wantLimit)
+			}
+
+			var resp struct {
+				Attempts []map[string]any `json:"attempts"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode body %s: %v", rec.Body, err)
+			}
+			if len(resp.Attempts) != 2 {
+				t.Fatalf("attempts = %v, want 2", resp.Attempts)
+			}
+
+			success, failure := resp.Attempts[0], resp.Attempts[1]
+			if success["success"] != true || success["device"] != "curl" || success["location"] != "Moscow, Russia" || success["created_at"] != "2024-05-01T12:00:00Z" {
+				t.Errorf("successful attempt = %v", success)
+			}
+			if _, ok := success["failure_reason"]; ok {
+				t.Errorf("successful attempt has a failure reason: %v", success)
+			}
+			if failure["success"] != false || failure["failure_reason"] != "invalid_credentials" || failure["ip"] != "198.51.100.2" {
+				t.Errorf("failed attempt = %v", failure)
+			}
+			if _, ok := failure["location"]; ok {
+				t.Errorf("failed attempt of an unknown location has one: %v", failure)
+			}
+			// The user agent itself is not shown.
+			if _, ok := success["user_agent"]; ok {
+				t.Errorf("attempt exposes the user agent: %v", success)
+			}
+		})
+	}
+
+	t.Run("empty history", func(t *testing.T) {
+		users := &fakeLoginHistoryUsers{fakeUsers: &fakeUsers{identities: map[string]types.Identity{"token": {SessionId: "s1", UserId: userId}}}}
+		handler := New(&UseCase{User: users}).Handler()
+
+		req := httptest.NewRequest(http.MethodGet, "/auth/login-history", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || rec.Body.String() != `{"attempts":[]}` {
+			t.Errorf("response = %d %s, want an empty list", rec.Code, rec.Body)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type notificationPreferenceResponse struct {
+	Event    string   `json:"event"`
+	Channels []string `json:"channels"`
+	Delivery string   `json:"delivery"`
+	Critical bool     `json:"critical"`
+}
+
+type notificationPreferencesResponse struct {
+	Preferences []notificationPreferenceResponse `json:"preferences"`
+	TimeZone    string                           `json:"time_zone"`
+}
+
+// NotificationPreferencesHandler returns how the caller is notified about security events.
+func (h *Handler) NotificationPreferencesHandler(c *gin.Context) {
+	identity := identityFrom(c)
+	preferences, err := h.auth.Notify.Preferences(c.Request.Context(), identity)
+	if err != nil {
+		logger.Errorf("failed to get notification preferences (user: %s): %s", identity.UserId, err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	timeZone, err := h.auth.Notify.TimeZone(c.Request.Context(), identity)
+	if err != nil {
+		logger.Errorf("failed to get time zone (user: %s): %s", identity.UserId, err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newNotificationPreferencesResponse(preferences, timeZone))
+}
+
+func newNotificationPreferencesResponse(preferences []types.NotificationPreference, timeZone string) notificationPreferencesResponse {
+	resp := notificationPreferencesResponse{
+		Preferences: make([]notificationPreferenceResponse, 0, len(preferences)),
+		TimeZone:    timeZone,
+	}
+	for _, preference := range preferences {
+		resp.Preferences = append(resp.Preferences, notificationPreferenceResponse{
+			Event:    preference.Event,
+			Channels: preference.Channels,
+			Delivery: preference.Delivery,
+			Critical: preference.Critical,
+		})
+	}
+	return resp
+}
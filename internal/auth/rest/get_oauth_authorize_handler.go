@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"net/http"
+	"net/url"
+)
+
+type authorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientId            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"`
+}
+
+func (r authorizeRequest) toTypes() types.AuthorizeRequest {
+	return types.AuthorizeRequest{
+		ResponseType:        r.ResponseType,
+		ClientId:            r.ClientId,
+		RedirectURI:         r.RedirectURI,
+		Scope:               r.Scope,
+		State:               r.State,
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+		Nonce:               r.Nonce,
+	}
+}
+
+type authorizePage struct {
+	Fatal      bool
+	Error      string
+	ClientName string
+	Scopes     []string
+	Request    authorizeRequest
+}
+
+func (h *Handler) AuthorizeHandler(c *gin.Context) {
+	var input authorizeRequest
+	if err := c.ShouldBindQuery(&input); err != nil {
+		logger.Errorf("failed to decode authorize request: %s", err.Error())
+		c.HTML(http.StatusBadRequest, "authorize.html", authorizePage{Fatal: true, Error: "invalid request"})
+		return
+	}
+
+	req := input.toTypes()
+	client, err := h.auth.OAuth.CheckClient(c.Request.Context(), req)
+	if err != nil {
+		renderAuthorizeFatal(c, err)
+		return
+	}
+
+	if err = h.auth.OAuth.CheckAuthorizeRequest(client, req); err != nil {
+		redirectAuthorizeError(c, req, err)
+		return
+	}
+
+	c.HTML(http.StatusOK, "authorize.html", authorizePage{
+		ClientName: client.Name,
+		Scopes:     oauth.ParseScope(req.Scope),
+		Request:    input,
+	})
+}
+
+// renderAuthorizeFatal shows errors that must not be redirected to an unverified redirect uri.
+func renderAuthorizeFatal(c *gin.Context, err error) {
+	logger.Errorf("failed to check oauth client: %s", err.Error())
+	switch {
+	case errors.Is(err, service.ErrInvalidClient):
+		c.HTML(http.StatusBadRequest, "authorize.html", authorizePage{Fatal: true, Error: "unknown client"})
+	case errors.Is(err, service.ErrInvalidRedirectURI):
+		c.HTML(http.StatusBadRequest, "authorize.html", authorizePage{Fatal: true, Error: "redirect uri is not registered"})
+	default:
+		c.HTML(http.StatusInternalServerError, "authorize.html", authorizePage{Fatal: true, Error: "Something went wrong. Try again later!"})
+	}
+}
+
+func redirectAuthorizeError(c *gin.Context, req types.AuthorizeRequest, err error) {
+	code := oauth.ErrCodeServerError
+	switch {
+	case errors.Is(err, service.ErrUnsupportedResponseType):
+		code = oauth.ErrCodeUnsupportedResponseType
+	case errors.Is(err, service.ErrInvalidCodeChallenge):
+		code = oauth.ErrCodeInvalidRequest
+	case errors.Is(err, service.ErrInvalidScope):
+		code = oauth.ErrCodeInvalidScope
+	case errors.Is(err, errAccessDenied):
+		code = oauth.ErrCodeAccessDenied
+	}
+
+	params := url.Values{
+		"error": {code},
+		"state": {req.State},
+	}
+	if code != oauth.ErrCodeServerError {
+		params.Set("error_description", err.Error())
+	}
+
+	c.Redirect(http.StatusFound, oauth.AppendQuery(req.RedirectURI, params))
+}
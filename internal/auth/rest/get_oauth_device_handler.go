@@ -0,0 +1,91 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"net/http"
+)
+
+type devicePage struct {
+	Error      string
+	Done       string
+	UserCode   string
+	ClientName string
+	Scopes     []string
+}
+
+type deviceVerification struct {
+	UserCode string `form:"user_code"`
+	Email    string `form:"email"`
+	Password string `form:"password"`
+	Action   string `form:"action"`
+}
+
+// DeviceVerificationHandler shows the page where the user enters the code displayed by the device.
+func (h *Handler) DeviceVerificationHandler(c *gin.Context) {
+	userCode := c.Query("user_code")
+	if userCode == "" {
+		c.HTML(http.StatusOK, "device.html", devicePage{})
+		return
+	}
+
+	code, client, err := h.auth.OAuth.CheckUserCode(c.Request.Context(), userCode)
+	if err != nil {
+		logger.Errorf("failed to check user code: %s", err.Error())
+		c.HTML(deviceErrorStatus(err), "device.html", devicePage{Error: deviceErrorMessage(err), UserCode: userCode})
+		return
+	}
+
+	c.HTML(http.StatusOK, "device.html", devicePage{
+		UserCode:   code.UserCode,
+		ClientName: client.Name,
+		Scopes:     oauth.ParseScope(code.Scope),
+	})
+}
+
+func (h *Handler) DeviceConsentHandler(c *gin.Context) {
+	var input deviceVerification
+	if err := c.ShouldBind(&input); err != nil {
+		logger.Errorf("failed to decode device verification form: %s", err.Error())
+		c.HTML(http.StatusBadRequest, "device.html", devicePage{Error: "invalid request"})
+		return
+	}
+
+	approve := input.Action == "approve"
+	err := h.auth.OAuth.ResolveUserCode(c.Request.Context(), input.UserCode, types.UserDTO{
+		Email:    input.Email,
+		Password: input.Password,
+	}, approve)
+	if err != nil {
+		logger.Errorf("failed to resolve user code (email: %s): %s", input.Email, err.Error())
+		c.HTML(deviceErrorStatus(err), "device.html", devicePage{Error: deviceErrorMessage(err), UserCode: input.UserCode})
+		return
+	}
+
+	done := "Доступ запрещён. Можно закрыть эту страницу."
+	if approve {
+		done = "Устройство подключено. Вернитесь к устройству, чтобы продолжить."
+	}
+	c.HTML(http.StatusOK, "device.html", devicePage{Done: done})
+}
+
+func deviceErrorStatus(err error) int {
+	if errors.Is(err, service.ErrUserCodeNotFound) || isCredentialsError(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func deviceErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, service.ErrUserCodeNotFound):
+		return "Код не найден или устарел"
+	case isCredentialsError(err):
+		return credentialsErrorMessage(err)
+	}
+	return "Something went wrong. Try again later!"
+}
@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type organizationResponse struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newOrganizationResponse(org types.Organization) organizationResponse {
+	return organizationResponse{
+		Id:        org.Id,
+		Name:      org.Name,
+		Slug:      org.Slug,
+		CreatedAt: org.CreatedAt,
+	}
+}
+
+type membershipResponse struct {
+	OrganizationId   string    `json:"organization_id"`
+	OrganizationName string    `json:"organization_name"`
+	UserId           string    `json:"user_id"`
+	Email            string    `json:"email"`
+	Roles            []string  `json:"roles"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func newMembershipResponse(membership types.Membership) membershipResponse {
+	return membershipResponse{
+		OrganizationId:   membership.OrganizationId,
+		OrganizationName: membership.OrganizationName,
+		UserId:           membership.UserId,
+		Email:            membership.Email,
+		Roles:            membership.Roles,
+		CreatedAt:        membership.CreatedAt,
+	}
+}
+
+func newMembershipsResponse(memberships []types.Membership) []membershipResponse {
+	resp := make([]membershipResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		resp = append(resp, newMembershipResponse(membership))
+	}
+	return resp
+}
+
+func (h *Handler) ListOrganizationsHandler(c *gin.Context) {
+	orgs, err := h.auth.Org.List(c.Request.Context())
+	if err != nil {
+		logger.Errorf("failed to list organizations: %s", err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := make([]organizationResponse, 0, len(orgs))
+	for _, org := range orgs {
+		resp = append(resp, newOrganizationResponse(org))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) GetOrganizationHandler(c *gin.Context) {
+	org, err := h.auth.Org.Get(c.Request.Context(), c.Param("org_id"))
+	if err != nil {
+		logger.Errorf("failed to get organization: %s", err.Error())
+		if errors.Is(err, service.ErrOrganizationNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newOrganizationResponse(*org))
+}
+
+// UserOrganizationsHandler lists the organizations of the caller.
+func (h *Handler) UserOrganizationsHandler(c *gin.Context) {
+	identity := identityFrom(c)
+	if identity.IsClient() {
+		newResponse(c, http.StatusForbidden, "user token required")
+		return
+	}
+
+	memberships, err := h.auth.Org.UserMemberships(c.Request.Context(), identity.UserId)
+	if err != nil {
+		logger.Errorf("failed to list user organizations: %s", err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newMembershipsResponse(memberships))
+}
+
+func (h *Handler) ListMembersHandler(c *gin.Context) {
+	members, err := h.auth.Org.Members(c.Request.Context(), organizationParam(c))
+	if err != nil {
+		logger.Errorf("failed to list members: %s", err.Error())
+		if errors.Is(err, service.ErrOrganizationNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newMembershipsResponse(members))
+}
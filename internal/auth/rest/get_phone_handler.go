@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type phoneResponse struct {
+	Phone        string     `json:"phone"`
+	Verified     bool       `json:"verified"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+	UseForMFA    bool       `json:"use_for_mfa"`
+	PendingPhone string     `json:"pending_phone,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func newPhoneResponse(phone *types.UserPhone) phoneResponse {
+	return phoneResponse{
+		Phone:        phone.Phone,
+		Verified:     phone.IsVerified(),
+		VerifiedAt:   phone.VerifiedAt,
+		UseForMFA:    phone.UseForMFA,
+		PendingPhone: phone.PendingPhone,
+		UpdatedAt:    phone.UpdatedAt,
+	}
+}
+
+// PhoneHandler returns the phone of the caller.
+func (h *Handler) PhoneHandler(c *gin.Context) {
+	identity := identityFrom(c)
+	phone, err := h.auth.User.Phone(c.Request.Context(), identity)
+	if err != nil {
+		logger.Errorf("failed to get phone (user: %s): %s", identity.UserId, err.Error())
+		if errors.Is(err, service.ErrPhoneNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newPhoneResponse(phone))
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+// socialStateCookieName binds a social login to the browser that started it, so that a
+// callback url of someone else's login cannot sign the browser in to their account.
+const (
+	socialStateCookieName = "social_state"
+	socialStateCookiePath = "/auth/social"
+)
+
+type socialCallback struct {
+	State            string `form:"state" binding:"required"`
+	Code             string `form:"code"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+func (h *Handler) SocialProvidersHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.auth.Social.Providers()})
+}
+
+func (h *Handler) SocialLoginHandler(c *gin.Context) {
+	provider := c.Param("provider")
+
+	login, err := h.auth.Social.LoginURL(c.Request.Context(), provider)
+	if err != nil {
+		logger.Errorf("failed to start social login (provider: %s): %s", provider, err.Error())
+		if errors.Is(err, service.ErrProviderNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(socialStateCookieName, login.State, int(time.Until(login.ExpiresAt).Seconds()), socialStateCookiePath, "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, login.URL)
+}
+
+func (h *Handler) SocialCallbackHandler(c *gin.Context) {
+	provider := c.Param("provider")
+
+	var input socialCallback
+	if err := c.ShouldBindQuery(&input); err != nil {
+		logger.Errorf("failed to decode social callback: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid callback request")
+		return
+	}
+
+	state, err := c.Cookie(socialStateCookieName)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(socialStateCookieName, "", -1, socialStateCookiePath, "", c.Request.TLS != nil, true)
+	if err != nil || subtle.ConstantTimeCompare([]byte(state), []byte(input.State)) != 1 {
+		logger.Errorf("social login state does not match the browser (provider: %s)", provider)
+		newResponse(c, http.StatusBadRequest, service.ErrInvalidLoginState.Error())
+		return
+	}
+
+	if input.Error != "" {
+		logger.Errorf("social login rejected by provider %s: %s: %s", provider, input.Error, input.ErrorDescription)
+		newResponse(c, http.StatusUnauthorized, "login was cancelled or rejected by the provider")
+		return
+	}
+
+	ip := c.ClientIP()
+	tokens, err := h.auth.Social.Callback(c.Request.Context(), provider, input.State, input.Code, ip)
+	if err != nil {
+		logger.Errorf("failed to complete social login (ip: %s, provider: %s): %s", ip, provider, err.Error())
+		if newMFAChallengeResponse(c, err) || newLinkConfirmationResponse(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrProviderNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidLoginState),
+			errors.Is(err, service.ErrEmailNotVerified):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrExternalLoginFailed):
+			newResponse(c, http.StatusUnauthorized, err.Error())
+			return
+		case isUserStatusError(err), errors.Is(err, service.ErrPasswordResetRequired), errors.Is(err, service.ErrSignInBlocked):
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, responseToken{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
@@ -0,0 +1,42 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type streamStatusResponse struct {
+	Id     string `json:"stream_id"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func newStreamStatusResponse(stream types.SignalStream) streamStatusResponse {
+	return streamStatusResponse{
+		Id:     stream.Id,
+		Status: stream.Status,
+		Reason: stream.StatusReason,
+	}
+}
+
+func (h *Handler) GetStreamStatusHandler(c *gin.Context) {
+	identity := identityFrom(c)
+
+	stream, err := h.auth.Signals.Stream(c.Request.Context(), identity.ClientId, c.Query("stream_id"))
+	if err != nil {
+		logger.Errorf("failed to get stream status (client: %s): %s", identity.ClientId, err.Error())
+		if errors.Is(err, service.ErrSignalStreamNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newStreamStatusResponse(*stream))
+}
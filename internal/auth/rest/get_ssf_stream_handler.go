@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type streamDeliveryResponse struct {
+	Method      string `json:"method"`
+	EndpointURL string `json:"endpoint_url"`
+}
+
+// streamResponse is the stream configuration of SSF 1.0, section 8.1.1. The authorization
+// header of push streams is never returned.
+type streamResponse struct {
+	Id              string                 `json:"stream_id"`
+	Iss             string                 `json:"iss"`
+	Aud             string                 `json:"aud"`
+	Delivery        streamDeliveryResponse `json:"delivery"`
+	EventsSupported []string               `json:"events_supported"`
+	EventsRequested []string               `json:"events_requested"`
+	EventsDelivered []string               `json:"events_delivered"`
+	Description     string                 `json:"description,omitempty"`
+}
+
+func (h *Handler) newStreamResponse(stream types.SignalStream) streamResponse {
+	return streamResponse{
+		Id:  stream.Id,
+		Iss: h.auth.Signals.Configuration().Issuer,
+		Aud: stream.ClientId,
+		Delivery: streamDeliveryResponse{
+			Method:      stream.DeliveryMethod,
+			EndpointURL: stream.EndpointURL,
+		},
+		EventsSupported: h.auth.Signals.EventsSupported(),
+		EventsRequested: stream.EventsRequested,
+		EventsDelivered: h.auth.Signals.EventsDelivered(stream),
+		Description:     stream.Description,
+	}
+}
+
+// GetStreamHandler returns the stream of the calling receiver.
+func (h *Handler) GetStreamHandler(c *gin.Context) {
+	identity := identityFrom(c)
+
+	stream, err := h.auth.Signals.Stream(c.Request.Context(), identity.ClientId, c.Query("stream_id"))
+	if err != nil {
+		logger.Errorf("failed to get stream (client: %s): %s", identity.ClientId, err.Error())
+		if errors.Is(err, service.ErrSignalStreamNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, h.newStreamResponse(*stream))
+}
@@ -0,0 +1,31 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+func (h *Handler) UserInfoHandler(c *gin.Context) {
+	info, err := h.auth.OAuth.UserInfo(c.Request.Context(), identityFrom(c))
+	if err != nil {
+		logger.Errorf("failed to get user info: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrInsufficientScope):
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		case errors.Is(err, service.ErrUserNotFound):
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			newResponse(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
@@ -0,0 +1,18 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func (h *Handler) OpenIDConfigurationHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, h.auth.OAuth.Discovery())
+}
+
+func (h *Handler) JWKSHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, h.auth.Keys.JWKS())
+}
+
+func (h *Handler) SSFConfigurationHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, h.auth.Signals.Configuration())
+}
@@ -4,18 +4,165 @@ import (
 	"context"
 	"github.com/gin-gonic/gin"
 	"medods-test/internal/auth/types"
+	"medods-test/pkg/auth"
+	"medods-test/pkg/oauth"
+	"medods-test/pkg/ssf"
 	"net/http"
 )
 
 type UserService interface {
 	SignUp(ctx context.Context, input types.UserDTO) error
 	SingIn(ctx context.Context, input types.UserDTO, IP string) (types.Tokens, error)
-	CreateSession(ctx context.Context, userId string, IP string) (types.Tokens, error)
+	VerifySignIn(ctx context.Context, token string, code string, IP string, rememberDevice bool) (types.Tokens, error)
+	CreateSession(ctx context.Context, userId string, orgId string, IP string) (types.Tokens, error)
 	RefreshTokens(ctx context.Context, newClientIP string, accessToken, refreshToken string) (types.Tokens, error)
+	Identify(ctx context.Context, accessToken string) (*types.Identity, error)
+	ResetPassword(ctx context.Context, token string, password string) error
+	ChangePassword(ctx context.Context, identity types.Identity, currentPassword, newPassword string, revokeOtherSessions bool) error
+	RequestEmailChange(ctx context.Context, identity types.Identity, password, newEmail string) error
+	ConfirmEmailChange(ctx context.Context, identity types.Identity, token string) (string, error)
+	SignOut(ctx context.Context, identity types.Identity) error
+	LoginHistory(ctx context.Context, identity types.Identity, limit int) ([]types.LoginAttempt, error)
+	NewDeviceId() (string, error)
+	DeviceCookie(deviceId string) types.DeviceCookie
+	ParseDeviceCookie(value string) (string, bool)
+	Devices(ctx context.Context, identity types.Identity) ([]types.KnownDevice, error)
+	ForgetDevice(ctx context.Context, identity types.Identity, deviceId string) error
+	Phone(ctx context.Context, identity types.Identity) (*types.UserPhone, error)
+	SetPhone(ctx context.Context, identity types.Identity, password string, number string, useForMFA bool) (*types.SMSChallenge, error)
+	VerifyPhone(ctx context.Context, identity types.Identity, token string, code string) error
+	RemovePhone(ctx context.Context, identity types.Identity) error
+	RequestPasswordRecovery(ctx context.Context, email string, organizationId string) (types.SMSChallenge, error)
+	RecoverPassword(ctx context.Context, token string, code string, password string) error
+}
+
+type NotificationService interface {
+	Preferences(ctx context.Context, identity types.Identity) ([]types.NotificationPreference, error)
+	UpdatePreferences(ctx context.Context, identity types.Identity, preferences []types.NotificationPreference) ([]types.NotificationPreference, error)
+	TimeZone(ctx context.Context, identity types.Identity) (string, error)
+	SetTimeZone(ctx context.Context, identity types.Identity, timeZone string) error
+}
+
+type OAuthService interface {
+	CheckClient(ctx context.Context, req types.AuthorizeRequest) (*types.OAuthClient, error)
+	CheckAuthorizeRequest(client *types.OAuthClient, req types.AuthorizeRequest) error
+	Authorize(ctx context.Context, req types.AuthorizeRequest, input types.UserDTO) (string, error)
+	Token(ctx context.Context, req types.TokenRequest, IP string) (types.Tokens, error)
+	UserInfo(ctx context.Context, identity types.Identity) (types.UserInfo, error)
+	Discovery() oauth.ProviderMetadata
+	DeviceAuthorization(ctx context.Context, clientId, clientSecret, scope string) (types.DeviceAuthorization, error)
+	CheckUserCode(ctx context.Context, userCode string) (*types.DeviceCode, *types.OAuthClient, error)
+	ResolveUserCode(ctx context.Context, userCode string, input types.UserDTO, approve bool) error
+}
+
+type ClientService interface {
+	Create(ctx context.Context, input types.OAuthClientDTO) (*types.OAuthClient, string, error)
+	Get(ctx context.Context, clientId string) (*types.OAuthClient, error)
+	List(ctx context.Context) ([]types.OAuthClient, error)
+	Update(ctx context.Context, input types.OAuthClientDTO) (*types.OAuthClient, error)
+	Delete(ctx context.Context, clientId string) error
+	RotateSecret(ctx context.Context, clientId string) (string, *types.OAuthClientSecret, error)
+	RevokeSecret(ctx context.Context, clientId string, secretId string) error
+}
+
+type SocialService interface {
+	Providers() []string
+	LoginURL(ctx context.Context, provider string) (types.SocialLogin, error)
+	Callback(ctx context.Context, provider, state, code, IP string) (types.Tokens, error)
+	ConfirmLink(ctx context.Context, token, password, IP string) (types.Tokens, error)
+}
+
+type RBACService interface {
+	Permissions(ctx context.Context) ([]types.Permission, error)
+	CreateRole(ctx context.Context, input types.RoleDTO) (*types.Role, error)
+	GetRole(ctx context.Context, roleId string) (*types.Role, error)
+	ListRoles(ctx context.Context) ([]types.Role, error)
+	UpdateRole(ctx context.Context, input types.RoleDTO) (*types.Role, error)
+	DeleteRole(ctx context.Context, roleId string) error
+	UserRoles(ctx context.Context, userId string) ([]types.UserRole, error)
+	Assign(ctx context.Context, userId string, roleId string) error
+	Unassign(ctx context.Context, userId string, roleId string) error
+	CheckPermissions(ctx context.Context, roles []string, permissions ...string) error
+}
+
+type OrganizationService interface {
+	Create(ctx context.Context, name string, slug string) (*types.Organization, error)
+	Get(ctx context.Context, orgId string) (*types.Organization, error)
+	List(ctx context.Context) ([]types.Organization, error)
+	Members(ctx context.Context, orgId string) ([]types.Membership, error)
+	UserMemberships(ctx context.Context, userId string) ([]types.Membership, error)
+	SetMember(ctx context.Context, orgId string, userId string, roleIds []string) error
+	UpdateMember(ctx context.Context, grantor types.Identity, userId string, roleIds []string) error
+	RemoveMember(ctx context.Context, orgId string, userId string) error
+	Invite(ctx context.Context, orgId string, inviterId string, email string, roleIds []string) (*types.Invitation, error)
+	InviteMember(ctx context.Context, grantor types.Identity, email string, roleIds []string) (*types.Invitation, error)
+	AcceptInvitation(ctx context.Context, userId string, token string) (*types.Membership, error)
+}
+
+type UserAdminService interface {
+	List(ctx context.Context, filter types.UserFilter) ([]types.User, int, error)
+	Get(ctx context.Context, userId string) (*types.User, []types.Session, error)
+	SetStatus(ctx context.Context, userId string, status string, reason string) error
+	ForcePasswordReset(ctx context.Context, userId string) error
+	Delete(ctx context.Context, userId string, reason string) error
+	RevokeSessions(ctx context.Context, userId string) (int64, error)
+	RevokeSession(ctx context.Context, userId string, sessionId string) error
+}
+
+type PrivacyService interface {
+	Export(ctx context.Context, userId string) (*types.UserDataExport, error)
+	RequestDeletion(ctx context.Context, userId string) error
+	ConfirmDeletion(ctx context.Context, userId string, token string) error
+}
+
+type AuditService interface {
+	Record(ctx context.Context, eventType string, userId string, metadata map[string]string)
+	List(ctx context.Context, filter types.AuditFilter) ([]types.AuditEvent, int, error)
+	Verify(ctx context.Context) (types.AuditVerification, error)
+}
+
+type WebhookService interface {
+	Create(ctx context.Context, input types.WebhookSubscriptionDTO) (*types.WebhookSubscription, string, error)
+	Get(ctx context.Context, subscriptionId string) (*types.WebhookSubscription, error)
+	List(ctx context.Context) ([]types.WebhookSubscription, error)
+	Update(ctx context.Context, input types.WebhookSubscriptionDTO) (*types.WebhookSubscription, error)
+	Delete(ctx context.Context, subscriptionId string) error
+	Deliveries(ctx context.Context, filter types.WebhookDeliveryFilter) ([]types.WebhookDelivery, int, error)
+	Delivery(ctx context.Context, subscriptionId string, deliveryId string) (*types.WebhookDelivery, []types.WebhookAttempt, error)
+	Replay(ctx context.Context, subscriptionId string, deliveryId string) (*types.WebhookDelivery, error)
+}
+
+type SignalService interface {
+	Configuration() ssf.TransmitterMetadata
+	EventsSupported() []string
+	EventsDelivered(stream types.SignalStream) []string
+	CreateStream(ctx context.Context, clientId string, input types.SignalStreamDTO) (*types.SignalStream, error)
+	Stream(ctx context.Context, clientId string, streamId string) (*types.SignalStream, error)
+	UpdateStream(ctx context.Context, clientId string, input types.SignalStreamDTO) (*types.SignalStream, error)
+	DeleteStream(ctx context.Context, clientId string, streamId string) error
+	SetStatus(ctx context.Context, clientId string, streamId string, status string, reason string) (*types.SignalStream, error)
+	Verify(ctx context.Context, clientId string, streamId string, state string) error
+	Poll(ctx context.Context, clientId string, request ssf.PollRequest) (ssf.PollResponse, error)
+}
+
+type KeySet interface {
+	JWKS() auth.JSONWebKeySet
 }
 
 type UseCase struct {
-	User UserService
+	User     UserService
+	OAuth    OAuthService
+	Client   ClientService
+	Social   SocialService
+	RBAC     RBACService
+	Org      OrganizationService
+	Admin    UserAdminService
+	Privacy  PrivacyService
+	Audit    AuditService
+	Notify   NotificationService
+	Webhooks WebhookService
+	Signals  SignalService
+	Keys     KeySet
 }
 type Handler struct {
 	api  *gin.Engine
@@ -24,6 +171,8 @@ type Handler struct {
 
 func New(auth *UseCase) *Handler {
 	api := gin.Default()
+	api.SetHTMLTemplate(templates)
+	api.Use(requestInfoMiddleware)
 
 	h := &Handler{
 		api:  api,
@@ -32,8 +181,117 @@ func New(auth *UseCase) *Handler {
 
 	// Init endpoints
 	api.POST("/auth/sign-up", h.SignUpHandler)
-	api.POST("/auth/sign-in", h.SignInHandler)
+	api.POST("/auth/sign-in", h.deviceMiddleware, h.SignInHandler)
+	api.POST("/auth/sign-in/mfa", h.deviceMiddleware, h.SignInMFAHandler)
 	api.POST("/auth/refresh-tokens", h.RefreshTokensHandler)
+	api.POST("/auth/logout", h.authMiddleware, h.SignOutHandler)
+	api.POST("/auth/password-reset", h.ResetPasswordHandler)
+	api.POST("/auth/password-reset/sms", h.SMSPasswordResetHandler)
+	api.POST("/auth/password-reset/sms/confirm", h.ConfirmSMSPasswordResetHandler)
+	api.GET("/auth/organizations", h.authMiddleware, h.UserOrganizationsHandler)
+	api.POST("/auth/invitations/accept", h.authMiddleware, h.AcceptInvitationHandler)
+
+	// The account is managed only by users signed in to this service directly
+	account := api.Group("/auth", h.authMiddleware, h.firstPartyMiddleware)
+	account.GET("/login-history", h.LoginHistoryHandler)
+	account.GET("/devices", h.deviceMiddleware, h.DevicesHandler)
+	account.DELETE("/devices/:device_id", h.ForgetDeviceHandler)
+	account.GET("/notifications", h.NotificationPreferencesHandler)
+	account.PUT("/notifications", h.UpdateNotificationPreferencesHandler)
+	account.PUT("/notifications/time-zone", h.SetTimeZoneHandler)
+	account.GET("/phone", h.PhoneHandler)
+	account.PUT("/phone", h.SetPhoneHandler)
+	account.POST("/phone/verify", h.VerifyPhoneHandler)
+	account.DELETE("/phone", h.RemovePhoneHandler)
+	account.POST("/password/change", h.ChangePasswordHandler)
+	account.POST("/email/change", h.ChangeEmailHandler)
+	account.POST("/email/confirm", h.ConfirmEmailChangeHandler)
+	account.GET("/account/export", h.ExportAccountHandler)
+	account.POST("/account/delete", h.DeleteAccountHandler)
+	account.POST("/account/delete/confirm", h.ConfirmAccountDeletionHandler)
+	account.POST("/switch-org", h.SwitchOrganizationHandler)
+
+	api.GET("/auth/social", h.SocialProvidersHandler)
+	api.GET("/auth/social/:provider", h.SocialLoginHandler)
+	api.GET("/auth/social/:provider/callback", h.deviceMiddleware, h.SocialCallbackHandler)
+	api.POST("/auth/social/link", h.deviceMiddleware, h.SocialLinkHandler)
+
+	api.GET("/oauth/authorize", noFrameMiddleware, h.AuthorizeHandler)
+	api.POST("/oauth/authorize", noFrameMiddleware, h.deviceMiddleware, h.AuthorizeConsentHandler)
+	api.POST("/oauth/token", h.TokenHandler)
+	api.POST("/oauth/device_authorization", h.DeviceAuthorizationHandler)
+	api.GET("/oauth/device", noFrameMiddleware, h.DeviceVerificationHandler)
+	api.POST("/oauth/device", noFrameMiddleware, h.deviceMiddleware, h.DeviceConsentHandler)
+
+	api.GET("/.well-known/openid-configuration", h.OpenIDConfigurationHandler)
+	api.GET("/.well-known/jwks.json", h.JWKSHandler)
+	api.GET("/.well-known/ssf-configuration", h.SSFConfigurationHandler)
+	api.GET("/userinfo", h.authMiddleware, h.UserInfoHandler)
+	api.POST("/userinfo", h.authMiddleware, h.UserInfoHandler)
+
+	// Shared Signals receivers manage their stream with client credentials tokens
+	signals := api.Group("/ssf", h.authMiddleware, h.receiverMiddleware)
+	signals.GET("/stream", h.GetStreamHandler)
+	signals.POST("/stream", h.CreateStreamHandler)
+	signals.PATCH("/stream", h.UpdateStreamHandler)
+	signals.DELETE("/stream", h.DeleteStreamHandler)
+	signals.GET("/status", h.GetStreamStatusHandler)
+	signals.POST("/status", h.UpdateStreamStatusHandler)
+	signals.POST("/verify", h.VerifyStreamHandler)
+	signals.POST("/poll", h.PollEventsHandler)
+
+	admin := api.Group("/admin", h.authMiddleware, h.auditAdminMiddleware)
+	admin.GET("/clients", h.RequirePermission(types.PermissionClientsRead), h.ListClientsHandler)
+	admin.POST("/clients", h.RequirePermission(types.PermissionClientsWrite), h.CreateClientHandler)
+	admin.GET("/clients/:client_id", h.RequirePermission(types.PermissionClientsRead), h.GetClientHandler)
+	admin.PUT("/clients/:client_id", h.RequirePermission(types.PermissionClientsWrite), h.UpdateClientHandler)
+	admin.DELETE("/clients/:client_id", h.RequirePermission(types.PermissionClientsWrite), h.DeleteClientHandler)
+	admin.POST("/clients/:client_id/secrets", h.RequirePermission(types.PermissionClientsWrite), h.RotateClientSecretHandler)
+	admin.DELETE("/clients/:client_id/secrets/:secret_id", h.RequirePermission(types.PermissionClientsWrite), h.RevokeClientSecretHandler)
+
+	admin.GET("/permissions", h.RequirePermission(types.PermissionRolesRead), h.ListPermissionsHandler)
+	admin.GET("/roles", h.RequirePermission(types.PermissionRolesRead), h.ListRolesHandler)
+	admin.POST("/roles", h.RequirePermission(types.PermissionRolesWrite), h.CreateRoleHandler)
+	admin.GET("/roles/:role_id", h.RequirePermission(types.PermissionRolesRead), h.GetRoleHandler)
+	admin.PUT("/roles/:role_id", h.RequirePermission(types.PermissionRolesWrite), h.UpdateRoleHandler)
+	admin.DELETE("/roles/:role_id", h.RequirePermission(types.PermissionRolesWrite), h.DeleteRoleHandler)
+	admin.GET("/users", h.RequirePermission(types.PermissionUsersRead), h.ListUsersHandler)
+	admin.GET("/users/:user_id", h.RequirePermission(types.PermissionUsersRead), h.GetUserHandler)
+	admin.DELETE("/users/:user_id", h.RequirePermission(types.PermissionUsersWrite), h.DeleteUserHandler)
+	admin.PUT("/users/:user_id/status", h.RequirePermission(types.PermissionUsersWrite), h.SetUserStatusHandler)
+	admin.POST("/users/:user_id/password-reset", h.RequirePermission(types.PermissionUsersWrite), h.ForcePasswordResetHandler)
+	admin.DELETE("/users/:user_id/sessions", h.RequirePermission(types.PermissionUsersWrite), h.RevokeUserSessionsHandler)
+	admin.DELETE("/users/:user_id/sessions/:session_id", h.RequirePermission(types.PermissionUsersWrite), h.RevokeUserSessionHandler)
+	admin.GET("/users/:user_id/roles", h.RequirePermission(types.PermissionRolesRead), h.ListUserRolesHandler)
+	admin.POST("/users/:user_id/roles", h.RequirePermission(types.PermissionRolesWrite), h.AssignRoleHandler)
+	admin.DELETE("/users/:user_id/roles/:role_id", h.RequirePermission(types.PermissionRolesWrite), h.UnassignRoleHandler)
+
+	admin.GET("/audit-events", h.RequirePermission(types.PermissionAuditRead), h.ListAuditEventsHandler)
+	admin.GET("/audit-events/verify", h.RequirePermission(types.PermissionAuditRead), h.VerifyAuditLogHandler)
+
+	admin.GET("/webhooks", h.RequirePermission(types.PermissionWebhooksRead), h.ListWebhooksHandler)
+	admin.POST("/webhooks", h.RequirePermission(types.PermissionWebhooksWrite), h.CreateWebhookHandler)
+	admin.GET("/webhooks/:webhook_id", h.RequirePermission(types.PermissionWebhooksRead), h.GetWebhookHandler)
+	admin.PUT("/webhooks/:webhook_id", h.RequirePermission(types.PermissionWebhooksWrite), h.UpdateWebhookHandler)
+	admin.DELETE("/webhooks/:webhook_id", h.RequirePermission(types.PermissionWebhooksWrite), h.DeleteWebhookHandler)
+	admin.GET("/webhooks/:webhook_id/deliveries", h.RequirePermission(types.PermissionWebhooksRead), h.ListWebhookDeliveriesHandler)
+	admin.GET("/webhooks/:webhook_id/deliveries/:delivery_id", h.RequirePermission(types.PermissionWebhooksRead), h.GetWebhookDeliveryHandler)
+	admin.POST("/webhooks/:webhook_id/deliveries/:delivery_id/replay", h.RequirePermission(types.PermissionWebhooksWrite), h.ReplayWebhookDeliveryHandler)
+
+	admin.GET("/organizations", h.RequirePermission(types.PermissionOrganizationsRead), h.ListOrganizationsHandler)
+	admin.POST("/organizations", h.RequirePermission(types.PermissionOrganizationsWrite), h.CreateOrganizationHandler)
+	admin.GET("/organizations/:org_id", h.RequirePermission(types.PermissionOrganizationsRead), h.GetOrganizationHandler)
+	admin.GET("/organizations/:org_id/members", h.RequirePermission(types.PermissionOrganizationsRead), h.ListMembersHandler)
+	admin.PUT("/organizations/:org_id/members/:user_id", h.RequirePermission(types.PermissionOrganizationsWrite), h.SetMemberHandler)
+	admin.DELETE("/organizations/:org_id/members/:user_id", h.RequirePermission(types.PermissionOrganizationsWrite), h.RemoveMemberHandler)
+	admin.POST("/organizations/:org_id/invitations", h.RequirePermission(types.PermissionOrganizationsWrite), h.InviteMemberHandler)
+
+	// Current organization of the caller, selected at sign-in or with /auth/switch-org
+	org := api.Group("/org", h.authMiddleware, h.firstPartyMiddleware)
+	org.GET("/members", h.RequireOrgPermission(types.PermissionMembersRead), h.ListMembersHandler)
+	org.PUT("/members/:user_id", h.RequireOrgPermission(types.PermissionMembersWrite), h.SetMemberHandler)
+	org.DELETE("/members/:user_id", h.RequireOrgPermission(types.PermissionMembersWrite), h.RemoveMemberHandler)
+	org.POST("/invitations", h.RequireOrgPermission(types.PermissionMembersWrite), h.InviteMemberHandler)
 
 	return h
 }
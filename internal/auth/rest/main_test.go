@@ -0,0 +1,12 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
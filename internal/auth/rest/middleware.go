@@ -0,0 +1,228 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const identityCtxKey = "identity"
+
+const (
+	deviceCookieName  = "device_id"
+	deviceIdHeader    = "X-Device-Id"
+	minDeviceIdLength = 16
+	maxDeviceIdLength = 128
+)
+
+// authMiddleware authenticates requests by the bearer access token.
+func (h *Handler) authMiddleware(c *gin.Context) {
+	accessToken, ok := bearerToken(c)
+	if !ok {
+		c.Header("WWW-Authenticate", `Bearer`)
+		newResponse(c, http.StatusUnauthorized, "missing access token")
+		return
+	}
+
+	identity, err := h.auth.User.Identify(c.Request.Context(), accessToken)
+	if err != nil {
+		logger.Errorf("failed to identify user (ip: %s): %s", c.ClientIP(), err.Error())
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		newResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	c.Set(identityCtxKey, *identity)
+
+	info := types.RequestInfoFrom(c.Request.Context())
+	info.ActorId = identity.UserId
+	if identity.IsClient() {
+		info.ActorId = identity.ClientId
+	}
+	c.Request = c.Request.WithContext(types.WithRequestInfo(c.Request.Context(), info))
+
+	c.Next()
+}
+
+// requestInfoMiddleware stores the client IP and user agent in the request context for the audit log.
+func requestInfoMiddleware(c *gin.Context) {
+	info := types.RequestInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	c.Request = c.Request.WithContext(types.WithRequestInfo(c.Request.Context(), info))
+	c.Next()
+}
+
+// noFrameMiddleware forbids showing the consent pages in frames of other sites, so that the
+// user cannot be tricked into approving a client by clicks on a hidden page.
+func noFrameMiddleware(c *gin.Context) {
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "frame-ancestors 'none'")
+	c.Next()
+}
+
+// deviceMiddleware identifies the device of sign-in requests by the X-Device-Id header sent by
+// API clients or by the signed device cookie of browsers. Browsers without a valid cookie get a
+// new device id; the cookie is renewed on every request, so that devices in use do not expire.
+func (h *Handler) deviceMiddleware(c *gin.Context) {
+	deviceId := c.GetHeader(deviceIdHeader)
+	if deviceId != "" {
+		if len(deviceId) < minDeviceIdLength || len(deviceId) > maxDeviceIdLength {
+			newResponse(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+	} else {
+		if cookie, err := c.Cookie(deviceCookieName); err == nil {
+			deviceId, _ = h.auth.User.ParseDeviceCookie(cookie)
+		}
+		if deviceId == "" {
+			var err error
+			if deviceId, err = h.auth.User.NewDeviceId(); err != nil {
+				newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+				return
+			}
+		}
+
+		cookie := h.auth.User.DeviceCookie(deviceId)
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(deviceCookieName, cookie.Value, int(time.Until(cookie.ExpiresAt).Seconds()), "/", "", c.Request.TLS != nil, true)
+	}
+
+	info := types.RequestInfoFrom(c.Request.Context())
+	info.DeviceId = deviceId
+	c.Request = c.Request.WithContext(types.WithRequestInfo(c.Request.Context(), info))
+
+	c.Next()
+}
+
+// auditAdminMiddleware records every successful change made through the admin API that the
+// service did not record as a specific event. It must run after authMiddleware.
+func (h *Handler) auditAdminMiddleware(c *gin.Context) {
+	ctx, mark := types.WithAuditMark(c.Request.Context())
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	if c.Request.Method == http.MethodGet || c.Writer.Status() >= http.StatusBadRequest || mark.Recorded() {
+		return
+	}
+
+	metadata := map[string]string{
+		"method": c.Request.Method,
+		"route":  c.FullPath(),
+		"status": strconv.Itoa(c.Writer.Status()),
+	}
+	for _, param := range c.Params {
+		metadata[param.Key] = param.Value
+	}
+	h.auth.Audit.Record(c.Request.Context(), types.AuditAdminAction, c.Param("user_id"), metadata)
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// receiverMiddleware lets through only client credentials tokens, Shared Signals streams
+// belong to OAuth clients. It must run after authMiddleware.
+func (h *Handler) receiverMiddleware(c *gin.Context) {
+	if !identityFrom(c).IsClient() {
+		newResponse(c, http.StatusForbidden, "streams are managed with client credentials")
+		return
+	}
+	c.Next()
+}
+
+// identityFrom returns the identity stored by authMiddleware.
+func identityFrom(c *gin.Context) types.Identity {
+	return c.MustGet(identityCtxKey).(types.Identity)
+}
+
+// isFirstPartyUser reports whether the identity is a user signed in to this service
+// directly, not a client, an OAuth client acting for the user or an exchanged token.
+func isFirstPartyUser(identity types.Identity) bool {
+	return !identity.IsClient() && identity.ClientId == "" && identity.Actor == ""
+}
+
+// firstPartyMiddleware lets through only users signed in to this service directly, so that
+// tokens of OAuth clients and exchanged tokens cannot manage the account.
+// It must run after authMiddleware.
+func (h *Handler) firstPartyMiddleware(c *gin.Context) {
+	if !isFirstPartyUser(identityFrom(c)) {
+		newResponse(c, http.StatusForbidden, "only first-party user tokens can manage the account")
+		return
+	}
+	c.Next()
+}
+
+// RequirePermission lets the request through only if the roles of the caller grant
+// every one of permissions. Only first-party users hold global roles.
+// It must run after authMiddleware.
+func (h *Handler) RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := identityFrom(c)
+		if !isFirstPartyUser(identity) {
+			logger.Errorf("permission check failed (user: %s, client: %s, path: %s): not a first-party token", identity.UserId, identity.ClientId, c.FullPath())
+			newResponse(c, http.StatusForbidden, "only first-party user tokens can use the admin API")
+			return
+		}
+
+		if err := h.auth.RBAC.CheckPermissions(c.Request.Context(), identity.Roles, permissions...); err != nil {
+			logger.Errorf("permission check failed (user: %s, path: %s): %s", identity.UserId, c.FullPath(), err.Error())
+			if errors.Is(err, service.ErrPermissionDenied) {
+				newResponse(c, http.StatusForbidden, err.Error())
+				return
+			}
+
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireOrgPermission is like RequirePermission, but checks the roles of the caller in
+// the organization selected in the access token.
+func (h *Handler) RequireOrgPermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := identityFrom(c)
+		if identity.OrgId == "" {
+			newResponse(c, http.StatusForbidden, "organization is not selected")
+			return
+		}
+
+		if err := h.auth.RBAC.CheckPermissions(c.Request.Context(), identity.OrgRoles, permissions...); err != nil {
+			logger.Errorf("permission check failed (user: %s, org: %s, path: %s): %s", identity.UserId, identity.OrgId, c.FullPath(), err.Error())
+			if errors.Is(err, service.ErrPermissionDenied) {
+				newResponse(c, http.StatusForbidden, err.Error())
+				return
+			}
+
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// organizationParam returns the organization from the path of admin routes, or the
+// current organization of the caller otherwise.
+func organizationParam(c *gin.Context) string {
+	if orgId := c.Param("org_id"); orgId != "" {
+		return orgId
+	}
+	return identityFrom(c).OrgId
+}
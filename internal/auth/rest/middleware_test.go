@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"context"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// fakeUsers identifies the bearer token by its value.
+type fakeUsers struct {
+	UserService
+
+	identities map[string]types.Identity
+}
+
+func (u *fakeUsers) Identify(_ context.Context, accessToken string) (*types.Identity, error) {
+	identity, ok := u.identities[accessToken]
+	if !ok {
+		return nil, service.ErrInvalidAccessToken
+	}
+	return &identity, nil
+}
+
+// fakeRBAC grants every permission to the admin role.
+type fakeRBAC struct {
+	RBACService
+}
+
+func (fakeRBAC) CheckPermissions(_ context.Context, roles []string, _ ...string) error {
+	if !slices.Contains(roles, "admin") {
+		return service.ErrPermissionDenied
+	}
+	return nil
+}
+
+type fakeClients struct {
+	ClientService
+}
+
+func (fakeClients) List(context.Context) ([]types.OAuthClient, error) {
+	return nil, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	const userId = "8c8f8a3e-5d43-4a71-9c55-0a1b0c9d2e11"
+
+	tests := []struct {
+		name       string
+		identity   types.Identity
+		wantStatus int
+	}{
+		{
+			name:       "admin signed in to the service",
+			identity:   types.Identity{SessionId: "s1", UserId: userId, Roles: []string{"admin"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "user without the role",
+			identity:   types.Identity{SessionId: "s1", UserId: userId},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "oauth client acting for an admin",
+			identity:   types.Identity{SessionId: "s1", UserId: userId, ClientId: "crm", Roles: []string{"admin"}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "exchanged token of an admin",
+			identity:   types.Identity{UserId: userId, ClientId: "crm", Actor: "support", Roles: []string{"admin"}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "client credentials",
+			identity:   types.Identity{ClientId: "crm", Roles: []string{"admin"}},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&UseCase{
+				User:   &fakeUsers{identities: map[string]types.Identity{"token": tt.identity}},
+				RBAC:   fakeRBAC{},
+				Client: fakeClients{},
+			}).Handler()
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/clients", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestFirstPartyMiddleware(t *testing.T) {
+	const (
+		userId = "8c8f8a3e-5d43-4a71-9c55-0a1b0c9d2e11"
+		orgId  = "2d4e6f80-1a3b-4c5d-8e7f-9a0b1c2d3e4f"
+	)
+
+	identities := map[string]types.Identity{
+		"oauth":    {SessionId: "s1", UserId: userId, ClientId: "crm", OrgId: orgId, OrgRoles: []string{"admin"}},
+		"exchange": {UserId: userId, ClientId: "crm", Actor: "support", OrgId: orgId, OrgRoles: []string{"admin"}},
+		"client":   {ClientId: "crm"},
+	}
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/auth/login-history"},
+		{http.MethodGet, "/auth/devices"},
+		{http.MethodPut, "/auth/notifications/time-zone"},
+		{http.MethodPut, "/auth/phone"},
+		{http.MethodPost, "/auth/password/change"},
+		{http.MethodPost, "/auth/email/change"},
+		{http.MethodGet, "/auth/account/export"},
+		{http.MethodPost, "/auth/account/delete"},
+		{http.MethodPost, "/auth/switch-org"},
+		{http.MethodGet, "/org/members"},
+		{http.MethodPut, "/org/members/" + userId},
+		{http.MethodPost, "/org/invitations"},
+	}
+
+	handler := New(&UseCase{User: &fakeUsers{identities: identities}, RBAC: fakeRBAC{}}).Handler()
+	for token := range identities {
+		for _, route := range routes {
+			t.Run(token+" "+route.method+" "+route.path, func(t *testing.T) {
+				req := httptest.NewRequest(route.method, route.path, nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				req.Header.Set(deviceIdHeader, testDeviceId)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+
+				if rec.Code != http.StatusForbidden {
+					t.Errorf("status = %d, want %d (body: %s)", rec.Code, http.StatusForbidden, rec.Body)
+				}
+			})
+		}
+	}
+}
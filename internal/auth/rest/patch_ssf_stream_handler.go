@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+// UpdateStreamHandler changes the fields of the stream that are present in the body.
+func (h *Handler) UpdateStreamHandler(c *gin.Context) {
+	identity := identityFrom(c)
+
+	var input streamInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	stream, err := h.auth.Signals.UpdateStream(c.Request.Context(), identity.ClientId, input.toTypes())
+	if err != nil {
+		logger.Errorf("failed to update stream (client: %s): %s", identity.ClientId, err.Error())
+		switch {
+		case isStreamInputError(err):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrSignalStreamNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+		default:
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, h.newStreamResponse(*stream))
+}
@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type accountDeleteConfirmInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// DeleteAccountHandler emails the caller a token that confirms erasing the account.
+func (h *Handler) DeleteAccountHandler(c *gin.Context) {
+	identity := identityFrom(c)
+	if err := h.auth.Privacy.RequestDeletion(c.Request.Context(), identity.UserId); err != nil {
+		logger.Errorf("failed to request account deletion (ip: %s, user: %s): %s", c.ClientIP(), identity.UserId, err.Error())
+		if errors.Is(err, service.ErrUserNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func (h *Handler) ConfirmAccountDeletionHandler(c *gin.Context) {
+	var input accountDeleteConfirmInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	if err := h.auth.Privacy.ConfirmDeletion(c.Request.Context(), identity.UserId, input.Token); err != nil {
+		logger.Errorf("failed to delete account (ip: %s, user: %s): %s", c.ClientIP(), identity.UserId, err.Error())
+		if errors.Is(err, service.ErrInvalidDeletionToken) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
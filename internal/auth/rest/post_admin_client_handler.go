@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type clientInput struct {
+	ClientId          string   `json:"client_id" binding:"max=64"`
+	Name              string   `json:"name" binding:"required,max=100"`
+	Type              string   `json:"client_type" binding:"omitempty,oneof=public confidential"`
+	RedirectURIs      []string `json:"redirect_uris" binding:"dive,url"`
+	Scopes            []string `json:"scopes"`
+	GrantTypes        []string `json:"grant_types"`
+	ExchangeAudiences []string `json:"exchange_audiences" binding:"max=32,dive,max=64"`
+}
+
+func (in clientInput) toTypes() types.OAuthClientDTO {
+	return types.OAuthClientDTO{
+		ClientId:          in.ClientId,
+		Name:              in.Name,
+		Type:              in.Type,
+		RedirectURIs:      in.RedirectURIs,
+		Scopes:            in.Scopes,
+		GrantTypes:        in.GrantTypes,
+		ExchangeAudiences: in.ExchangeAudiences,
+	}
+}
+
+func (h *Handler) CreateClientHandler(c *gin.Context) {
+	var input clientInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	client, secret, err := h.auth.Client.Create(c.Request.Context(), input.toTypes())
+	if err != nil {
+		logger.Errorf("failed to create client: %s", err.Error())
+		if isClientInputError(err) || errors.Is(err, service.ErrClientAlreadyExists) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := newClientResponse(*client)
+	resp.ClientSecret = secret
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func isClientInputError(err error) bool {
+	return errors.Is(err, service.ErrInvalidClientType) ||
+		errors.Is(err, service.ErrInvalidGrantTypes) ||
+		errors.Is(err, service.ErrInvalidRedirectURI)
+}
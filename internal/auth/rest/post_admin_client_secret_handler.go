@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type clientSecretResponse struct {
+	Id           string    `json:"id"`
+	ClientSecret string    `json:"client_secret"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (h *Handler) RotateClientSecretHandler(c *gin.Context) {
+	secret, clientSecret, err := h.auth.Client.RotateSecret(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		logger.Errorf("failed to rotate client secret: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrClientNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidClientType):
+			newResponse(c, http.StatusBadRequest, "public clients have no secrets")
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusCreated, clientSecretResponse{
+		Id:           clientSecret.Id,
+		ClientSecret: secret,
+		CreatedAt:    clientSecret.CreatedAt,
+	})
+}
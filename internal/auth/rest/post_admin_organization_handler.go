@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type organizationInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+	Slug string `json:"slug" binding:"required,max=64,alphanum"`
+}
+
+func (h *Handler) CreateOrganizationHandler(c *gin.Context) {
+	var input organizationInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	org, err := h.auth.Org.Create(c.Request.Context(), input.Name, input.Slug)
+	if err != nil {
+		logger.Errorf("failed to create organization: %s", err.Error())
+		if errors.Is(err, service.ErrOrganizationAlreadyExists) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusCreated, newOrganizationResponse(*org))
+}
@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type roleInput struct {
+	Name        string   `json:"name" binding:"required,max=64"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+func (in roleInput) toTypes() types.RoleDTO {
+	return types.RoleDTO{
+		Name:        in.Name,
+		Description: in.Description,
+		Permissions: in.Permissions,
+	}
+}
+
+func (h *Handler) CreateRoleHandler(c *gin.Context) {
+	var input roleInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	role, err := h.auth.RBAC.CreateRole(c.Request.Context(), input.toTypes())
+	if err != nil {
+		logger.Errorf("failed to create role: %s", err.Error())
+		if errors.Is(err, service.ErrUnknownPermission) || errors.Is(err, service.ErrRoleAlreadyExists) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusCreated, newRoleResponse(*role))
+}
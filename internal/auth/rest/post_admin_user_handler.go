@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type userStatusInput struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+func (h *Handler) SetUserStatusHandler(c *gin.Context) {
+	var input userStatusInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	if err := h.auth.Admin.SetStatus(c.Request.Context(), c.Param("user_id"), input.Status, input.Reason); err != nil {
+		logger.Errorf("failed to update user status: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidUserStatus):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ForcePasswordResetHandler(c *gin.Context) {
+	if err := h.auth.Admin.ForcePasswordReset(c.Request.Context(), c.Param("user_id")); err != nil {
+		logger.Errorf("failed to force password reset: %s", err.Error())
+		if errors.Is(err, service.ErrUserNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type assignRoleInput struct {
+	RoleId string `json:"role_id" binding:"required,uuid"`
+}
+
+func (h *Handler) AssignRoleHandler(c *gin.Context) {
+	var input assignRoleInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	if err := h.auth.RBAC.Assign(c.Request.Context(), c.Param("user_id"), input.RoleId); err != nil {
+		logger.Errorf("failed to assign role: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrRoleNotFound):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type webhookInput struct {
+	Id          string   `json:"-"`
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	Description string   `json:"description" binding:"max=255"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1"`
+	Active      *bool    `json:"active"`
+}
+
+func (in webhookInput) toTypes() types.WebhookSubscriptionDTO {
+	return types.WebhookSubscriptionDTO{
+		Id:          in.Id,
+		URL:         in.URL,
+		Description: in.Description,
+		EventTypes:  in.EventTypes,
+		Active:      in.Active,
+	}
+}
+
+// CreateWebhookHandler registers a webhook. The signing secret is only returned here.
+func (h *Handler) CreateWebhookHandler(c *gin.Context) {
+	var input webhookInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	subscription, secret, err := h.auth.Webhooks.Create(c.Request.Context(), input.toTypes())
+	if err != nil {
+		logger.Errorf("failed to create webhook: %s", err.Error())
+		if isWebhookInputError(err) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	resp := newWebhookResponse(*subscription)
+	resp.Secret = secret
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func isWebhookInputError(err error) bool {
+	return errors.Is(err, service.ErrInvalidWebhookURL) ||
+		errors.Is(err, service.ErrInvalidWebhookEvents)
+}
@@ -0,0 +1,26 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+// ReplayWebhookDeliveryHandler queues the payload of a delivery to be sent again.
+func (h *Handler) ReplayWebhookDeliveryHandler(c *gin.Context) {
+	delivery, err := h.auth.Webhooks.Replay(c.Request.Context(), c.Param("webhook_id"), c.Param("delivery_id"))
+	if err != nil {
+		logger.Errorf("failed to replay webhook delivery: %s", err.Error())
+		if errors.Is(err, service.ErrWebhookDeliveryNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, newWebhookDeliveryResponse(*delivery))
+}
@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type emailChangeInput struct {
+	Password string `json:"password" binding:"required"`
+	NewEmail string `json:"new_email" binding:"required,email,max=64"`
+}
+
+type emailConfirmInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type emailResponse struct {
+	Email string `json:"email"`
+}
+
+// ChangeEmailHandler starts an email change. The email changes after the token sent to the
+// new address is confirmed with ConfirmEmailChangeHandler.
+func (h *Handler) ChangeEmailHandler(c *gin.Context) {
+	var input emailChangeInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	if err := h.auth.User.RequestEmailChange(c.Request.Context(), identity, input.Password, input.NewEmail); err != nil {
+		logger.Errorf("failed to request email change (ip: %s, user: %s): %s", c.ClientIP(), identity.UserId, err.Error())
+		switch {
+		case errors.Is(err, service.ErrInvalidPassword), errors.Is(err, service.ErrSameEmail):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrUserAlreadyExists), errors.Is(err, service.ErrExternalCredentials):
+			newResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func (h *Handler) ConfirmEmailChangeHandler(c *gin.Context) {
+	var input emailConfirmInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	newEmail, err := h.auth.User.ConfirmEmailChange(c.Request.Context(), identity, input.Token)
+	if err != nil {
+		logger.Errorf("failed to confirm email change (ip: %s, user: %s): %s", c.ClientIP(), identity.UserId, err.Error())
+		switch {
+		case errors.Is(err, service.ErrInvalidEmailChangeToken):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrUserAlreadyExists):
+			newResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, emailResponse{Email: newEmail})
+}
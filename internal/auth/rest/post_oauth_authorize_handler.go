@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"net/http"
+	"net/url"
+)
+
+var errAccessDenied = errors.New("the resource owner denied the request")
+
+type authorizeConsent struct {
+	authorizeRequest
+	Email    string `form:"email"`
+	Password string `form:"password"`
+	Action   string `form:"action"`
+}
+
+func (h *Handler) AuthorizeConsentHandler(c *gin.Context) {
+	var input authorizeConsent
+	if err := c.ShouldBind(&input); err != nil {
+		logger.Errorf("failed to decode authorize form: %s", err.Error())
+		c.HTML(http.StatusBadRequest, "authorize.html", authorizePage{Fatal: true, Error: "invalid request"})
+		return
+	}
+
+	req := input.toTypes()
+	client, err := h.auth.OAuth.CheckClient(c.Request.Context(), req)
+	if err != nil {
+		renderAuthorizeFatal(c, err)
+		return
+	}
+
+	if input.Action != "approve" {
+		redirectAuthorizeError(c, req, errAccessDenied)
+		return
+	}
+
+	code, err := h.auth.OAuth.Authorize(c.Request.Context(), req, types.UserDTO{
+		Email:    input.Email,
+		Password: input.Password,
+	})
+	if err != nil {
+		logger.Errorf("failed to authorize (client: %s, email: %s): %s", req.ClientId, input.Email, err.Error())
+		if isCredentialsError(err) {
+			c.HTML(http.StatusOK, "authorize.html", authorizePage{
+				Error:      credentialsErrorMessage(err),
+				ClientName: client.Name,
+				Scopes:     oauth.ParseScope(req.Scope),
+				Request:    input.authorizeRequest,
+			})
+			return
+		}
+
+		redirectAuthorizeError(c, req, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, oauth.AppendQuery(req.RedirectURI, url.Values{
+		"code":  {code},
+		"state": {req.State},
+	}))
+}
+
+// isCredentialsError reports whether the user cannot sign in with the entered credentials.
+func isCredentialsError(err error) bool {
+	return errors.Is(err, service.ErrUserNotFound) ||
+		isUserStatusError(err) ||
+		errors.Is(err, service.ErrPasswordResetRequired) ||
+		errors.Is(err, service.ErrMFARequired) ||
+		errors.Is(err, service.ErrSignInBlocked) ||
+		errors.Is(err, service.ErrNotDirectoryAccount)
+}
+
+// isUserStatusError reports whether the user account is not active.
+func isUserStatusError(err error) bool {
+	return errors.Is(err, service.ErrUserDisabled) ||
+		errors.Is(err, service.ErrUserBanned) ||
+		errors.Is(err, service.ErrUserPendingDeletion)
+}
+
+func credentialsErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, service.ErrUserDisabled):
+		return "Учётная запись отключена"
+	case errors.Is(err, service.ErrUserBanned):
+		return "Учётная запись заблокирована"
+	case errors.Is(err, service.ErrUserPendingDeletion):
+		return "Учётная запись удалена"
+	case errors.Is(err, service.ErrPasswordResetRequired):
+		return "Требуется сменить пароль, инструкция отправлена на ваш email"
+	case errors.Is(err, service.ErrMFARequired):
+		return "Вход требует подтверждения кодом, войдите в приложении"
+	case errors.Is(err, service.ErrSignInBlocked):
+		return "Вход заблокирован из-за подозрительной активности"
+	case errors.Is(err, service.ErrNotDirectoryAccount):
+		return "Учётная запись с этим email создана не через корпоративный каталог"
+	}
+	return "Неверный email или пароль"
+}
@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"net/http"
+)
+
+type deviceAuthorizationRequest struct {
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+func (h *Handler) DeviceAuthorizationHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	var input deviceAuthorizationRequest
+	if err := c.ShouldBind(&input); err != nil {
+		logger.Errorf("failed to decode device authorization request: %s", err.Error())
+		newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeInvalidRequest, "invalid body request")
+		return
+	}
+
+	if err := withClientBasicAuth(c, &input.ClientId, &input.ClientSecret); err != nil {
+		logger.Errorf("failed to decode client credentials: %s", err.Error())
+		newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	auth, err := h.auth.OAuth.DeviceAuthorization(c.Request.Context(), input.ClientId, input.ClientSecret, input.Scope)
+	if err != nil {
+		logger.Errorf("failed to start device authorization (client: %s): %s", input.ClientId, err.Error())
+		switch {
+		case errors.Is(err, service.ErrInvalidClient):
+			newOAuthErrorResponse(c, http.StatusUnauthorized, oauth.ErrCodeInvalidClient, err.Error())
+			return
+		case errors.Is(err, service.ErrUnauthorizedClient):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeUnauthorizedClient, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidScope):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeInvalidScope, err.Error())
+			return
+		}
+
+		newOAuthErrorResponse(c, http.StatusInternalServerError, oauth.ErrCodeServerError, "")
+		return
+	}
+
+	c.JSON(http.StatusOK, deviceAuthorizationResponse{
+		DeviceCode:              auth.DeviceCode,
+		UserCode:                auth.UserCode,
+		VerificationURI:         auth.VerificationURI,
+		VerificationURIComplete: auth.VerificationURIComplete,
+		ExpiresIn:               int64(auth.ExpiresIn.Seconds()),
+		Interval:                int64(auth.Interval.Seconds()),
+	})
+}
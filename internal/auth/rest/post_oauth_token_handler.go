@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"net/http"
+	"net/url"
+)
+
+type tokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	DeviceCode   string `form:"device_code"`
+
+	SubjectToken       string `form:"subject_token"`
+	SubjectTokenType   string `form:"subject_token_type"`
+	ActorToken         string `form:"actor_token"`
+	ActorTokenType     string `form:"actor_token_type"`
+	RequestedTokenType string `form:"requested_token_type"`
+}
+
+type tokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	IDToken         string `json:"id_token,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+}
+
+func (h *Handler) TokenHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	var input tokenRequest
+	if err := c.ShouldBind(&input); err != nil {
+		logger.Errorf("failed to decode token request: %s", err.Error())
+		newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeInvalidRequest, "invalid body request")
+		return
+	}
+
+	if err := withClientBasicAuth(c, &input.ClientId, &input.ClientSecret); err != nil {
+		logger.Errorf("failed to decode client credentials: %s", err.Error())
+		newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	tokens, err := h.auth.OAuth.Token(c.Request.Context(), types.TokenRequest{
+		GrantType:    input.GrantType,
+		ClientId:     input.ClientId,
+		ClientSecret: input.ClientSecret,
+		Scope:        input.Scope,
+		Code:         input.Code,
+		RedirectURI:  input.RedirectURI,
+		CodeVerifier: input.CodeVerifier,
+		RefreshToken: input.RefreshToken,
+		DeviceCode:   input.DeviceCode,
+		Exchange: types.TokenExchangeRequest{
+			SubjectToken:       input.SubjectToken,
+			SubjectTokenType:   input.SubjectTokenType,
+			ActorToken:         input.ActorToken,
+			ActorTokenType:     input.ActorTokenType,
+			RequestedTokenType: input.RequestedTokenType,
+			Scope:              input.Scope,
+		},
+	}, c.ClientIP())
+	if err != nil {
+		logger.Errorf("failed to issue oauth tokens (client: %s, grant: %s): %s", input.ClientId, input.GrantType, err.Error())
+		switch {
+		case errors.Is(err, service.ErrInvalidClient):
+			if _, _, ok := c.Request.BasicAuth(); ok {
+				c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			newOAuthErrorResponse(c, http.StatusUnauthorized, oauth.ErrCodeInvalidClient, err.Error())
+			return
+		case errors.Is(err, service.ErrUnauthorizedClient):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeUnauthorizedClient, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidScope):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeInvalidScope, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidGrant),
+			errors.Is(err, service.ErrInvalidSubjectToken),
+			errors.Is(err, service.ErrInvalidActorToken),
+			errors.Is(err, service.ErrActorNotAllowed),
+			errors.Is(err, service.ErrActorAudienceNotAllowed),
+			errors.Is(err, service.ErrAudienceNotAllowed):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeInvalidGrant, err.Error())
+			return
+		case errors.Is(err, service.ErrUnsupportedTokenType):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeInvalidRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrUnsupportedGrantType):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeUnsupportedGrantType, err.Error())
+			return
+		case errors.Is(err, service.ErrAuthorizationPending):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeAuthorizationPending, err.Error())
+			return
+		case errors.Is(err, service.ErrSlowDown):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeSlowDown, err.Error())
+			return
+		case errors.Is(err, service.ErrExpiredToken):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeExpiredToken, err.Error())
+			return
+		case errors.Is(err, service.ErrAccessDenied):
+			newOAuthErrorResponse(c, http.StatusBadRequest, oauth.ErrCodeAccessDenied, err.Error())
+			return
+		}
+
+		newOAuthErrorResponse(c, http.StatusInternalServerError, oauth.ErrCodeServerError, "")
+		return
+	}
+
+	c.JSON(http.StatusOK, newTokenResponse(tokens))
+}
+
+// withClientBasicAuth fills client credentials from the client_secret_basic
+// Authorization header, if any (RFC 6749, section 2.3.1).
+func withClientBasicAuth(c *gin.Context, clientId, clientSecret *string) error {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return nil
+	}
+
+	if *clientSecret != "" {
+		return errors.New("multiple client authentication methods used")
+	}
+
+	var err error
+	if *clientId, err = url.QueryUnescape(id); err != nil {
+		return err
+	}
+	if *clientSecret, err = url.QueryUnescape(secret); err != nil {
+		return err
+	}
+	return nil
+}
+
+func newTokenResponse(tokens types.Tokens) tokenResponse {
+	return tokenResponse{
+		AccessToken:     tokens.AccessToken,
+		IssuedTokenType: tokens.TokenType,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(tokens.ExpiresIn.Seconds()),
+		RefreshToken:    tokens.RefreshToken,
+		IDToken:         tokens.IDToken,
+		Scope:           tokens.Scope,
+	}
+}
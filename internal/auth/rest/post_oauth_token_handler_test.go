@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/oauth"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeOAuth fails every token request with tokenErr.
+type fakeOAuth struct {
+	OAuthService
+
+	tokenErr error
+}
+
+func (o fakeOAuth) Token(context.Context, types.TokenRequest, string) (types.Tokens, error) {
+	if o.tokenErr != nil {
+		return types.Tokens{}, o.tokenErr
+	}
+	return types.Tokens{AccessToken: "access"}, nil
+}
+
+func (o fakeOAuth) CheckClient(_ context.Context, req types.AuthorizeRequest) (*types.OAuthClient, error) {
+	return &types.OAuthClient{ClientId: req.ClientId, Name: "Test app"}, nil
+}
+
+func (o fakeOAuth) CheckAuthorizeRequest(*types.OAuthClient, types.AuthorizeRequest) error {
+	return nil
+}
+
+func TestTokenHandlerDeviceCodeErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "authorization pending",
+			err:        service.ErrAuthorizationPending,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   oauth.ErrCodeAuthorizationPending,
+		},
+		{
+			name:       "slow down",
+			err:        service.ErrSlowDown,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   oauth.ErrCodeSlowDown,
+		},
+		{
+			name:       "expired device code",
+			err:        service.ErrExpiredToken,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   oauth.ErrCodeExpiredToken,
+		},
+		{
+			name:       "denied by the user",
+			err:        service.ErrAccessDenied,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   oauth.ErrCodeAccessDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&UseCase{OAuth: fakeOAuth{tokenErr: tt.err}}).Handler()
+
+			form := url.Values{
+				"grant_type":  {oauth.GrantTypeDeviceCode},
+				"client_id":   {"tv"},
+				"device_code": {"device-code"},
+			}
+			req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			var body oauth.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body %s: %v", rec.Body, err)
+			}
+			if body.Error != tt.wantCode {
+				t.Errorf("error = %q, want %q", body.Error, tt.wantCode)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type invitationInput struct {
+	Email   string   `json:"email" binding:"required,email,max=64"`
+	RoleIds []string `json:"role_ids" binding:"dive,uuid"`
+}
+
+type invitationResponse struct {
+	Id             string    `json:"id"`
+	OrganizationId string    `json:"organization_id"`
+	Email          string    `json:"email"`
+	RoleIds        []string  `json:"role_ids"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+func (h *Handler) InviteMemberHandler(c *gin.Context) {
+	var input invitationInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	var (
+		invitation *types.Invitation
+		err        error
+	)
+	if orgId := c.Param("org_id"); orgId != "" {
+		invitation, err = h.auth.Org.Invite(c.Request.Context(), orgId, identityFrom(c).UserId, input.Email, input.RoleIds)
+	} else {
+		invitation, err = h.auth.Org.InviteMember(c.Request.Context(), identityFrom(c), input.Email, input.RoleIds)
+	}
+	if err != nil {
+		logger.Errorf("failed to invite member: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrOrganizationNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrRoleNotFound), errors.Is(err, service.ErrRoleNotAssignable):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrPermissionDenied):
+			newResponse(c, http.StatusForbidden, err.Error())
+		default:
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitationResponse{
+		Id:             invitation.Id,
+		OrganizationId: invitation.OrganizationId,
+		Email:          invitation.Email,
+		RoleIds:        invitation.RoleIds,
+		ExpiresAt:      invitation.ExpiresAt,
+	})
+}
+
+type acceptInvitationInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *Handler) AcceptInvitationHandler(c *gin.Context) {
+	var input acceptInvitationInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	if identity.IsClient() {
+		newResponse(c, http.StatusForbidden, "user token required")
+		return
+	}
+
+	membership, err := h.auth.Org.AcceptInvitation(c.Request.Context(), identity.UserId, input.Token)
+	if err != nil {
+		logger.Errorf("failed to accept invitation (user: %s): %s", identity.UserId, err.Error())
+		switch {
+		case errors.Is(err, service.ErrInvalidInvitation):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrInvitationEmailMismatch):
+			newResponse(c, http.StatusForbidden, err.Error())
+		default:
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newMembershipResponse(*membership))
+}
@@ -0,0 +1,50 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type passwordChangeInput struct {
+	CurrentPassword     string `json:"current_password" binding:"required"`
+	NewPassword         string `json:"new_password" binding:"required,max=256"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+}
+
+func (h *Handler) ChangePasswordHandler(c *gin.Context) {
+	var input passwordChangeInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	err := h.auth.User.ChangePassword(c.Request.Context(), identity, input.CurrentPassword, input.NewPassword, input.RevokeOtherSessions)
+	if err != nil {
+		logger.Errorf("failed to change password (ip: %s, user: %s): %s", c.ClientIP(), identity.UserId, err.Error())
+		if newPasswordPolicyResponse(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrPasswordReused) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrInvalidPassword) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrExternalCredentials) {
+			newResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type passwordResetInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,max=256"`
+}
+
+func (h *Handler) ResetPasswordHandler(c *gin.Context) {
+	var input passwordResetInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	if err := h.auth.User.ResetPassword(c.Request.Context(), input.Token, input.Password); err != nil {
+		logger.Errorf("failed to reset password (ip: %s): %s", c.ClientIP(), err.Error())
+		if newPasswordPolicyResponse(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrPasswordReused) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
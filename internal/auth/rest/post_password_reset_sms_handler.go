@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type smsPasswordResetInput struct {
+	Email          string `json:"email" binding:"required,email,max=64"`
+	OrganizationId string `json:"organization_id"`
+}
+
+type smsPasswordResetConfirmInput struct {
+	RecoveryToken string `json:"recovery_token" binding:"required"`
+	Code          string `json:"code" binding:"required,max=16"`
+	Password      string `json:"password" binding:"required,max=256"`
+}
+
+type smsPasswordResetResponse struct {
+	RecoveryToken string    `json:"recovery_token"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// SMSPasswordResetHandler texts a password recovery code to the verified phone of the user
+// with the email. The response is the same whether the code was sent or not.
+func (h *Handler) SMSPasswordResetHandler(c *gin.Context) {
+	var input smsPasswordResetInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	challenge, err := h.auth.User.RequestPasswordRecovery(c.Request.Context(), input.Email, input.OrganizationId)
+	if err != nil {
+		logger.Errorf("failed to request password recovery (ip: %s, email: %s): %s", c.ClientIP(), input.Email, err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, smsPasswordResetResponse{
+		RecoveryToken: challenge.Token,
+		ExpiresAt:     challenge.ExpiresAt,
+	})
+}
+
+// ConfirmSMSPasswordResetHandler sets a new password with the code texted by
+// SMSPasswordResetHandler, every session of the user is revoked.
+func (h *Handler) ConfirmSMSPasswordResetHandler(c *gin.Context) {
+	var input smsPasswordResetConfirmInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	if err := h.auth.User.RecoverPassword(c.Request.Context(), input.RecoveryToken, input.Code, input.Password); err != nil {
+		logger.Errorf("failed to recover password (ip: %s): %s", c.ClientIP(), err.Error())
+		if newPasswordPolicyResponse(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrPasswordReused), errors.Is(err, service.ErrInvalidSMSCode):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		case isUserStatusError(err):
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
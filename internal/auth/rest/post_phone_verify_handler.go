@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type phoneVerifyInput struct {
+	VerificationToken string `json:"verification_token" binding:"required"`
+	Code              string `json:"code" binding:"required,max=16"`
+}
+
+// VerifyPhoneHandler verifies the phone of the caller with the code texted by SetPhoneHandler.
+func (h *Handler) VerifyPhoneHandler(c *gin.Context) {
+	var input phoneVerifyInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	if err := h.auth.User.VerifyPhone(c.Request.Context(), identity, input.VerificationToken, input.Code); err != nil {
+		logger.Errorf("failed to verify phone (ip: %s, user: %s): %s", c.ClientIP(), identity.UserId, err.Error())
+		if errors.Is(err, service.ErrInvalidSMSCode) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
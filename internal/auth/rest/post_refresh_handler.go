@@ -35,9 +35,12 @@ func (h *Handler) RefreshTokensHandler(c *gin.Context) {
 		case errors.Is(err, service.ErrRefreshTokenAlreadyUsed):
 			newResponse(c, http.StatusBadRequest, err.Error())
 			return
-		case errors.Is(err, service.ErrRefreshTokenExpired):
+		case errors.Is(err, service.ErrRefreshTokenExpired), errors.Is(err, service.ErrSessionRevoked):
 			newResponse(c, http.StatusUnauthorized, err.Error())
 			return
+		case isUserStatusError(err), errors.Is(err, service.ErrRefreshBlocked):
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
 		}
 
 		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
@@ -10,8 +10,9 @@ import (
 )
 
 type userSignIn struct {
-	Email    string `json:"email" binding:"required,email,max=64"`
-	Password string `json:"password" binding:"required,min=6,max=64"`
+	Email          string `json:"email" binding:"required,email,max=64"`
+	Password       string `json:"password" binding:"required,min=6,max=64"`
+	OrganizationId string `json:"organization_id" binding:"omitempty,uuid"`
 }
 
 type responseToken struct {
@@ -29,15 +30,27 @@ func (h *Handler) SignInHandler(c *gin.Context) {
 
 	ip := c.ClientIP()
 	tokens, err := h.auth.User.SingIn(c.Request.Context(), types.UserDTO{
-		Email:    input.Email,
-		Password: input.Password,
+		Email:          input.Email,
+		Password:       input.Password,
+		OrganizationId: input.OrganizationId,
 	}, ip)
 	if err != nil {
 		logger.Errorf("failed to sign in: (ip: %s, email: %s): %s", ip, input.Email, err.Error())
+		if newMFAChallengeResponse(c, err) {
+			return
+		}
 		if errors.Is(err, service.ErrUserNotFound) {
 			newResponse(c, http.StatusBadRequest, err.Error())
 			return
 		}
+		if errors.Is(err, service.ErrNotOrganizationMember) ||
+			isUserStatusError(err) ||
+			errors.Is(err, service.ErrPasswordResetRequired) ||
+			errors.Is(err, service.ErrSignInBlocked) ||
+			errors.Is(err, service.ErrNotDirectoryAccount) {
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
 
 		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
 		return
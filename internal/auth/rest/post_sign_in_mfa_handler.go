@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type signInMFA struct {
+	MFAToken       string `json:"mfa_token" binding:"required,max=128"`
+	Code           string `json:"code" binding:"required,numeric,max=16"`
+	RememberDevice bool   `json:"remember_device"`
+}
+
+func (h *Handler) SignInMFAHandler(c *gin.Context) {
+	var input signInMFA
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	ip := c.ClientIP()
+	tokens, err := h.auth.User.VerifySignIn(c.Request.Context(), input.MFAToken, input.Code, ip, input.RememberDevice)
+	if err != nil {
+		logger.Errorf("failed to verify sign in (ip: %s): %s", ip, err.Error())
+		switch {
+		case errors.Is(err, service.ErrInvalidMFACode):
+			newResponse(c, http.StatusUnauthorized, err.Error())
+			return
+		case errors.Is(err, service.ErrUserNotFound):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		case isUserStatusError(err):
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, responseToken{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
@@ -0,0 +1,31 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+// SignOutHandler revokes the session of the access token.
+func (h *Handler) SignOutHandler(c *gin.Context) {
+	identity := identityFrom(c)
+	if identity.IsClient() || identity.SessionId == "" {
+		newResponse(c, http.StatusForbidden, "the token is not bound to a session")
+		return
+	}
+
+	if err := h.auth.User.SignOut(c.Request.Context(), identity); err != nil {
+		logger.Errorf("failed to sign out (ip: %s, user: %s): %s", c.ClientIP(), identity.UserId, err.Error())
+		if errors.Is(err, service.ErrSessionNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
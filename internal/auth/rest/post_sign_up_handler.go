@@ -10,8 +10,9 @@ import (
 )
 
 type userSignUp struct {
-	Email    string `json:"email" binding:"required,email,max=64"`
-	Password string `json:"password" binding:"required,min=6,max=64"`
+	Email          string `json:"email" binding:"required,email,max=64"`
+	Password       string `json:"password" binding:"required,max=256"`
+	OrganizationId string `json:"organization_id" binding:"omitempty,uuid"`
 }
 
 func (h *Handler) SignUpHandler(c *gin.Context) {
@@ -23,14 +24,22 @@ func (h *Handler) SignUpHandler(c *gin.Context) {
 	}
 
 	if err := h.auth.User.SignUp(c.Request.Context(), types.UserDTO{
-		Email:    input.Email,
-		Password: input.Password,
+		Email:          input.Email,
+		Password:       input.Password,
+		OrganizationId: input.OrganizationId,
 	}); err != nil {
 		logger.Errorf("failed to sign up: %s", err.Error())
-		if errors.Is(err, service.ErrUserAlreadyExists) {
+		if newPasswordPolicyResponse(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrUserAlreadyExists) || errors.Is(err, service.ErrOrganizationNotFound) {
 			newResponse(c, http.StatusBadRequest, err.Error())
 			return
 		}
+		if errors.Is(err, service.ErrExternalCredentials) {
+			newResponse(c, http.StatusConflict, err.Error())
+			return
+		}
 
 		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
 		return
@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type socialLink struct {
+	LinkToken string `json:"link_token" binding:"required,max=128"`
+	Password  string `json:"password" binding:"required"`
+}
+
+func (h *Handler) SocialLinkHandler(c *gin.Context) {
+	var input socialLink
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	ip := c.ClientIP()
+	tokens, err := h.auth.Social.ConfirmLink(c.Request.Context(), input.LinkToken, input.Password, ip)
+	if err != nil {
+		logger.Errorf("failed to confirm social link (ip: %s): %s", ip, err.Error())
+		if newMFAChallengeResponse(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrInvalidLinkToken), errors.Is(err, service.ErrInvalidPassword):
+			newResponse(c, http.StatusUnauthorized, err.Error())
+			return
+		case errors.Is(err, service.ErrExternalCredentials):
+			newResponse(c, http.StatusConflict, err.Error())
+			return
+		case errors.Is(err, service.ErrUserNotFound):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		case isUserStatusError(err), errors.Is(err, service.ErrPasswordResetRequired), errors.Is(err, service.ErrSignInBlocked):
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, responseToken{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
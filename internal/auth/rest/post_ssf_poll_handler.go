@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/ssf"
+	"net/http"
+)
+
+// PollEventsHandler acknowledges the tokens of the calling receiver and returns its next
+// pending tokens (RFC 8936).
+func (h *Handler) PollEventsHandler(c *gin.Context) {
+	identity := identityFrom(c)
+
+	var input ssf.PollRequest
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	resp, err := h.auth.Signals.Poll(c.Request.Context(), identity.ClientId, input)
+	if err != nil {
+		logger.Errorf("failed to poll security events (client: %s): %s", identity.ClientId, err.Error())
+		switch {
+		case errors.Is(err, service.ErrSignalStreamNotPolled):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrSignalStreamNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+		default:
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
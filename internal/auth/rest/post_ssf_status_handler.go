@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type streamStatusInput struct {
+	Id     string `json:"stream_id"`
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// UpdateStreamStatusHandler enables, pauses or disables the stream of the calling receiver.
+func (h *Handler) UpdateStreamStatusHandler(c *gin.Context) {
+	identity := identityFrom(c)
+
+	var input streamStatusInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	stream, err := h.auth.Signals.SetStatus(c.Request.Context(), identity.ClientId, input.Id, input.Status, input.Reason)
+	if err != nil {
+		logger.Errorf("failed to update stream status (client: %s): %s", identity.ClientId, err.Error())
+		switch {
+		case errors.Is(err, service.ErrInvalidSignalStatus):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrSignalStreamNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+		default:
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newStreamStatusResponse(*stream))
+}
@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type streamDeliveryInput struct {
+	Method              *string `json:"method"`
+	EndpointURL         *string `json:"endpoint_url" binding:"omitempty,max=2048"`
+	AuthorizationHeader *string `json:"authorization_header" binding:"omitempty,max=2048"`
+}
+
+type streamInput struct {
+	Id              string               `json:"stream_id"`
+	Delivery        *streamDeliveryInput `json:"delivery"`
+	EventsRequested []string             `json:"events_requested"`
+	Description     *string              `json:"description" binding:"omitempty,max=255"`
+}
+
+func (in streamInput) toTypes() types.SignalStreamDTO {
+	dto := types.SignalStreamDTO{
+		Id:              in.Id,
+		EventsRequested: in.EventsRequested,
+		Description:     in.Description,
+	}
+	if in.Delivery != nil {
+		dto.DeliveryMethod = in.Delivery.Method
+		dto.EndpointURL = in.Delivery.EndpointURL
+		dto.AuthorizationHeader = in.Delivery.AuthorizationHeader
+	}
+	return dto
+}
+
+// CreateStreamHandler creates the stream of the calling receiver.
+func (h *Handler) CreateStreamHandler(c *gin.Context) {
+	identity := identityFrom(c)
+
+	var input streamInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	stream, err := h.auth.Signals.CreateStream(c.Request.Context(), identity.ClientId, input.toTypes())
+	if err != nil {
+		logger.Errorf("failed to create stream (client: %s): %s", identity.ClientId, err.Error())
+		switch {
+		case isStreamInputError(err):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrSignalStreamExists):
+			newResponse(c, http.StatusConflict, err.Error())
+		default:
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, h.newStreamResponse(*stream))
+}
+
+func isStreamInputError(err error) bool {
+	return errors.Is(err, service.ErrInvalidDeliveryMethod) ||
+		errors.Is(err, service.ErrInvalidSignalEndpoint)
+}
@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type streamVerificationInput struct {
+	Id    string `json:"stream_id"`
+	State string `json:"state" binding:"max=255"`
+}
+
+// VerifyStreamHandler queues a verification event for the stream of the calling receiver.
+func (h *Handler) VerifyStreamHandler(c *gin.Context) {
+	identity := identityFrom(c)
+
+	var input streamVerificationInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	if err := h.auth.Signals.Verify(c.Request.Context(), identity.ClientId, input.Id, input.State); err != nil {
+		logger.Errorf("failed to verify stream (client: %s): %s", identity.ClientId, err.Error())
+		if errors.Is(err, service.ErrSignalStreamNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type switchOrganizationInput struct {
+	OrganizationId string `json:"organization_id" binding:"omitempty,uuid"`
+}
+
+// SwitchOrganizationHandler issues a new token pair for another organization of the
+// user. An empty organization_id issues tokens without an organization.
+func (h *Handler) SwitchOrganizationHandler(c *gin.Context) {
+	var input switchOrganizationInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	ip := c.ClientIP()
+	tokens, err := h.auth.User.CreateSession(c.Request.Context(), identity.UserId, input.OrganizationId, ip)
+	if err != nil {
+		logger.Errorf("failed to switch organization (ip: %s, user: %s): %s", ip, identity.UserId, err.Error())
+		if errors.Is(err, service.ErrNotOrganizationMember) {
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, responseToken{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
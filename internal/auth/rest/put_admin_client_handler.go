@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+func (h *Handler) UpdateClientHandler(c *gin.Context) {
+	var input clientInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+	input.ClientId = c.Param("client_id")
+
+	client, err := h.auth.Client.Update(c.Request.Context(), input.toTypes())
+	if err != nil {
+		logger.Errorf("failed to update client: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrClientNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		case isClientInputError(err):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newClientResponse(*client))
+}
@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+func (h *Handler) UpdateRoleHandler(c *gin.Context) {
+	var input roleInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	dto := input.toTypes()
+	dto.Id = c.Param("role_id")
+
+	role, err := h.auth.RBAC.UpdateRole(c.Request.Context(), dto)
+	if err != nil {
+		logger.Errorf("failed to update role: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrRoleNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrUnknownPermission), errors.Is(err, service.ErrRoleAlreadyExists):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleResponse(*role))
+}
@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+func (h *Handler) UpdateWebhookHandler(c *gin.Context) {
+	var input webhookInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+	input.Id = c.Param("webhook_id")
+
+	subscription, err := h.auth.Webhooks.Update(c.Request.Context(), input.toTypes())
+	if err != nil {
+		logger.Errorf("failed to update webhook: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrWebhookNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		case isWebhookInputError(err):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newWebhookResponse(*subscription))
+}
@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type notificationPreferenceInput struct {
+	Event    string   `json:"event" binding:"required,max=64"`
+	Channels []string `json:"channels" binding:"max=8"`
+	Delivery string   `json:"delivery" binding:"omitempty,oneof=immediate digest"`
+}
+
+type notificationPreferencesInput struct {
+	Preferences []notificationPreferenceInput `json:"preferences" binding:"required,min=1,max=32,dive"`
+}
+
+// UpdateNotificationPreferencesHandler changes how the caller is notified about the listed
+// events, the other events keep their preferences.
+func (h *Handler) UpdateNotificationPreferencesHandler(c *gin.Context) {
+	var input notificationPreferencesInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	changes := make([]types.NotificationPreference, 0, len(input.Preferences))
+	for _, preference := range input.Preferences {
+		changes = append(changes, types.NotificationPreference{
+			Event:    preference.Event,
+			Channels: preference.Channels,
+			Delivery: preference.Delivery,
+		})
+	}
+
+	preferences, err := h.auth.Notify.UpdatePreferences(c.Request.Context(), identity, changes)
+	if err != nil {
+		logger.Errorf("failed to update notification preferences (user: %s): %s", identity.UserId, err.Error())
+		switch {
+		case errors.Is(err, service.ErrUnknownNotification),
+			errors.Is(err, service.ErrInvalidNotificationOption),
+			errors.Is(err, service.ErrCriticalNotification):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	timeZone, err := h.auth.Notify.TimeZone(c.Request.Context(), identity)
+	if err != nil {
+		logger.Errorf("failed to get time zone (user: %s): %s", identity.UserId, err.Error())
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.JSON(http.StatusOK, newNotificationPreferencesResponse(preferences, timeZone))
+}
@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type timeZoneInput struct {
+	TimeZone string `json:"time_zone" binding:"required,max=64"`
+}
+
+// SetTimeZoneHandler sets the time zone the weekly summary of the caller is scheduled and
+// shown in.
+func (h *Handler) SetTimeZoneHandler(c *gin.Context) {
+	var input timeZoneInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	if err := h.auth.Notify.SetTimeZone(c.Request.Context(), identity, input.TimeZone); err != nil {
+		logger.Errorf("failed to set time zone (user: %s): %s", identity.UserId, err.Error())
+		if errors.Is(err, service.ErrInvalidTimeZone) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
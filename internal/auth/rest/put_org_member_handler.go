@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+)
+
+type memberInput struct {
+	RoleIds []string `json:"role_ids" binding:"dive,uuid"`
+}
+
+// SetMemberHandler adds a user to the organization or replaces the user's roles in it.
+// In the current organization only roles of existing members can be changed.
+func (h *Handler) SetMemberHandler(c *gin.Context) {
+	var input memberInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	var err error
+	if orgId := c.Param("org_id"); orgId != "" {
+		err = h.auth.Org.SetMember(c.Request.Context(), orgId, c.Param("user_id"), input.RoleIds)
+	} else {
+		err = h.auth.Org.UpdateMember(c.Request.Context(), identityFrom(c), c.Param("user_id"), input.RoleIds)
+	}
+	if err != nil {
+		logger.Errorf("failed to set member: %s", err.Error())
+		switch {
+		case errors.Is(err, service.ErrOrganizationNotFound), errors.Is(err, service.ErrUserNotFound),
+			errors.Is(err, service.ErrNotOrganizationMember):
+			newResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrRoleNotFound), errors.Is(err, service.ErrRoleNotAssignable):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrPermissionDenied):
+			newResponse(c, http.StatusForbidden, err.Error())
+		default:
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type phoneInput struct {
+	Password  string `json:"password" binding:"required"`
+	Phone     string `json:"phone" binding:"required,max=32"`
+	UseForMFA *bool  `json:"use_for_mfa"`
+}
+
+type phoneVerificationResponse struct {
+	VerificationToken string    `json:"verification_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// SetPhoneHandler sets the phone of the caller and texts it a code. The phone is verified
+// after the code is confirmed with VerifyPhoneHandler, a verified phone is replaced only then.
+func (h *Handler) SetPhoneHandler(c *gin.Context) {
+	var input phoneInput
+	if err := c.BindJSON(&input); err != nil {
+		logger.Errorf("failed to decode request body: %s", err.Error())
+		newResponse(c, http.StatusBadRequest, "invalid body request")
+		return
+	}
+
+	identity := identityFrom(c)
+	useForMFA := true
+	if input.UseForMFA != nil {
+		useForMFA = *input.UseForMFA
+	}
+
+	challenge, err := h.auth.User.SetPhone(c.Request.Context(), identity, input.Password, input.Phone, useForMFA)
+	if err != nil {
+		logger.Errorf("failed to set phone (ip: %s, user: %s): %s", c.ClientIP(), identity.UserId, err.Error())
+		switch {
+		case errors.Is(err, service.ErrInvalidPhone), errors.Is(err, service.ErrInvalidPassword):
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrExternalCredentials):
+			newResponse(c, http.StatusConflict, err.Error())
+			return
+		case errors.Is(err, service.ErrTooManySMSCodes):
+			newResponse(c, http.StatusTooManyRequests, err.Error())
+			return
+		case errors.Is(err, service.ErrChannelUnavailable):
+			newResponse(c, http.StatusServiceUnavailable, "sms is not available")
+			return
+		}
+
+		newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+		return
+	}
+
+	if challenge == nil {
+		phone, err := h.auth.User.Phone(c.Request.Context(), identity)
+		if err != nil {
+			logger.Errorf("failed to get phone (user: %s): %s", identity.UserId, err.Error())
+			newResponse(c, http.StatusInternalServerError, "Something went wrong. Try again later!")
+			return
+		}
+
+		c.JSON(http.StatusOK, newPhoneResponse(phone))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, phoneVerificationResponse{
+		VerificationToken: challenge.Token,
+		ExpiresAt:         challenge.ExpiresAt,
+	})
+}
@@ -1,11 +1,37 @@
 package rest
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"medods-test/internal/auth/service"
+	"medods-test/pkg/oauth"
+	"medods-test/pkg/passwordpolicy"
+	"net/http"
+	"time"
+)
 
 type response struct {
 	Message string `json:"message"`
 }
 
+type passwordPolicyResponse struct {
+	Message    string                     `json:"message"`
+	Violations []passwordpolicy.Violation `json:"violations"`
+}
+
+type mfaChallengeResponse struct {
+	Message   string    `json:"message"`
+	MFAToken  string    `json:"mfa_token"`
+	Channel   string    `json:"channel"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type linkConfirmationResponse struct {
+	Message   string    `json:"message"`
+	LinkToken string    `json:"link_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 func newResponse(c *gin.Context, statusCode int, msg string) {
 	c.AbortWithStatusJSON(
 		statusCode,
@@ -13,3 +39,66 @@ func newResponse(c *gin.Context, statusCode int, msg string) {
 			msg,
 		})
 }
+
+// newPasswordPolicyResponse responds with the violated password rules if err is a
+// *passwordpolicy.PolicyError and reports whether it did.
+func newPasswordPolicyResponse(c *gin.Context, err error) bool {
+	var policyErr *passwordpolicy.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	c.AbortWithStatusJSON(
+		http.StatusBadRequest,
+		passwordPolicyResponse{
+			Message:    "password does not satisfy the policy",
+			Violations: policyErr.Violations,
+		})
+	return true
+}
+
+// newMFAChallengeResponse responds with the MFA challenge to complete the sign-in at
+// /auth/sign-in/mfa if err is a *service.MFAChallengeError and reports whether it did.
+func newMFAChallengeResponse(c *gin.Context, err error) bool {
+	var challengeErr *service.MFAChallengeError
+	if !errors.As(err, &challengeErr) {
+		return false
+	}
+
+	c.AbortWithStatusJSON(
+		http.StatusUnauthorized,
+		mfaChallengeResponse{
+			Message:   challengeErr.Error(),
+			MFAToken:  challengeErr.Token,
+			Channel:   challengeErr.Channel,
+			ExpiresAt: challengeErr.ExpiresAt,
+		})
+	return true
+}
+
+// newLinkConfirmationResponse responds with the link request to confirm at /auth/social/link
+// if err is a *service.LinkConfirmationError and reports whether it did.
+func newLinkConfirmationResponse(c *gin.Context, err error) bool {
+	var linkErr *service.LinkConfirmationError
+	if !errors.As(err, &linkErr) {
+		return false
+	}
+
+	c.AbortWithStatusJSON(
+		http.StatusConflict,
+		linkConfirmationResponse{
+			Message:   linkErr.Error(),
+			LinkToken: linkErr.Token,
+			ExpiresAt: linkErr.ExpiresAt,
+		})
+	return true
+}
+
+func newOAuthErrorResponse(c *gin.Context, statusCode int, code string, description string) {
+	c.AbortWithStatusJSON(
+		statusCode,
+		oauth.ErrorResponse{
+			Error:            code,
+			ErrorDescription: description,
+		})
+}
@@ -0,0 +1,145 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"medods-test/internal/auth/service"
+	"medods-test/internal/auth/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDeviceId = "0123456789abcdef0123456789abcdef"
+
+// fakeSocial starts logins with a fixed state and completes callbacks with callbackErr.
+type fakeSocial struct {
+	SocialService
+
+	state       string
+	callbackErr error
+	callbacks   int
+}
+
+func (s *fakeSocial) LoginURL(context.Context, string) (types.SocialLogin, error) {
+	return types.SocialLogin{
+		URL:       "https://provider.example.com/authorize?state=" + s.state,
+		State:     s.state,
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	}, nil
+}
+
+func (s *fakeSocial) Callback(context.Context, string, string, string, string) (types.Tokens, error) {
+	s.callbacks++
+	if s.callbackErr != nil {
+		return types.Tokens{}, s.callbackErr
+	}
+	return types.Tokens{AccessToken: "access", RefreshToken: "refresh"}, nil
+}
+
+func newSocialTestHandler(social *fakeSocial) http.Handler {
+	return New(&UseCase{Social: social}).Handler()
+}
+
+func socialRequest(method, target string, cookies ...*http.Cookie) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set(deviceIdHeader, testDeviceId)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+func TestSocialLoginSetsStateCookie(t *testing.T) {
+	handler := newSocialTestHandler(&fakeSocial{state: "state-1"})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, socialRequest(http.MethodGet, "/auth/social/fake"))
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == socialStateCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatalf("no %s cookie set", socialStateCookieName)
+	}
+	if cookie.Value != "state-1" || !cookie.HttpOnly || cookie.Path != socialStateCookiePath || cookie.MaxAge <= 0 {
+		t.Errorf("cookie = %+v, want HttpOnly state-1 at %s with a max age", cookie, socialStateCookiePath)
+	}
+}
+
+func TestSocialCallbackState(t *testing.T) {
+	tests := []struct {
+		name          string
+		cookie        *http.Cookie
+		callbackErr   error
+		wantStatus    int
+		wantCallbacks int
+		wantLinkToken string
+	}{
+		{
+			name:       "no cookie",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "cookie of another login",
+			cookie:     &http.Cookie{Name: socialStateCookieName, Value: "state-2"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:          "cookie of this login",
+			cookie:        &http.Cookie{Name: socialStateCookieName, Value: "state-1"},
+			wantStatus:    http.StatusOK,
+			wantCallbacks: 1,
+		},
+		{
+			name:          "existing user has to confirm the link",
+			cookie:        &http.Cookie{Name: socialStateCookieName, Value: "state-1"},
+			callbackErr:   &service.LinkConfirmationError{Token: "link-token", ExpiresAt: time.Now().Add(time.Minute)},
+			wantStatus:    http.StatusConflict,
+			wantCallbacks: 1,
+			wantLinkToken: "link-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			social := &fakeSocial{state: "state-1", callbackErr: tt.callbackErr}
+			handler := newSocialTestHandler(social)
+
+			var cookies []*http.Cookie
+			if tt.cookie != nil {
+				cookies = append(cookies, tt.cookie)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, socialRequest(http.MethodGet, "/auth/social/fake/callback?state=state-1&code=code", cookies...))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if social.callbacks != tt.wantCallbacks {
+				t.Errorf("Callback() called %d times, want %d", social.callbacks, tt.wantCallbacks)
+			}
+			if !strings.Contains(rec.Header().Get("Set-Cookie"), socialStateCookieName+"=;") {
+				t.Errorf("Set-Cookie = %q, want the state cookie cleared", rec.Header().Get("Set-Cookie"))
+			}
+
+			if tt.wantLinkToken != "" {
+				var body linkConfirmationResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if body.LinkToken != tt.wantLinkToken {
+					t.Errorf("link_token = %q, want %q", body.LinkToken, tt.wantLinkToken)
+				}
+			}
+		})
+	}
+}
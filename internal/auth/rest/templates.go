@@ -0,0 +1,11 @@
+package rest
+
+import (
+	"embed"
+	"html/template"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
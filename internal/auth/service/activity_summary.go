@@ -0,0 +1,306 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/useragent"
+	"strings"
+	"time"
+)
+
+const (
+	summaryBatchSize = 100
+	summaryPeriod    = 7 * 24 * time.Hour
+	// maxSummarySignIns is how many of the most recent sign-ins are listed in a summary.
+	maxSummarySignIns = 20
+)
+
+var ErrInvalidTimeZone = errors.New("time zone must be an IANA time zone name, e.g. Europe/Moscow")
+
+// ActivitySummaryConfig is when activity summaries are sent in the time zone of each user.
+type ActivitySummaryConfig struct {
+	Weekday time.Weekday
+	Hour    int
+}
+
+// ActivitySummaries sends users a weekly summary of their sign-ins and active sessions.
+type ActivitySummaries struct {
+	notifications    *Notifications
+	notificationrepo NotificationRepo
+	userrepo         UserRepo
+	sessionrepo      SessionRepo
+	auditrepo        AuditRepo
+	config           ActivitySummaryConfig
+}
+
+// TimeZone returns the time zone of the user of identity, UTC if the user has not chosen one.
+func (n *Notifications) TimeZone(ctx context.Context, identity types.Identity) (string, error) {
+	timeZone, err := n.notificationrepo.GetTimeZone(ctx, identity.UserId)
+	if err != nil {
+		logger.Errorf("failed to get time zone: %s", err)
+		return "", err
+	}
+	if timeZone == "" {
+		timeZone = time.UTC.String()
+	}
+	return timeZone, nil
+}
+
+// SetTimeZone sets the time zone the summaries of the user of identity are scheduled and
+// shown in.
+func (n *Notifications) SetTimeZone(ctx context.Context, identity types.Identity, timeZone string) error {
+	if timeZone == "" || timeZone == "Local" {
+		return ErrInvalidTimeZone
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return ErrInvalidTimeZone
+	}
+
+	if err := n.notificationrepo.SaveTimeZone(ctx, identity.UserId, timeZone); err != nil {
+		logger.Errorf("failed to save time zone: %s", err)
+		return err
+	}
+
+	n.audit.Record(ctx, types.AuditNotificationsChanged, identity.UserId, map[string]string{"time_zone": timeZone})
+	return nil
+}
+
+// SendSummaries sends the summaries that are due and returns how many were sent. Users
+// without a schedule are scheduled for the next summary time in their time zone.
+func (a *ActivitySummaries) SendSummaries(ctx context.Context) (int, error) {
+	var sent int
+	for {
+		now := time.Now().UTC()
+		schedules, err := a.notificationrepo.ListDueSummaries(ctx, now, summaryBatchSize)
+		if err != nil {
+			logger.Errorf("failed to list due activity summaries: %s", err)
+			return sent, err
+		}
+
+		var claimed int
+		for _, schedule := range schedules {
+			location, err := time.LoadLocation(schedule.TimeZone)
+			if err != nil {
+				logger.Errorf("failed to load time zone %s (user: %s): %s", schedule.TimeZone, schedule.UserId, err)
+				location = time.UTC
+			}
+
+			next := a.nextSummaryAt(now, location)
+			ok, err := a.notificationrepo.ScheduleSummary(ctx, schedule.UserId, schedule.NextAt, next)
+			if err != nil {
+				logger.Errorf("failed to schedule activity summary: %s", err)
+				return sent, err
+			}
+			// Scheduled by a concurrent sender.
+			if !ok {
+				continue
+			}
+			claimed++
+			if schedule.NextAt == nil {
+				continue
+			}
+
+			ok, err = a.sendSummary(ctx, schedule.UserId, now, location)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+
+		if len(schedules) < summaryBatchSize || claimed == 0 {
+			return sent, nil
+		}
+	}
+}
+
+// RunSummaries sends due summaries every interval until ctx is done.
+func (a *ActivitySummaries) RunSummaries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = a.SendSummaries(ctx)
+		}
+	}
+}
+
+// sendSummary sends the user the summary of the period ending at now, unless the user has
+// turned summaries off or there was no activity, and reports whether it was sent.
+func (a *ActivitySummaries) sendSummary(ctx context.Context, userId string, now time.Time, location *time.Location) (bool, error) {
+	user, err := a.userrepo.GetUserByID(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get user by id: %s", err)
+		return false, err
+	}
+	if user == nil {
+		return false, nil
+	}
+
+	preferences, err := a.notifications.preferences(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	var channels []string
+	for _, preference := range preferences {
+		if preference.Event == types.NotificationWeeklySummary {
+			channels = preference.Channels
+		}
+	}
+	if len(channels) == 0 {
+		return false, nil
+	}
+
+	summary, err := a.compileSummary(ctx, userId, now.Add(-summaryPeriod), now)
+	if err != nil {
+		return false, err
+	}
+	if summary.TotalSignIns == 0 && summary.FailedSignIns == 0 && len(summary.ActiveSessions) == 0 {
+		return false, nil
+	}
+
+	body, err := summaryEmail(summary, location)
+	if err != nil {
+		logger.Errorf("failed to render activity summary: %s", err)
+		return false, err
+	}
+	for _, channel := range channels {
+		a.notifications.send(ctx, user, channel, types.ChannelMessage{
+			Subject: "Активность учётной записи за неделю",
+			HTML:    body,
+		})
+	}
+	return true, nil
+}
+
+func (a *ActivitySummaries) compileSummary(ctx context.Context, userId string, from, to time.Time) (types.ActivitySummary, error) {
+	summary := types.ActivitySummary{
+		From: from,
+		To:   to,
+	}
+
+	signIns, total, err := a.auditrepo.ListEvents(ctx, types.AuditFilter{
+		Type:   types.AuditUserSignedIn,
+		UserId: userId,
+		From:   from,
+		To:     to,
+		Limit:  maxSummarySignIns,
+	})
+	if err != nil {
+		logger.Errorf("failed to list sign-ins: %s", err)
+		return summary, err
+	}
+	summary.SignIns = signIns
+	summary.TotalSignIns = total
+
+	_, summary.FailedSignIns, err = a.auditrepo.ListEvents(ctx, types.AuditFilter{
+		Type:   types.AuditUserSignInFailed,
+		UserId: userId,
+		From:   from,
+		To:     to,
+	})
+	if err != nil {
+		logger.Errorf("failed to count failed sign-ins: %s", err)
+		return summary, err
+	}
+
+	summary.ActiveSessions, err = a.sessionrepo.ListActiveSessions(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list active sessions: %s", err)
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+// nextSummaryAt returns the first summary time after now in location, in UTC.
+func (a *ActivitySummaries) nextSummaryAt(now time.Time, location *time.Location) time.Time {
+	local := now.In(location)
+	days := (int(a.config.Weekday) - int(local.Weekday()) + 7) % 7
+	next := time.Date(local.Year(), local.Month(), local.Day()+days, a.config.Hour, 0, 0, 0, location)
+	if !next.After(now) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+days+7, a.config.Hour, 0, 0, 0, location)
+	}
+	return next.UTC()
+}
+
+type summarySignIn struct {
+	Time     string
+	IP       string
+	Location string
+	Method   string
+	Device   string
+}
+
+type summarySession struct {
+	CreatedAt string
+	IP        string
+	Location  string
+	Client    string
+}
+
+type summaryData struct {
+	From           string
+	To             string
+	TimeZone       string
+	TotalSignIns   int
+	FailedSignIns  int
+	SignIns        []summarySignIn
+	MoreSignIns    int
+	ActiveSessions []summarySession
+}
+
+func summaryEmail(summary types.ActivitySummary, location *time.Location) (string, error) {
+	const layout = "02.01.2006 15:04"
+
+	data := summaryData{
+		From:          summary.From.In(location).Format(layout),
+		To:            summary.To.In(location).Format(layout),
+		TimeZone:      location.String(),
+		TotalSignIns:  summary.TotalSignIns,
+		FailedSignIns: summary.FailedSignIns,
+		MoreSignIns:   summary.TotalSignIns - len(summary.SignIns),
+	}
+	for _, event := range summary.SignIns {
+		data.SignIns = append(data.SignIns, summarySignIn{
+			Time:     event.CreatedAt.In(location).Format(layout),
+			IP:       event.IP,
+			Location: joinLocation(event.Metadata["city"], event.Metadata["country"]),
+			Method:   event.Metadata["method"],
+			Device:   useragent.Describe(event.UserAgent),
+		})
+	}
+	for _, session := range summary.ActiveSessions {
+		data.ActiveSessions = append(data.ActiveSessions, summarySession{
+			CreatedAt: session.CreatedAt.In(location).Format(layout),
+			IP:        session.IP,
+			Location:  joinLocation(session.City, session.Country),
+			Client:    session.ClientId,
+		})
+	}
+
+	var body bytes.Buffer
+	if err := emailTemplates.ExecuteTemplate(&body, "activity_summary.html", data); err != nil {
+		return "", fmt.Errorf("execute activity summary template: %w", err)
+	}
+	return body.String(), nil
+}
+
+func joinLocation(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+const carolUserId = "7d8e9f0a-1b2c-4d3e-8f4a-5b6c7d8e9f0a"
+
+type summaryTest struct {
+	*notificationTest
+	summaries *ActivitySummaries
+	sessions  *fakeSessionRepo
+}
+
+// newSummaryTest returns ActivitySummaries sent on Mondays at 9:00 to the active users of
+// testUserId and otherUserId. The user of carolUserId is disabled.
+func newSummaryTest() *summaryTest {
+	test := &summaryTest{notificationTest: newNotificationTest(), sessions: &fakeSessionRepo{}}
+	users := newFakeUserRepo(
+		test.user,
+		types.User{UserUUID: otherUserId, Email: "bob@example.com", Status: types.UserStatusActive},
+		types.User{UserUUID: carolUserId, Email: "carol@example.com", Status: types.UserStatusDisabled},
+	)
+	test.repo.userrepo = users
+	test.summaries = &ActivitySummaries{
+		notifications:    test.notifications,
+		notificationrepo: test.repo,
+		userrepo:         users,
+		sessionrepo:      test.sessions,
+		auditrepo:        test.auditrepo,
+		config:           ActivitySummaryConfig{Weekday: time.Monday, Hour: 9},
+	}
+	return test
+}
+
+// recordSignIn appends a sign-in event of the user at createdAt.
+func (s *summaryTest) recordSignIn(eventType string, userId string, createdAt time.Time) {
+	_, _ = s.auditrepo.AppendEvent(context.Background(), types.AuditEvent{
+		Type:      eventType,
+		UserId:    userId,
+		IP:        "203.0.113.7",
+		UserAgent: testUserAgent,
+		Metadata:  map[string]string{"method": types.LoginMethodPassword, "city": "Moscow", "country": "RU"},
+		CreatedAt: createdAt,
+	})
+}
+
+func TestNextSummaryAt(t *testing.T) {
+	summaries := &ActivitySummaries{config: ActivitySummaryConfig{Weekday: time.Monday, Hour: 9}}
+
+	tests := []struct {
+		name     string
+		now      string
+		timeZone string
+		want     string
+	}{
+		{name: "later this week", now: "2024-05-05T12:00:00Z", timeZone: "Europe/Moscow", want: "2024-05-06T06:00:00Z"},
+		{name: "later today", now: "2024-05-06T05:59:00Z", timeZone: "Europe/Moscow", want: "2024-05-06T06:00:00Z"},
+		{name: "exactly at the summary time", now: "2024-05-06T06:00:00Z", timeZone: "Europe/Moscow", want: "2024-05-13T06:00:00Z"},
+		{name: "already monday in the time zone", now: "2024-05-05T23:30:00Z", timeZone: "Asia/Tokyo", want: "2024-05-06T00:00:00Z"},
+		{name: "still sunday in utc", now: "2024-05-05T23:30:00Z", timeZone: "UTC", want: "2024-05-06T09:00:00Z"},
+		{name: "behind utc", now: "2024-05-06T15:00:00Z", timeZone: "America/Los_Angeles", want: "2024-05-06T16:00:00Z"},
+		{name: "across the daylight saving change", now: "2024-03-05T12:00:00Z", timeZone: "America/New_York", want: "2024-03-11T13:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, tt.now)
+			location, err := time.LoadLocation(tt.timeZone)
+			if err != nil {
+				t.Fatalf("LoadLocation() error = %v", err)
+			}
+
+			got := summaries.nextSummaryAt(now, location)
+			if got.Format(time.RFC3339) != tt.want {
+				t.Errorf("nextSummaryAt() = %s, want %s", got.Format(time.RFC3339), tt.want)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("nextSummaryAt() location = %s, want UTC", got.Location())
+			}
+		})
+	}
+}
+
+func TestSetTimeZone(t *testing.T) {
+	tests := []struct {
+		timeZone string
+		wantErr  error
+	}{
+		{timeZone: "Europe/Moscow"},
+		{timeZone: "America/New_York"},
+		{timeZone: "UTC"},
+		{timeZone: "Mars/Olympus_Mons", wantErr: ErrInvalidTimeZone},
+		{timeZone: "Local", wantErr: ErrInvalidTimeZone},
+		{timeZone: "", wantErr: ErrInvalidTimeZone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.timeZone, func(t *testing.T) {
+			test := newSummaryTest()
+			identity := types.Identity{UserId: testUserId}
+			ctx := context.Background()
+			nextAt := time.Now().Add(time.Hour)
+			test.repo.summaries = map[string]*time.Time{testUserId: &nextAt}
+
+			if timeZone, err := test.notifications.TimeZone(ctx, identity); err != nil || timeZone != "UTC" {
+				t.Fatalf("TimeZone() = %q, %v, want UTC by default", timeZone, err)
+			}
+
+			err := test.notifications.SetTimeZone(ctx, identity, tt.timeZone)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetTimeZone() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(test.repo.timeZones) != 0 || test.repo.summaries[testUserId] == nil || len(test.auditrepo.events) != 0 {
+					t.Errorf("rejected SetTimeZone() changed the schedule or was audited")
+				}
+				return
+			}
+
+			if timeZone, _ := test.notifications.TimeZone(ctx, identity); timeZone != tt.timeZone {
+				t.Errorf("TimeZone() = %q, want %q", timeZone, tt.timeZone)
+			}
+			// The next summary is scheduled again in the new time zone.
+			if nextAt, ok := test.repo.summaries[testUserId]; !ok || nextAt != nil {
+				t.Errorf("next summary at %v, want it reset", nextAt)
+			}
+			if len(test.auditrepo.events) != 1 || test.auditrepo.events[0].Metadata["time_zone"] != tt.timeZone {
+				t.Errorf("audit events = %+v, want the time zone change", test.auditrepo.events)
+			}
+		})
+	}
+}
+
+func TestSendSummaries(t *testing.T) {
+	test := newSummaryTest()
+	ctx := context.Background()
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+
+	// The summary of the user of testUserId is due, the others have no schedule yet.
+	due := time.Now().Add(-time.Minute)
+	test.repo.timeZones = map[string]string{testUserId: "Europe/Moscow"}
+	test.repo.summaries = map[string]*time.Time{testUserId: &due}
+
+	signedInAt := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Minute)
+	test.recordSignIn(types.AuditUserSignedIn, testUserId, time.Now().Add(-8*24*time.Hour))
+	test.recordSignIn(types.AuditUserSignedIn, testUserId, signedInAt)
+	test.recordSignIn(types.AuditUserSignInFailed, testUserId, time.Now().Add(-time.Hour))
+	test.recordSignIn(types.AuditUserSignedIn, otherUserId, signedInAt)
+	test.sessions.sessions = []types.Session{
+		{SessionId: "s1", UserId: testUserId, IP: "203.0.113.7", ClientId: "crm", CreatedAt: signedInAt, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+
+	sent, err := test.summaries.SendSummaries(ctx)
+	if err != nil || sent != 1 {
+		t.Fatalf("SendSummaries() = %d, %v, want 1 sent", sent, err)
+	}
+
+	emails := test.smtp.messages("alice@example.com")
+	if len(emails) != 1 {
+		t.Fatalf("summary emails = %d, want 1", len(emails))
+	}
+	body := emails[0].Body
+	for _, want := range []string{
+		"(Europe/Moscow)",
+		"Успешных входов: 1.",
+		"Неудачных попыток входа: 1.",
+		signedInAt.In(moscow).Format("02.01.2006 15:04") + " — Chrome on Windows, 203.0.113.7 (Moscow, RU), password",
+		"приложение crm",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("summary email does not contain %q:\n%s", want, body)
+		}
+	}
+	// The user without a schedule is scheduled, the next summary is the first one sent.
+	if len(test.smtp.messages("bob@example.com")) != 0 || len(test.smtp.messages("carol@example.com")) != 0 {
+		t.Errorf("summaries sent to users without a due summary")
+	}
+
+	now := time.Now()
+	for userId, timeZone := range map[string]string{testUserId: "Europe/Moscow", otherUserId: "UTC"} {
+		location, _ := time.LoadLocation(timeZone)
+		nextAt := test.repo.summaries[userId]
+		if nextAt == nil || !nextAt.Equal(test.summaries.nextSummaryAt(now, location)) {
+			t.Errorf("next summary of %s at %v, want the next Monday 9:00 in %s", userId, nextAt, timeZone)
+		}
+	}
+	if _, ok := test.repo.summaries[carolUserId]; ok {
+		t.Errorf("disabled user is scheduled")
+	}
+
+	if sent, _ = test.summaries.SendSummaries(ctx); sent != 0 {
+		t.Errorf("second SendSummaries() = %d, want 0", sent)
+	}
+}
+
+func TestSendSummariesSkips(t *testing.T) {
+	tests := []struct {
+		name        string
+		preferences []types.NotificationPreference
+		activity    bool
+	}{
+		{name: "no activity"},
+		{
+			name:        "summaries turned off",
+			preferences: []types.NotificationPreference{{Event: types.NotificationWeeklySummary, Channels: []string{}, Delivery: types.NotificationDeliveryImmediate}},
+			activity:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newSummaryTest()
+			due := time.Now().Add(-time.Minute)
+			test.repo.summaries = map[string]*time.Time{testUserId: &due}
+			test.repo.preferences = map[string][]types.NotificationPreference{testUserId: tt.preferences}
+			if tt.activity {
+				test.recordSignIn(types.AuditUserSignedIn, testUserId, time.Now().Add(-time.Hour))
+			}
+
+			sent, err := test.summaries.SendSummaries(context.Background())
+			if err != nil || sent != 0 {
+				t.Fatalf("SendSummaries() = %d, %v, want none sent", sent, err)
+			}
+			if len(test.smtp.sent) != 0 {
+				t.Errorf("emails sent: %+v", test.smtp.sent)
+			}
+			if nextAt := test.repo.summaries[testUserId]; nextAt == nil || !nextAt.After(time.Now()) {
+				t.Errorf("next summary at %v, want the next week", nextAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"time"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+	auditVerifyBatchSize = 1000
+)
+
+type AuditRepo interface {
+	AppendEvent(ctx context.Context, event types.AuditEvent) (int64, error)
+	ListEvents(ctx context.Context, filter types.AuditFilter) ([]types.AuditEvent, int, error)
+	ListEventsAfter(ctx context.Context, afterId int64, limit int) ([]types.AuditEvent, error)
+	ListUserEvents(ctx context.Context, userId string) ([]types.AuditEvent, error)
+}
+
+// Audit keeps the security audit log.
+type Audit struct {
+	auditrepo   AuditRepo
+	webhookrepo WebhookRepo
+	signalrepo  SignalRepo
+	locator     GeoLocator
+}
+
+// Record appends an event about the user to the audit log. The actor, IP and user agent are
+// taken from the request info of ctx, the location of the IP is added to metadata.
+// The event is queued for the webhooks subscribed to its type and as security events for the
+// Shared Signals streams that requested them. A failure is logged and does
+// not fail the caller.
+func (a *Audit) Record(ctx context.Context, eventType string, userId string, metadata map[string]string) {
+	info := types.RequestInfoFrom(ctx)
+
+	if location, ok := a.locator.Locate(info.IP); ok {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		locationMetadata(metadata, location)
+	}
+
+	salt, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to record audit event %s (user: %s): %s", eventType, userId, err)
+		return
+	}
+
+	event := types.AuditEvent{
+		Type:        eventType,
+		ActorId:     info.ActorId,
+		UserId:      userId,
+		IP:          info.IP,
+		UserAgent:   info.UserAgent,
+		Metadata:    metadata,
+		DetailsSalt: salt,
+		CreatedAt:   time.Now().UTC().Truncate(time.Microsecond),
+	}
+	eventId, err := a.auditrepo.AppendEvent(ctx, event)
+	if err != nil {
+		logger.Errorf("failed to record audit event %s (user: %s): %s", eventType, userId, err)
+		return
+	}
+
+	types.MarkAudited(ctx)
+
+	event.Id = eventId
+	a.enqueueWebhooks(ctx, event)
+	a.queueSecurityEvents(ctx, event)
+}
+
+func (a *Audit) List(ctx context.Context, filter types.AuditFilter) ([]types.AuditEvent, int, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditPageSize
+	}
+	filter.Limit = min(filter.Limit, maxAuditPageSize)
+	filter.Offset = max(filter.Offset, 0)
+
+	events, total, err := a.auditrepo.ListEvents(ctx, filter)
+	if err != nil {
+		logger.Errorf("failed to list audit events: %s", err)
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// Verify walks the whole audit log and checks that every event is chained to the previous one
+// and that the kept details of the events match their hashes.
+func (a *Audit) Verify(ctx context.Context) (types.AuditVerification, error) {
+	result := types.AuditVerification{Valid: true}
+
+	var (
+		lastId   int64
+		lastHash = types.AuditGenesisHash
+	)
+	for {
+		events, err := a.auditrepo.ListEventsAfter(ctx, lastId, auditVerifyBatchSize)
+		if err != nil {
+			logger.Errorf("failed to list audit events: %s", err)
+			return result, err
+		}
+
+		for _, event := range events {
+			result.Checked++
+			chained := event.Id == lastId+1 && event.PrevHash == lastHash
+			// The details of events about erased users are gone, the rest of the chain is still checked.
+			detailsValid := !event.HasDetails() || event.ComputeDetailsHash() == event.DetailsHash
+			if !chained || !detailsValid || event.ComputeHash() != event.Hash {
+				result.Valid = false
+				result.BrokenAt = event.Id
+				logger.Errorf("audit log chain is broken at event %d", event.Id)
+				return result, nil
+			}
+			lastId, lastHash = event.Id, event.Hash
+		}
+
+		if len(events) < auditVerifyBatchSize {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"medods-test/internal/auth/types"
+	"testing"
+)
+
+func TestRecordMarksRequest(t *testing.T) {
+	auditrepo := &fakeAuditRepo{}
+	audit := newTestAudit(auditrepo)
+
+	ctx, mark := types.WithAuditMark(context.Background())
+	if mark.Recorded() {
+		t.Fatalf("Recorded() = true before any event")
+	}
+
+	audit.Record(ctx, types.AuditUserStatusChanged, "user-1", map[string]string{"status": "blocked"})
+	if !mark.Recorded() {
+		t.Errorf("Recorded() = false after Record()")
+	}
+	if got := auditrepo.eventTypes(); len(got) != 1 || got[0] != types.AuditUserStatusChanged {
+		t.Errorf("recorded events = %v, want [%s]", got, types.AuditUserStatusChanged)
+	}
+
+	// Requests without a mark are recorded as before.
+	audit.Record(context.Background(), types.AuditSessionRevoked, "user-1", nil)
+	if got := len(auditrepo.eventTypes()); got != 2 {
+		t.Errorf("recorded %d events, want 2", got)
+	}
+}
+
+func TestVerifyAfterErasure(t *testing.T) {
+	auditrepo := &fakeAuditRepo{}
+	audit := newTestAudit(auditrepo)
+	ctx := types.WithRequestInfo(context.Background(), types.RequestInfo{IP: "203.0.113.1", UserAgent: "curl/8.0"})
+	for _, userId := range []string{"user-1", "user-2", "user-1"} {
+		audit.Record(ctx, types.AuditEmailChangeRequested, userId, map[string]string{"new_email": userId + "@example.com"})
+	}
+
+	auditrepo.eraseDetails("user-1")
+
+	result, err := audit.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !result.Valid || result.Checked != 3 {
+		t.Errorf("Verify() = %+v after erasure, want 3 valid events", result)
+	}
+	for _, event := range auditrepo.events {
+		erased := event.UserId == "user-1"
+		if erased == event.HasDetails() || erased != (event.IP == "" && len(event.Metadata) == 0) {
+			t.Errorf("event %d of %s has details %v (ip %q, metadata %v)", event.Id, event.UserId, event.HasDetails(), event.IP, event.Metadata)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		events int
+		// tamper changes the stored events after they are recorded.
+		tamper       func(events []types.AuditEvent) []types.AuditEvent
+		wantValid    bool
+		wantBrokenAt int64
+		wantChecked  int64
+	}{
+		{
+			name:      "empty log",
+			wantValid: true,
+		},
+		{
+			name:        "intact chain",
+			events:      5,
+			wantValid:   true,
+			wantChecked: 5,
+		},
+		{
+			name:        "chain longer than a batch",
+			events:      auditVerifyBatchSize + 1,
+			wantValid:   true,
+			wantChecked: auditVerifyBatchSize + 1,
+		},
+		{
+			name:   "changed metadata",
+			events: 5,
+			tamper: func(events []types.AuditEvent) []types.AuditEvent {
+				events[2].Metadata = map[string]string{"status": "active"}
+				return events
+			},
+			wantBrokenAt: 3,
+			wantChecked:  3,
+		},
+		{
+			name:   "changed ip with the salt kept",
+			events: 5,
+			tamper: func(events []types.AuditEvent) []types.AuditEvent {
+				events[1].IP = "198.51.100.7"
+				return events
+			},
+			wantBrokenAt: 2,
+			wantChecked:  2,
+		},
+		{
+			name:   "changed details with a recomputed details hash",
+			events: 5,
+			tamper: func(events []types.AuditEvent) []types.AuditEvent {
+				events[2].Metadata = map[string]string{"status": "active"}
+				events[2].DetailsHash = events[2].ComputeDetailsHash()
+				return events
+			},
+			wantBrokenAt: 3,
+			wantChecked:  3,
+		},
+		{
+			name:   "changed event with a recomputed hash",
+			events: 5,
+			tamper: func(events []types.AuditEvent) []types.AuditEvent {
+				events[2].UserId = "user-2"
+				events[2].Hash = events[2].ComputeHash()
+				return events
+			},
+			wantBrokenAt: 4,
+			wantChecked:  4,
+		},
+		{
+			name:   "deleted event",
+			events: 5,
+			tamper: func(events []types.AuditEvent) []types.AuditEvent {
+				return append(events[:2], events[3:]...)
+			},
+			wantBrokenAt: 4,
+			wantChecked:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditrepo := &fakeAuditRepo{}
+			audit := newTestAudit(auditrepo)
+			for i := 0; i < tt.events; i++ {
+				audit.Record(context.Background(), types.AuditUserStatusChanged, "user-1", map[string]string{"status": "blocked"})
+			}
+			if tt.tamper != nil {
+				auditrepo.events = tt.tamper(auditrepo.events)
+			}
+
+			result, err := audit.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if result.Valid != tt.wantValid || result.BrokenAt != tt.wantBrokenAt || result.Checked != tt.wantChecked {
+				t.Errorf("Verify() = %+v, want valid %v, broken at %d, checked %d", result, tt.wantValid, tt.wantBrokenAt, tt.wantChecked)
+			}
+		})
+	}
+}
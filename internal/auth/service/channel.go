@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/email"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/sms"
+	"time"
+)
+
+// ErrChannelUnavailable is returned when the user cannot be reached through a channel: SMS is
+// not configured or the user has no verified phone.
+var ErrChannelUnavailable = errors.New("channel is not available")
+
+// Channel delivers messages to users through one medium.
+type Channel interface {
+	// Recipient returns the address of the user on the channel, empty if the user cannot be
+	// reached through it.
+	Recipient(ctx context.Context, user *types.User) (string, error)
+	Send(recipient string, message types.ChannelMessage) error
+}
+
+type PhoneRepo interface {
+	GetPhone(ctx context.Context, userId string) (*types.UserPhone, error)
+	SavePhone(ctx context.Context, phone types.UserPhone) error
+	SavePendingPhone(ctx context.Context, userId string, phone string, useForMFA bool) (bool, error)
+	VerifyPhone(ctx context.Context, userId string, phone string, verifiedAt time.Time) (bool, error)
+	DeletePhone(ctx context.Context, userId string) (bool, error)
+	CreateSMSCode(ctx context.Context, code types.SMSCode) error
+	GetSMSCode(ctx context.Context, tokenHash string) (*types.SMSCode, error)
+	CountSMSCodes(ctx context.Context, userId string, purpose string, since time.Time) (int, error)
+	AddSMSCodeAttempt(ctx context.Context, tokenHash string) error
+	UseSMSCode(ctx context.Context, tokenHash string) (bool, error)
+	DeleteSMSCodes(ctx context.Context, before time.Time) (int64, error)
+}
+
+// emailChannel sends messages to the email of the user.
+type emailChannel struct {
+	smtp email.Sender
+}
+
+func (c emailChannel) Recipient(_ context.Context, user *types.User) (string, error) {
+	return user.Email, nil
+}
+
+func (c emailChannel) Send(recipient string, message types.ChannelMessage) error {
+	return c.smtp.Send(email.Send{
+		Recipient: recipient,
+		Subject:   message.Subject,
+		Body:      message.HTML,
+	})
+}
+
+// smsChannel sends the text of messages to the verified phone of the user.
+type smsChannel struct {
+	sender    sms.Sender
+	phonerepo PhoneRepo
+}
+
+func (c smsChannel) Recipient(ctx context.Context, user *types.User) (string, error) {
+	phone, err := c.phonerepo.GetPhone(ctx, user.UserUUID)
+	if err != nil {
+		logger.Errorf("failed to get phone: %s", err)
+		return "", err
+	}
+	if phone == nil || !phone.IsVerified() {
+		return "", nil
+	}
+	return phone.Phone, nil
+}
+
+func (c smsChannel) Send(recipient string, message types.ChannelMessage) error {
+	text := message.Text
+	if text == "" {
+		text = message.Subject
+	}
+	return c.sender.Send(sms.Send{
+		Recipient: recipient,
+		Body:      text,
+	})
+}
+
+// sendTo sends the message to the user through the channel.
+func sendTo(ctx context.Context, channel Channel, user *types.User, message types.ChannelMessage) error {
+	recipient, err := channel.Recipient(ctx, user)
+	if err != nil {
+		return err
+	}
+	if recipient == "" {
+		return ErrChannelUnavailable
+	}
+	return channel.Send(recipient, message)
+}
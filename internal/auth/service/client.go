@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"medods-test/internal/auth/repo/postgres"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/auth"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"net/url"
+	"slices"
+	"time"
+)
+
+var (
+	ErrClientNotFound       = errors.New("client not found")
+	ErrClientAlreadyExists  = errors.New("client already exists")
+	ErrClientSecretNotFound = errors.New("client secret not found")
+	ErrInvalidClientType    = errors.New("client type must be public or confidential")
+	ErrInvalidGrantTypes    = errors.New("grant types are not supported by the client type")
+)
+
+// grantTypesByClientType lists the grants each kind of client may be registered for.
+var grantTypesByClientType = map[string][]string{
+	types.ClientTypePublic: {
+		oauth.GrantTypeAuthorizationCode,
+		oauth.GrantTypeRefreshToken,
+		oauth.GrantTypeDeviceCode,
+	},
+	types.ClientTypeConfidential: {
+		oauth.GrantTypeAuthorizationCode,
+		oauth.GrantTypeRefreshToken,
+		oauth.GrantTypeClientCredentials,
+		oauth.GrantTypeDeviceCode,
+		oauth.GrantTypeTokenExchange,
+	},
+}
+
+type ClientRepo interface {
+	CreateClient(ctx context.Context, client types.OAuthClient) error
+	GetClientByID(ctx context.Context, clientId string) (*types.OAuthClient, error)
+	ListClients(ctx context.Context) ([]types.OAuthClient, error)
+	UpdateClient(ctx context.Context, client types.OAuthClient) error
+	DeleteClient(ctx context.Context, clientId string) error
+	GetActiveClientSecrets(ctx context.Context, clientId string) ([]types.OAuthClientSecret, error)
+	RotateClientSecret(ctx context.Context, secret types.OAuthClientSecret, previousExpiresAt time.Time) error
+	DeleteClientSecret(ctx context.Context, clientId string, secretId string) (bool, error)
+}
+
+// Client manages OAuth clients and service accounts.
+type Client struct {
+	clientrepo   ClientRepo
+	tokenManager auth.TokenManager
+
+	secretRotationGrace time.Duration
+}
+
+// Create registers a client. Confidential clients get their first secret, which is
+// returned in plain text only once.
+func (c *Client) Create(ctx context.Context, input types.OAuthClientDTO) (*types.OAuthClient, string, error) {
+	if input.ClientId == "" {
+		input.ClientId = uuid.NewString()
+	}
+	if input.Type == "" {
+		input.Type = types.ClientTypePublic
+	}
+	if len(input.GrantTypes) == 0 {
+		input.GrantTypes = []string{oauth.GrantTypeAuthorizationCode, oauth.GrantTypeRefreshToken}
+	}
+
+	client := types.OAuthClient{
+		ClientId:          input.ClientId,
+		Name:              input.Name,
+		Type:              input.Type,
+		RedirectURIs:      nonNil(input.RedirectURIs),
+		Scopes:            nonNil(input.Scopes),
+		GrantTypes:        input.GrantTypes,
+		ExchangeAudiences: nonNil(input.ExchangeAudiences),
+	}
+	if err := validateClient(client); err != nil {
+		return nil, "", err
+	}
+
+	if err := c.clientrepo.CreateClient(ctx, client); err != nil {
+		logger.Errorf("failed to create client: %s", err)
+		if errors.Is(err, postgres.ErrUniqueContraintFailed) {
+			return nil, "", ErrClientAlreadyExists
+		}
+		return nil, "", err
+	}
+
+	var secret string
+	if client.IsConfidential() {
+		var err error
+		if secret, _, err = c.RotateSecret(ctx, client.ClientId); err != nil {
+			return nil, "", err
+		}
+	}
+
+	created, err := c.Get(ctx, client.ClientId)
+	if err != nil {
+		return nil, "", err
+	}
+	return created, secret, nil
+}
+
+func (c *Client) Get(ctx context.Context, clientId string) (*types.OAuthClient, error) {
+	client, err := c.clientrepo.GetClientByID(ctx, clientId)
+	if err != nil {
+		logger.Errorf("failed to get client: %s", err)
+		return nil, err
+	}
+	if client == nil {
+		return nil, ErrClientNotFound
+	}
+	return client, nil
+}
+
+func (c *Client) List(ctx context.Context) ([]types.OAuthClient, error) {
+	clients, err := c.clientrepo.ListClients(ctx)
+	if err != nil {
+		logger.Errorf("failed to list clients: %s", err)
+		return nil, err
+	}
+	return clients, nil
+}
+
+// Update replaces the mutable attributes of a client. The client type cannot be changed.
+func (c *Client) Update(ctx context.Context, input types.OAuthClientDTO) (*types.OAuthClient, error) {
+	client, err := c.Get(ctx, input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Name = input.Name
+	client.RedirectURIs = nonNil(input.RedirectURIs)
+	client.Scopes = nonNil(input.Scopes)
+	client.ExchangeAudiences = nonNil(input.ExchangeAudiences)
+	if len(input.GrantTypes) > 0 {
+		client.GrantTypes = input.GrantTypes
+	}
+	if err = validateClient(*client); err != nil {
+		return nil, err
+	}
+
+	if err = c.clientrepo.UpdateClient(ctx, *client); err != nil {
+		logger.Errorf("failed to update client: %s", err)
+		return nil, err
+	}
+
+	return c.Get(ctx, client.ClientId)
+}
+
+func (c *Client) Delete(ctx context.Context, clientId string) error {
+	if _, err := c.Get(ctx, clientId); err != nil {
+		return err
+	}
+
+	if err := c.clientrepo.DeleteClient(ctx, clientId); err != nil {
+		logger.Errorf("failed to delete client: %s", err)
+		return err
+	}
+	return nil
+}
+
+// RotateSecret issues a new client secret. The previous secret keeps working for the
+// rotation grace period, so two secrets are active while clients are redeployed.
+func (c *Client) RotateSecret(ctx context.Context, clientId string) (string, *types.OAuthClientSecret, error) {
+	client, err := c.Get(ctx, clientId)
+	if err != nil {
+		return "", nil, err
+	}
+	if !client.IsConfidential() {
+		return "", nil, ErrInvalidClientType
+	}
+
+	secret, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate client secret: %s", err)
+		return "", nil, err
+	}
+
+	hash, err := c.tokenManager.HashToken(secret)
+	if err != nil {
+		logger.Errorf("failed to hash client secret: %s", err)
+		return "", nil, err
+	}
+
+	clientSecret := types.OAuthClientSecret{
+		Id:         uuid.NewString(),
+		ClientId:   clientId,
+		SecretHash: hash,
+		CreatedAt:  time.Now(),
+	}
+
+	if err = c.clientrepo.RotateClientSecret(ctx, clientSecret, time.Now().Add(c.secretRotationGrace)); err != nil {
+		logger.Errorf("failed to rotate client secret: %s", err)
+		return "", nil, err
+	}
+
+	return secret, &clientSecret, nil
+}
+
+// RevokeSecret immediately invalidates one of the client secrets.
+func (c *Client) RevokeSecret(ctx context.Context, clientId string, secretId string) error {
+	if err := uuid.Validate(secretId); err != nil {
+		return ErrClientSecretNotFound
+	}
+
+	deleted, err := c.clientrepo.DeleteClientSecret(ctx, clientId, secretId)
+	if err != nil {
+		logger.Errorf("failed to delete client secret: %s", err)
+		return err
+	}
+	if !deleted {
+		return ErrClientSecretNotFound
+	}
+	return nil
+}
+
+func validateClient(client types.OAuthClient) error {
+	allowed, ok := grantTypesByClientType[client.Type]
+	if !ok {
+		return ErrInvalidClientType
+	}
+
+	for _, grantType := range client.GrantTypes {
+		if !slices.Contains(allowed, grantType) {
+			return ErrInvalidGrantTypes
+		}
+	}
+
+	for _, uri := range client.RedirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return ErrInvalidRedirectURI
+		}
+	}
+
+	return nil
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/oauth"
+	"testing"
+	"time"
+)
+
+func newTestClients(t *testing.T) (*Client, *OAuth, *fakeOAuthRepo) {
+	t.Helper()
+
+	oauthrepo := newFakeOAuthRepo()
+	o, _ := newTestOAuth(t, oauthrepo)
+	return &Client{
+		clientrepo:          fakeClientRepo{oauthrepo: oauthrepo},
+		tokenManager:        o.user.tokenManager,
+		secretRotationGrace: time.Hour,
+	}, o, oauthrepo
+}
+
+func TestClientCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      types.OAuthClientDTO
+		wantErr    error
+		wantSecret bool
+	}{
+		{
+			name:       "confidential client",
+			input:      types.OAuthClientDTO{ClientId: "billing", Type: types.ClientTypeConfidential, GrantTypes: []string{oauth.GrantTypeClientCredentials}},
+			wantSecret: true,
+		},
+		{
+			name:  "public client",
+			input: types.OAuthClientDTO{ClientId: "spa", RedirectURIs: []string{testRedirectURI}},
+		},
+		{
+			name:    "public client with client credentials",
+			input:   types.OAuthClientDTO{ClientId: "spa", Type: types.ClientTypePublic, GrantTypes: []string{oauth.GrantTypeClientCredentials}},
+			wantErr: ErrInvalidGrantTypes,
+		},
+		{
+			name:    "unknown type",
+			input:   types.OAuthClientDTO{ClientId: "spa", Type: "trusted"},
+			wantErr: ErrInvalidClientType,
+		},
+		{
+			name:    "relative redirect uri",
+			input:   types.OAuthClientDTO{ClientId: "spa", RedirectURIs: []string{"/callback"}},
+			wantErr: ErrInvalidRedirectURI,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients, _, oauthrepo := newTestClients(t)
+
+			client, secret, err := clients.Create(context.Background(), tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(oauthrepo.clients) != 0 {
+					t.Errorf("Create() stored an invalid client")
+				}
+				return
+			}
+
+			wantSecrets := 0
+			if tt.wantSecret {
+				wantSecrets = 1
+			}
+			if client.ClientId != tt.input.ClientId || (secret != "") != tt.wantSecret || len(oauthrepo.secrets) != wantSecrets {
+				t.Errorf("Create() = %+v with secret %q and %d stored secrets", client, secret, len(oauthrepo.secrets))
+			}
+		})
+	}
+}
+
+func TestClientSecretRotation(t *testing.T) {
+	clients, o, oauthrepo := newTestClients(t)
+	ctx := context.Background()
+
+	authenticates := func(secret string) bool {
+		_, err := o.authenticateClient(ctx, "billing", secret)
+		if err != nil && !errors.Is(err, ErrInvalidClient) {
+			t.Fatalf("authenticateClient() error = %v", err)
+		}
+		return err == nil
+	}
+
+	_, first, err := clients.Create(ctx, types.OAuthClientDTO{ClientId: "billing", Type: types.ClientTypeConfidential, GrantTypes: []string{oauth.GrantTypeClientCredentials}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !authenticates(first) || authenticates("") || authenticates("wrong") {
+		t.Fatalf("only the issued secret authenticates the client")
+	}
+
+	// Both secrets work during the grace period.
+	second, secondSecret, err := clients.RotateSecret(ctx, "billing")
+	if err != nil {
+		t.Fatalf("RotateSecret() error = %v", err)
+	}
+	if second == first || !authenticates(first) || !authenticates(second) {
+		t.Fatalf("first and second secrets are not both active after rotation")
+	}
+
+	// Another rotation removes the oldest one, at most two secrets are active.
+	third, _, err := clients.RotateSecret(ctx, "billing")
+	if err != nil {
+		t.Fatalf("second RotateSecret() error = %v", err)
+	}
+	if authenticates(first) || !authenticates(second) || !authenticates(third) {
+		t.Errorf("active secrets after the second rotation, want the second and the third")
+	}
+	if len(oauthrepo.secrets) != 2 {
+		t.Errorf("stored secrets = %d, want 2", len(oauthrepo.secrets))
+	}
+
+	// The previous secret stops working when the grace period ends.
+	for i := range oauthrepo.secrets {
+		if oauthrepo.secrets[i].Id == secondSecret.Id {
+			expired := time.Now().Add(-time.Second)
+			oauthrepo.secrets[i].ExpiresAt = &expired
+		}
+	}
+	if authenticates(second) || !authenticates(third) {
+		t.Errorf("the second secret works after the grace period")
+	}
+}
+
+func TestClientRevokeSecret(t *testing.T) {
+	clients, o, _ := newTestClients(t)
+	ctx := context.Background()
+
+	if _, _, err := clients.Create(ctx, types.OAuthClientDTO{ClientId: "billing", Type: types.ClientTypeConfidential, GrantTypes: []string{oauth.GrantTypeClientCredentials}}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	secret, clientSecret, err := clients.RotateSecret(ctx, "billing")
+	if err != nil {
+		t.Fatalf("RotateSecret() error = %v", err)
+	}
+
+	for _, secretId := range []string{"not-a-uuid", "0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9"} {
+		if err = clients.RevokeSecret(ctx, "billing", secretId); !errors.Is(err, ErrClientSecretNotFound) {
+			t.Errorf("RevokeSecret(%q) error = %v, want %v", secretId, err, ErrClientSecretNotFound)
+		}
+	}
+	if err = clients.RevokeSecret(ctx, "other", clientSecret.Id); !errors.Is(err, ErrClientSecretNotFound) {
+		t.Errorf("RevokeSecret() of another client error = %v, want %v", err, ErrClientSecretNotFound)
+	}
+
+	if err = clients.RevokeSecret(ctx, "billing", clientSecret.Id); err != nil {
+		t.Fatalf("RevokeSecret() error = %v", err)
+	}
+	if _, err = o.authenticateClient(ctx, "billing", secret); !errors.Is(err, ErrInvalidClient) {
+		t.Errorf("authenticateClient() with a revoked secret error = %v, want %v", err, ErrInvalidClient)
+	}
+}
+
+func TestClientRotatePublicSecret(t *testing.T) {
+	clients, _, oauthrepo := newTestClients(t)
+	ctx := context.Background()
+
+	if _, _, err := clients.Create(ctx, types.OAuthClientDTO{ClientId: "spa", RedirectURIs: []string{testRedirectURI}}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := clients.RotateSecret(ctx, "spa"); !errors.Is(err, ErrInvalidClientType) {
+		t.Errorf("RotateSecret() error = %v, want %v", err, ErrInvalidClientType)
+	}
+	if _, _, err := clients.RotateSecret(ctx, "unknown"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("RotateSecret() of an unknown client error = %v, want %v", err, ErrClientNotFound)
+	}
+	if len(oauthrepo.secrets) != 0 {
+		t.Errorf("stored secrets = %d, want none", len(oauthrepo.secrets))
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	tests := []struct {
+		name      string
+		scope     string
+		wantScope string
+		wantErr   error
+	}{
+		{name: "default scopes", wantScope: "invoices:read invoices:write"},
+		{name: "narrowed scope", scope: "invoices:read", wantScope: "invoices:read"},
+		{name: "scope of another client", scope: "users:read", wantErr: ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients, o, _ := newTestClients(t)
+			ctx := context.Background()
+
+			_, secret, err := clients.Create(ctx, types.OAuthClientDTO{
+				ClientId:   "billing",
+				Type:       types.ClientTypeConfidential,
+				Scopes:     []string{"invoices:read", "invoices:write"},
+				GrantTypes: []string{oauth.GrantTypeClientCredentials},
+			})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			tokens, err := o.Token(ctx, types.TokenRequest{
+				GrantType:    oauth.GrantTypeClientCredentials,
+				ClientId:     "billing",
+				ClientSecret: secret,
+				Scope:        tt.scope,
+			}, "203.0.113.1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Token() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if tokens.RefreshToken != "" || tokens.Scope != tt.wantScope {
+				t.Errorf("tokens = %+v, want scope %q without a refresh token", tokens, tt.wantScope)
+			}
+			claims, err := o.user.tokenManager.ParseAccessToken(tokens.AccessToken)
+			if err != nil {
+				t.Fatalf("ParseAccessToken() error = %v", err)
+			}
+			if claims.Subject != "billing" || claims.ClientId != "billing" || claims.SessionId != "" {
+				t.Errorf("claims of %s for %s in session %q, want the client itself", claims.Subject, claims.ClientId, claims.SessionId)
+			}
+		})
+	}
+}
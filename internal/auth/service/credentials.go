@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/ldapauth"
+	"medods-test/pkg/logger"
+	"strings"
+)
+
+var ErrNotDirectoryAccount = errors.New("an account with this email exists but was not created through the directory")
+
+// CredentialVerifier checks passwords of users kept in an external directory.
+type CredentialVerifier interface {
+	Name() string
+	Domains() []string
+	Authenticate(ctx context.Context, email, password string) (*ldapauth.Principal, error)
+}
+
+// verifierFor returns the verifier configured for the email domain, or nil if the
+// user's password is kept in the local database.
+func (u *User) verifierFor(email string) CredentialVerifier {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return nil
+	}
+	return u.verifiers[strings.ToLower(email[at+1:])]
+}
+
+// authenticateExternal checks the password in the directory and provisions the local user on first login.
+// An existing user with the email is signed in only if it was provisioned by the same directory, so
+// that a local or social account cannot be taken over by whoever gets the address in the directory.
+func (u *User) authenticateExternal(ctx context.Context, verifier CredentialVerifier, input types.UserDTO) (*types.User, error) {
+	principal, err := verifier.Authenticate(ctx, input.Email, input.Password)
+	if err != nil {
+		if errors.Is(err, ldapauth.ErrInvalidCredentials) {
+			return nil, ErrUserNotFound
+		}
+		logger.Errorf("failed to authenticate in %s: %s", verifier.Name(), err)
+		return nil, err
+	}
+
+	email := strings.ToLower(principal.Email)
+	if email == "" {
+		email = strings.ToLower(input.Email)
+	}
+	provider := directoryProvider(verifier)
+
+	user, err := u.userrepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		logger.Errorf("failed to get user by email: %s", err)
+		return nil, err
+	}
+	if user == nil {
+		if user, err = u.provisionUser(ctx, email); err != nil {
+			return nil, err
+		}
+		if err = u.identityrepo.CreateIdentity(ctx, types.ExternalIdentity{
+			Id:       uuid.NewString(),
+			Provider: provider,
+			Subject:  principal.DN,
+			UserId:   user.UserUUID,
+			Email:    email,
+		}); err != nil {
+			logger.Errorf("failed to link directory identity: %s", err)
+			return nil, err
+		}
+	} else if err = u.checkDirectoryAccount(ctx, user, provider, email); err != nil {
+		return nil, err
+	}
+	if err = checkUserActive(user); err != nil {
+		return nil, err
+	}
+
+	if err = u.roles.SyncDirectoryRoles(ctx, user.UserUUID, verifier.Name(), principal.Roles); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// checkDirectoryAccount fails with ErrNotDirectoryAccount unless user was provisioned by the
+// directory of provider.
+func (u *User) checkDirectoryAccount(ctx context.Context, user *types.User, provider string, email string) error {
+	identities, err := u.identityrepo.ListUserIdentities(ctx, user.UserUUID)
+	if err != nil {
+		logger.Errorf("failed to list user identities: %s", err)
+		return err
+	}
+
+	for _, identity := range identities {
+		if identity.Provider != provider {
+			continue
+		}
+		if err = u.identityrepo.UpdateIdentityLogin(ctx, identity.Id, email); err != nil {
+			logger.Errorf("failed to update identity: %s", err)
+			return err
+		}
+		return nil
+	}
+
+	logger.Errorf("refused directory sign-in to user %s not provisioned by %s", user.UserUUID, provider)
+	return ErrNotDirectoryAccount
+}
+
+// directoryProvider is the provider of the identities of users provisioned by the directory.
+func directoryProvider(verifier CredentialVerifier) string {
+	return "ldap:" + verifier.Name()
+}
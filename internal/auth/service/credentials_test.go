@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/ldapauth"
+	"slices"
+	"testing"
+)
+
+// fakeVerifier accepts the password "directory-secret" for every email.
+type fakeVerifier struct{}
+
+func (fakeVerifier) Name() string {
+	return "corp"
+}
+
+func (fakeVerifier) Domains() []string {
+	return []string{"corp.example.com"}
+}
+
+func (fakeVerifier) Authenticate(_ context.Context, email, password string) (*ldapauth.Principal, error) {
+	if password != "directory-secret" {
+		return nil, ldapauth.ErrInvalidCredentials
+	}
+	return &ldapauth.Principal{DN: "uid=" + email + ",dc=corp", Email: email, Roles: []string{"support"}}, nil
+}
+
+func newDirectoryTestUser(users *fakeUserRepo, identities *fakeIdentityRepo) (*User, *fakeRBACRepo) {
+	rbacrepo := &fakeRBACRepo{}
+	user := newTestUser(users, &fakeAuditRepo{})
+	user.identityrepo = identities
+	user.roles = &RBAC{rbacrepo: rbacrepo, userrepo: users}
+	user.verifiers["corp.example.com"] = fakeVerifier{}
+	return user, rbacrepo
+}
+
+func TestAuthenticateExternal(t *testing.T) {
+	const email = "carol@corp.example.com"
+	existing := types.User{UserUUID: "3f0b8a52-3c1e-4a6f-8a43-0b2b6f3d9e21", Email: email, Status: types.UserStatusActive}
+
+	tests := []struct {
+		name        string
+		users       []types.User
+		identities  []types.ExternalIdentity
+		password    string
+		wantErr     error
+		wantUserId  string
+		wantNewUser bool
+	}{
+		{
+			name:        "first sign-in provisions the user",
+			password:    "directory-secret",
+			wantNewUser: true,
+		},
+		{
+			name:       "user provisioned by the directory",
+			users:      []types.User{existing},
+			identities: []types.ExternalIdentity{{Id: "1", Provider: "ldap:corp", Subject: "uid=carol", UserId: existing.UserUUID}},
+			password:   "directory-secret",
+			wantUserId: existing.UserUUID,
+		},
+		{
+			name:     "local user with the email",
+			users:    []types.User{existing},
+			password: "directory-secret",
+			wantErr:  ErrNotDirectoryAccount,
+		},
+		{
+			name:       "user linked to another provider",
+			users:      []types.User{existing},
+			identities: []types.ExternalIdentity{{Id: "1", Provider: "google", Subject: "1", UserId: existing.UserUUID}},
+			password:   "directory-secret",
+			wantErr:    ErrNotDirectoryAccount,
+		},
+		{
+			name:     "wrong password",
+			password: "guess",
+			wantErr:  ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newFakeUserRepo(tt.users...)
+			identities := newFakeIdentityRepo()
+			identities.identities = tt.identities
+			u, rbacrepo := newDirectoryTestUser(users, identities)
+
+			user, err := u.authenticateExternal(context.Background(), fakeVerifier{}, types.UserDTO{Email: email, Password: tt.password})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("authenticateExternal() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(rbacrepo.sourceRoles) != 0 {
+					t.Errorf("authenticateExternal() synced roles %v of a refused sign-in", rbacrepo.sourceRoles)
+				}
+				return
+			}
+
+			if tt.wantNewUser {
+				if len(users.users) != 1 {
+					t.Fatalf("authenticateExternal() created %d users, want 1", len(users.users))
+				}
+				linked, _ := identities.ListUserIdentities(context.Background(), user.UserUUID)
+				if len(linked) != 1 || linked[0].Provider != "ldap:corp" {
+					t.Errorf("identities of the new user = %+v, want one of ldap:corp", linked)
+				}
+			} else if user.UserUUID != tt.wantUserId {
+				t.Errorf("authenticateExternal() user = %s, want %s", user.UserUUID, tt.wantUserId)
+			}
+			if roles := rbacrepo.sourceRoles["corp/"+user.UserUUID]; !slices.Equal(roles, []string{"support"}) {
+				t.Errorf("directory roles = %v, want [support]", roles)
+			}
+		})
+	}
+}
+
+func TestSignUpDirectoryDomain(t *testing.T) {
+	users := newFakeUserRepo()
+	u, _ := newDirectoryTestUser(users, newFakeIdentityRepo())
+
+	err := u.SignUp(context.Background(), types.UserDTO{Email: "mallory@Corp.Example.com", Password: "directory-secret"})
+	if !errors.Is(err, ErrExternalCredentials) {
+		t.Fatalf("SignUp() error = %v, want %v", err, ErrExternalCredentials)
+	}
+	if len(users.users) != 0 {
+		t.Errorf("SignUp() created %d users, want none", len(users.users))
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/repo/postgres"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"net/url"
+	"slices"
+	"time"
+)
+
+var (
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("polling too frequently")
+	ErrExpiredToken         = errors.New("device code is expired")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrUserCodeNotFound     = errors.New("user code not found or expired")
+)
+
+// userCodeAttempts bounds retries on the unlikely user code collision.
+const userCodeAttempts = 5
+
+type DeviceRepo interface {
+	CreateDeviceCode(ctx context.Context, code types.DeviceCode) error
+	GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*types.DeviceCode, error)
+	ResolveDeviceCode(ctx context.Context, userCode string, status string, userId string) (bool, error)
+	PollDeviceCode(ctx context.Context, deviceCodeHash string) (*types.DeviceCode, error)
+}
+
+// DeviceAuthorization starts the device authorization grant (RFC 8628) for input-constrained clients.
+func (o *OAuth) DeviceAuthorization(ctx context.Context, clientId, clientSecret, scope string) (types.DeviceAuthorization, error) {
+	client, err := o.authenticateClient(ctx, clientId, clientSecret)
+	if err != nil {
+		return types.DeviceAuthorization{}, err
+	}
+
+	if !client.AllowsGrant(oauth.GrantTypeDeviceCode) {
+		return types.DeviceAuthorization{}, ErrUnauthorizedClient
+	}
+
+	if !oauth.ScopeAllowed(slices.Concat(client.Scopes, oauth.StandardScopes), scope) {
+		return types.DeviceAuthorization{}, ErrInvalidScope
+	}
+
+	deviceCode, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to create device code: %s", err)
+		return types.DeviceAuthorization{}, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		userCode, err := oauth.NewUserCode()
+		if err != nil {
+			logger.Errorf("failed to create user code: %s", err)
+			return types.DeviceAuthorization{}, err
+		}
+
+		err = o.devicerepo.CreateDeviceCode(ctx, types.DeviceCode{
+			DeviceCodeHash: oauth.HashCode(deviceCode),
+			UserCode:       userCode,
+			ClientId:       client.ClientId,
+			Scope:          scope,
+			Status:         types.DeviceCodePending,
+			Interval:       o.devicePollInterval,
+			ExpiresAt:      time.Now().Add(o.deviceCodeTTL),
+		})
+		if errors.Is(err, postgres.ErrUniqueContraintFailed) && attempt < userCodeAttempts {
+			continue
+		}
+		if err != nil {
+			logger.Errorf("failed to save device code: %s", err)
+			return types.DeviceAuthorization{}, err
+		}
+
+		verificationURI := o.issuer + "/oauth/device"
+		return types.DeviceAuthorization{
+			DeviceCode:              deviceCode,
+			UserCode:                userCode,
+			VerificationURI:         verificationURI,
+			VerificationURIComplete: oauth.AppendQuery(verificationURI, url.Values{"user_code": {userCode}}),
+			ExpiresIn:               o.deviceCodeTTL,
+			Interval:                o.devicePollInterval,
+		}, nil
+	}
+}
+
+// CheckUserCode returns the pending device code the user is going to approve.
+func (o *OAuth) CheckUserCode(ctx context.Context, userCode string) (*types.DeviceCode, *types.OAuthClient, error) {
+	code, err := o.devicerepo.GetDeviceCodeByUserCode(ctx, oauth.NormalizeUserCode(userCode))
+	if err != nil {
+		logger.Errorf("failed to get device code: %s", err)
+		return nil, nil, err
+	}
+	if code == nil || code.Status != types.DeviceCodePending || code.IsExpired() {
+		return nil, nil, ErrUserCodeNotFound
+	}
+
+	client, err := o.oauthrepo.GetClientByID(ctx, code.ClientId)
+	if err != nil {
+		logger.Errorf("failed to get oauth client: %s", err)
+		return nil, nil, err
+	}
+	if client == nil {
+		return nil, nil, ErrUserCodeNotFound
+	}
+
+	return code, client, nil
+}
+
+// ResolveUserCode lets the signed-in user approve or deny the device.
+func (o *OAuth) ResolveUserCode(ctx context.Context, userCode string, input types.UserDTO, approve bool) error {
+	code, _, err := o.CheckUserCode(ctx, userCode)
+	if err != nil {
+		return err
+	}
+
+	user, err := o.user.Authenticate(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	status := types.DeviceCodeDenied
+	if approve {
+		status = types.DeviceCodeApproved
+	}
+
+	resolved, err := o.devicerepo.ResolveDeviceCode(ctx, code.UserCode, status, user.UserUUID)
+	if err != nil {
+		logger.Errorf("failed to resolve device code: %s", err)
+		return err
+	}
+	if !resolved {
+		return ErrUserCodeNotFound
+	}
+
+	return nil
+}
+
+func (o *OAuth) exchangeDeviceCode(ctx context.Context, client *types.OAuthClient, req types.TokenRequest, IP string) (types.Tokens, error) {
+	code, err := o.devicerepo.PollDeviceCode(ctx, oauth.HashCode(req.DeviceCode))
+	if err != nil {
+		logger.Errorf("failed to poll device code: %s", err)
+		return types.Tokens{}, err
+	}
+
+	switch {
+	case code == nil || code.ClientId != client.ClientId:
+		return types.Tokens{}, ErrInvalidGrant
+	case code.IsExpired():
+		return types.Tokens{}, ErrExpiredToken
+	case code.SlowDown:
+		return types.Tokens{}, ErrSlowDown
+	case code.Status == types.DeviceCodePending:
+		return types.Tokens{}, ErrAuthorizationPending
+	case code.Status == types.DeviceCodeDenied:
+		return types.Tokens{}, ErrAccessDenied
+	case code.Status == types.DeviceCodeConsumed:
+		logger.Warnf("device code reuse (client: %s, user: %s)", code.ClientId, code.UserId)
+		return types.Tokens{}, ErrInvalidGrant
+	}
+
+	tokens, err := o.user.StartSession(ctx, types.SessionParams{
+		UserId:   code.UserId,
+		IP:       IP,
+		ClientId: client.ClientId,
+		Scope:    code.Scope,
+	})
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	return o.withIDToken(ctx, tokens, client.ClientId, "", time.Time{})
+}
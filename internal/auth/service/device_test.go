@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/auth"
+	"medods-test/pkg/oauth"
+	"testing"
+	"time"
+)
+
+const testUserId = "7a1c2e3d-4b5f-4a6e-9d8c-1b2a3c4d5e6f"
+
+var tvClient = types.OAuthClient{
+	ClientId:   "tv",
+	Type:       types.ClientTypePublic,
+	Scopes:     []string{"profile"},
+	GrantTypes: []string{oauth.GrantTypeDeviceCode},
+}
+
+// newTestOAuth returns an OAuth backed by the fake repositories that issues sessions of a
+// User with a real token manager.
+func newTestOAuth(t *testing.T, oauthrepo *fakeOAuthRepo) (*OAuth, *fakeSessionRepo) {
+	t.Helper()
+
+	tokenManager, err := auth.NewManager("test-signing-key", nil)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	sessions := &fakeSessionRepo{}
+	user := newTestUser(newFakeUserRepo(), &fakeAuditRepo{})
+	user.sessionrepo = sessions
+	user.tokenManager = tokenManager
+	user.accessTokenTTL = time.Minute
+	user.refreshTokenTTL = time.Hour
+
+	return &OAuth{
+		oauthrepo:            oauthrepo,
+		devicerepo:           newFakeDeviceRepo(),
+		user:                 user,
+		issuer:               "https://auth.example.com",
+		authorizationCodeTTL: time.Minute,
+		clientTokenTTL:       time.Minute,
+		deviceCodeTTL:        10 * time.Minute,
+		devicePollInterval:   5 * time.Second,
+		exchangeTokenTTL:     time.Minute,
+	}, sessions
+}
+
+func TestExchangeDeviceCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		expired    bool
+		polled     bool
+		clientId   string
+		deviceCode string
+		wantErr    error
+	}{
+		{
+			name:    "not yet approved",
+			status:  types.DeviceCodePending,
+			wantErr: ErrAuthorizationPending,
+		},
+		{
+			name:    "polled faster than the interval",
+			status:  types.DeviceCodePending,
+			polled:  true,
+			wantErr: ErrSlowDown,
+		},
+		{
+			name:    "denied by the user",
+			status:  types.DeviceCodeDenied,
+			wantErr: ErrAccessDenied,
+		},
+		{
+			name:    "expired",
+			status:  types.DeviceCodeApproved,
+			expired: true,
+			wantErr: ErrExpiredToken,
+		},
+		{
+			name:    "already exchanged",
+			status:  types.DeviceCodeConsumed,
+			wantErr: ErrInvalidGrant,
+		},
+		{
+			name:       "unknown device code",
+			status:     types.DeviceCodeApproved,
+			deviceCode: "unknown",
+			wantErr:    ErrInvalidGrant,
+		},
+		{
+			name:     "device code of another client",
+			status:   types.DeviceCodeApproved,
+			clientId: "kiosk",
+			wantErr:  ErrInvalidGrant,
+		},
+		{
+			name:   "approved",
+			status: types.DeviceCodeApproved,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kiosk := tvClient
+			kiosk.ClientId = "kiosk"
+			o, sessions := newTestOAuth(t, newFakeOAuthRepo(tvClient, kiosk))
+			devices := o.devicerepo.(*fakeDeviceRepo)
+
+			authorization, err := o.DeviceAuthorization(context.Background(), tvClient.ClientId, "", "profile")
+			if err != nil {
+				t.Fatalf("DeviceAuthorization() error = %v", err)
+			}
+			deviceHash := oauth.HashCode(authorization.DeviceCode)
+			devices.resolve(deviceHash, tt.status, testUserId)
+
+			code := devices.codes[deviceHash]
+			if tt.expired {
+				code.ExpiresAt = time.Now().Add(-time.Second)
+			}
+			if tt.polled {
+				polledAt := time.Now()
+				code.LastPolledAt = &polledAt
+			}
+			devices.codes[deviceHash] = code
+
+			req := types.TokenRequest{
+				GrantType:  oauth.GrantTypeDeviceCode,
+				ClientId:   tvClient.ClientId,
+				DeviceCode: authorization.DeviceCode,
+			}
+			if tt.clientId != "" {
+				req.ClientId = tt.clientId
+			}
+			if tt.deviceCode != "" {
+				req.DeviceCode = tt.deviceCode
+			}
+
+			tokens, err := o.Token(context.Background(), req, "203.0.113.1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Token() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(sessions.sessions) != 0 {
+					t.Errorf("Token() created %d sessions, want none", len(sessions.sessions))
+				}
+				return
+			}
+
+			if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.Scope != "profile" {
+				t.Errorf("Token() = %+v, want tokens for the profile scope", tokens)
+			}
+			if len(sessions.sessions) != 1 || sessions.sessions[0].UserId != testUserId || sessions.sessions[0].ClientId != tvClient.ClientId {
+				t.Errorf("Token() sessions = %+v, want one of %s for %s", sessions.sessions, testUserId, tvClient.ClientId)
+			}
+
+			// The code is consumed by the exchange.
+			code = devices.codes[deviceHash]
+			code.LastPolledAt = nil
+			devices.codes[deviceHash] = code
+			if _, err = o.Token(context.Background(), req, "203.0.113.1"); !errors.Is(err, ErrInvalidGrant) {
+				t.Errorf("second Token() error = %v, want %v", err, ErrInvalidGrant)
+			}
+		})
+	}
+}
@@ -0,0 +1,1536 @@
+package service
+
+import (
+	"context"
+	"medods-test/internal/auth/repo/postgres"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/email"
+	"medods-test/pkg/geoip"
+	"medods-test/pkg/hash"
+	"medods-test/pkg/risk"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The fakes keep their data in memory and implement only the methods the tests reach, the
+// embedded interfaces panic on any other call.
+
+type fakeUserRepo struct {
+	UserRepo
+
+	mu          sync.Mutex
+	users       map[string]types.User
+	deletions   map[string]types.AccountDeletionToken
+	resetTokens map[string]types.PasswordResetToken
+	// history holds the previous passwords of every user, newest first.
+	history map[string][]passwordHistoryEntry
+	// sessions, if set, has the sessions of the user revoked together with the changes
+	// the database revokes them for.
+	sessions *fakeSessionRepo
+}
+
+func newFakeUserRepo(users ...types.User) *fakeUserRepo {
+	r := &fakeUserRepo{users: make(map[string]types.User)}
+	for _, user := range users {
+		r.users[user.UserUUID] = user
+	}
+	return r
+}
+
+func (r *fakeUserRepo) Create(_ context.Context, user types.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[user.UserUUID] = user
+	return nil
+}
+
+func (r *fakeUserRepo) GetUserByID(_ context.Context, userId string) (*types.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[userId]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (r *fakeUserRepo) GetUserByEmail(_ context.Context, email string) (*types.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetUserByCreds ignores the organization, the tests use emails unique across organizations.
+func (r *fakeUserRepo) GetUserByCreds(_ context.Context, email string, password string, _ string) (*types.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) && user.Password == password {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepo) ListUsers(_ context.Context, filter types.UserFilter) ([]types.User, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := make([]types.User, 0)
+	for _, user := range r.users {
+		if strings.Contains(strings.ToLower(user.Email), strings.ToLower(filter.Query)) {
+			users = append(users, user)
+		}
+	}
+	slices.SortFunc(users, func(a, b types.User) int { return strings.Compare(a.Email, b.Email) })
+	total := len(users)
+	users = users[min(filter.Offset, total):min(filter.Offset+filter.Limit, total)]
+	return users, total, nil
+}
+
+func (r *fakeUserRepo) SetStatus(_ context.Context, change types.UserStatusChange) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[change.UserId]
+	if !ok {
+		return false, nil
+	}
+	now := time.Now()
+	user.Status, user.StatusReason, user.StatusChangedAt, user.PurgeAt = change.Status, change.Reason, &now, change.PurgeAt
+	r.users[change.UserId] = user
+	if change.Status != types.UserStatusActive && r.sessions != nil {
+		_, _ = r.sessions.RevokeUserSessions(context.Background(), change.UserId)
+	}
+	return true, nil
+}
+
+func (r *fakeUserRepo) RequirePasswordReset(_ context.Context, token types.PasswordResetToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resetTokens == nil {
+		r.resetTokens = make(map[string]types.PasswordResetToken)
+	}
+	r.resetTokens[token.TokenHash] = token
+	if user, ok := r.users[token.UserId]; ok {
+		user.PasswordResetRequired = true
+		r.users[token.UserId] = user
+	}
+	if r.sessions != nil {
+		_, _ = r.sessions.RevokeUserSessions(context.Background(), token.UserId)
+	}
+	return nil
+}
+
+func (r *fakeUserRepo) PurgeUsers(_ context.Context, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	userIds := make([]string, 0)
+	for userId, user := range r.users {
+		if user.Status == types.UserStatusPendingDeletion && user.PurgeAt != nil && !user.PurgeAt.After(time.Now()) && len(userIds) < limit {
+			userIds = append(userIds, userId)
+			delete(r.users, userId)
+		}
+	}
+	return userIds, nil
+}
+
+type passwordHistoryEntry struct {
+	hash      string
+	createdAt time.Time
+}
+
+func (r *fakeUserRepo) ChangePassword(_ context.Context, change types.PasswordChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user := r.users[change.UserId]
+	user.Password = change.PasswordHash
+	r.users[change.UserId] = user
+	return nil
+}
+
+func (r *fakeUserRepo) GetUserByResetToken(_ context.Context, tokenHash string) (*types.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.resetTokens[tokenHash]
+	if !ok || time.Now().After(token.ExpiresAt) {
+		return nil, nil
+	}
+	user, ok := r.users[token.UserId]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (r *fakeUserRepo) ResetPassword(_ context.Context, tokenHash string, passwordHash string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.resetTokens[tokenHash]
+	if !ok || time.Now().After(token.ExpiresAt) {
+		return "", nil
+	}
+	delete(r.resetTokens, tokenHash)
+	user := r.users[token.UserId]
+	user.Password, user.PasswordResetRequired = passwordHash, false
+	r.users[token.UserId] = user
+	return token.UserId, nil
+}
+
+func (r *fakeUserRepo) ListPasswordHistory(_ context.Context, userId string, limit int, since time.Time) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hashes := make([]string, 0)
+	for _, entry := range r.history[userId] {
+		if entry.createdAt.After(since) && len(hashes) < limit {
+			hashes = append(hashes, entry.hash)
+		}
+	}
+	return hashes, nil
+}
+
+func (r *fakeUserRepo) AddPasswordHistory(_ context.Context, userId string, passwordHash string, keep int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.history == nil {
+		r.history = make(map[string][]passwordHistoryEntry)
+	}
+	history := append([]passwordHistoryEntry{{hash: passwordHash, createdAt: time.Now()}}, r.history[userId]...)
+	r.history[userId] = history[:min(len(history), keep)]
+	return nil
+}
+
+func (r *fakeUserRepo) DeletePasswordHistory(_ context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for userId, history := range r.history {
+		kept := slices.DeleteFunc(history, func(entry passwordHistoryEntry) bool { return !entry.createdAt.After(before) })
+		deleted += int64(len(history) - len(kept))
+		r.history[userId] = kept
+	}
+	return deleted, nil
+}
+
+func (r *fakeUserRepo) CreateDeletionRequest(_ context.Context, token types.AccountDeletionToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.deletions == nil {
+		r.deletions = make(map[string]types.AccountDeletionToken)
+	}
+	r.deletions[token.TokenHash] = token
+	return nil
+}
+
+// EraseUser removes the user, consuming the deletion token of tokenHash unless it is empty.
+func (r *fakeUserRepo) EraseUser(_ context.Context, userId string, tokenHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if tokenHash != "" {
+		token, ok := r.deletions[tokenHash]
+		if !ok || token.UserId != userId || time.Now().After(token.ExpiresAt) {
+			return false, nil
+		}
+		delete(r.deletions, tokenHash)
+	}
+	if _, ok := r.users[userId]; !ok {
+		return false, nil
+	}
+	delete(r.users, userId)
+	return true, nil
+}
+
+type fakeIdentityRepo struct {
+	IdentityRepo
+
+	mu           sync.Mutex
+	identities   []types.ExternalIdentity
+	linkRequests map[string]types.SocialLinkRequest
+}
+
+func newFakeIdentityRepo() *fakeIdentityRepo {
+	return &fakeIdentityRepo{linkRequests: make(map[string]types.SocialLinkRequest)}
+}
+
+func (r *fakeIdentityRepo) CreateIdentity(_ context.Context, identity types.ExternalIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *fakeIdentityRepo) GetIdentity(_ context.Context, provider string, subject string) (*types.ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeIdentityRepo) UpdateIdentityLogin(context.Context, string, string) error {
+	return nil
+}
+
+func (r *fakeIdentityRepo) ListUserIdentities(_ context.Context, userId string) ([]types.ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	identities := make([]types.ExternalIdentity, 0)
+	for _, identity := range r.identities {
+		if identity.UserId == userId {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *fakeIdentityRepo) CreateLinkRequest(_ context.Context, request types.SocialLinkRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.linkRequests[request.TokenHash] = request
+	return nil
+}
+
+func (r *fakeIdentityRepo) GetLinkRequest(_ context.Context, tokenHash string) (*types.SocialLinkRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	request, ok := r.linkRequests[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	return &request, nil
+}
+
+func (r *fakeIdentityRepo) AddLinkRequestAttempt(_ context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if request, ok := r.linkRequests[tokenHash]; ok {
+		request.Attempts++
+		r.linkRequests[tokenHash] = request
+	}
+	return nil
+}
+
+func (r *fakeIdentityRepo) DeleteLinkRequest(_ context.Context, tokenHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.linkRequests[tokenHash]
+	delete(r.linkRequests, tokenHash)
+	return ok, nil
+}
+
+type fakeRBACRepo struct {
+	RBACRepo
+
+	mu          sync.Mutex
+	permissions []types.Permission
+	roles       []types.Role
+	userRoles   []types.UserRole
+	sourceRoles map[string][]string
+}
+
+func (r *fakeRBACRepo) EnsurePermissions(_ context.Context, permissions []types.Permission) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, permission := range permissions {
+		if !slices.ContainsFunc(r.permissions, func(p types.Permission) bool { return p.Name == permission.Name }) {
+			r.permissions = append(r.permissions, permission)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRBACRepo) CreateRole(_ context.Context, role types.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.roles {
+		if existing.Name == role.Name {
+			return postgres.ErrUniqueContraintFailed
+		}
+	}
+	r.roles = append(r.roles, role)
+	return nil
+}
+
+func (r *fakeRBACRepo) GetRoleByName(_ context.Context, name string) (*types.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, role := range r.roles {
+		if role.Name == name {
+			return &role, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRBACRepo) UpdateRole(_ context.Context, role types.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.roles {
+		if existing.Name == role.Name && existing.Id != role.Id {
+			return postgres.ErrUniqueContraintFailed
+		}
+		if existing.Id == role.Id {
+			r.roles[i] = role
+		}
+	}
+	return nil
+}
+
+func (r *fakeRBACRepo) GrantRolePermissions(_ context.Context, roleId string, permissions []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, role := range r.roles {
+		if role.Id != roleId {
+			continue
+		}
+		for _, permission := range permissions {
+			if !slices.Contains(role.Permissions, permission) {
+				r.roles[i].Permissions = append(r.roles[i].Permissions, permission)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *fakeRBACRepo) DeleteRole(_ context.Context, roleId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, role := range r.roles {
+		if role.Id == roleId {
+			r.roles = slices.Delete(r.roles, i, i+1)
+			r.userRoles = slices.DeleteFunc(r.userRoles, func(userRole types.UserRole) bool { return userRole.RoleId == roleId })
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRBACRepo) AssignRole(_ context.Context, userRole types.UserRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.userRoles {
+		if existing.UserId == userRole.UserId && existing.RoleId == userRole.RoleId && existing.Source == userRole.Source {
+			return nil
+		}
+	}
+	r.userRoles = append(r.userRoles, userRole)
+	return nil
+}
+
+func (r *fakeRBACRepo) UnassignRole(_ context.Context, userRole types.UserRole) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.userRoles {
+		if existing.UserId == userRole.UserId && existing.RoleId == userRole.RoleId && existing.Source == userRole.Source {
+			r.userRoles = slices.Delete(r.userRoles, i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRBACRepo) GetRoleByID(_ context.Context, roleId string) (*types.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, role := range r.roles {
+		if role.Id == roleId {
+			return &role, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRBACRepo) GetRolePermissions(_ context.Context, roleNames []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	permissions := make([]string, 0)
+	for _, role := range r.roles {
+		if slices.Contains(roleNames, role.Name) {
+			permissions = append(permissions, role.Permissions...)
+		}
+	}
+	return permissions, nil
+}
+
+func (r *fakeRBACRepo) ReplaceSourceRoles(_ context.Context, userId string, source string, roleNames []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sourceRoles == nil {
+		r.sourceRoles = make(map[string][]string)
+	}
+	r.sourceRoles[source+"/"+userId] = roleNames
+	return nil
+}
+
+// ListUserRoles returns the assigned roles of the user followed by those granted by directories.
+func (r *fakeRBACRepo) ListUserRoles(_ context.Context, userId string) ([]types.UserRole, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var userRoles []types.UserRole
+	for _, userRole := range r.userRoles {
+		if userRole.UserId != userId {
+			continue
+		}
+		for _, role := range r.roles {
+			if role.Id == userRole.RoleId {
+				userRole.RoleName = role.Name
+			}
+		}
+		userRoles = append(userRoles, userRole)
+	}
+	for key, roleNames := range r.sourceRoles {
+		source, id, _ := strings.Cut(key, "/")
+		if id != userId {
+			continue
+		}
+		for _, roleName := range roleNames {
+			userRoles = append(userRoles, types.UserRole{UserId: userId, RoleName: roleName, Source: source})
+		}
+	}
+	return userRoles, nil
+}
+
+type fakeSessionRepo struct {
+	SessionRepo
+
+	mu       sync.Mutex
+	sessions []types.Session
+	// createErr is returned by CreateSession instead of storing the session.
+	createErr error
+}
+
+func (r *fakeSessionRepo) CreateSession(_ context.Context, session types.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.createErr != nil {
+		return r.createErr
+	}
+	r.sessions = append(r.sessions, session)
+	return nil
+}
+
+func (r *fakeSessionRepo) ListUserSessions(_ context.Context, userId string) ([]types.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := make([]types.Session, 0)
+	for _, session := range r.sessions {
+		if session.UserId == userId {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeSessionRepo) GetSessionById(_ context.Context, sessionId string) (*types.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.SessionId == sessionId {
+			return &session, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSessionRepo) ListActiveSessions(_ context.Context, userId string) ([]types.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := make([]types.Session, 0)
+	for _, session := range r.sessions {
+		if session.UserId == userId && !session.Used && !session.IsRevoked() && !session.IsRefreshTokenExpired() {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeSessionRepo) RevokeSession(_ context.Context, userId string, sessionId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for i := range r.sessions {
+		if r.sessions[i].UserId == userId && r.sessions[i].SessionId == sessionId && !r.sessions[i].IsRevoked() {
+			r.sessions[i].RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeSessionRepo) RevokeUserSessions(_ context.Context, userId string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var revoked int64
+	for i := range r.sessions {
+		if r.sessions[i].UserId == userId && !r.sessions[i].IsRevoked() {
+			r.sessions[i].RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// RevokeSessionFamily revokes the session of sessionId, the fake keeps no refreshed sessions.
+func (r *fakeSessionRepo) RevokeSessionFamily(_ context.Context, sessionId string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var revoked int64
+	for i := range r.sessions {
+		if r.sessions[i].SessionId == sessionId && r.sessions[i].RevokedAt == nil {
+			r.sessions[i].RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+type fakeMFARepo struct {
+	MFARepo
+
+	mu         sync.Mutex
+	challenges map[string]types.MFAChallenge
+}
+
+func newFakeMFARepo() *fakeMFARepo {
+	return &fakeMFARepo{challenges: make(map[string]types.MFAChallenge)}
+}
+
+func (r *fakeMFARepo) CreateChallenge(_ context.Context, challenge types.MFAChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.challenges[challenge.TokenHash] = challenge
+	return nil
+}
+
+func (r *fakeMFARepo) GetChallenge(_ context.Context, tokenHash string) (*types.MFAChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	challenge, ok := r.challenges[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	return &challenge, nil
+}
+
+func (r *fakeMFARepo) AddChallengeAttempt(_ context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if challenge, ok := r.challenges[tokenHash]; ok {
+		challenge.Attempts++
+		r.challenges[tokenHash] = challenge
+	}
+	return nil
+}
+
+func (r *fakeMFARepo) DeleteChallenge(_ context.Context, tokenHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.challenges[tokenHash]
+	delete(r.challenges, tokenHash)
+	return ok, nil
+}
+
+type fakeKnownDeviceRepo struct {
+	KnownDeviceRepo
+
+	mu      sync.Mutex
+	devices map[string]types.KnownDevice
+}
+
+func newFakeKnownDeviceRepo() *fakeKnownDeviceRepo {
+	return &fakeKnownDeviceRepo{devices: make(map[string]types.KnownDevice)}
+}
+
+func (r *fakeKnownDeviceRepo) GetDevice(_ context.Context, userId string, deviceHash string) (*types.KnownDevice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, device := range r.devices {
+		if device.UserId == userId && device.DeviceHash == deviceHash {
+			return &device, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeKnownDeviceRepo) HasDevices(_ context.Context, userId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, device := range r.devices {
+		if device.UserId == userId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeKnownDeviceRepo) SaveDevice(_ context.Context, device types.KnownDevice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.devices[device.Id] = device
+	return nil
+}
+
+func (r *fakeKnownDeviceRepo) ListDevices(_ context.Context, userId string) ([]types.KnownDevice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	devices := make([]types.KnownDevice, 0)
+	for _, device := range r.devices {
+		if device.UserId == userId {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+type fakePhoneRepo struct {
+	PhoneRepo
+
+	mu     sync.Mutex
+	phones map[string]types.UserPhone
+	codes  map[string]types.SMSCode
+}
+
+func newFakePhoneRepo(phones ...types.UserPhone) *fakePhoneRepo {
+	r := &fakePhoneRepo{phones: make(map[string]types.UserPhone), codes: make(map[string]types.SMSCode)}
+	for _, phone := range phones {
+		r.phones[phone.UserId] = phone
+	}
+	return r
+}
+
+func (r *fakePhoneRepo) GetPhone(_ context.Context, userId string) (*types.UserPhone, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	phone, ok := r.phones[userId]
+	if !ok {
+		return nil, nil
+	}
+	return &phone, nil
+}
+
+func (r *fakePhoneRepo) SavePhone(_ context.Context, phone types.UserPhone) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.phones[phone.UserId]; ok {
+		if current.Phone == phone.Phone {
+			phone.VerifiedAt = current.VerifiedAt
+		}
+		phone.PendingPhone, phone.PendingUseForMFA = current.PendingPhone, current.PendingUseForMFA
+	}
+	r.phones[phone.UserId] = phone
+	return nil
+}
+
+func (r *fakePhoneRepo) SavePendingPhone(_ context.Context, userId string, number string, useForMFA bool) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	phone, ok := r.phones[userId]
+	if !ok {
+		return false, nil
+	}
+	phone.PendingPhone, phone.PendingUseForMFA = number, useForMFA
+	r.phones[userId] = phone
+	return true, nil
+}
+
+func (r *fakePhoneRepo) VerifyPhone(_ context.Context, userId string, number string, verifiedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	phone, ok := r.phones[userId]
+	if !ok || (phone.Phone != number && phone.PendingPhone != number) {
+		return false, nil
+	}
+	if phone.Phone != number {
+		phone.Phone, phone.UseForMFA = number, phone.PendingUseForMFA
+	}
+	phone.PendingPhone = ""
+	phone.VerifiedAt = &verifiedAt
+	r.phones[userId] = phone
+	return true, nil
+}
+
+func (r *fakePhoneRepo) CreateSMSCode(_ context.Context, code types.SMSCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[code.TokenHash] = code
+	return nil
+}
+
+func (r *fakePhoneRepo) GetSMSCode(_ context.Context, tokenHash string) (*types.SMSCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	return &code, nil
+}
+
+func (r *fakePhoneRepo) CountSMSCodes(_ context.Context, userId string, purpose string, since time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int
+	for _, code := range r.codes {
+		if code.UserId == userId && code.Purpose == purpose && !code.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakePhoneRepo) AddSMSCodeAttempt(_ context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if code, ok := r.codes[tokenHash]; ok {
+		code.Attempts++
+		r.codes[tokenHash] = code
+	}
+	return nil
+}
+
+func (r *fakePhoneRepo) UseSMSCode(_ context.Context, tokenHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.codes[tokenHash]
+	delete(r.codes, tokenHash)
+	return ok, nil
+}
+
+// fakePasswordPolicy accepts every password.
+type fakePasswordPolicy struct{}
+
+func (fakePasswordPolicy) Check(context.Context, string, string) error {
+	return nil
+}
+
+// fakeEmailSender keeps the sent emails instead of delivering them.
+type fakeEmailSender struct {
+	mu   sync.Mutex
+	sent []email.Send
+}
+
+func (s *fakeEmailSender) Send(input email.Send) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, input)
+	return nil
+}
+
+// messages returns the emails sent to recipient, oldest first.
+func (s *fakeEmailSender) messages(recipient string) []email.Send {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := make([]email.Send, 0, len(s.sent))
+	for _, message := range s.sent {
+		if message.Recipient == recipient {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// fakeLoginHistoryRepo keeps attempts in the order they are added and lists them newest first.
+type fakeLoginHistoryRepo struct {
+	LoginHistoryRepo
+
+	mu       sync.Mutex
+	attempts []types.LoginAttempt
+}
+
+func (r *fakeLoginHistoryRepo) AddLoginAttempt(_ context.Context, attempt types.LoginAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = time.Now()
+	}
+	r.attempts = append(r.attempts, attempt)
+	return nil
+}
+
+func (r *fakeLoginHistoryRepo) ListLoginAttempts(_ context.Context, userId string, limit int, since time.Time) ([]types.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempts := make([]types.LoginAttempt, 0)
+	for i := len(r.attempts) - 1; i >= 0 && len(attempts) < limit; i-- {
+		if attempt := r.attempts[i]; attempt.UserId == userId && !attempt.CreatedAt.Before(since) {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts, nil
+}
+
+func (r *fakeLoginHistoryRepo) DeleteLoginAttempts(_ context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.attempts[:0]
+	for _, attempt := range r.attempts {
+		if !attempt.CreatedAt.Before(before) {
+			kept = append(kept, attempt)
+		}
+	}
+	deleted := int64(len(r.attempts) - len(kept))
+	r.attempts = kept
+	return deleted, nil
+}
+
+// fakeAuditRepo chains the appended events the way the database does.
+type fakeAuditRepo struct {
+	AuditRepo
+
+	mu     sync.Mutex
+	events []types.AuditEvent
+}
+
+func (r *fakeAuditRepo) AppendEvent(_ context.Context, event types.AuditEvent) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.Id = int64(len(r.events) + 1)
+	event.PrevHash = types.AuditGenesisHash
+	if len(r.events) > 0 {
+		event.PrevHash = r.events[len(r.events)-1].Hash
+	}
+	if event.Metadata == nil {
+		event.Metadata = map[string]string{}
+	}
+	event.DetailsHash = event.ComputeDetailsHash()
+	event.Hash = event.ComputeHash()
+	r.events = append(r.events, event)
+	return event.Id, nil
+}
+
+func (r *fakeAuditRepo) ListEvents(_ context.Context, filter types.AuditFilter) ([]types.AuditEvent, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matching := make([]types.AuditEvent, 0)
+	for i := len(r.events) - 1; i >= 0; i-- {
+		event := r.events[i]
+		if (filter.Type == "" || event.Type == filter.Type) &&
+			(filter.UserId == "" || event.UserId == filter.UserId) &&
+			(filter.ActorId == "" || event.ActorId == filter.ActorId) &&
+			(filter.From.IsZero() || !event.CreatedAt.Before(filter.From)) &&
+			(filter.To.IsZero() || event.CreatedAt.Before(filter.To)) {
+			matching = append(matching, event)
+		}
+	}
+	from := min(filter.Offset, len(matching))
+	return matching[from:min(from+filter.Limit, len(matching))], len(matching), nil
+}
+
+func (r *fakeAuditRepo) ListEventsAfter(_ context.Context, afterId int64, limit int) ([]types.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]types.AuditEvent, 0, limit)
+	for _, event := range r.events {
+		if event.Id > afterId && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeAuditRepo) ListUserEvents(_ context.Context, userId string) ([]types.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]types.AuditEvent, 0)
+	for _, event := range r.events {
+		if event.UserId == userId || event.ActorId == userId {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// eraseDetails removes the personal data of the events about or by the user, as erasing the
+// user does.
+func (r *fakeAuditRepo) eraseDetails(userId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, event := range r.events {
+		if event.UserId == userId || event.ActorId == userId {
+			event.IP, event.UserAgent, event.Metadata, event.DetailsSalt = "", "", map[string]string{}, ""
+			r.events[i] = event
+		}
+	}
+}
+
+func (r *fakeAuditRepo) eventTypes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	eventTypes := make([]string, 0, len(r.events))
+	for _, event := range r.events {
+		eventTypes = append(eventTypes, event.Type)
+	}
+	return eventTypes
+}
+
+type fakeWebhookRepo struct {
+	WebhookRepo
+
+	mu            sync.Mutex
+	subscriptions []types.WebhookSubscription
+	deliveries    []types.WebhookDelivery
+	attempts      []types.WebhookAttempt
+}
+
+func (r *fakeWebhookRepo) CreateSubscription(_ context.Context, subscription types.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions = append(r.subscriptions, subscription)
+	return nil
+}
+
+func (r *fakeWebhookRepo) GetSubscription(_ context.Context, subscriptionId string) (*types.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, subscription := range r.subscriptions {
+		if subscription.Id == subscriptionId {
+			return &subscription, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeWebhookRepo) UpdateSubscription(_ context.Context, subscription types.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.subscriptions {
+		if r.subscriptions[i].Id == subscription.Id {
+			r.subscriptions[i] = subscription
+		}
+	}
+	return nil
+}
+
+func (r *fakeWebhookRepo) ListEventSubscriptions(_ context.Context, eventType string) ([]types.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var subscriptions []types.WebhookSubscription
+	for _, subscription := range r.subscriptions {
+		if subscription.Active && (slices.Contains(subscription.EventTypes, eventType) || slices.Contains(subscription.EventTypes, types.WebhookEventAll)) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r *fakeWebhookRepo) CreateDelivery(_ context.Context, delivery types.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.Status = types.WebhookDeliveryPending
+	r.deliveries = append(r.deliveries, delivery)
+	return nil
+}
+
+// ClaimDeliveries does not check that the subscriptions are active.
+func (r *fakeWebhookRepo) ClaimDeliveries(_ context.Context, now time.Time, leaseUntil time.Time, limit int) ([]types.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []types.WebhookDelivery
+	for i, delivery := range r.deliveries {
+		if delivery.Status == types.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) && len(claimed) < limit {
+			r.deliveries[i].NextAttemptAt = leaseUntil
+			claimed = append(claimed, r.deliveries[i])
+		}
+	}
+	return claimed, nil
+}
+
+func (r *fakeWebhookRepo) SaveAttempt(_ context.Context, delivery types.WebhookDelivery, attempt types.WebhookAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.deliveries {
+		if r.deliveries[i].Id == delivery.Id {
+			r.deliveries[i] = delivery
+		}
+	}
+	r.attempts = append(r.attempts, attempt)
+	return nil
+}
+
+type fakeSignalRepo struct {
+	SignalRepo
+}
+
+func (fakeSignalRepo) ListEventStreams(context.Context, string) ([]types.SignalStream, error) {
+	return nil, nil
+}
+
+// fakeGeoLocator locates the addresses it has a location of.
+type fakeGeoLocator map[string]geoip.Location
+
+func (l fakeGeoLocator) Locate(ip string) (geoip.Location, bool) {
+	location, ok := l[ip]
+	return location, ok
+}
+
+func newTestAudit(auditrepo AuditRepo) *Audit {
+	return &Audit{
+		auditrepo:   auditrepo,
+		webhookrepo: &fakeWebhookRepo{},
+		signalrepo:  fakeSignalRepo{},
+		locator:     noGeoLocator{},
+	}
+}
+
+// newTestUser returns a User backed by the fake repositories. Dependencies a test needs beyond
+// them are set on the result.
+func newTestUser(userrepo *fakeUserRepo, auditrepo *fakeAuditRepo) *User {
+	loginrepo := &fakeLoginHistoryRepo{}
+	audit := newTestAudit(auditrepo)
+	return &User{
+		userrepo:  userrepo,
+		loginrepo: loginrepo,
+		hasher:    hash.NewSHA1Hasher("test"),
+		audit:     audit,
+		locator:   noGeoLocator{},
+		// The empty policy allows every sign-in.
+		risk: &RiskEngine{
+			loginrepo: loginrepo,
+			locator:   noGeoLocator{},
+			audit:     audit,
+			config:    RiskConfig{Policy: &risk.Policy{}},
+		},
+		verifiers: make(map[string]CredentialVerifier),
+	}
+}
+
+type fakeOAuthRepo struct {
+	OAuthRepo
+
+	mu      sync.Mutex
+	clients map[string]types.OAuthClient
+	secrets []types.OAuthClientSecret
+	codes   map[string]types.AuthorizationCode
+}
+
+func newFakeOAuthRepo(clients ...types.OAuthClient) *fakeOAuthRepo {
+	r := &fakeOAuthRepo{clients: make(map[string]types.OAuthClient), codes: make(map[string]types.AuthorizationCode)}
+	for _, client := range clients {
+		r.clients[client.ClientId] = client
+	}
+	return r
+}
+
+func (r *fakeOAuthRepo) GetClientByID(_ context.Context, clientId string) (*types.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client, ok := r.clients[clientId]
+	if !ok {
+		return nil, nil
+	}
+	return &client, nil
+}
+
+func (r *fakeOAuthRepo) GetActiveClientSecrets(_ context.Context, clientId string) ([]types.OAuthClientSecret, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	secrets := make([]types.OAuthClientSecret, 0)
+	for _, secret := range r.secrets {
+		if secret.ClientId == clientId && !secret.IsExpired() {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets, nil
+}
+
+func (r *fakeOAuthRepo) CreateClient(_ context.Context, client types.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[client.ClientId] = client
+	return nil
+}
+
+// RotateClientSecret keeps the newest current secret until previousExpiresAt and removes the
+// others, as the database does.
+func (r *fakeOAuthRepo) RotateClientSecret(_ context.Context, secret types.OAuthClientSecret, previousExpiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var newest *types.OAuthClientSecret
+	secrets := make([]types.OAuthClientSecret, 0, 2)
+	for _, current := range r.secrets {
+		if current.ClientId != secret.ClientId {
+			secrets = append(secrets, current)
+		} else if !current.IsExpired() && (newest == nil || current.CreatedAt.After(newest.CreatedAt)) {
+			newest = &current
+		}
+	}
+	if newest != nil {
+		if newest.ExpiresAt == nil || newest.ExpiresAt.After(previousExpiresAt) {
+			newest.ExpiresAt = &previousExpiresAt
+		}
+		secrets = append(secrets, *newest)
+	}
+	r.secrets = append(secrets, secret)
+	return nil
+}
+
+func (r *fakeOAuthRepo) DeleteClientSecret(_ context.Context, clientId string, secretId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, secret := range r.secrets {
+		if secret.ClientId == clientId && secret.Id == secretId {
+			r.secrets = slices.Delete(r.secrets, i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fakeClientRepo manages the clients of a fakeOAuthRepo, as the same repository does both.
+type fakeClientRepo struct {
+	ClientRepo
+
+	oauthrepo *fakeOAuthRepo
+}
+
+func (r fakeClientRepo) CreateClient(ctx context.Context, client types.OAuthClient) error {
+	return r.oauthrepo.CreateClient(ctx, client)
+}
+
+func (r fakeClientRepo) GetClientByID(ctx context.Context, clientId string) (*types.OAuthClient, error) {
+	return r.oauthrepo.GetClientByID(ctx, clientId)
+}
+
+func (r fakeClientRepo) RotateClientSecret(ctx context.Context, secret types.OAuthClientSecret, previousExpiresAt time.Time) error {
+	return r.oauthrepo.RotateClientSecret(ctx, secret, previousExpiresAt)
+}
+
+func (r fakeClientRepo) DeleteClientSecret(ctx context.Context, clientId string, secretId string) (bool, error) {
+	return r.oauthrepo.DeleteClientSecret(ctx, clientId, secretId)
+}
+
+func (r *fakeOAuthRepo) CreateAuthorizationCode(_ context.Context, code types.AuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[code.CodeHash] = code
+	return nil
+}
+
+func (r *fakeOAuthRepo) GetAuthorizationCode(_ context.Context, codeHash string) (*types.AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[codeHash]
+	if !ok {
+		return nil, nil
+	}
+	return &code, nil
+}
+
+func (r *fakeOAuthRepo) ConsumeAuthorizationCode(_ context.Context, codeHash string, sessionId string) (*types.AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[codeHash]
+	if !ok {
+		return nil, nil
+	}
+	if !code.Used {
+		consumed := code
+		consumed.Used, consumed.SessionId = true, sessionId
+		r.codes[codeHash] = consumed
+	}
+	return &code, nil
+}
+
+// fakeDeviceRepo polls the device codes the way the database does.
+type fakeDeviceRepo struct {
+	DeviceRepo
+
+	mu    sync.Mutex
+	codes map[string]types.DeviceCode
+}
+
+func newFakeDeviceRepo() *fakeDeviceRepo {
+	return &fakeDeviceRepo{codes: make(map[string]types.DeviceCode)}
+}
+
+func (r *fakeDeviceRepo) CreateDeviceCode(_ context.Context, code types.DeviceCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[code.DeviceCodeHash] = code
+	return nil
+}
+
+func (r *fakeDeviceRepo) PollDeviceCode(_ context.Context, deviceCodeHash string) (*types.DeviceCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[deviceCodeHash]
+	if !ok {
+		return nil, nil
+	}
+
+	now := time.Now()
+	code.SlowDown = code.LastPolledAt != nil && now.Sub(*code.LastPolledAt) < code.Interval
+	polled := code
+	polled.LastPolledAt = &now
+	if !code.SlowDown && code.Status == types.DeviceCodeApproved {
+		polled.Status = types.DeviceCodeConsumed
+	}
+	r.codes[deviceCodeHash] = polled
+	return &code, nil
+}
+
+// resolve sets the status the user chose for the device code.
+func (r *fakeDeviceRepo) resolve(deviceCodeHash string, status string, userId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code := r.codes[deviceCodeHash]
+	code.Status, code.UserId = status, userId
+	r.codes[deviceCodeHash] = code
+}
+
+type fakeOrganizationRepo struct {
+	OrganizationRepo
+
+	mu          sync.Mutex
+	orgs        map[string]types.Organization
+	memberships map[string]types.Membership
+	invitations []types.Invitation
+}
+
+func newFakeOrganizationRepo(orgs ...types.Organization) *fakeOrganizationRepo {
+	r := &fakeOrganizationRepo{orgs: make(map[string]types.Organization), memberships: make(map[string]types.Membership)}
+	for _, org := range orgs {
+		r.orgs[org.Id] = org
+	}
+	return r
+}
+
+func (r *fakeOrganizationRepo) GetOrganizationByID(_ context.Context, orgId string) (*types.Organization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	org, ok := r.orgs[orgId]
+	if !ok {
+		return nil, nil
+	}
+	return &org, nil
+}
+
+func (r *fakeOrganizationRepo) GetMembership(_ context.Context, orgId string, userId string) (*types.Membership, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	membership, ok := r.memberships[orgId+"/"+userId]
+	if !ok {
+		return nil, nil
+	}
+	return &membership, nil
+}
+
+// SetMembership keeps the role ids of the member in Roles.
+func (r *fakeOrganizationRepo) SetMembership(_ context.Context, orgId string, userId string, roleIds []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.memberships[orgId+"/"+userId] = types.Membership{OrganizationId: orgId, UserId: userId, Roles: roleIds}
+	return nil
+}
+
+func (r *fakeOrganizationRepo) CreateInvitation(_ context.Context, invitation types.Invitation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invitations = append(r.invitations, invitation)
+	return nil
+}
+
+func (r *fakeOrganizationRepo) ListUserMemberships(_ context.Context, userId string) ([]types.Membership, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memberships := make([]types.Membership, 0)
+	for _, membership := range r.memberships {
+		if membership.UserId == userId {
+			memberships = append(memberships, membership)
+		}
+	}
+	return memberships, nil
+}
+
+type fakeTokenExchangeRepo struct {
+	TokenExchangeRepo
+
+	exchanges []types.TokenExchange
+}
+
+func (r *fakeTokenExchangeRepo) CreateTokenExchange(_ context.Context, exchange types.TokenExchange) error {
+	r.exchanges = append(r.exchanges, exchange)
+	return nil
+}
+
+func (r *fakeTokenExchangeRepo) ListUserTokenExchanges(_ context.Context, userId string) ([]types.TokenExchange, error) {
+	exchanges := make([]types.TokenExchange, 0)
+	for _, exchange := range r.exchanges {
+		if exchange.Subject == userId || exchange.Actor == userId {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+	return exchanges, nil
+}
+
+type fakeNotificationRepo struct {
+	NotificationRepo
+
+	mu          sync.Mutex
+	preferences map[string][]types.NotificationPreference
+	timeZones   map[string]string
+
+	pending       []types.PendingNotification
+	lastPendingId int64
+
+	// summaries holds the next summary time of the users that have a schedule, nil until it
+	// is set. ListDueSummaries lists the active users of userrepo.
+	summaries map[string]*time.Time
+	userrepo  *fakeUserRepo
+}
+
+func (r *fakeNotificationRepo) ListPreferences(_ context.Context, userId string) ([]types.NotificationPreference, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.preferences[userId], nil
+}
+
+func (r *fakeNotificationRepo) SavePreferences(_ context.Context, userId string, preferences []types.NotificationPreference) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.preferences == nil {
+		r.preferences = make(map[string][]types.NotificationPreference)
+	}
+	for _, preference := range preferences {
+		i := slices.IndexFunc(r.preferences[userId], func(stored types.NotificationPreference) bool {
+			return stored.Event == preference.Event
+		})
+		if i < 0 {
+			r.preferences[userId] = append(r.preferences[userId], preference)
+		} else {
+			r.preferences[userId][i] = preference
+		}
+	}
+	return nil
+}
+
+func (r *fakeNotificationRepo) EnqueueNotification(_ context.Context, notification types.PendingNotification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastPendingId++
+	notification.Id = r.lastPendingId
+	notification.CreatedAt = time.Now()
+	r.pending = append(r.pending, notification)
+	return nil
+}
+
+func (r *fakeNotificationRepo) ListPendingUsers(_ context.Context, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	userIds := make([]string, 0)
+	for _, notification := range r.pending {
+		if !slices.Contains(userIds, notification.UserId) {
+			userIds = append(userIds, notification.UserId)
+		}
+	}
+	slices.Sort(userIds)
+	return userIds[:min(limit, len(userIds))], nil
+}
+
+func (r *fakeNotificationRepo) TakePendingNotifications(_ context.Context, userId string) ([]types.PendingNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	taken := make([]types.PendingNotification, 0)
+	kept := make([]types.PendingNotification, 0, len(r.pending))
+	for _, notification := range r.pending {
+		if notification.UserId == userId {
+			taken = append(taken, notification)
+		} else {
+			kept = append(kept, notification)
+		}
+	}
+	r.pending = kept
+	return taken, nil
+}
+
+func (r *fakeNotificationRepo) GetTimeZone(_ context.Context, userId string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.timeZones[userId], nil
+}
+
+func (r *fakeNotificationRepo) SaveTimeZone(_ context.Context, userId string, timeZone string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.timeZones == nil {
+		r.timeZones = make(map[string]string)
+	}
+	r.timeZones[userId] = timeZone
+	if r.summaries == nil {
+		r.summaries = make(map[string]*time.Time)
+	}
+	r.summaries[userId] = nil
+	return nil
+}
+
+func (r *fakeNotificationRepo) ListDueSummaries(_ context.Context, now time.Time, limit int) ([]types.SummarySchedule, error) {
+	r.userrepo.mu.Lock()
+	userIds := make([]string, 0, len(r.userrepo.users))
+	for userId, user := range r.userrepo.users {
+		if user.Status == types.UserStatusActive {
+			userIds = append(userIds, userId)
+		}
+	}
+	r.userrepo.mu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	schedules := make([]types.SummarySchedule, 0)
+	for _, userId := range userIds {
+		nextAt := r.summaries[userId]
+		if nextAt != nil && nextAt.After(now) {
+			continue
+		}
+		timeZone := r.timeZones[userId]
+		if timeZone == "" {
+			timeZone = time.UTC.String()
+		}
+		schedules = append(schedules, types.SummarySchedule{UserId: userId, TimeZone: timeZone, NextAt: nextAt})
+	}
+	slices.SortFunc(schedules, func(a, b types.SummarySchedule) int {
+		switch {
+		case a.NextAt == nil && b.NextAt != nil:
+			return -1
+		case a.NextAt != nil && b.NextAt == nil:
+			return 1
+		case a.NextAt != nil && !a.NextAt.Equal(*b.NextAt):
+			return a.NextAt.Compare(*b.NextAt)
+		default:
+			return strings.Compare(a.UserId, b.UserId)
+		}
+	})
+	return schedules[:min(limit, len(schedules))], nil
+}
+
+func (r *fakeNotificationRepo) ScheduleSummary(_ context.Context, userId string, previous *time.Time, next time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.summaries == nil {
+		r.summaries = make(map[string]*time.Time)
+	}
+	if current, ok := r.summaries[userId]; ok {
+		if (current == nil) != (previous == nil) || (current != nil && !current.Equal(*previous)) {
+			return false, nil
+		}
+	}
+	r.summaries[userId] = &next
+	return true, nil
+}
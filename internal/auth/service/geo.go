@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"medods-test/pkg/geoip"
+	"strconv"
+)
+
+// GeoLocator resolves the coarse location of IP addresses.
+type GeoLocator interface {
+	Locate(ip string) (geoip.Location, bool)
+}
+
+// noGeoLocator is used when no GeoIP database is configured.
+type noGeoLocator struct{}
+
+func (noGeoLocator) Locate(string) (geoip.Location, bool) {
+	return geoip.Location{}, false
+}
+
+// locationMetadata adds the location of ip to metadata of an audit event.
+func locationMetadata(metadata map[string]string, location geoip.Location) {
+	if location.CountryCode != "" {
+		metadata["country"] = location.CountryCode
+	}
+	if location.City != "" {
+		metadata["city"] = location.City
+	}
+	if location.ASN != 0 {
+		metadata["asn"] = strconv.FormatUint(uint64(location.ASN), 10)
+	}
+}
+
+// describeIP returns ip with its location and network for notification emails,
+// such as "203.0.113.7 (Moscow, Russia, AS12389 Rostelecom)".
+func (u *User) describeIP(ip string) string {
+	location, ok := u.locator.Locate(ip)
+	if !ok {
+		return html.EscapeString(ip)
+	}
+
+	details := location.String()
+	if location.ASN != 0 {
+		network := fmt.Sprintf("AS%d %s", location.ASN, location.ASOrganization)
+		if details != "" {
+			details += ", "
+		}
+		details += network
+	}
+	return html.EscapeString(fmt.Sprintf("%s (%s)", ip, details))
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"medods-test/internal/auth/types"
+	"testing"
+)
+
+var testLocations = fakeGeoLocator{
+	"203.0.113.7": {
+		CountryCode:    "RU",
+		Country:        "Russia",
+		City:           "Moscow",
+		Latitude:       55.75,
+		Longitude:      37.62,
+		ASN:            12389,
+		ASOrganization: "Rostelecom",
+	},
+	"198.51.100.2": {ASN: 64500, ASOrganization: "<Example> & Co"},
+}
+
+func TestDescribeIP(t *testing.T) {
+	u := &User{locator: testLocations}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "203.0.113.7", want: "203.0.113.7 (Moscow, Russia, AS12389 Rostelecom)"},
+		{ip: "198.51.100.2", want: "198.51.100.2 (AS64500 &lt;Example&gt; &amp; Co)"},
+		{ip: "192.0.2.1", want: "192.0.2.1"},
+		{ip: "<script>", want: "&lt;script&gt;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := u.describeIP(tt.ip); got != tt.want {
+				t.Errorf("describeIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditRecordLocation(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want map[string]string
+	}{
+		{ip: "203.0.113.7", want: map[string]string{"country": "RU", "city": "Moscow", "asn": "12389"}},
+		{ip: "198.51.100.2", want: map[string]string{"asn": "64500"}},
+		{ip: "192.0.2.1", want: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			auditrepo := &fakeAuditRepo{}
+			audit := newTestAudit(auditrepo)
+			audit.locator = testLocations
+
+			ctx := types.WithRequestInfo(context.Background(), types.RequestInfo{IP: tt.ip})
+			audit.Record(ctx, types.AuditUserSignedIn, testUserId, nil)
+
+			if len(auditrepo.events) != 1 {
+				t.Fatalf("audit events = %d, want 1", len(auditrepo.events))
+			}
+			metadata := auditrepo.events[0].Metadata
+			if len(metadata) != len(tt.want) {
+				t.Errorf("metadata = %v, want %v", metadata, tt.want)
+			}
+			for key, value := range tt.want {
+				if metadata[key] != value {
+					t.Errorf("metadata[%q] = %q, want %q", key, metadata[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionLocation(t *testing.T) {
+	o, sessions := newTestOAuth(t, newFakeOAuthRepo())
+	o.user.locator = testLocations
+	ctx := context.Background()
+
+	for _, ip := range []string{"203.0.113.7", "192.0.2.1"} {
+		if _, err := o.user.StartSession(ctx, types.SessionParams{UserId: testUserId, ClientId: "crm", IP: ip}); err != nil {
+			t.Fatalf("StartSession() error = %v", err)
+		}
+	}
+
+	located, unknown := sessions.sessions[0], sessions.sessions[1]
+	if located.Country != "RU" || located.City != "Moscow" || located.ASN != 12389 {
+		t.Errorf("session from a known address = %+v, want its location", located)
+	}
+	if unknown.Country != "" || unknown.City != "" || unknown.ASN != 0 {
+		t.Errorf("session from an unknown address = %+v, want no location", unknown)
+	}
+}
+
+func TestLoginAttemptLocation(t *testing.T) {
+	users := newFakeUserRepo()
+	u := newTestUser(users, &fakeAuditRepo{})
+	u.locator = testLocations
+	password, err := u.hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	users.users[testUserId] = types.User{UserUUID: testUserId, Email: "alice@example.com", Password: password, Status: types.UserStatusActive}
+
+	ctx := types.WithRequestInfo(context.Background(), types.RequestInfo{IP: "203.0.113.7"})
+	if _, err = u.Authenticate(ctx, types.UserDTO{Email: "alice@example.com", Password: testPassword}); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	attempts := u.loginrepo.(*fakeLoginHistoryRepo).attempts
+	if len(attempts) != 1 {
+		t.Fatalf("login attempts = %+v, want one", attempts)
+	}
+	want := testLocations["203.0.113.7"]
+	got := attempts[0]
+	if got.Location != "Moscow, Russia" || got.Country != want.CountryCode || got.ASN != int64(want.ASN) || got.Latitude != want.Latitude || got.Longitude != want.Longitude {
+		t.Errorf("login attempt = %+v, want located at %+v", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"html"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"medods-test/pkg/risk"
+	"medods-test/pkg/useragent"
+	"time"
+)
+
+var ErrKnownDeviceNotFound = errors.New("device not found")
+
+type KnownDeviceRepo interface {
+	GetDevice(ctx context.Context, userId string, deviceHash string) (*types.KnownDevice, error)
+	HasDevices(ctx context.Context, userId string) (bool, error)
+	SaveDevice(ctx context.Context, device types.KnownDevice) error
+	ListDevices(ctx context.Context, userId string) ([]types.KnownDevice, error)
+	DeleteDevice(ctx context.Context, userId string, deviceId string) (bool, error)
+	DeleteStaleDevices(ctx context.Context, before time.Time) (int64, error)
+}
+
+// NewDeviceId generates an id for a device that has none yet.
+func (u *User) NewDeviceId() (string, error) {
+	deviceId, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate device id: %s", err)
+		return "", err
+	}
+	return deviceId, nil
+}
+
+// DeviceCookie signs deviceId to be stored in the browser.
+func (u *User) DeviceCookie(deviceId string) types.DeviceCookie {
+	return types.DeviceCookie{
+		Value:     u.tokenManager.SignValue(deviceId),
+		ExpiresAt: time.Now().Add(u.deviceCookieTTL),
+	}
+}
+
+// ParseDeviceCookie returns the device id of a cookie made by DeviceCookie.
+func (u *User) ParseDeviceCookie(value string) (string, bool) {
+	return u.tokenManager.VerifyValue(value)
+}
+
+// Devices returns the devices the user of identity has signed in from.
+func (u *User) Devices(ctx context.Context, identity types.Identity) ([]types.KnownDevice, error) {
+	devices, err := u.devicerepo.ListDevices(ctx, identity.UserId)
+	if err != nil {
+		logger.Errorf("failed to list known devices: %s", err)
+		return nil, err
+	}
+
+	if deviceId := types.RequestInfoFrom(ctx).DeviceId; deviceId != "" {
+		deviceHash := oauth.HashCode(deviceId)
+		for i := range devices {
+			devices[i].Current = devices[i].DeviceHash == deviceHash
+		}
+	}
+	return devices, nil
+}
+
+// ForgetDevice removes the device from the devices of the user of identity. The next sign-in
+// from it is treated as a sign-in from a new device.
+func (u *User) ForgetDevice(ctx context.Context, identity types.Identity, deviceId string) error {
+	if uuid.Validate(deviceId) != nil {
+		return ErrKnownDeviceNotFound
+	}
+
+	deleted, err := u.devicerepo.DeleteDevice(ctx, identity.UserId, deviceId)
+	if err != nil {
+		logger.Errorf("failed to delete known device: %s", err)
+		return err
+	}
+	if !deleted {
+		return ErrKnownDeviceNotFound
+	}
+
+	u.audit.Record(ctx, types.AuditDeviceForgotten, identity.UserId, map[string]string{"device_id": deviceId})
+	return nil
+}
+
+// requestDevice returns the known device of the user the request of ctx comes from, nil if
+// the device is new or the request carries no device id.
+func (u *User) requestDevice(ctx context.Context, userId string) *types.KnownDevice {
+	deviceId := types.RequestInfoFrom(ctx).DeviceId
+	if deviceId == "" {
+		return nil
+	}
+
+	device, err := u.devicerepo.GetDevice(ctx, userId, oauth.HashCode(deviceId))
+	if err != nil {
+		logger.Errorf("failed to get known device: %s", err)
+		return nil
+	}
+	return device
+}
+
+// rememberDevice records a successful sign-in of user from the device of the request, known
+// is the device found by requestDevice. The first sign-in from a new device is reported to
+// the user, unless it is the first device of the user. trust remembers the device, so that
+// it signs in without a second factor.
+func (u *User) rememberDevice(ctx context.Context, user *types.User, known *types.KnownDevice, assessment risk.Assessment, trust bool) {
+	info := types.RequestInfoFrom(ctx)
+	if info.DeviceId == "" {
+		if assessment.Action == risk.ActionNotify {
+			u.notifyNewDevice(ctx, user, assessment)
+		}
+		return
+	}
+
+	device := types.KnownDevice{
+		Id:         uuid.NewString(),
+		UserId:     user.UserUUID,
+		DeviceHash: oauth.HashCode(info.DeviceId),
+		Name:       useragent.Describe(info.UserAgent),
+		IP:         info.IP,
+	}
+	if known != nil {
+		device.Id = known.Id
+	}
+	if location, ok := u.locator.Locate(info.IP); ok {
+		device.Location = location.String()
+	}
+	if trust {
+		trustedUntil := time.Now().Add(u.deviceTrustTTL)
+		device.TrustedUntil = &trustedUntil
+	}
+
+	var notify bool
+	if known == nil {
+		hasDevices, err := u.devicerepo.HasDevices(ctx, user.UserUUID)
+		if err != nil {
+			logger.Errorf("failed to check known devices: %s", err)
+		}
+		notify = hasDevices
+	}
+
+	if err := u.devicerepo.SaveDevice(ctx, device); err != nil {
+		logger.Errorf("failed to save known device (user: %s): %s", user.UserUUID, err)
+		return
+	}
+
+	if known == nil {
+		u.audit.Record(ctx, types.AuditDeviceAdded, user.UserUUID, map[string]string{
+			"device_id": device.Id,
+			"device":    device.Name,
+		})
+	}
+	if trust {
+		u.audit.Record(ctx, types.AuditDeviceTrusted, user.UserUUID, map[string]string{
+			"device_id":     device.Id,
+			"trusted_until": device.TrustedUntil.UTC().Format(time.RFC3339),
+		})
+	}
+	if notify {
+		u.notifyNewDevice(ctx, user, assessment)
+	}
+}
+
+func (u *User) notifyNewDevice(ctx context.Context, user *types.User, assessment risk.Assessment) {
+	info := types.RequestInfoFrom(ctx)
+
+	details := ""
+	if len(assessment.Signals) > 0 {
+		details = fmt.Sprintf("<p>Вход отличается от обычных: %s.</p>\n", describeSignals(assessment.Signals))
+	}
+
+	u.notifications.Notify(ctx, user, types.Notice{
+		Event:   types.NotificationNewDevice,
+		Subject: "Вход с нового устройства",
+		Body: fmt.Sprintf(`<p>В вашу учётную запись выполнен вход с устройства, с которого вы раньше не входили.</p>
+%s<p>IP-адрес: %s<br>Устройство: %s</p>
+<p>Если это были не вы, смените пароль и свяжитесь с нашей службой поддержки.</p>`, details, u.describeIP(info.IP),
+			html.EscapeString(useragent.Describe(info.UserAgent))),
+		Text: fmt.Sprintf("Вход с нового устройства: %s. Если это были не вы, смените пароль.",
+			useragent.Describe(info.UserAgent)),
+	})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/useragent"
+	"time"
+)
+
+const (
+	defaultLoginHistorySize = 50
+	maxLoginHistorySize     = 200
+)
+
+// Failure reasons of login attempts shown to users.
+const (
+	loginFailureInvalidCredentials    = "invalid_credentials"
+	loginFailurePasswordResetRequired = "password_reset_required"
+	loginFailureAccountInactive       = "account_inactive"
+	loginFailureBlocked               = "blocked"
+	loginFailureMFARequired           = "mfa_required"
+	loginFailureInvalidCode           = "invalid_code"
+	loginFailureError                 = "error"
+)
+
+type LoginHistoryRepo interface {
+	AddLoginAttempt(ctx context.Context, attempt types.LoginAttempt) error
+	ListLoginAttempts(ctx context.Context, userId string, limit int, since time.Time) ([]types.LoginAttempt, error)
+	DeleteLoginAttempts(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LoginHistory returns up to limit recent sign-in attempts to the account of identity, newest first.
+func (u *User) LoginHistory(ctx context.Context, identity types.Identity, limit int) ([]types.LoginAttempt, error) {
+	if limit <= 0 {
+		limit = defaultLoginHistorySize
+	}
+	limit = min(limit, maxLoginHistorySize)
+
+	attempts, err := u.loginrepo.ListLoginAttempts(ctx, identity.UserId, limit, time.Now().Add(-u.loginHistoryRetention))
+	if err != nil {
+		logger.Errorf("failed to list login attempts: %s", err)
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// recordLoginAttempt saves a sign-in attempt of the user that failed with err, or succeeded if err is nil.
+func (u *User) recordLoginAttempt(ctx context.Context, userId string, method string, err error) {
+	info := types.RequestInfoFrom(ctx)
+
+	attempt := types.LoginAttempt{
+		UserId:    userId,
+		Method:    method,
+		Success:   err == nil,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Device:    useragent.Describe(info.UserAgent),
+	}
+	if location, ok := u.locator.Locate(info.IP); ok {
+		attempt.Location = location.String()
+		attempt.Country = location.CountryCode
+		attempt.ASN = int64(location.ASN)
+		attempt.Latitude = location.Latitude
+		attempt.Longitude = location.Longitude
+	}
+	if err != nil {
+		attempt.FailureReason = loginFailureReason(err)
+	}
+
+	if err = u.loginrepo.AddLoginAttempt(ctx, attempt); err != nil {
+		logger.Errorf("failed to save login attempt (user: %s): %s", userId, err)
+	}
+}
+
+// attemptedUser returns the user a failed sign-in with email was meant for, if there is one.
+func (u *User) attemptedUser(ctx context.Context, email string) *types.User {
+	user, err := u.userrepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		logger.Errorf("failed to get user by email: %s", err)
+		return nil
+	}
+	return user
+}
+
+func loginFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrInvalidPassword):
+		return loginFailureInvalidCredentials
+	case errors.Is(err, ErrPasswordResetRequired):
+		return loginFailurePasswordResetRequired
+	case checkUserActiveError(err):
+		return loginFailureAccountInactive
+	case errors.Is(err, ErrSignInBlocked):
+		return loginFailureBlocked
+	case errors.Is(err, ErrMFARequired):
+		return loginFailureMFARequired
+	case errors.Is(err, ErrInvalidMFACode):
+		return loginFailureInvalidCode
+	default:
+		return loginFailureError
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"medods-test/internal/auth/types"
+	"testing"
+	"time"
+)
+
+const testUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36"
+
+func TestAuthenticateRecordsLoginAttempt(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       types.User
+		email      string
+		password   string
+		wantErr    error
+		wantReason string
+		wantSaved  bool
+	}{
+		{
+			name:      "success",
+			user:      types.User{Status: types.UserStatusActive},
+			email:     "alice@example.com",
+			password:  testPassword,
+			wantSaved: true,
+		},
+		{
+			name:       "wrong password",
+			user:       types.User{Status: types.UserStatusActive},
+			email:      "alice@example.com",
+			password:   "wrong",
+			wantErr:    ErrUserNotFound,
+			wantReason: loginFailureInvalidCredentials,
+			wantSaved:  true,
+		},
+		{
+			name:       "password reset required",
+			user:       types.User{Status: types.UserStatusActive, PasswordResetRequired: true},
+			email:      "alice@example.com",
+			password:   testPassword,
+			wantErr:    ErrPasswordResetRequired,
+			wantReason: loginFailurePasswordResetRequired,
+			wantSaved:  true,
+		},
+		{
+			name:       "disabled account",
+			user:       types.User{Status: types.UserStatusDisabled},
+			email:      "alice@example.com",
+			password:   testPassword,
+			wantErr:    ErrUserDisabled,
+			wantReason: loginFailureAccountInactive,
+			wantSaved:  true,
+		},
+		{
+			// There is no account to show the attempt to.
+			name:     "unknown email",
+			user:     types.User{Status: types.UserStatusActive},
+			email:    "mallory@example.com",
+			password: testPassword,
+			wantErr:  ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newFakeUserRepo()
+			u := newTestUser(users, &fakeAuditRepo{})
+			password, err := u.hasher.Hash(testPassword)
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			tt.user.UserUUID, tt.user.Email, tt.user.Password = testUserId, "alice@example.com", password
+			users.users[testUserId] = tt.user
+
+			ctx := types.WithRequestInfo(context.Background(), types.RequestInfo{IP: "203.0.113.1", UserAgent: testUserAgent})
+			if _, err = u.Authenticate(ctx, types.UserDTO{Email: tt.email, Password: tt.password}); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			attempts := u.loginrepo.(*fakeLoginHistoryRepo).attempts
+			if !tt.wantSaved {
+				if len(attempts) != 0 {
+					t.Errorf("login attempts = %+v, want none", attempts)
+				}
+				return
+			}
+			if len(attempts) != 1 {
+				t.Fatalf("login attempts = %+v, want one", attempts)
+			}
+			attempt := attempts[0]
+			if attempt.UserId != testUserId || attempt.Method != types.LoginMethodPassword || attempt.Success != (tt.wantErr == nil) || attempt.FailureReason != tt.wantReason {
+				t.Errorf("login attempt = %+v, want %s by password with reason %q", attempt, testUserId, tt.wantReason)
+			}
+			if attempt.IP != "203.0.113.1" || attempt.Device != "Chrome on Windows" {
+				t.Errorf("login attempt from %s on %q, want the request's", attempt.IP, attempt.Device)
+			}
+		})
+	}
+}
+
+func TestLoginFailureReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: ErrInvalidPassword, want: loginFailureInvalidCredentials},
+		{err: ErrUserBanned, want: loginFailureAccountInactive},
+		{err: ErrUserPendingDeletion, want: loginFailureAccountInactive},
+		{err: ErrSignInBlocked, want: loginFailureBlocked},
+		{err: ErrMFARequired, want: loginFailureMFARequired},
+		{err: fmt.Errorf("verify: %w", ErrInvalidMFACode), want: loginFailureInvalidCode},
+		{err: errors.New("connection refused"), want: loginFailureError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := loginFailureReason(tt.err); got != tt.want {
+				t.Errorf("loginFailureReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginHistory(t *testing.T) {
+	now := time.Now()
+	loginrepo := &fakeLoginHistoryRepo{}
+	// An attempt past the retention, then one every minute up to now.
+	loginrepo.attempts = append(loginrepo.attempts, types.LoginAttempt{Id: 0, UserId: testUserId, CreatedAt: now.Add(-31 * 24 * time.Hour)})
+	for i := 1; i <= 250; i++ {
+		loginrepo.attempts = append(loginrepo.attempts, types.LoginAttempt{Id: int64(i), UserId: testUserId, CreatedAt: now.Add(time.Duration(i-250) * time.Minute)})
+	}
+	loginrepo.attempts = append(loginrepo.attempts, types.LoginAttempt{Id: 251, UserId: otherUserId, CreatedAt: now})
+
+	u := &User{loginrepo: loginrepo, loginHistoryRetention: 30 * 24 * time.Hour}
+	identity := types.Identity{UserId: testUserId}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{name: "default limit", limit: 0, want: defaultLoginHistorySize},
+		{name: "negative limit", limit: -1, want: defaultLoginHistorySize},
+		{name: "requested limit", limit: 10, want: 10},
+		{name: "limit above the maximum", limit: 1000, want: maxLoginHistorySize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, err := u.LoginHistory(context.Background(), identity, tt.limit)
+			if err != nil {
+				t.Fatalf("LoginHistory() error = %v", err)
+			}
+			if len(attempts) != tt.want {
+				t.Fatalf("LoginHistory() = %d attempts, want %d", len(attempts), tt.want)
+			}
+			if attempts[0].Id != 250 {
+				t.Errorf("first attempt = %d, want the newest of the user", attempts[0].Id)
+			}
+		})
+	}
+
+	// Only the attempts within the retention are listed.
+	u.loginHistoryRetention = time.Hour
+	attempts, err := u.LoginHistory(context.Background(), identity, maxLoginHistorySize)
+	if err != nil {
+		t.Fatalf("LoginHistory() error = %v", err)
+	}
+	if len(attempts) != 60 {
+		t.Errorf("LoginHistory() = %d attempts within an hour, want 60", len(attempts))
+	}
+}
+
+func TestPurgeLoginHistory(t *testing.T) {
+	now := time.Now()
+	loginrepo := &fakeLoginHistoryRepo{attempts: []types.LoginAttempt{
+		{Id: 1, UserId: testUserId, CreatedAt: now.Add(-48 * time.Hour)},
+		{Id: 2, UserId: otherUserId, CreatedAt: now.Add(-25 * time.Hour)},
+		{Id: 3, UserId: testUserId, CreatedAt: now.Add(-time.Hour)},
+	}}
+	admin := &UserAdmin{loginrepo: loginrepo, loginHistoryRetention: 24 * time.Hour}
+
+	deleted, err := admin.PurgeLoginHistory(context.Background())
+	if err != nil || deleted != 2 {
+		t.Fatalf("PurgeLoginHistory() = %d, %v, want 2 deleted", deleted, err)
+	}
+	if len(loginrepo.attempts) != 1 || loginrepo.attempts[0].Id != 3 {
+		t.Errorf("attempts = %+v, want the recent one only", loginrepo.attempts)
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"medods-test/pkg/risk"
+	"time"
+)
+
+const (
+	mfaCodeLength   = 6
+	maxMFAAttempts  = 5
+	mfaChallengeTTL = 10 * time.Minute
+)
+
+var (
+	ErrMFARequired    = errors.New("additional verification is required")
+	ErrInvalidMFACode = errors.New("invalid or expired verification code")
+)
+
+// MFAChallengeError is returned by a sign-in that has to be completed with a one-time code
+// sent to the user. Token identifies the challenge in VerifySignIn.
+type MFAChallengeError struct {
+	Token     string
+	Channel   string
+	ExpiresAt time.Time
+}
+
+func (e *MFAChallengeError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFAChallengeError) Is(target error) bool {
+	return target == ErrMFARequired
+}
+
+// signInMFAError is returned by Authenticate when the sign-in of user needs a second factor.
+type signInMFAError struct {
+	user *types.User
+}
+
+func (e *signInMFAError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *signInMFAError) Is(target error) bool {
+	return target == ErrMFARequired
+}
+
+type MFARepo interface {
+	CreateChallenge(ctx context.Context, challenge types.MFAChallenge) error
+	GetChallenge(ctx context.Context, tokenHash string) (*types.MFAChallenge, error)
+	AddChallengeAttempt(ctx context.Context, tokenHash string) error
+	DeleteChallenge(ctx context.Context, tokenHash string) (bool, error)
+	DeleteExpiredChallenges(ctx context.Context) (int64, error)
+}
+
+// startMFAChallenge sends a one-time code to the user, by SMS if the user verified a phone for
+// it or by email, and returns the challenge that completes the sign-in as *MFAChallengeError.
+func (u *User) startMFAChallenge(ctx context.Context, user *types.User, orgId string, method string) error {
+	token, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate mfa challenge token: %s", err)
+		return err
+	}
+	code, err := oauth.NewDigits(mfaCodeLength)
+	if err != nil {
+		logger.Errorf("failed to generate mfa code: %s", err)
+		return err
+	}
+
+	channelName, channel := u.mfaChannel(ctx, user)
+	challenge := types.MFAChallenge{
+		TokenHash:      oauth.HashCode(token),
+		UserId:         user.UserUUID,
+		OrganizationId: orgId,
+		Method:         method,
+		Channel:        channelName,
+		CodeHash:       oauth.HashCode(code),
+		ExpiresAt:      time.Now().Add(mfaChallengeTTL),
+	}
+	if err = u.mfarepo.CreateChallenge(ctx, challenge); err != nil {
+		logger.Errorf("failed to save mfa challenge: %s", err)
+		return err
+	}
+
+	message := types.ChannelMessage{
+		Subject: "Код подтверждения входа",
+		HTML: fmt.Sprintf(`<h1>Подтверждение входа</h1>
+<p>Вход в вашу учётную запись выглядит необычно, поэтому нужно подтвердить его кодом:</p>
+<p><b>%s</b></p>
+<p>Код действует до %s. Если вы не входили в учётную запись, смените пароль.</p>
+<p>С уважением,<br>Команда поддержки</p>`, code, challenge.ExpiresAt.Format("02.01.2006 15:04")),
+		Text: fmt.Sprintf("Код подтверждения входа: %s. Никому его не сообщайте.", code),
+	}
+	if err = sendTo(ctx, channel, user, message); err != nil {
+		logger.Errorf("failed to send mfa code: %s", err.Error())
+		return err
+	}
+
+	return &MFAChallengeError{
+		Token:     token,
+		Channel:   challenge.Channel,
+		ExpiresAt: challenge.ExpiresAt,
+	}
+}
+
+// VerifySignIn completes a sign-in challenged with MFA and creates the session. rememberDevice
+// trusts the device of the request, so that its next sign-ins skip the second factor.
+func (u *User) VerifySignIn(ctx context.Context, token string, code string, IP string, rememberDevice bool) (types.Tokens, error) {
+	tokenHash := oauth.HashCode(token)
+
+	challenge, err := u.mfarepo.GetChallenge(ctx, tokenHash)
+	if err != nil {
+		logger.Errorf("failed to get mfa challenge: %s", err)
+		return types.Tokens{}, err
+	}
+	if challenge == nil || challenge.IsExpired() || challenge.Attempts >= maxMFAAttempts {
+		return types.Tokens{}, ErrInvalidMFACode
+	}
+
+	if subtle.ConstantTimeCompare([]byte(oauth.HashCode(code)), []byte(challenge.CodeHash)) != 1 {
+		if err = u.mfarepo.AddChallengeAttempt(ctx, tokenHash); err != nil {
+			logger.Errorf("failed to count mfa attempt: %s", err)
+		}
+		u.recordLoginAttempt(ctx, challenge.UserId, challenge.Method, ErrInvalidMFACode)
+		u.audit.Record(ctx, types.AuditUserSignInFailed, challenge.UserId, map[string]string{
+			"method": challenge.Method,
+			"mfa":    challenge.Channel,
+			"reason": ErrInvalidMFACode.Error(),
+		})
+		return types.Tokens{}, ErrInvalidMFACode
+	}
+
+	consumed, err := u.mfarepo.DeleteChallenge(ctx, tokenHash)
+	if err != nil {
+		logger.Errorf("failed to delete mfa challenge: %s", err)
+		return types.Tokens{}, err
+	}
+	if !consumed {
+		return types.Tokens{}, ErrInvalidMFACode
+	}
+
+	user, err := u.userrepo.GetUserByID(ctx, challenge.UserId)
+	if err != nil {
+		logger.Errorf("failed to get user by id: %s", err)
+		return types.Tokens{}, err
+	}
+	if user == nil {
+		return types.Tokens{}, ErrUserNotFound
+	}
+	if err = checkUserActive(user); err != nil {
+		return types.Tokens{}, err
+	}
+
+	tokens, err := u.CreateSession(ctx, user.UserUUID, challenge.OrganizationId, IP)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	// The device is trusted only once the sign-in has produced a session.
+	u.rememberDevice(ctx, user, u.requestDevice(ctx, user.UserUUID), risk.Assessment{}, rememberDevice)
+	u.recordLoginAttempt(ctx, user.UserUUID, challenge.Method, nil)
+	u.audit.Record(ctx, types.AuditUserSignedIn, user.UserUUID, map[string]string{
+		"method": challenge.Method,
+		"mfa":    challenge.Channel,
+	})
+	return tokens, nil
+}
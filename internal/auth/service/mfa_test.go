@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/auth"
+	"medods-test/pkg/oauth"
+	"regexp"
+	"testing"
+	"time"
+)
+
+const testDeviceId = "0123456789abcdef0123456789abcdef"
+
+var (
+	mfaCodePattern = regexp.MustCompile(`<b>(\d+)</b>`)
+	errTestStore   = errors.New("connection refused")
+)
+
+type mfaTest struct {
+	user     *User
+	sessions *fakeSessionRepo
+	devices  *fakeKnownDeviceRepo
+	mfa      *fakeMFARepo
+	smtp     *fakeEmailSender
+	local    types.User
+	ctx      context.Context
+}
+
+func newMFATest(t *testing.T) *mfaTest {
+	t.Helper()
+
+	tokenManager, err := auth.NewManager("test-signing-key", nil)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	test := &mfaTest{
+		sessions: &fakeSessionRepo{},
+		devices:  newFakeKnownDeviceRepo(),
+		mfa:      newFakeMFARepo(),
+		smtp:     &fakeEmailSender{},
+		local: types.User{
+			UserUUID: "2f0d7a4c-1b8e-4c3a-9e57-6d5b4a3c2e10",
+			Email:    "alice@example.com",
+			Status:   types.UserStatusActive,
+		},
+		ctx: types.WithRequestInfo(context.Background(), types.RequestInfo{IP: "203.0.113.1", DeviceId: testDeviceId}),
+	}
+
+	test.user = newTestUser(newFakeUserRepo(test.local), &fakeAuditRepo{})
+	test.user.sessionrepo = test.sessions
+	test.user.devicerepo = test.devices
+	test.user.mfarepo = test.mfa
+	test.user.roles = &RBAC{rbacrepo: &fakeRBACRepo{}}
+	test.user.tokenManager = tokenManager
+	test.user.channels = map[string]Channel{types.NotificationChannelEmail: emailChannel{smtp: test.smtp}}
+	test.user.accessTokenTTL = time.Minute
+	test.user.refreshTokenTTL = time.Hour
+	test.user.deviceTrustTTL = time.Hour
+	return test
+}
+
+// challenge starts an MFA challenge and returns its token and the code emailed to the user.
+func (m *mfaTest) challenge(t *testing.T) (string, string) {
+	t.Helper()
+
+	err := m.user.startMFAChallenge(m.ctx, &m.local, "", "password")
+	var challengeErr *MFAChallengeError
+	if !errors.As(err, &challengeErr) {
+		t.Fatalf("startMFAChallenge() error = %v, want *MFAChallengeError", err)
+	}
+
+	messages := m.smtp.messages(m.local.Email)
+	if len(messages) != 1 {
+		t.Fatalf("startMFAChallenge() sent %d emails, want 1", len(messages))
+	}
+	match := mfaCodePattern.FindStringSubmatch(messages[0].Body)
+	if match == nil || len(match[1]) != mfaCodeLength {
+		t.Fatalf("startMFAChallenge() email %q has no code", messages[0].Body)
+	}
+	return challengeErr.Token, match[1]
+}
+
+func (m *mfaTest) trustedDevices() int {
+	m.devices.mu.Lock()
+	defer m.devices.mu.Unlock()
+
+	trusted := 0
+	for _, device := range m.devices.devices {
+		if device.IsTrusted() {
+			trusted++
+		}
+	}
+	return trusted
+}
+
+func TestVerifySignIn(t *testing.T) {
+	tests := []struct {
+		name       string
+		wrongCodes int
+		expired    bool
+		sessionErr error
+		wantErr    error
+	}{
+		{
+			name: "correct code",
+		},
+		{
+			name:       "correct code after wrong ones",
+			wrongCodes: maxMFAAttempts - 1,
+		},
+		{
+			name:       "too many wrong codes",
+			wrongCodes: maxMFAAttempts,
+			wantErr:    ErrInvalidMFACode,
+		},
+		{
+			name:    "expired challenge",
+			expired: true,
+			wantErr: ErrInvalidMFACode,
+		},
+		{
+			name:       "session not created",
+			sessionErr: errTestStore,
+			wantErr:    errTestStore,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMFATest(t)
+			m.sessions.createErr = tt.sessionErr
+			token, code := m.challenge(t)
+
+			wrongCode := "000000"
+			if code == wrongCode {
+				wrongCode = "111111"
+			}
+			for i := 0; i < tt.wrongCodes; i++ {
+				if _, err := m.user.VerifySignIn(m.ctx, token, wrongCode, "203.0.113.1", true); !errors.Is(err, ErrInvalidMFACode) {
+					t.Fatalf("VerifySignIn() wrong code %d error = %v, want %v", i+1, err, ErrInvalidMFACode)
+				}
+			}
+			if tt.expired {
+				tokenHash := oauth.HashCode(token)
+				challenge := m.mfa.challenges[tokenHash]
+				challenge.ExpiresAt = time.Now().Add(-time.Second)
+				m.mfa.challenges[tokenHash] = challenge
+			}
+
+			tokens, err := m.user.VerifySignIn(m.ctx, token, code, "203.0.113.1", true)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("VerifySignIn() error = %v, want %v", err, tt.wantErr)
+				}
+				if got := m.trustedDevices(); got != 0 {
+					t.Errorf("VerifySignIn() trusted %d devices, want none", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifySignIn() error = %v", err)
+			}
+
+			if tokens.AccessToken == "" || tokens.RefreshToken == "" || len(m.sessions.sessions) != 1 {
+				t.Errorf("VerifySignIn() tokens = %+v with %d sessions, want a new session", tokens, len(m.sessions.sessions))
+			}
+			if got := m.trustedDevices(); got != 1 {
+				t.Errorf("VerifySignIn() trusted %d devices, want 1", got)
+			}
+			if _, err = m.user.VerifySignIn(m.ctx, token, code, "203.0.113.1", true); !errors.Is(err, ErrInvalidMFACode) {
+				t.Errorf("second VerifySignIn() error = %v, want %v", err, ErrInvalidMFACode)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/sms"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const digestBatchSize = 100
+
+var (
+	ErrUnknownNotification       = errors.New("unknown notification event")
+	ErrInvalidNotificationOption = errors.New("invalid notification channel or delivery")
+	ErrCriticalNotification      = errors.New("critical notifications cannot be changed")
+)
+
+// notificationDefaults are the preferences of users that have not changed them, in the order
+// they are shown. Critical notifications always use their defaults.
+var notificationDefaults = []types.NotificationPreference{
+	{
+		Event:    types.NotificationNewDevice,
+		Channels: []string{types.NotificationChannelEmail},
+		Delivery: types.NotificationDeliveryImmediate,
+	},
+	{
+		Event:    types.NotificationIPChanged,
+		Channels: []string{},
+		Delivery: types.NotificationDeliveryImmediate,
+	},
+	{
+		Event:    types.NotificationPasswordChanged,
+		Channels: []string{types.NotificationChannelEmail},
+		Delivery: types.NotificationDeliveryImmediate,
+		Critical: true,
+	},
+	{
+		Event:    types.NotificationEmailChangeRequested,
+		Channels: []string{types.NotificationChannelEmail},
+		Delivery: types.NotificationDeliveryImmediate,
+		Critical: true,
+	},
+	{
+		Event:    types.NotificationPhoneChanged,
+		Channels: []string{types.NotificationChannelEmail, types.NotificationChannelSMS},
+		Delivery: types.NotificationDeliveryImmediate,
+		Critical: true,
+	},
+	{
+		Event:     types.NotificationWeeklySummary,
+		Channels:  []string{types.NotificationChannelEmail},
+		Delivery:  types.NotificationDeliveryImmediate,
+		Scheduled: true,
+	},
+}
+
+// notificationChannels are the channels notifications can be sent through.
+var notificationChannels = map[string]bool{
+	types.NotificationChannelEmail: true,
+	types.NotificationChannelSMS:   true,
+}
+
+type NotificationRepo interface {
+	ListPreferences(ctx context.Context, userId string) ([]types.NotificationPreference, error)
+	SavePreferences(ctx context.Context, userId string, preferences []types.NotificationPreference) error
+	EnqueueNotification(ctx context.Context, notification types.PendingNotification) error
+	ListPendingUsers(ctx context.Context, limit int) ([]string, error)
+	TakePendingNotifications(ctx context.Context, userId string) ([]types.PendingNotification, error)
+	GetTimeZone(ctx context.Context, userId string) (string, error)
+	SaveTimeZone(ctx context.Context, userId string, timeZone string) error
+	ListDueSummaries(ctx context.Context, now time.Time, limit int) ([]types.SummarySchedule, error)
+	ScheduleSummary(ctx context.Context, userId string, previous *time.Time, next time.Time) (bool, error)
+}
+
+// Notifications sends security notifications to users as their preferences say.
+type Notifications struct {
+	notificationrepo NotificationRepo
+	userrepo         UserRepo
+	audit            *Audit
+	channels         map[string]Channel
+}
+
+// Preferences returns the preferences of the user of identity for every notification.
+func (n *Notifications) Preferences(ctx context.Context, identity types.Identity) ([]types.NotificationPreference, error) {
+	return n.preferences(ctx, identity.UserId)
+}
+
+// UpdatePreferences changes the preferences of the user of identity for the given events and
+// returns the preferences for every notification.
+func (n *Notifications) UpdatePreferences(ctx context.Context, identity types.Identity, preferences []types.NotificationPreference) ([]types.NotificationPreference, error) {
+	changes := make([]types.NotificationPreference, 0, len(preferences))
+	for _, preference := range preferences {
+		defaults, ok := notificationDefault(preference.Event)
+		if !ok {
+			return nil, ErrUnknownNotification
+		}
+		if defaults.Critical {
+			return nil, ErrCriticalNotification
+		}
+		if preference.Delivery == "" {
+			preference.Delivery = types.NotificationDeliveryImmediate
+		}
+		if preference.Delivery != types.NotificationDeliveryImmediate && preference.Delivery != types.NotificationDeliveryDigest {
+			return nil, ErrInvalidNotificationOption
+		}
+		if defaults.Scheduled && preference.Delivery != defaults.Delivery {
+			return nil, ErrInvalidNotificationOption
+		}
+
+		channels := make([]string, 0, len(preference.Channels))
+		for _, channel := range preference.Channels {
+			if !notificationChannels[channel] || n.channels[channel] == nil {
+				return nil, ErrInvalidNotificationOption
+			}
+			if defaults.Scheduled && !slices.Contains(defaults.Channels, channel) {
+				return nil, ErrInvalidNotificationOption
+			}
+			if !slices.Contains(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+
+		changes = append(changes, types.NotificationPreference{
+			Event:    preference.Event,
+			Channels: channels,
+			Delivery: preference.Delivery,
+		})
+	}
+
+	if err := n.notificationrepo.SavePreferences(ctx, identity.UserId, changes); err != nil {
+		logger.Errorf("failed to save notification preferences: %s", err)
+		return nil, err
+	}
+
+	events := make([]string, 0, len(changes))
+	for _, change := range changes {
+		events = append(events, change.Event)
+	}
+	n.audit.Record(ctx, types.AuditNotificationsChanged, identity.UserId, map[string]string{
+		"events": strings.Join(events, ","),
+	})
+
+	return n.preferences(ctx, identity.UserId)
+}
+
+// Notify sends the notice to user through the channels the user has chosen for its event,
+// immediately or in the next digest. A failure is logged and does not fail the caller.
+func (n *Notifications) Notify(ctx context.Context, user *types.User, notice types.Notice) {
+	preference, ok := notificationDefault(notice.Event)
+	if !ok {
+		logger.Errorf("unknown notification event %s", notice.Event)
+		return
+	}
+	if !preference.Critical {
+		preferences, err := n.preferences(ctx, user.UserUUID)
+		if err != nil {
+			return
+		}
+		for _, stored := range preferences {
+			if stored.Event == notice.Event {
+				preference = stored
+			}
+		}
+	}
+
+	for _, channel := range preference.Channels {
+		if preference.Delivery == types.NotificationDeliveryDigest {
+			pending := types.PendingNotification{
+				UserId:  user.UserUUID,
+				Event:   notice.Event,
+				Channel: channel,
+				Subject: notice.Subject,
+				Body:    notice.Body,
+			}
+			if err := n.notificationrepo.EnqueueNotification(ctx, pending); err != nil {
+				logger.Errorf("failed to queue %s notification (user: %s): %s", notice.Event, user.UserUUID, err)
+			}
+			continue
+		}
+
+		n.send(ctx, user, channel, types.ChannelMessage{
+			Subject: notice.Subject,
+			HTML:    noticeEmail(notice.Subject, notice.Body),
+			Text:    notice.Text,
+		})
+	}
+}
+
+// SendDigests sends every user with queued notifications one digest and returns how many were sent.
+func (n *Notifications) SendDigests(ctx context.Context) (int, error) {
+	var sent int
+	for {
+		userIds, err := n.notificationrepo.ListPendingUsers(ctx, digestBatchSize)
+		if err != nil {
+			logger.Errorf("failed to list users with pending notifications: %s", err)
+			return sent, err
+		}
+
+		var taken int
+		for _, userId := range userIds {
+			pending, err := n.notificationrepo.TakePendingNotifications(ctx, userId)
+			if err != nil {
+				logger.Errorf("failed to take pending notifications: %s", err)
+				return sent, err
+			}
+			if len(pending) == 0 {
+				continue
+			}
+			taken++
+
+			user, err := n.userrepo.GetUserByID(ctx, userId)
+			if err != nil {
+				logger.Errorf("failed to get user by id: %s", err)
+				return sent, err
+			}
+			if user == nil {
+				continue
+			}
+
+			sort.Slice(pending, func(i, j int) bool {
+				return pending[i].Id < pending[j].Id
+			})
+			byChannel := make(map[string][]types.PendingNotification)
+			for _, notification := range pending {
+				byChannel[notification.Channel] = append(byChannel[notification.Channel], notification)
+			}
+			for channel, notifications := range byChannel {
+				n.send(ctx, user, channel, types.ChannelMessage{
+					Subject: "Сводка уведомлений безопасности",
+					HTML:    digestEmail(notifications),
+					Text:    digestText(notifications),
+				})
+			}
+			sent++
+		}
+
+		// Notifications locked by a concurrent sender are left to it.
+		if len(userIds) < digestBatchSize || taken == 0 {
+			return sent, nil
+		}
+	}
+}
+
+// RunDigests sends digests every interval until ctx is done.
+func (n *Notifications) RunDigests(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = n.SendDigests(ctx)
+		}
+	}
+}
+
+func (n *Notifications) preferences(ctx context.Context, userId string) ([]types.NotificationPreference, error) {
+	stored, err := n.notificationrepo.ListPreferences(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list notification preferences: %s", err)
+		return nil, err
+	}
+
+	preferences := make([]types.NotificationPreference, 0, len(notificationDefaults))
+	for _, preference := range notificationDefaults {
+		if !preference.Critical {
+			for _, changed := range stored {
+				if changed.Event == preference.Event {
+					preference.Channels = changed.Channels
+					preference.Delivery = changed.Delivery
+				}
+			}
+		}
+		preferences = append(preferences, preference)
+	}
+	return preferences, nil
+}
+
+// send sends the message through the named channel. Users that cannot be reached through it,
+// such as ones without a verified phone, are skipped.
+func (n *Notifications) send(ctx context.Context, user *types.User, channelName string, message types.ChannelMessage) {
+	channel := n.channels[channelName]
+	if channel == nil {
+		return
+	}
+	if err := sendTo(ctx, channel, user, message); err != nil && !errors.Is(err, ErrChannelUnavailable) {
+		logger.Errorf("failed to send notification %q (user: %s): %s", message.Subject, user.UserUUID, err.Error())
+	}
+}
+
+func notificationDefault(event string) (types.NotificationPreference, bool) {
+	for _, preference := range notificationDefaults {
+		if preference.Event == event {
+			return preference, true
+		}
+	}
+	return types.NotificationPreference{}, false
+}
+
+func noticeEmail(subject string, body string) string {
+	return fmt.Sprintf(`<h1>%s</h1>
+%s
+<p>С уважением,<br>Команда поддержки</p>`, html.EscapeString(subject), body)
+}
+
+func digestEmail(notifications []types.PendingNotification) string {
+	var body strings.Builder
+	body.WriteString("<h1>Сводка уведомлений безопасности</h1>\n")
+	for _, notification := range notifications {
+		fmt.Fprintf(&body, "<h2>%s, %s</h2>\n%s\n", html.EscapeString(notification.Subject),
+			notification.CreatedAt.Format("02.01.2006 15:04"), notification.Body)
+	}
+	body.WriteString("<p>С уважением,<br>Команда поддержки</p>")
+	return body.String()
+}
+
+// digestText lists the subjects of the notifications, cut to the length of one SMS.
+func digestText(notifications []types.PendingNotification) string {
+	subjects := make([]string, 0, len(notifications))
+	for _, notification := range notifications {
+		subjects = append(subjects, notification.Subject)
+	}
+	text := []rune("Уведомления безопасности: " + strings.Join(subjects, "; "))
+	if len(text) > sms.MaxBodyLen {
+		text = append(text[:sms.MaxBodyLen-1], '…')
+	}
+	return string(text)
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/sms"
+	"medods-test/pkg/sms/fake"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+const testPhone = "+79990000000"
+
+type notificationTest struct {
+	notifications *Notifications
+	repo          *fakeNotificationRepo
+	auditrepo     *fakeAuditRepo
+	smtp          *fakeEmailSender
+	sms           *fake.FakeSender
+	user          types.User
+}
+
+// newNotificationTest returns Notifications through email and SMS for the user of testUserId,
+// who has a verified phone.
+func newNotificationTest() *notificationTest {
+	verifiedAt := time.Now()
+	test := &notificationTest{
+		repo:      &fakeNotificationRepo{},
+		auditrepo: &fakeAuditRepo{},
+		smtp:      &fakeEmailSender{},
+		sms:       fake.NewFakeSender(""),
+		user:      types.User{UserUUID: testUserId, Email: "alice@example.com", Status: types.UserStatusActive},
+	}
+	test.notifications = &Notifications{
+		notificationrepo: test.repo,
+		userrepo:         newFakeUserRepo(test.user),
+		audit:            newTestAudit(test.auditrepo),
+		channels: map[string]Channel{
+			types.NotificationChannelEmail: emailChannel{smtp: test.smtp},
+			types.NotificationChannelSMS: smsChannel{
+				sender:    test.sms,
+				phonerepo: newFakePhoneRepo(types.UserPhone{UserId: testUserId, Phone: testPhone, VerifiedAt: &verifiedAt}),
+			},
+		},
+	}
+	return test
+}
+
+func (n *notificationTest) notify(event string, subject string) {
+	n.notifications.Notify(context.Background(), &n.user, types.Notice{
+		Event:   event,
+		Subject: subject,
+		Body:    "<p>" + subject + "</p>",
+	})
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	test := newNotificationTest()
+	identity := types.Identity{UserId: testUserId}
+	ctx := context.Background()
+
+	preferences, err := test.notifications.Preferences(ctx, identity)
+	if err != nil {
+		t.Fatalf("Preferences() error = %v", err)
+	}
+	if len(preferences) != len(notificationDefaults) {
+		t.Fatalf("Preferences() = %+v, want every notification", preferences)
+	}
+	for i, preference := range preferences {
+		if preference.Event != notificationDefaults[i].Event || !slices.Equal(preference.Channels, notificationDefaults[i].Channels) {
+			t.Errorf("preference %d = %+v, want the default %+v", i, preference, notificationDefaults[i])
+		}
+	}
+
+	// A stored preference of a critical notification is ignored.
+	test.repo.preferences = map[string][]types.NotificationPreference{testUserId: {
+		{Event: types.NotificationPasswordChanged, Channels: []string{}, Delivery: types.NotificationDeliveryImmediate},
+		{Event: types.NotificationIPChanged, Channels: []string{types.NotificationChannelSMS}, Delivery: types.NotificationDeliveryDigest},
+	}}
+	preferences, _ = test.notifications.Preferences(ctx, identity)
+	for _, preference := range preferences {
+		switch preference.Event {
+		case types.NotificationPasswordChanged:
+			if !preference.Critical || !slices.Equal(preference.Channels, []string{types.NotificationChannelEmail}) {
+				t.Errorf("critical preference = %+v, want the default", preference)
+			}
+		case types.NotificationIPChanged:
+			if preference.Delivery != types.NotificationDeliveryDigest || !slices.Equal(preference.Channels, []string{types.NotificationChannelSMS}) {
+				t.Errorf("changed preference = %+v, want the stored one", preference)
+			}
+		}
+	}
+}
+
+func TestUpdateNotificationPreferences(t *testing.T) {
+	tests := []struct {
+		name         string
+		preference   types.NotificationPreference
+		withoutSMS   bool
+		wantErr      error
+		wantChannels []string
+		wantDelivery string
+	}{
+		{
+			name:         "digest through both channels",
+			preference:   types.NotificationPreference{Event: types.NotificationNewDevice, Channels: []string{"sms", "email", "sms"}, Delivery: types.NotificationDeliveryDigest},
+			wantChannels: []string{"sms", "email"},
+			wantDelivery: types.NotificationDeliveryDigest,
+		},
+		{
+			name:         "immediate by default",
+			preference:   types.NotificationPreference{Event: types.NotificationIPChanged, Channels: []string{"email"}},
+			wantChannels: []string{"email"},
+			wantDelivery: types.NotificationDeliveryImmediate,
+		},
+		{
+			name:         "turned off",
+			preference:   types.NotificationPreference{Event: types.NotificationNewDevice, Channels: []string{}},
+			wantChannels: []string{},
+			wantDelivery: types.NotificationDeliveryImmediate,
+		},
+		{
+			name:         "weekly summary turned off",
+			preference:   types.NotificationPreference{Event: types.NotificationWeeklySummary},
+			wantChannels: []string{},
+			wantDelivery: types.NotificationDeliveryImmediate,
+		},
+		{
+			name:       "unknown event",
+			preference: types.NotificationPreference{Event: "login", Channels: []string{"email"}},
+			wantErr:    ErrUnknownNotification,
+		},
+		{
+			name:       "critical notification",
+			preference: types.NotificationPreference{Event: types.NotificationPasswordChanged, Channels: []string{}},
+			wantErr:    ErrCriticalNotification,
+		},
+		{
+			name:       "unknown delivery",
+			preference: types.NotificationPreference{Event: types.NotificationNewDevice, Channels: []string{"email"}, Delivery: "hourly"},
+			wantErr:    ErrInvalidNotificationOption,
+		},
+		{
+			name:       "unknown channel",
+			preference: types.NotificationPreference{Event: types.NotificationNewDevice, Channels: []string{"pigeon"}},
+			wantErr:    ErrInvalidNotificationOption,
+		},
+		{
+			name:       "channel not configured",
+			preference: types.NotificationPreference{Event: types.NotificationNewDevice, Channels: []string{"sms"}},
+			withoutSMS: true,
+			wantErr:    ErrInvalidNotificationOption,
+		},
+		{
+			name:       "weekly summary in a digest",
+			preference: types.NotificationPreference{Event: types.NotificationWeeklySummary, Channels: []string{"email"}, Delivery: types.NotificationDeliveryDigest},
+			wantErr:    ErrInvalidNotificationOption,
+		},
+		{
+			name:       "weekly summary by sms",
+			preference: types.NotificationPreference{Event: types.NotificationWeeklySummary, Channels: []string{"sms"}},
+			wantErr:    ErrInvalidNotificationOption,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newNotificationTest()
+			if tt.withoutSMS {
+				delete(test.notifications.channels, types.NotificationChannelSMS)
+			}
+
+			preferences, err := test.notifications.UpdatePreferences(context.Background(), types.Identity{UserId: testUserId}, []types.NotificationPreference{tt.preference})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdatePreferences() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(test.repo.preferences) != 0 || len(test.auditrepo.events) != 0 {
+					t.Errorf("rejected UpdatePreferences() saved %+v or was audited", test.repo.preferences)
+				}
+				return
+			}
+
+			for _, preference := range preferences {
+				if preference.Event == tt.preference.Event && (!slices.Equal(preference.Channels, tt.wantChannels) || preference.Delivery != tt.wantDelivery) {
+					t.Errorf("preference = %+v, want %v %s", preference, tt.wantChannels, tt.wantDelivery)
+				}
+			}
+			if len(test.auditrepo.events) != 1 || test.auditrepo.events[0].Type != types.AuditNotificationsChanged || test.auditrepo.events[0].Metadata["events"] != tt.preference.Event {
+				t.Errorf("audit events = %+v, want the change of %s", test.auditrepo.events, tt.preference.Event)
+			}
+		})
+	}
+}
+
+func TestNotify(t *testing.T) {
+	tests := []struct {
+		name       string
+		stored     []types.NotificationPreference
+		event      string
+		wantEmails int
+		wantSMS    int
+	}{
+		{name: "default channel", event: types.NotificationNewDevice, wantEmails: 1},
+		{name: "off by default", event: types.NotificationIPChanged},
+		{
+			name:    "chosen channel",
+			stored:  []types.NotificationPreference{{Event: types.NotificationNewDevice, Channels: []string{"sms"}, Delivery: types.NotificationDeliveryImmediate}},
+			event:   types.NotificationNewDevice,
+			wantSMS: 1,
+		},
+		{
+			name:   "turned off",
+			stored: []types.NotificationPreference{{Event: types.NotificationNewDevice, Channels: []string{}, Delivery: types.NotificationDeliveryImmediate}},
+			event:  types.NotificationNewDevice,
+		},
+		{
+			name:       "critical notification turned off in storage",
+			stored:     []types.NotificationPreference{{Event: types.NotificationPhoneChanged, Channels: []string{}, Delivery: types.NotificationDeliveryDigest}},
+			event:      types.NotificationPhoneChanged,
+			wantEmails: 1,
+			wantSMS:    1,
+		},
+		{name: "unknown event", event: "login"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newNotificationTest()
+			test.repo.preferences = map[string][]types.NotificationPreference{testUserId: tt.stored}
+
+			test.notify(tt.event, "Вход с нового устройства")
+
+			if got := len(test.smtp.messages("alice@example.com")); got != tt.wantEmails {
+				t.Errorf("emails = %d, want %d", got, tt.wantEmails)
+			}
+			if got := len(test.sms.Messages(testPhone)); got != tt.wantSMS {
+				t.Errorf("sms = %d, want %d", got, tt.wantSMS)
+			}
+			if len(test.repo.pending) != 0 {
+				t.Errorf("pending notifications = %+v, want none", test.repo.pending)
+			}
+		})
+	}
+}
+
+func TestSendDigests(t *testing.T) {
+	test := newNotificationTest()
+	ctx := context.Background()
+	test.repo.preferences = map[string][]types.NotificationPreference{testUserId: {
+		{Event: types.NotificationNewDevice, Channels: []string{"email", "sms"}, Delivery: types.NotificationDeliveryDigest},
+		{Event: types.NotificationIPChanged, Channels: []string{"email"}, Delivery: types.NotificationDeliveryDigest},
+	}}
+
+	test.notify(types.NotificationNewDevice, "Вход с нового устройства")
+	test.notify(types.NotificationIPChanged, "Смена IP-адреса")
+	// A critical notification is never held back.
+	test.notify(types.NotificationPasswordChanged, "Пароль изменён")
+
+	emails := test.smtp.messages("alice@example.com")
+	if len(emails) != 1 || len(test.sms.Messages("")) != 0 || len(test.repo.pending) != 3 {
+		t.Fatalf("sent %d emails and %d sms with %d queued, want the critical email only and 3 queued",
+			len(emails), len(test.sms.Messages("")), len(test.repo.pending))
+	}
+
+	// A user who no longer exists gets nothing, the queue is emptied anyway.
+	_ = test.repo.EnqueueNotification(ctx, types.PendingNotification{UserId: otherUserId, Event: types.NotificationNewDevice, Channel: "email", Subject: "Вход"})
+
+	sent, err := test.notifications.SendDigests(ctx)
+	if err != nil || sent != 1 {
+		t.Fatalf("SendDigests() = %d, %v, want 1 sent", sent, err)
+	}
+	if len(test.repo.pending) != 0 {
+		t.Errorf("pending notifications = %+v, want none after the digest", test.repo.pending)
+	}
+
+	emails = test.smtp.messages("alice@example.com")
+	if len(emails) != 2 {
+		t.Fatalf("emails = %d, want the digest", len(emails))
+	}
+	digest := emails[1].Body
+	first, second := strings.Index(digest, "Вход с нового устройства"), strings.Index(digest, "Смена IP-адреса")
+	if first < 0 || second < first {
+		t.Errorf("digest email = %s, want both notices in the order they were queued", digest)
+	}
+	messages := test.sms.Messages(testPhone)
+	if len(messages) != 1 || messages[0].Body != "Уведомления безопасности: Вход с нового устройства" {
+		t.Errorf("sms = %+v, want the digest of the sms notices", messages)
+	}
+
+	if sent, _ = test.notifications.SendDigests(ctx); sent != 0 {
+		t.Errorf("second SendDigests() = %d, want 0", sent)
+	}
+}
+
+func TestDigestText(t *testing.T) {
+	notifications := make([]types.PendingNotification, 0, 20)
+	for range 20 {
+		notifications = append(notifications, types.PendingNotification{Subject: "Вход с нового устройства"})
+	}
+
+	text := digestText(notifications)
+	if utf8.RuneCountInString(text) != sms.MaxBodyLen || !strings.HasSuffix(text, "…") {
+		t.Errorf("digestText() = %q (%d characters), want it cut to %d", text, utf8.RuneCountInString(text), sms.MaxBodyLen)
+	}
+	if text = digestText(notifications[:1]); text != "Уведомления безопасности: Вход с нового устройства" {
+		t.Errorf("digestText() of one notice = %q", text)
+	}
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/auth"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidClient           = errors.New("invalid client")
+	ErrInvalidRedirectURI      = errors.New("invalid redirect uri")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrInvalidCodeChallenge    = errors.New("code challenge is required and must use S256")
+	ErrInvalidScope            = errors.New("invalid scope")
+	ErrInvalidGrant            = errors.New("invalid grant")
+	ErrUnsupportedGrantType    = errors.New("unsupported grant type")
+	ErrUnauthorizedClient      = errors.New("client is not allowed to use this grant type")
+)
+
+type OAuthRepo interface {
+	GetClientByID(ctx context.Context, clientId string) (*types.OAuthClient, error)
+	GetActiveClientSecrets(ctx context.Context, clientId string) ([]types.OAuthClientSecret, error)
+	CreateAuthorizationCode(ctx context.Context, code types.AuthorizationCode) error
+	GetAuthorizationCode(ctx context.Context, codeHash string) (*types.AuthorizationCode, error)
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string, sessionId string) (*types.AuthorizationCode, error)
+}
+
+type OAuth struct {
+	oauthrepo    OAuthRepo
+	devicerepo   DeviceRepo
+	exchangerepo TokenExchangeRepo
+	user         *User
+
+	issuer               string
+	authorizationCodeTTL time.Duration
+	idTokenTTL           time.Duration
+	clientTokenTTL       time.Duration
+	deviceCodeTTL        time.Duration
+	devicePollInterval   time.Duration
+	exchangeTokenTTL     time.Duration
+}
+
+// CheckClient validates the client and redirect uri of an authorization request.
+// Errors returned here must be shown to the user instead of redirecting back to the client.
+func (o *OAuth) CheckClient(ctx context.Context, req types.AuthorizeRequest) (*types.OAuthClient, error) {
+	client, err := o.oauthrepo.GetClientByID(ctx, req.ClientId)
+	if err != nil {
+		logger.Errorf("failed to get oauth client: %s", err)
+		return nil, err
+	}
+	if client == nil {
+		return nil, ErrInvalidClient
+	}
+
+	if !oauth.MatchRedirectURI(client.RedirectURIs, req.RedirectURI) {
+		return nil, ErrInvalidRedirectURI
+	}
+
+	return client, nil
+}
+
+// CheckAuthorizeRequest validates the rest of an authorization request once the
+// redirect uri is known to be safe.
+func (o *OAuth) CheckAuthorizeRequest(client *types.OAuthClient, req types.AuthorizeRequest) error {
+	if !client.AllowsGrant(oauth.GrantTypeAuthorizationCode) {
+		return ErrUnauthorizedClient
+	}
+
+	if req.ResponseType != oauth.ResponseTypeCode {
+		return ErrUnsupportedResponseType
+	}
+
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != oauth.CodeChallengeMethodS256 {
+		return ErrInvalidCodeChallenge
+	}
+
+	if !oauth.ScopeAllowed(slices.Concat(client.Scopes, oauth.StandardScopes), req.Scope) {
+		return ErrInvalidScope
+	}
+
+	return nil
+}
+
+// Authorize authenticates the resource owner and issues an authorization code.
+func (o *OAuth) Authorize(ctx context.Context, req types.AuthorizeRequest, input types.UserDTO) (string, error) {
+	client, err := o.CheckClient(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	if err = o.CheckAuthorizeRequest(client, req); err != nil {
+		return "", err
+	}
+
+	user, err := o.user.Authenticate(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to create authorization code: %s", err)
+		return "", err
+	}
+
+	if err = o.oauthrepo.CreateAuthorizationCode(ctx, types.AuthorizationCode{
+		CodeHash:            oauth.HashCode(code),
+		ClientId:            client.ClientId,
+		UserId:              user.UserUUID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		AuthTime:            time.Now(),
+		ExpiresAt:           time.Now().Add(o.authorizationCodeTTL),
+	}); err != nil {
+		logger.Errorf("failed to save authorization code: %s", err)
+		return "", err
+	}
+
+	return code, nil
+}
+
+// Token handles a request to the token endpoint.
+func (o *OAuth) Token(ctx context.Context, req types.TokenRequest, IP string) (types.Tokens, error) {
+	client, err := o.authenticateClient(ctx, req.ClientId, req.ClientSecret)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	if !client.AllowsGrant(req.GrantType) {
+		if !slices.Contains(o.Discovery().GrantTypesSupported, req.GrantType) {
+			return types.Tokens{}, ErrUnsupportedGrantType
+		}
+		return types.Tokens{}, ErrUnauthorizedClient
+	}
+
+	switch req.GrantType {
+	case oauth.GrantTypeClientCredentials:
+		return o.clientCredentials(client, req)
+	case oauth.GrantTypeDeviceCode:
+		return o.exchangeDeviceCode(ctx, client, req, IP)
+	case oauth.GrantTypeTokenExchange:
+		return o.exchangeToken(ctx, client, req, IP)
+	case oauth.GrantTypeAuthorizationCode:
+		return o.exchangeCode(ctx, client, req, IP)
+	case oauth.GrantTypeRefreshToken:
+		tokens, err := o.user.RefreshSession(ctx, IP, client.ClientId, req.RefreshToken)
+		if err != nil {
+			if errors.Is(err, ErrSessionNotFound) ||
+				errors.Is(err, ErrInvalidRefreshToken) ||
+				errors.Is(err, ErrRefreshTokenAlreadyUsed) ||
+				errors.Is(err, ErrRefreshTokenExpired) ||
+				errors.Is(err, ErrSessionRevoked) ||
+				errors.Is(err, ErrRefreshBlocked) ||
+				checkUserActiveError(err) {
+				return types.Tokens{}, errors.Join(ErrInvalidGrant, err)
+			}
+			return types.Tokens{}, err
+		}
+		return o.withIDToken(ctx, tokens, client.ClientId, "", time.Time{})
+	}
+
+	return types.Tokens{}, ErrUnsupportedGrantType
+}
+
+// authenticateClient identifies the client at the token endpoint. Confidential clients
+// must present one of their active secrets, public clients must not present any.
+func (o *OAuth) authenticateClient(ctx context.Context, clientId, clientSecret string) (*types.OAuthClient, error) {
+	client, err := o.oauthrepo.GetClientByID(ctx, clientId)
+	if err != nil {
+		logger.Errorf("failed to get oauth client: %s", err)
+		return nil, err
+	}
+	if client == nil {
+		return nil, ErrInvalidClient
+	}
+
+	if !client.IsConfidential() {
+		if clientSecret != "" {
+			return nil, ErrInvalidClient
+		}
+		return client, nil
+	}
+
+	if clientSecret == "" {
+		return nil, ErrInvalidClient
+	}
+
+	secrets, err := o.oauthrepo.GetActiveClientSecrets(ctx, client.ClientId)
+	if err != nil {
+		logger.Errorf("failed to get client secrets: %s", err)
+		return nil, err
+	}
+
+	for _, secret := range secrets {
+		if bcrypt.CompareHashAndPassword([]byte(secret.SecretHash), []byte(clientSecret)) == nil {
+			return client, nil
+		}
+	}
+
+	return nil, ErrInvalidClient
+}
+
+// clientCredentials issues an access token to the client itself. No session and no
+// refresh token are created.
+func (o *OAuth) clientCredentials(client *types.OAuthClient, req types.TokenRequest) (types.Tokens, error) {
+	if !client.IsConfidential() {
+		return types.Tokens{}, ErrUnauthorizedClient
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = oauth.JoinScope(client.Scopes)
+	}
+	if !oauth.ScopeAllowed(client.Scopes, scope) {
+		return types.Tokens{}, ErrInvalidScope
+	}
+
+	accessToken, err := o.user.tokenManager.NewJWT(auth.TokenParams{
+		UserId:   client.ClientId,
+		ClientId: client.ClientId,
+		Scope:    scope,
+	}, o.clientTokenTTL)
+	if err != nil {
+		logger.Errorf("failed to create client access token: %s", err)
+		return types.Tokens{}, err
+	}
+
+	return types.Tokens{
+		AccessToken: accessToken,
+		ExpiresIn:   o.clientTokenTTL,
+		Scope:       scope,
+	}, nil
+}
+
+// exchangeCode checks the request against the code before using it up, so that a code seen by
+// someone else cannot be burnt, or its session revoked, without the client's code verifier.
+func (o *OAuth) exchangeCode(ctx context.Context, client *types.OAuthClient, req types.TokenRequest, IP string) (types.Tokens, error) {
+	codeHash := oauth.HashCode(req.Code)
+	code, err := o.oauthrepo.GetAuthorizationCode(ctx, codeHash)
+	if err != nil {
+		logger.Errorf("failed to get authorization code: %s", err)
+		return types.Tokens{}, err
+	}
+
+	switch {
+	case code == nil:
+		return types.Tokens{}, ErrInvalidGrant
+	case code.ClientId != client.ClientId:
+		return types.Tokens{}, ErrInvalidGrant
+	case code.RedirectURI != req.RedirectURI:
+		return types.Tokens{}, ErrInvalidGrant
+	case !oauth.VerifyCodeChallenge(code.CodeChallenge, code.CodeChallengeMethod, req.CodeVerifier):
+		return types.Tokens{}, ErrInvalidGrant
+	}
+
+	sessionId := uuid.NewString()
+	code, err = o.oauthrepo.ConsumeAuthorizationCode(ctx, codeHash, sessionId)
+	if err != nil {
+		logger.Errorf("failed to consume authorization code: %s", err)
+		return types.Tokens{}, err
+	}
+
+	switch {
+	case code == nil:
+		return types.Tokens{}, ErrInvalidGrant
+	case code.Used:
+		logger.Warnf("authorization code reuse (client: %s, user: %s)", code.ClientId, code.UserId)
+		o.revokeCodeSession(ctx, code)
+		return types.Tokens{}, ErrInvalidGrant
+	case code.IsExpired():
+		return types.Tokens{}, ErrInvalidGrant
+	}
+
+	tokens, err := o.user.StartSession(ctx, types.SessionParams{
+		SessionId: sessionId,
+		UserId:    code.UserId,
+		IP:        IP,
+		ClientId:  client.ClientId,
+		Scope:     code.Scope,
+	})
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	return o.withIDToken(ctx, tokens, client.ClientId, code.Nonce, code.AuthTime)
+}
+
+// revokeCodeSession revokes the tokens issued for an authorization code that is used again,
+// as the code may have been stolen (RFC 6749 section 10.5).
+func (o *OAuth) revokeCodeSession(ctx context.Context, code *types.AuthorizationCode) {
+	if code.SessionId == "" {
+		return
+	}
+
+	revoked, err := o.user.sessionrepo.RevokeSessionFamily(ctx, code.SessionId)
+	if err != nil {
+		logger.Errorf("failed to revoke session of reused authorization code: %s", err)
+		return
+	}
+	if revoked > 0 {
+		o.user.audit.Record(ctx, types.AuditSessionRevoked, code.UserId, map[string]string{
+			"session_id": code.SessionId,
+			"reason":     "authorization_code_reuse",
+		})
+	}
+}
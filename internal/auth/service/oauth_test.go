@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/oauth"
+	"testing"
+	"time"
+)
+
+const (
+	testRedirectURI  = "https://app.example.com/callback"
+	testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+var webClient = types.OAuthClient{
+	ClientId:     "web",
+	Type:         types.ClientTypePublic,
+	RedirectURIs: []string{testRedirectURI},
+	Scopes:       []string{"profile"},
+	GrantTypes:   []string{oauth.GrantTypeAuthorizationCode},
+}
+
+func TestExchangeCode(t *testing.T) {
+	otherClient := webClient
+	otherClient.ClientId = "other"
+
+	tests := []struct {
+		name    string
+		expired bool
+		// change changes the request of the legitimate client.
+		change     func(req *types.TokenRequest)
+		wantErr    error
+		wantUnused bool
+	}{
+		{
+			name: "valid request",
+		},
+		{
+			name:       "unknown code",
+			change:     func(req *types.TokenRequest) { req.Code = "unknown" },
+			wantErr:    ErrInvalidGrant,
+			wantUnused: true,
+		},
+		{
+			name:       "other client",
+			change:     func(req *types.TokenRequest) { req.ClientId = otherClient.ClientId },
+			wantErr:    ErrInvalidGrant,
+			wantUnused: true,
+		},
+		{
+			name:       "other redirect uri",
+			change:     func(req *types.TokenRequest) { req.RedirectURI = "https://evil.example.com/callback" },
+			wantErr:    ErrInvalidGrant,
+			wantUnused: true,
+		},
+		{
+			name:       "wrong code verifier",
+			change:     func(req *types.TokenRequest) { req.CodeVerifier = testCodeVerifier[1:] + "A" },
+			wantErr:    ErrInvalidGrant,
+			wantUnused: true,
+		},
+		{
+			name:    "expired code",
+			expired: true,
+			wantErr: ErrInvalidGrant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oauthrepo := newFakeOAuthRepo(webClient, otherClient)
+			o, sessions := newTestOAuth(t, oauthrepo)
+
+			expiresAt := time.Now().Add(time.Minute)
+			if tt.expired {
+				expiresAt = time.Now().Add(-time.Second)
+			}
+			code := "authorization-code"
+			oauthrepo.codes[oauth.HashCode(code)] = types.AuthorizationCode{
+				CodeHash:            oauth.HashCode(code),
+				ClientId:            webClient.ClientId,
+				UserId:              testUserId,
+				RedirectURI:         testRedirectURI,
+				Scope:               "profile",
+				CodeChallenge:       oauth.CodeChallenge(testCodeVerifier),
+				CodeChallengeMethod: oauth.CodeChallengeMethodS256,
+				ExpiresAt:           expiresAt,
+			}
+
+			req := types.TokenRequest{
+				GrantType:    oauth.GrantTypeAuthorizationCode,
+				ClientId:     webClient.ClientId,
+				Code:         code,
+				RedirectURI:  testRedirectURI,
+				CodeVerifier: testCodeVerifier,
+			}
+			if tt.change != nil {
+				tt.change(&req)
+			}
+
+			_, err := o.Token(context.Background(), req, "203.0.113.1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Token() error = %v, want %v", err, tt.wantErr)
+			}
+			if used := oauthrepo.codes[oauth.HashCode(code)].Used; used == tt.wantUnused {
+				t.Errorf("code used = %v, want %v", used, !tt.wantUnused)
+			}
+			if tt.wantErr != nil {
+				if len(sessions.sessions) != 0 {
+					t.Errorf("Token() created %d sessions, want none", len(sessions.sessions))
+				}
+				return
+			}
+
+			if len(sessions.sessions) != 1 || sessions.sessions[0].ClientId != webClient.ClientId {
+				t.Fatalf("sessions = %+v, want one of the client", sessions.sessions)
+			}
+			if sessions.sessions[0].SessionId != oauthrepo.codes[oauth.HashCode(code)].SessionId {
+				t.Errorf("code is used for session %q, want %q", oauthrepo.codes[oauth.HashCode(code)].SessionId, sessions.sessions[0].SessionId)
+			}
+		})
+	}
+}
+
+func TestExchangeCodeReuse(t *testing.T) {
+	oauthrepo := newFakeOAuthRepo(webClient)
+	o, sessions := newTestOAuth(t, oauthrepo)
+
+	code := "authorization-code"
+	oauthrepo.codes[oauth.HashCode(code)] = types.AuthorizationCode{
+		CodeHash:            oauth.HashCode(code),
+		ClientId:            webClient.ClientId,
+		UserId:              testUserId,
+		RedirectURI:         testRedirectURI,
+		Scope:               "profile",
+		CodeChallenge:       oauth.CodeChallenge(testCodeVerifier),
+		CodeChallengeMethod: oauth.CodeChallengeMethodS256,
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}
+	req := types.TokenRequest{
+		GrantType:    oauth.GrantTypeAuthorizationCode,
+		ClientId:     webClient.ClientId,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: testCodeVerifier,
+	}
+	if _, err := o.Token(context.Background(), req, "203.0.113.1"); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	// A replay without the code verifier is rejected, but does not revoke the session.
+	replay := req
+	replay.CodeVerifier = ""
+	if _, err := o.Token(context.Background(), replay, "198.51.100.2"); !errors.Is(err, ErrInvalidGrant) {
+		t.Fatalf("Token() without the verifier error = %v, want %v", err, ErrInvalidGrant)
+	}
+	if sessions.sessions[0].IsRevoked() {
+		t.Fatalf("session is revoked by a replay without the code verifier")
+	}
+
+	// A replay that passes the checks revokes the session issued for the code.
+	if _, err := o.Token(context.Background(), req, "198.51.100.2"); !errors.Is(err, ErrInvalidGrant) {
+		t.Fatalf("second Token() error = %v, want %v", err, ErrInvalidGrant)
+	}
+	if len(sessions.sessions) != 1 || !sessions.sessions[0].IsRevoked() {
+		t.Errorf("sessions = %+v, want the one issued for the code revoked", sessions.sessions)
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/auth"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"time"
+)
+
+var (
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
+// withIDToken adds an ID token to tokens issued for the openid scope.
+func (o *OAuth) withIDToken(ctx context.Context, tokens types.Tokens, clientId, nonce string, authTime time.Time) (types.Tokens, error) {
+	if !oauth.HasScope(tokens.Scope, oauth.ScopeOpenID) {
+		return tokens, nil
+	}
+
+	claims, err := o.user.tokenManager.ParseAccessToken(tokens.AccessToken)
+	if err != nil {
+		logger.Errorf("failed to parse issued access token: %s", err)
+		return types.Tokens{}, err
+	}
+
+	info, err := o.userInfo(ctx, claims.Subject, tokens.Scope)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	idClaims := auth.IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   o.issuer,
+			Subject:  info.Subject,
+			Audience: jwt.ClaimStrings{clientId},
+		},
+		Nonce:             nonce,
+		AtHash:            auth.AccessTokenHash(tokens.AccessToken),
+		Email:             info.Email,
+		EmailVerified:     info.EmailVerified,
+		PreferredUsername: info.PreferredUsername,
+	}
+	if !authTime.IsZero() {
+		idClaims.AuthTime = jwt.NewNumericDate(authTime)
+	}
+
+	tokens.IDToken, err = o.user.tokenManager.NewIDToken(idClaims, o.idTokenTTL)
+	if err != nil {
+		logger.Errorf("failed to create id token: %s", err)
+		return types.Tokens{}, err
+	}
+
+	return tokens, nil
+}
+
+// UserInfo returns the claims of the token owner released for the token scope.
+func (o *OAuth) UserInfo(ctx context.Context, identity types.Identity) (types.UserInfo, error) {
+	if identity.IsClient() || !oauth.HasScope(identity.Scope, oauth.ScopeOpenID) {
+		return types.UserInfo{}, ErrInsufficientScope
+	}
+
+	return o.userInfo(ctx, identity.UserId, identity.Scope)
+}
+
+func (o *OAuth) userInfo(ctx context.Context, userId string, scope string) (types.UserInfo, error) {
+	user, err := o.user.userrepo.GetUserByID(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get user by id: %s", err)
+		return types.UserInfo{}, err
+	}
+	if user == nil {
+		return types.UserInfo{}, ErrUserNotFound
+	}
+
+	info := types.UserInfo{
+		Subject: user.UserUUID,
+	}
+
+	if oauth.HasScope(scope, oauth.ScopeEmail) {
+		// Email addresses are not confirmed on sign up.
+		verified := false
+		info.Email = user.Email
+		info.EmailVerified = &verified
+	}
+
+	if oauth.HasScope(scope, oauth.ScopeProfile) {
+		info.PreferredUsername = user.Email
+	}
+
+	return info, nil
+}
+
+// Discovery returns the OpenID Connect provider metadata.
+func (o *OAuth) Discovery() oauth.ProviderMetadata {
+	return oauth.ProviderMetadata{
+		Issuer:                 o.issuer,
+		AuthorizationEndpoint:  o.issuer + "/oauth/authorize",
+		TokenEndpoint:          o.issuer + "/oauth/token",
+		UserinfoEndpoint:       o.issuer + "/userinfo",
+		JwksURI:                o.issuer + "/.well-known/jwks.json",
+		ScopesSupported:        oauth.StandardScopes,
+		ResponseTypesSupported: []string{oauth.ResponseTypeCode},
+		GrantTypesSupported: []string{
+			oauth.GrantTypeAuthorizationCode,
+			oauth.GrantTypeRefreshToken,
+			oauth.GrantTypeClientCredentials,
+			oauth.GrantTypeDeviceCode,
+			oauth.GrantTypeTokenExchange,
+		},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{jwt.SigningMethodRS256.Alg()},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{oauth.CodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "at_hash",
+			"email", "email_verified", "preferred_username",
+		},
+	}
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"html"
+	"medods-test/internal/auth/repo/postgres"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/email"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"strings"
+	"time"
+)
+
+var (
+	ErrOrganizationNotFound      = errors.New("organization not found")
+	ErrOrganizationAlreadyExists = errors.New("organization already exists")
+	ErrNotOrganizationMember     = errors.New("user is not a member of the organization")
+	ErrInvalidInvitation         = errors.New("invalid or expired invitation")
+	ErrInvitationEmailMismatch   = errors.New("invitation was sent to another email")
+	ErrRoleNotAssignable         = errors.New("role cannot be given to organization members")
+)
+
+type OrganizationRepo interface {
+	CreateOrganization(ctx context.Context, org types.Organization) error
+	GetOrganizationByID(ctx context.Context, orgId string) (*types.Organization, error)
+	ListOrganizations(ctx context.Context) ([]types.Organization, error)
+	GetMembership(ctx context.Context, orgId string, userId string) (*types.Membership, error)
+	ListMembers(ctx context.Context, orgId string) ([]types.Membership, error)
+	ListUserMemberships(ctx context.Context, userId string) ([]types.Membership, error)
+	SetMembership(ctx context.Context, orgId string, userId string, roleIds []string) error
+	DeleteMembership(ctx context.Context, orgId string, userId string) (bool, error)
+	CreateInvitation(ctx context.Context, invitation types.Invitation) error
+	GetInvitation(ctx context.Context, tokenHash string) (*types.Invitation, error)
+	AcceptInvitation(ctx context.Context, tokenHash string, userId string) (*types.Invitation, error)
+}
+
+// Organization manages organizations, their members and invitations.
+type Organization struct {
+	orgrepo  OrganizationRepo
+	userrepo UserRepo
+	rbac     *RBAC
+	smtp     email.Sender
+
+	invitationTTL time.Duration
+}
+
+func (o *Organization) Create(ctx context.Context, name string, slug string) (*types.Organization, error) {
+	org := types.Organization{
+		Id:   uuid.NewString(),
+		Name: name,
+		Slug: strings.ToLower(slug),
+	}
+
+	if err := o.orgrepo.CreateOrganization(ctx, org); err != nil {
+		logger.Errorf("failed to create organization: %s", err)
+		if errors.Is(err, postgres.ErrUniqueContraintFailed) {
+			return nil, ErrOrganizationAlreadyExists
+		}
+		return nil, err
+	}
+
+	return o.Get(ctx, org.Id)
+}
+
+func (o *Organization) Get(ctx context.Context, orgId string) (*types.Organization, error) {
+	if uuid.Validate(orgId) != nil {
+		return nil, ErrOrganizationNotFound
+	}
+
+	org, err := o.orgrepo.GetOrganizationByID(ctx, orgId)
+	if err != nil {
+		logger.Errorf("failed to get organization: %s", err)
+		return nil, err
+	}
+	if org == nil {
+		return nil, ErrOrganizationNotFound
+	}
+	return org, nil
+}
+
+func (o *Organization) List(ctx context.Context) ([]types.Organization, error) {
+	orgs, err := o.orgrepo.ListOrganizations(ctx)
+	if err != nil {
+		logger.Errorf("failed to list organizations: %s", err)
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (o *Organization) Members(ctx context.Context, orgId string) ([]types.Membership, error) {
+	if _, err := o.Get(ctx, orgId); err != nil {
+		return nil, err
+	}
+
+	members, err := o.orgrepo.ListMembers(ctx, orgId)
+	if err != nil {
+		logger.Errorf("failed to list members: %s", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// UserMemberships returns the organizations the user belongs to.
+func (o *Organization) UserMemberships(ctx context.Context, userId string) ([]types.Membership, error) {
+	memberships, err := o.orgrepo.ListUserMemberships(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list user memberships: %s", err)
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// SetMember adds the user to the organization or replaces the user's roles in it.
+func (o *Organization) SetMember(ctx context.Context, orgId string, userId string, roleIds []string) error {
+	if _, err := o.Get(ctx, orgId); err != nil {
+		return err
+	}
+	if uuid.Validate(userId) != nil {
+		return ErrUserNotFound
+	}
+
+	user, err := o.userrepo.GetUserByID(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get user: %s", err)
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if _, err = o.checkRoles(ctx, roleIds); err != nil {
+		return err
+	}
+
+	if err = o.orgrepo.SetMembership(ctx, orgId, userId, nonNil(roleIds)); err != nil {
+		logger.Errorf("failed to set membership: %s", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateMember replaces the roles of an existing member of the grantor's current organization.
+// Unlike SetMember it cannot add users to the organization, which organization administrators
+// do with invitations, and it gives only roles within the grantor's own permissions there.
+func (o *Organization) UpdateMember(ctx context.Context, grantor types.Identity, userId string, roleIds []string) error {
+	if uuid.Validate(grantor.OrgId) != nil || uuid.Validate(userId) != nil {
+		return ErrNotOrganizationMember
+	}
+
+	membership, err := o.orgrepo.GetMembership(ctx, grantor.OrgId, userId)
+	if err != nil {
+		logger.Errorf("failed to get membership: %s", err)
+		return err
+	}
+	if membership == nil {
+		return ErrNotOrganizationMember
+	}
+
+	if err = o.checkGrantor(ctx, grantor, roleIds); err != nil {
+		return err
+	}
+
+	return o.SetMember(ctx, grantor.OrgId, userId, roleIds)
+}
+
+func (o *Organization) RemoveMember(ctx context.Context, orgId string, userId string) error {
+	if uuid.Validate(orgId) != nil || uuid.Validate(userId) != nil {
+		return ErrNotOrganizationMember
+	}
+
+	deleted, err := o.orgrepo.DeleteMembership(ctx, orgId, userId)
+	if err != nil {
+		logger.Errorf("failed to delete membership: %s", err)
+		return err
+	}
+	if !deleted {
+		return ErrNotOrganizationMember
+	}
+	return nil
+}
+
+// InviteMember invites to the grantor's current organization with roles within the grantor's
+// own permissions there.
+func (o *Organization) InviteMember(ctx context.Context, grantor types.Identity, recipient string, roleIds []string) (*types.Invitation, error) {
+	if uuid.Validate(grantor.OrgId) != nil {
+		return nil, ErrOrganizationNotFound
+	}
+	if err := o.checkGrantor(ctx, grantor, roleIds); err != nil {
+		return nil, err
+	}
+
+	return o.Invite(ctx, grantor.OrgId, grantor.UserId, recipient, roleIds)
+}
+
+// Invite emails a one-time invitation to join the organization with the given roles.
+func (o *Organization) Invite(ctx context.Context, orgId string, inviterId string, recipient string, roleIds []string) (*types.Invitation, error) {
+	org, err := o.Get(ctx, orgId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = o.checkRoles(ctx, roleIds); err != nil {
+		return nil, err
+	}
+
+	token, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate invitation token: %s", err)
+		return nil, err
+	}
+
+	invitation := types.Invitation{
+		Id:             uuid.NewString(),
+		OrganizationId: org.Id,
+		Email:          strings.ToLower(recipient),
+		RoleIds:        nonNil(roleIds),
+		TokenHash:      oauth.HashCode(token),
+		InvitedBy:      inviterId,
+		ExpiresAt:      time.Now().Add(o.invitationTTL),
+	}
+	if err = o.orgrepo.CreateInvitation(ctx, invitation); err != nil {
+		logger.Errorf("failed to create invitation: %s", err)
+		return nil, err
+	}
+
+	send := email.Send{
+		Recipient: invitation.Email,
+		Subject:   fmt.Sprintf("Приглашение в %s", org.Name),
+		Body: fmt.Sprintf(`<h1>Приглашение в организацию</h1>
+<p>Вас пригласили в организацию «%s».</p>
+<p>Чтобы принять приглашение, войдите в свою учётную запись и отправьте код приглашения:</p>
+<p><b>%s</b></p>
+<p>Приглашение действует до %s.</p>
+<p>С уважением,<br>Команда поддержки</p>`, html.EscapeString(org.Name), token, invitation.ExpiresAt.Format("02.01.2006 15:04")),
+	}
+	if err = o.smtp.Send(send); err != nil {
+		logger.Errorf("failed to send invitation: %s", err.Error())
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// AcceptInvitation makes the user a member of the organization the invitation was sent for.
+// The invitation must have been sent to the user's email.
+func (o *Organization) AcceptInvitation(ctx context.Context, userId string, token string) (*types.Membership, error) {
+	tokenHash := oauth.HashCode(token)
+
+	invitation, err := o.orgrepo.GetInvitation(ctx, tokenHash)
+	if err != nil {
+		logger.Errorf("failed to get invitation: %s", err)
+		return nil, err
+	}
+	if invitation == nil || invitation.AcceptedAt != nil || invitation.IsExpired() {
+		return nil, ErrInvalidInvitation
+	}
+
+	user, err := o.userrepo.GetUserByID(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get user: %s", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	if user.OrganizationId != "" && user.OrganizationId != invitation.OrganizationId {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	accepted, err := o.orgrepo.AcceptInvitation(ctx, tokenHash, userId)
+	if err != nil {
+		logger.Errorf("failed to accept invitation: %s", err)
+		return nil, err
+	}
+	if accepted == nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	membership, err := o.orgrepo.GetMembership(ctx, accepted.OrganizationId, userId)
+	if err != nil {
+		logger.Errorf("failed to get membership: %s", err)
+		return nil, err
+	}
+	return membership, nil
+}
+
+// checkRoles returns the roles if they exist and may be given to organization members.
+func (o *Organization) checkRoles(ctx context.Context, roleIds []string) ([]types.Role, error) {
+	roles := make([]types.Role, 0, len(roleIds))
+	for _, roleId := range roleIds {
+		role, err := o.rbac.GetRole(ctx, roleId)
+		if err != nil {
+			return nil, err
+		}
+		if !role.IsOrganizationRole() {
+			return nil, fmt.Errorf("%w: %s", ErrRoleNotAssignable, role.Name)
+		}
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+// checkGrantor returns ErrPermissionDenied if any of the roles grants a permission the
+// grantor does not have in the current organization.
+func (o *Organization) checkGrantor(ctx context.Context, grantor types.Identity, roleIds []string) error {
+	roles, err := o.checkRoles(ctx, roleIds)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		if len(role.Permissions) == 0 {
+			continue
+		}
+		if err = o.rbac.CheckPermissions(ctx, grantor.OrgRoles, role.Permissions...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"slices"
+	"testing"
+)
+
+const testOrgId = "9b8a7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+
+var (
+	orgAdminRole   = types.Role{Id: "0f1e2d3c-4b5a-4697-8877-665544332211", Name: types.RoleAdmin, Permissions: types.PermissionNames()}
+	orgSupportRole = types.Role{Id: "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d", Name: "support", Permissions: []string{types.PermissionUsersRead}}
+	orgManagerRole = types.Role{Id: "2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e", Name: "manager", Permissions: []string{types.PermissionMembersRead, types.PermissionMembersWrite}}
+	orgViewerRole  = types.Role{Id: "3c4d5e6f-7a8b-4c9d-8e1f-2a3b4c5d6e7f", Name: "viewer", Permissions: []string{types.PermissionMembersRead}}
+	orgGuestRole   = types.Role{Id: "4d5e6f7a-8b9c-4d0e-9f2a-3b4c5d6e7f8a", Name: "guest"}
+	orgInviterRole = types.Role{Id: "5e6f7a8b-9c0d-4e1f-8a3b-4c5d6e7f8a9b", Name: "inviter", Permissions: []string{types.PermissionMembersWrite}}
+)
+
+func newTestOrganization() (*Organization, *fakeOrganizationRepo, types.User) {
+	member := types.User{UserUUID: "6f7a8b9c-0d1e-4f2a-9b4c-5d6e7f8a9b0c", Email: "bob@example.com", Status: types.UserStatusActive}
+	orgrepo := newFakeOrganizationRepo(types.Organization{Id: testOrgId, Name: "Clinic", Slug: "clinic"})
+	orgrepo.memberships[testOrgId+"/"+member.UserUUID] = types.Membership{OrganizationId: testOrgId, UserId: member.UserUUID}
+
+	users := newFakeUserRepo(member)
+	rbacrepo := &fakeRBACRepo{roles: []types.Role{orgAdminRole, orgSupportRole, orgManagerRole, orgViewerRole, orgGuestRole, orgInviterRole}}
+	return &Organization{
+		orgrepo:  orgrepo,
+		userrepo: users,
+		rbac:     &RBAC{rbacrepo: rbacrepo, userrepo: users},
+		smtp:     &fakeEmailSender{},
+	}, orgrepo, member
+}
+
+func TestOrganizationMemberRoles(t *testing.T) {
+	manager := types.Identity{SessionId: "s1", UserId: "7a8b9c0d-1e2f-4a3b-8c5d-6e7f8a9b0c1d", OrgId: testOrgId, OrgRoles: []string{orgManagerRole.Name}}
+	inviter := manager
+	inviter.OrgRoles = []string{orgInviterRole.Name}
+
+	tests := []struct {
+		name    string
+		grantor *types.Identity
+		roles   []types.Role
+		wantErr error
+	}{
+		{
+			name:  "admin gives organization roles",
+			roles: []types.Role{orgManagerRole, orgGuestRole},
+		},
+		{
+			name:    "admin gives the admin role",
+			roles:   []types.Role{orgAdminRole},
+			wantErr: ErrRoleNotAssignable,
+		},
+		{
+			name:    "admin gives a global role",
+			roles:   []types.Role{orgViewerRole, orgSupportRole},
+			wantErr: ErrRoleNotAssignable,
+		},
+		{
+			name:    "member gives roles within own permissions",
+			grantor: &manager,
+			roles:   []types.Role{orgManagerRole, orgViewerRole},
+		},
+		{
+			name:    "member gives a role without permissions",
+			grantor: &inviter,
+			roles:   []types.Role{orgGuestRole},
+		},
+		{
+			name:    "member gives the admin role",
+			grantor: &manager,
+			roles:   []types.Role{orgAdminRole},
+			wantErr: ErrRoleNotAssignable,
+		},
+		{
+			name:    "member gives a global role",
+			grantor: &manager,
+			roles:   []types.Role{orgSupportRole},
+			wantErr: ErrRoleNotAssignable,
+		},
+		{
+			name:    "member gives a permission they do not have",
+			grantor: &inviter,
+			roles:   []types.Role{orgViewerRole},
+			wantErr: ErrPermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roleIds := make([]string, 0, len(tt.roles))
+			for _, role := range tt.roles {
+				roleIds = append(roleIds, role.Id)
+			}
+
+			t.Run("set member", func(t *testing.T) {
+				org, orgrepo, member := newTestOrganization()
+
+				var err error
+				if tt.grantor == nil {
+					err = org.SetMember(context.Background(), testOrgId, member.UserUUID, roleIds)
+				} else {
+					err = org.UpdateMember(context.Background(), *tt.grantor, member.UserUUID, roleIds)
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+
+				membership := orgrepo.memberships[testOrgId+"/"+member.UserUUID]
+				wantRoles := roleIds
+				if tt.wantErr != nil {
+					wantRoles = nil
+				}
+				if !slices.Equal(membership.Roles, wantRoles) {
+					t.Errorf("member roles = %v, want %v", membership.Roles, wantRoles)
+				}
+			})
+
+			t.Run("invite", func(t *testing.T) {
+				org, orgrepo, _ := newTestOrganization()
+
+				var err error
+				if tt.grantor == nil {
+					_, err = org.Invite(context.Background(), testOrgId, manager.UserId, "carol@example.com", roleIds)
+				} else {
+					_, err = org.InviteMember(context.Background(), *tt.grantor, "carol@example.com", roleIds)
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+
+				wantInvitations := 1
+				if tt.wantErr != nil {
+					wantInvitations = 0
+				}
+				if got := len(orgrepo.invitations); got != wantInvitations {
+					t.Errorf("invitations = %d, want %d", got, wantInvitations)
+				}
+				if got := len(org.smtp.(*fakeEmailSender).messages("carol@example.com")); got != wantInvitations {
+					t.Errorf("invitation emails = %d, want %d", got, wantInvitations)
+				}
+			})
+		})
+	}
+}
+
+func TestUpdateMemberNotMember(t *testing.T) {
+	org, _, _ := newTestOrganization()
+	grantor := types.Identity{SessionId: "s1", UserId: "7a8b9c0d-1e2f-4a3b-8c5d-6e7f8a9b0c1d", OrgId: testOrgId, OrgRoles: []string{orgManagerRole.Name}}
+
+	err := org.UpdateMember(context.Background(), grantor, "8b9c0d1e-2f3a-4b4c-9d6e-7f8a9b0c1d2e", []string{orgViewerRole.Id})
+	if !errors.Is(err, ErrNotOrganizationMember) {
+		t.Errorf("UpdateMember() error = %v, want %v", err, ErrNotOrganizationMember)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"time"
+)
+
+var ErrPasswordReused = errors.New("password was used recently")
+
+// checkPasswordReuse rejects passwordHash if it is the current password of the user or one of
+// the previous passwords kept in the password history.
+func (u *User) checkPasswordReuse(ctx context.Context, user *types.User, passwordHash string) error {
+	if u.passwordHistorySize <= 0 {
+		return nil
+	}
+
+	hashes, err := u.userrepo.ListPasswordHistory(ctx, user.UserUUID, u.passwordHistorySize, time.Now().Add(-u.passwordHistoryRetention))
+	if err != nil {
+		logger.Errorf("failed to list password history: %s", err)
+		return err
+	}
+
+	for _, previous := range append(hashes, user.Password) {
+		if subtle.ConstantTimeCompare([]byte(previous), []byte(passwordHash)) == 1 {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// rememberPassword keeps the replaced password of the user in the password history.
+func (u *User) rememberPassword(ctx context.Context, user *types.User) {
+	if u.passwordHistorySize <= 0 {
+		return
+	}
+
+	if err := u.userrepo.AddPasswordHistory(ctx, user.UserUUID, user.Password, u.passwordHistorySize); err != nil {
+		logger.Errorf("failed to save password history: %s", err)
+	}
+}
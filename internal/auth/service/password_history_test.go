@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/oauth"
+	"testing"
+	"time"
+)
+
+// newPasswordHistoryTest returns a User keeping historySize previous passwords of the user of
+// testUserId, whose password is "password-0".
+func newPasswordHistoryTest(t *testing.T, historySize int) (*User, *fakeUserRepo) {
+	t.Helper()
+
+	users := newFakeUserRepo()
+	u := newTestUser(users, &fakeAuditRepo{})
+	u.policy = fakePasswordPolicy{}
+	u.notifications = &Notifications{audit: u.audit, channels: map[string]Channel{
+		types.NotificationChannelEmail: emailChannel{smtp: &fakeEmailSender{}},
+	}}
+	u.passwordHistorySize = historySize
+	u.passwordHistoryRetention = 24 * time.Hour
+
+	password, err := u.hasher.Hash("password-0")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	users.users[testUserId] = types.User{UserUUID: testUserId, Email: "alice@example.com", Password: password, Status: types.UserStatusActive}
+	return u, users
+}
+
+func TestChangePasswordHistory(t *testing.T) {
+	u, _ := newPasswordHistoryTest(t, 2)
+	identity := types.Identity{UserId: testUserId}
+	ctx := context.Background()
+
+	steps := []struct {
+		current string
+		next    string
+		wantErr error
+	}{
+		{current: "password-0", next: "password-0", wantErr: ErrPasswordReused},
+		{current: "password-0", next: "password-1"},
+		{current: "password-1", next: "password-2"},
+		{current: "password-2", next: "password-0", wantErr: ErrPasswordReused},
+		{current: "password-2", next: "password-1", wantErr: ErrPasswordReused},
+		{current: "password-2", next: "password-3"},
+		// Only the last two previous passwords are kept.
+		{current: "password-3", next: "password-0"},
+	}
+
+	for i, step := range steps {
+		err := u.ChangePassword(ctx, identity, step.current, step.next, false)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("step %d: ChangePassword(%s -> %s) error = %v, want %v", i, step.current, step.next, err, step.wantErr)
+		}
+	}
+}
+
+func TestPasswordHistoryRetention(t *testing.T) {
+	u, users := newPasswordHistoryTest(t, 5)
+	identity := types.Identity{UserId: testUserId}
+	ctx := context.Background()
+
+	if err := u.ChangePassword(ctx, identity, "password-0", "password-1", false); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+
+	// A password older than the retention can be used again.
+	users.history[testUserId][0].createdAt = time.Now().Add(-25 * time.Hour)
+	if err := u.ChangePassword(ctx, identity, "password-1", "password-0", false); err != nil {
+		t.Errorf("ChangePassword() to a password past the retention error = %v", err)
+	}
+
+	// PurgePasswordHistory removes it for good.
+	admin := &UserAdmin{userrepo: users, passwordHistoryRetention: u.passwordHistoryRetention}
+	if deleted, err := admin.PurgePasswordHistory(ctx); err != nil || deleted != 1 {
+		t.Errorf("PurgePasswordHistory() = %d, %v, want 1 deleted", deleted, err)
+	}
+	if history := users.history[testUserId]; len(history) != 1 {
+		t.Errorf("history = %+v, want the latest password only", history)
+	}
+}
+
+func TestPasswordHistoryDisabled(t *testing.T) {
+	u, users := newPasswordHistoryTest(t, 0)
+
+	if err := u.ChangePassword(context.Background(), types.Identity{UserId: testUserId}, "password-0", "password-0", false); err != nil {
+		t.Errorf("ChangePassword() to the same password without history error = %v", err)
+	}
+	if len(users.history) != 0 {
+		t.Errorf("history = %+v, want none kept", users.history)
+	}
+}
+
+func TestResetPasswordHistory(t *testing.T) {
+	u, users := newPasswordHistoryTest(t, 2)
+	ctx := context.Background()
+
+	token := "reset-token"
+	users.resetTokens = map[string]types.PasswordResetToken{
+		oauth.HashCode(token): {TokenHash: oauth.HashCode(token), UserId: testUserId, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+
+	// The current password cannot be set again, and the token is kept for another try.
+	if err := u.ResetPassword(ctx, token, "password-0"); !errors.Is(err, ErrPasswordReused) {
+		t.Fatalf("ResetPassword() to the current password error = %v, want %v", err, ErrPasswordReused)
+	}
+	if err := u.ResetPassword(ctx, token, "password-1"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	password, _ := u.hasher.Hash("password-0")
+	if history := users.history[testUserId]; len(history) != 1 || history[0].hash != password {
+		t.Errorf("history = %+v, want the replaced password", history)
+	}
+}
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"medods-test/pkg/sms"
+	"strings"
+	"time"
+)
+
+const (
+	smsCodeTTL         = 10 * time.Minute
+	maxSMSCodeAttempts = 5
+	// maxSMSCodes is how many codes for one purpose a user can be sent within smsCodeWindow.
+	maxSMSCodes   = 5
+	smsCodeWindow = time.Hour
+)
+
+var (
+	ErrInvalidPhone    = errors.New("phone must be in the international format, e.g. +79991234567")
+	ErrPhoneNotFound   = errors.New("phone not found")
+	ErrInvalidSMSCode  = errors.New("invalid or expired sms code")
+	ErrTooManySMSCodes = errors.New("too many sms codes requested, try again later")
+)
+
+// Phone returns the phone of the user of identity.
+func (u *User) Phone(ctx context.Context, identity types.Identity) (*types.UserPhone, error) {
+	phone, err := u.phonerepo.GetPhone(ctx, identity.UserId)
+	if err != nil {
+		logger.Errorf("failed to get phone: %s", err)
+		return nil, err
+	}
+	if phone == nil {
+		return nil, ErrPhoneNotFound
+	}
+	return phone, nil
+}
+
+// SetPhone sets the phone of the user of identity and texts it a code that completes the
+// verification in VerifyPhone. The current password is required, since the phone receives
+// sign-in and recovery codes. A new number for a verified phone stays pending until it is
+// verified, the verified phone keeps working and is told about the change together with the
+// email. Changing only useForMFA of a verified phone sends no code and returns a nil challenge.
+func (u *User) SetPhone(ctx context.Context, identity types.Identity, password string, number string, useForMFA bool) (*types.SMSChallenge, error) {
+	number = sms.NormalizePhone(number)
+	if !sms.IsPhoneValid(number) {
+		return nil, ErrInvalidPhone
+	}
+	if u.channels[types.NotificationChannelSMS] == nil {
+		return nil, ErrChannelUnavailable
+	}
+
+	user, err := u.checkPassword(ctx, identity.UserId, password)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := u.phonerepo.GetPhone(ctx, identity.UserId)
+	if err != nil {
+		logger.Errorf("failed to get phone: %s", err)
+		return nil, err
+	}
+
+	phone := types.UserPhone{
+		UserId:    identity.UserId,
+		Phone:     number,
+		UseForMFA: useForMFA,
+	}
+	if current != nil && current.Phone == number && current.IsVerified() {
+		if err = u.phonerepo.SavePhone(ctx, phone); err != nil {
+			logger.Errorf("failed to save phone: %s", err)
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	// Checked before the number is saved, so that a user over the limit keeps the verified phone.
+	if err = u.checkSMSLimit(ctx, identity.UserId, types.SMSCodePhoneVerification); err != nil {
+		return nil, err
+	}
+
+	replacing := current != nil && current.IsVerified()
+	if replacing {
+		saved, err := u.phonerepo.SavePendingPhone(ctx, identity.UserId, number, useForMFA)
+		if err != nil {
+			logger.Errorf("failed to save pending phone: %s", err)
+			return nil, err
+		}
+		if !saved {
+			return nil, ErrPhoneNotFound
+		}
+	} else if err = u.phonerepo.SavePhone(ctx, phone); err != nil {
+		logger.Errorf("failed to save phone: %s", err)
+		return nil, err
+	}
+	u.audit.Record(ctx, types.AuditPhoneChanged, identity.UserId, map[string]string{"phone": maskPhone(number)})
+
+	if replacing {
+		u.notifications.Notify(ctx, user, types.Notice{
+			Event:   types.NotificationPhoneChanged,
+			Subject: "Смена телефона",
+			Body: fmt.Sprintf(`<p>Для вашей учётной записи запрошена смена телефона %s на %s. Прежний номер действует, пока не будет подтверждён новый.</p>
+<p>Если вы этого не делали, смените пароль и свяжитесь с нашей службой поддержки.</p>`, maskPhone(current.Phone), maskPhone(number)),
+			Text: "Запрошена смена телефона вашей учётной записи. Если это были не вы, смените пароль.",
+		})
+	}
+
+	challenge, err := u.sendSMSCode(ctx, identity.UserId, types.SMSCodePhoneVerification, number,
+		"Код подтверждения телефона: %s. Никому его не сообщайте.")
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// VerifyPhone completes the verification of the phone of the user of identity with the code
+// texted by SetPhone.
+func (u *User) VerifyPhone(ctx context.Context, identity types.Identity, token string, code string) error {
+	smsCode, err := u.checkSMSCode(ctx, token, code, types.SMSCodePhoneVerification)
+	if err != nil {
+		return err
+	}
+	if smsCode.UserId != identity.UserId {
+		return ErrInvalidSMSCode
+	}
+
+	if err = u.useSMSCode(ctx, smsCode); err != nil {
+		return err
+	}
+
+	// The phone may have been changed after the code was sent. A verified pending number
+	// replaces the phone.
+	verified, err := u.phonerepo.VerifyPhone(ctx, identity.UserId, smsCode.Phone, time.Now())
+	if err != nil {
+		logger.Errorf("failed to verify phone: %s", err)
+		return err
+	}
+	if !verified {
+		return ErrInvalidSMSCode
+	}
+
+	u.audit.Record(ctx, types.AuditPhoneVerified, identity.UserId, map[string]string{"phone": maskPhone(smsCode.Phone)})
+	return nil
+}
+
+// RemovePhone removes the phone of the user of identity. Sign-in codes are emailed again.
+func (u *User) RemovePhone(ctx context.Context, identity types.Identity) error {
+	deleted, err := u.phonerepo.DeletePhone(ctx, identity.UserId)
+	if err != nil {
+		logger.Errorf("failed to delete phone: %s", err)
+		return err
+	}
+	if !deleted {
+		return ErrPhoneNotFound
+	}
+
+	u.audit.Record(ctx, types.AuditPhoneRemoved, identity.UserId, nil)
+	return nil
+}
+
+// RequestPasswordRecovery texts a code to the verified phone of the user with the email, the
+// code and the returned token set a new password in RecoverPassword. A challenge is returned
+// whether the user exists or not, so that the response does not tell which emails have a phone.
+func (u *User) RequestPasswordRecovery(ctx context.Context, email string, organizationId string) (types.SMSChallenge, error) {
+	token, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate sms token: %s", err)
+		return types.SMSChallenge{}, err
+	}
+	decoy := types.SMSChallenge{
+		Token:     token,
+		ExpiresAt: time.Now().Add(smsCodeTTL),
+	}
+
+	if u.channels[types.NotificationChannelSMS] == nil || u.verifierFor(email) != nil {
+		return decoy, nil
+	}
+
+	user, err := u.userrepo.GetUserByLogin(ctx, email, u.userOrganization(organizationId))
+	if err != nil {
+		logger.Errorf("failed to get user by email: %s", err)
+		return types.SMSChallenge{}, err
+	}
+	if user == nil || !user.IsActive() {
+		return decoy, nil
+	}
+
+	phone, err := u.phonerepo.GetPhone(ctx, user.UserUUID)
+	if err != nil {
+		logger.Errorf("failed to get phone: %s", err)
+		return types.SMSChallenge{}, err
+	}
+	if phone == nil || !phone.IsVerified() {
+		return decoy, nil
+	}
+
+	if err = u.checkSMSLimit(ctx, user.UserUUID, types.SMSCodePasswordReset); err != nil {
+		return decoy, nil
+	}
+	challenge, err := u.sendSMSCode(ctx, user.UserUUID, types.SMSCodePasswordReset, phone.Phone,
+		"Код для сброса пароля: %s. Если вы его не запрашивали, не сообщайте его никому.")
+	if err != nil {
+		return types.SMSChallenge{}, err
+	}
+
+	u.audit.Record(ctx, types.AuditPasswordRecovery, user.UserUUID, map[string]string{"channel": types.NotificationChannelSMS})
+	return challenge, nil
+}
+
+// RecoverPassword sets a new password with the code texted by RequestPasswordRecovery and
+// revokes every session of the user.
+func (u *User) RecoverPassword(ctx context.Context, token string, code string, password string) error {
+	smsCode, err := u.checkSMSCode(ctx, token, code, types.SMSCodePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.userrepo.GetUserByID(ctx, smsCode.UserId)
+	if err != nil {
+		logger.Errorf("failed to get user by id: %s", err)
+		return err
+	}
+	if user == nil {
+		return ErrInvalidSMSCode
+	}
+	if err = checkUserActive(user); err != nil {
+		return err
+	}
+
+	// The code only proves the phone it was sent to.
+	phone, err := u.phonerepo.GetPhone(ctx, user.UserUUID)
+	if err != nil {
+		logger.Errorf("failed to get phone: %s", err)
+		return err
+	}
+	if phone == nil || !phone.IsVerified() || phone.Phone != smsCode.Phone {
+		return ErrInvalidSMSCode
+	}
+
+	if err = u.checkPasswordPolicy(ctx, password, user.Email); err != nil {
+		return err
+	}
+
+	passwordHash, err := u.hasher.Hash(password)
+	if err != nil {
+		logger.Errorf("failed to hash password: %s", err)
+		return err
+	}
+
+	if err = u.checkPasswordReuse(ctx, user, passwordHash); err != nil {
+		return err
+	}
+
+	if err = u.useSMSCode(ctx, smsCode); err != nil {
+		return err
+	}
+
+	if err = u.userrepo.ChangePassword(ctx, types.PasswordChange{
+		UserId:         user.UserUUID,
+		PasswordHash:   passwordHash,
+		RevokeSessions: true,
+	}); err != nil {
+		logger.Errorf("failed to change password: %s", err)
+		return err
+	}
+	u.rememberPassword(ctx, user)
+	u.audit.Record(ctx, types.AuditPasswordReset, user.UserUUID, map[string]string{"channel": types.NotificationChannelSMS})
+
+	u.notifications.Notify(ctx, user, types.Notice{
+		Event:   types.NotificationPasswordChanged,
+		Subject: "Пароль изменён",
+		Body: `<p>Пароль вашей учётной записи был сброшен по коду из SMS, все сессии завершены.</p>
+<p>Если вы этого не делали, пожалуйста, свяжитесь с нашей службой поддержки.</p>`,
+		Text: "Пароль вашей учётной записи сброшен по коду из SMS. Если это были не вы, свяжитесь с поддержкой.",
+	})
+
+	return nil
+}
+
+// checkSMSLimit returns ErrTooManySMSCodes if the user was sent too many codes for the purpose lately.
+func (u *User) checkSMSLimit(ctx context.Context, userId string, purpose string) error {
+	sent, err := u.phonerepo.CountSMSCodes(ctx, userId, purpose, time.Now().Add(-smsCodeWindow))
+	if err != nil {
+		logger.Errorf("failed to count sms codes: %s", err)
+		return err
+	}
+	if sent >= maxSMSCodes {
+		logger.Infof("sms code limit reached (user: %s, purpose: %s)", userId, purpose)
+		return ErrTooManySMSCodes
+	}
+	return nil
+}
+
+// sendSMSCode texts a new code for the purpose to the phone. text is the format of the message,
+// its verb is replaced with the code.
+func (u *User) sendSMSCode(ctx context.Context, userId string, purpose string, phone string, text string) (types.SMSChallenge, error) {
+	token, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate sms token: %s", err)
+		return types.SMSChallenge{}, err
+	}
+	code, err := oauth.NewDigits(mfaCodeLength)
+	if err != nil {
+		logger.Errorf("failed to generate sms code: %s", err)
+		return types.SMSChallenge{}, err
+	}
+
+	now := time.Now()
+	smsCode := types.SMSCode{
+		TokenHash: oauth.HashCode(token),
+		UserId:    userId,
+		Purpose:   purpose,
+		Phone:     phone,
+		CodeHash:  oauth.HashCode(code),
+		ExpiresAt: now.Add(smsCodeTTL),
+		CreatedAt: now,
+	}
+	if err = u.phonerepo.CreateSMSCode(ctx, smsCode); err != nil {
+		logger.Errorf("failed to save sms code: %s", err)
+		return types.SMSChallenge{}, err
+	}
+
+	message := types.ChannelMessage{Text: fmt.Sprintf(text, code)}
+	if err = u.channels[types.NotificationChannelSMS].Send(phone, message); err != nil {
+		logger.Errorf("failed to send sms code: %s", err)
+		return types.SMSChallenge{}, err
+	}
+
+	return types.SMSChallenge{
+		Token:     token,
+		ExpiresAt: smsCode.ExpiresAt,
+	}, nil
+}
+
+// checkSMSCode returns the code of the token if code matches it. A wrong code is counted
+// against the attempts of the token. The code is not used up, see useSMSCode.
+func (u *User) checkSMSCode(ctx context.Context, token string, code string, purpose string) (*types.SMSCode, error) {
+	tokenHash := oauth.HashCode(token)
+
+	smsCode, err := u.phonerepo.GetSMSCode(ctx, tokenHash)
+	if err != nil {
+		logger.Errorf("failed to get sms code: %s", err)
+		return nil, err
+	}
+	if smsCode == nil || smsCode.Purpose != purpose || smsCode.IsExpired() || smsCode.Attempts >= maxSMSCodeAttempts {
+		return nil, ErrInvalidSMSCode
+	}
+
+	if subtle.ConstantTimeCompare([]byte(oauth.HashCode(code)), []byte(smsCode.CodeHash)) != 1 {
+		if err = u.phonerepo.AddSMSCodeAttempt(ctx, tokenHash); err != nil {
+			logger.Errorf("failed to count sms code attempt: %s", err)
+		}
+		return nil, ErrInvalidSMSCode
+	}
+	return smsCode, nil
+}
+
+// useSMSCode uses up the code, so that only one request can complete its action.
+func (u *User) useSMSCode(ctx context.Context, smsCode *types.SMSCode) error {
+	used, err := u.phonerepo.UseSMSCode(ctx, smsCode.TokenHash)
+	if err != nil {
+		logger.Errorf("failed to use sms code: %s", err)
+		return err
+	}
+	if !used {
+		return ErrInvalidSMSCode
+	}
+	return nil
+}
+
+// mfaChannel returns the channel sign-in codes are sent to the user through: SMS if the user
+// verified a phone for it, email otherwise.
+func (u *User) mfaChannel(ctx context.Context, user *types.User) (string, Channel) {
+	if channel := u.channels[types.NotificationChannelSMS]; channel != nil {
+		phone, err := u.phonerepo.GetPhone(ctx, user.UserUUID)
+		if err != nil {
+			logger.Errorf("failed to get phone: %s", err)
+		} else if phone != nil && phone.IsVerified() && phone.UseForMFA {
+			return types.MFAChannelSMS, channel
+		}
+	}
+	return types.MFAChannelEmail, u.channels[types.NotificationChannelEmail]
+}
+
+// maskPhone hides all but the last digits of the phone for logs.
+func maskPhone(phone string) string {
+	const visible = 4
+	if len(phone) <= visible+1 {
+		return phone
+	}
+	return phone[:1] + strings.Repeat("*", len(phone)-visible-1) + phone[len(phone)-visible:]
+}
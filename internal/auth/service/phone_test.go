@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/sms/fake"
+	"regexp"
+	"testing"
+	"time"
+)
+
+const (
+	oldPhone = "+79991234567"
+	newPhone = "+79997654321"
+)
+
+var smsCodePattern = regexp.MustCompile(`\d{6}`)
+
+type phoneTest struct {
+	user   *User
+	phones *fakePhoneRepo
+	sms    *fake.FakeSender
+	smtp   *fakeEmailSender
+	local  types.User
+}
+
+// newPhoneTest returns a user with the password testPassword and, if current is set, that phone.
+func newPhoneTest(t *testing.T, current *types.UserPhone) *phoneTest {
+	t.Helper()
+
+	test := &phoneTest{
+		phones: newFakePhoneRepo(),
+		sms:    fake.NewFakeSender(""),
+		smtp:   &fakeEmailSender{},
+	}
+	users := newFakeUserRepo()
+	auditrepo := &fakeAuditRepo{}
+	test.user = newTestUser(users, auditrepo)
+
+	passwordHash, err := test.user.hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	test.local = types.User{
+		UserUUID: "5e6f7a8b-9c0d-4e1f-8a2b-3c4d5e6f7a8b",
+		Email:    "alice@example.com",
+		Password: passwordHash,
+		Status:   types.UserStatusActive,
+	}
+	_ = users.Create(context.Background(), test.local)
+
+	if current != nil {
+		current.UserId = test.local.UserUUID
+		test.phones.phones[current.UserId] = *current
+	}
+
+	channels := map[string]Channel{
+		types.NotificationChannelEmail: emailChannel{smtp: test.smtp},
+		types.NotificationChannelSMS:   smsChannel{sender: test.sms, phonerepo: test.phones},
+	}
+	test.user.phonerepo = test.phones
+	test.user.channels = channels
+	test.user.notifications = &Notifications{audit: test.user.audit, channels: channels}
+	return test
+}
+
+// lastCode returns the code of the last SMS sent to phone.
+func (p *phoneTest) lastCode(t *testing.T, phone string) string {
+	t.Helper()
+
+	message, ok := p.sms.Last(phone)
+	if !ok {
+		t.Fatalf("no sms sent to %s", phone)
+	}
+	code := smsCodePattern.FindString(message.Body)
+	if code == "" {
+		t.Fatalf("sms %q has no code", message.Body)
+	}
+	return code
+}
+
+func TestSetPhone(t *testing.T) {
+	verifiedAt := time.Now().Add(-24 * time.Hour)
+
+	tests := []struct {
+		name          string
+		current       *types.UserPhone
+		password      string
+		number        string
+		wantErr       error
+		wantChallenge bool
+		// wantPhone is the phone the codes go to right after SetPhone.
+		wantPhone   string
+		wantPending string
+		wantNotice  bool
+	}{
+		{
+			name:     "wrong password",
+			current:  &types.UserPhone{Phone: oldPhone, VerifiedAt: &verifiedAt, UseForMFA: true},
+			password: "guess",
+			number:   newPhone,
+			wantErr:  ErrInvalidPassword,
+		},
+		{
+			name:          "first phone",
+			password:      testPassword,
+			number:        "+7 (999) 765-43-21",
+			wantChallenge: true,
+		},
+		{
+			name:          "replacing an unverified phone",
+			current:       &types.UserPhone{Phone: oldPhone, UseForMFA: true},
+			password:      testPassword,
+			number:        newPhone,
+			wantChallenge: true,
+		},
+		{
+			name:          "replacing a verified phone",
+			current:       &types.UserPhone{Phone: oldPhone, VerifiedAt: &verifiedAt, UseForMFA: true},
+			password:      testPassword,
+			number:        newPhone,
+			wantChallenge: true,
+			wantPhone:     oldPhone,
+			wantPending:   newPhone,
+			wantNotice:    true,
+		},
+		{
+			name:      "same verified phone",
+			current:   &types.UserPhone{Phone: oldPhone, VerifiedAt: &verifiedAt, UseForMFA: true},
+			password:  testPassword,
+			number:    oldPhone,
+			wantPhone: oldPhone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPhoneTest(t, tt.current)
+			identity := types.Identity{SessionId: "s1", UserId: p.local.UserUUID}
+
+			challenge, err := p.user.SetPhone(context.Background(), identity, tt.password, tt.number, false)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetPhone() error = %v, want %v", err, tt.wantErr)
+			}
+			if (challenge != nil) != tt.wantChallenge {
+				t.Fatalf("SetPhone() challenge = %+v, want one: %v", challenge, tt.wantChallenge)
+			}
+			if tt.wantErr != nil {
+				if got := len(p.sms.Messages("")); got != 0 {
+					t.Errorf("SetPhone() sent %d sms, want none", got)
+				}
+				if phone := p.phones.phones[p.local.UserUUID]; phone != *tt.current {
+					t.Errorf("SetPhone() changed the phone to %+v", phone)
+				}
+				return
+			}
+
+			phone := p.phones.phones[p.local.UserUUID]
+			if tt.wantPhone != "" && (phone.Phone != tt.wantPhone || !phone.IsVerified()) {
+				t.Errorf("phone = %s (verified: %v), want %s verified", phone.Phone, phone.IsVerified(), tt.wantPhone)
+			}
+			if phone.PendingPhone != tt.wantPending {
+				t.Errorf("pending phone = %q, want %q", phone.PendingPhone, tt.wantPending)
+			}
+			if tt.wantChallenge {
+				p.lastCode(t, newPhone)
+			}
+
+			notices := len(p.smtp.messages(p.local.Email))
+			if tt.wantNotice != (notices > 0) {
+				t.Errorf("SetPhone() emailed %d notices, want one: %v", notices, tt.wantNotice)
+			}
+			if _, ok := p.sms.Last(oldPhone); ok != tt.wantNotice {
+				t.Errorf("SetPhone() texted the previous phone: %v, want %v", ok, tt.wantNotice)
+			}
+		})
+	}
+}
+
+func TestVerifyPendingPhone(t *testing.T) {
+	verifiedAt := time.Now().Add(-24 * time.Hour)
+	p := newPhoneTest(t, &types.UserPhone{Phone: oldPhone, VerifiedAt: &verifiedAt, UseForMFA: true})
+	identity := types.Identity{SessionId: "s1", UserId: p.local.UserUUID}
+
+	challenge, err := p.user.SetPhone(context.Background(), identity, testPassword, newPhone, false)
+	if err != nil {
+		t.Fatalf("SetPhone() error = %v", err)
+	}
+
+	// Until the new number is verified sign-in codes still go to the previous phone.
+	if channel, _ := p.user.mfaChannel(context.Background(), &p.local); channel != types.MFAChannelSMS {
+		t.Errorf("mfaChannel() = %s before verification, want %s", channel, types.MFAChannelSMS)
+	}
+	if recipient, _ := p.user.channels[types.NotificationChannelSMS].Recipient(context.Background(), &p.local); recipient != oldPhone {
+		t.Errorf("sms recipient = %s before verification, want %s", recipient, oldPhone)
+	}
+
+	code := p.lastCode(t, newPhone)
+	wrongCode := "000000"
+	if code == wrongCode {
+		wrongCode = "111111"
+	}
+	if err = p.user.VerifyPhone(context.Background(), identity, challenge.Token, wrongCode); !errors.Is(err, ErrInvalidSMSCode) {
+		t.Fatalf("VerifyPhone() wrong code error = %v, want %v", err, ErrInvalidSMSCode)
+	}
+	if p.phones.phones[p.local.UserUUID].Phone != oldPhone {
+		t.Fatalf("VerifyPhone() with a wrong code replaced the phone")
+	}
+	if err = p.user.VerifyPhone(context.Background(), identity, challenge.Token, code); err != nil {
+		t.Fatalf("VerifyPhone() error = %v", err)
+	}
+
+	phone := p.phones.phones[p.local.UserUUID]
+	if phone.Phone != newPhone || !phone.IsVerified() || phone.PendingPhone != "" || phone.UseForMFA {
+		t.Errorf("phone after verification = %+v, want %s verified without mfa", phone, newPhone)
+	}
+	if channel, _ := p.user.mfaChannel(context.Background(), &p.local); channel != types.MFAChannelEmail {
+		t.Errorf("mfaChannel() = %s after verification, want %s", channel, types.MFAChannelEmail)
+	}
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/email"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"time"
+)
+
+var ErrInvalidDeletionToken = errors.New("invalid or expired account deletion token")
+
+// Privacy serves data subject requests: exports the data stored about a user and erases it.
+type Privacy struct {
+	userrepo     UserRepo
+	sessionrepo  SessionRepo
+	identityrepo IdentityRepo
+	rbacrepo     RBACRepo
+	orgrepo      OrganizationRepo
+	exchangerepo TokenExchangeRepo
+	auditrepo    AuditRepo
+	loginrepo    LoginHistoryRepo
+	devicerepo   KnownDeviceRepo
+	notifyrepo   NotificationRepo
+	phonerepo    PhoneRepo
+	audit        *Audit
+	smtp         email.Sender
+
+	deletionTTL time.Duration
+}
+
+// Export collects everything stored about the user.
+func (p *Privacy) Export(ctx context.Context, userId string) (*types.UserDataExport, error) {
+	user, err := p.getUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := p.sessionrepo.ListUserSessions(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list sessions: %s", err)
+		return nil, err
+	}
+	identities, err := p.identityrepo.ListUserIdentities(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list identities: %s", err)
+		return nil, err
+	}
+	roles, err := p.rbacrepo.ListUserRoles(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list user roles: %s", err)
+		return nil, err
+	}
+	memberships, err := p.orgrepo.ListUserMemberships(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list memberships: %s", err)
+		return nil, err
+	}
+	exchanges, err := p.exchangerepo.ListUserTokenExchanges(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list token exchanges: %s", err)
+		return nil, err
+	}
+	loginAttempts, err := p.loginrepo.ListLoginAttempts(ctx, userId, math.MaxInt32, time.Time{})
+	if err != nil {
+		logger.Errorf("failed to list login attempts: %s", err)
+		return nil, err
+	}
+	devices, err := p.devicerepo.ListDevices(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list known devices: %s", err)
+		return nil, err
+	}
+	notifications, err := p.notifyrepo.ListPreferences(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list notification preferences: %s", err)
+		return nil, err
+	}
+	timeZone, err := p.notifyrepo.GetTimeZone(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get time zone: %s", err)
+		return nil, err
+	}
+	phone, err := p.phonerepo.GetPhone(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get phone: %s", err)
+		return nil, err
+	}
+	auditEvents, err := p.auditrepo.ListUserEvents(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list audit events: %s", err)
+		return nil, err
+	}
+
+	export := &types.UserDataExport{
+		ExportedAt: time.Now().UTC(),
+		Profile: types.ProfileExport{
+			Id:                    user.UserUUID,
+			Email:                 user.Email,
+			OrganizationId:        user.OrganizationId,
+			CreatedAt:             user.CreatedAt,
+			Status:                user.Status,
+			StatusReason:          user.StatusReason,
+			StatusChangedAt:       user.StatusChangedAt,
+			PasswordResetRequired: user.PasswordResetRequired,
+			TimeZone:              timeZone,
+		},
+		Sessions:       make([]types.SessionExport, 0, len(sessions)),
+		Identities:     make([]types.IdentityExport, 0, len(identities)),
+		Roles:          make([]types.RoleExport, 0, len(roles)),
+		Memberships:    make([]types.MembershipExport, 0, len(memberships)),
+		TokenExchanges: make([]types.TokenExchangeExport, 0, len(exchanges)),
+		LoginHistory:   make([]types.LoginAttemptExport, 0, len(loginAttempts)),
+		Devices:        make([]types.KnownDeviceExport, 0, len(devices)),
+		Notifications:  make([]types.NotificationExport, 0, len(notifications)),
+		AuditEvents:    make([]types.AuditEventExport, 0, len(auditEvents)),
+	}
+	if phone != nil {
+		export.Profile.Phone = phone.Phone
+		export.Profile.PhoneVerifiedAt = phone.VerifiedAt
+		export.Profile.PendingPhone = phone.PendingPhone
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, types.SessionExport{
+			Id:             session.SessionId,
+			IP:             session.IP,
+			Country:        session.Country,
+			City:           session.City,
+			ASN:            session.ASN,
+			ClientId:       session.ClientId,
+			Scope:          session.Scope,
+			OrganizationId: session.OrganizationId,
+			CreatedAt:      session.CreatedAt,
+			ExpiresAt:      session.ExpiresAt,
+			Used:           session.Used,
+			RevokedAt:      session.RevokedAt,
+		})
+	}
+	for _, identity := range identities {
+		export.Identities = append(export.Identities, types.IdentityExport{
+			Provider:    identity.Provider,
+			Subject:     identity.Subject,
+			Email:       identity.Email,
+			CreatedAt:   identity.CreatedAt,
+			LastLoginAt: identity.LastLoginAt,
+		})
+	}
+	for _, role := range roles {
+		export.Roles = append(export.Roles, types.RoleExport{
+			Name:      role.RoleName,
+			Source:    role.Source,
+			CreatedAt: role.CreatedAt,
+		})
+	}
+	for _, membership := range memberships {
+		export.Memberships = append(export.Memberships, types.MembershipExport{
+			OrganizationId:   membership.OrganizationId,
+			OrganizationName: membership.OrganizationName,
+			Roles:            membership.Roles,
+			CreatedAt:        membership.CreatedAt,
+		})
+	}
+	for _, exchange := range exchanges {
+		export.TokenExchanges = append(export.TokenExchanges, types.TokenExchangeExport{
+			ClientId:       exchange.ClientId,
+			Subject:        exchange.Subject,
+			Actor:          exchange.Actor,
+			RequestedScope: exchange.RequestedScope,
+			GrantedScope:   exchange.GrantedScope,
+			IP:             exchange.IP,
+			Success:        exchange.Success,
+			CreatedAt:      exchange.CreatedAt,
+		})
+	}
+
+	for _, attempt := range loginAttempts {
+		export.LoginHistory = append(export.LoginHistory, types.LoginAttemptExport{
+			Method:        attempt.Method,
+			Success:       attempt.Success,
+			FailureReason: attempt.FailureReason,
+			IP:            attempt.IP,
+			UserAgent:     attempt.UserAgent,
+			Device:        attempt.Device,
+			Location:      attempt.Location,
+			CreatedAt:     attempt.CreatedAt,
+		})
+	}
+	for _, device := range devices {
+		export.Devices = append(export.Devices, types.KnownDeviceExport{
+			Name:         device.Name,
+			IP:           device.IP,
+			Location:     device.Location,
+			TrustedUntil: device.TrustedUntil,
+			CreatedAt:    device.CreatedAt,
+			LastSeenAt:   device.LastSeenAt,
+		})
+	}
+	for _, preference := range notifications {
+		export.Notifications = append(export.Notifications, types.NotificationExport{
+			Event:    preference.Event,
+			Channels: preference.Channels,
+			Delivery: preference.Delivery,
+		})
+	}
+	for _, event := range auditEvents {
+		export.AuditEvents = append(export.AuditEvents, types.AuditEventExport{
+			Type:      event.Type,
+			ActorId:   event.ActorId,
+			UserId:    event.UserId,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			Metadata:  event.Metadata,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	return export, nil
+}
+
+// RequestDeletion emails the user a token that confirms erasing the account.
+func (p *Privacy) RequestDeletion(ctx context.Context, userId string) error {
+	user, err := p.getUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	token, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate account deletion token: %s", err)
+		return err
+	}
+
+	deletionToken := types.AccountDeletionToken{
+		TokenHash: oauth.HashCode(token),
+		UserId:    user.UserUUID,
+		ExpiresAt: time.Now().Add(p.deletionTTL),
+	}
+	if err = p.userrepo.CreateDeletionRequest(ctx, deletionToken); err != nil {
+		logger.Errorf("failed to save account deletion request: %s", err)
+		return err
+	}
+
+	send := email.Send{
+		Recipient: user.Email,
+		Subject:   "Удаление учётной записи",
+		Body: fmt.Sprintf(`<h1>Удаление учётной записи</h1>
+<p>Получен запрос на удаление вашей учётной записи и всех связанных с ней данных. Это действие нельзя отменить.</p>
+<p>Чтобы подтвердить удаление, отправьте этот код:</p>
+<p><b>%s</b></p>
+<p>Код действует до %s. Если вы не запрашивали удаление, смените пароль и свяжитесь с нашей службой поддержки.</p>
+<p>С уважением,<br>Команда поддержки</p>`, token, deletionToken.ExpiresAt.Format("02.01.2006 15:04")),
+	}
+	if err = p.smtp.Send(send); err != nil {
+		logger.Errorf("failed to send account deletion email: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmDeletion erases the account with the token from the account deletion email.
+func (p *Privacy) ConfirmDeletion(ctx context.Context, userId string, token string) error {
+	erased, err := p.userrepo.EraseUser(ctx, userId, oauth.HashCode(token))
+	if err != nil {
+		logger.Errorf("failed to erase user: %s", err)
+		return err
+	}
+	if !erased {
+		return ErrInvalidDeletionToken
+	}
+
+	logger.Infof("user %s erased on request", userId)
+	p.audit.Record(ctx, types.AuditAccountErased, userId, map[string]string{"initiator": "user"})
+	return nil
+}
+
+// Erase removes the user and the personal data kept about the user without confirmation.
+func (p *Privacy) Erase(ctx context.Context, userId string) error {
+	if uuid.Validate(userId) != nil {
+		return ErrUserNotFound
+	}
+
+	erased, err := p.userrepo.EraseUser(ctx, userId, "")
+	if err != nil {
+		logger.Errorf("failed to erase user: %s", err)
+		return err
+	}
+	if !erased {
+		return ErrUserNotFound
+	}
+
+	logger.Infof("user %s erased by operator", userId)
+	p.audit.Record(ctx, types.AuditAccountErased, userId, map[string]string{"initiator": "operator"})
+	return nil
+}
+
+func (p *Privacy) getUser(ctx context.Context, userId string) (*types.User, error) {
+	if uuid.Validate(userId) != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := p.userrepo.GetUserByID(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get user: %s", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+const otherUserId = "8b2d3f4e-5c6a-4b7f-8e9d-2c3b4d5e6f7a"
+
+// newTestPrivacy returns a Privacy with data stored about the user of testUserId and about
+// another user.
+func newTestPrivacy() (*Privacy, *fakeUserRepo, *fakeAuditRepo) {
+	users := newFakeUserRepo(
+		types.User{UserUUID: testUserId, Email: "alice@example.com", Status: types.UserStatusActive},
+		types.User{UserUUID: otherUserId, Email: "bob@example.com", Status: types.UserStatusActive},
+	)
+	orgrepo := newFakeOrganizationRepo(types.Organization{Id: testOrgId, Name: "Clinic", Slug: "clinic"})
+	orgrepo.memberships[testOrgId+"/"+testUserId] = types.Membership{OrganizationId: testOrgId, UserId: testUserId}
+	devicerepo := newFakeKnownDeviceRepo()
+	devicerepo.devices["d1"] = types.KnownDevice{Id: "d1", UserId: testUserId, Name: "Chrome on Linux", IP: "203.0.113.1"}
+	devicerepo.devices["d2"] = types.KnownDevice{Id: "d2", UserId: otherUserId, Name: "Safari on iOS"}
+	identityrepo := newFakeIdentityRepo()
+	identityrepo.identities = append(identityrepo.identities, types.ExternalIdentity{Provider: "github", Subject: "42", UserId: testUserId})
+
+	auditrepo := &fakeAuditRepo{}
+	return &Privacy{
+		userrepo: users,
+		sessionrepo: &fakeSessionRepo{sessions: []types.Session{
+			{SessionId: "s1", UserId: testUserId, IP: "203.0.113.1"},
+			{SessionId: "s2", UserId: otherUserId},
+		}},
+		identityrepo: identityrepo,
+		rbacrepo:     &fakeRBACRepo{},
+		orgrepo:      orgrepo,
+		exchangerepo: &fakeTokenExchangeRepo{exchanges: []types.TokenExchange{
+			{ClientId: "reports", Subject: testUserId, Actor: otherUserId, IP: "198.51.100.2", Success: true},
+		}},
+		auditrepo: auditrepo,
+		loginrepo: &fakeLoginHistoryRepo{attempts: []types.LoginAttempt{
+			{UserId: testUserId, Method: "password", Success: true},
+			{UserId: otherUserId, Method: "password"},
+		}},
+		devicerepo: devicerepo,
+		notifyrepo: &fakeNotificationRepo{timeZones: map[string]string{testUserId: "Europe/Moscow"}},
+		phonerepo:  newFakePhoneRepo(types.UserPhone{UserId: testUserId, Phone: "+79990000000"}),
+		audit:      newTestAudit(auditrepo),
+		smtp:       &fakeEmailSender{},
+
+		deletionTTL: time.Hour,
+	}, users, auditrepo
+}
+
+func TestPrivacyExport(t *testing.T) {
+	privacy, _, _ := newTestPrivacy()
+	ctx := types.WithRequestInfo(context.Background(), types.RequestInfo{IP: "203.0.113.1", UserAgent: "curl/8.0"})
+	privacy.audit.Record(ctx, types.AuditEmailChangeRequested, testUserId, map[string]string{"new_email": "alice@example.org"})
+	privacy.audit.Record(ctx, types.AuditUserSignedIn, otherUserId, nil)
+
+	export, err := privacy.Export(context.Background(), testUserId)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if export.Profile.Id != testUserId || export.Profile.Email != "alice@example.com" {
+		t.Errorf("profile = %+v, want the user", export.Profile)
+	}
+	if export.Profile.Phone != "+79990000000" || export.Profile.TimeZone != "Europe/Moscow" {
+		t.Errorf("profile phone %q, time zone %q", export.Profile.Phone, export.Profile.TimeZone)
+	}
+	if len(export.Sessions) != 1 || export.Sessions[0].Id != "s1" || export.Sessions[0].IP != "203.0.113.1" {
+		t.Errorf("sessions = %+v, want s1 only", export.Sessions)
+	}
+	if len(export.Identities) != 1 || len(export.Memberships) != 1 || len(export.TokenExchanges) != 1 {
+		t.Errorf("identities %d, memberships %d, token exchanges %d, want 1 of each", len(export.Identities), len(export.Memberships), len(export.TokenExchanges))
+	}
+	if len(export.LoginHistory) != 1 || len(export.Devices) != 1 || export.Devices[0].Name != "Chrome on Linux" {
+		t.Errorf("login history = %+v, devices = %+v, want the user's only", export.LoginHistory, export.Devices)
+	}
+	if len(export.AuditEvents) != 1 || export.AuditEvents[0].Metadata["new_email"] != "alice@example.org" || export.AuditEvents[0].UserAgent != "curl/8.0" {
+		t.Errorf("audit events = %+v, want the email change with its details", export.AuditEvents)
+	}
+	// Empty lists are exported as [] rather than null.
+	if export.Roles == nil || export.Notifications == nil {
+		t.Errorf("roles = %v, notifications = %v, want empty lists", export.Roles, export.Notifications)
+	}
+}
+
+func TestPrivacyExportUnknownUser(t *testing.T) {
+	privacy, _, _ := newTestPrivacy()
+
+	for _, userId := range []string{"not-a-uuid", "9c3e4f5a-6b7c-4d8e-9f0a-3b4c5d6e7f8a"} {
+		if _, err := privacy.Export(context.Background(), userId); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Export(%q) error = %v, want %v", userId, err, ErrUserNotFound)
+		}
+	}
+}
+
+func TestPrivacyConfirmDeletion(t *testing.T) {
+	privacy, users, auditrepo := newTestPrivacy()
+	ctx := context.Background()
+
+	if err := privacy.RequestDeletion(ctx, testUserId); err != nil {
+		t.Fatalf("RequestDeletion() error = %v", err)
+	}
+	messages := privacy.smtp.(*fakeEmailSender).messages("alice@example.com")
+	if len(messages) != 1 {
+		t.Fatalf("deletion emails = %d, want 1", len(messages))
+	}
+	body := messages[0].Body
+	token := body[strings.Index(body, "<b>")+len("<b>") : strings.Index(body, "</b>")]
+
+	// The token of one user does not erase another.
+	if err := privacy.ConfirmDeletion(ctx, otherUserId, token); !errors.Is(err, ErrInvalidDeletionToken) {
+		t.Errorf("ConfirmDeletion() of another user error = %v, want %v", err, ErrInvalidDeletionToken)
+	}
+	if err := privacy.ConfirmDeletion(ctx, testUserId, "wrong-token"); !errors.Is(err, ErrInvalidDeletionToken) {
+		t.Errorf("ConfirmDeletion() with a wrong token error = %v, want %v", err, ErrInvalidDeletionToken)
+	}
+	if len(auditrepo.events) != 0 || len(users.users) != 2 {
+		t.Fatalf("users = %d, audit events = %d after rejected confirmations", len(users.users), len(auditrepo.events))
+	}
+
+	if err := privacy.ConfirmDeletion(ctx, testUserId, token); err != nil {
+		t.Fatalf("ConfirmDeletion() error = %v", err)
+	}
+	if _, ok := users.users[testUserId]; ok {
+		t.Errorf("user is kept after deletion")
+	}
+	if got := auditrepo.eventTypes(); len(got) != 1 || got[0] != types.AuditAccountErased || auditrepo.events[0].Metadata["initiator"] != "user" {
+		t.Errorf("audit events = %v, want %s by the user", got, types.AuditAccountErased)
+	}
+
+	// The token is used once.
+	if err := privacy.ConfirmDeletion(ctx, testUserId, token); !errors.Is(err, ErrInvalidDeletionToken) {
+		t.Errorf("second ConfirmDeletion() error = %v, want %v", err, ErrInvalidDeletionToken)
+	}
+}
+
+func TestPrivacyErase(t *testing.T) {
+	tests := []struct {
+		name    string
+		userId  string
+		wantErr error
+	}{
+		{name: "existing user", userId: testUserId},
+		{name: "invalid id", userId: "not-a-uuid", wantErr: ErrUserNotFound},
+		{name: "unknown user", userId: "9c3e4f5a-6b7c-4d8e-9f0a-3b4c5d6e7f8a", wantErr: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privacy, users, auditrepo := newTestPrivacy()
+
+			err := privacy.Erase(context.Background(), tt.userId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Erase() error = %v, want %v", err, tt.wantErr)
+			}
+
+			wantUsers, wantEvents := 2, 0
+			if tt.wantErr == nil {
+				wantUsers, wantEvents = 1, 1
+			}
+			if len(users.users) != wantUsers || len(auditrepo.events) != wantEvents {
+				t.Errorf("users = %d, audit events = %d, want %d and %d", len(users.users), len(auditrepo.events), wantUsers, wantEvents)
+			}
+			if tt.wantErr == nil && auditrepo.events[0].Metadata["initiator"] != "operator" {
+				t.Errorf("erasure recorded with metadata %v, want the operator as initiator", auditrepo.events[0].Metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"medods-test/internal/auth/repo/postgres"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"slices"
+)
+
+var (
+	ErrRoleNotFound          = errors.New("role not found")
+	ErrRoleAlreadyExists     = errors.New("role already exists")
+	ErrRoleAssignmentMissing = errors.New("role is not assigned to the user")
+	ErrUnknownPermission     = errors.New("unknown permission")
+	ErrPermissionDenied      = errors.New("permission denied")
+)
+
+type RBACRepo interface {
+	EnsurePermissions(ctx context.Context, permissions []types.Permission) error
+	ListPermissions(ctx context.Context) ([]types.Permission, error)
+	CreateRole(ctx context.Context, role types.Role) error
+	GetRoleByID(ctx context.Context, roleId string) (*types.Role, error)
+	GetRoleByName(ctx context.Context, name string) (*types.Role, error)
+	ListRoles(ctx context.Context) ([]types.Role, error)
+	UpdateRole(ctx context.Context, role types.Role) error
+	GrantRolePermissions(ctx context.Context, roleId string, permissions []string) error
+	DeleteRole(ctx context.Context, roleId string) (bool, error)
+	ListUserRoles(ctx context.Context, userId string) ([]types.UserRole, error)
+	AssignRole(ctx context.Context, userRole types.UserRole) error
+	UnassignRole(ctx context.Context, userRole types.UserRole) (bool, error)
+	ReplaceSourceRoles(ctx context.Context, userId string, source string, roleNames []string) error
+	GetRolePermissions(ctx context.Context, roleNames []string) ([]string, error)
+}
+
+// RBAC manages roles, their permissions and role assignments.
+type RBAC struct {
+	rbacrepo RBACRepo
+	userrepo UserRepo
+}
+
+// Bootstrap registers the known permissions and the admin role, which always has
+// every permission. If adminEmail is set, the user with that email becomes an admin.
+func (r *RBAC) Bootstrap(ctx context.Context, adminEmail string) error {
+	if err := r.rbacrepo.EnsurePermissions(ctx, types.Permissions); err != nil {
+		logger.Errorf("failed to register permissions: %s", err)
+		return err
+	}
+
+	admin, err := r.rbacrepo.GetRoleByName(ctx, types.RoleAdmin)
+	if err != nil {
+		logger.Errorf("failed to get admin role: %s", err)
+		return err
+	}
+	if admin == nil {
+		admin = &types.Role{
+			Id:          uuid.NewString(),
+			Name:        types.RoleAdmin,
+			Description: "Full access to the admin API",
+			Permissions: types.PermissionNames(),
+		}
+		if err = r.rbacrepo.CreateRole(ctx, *admin); err != nil && !errors.Is(err, postgres.ErrUniqueContraintFailed) {
+			logger.Errorf("failed to create admin role: %s", err)
+			return err
+		}
+		logger.Info("admin role created")
+	} else if err = r.rbacrepo.GrantRolePermissions(ctx, admin.Id, types.PermissionNames()); err != nil {
+		logger.Errorf("failed to grant permissions to admin role: %s", err)
+		return err
+	}
+
+	if adminEmail == "" {
+		return nil
+	}
+
+	user, err := r.userrepo.GetUserByEmail(ctx, adminEmail)
+	if err != nil {
+		logger.Errorf("failed to get user by email: %s", err)
+		return err
+	}
+	if user == nil {
+		logger.Warnf("bootstrap admin %s is not registered yet", adminEmail)
+		return nil
+	}
+
+	return r.Assign(ctx, user.UserUUID, admin.Id)
+}
+
+func (r *RBAC) Permissions(ctx context.Context) ([]types.Permission, error) {
+	permissions, err := r.rbacrepo.ListPermissions(ctx)
+	if err != nil {
+		logger.Errorf("failed to list permissions: %s", err)
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *RBAC) CreateRole(ctx context.Context, input types.RoleDTO) (*types.Role, error) {
+	if err := validatePermissions(input.Permissions); err != nil {
+		return nil, err
+	}
+
+	role := types.Role{
+		Id:          uuid.NewString(),
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: nonNil(input.Permissions),
+	}
+	if err := r.rbacrepo.CreateRole(ctx, role); err != nil {
+		logger.Errorf("failed to create role: %s", err)
+		if errors.Is(err, postgres.ErrUniqueContraintFailed) {
+			return nil, ErrRoleAlreadyExists
+		}
+		return nil, err
+	}
+
+	return r.GetRole(ctx, role.Id)
+}
+
+func (r *RBAC) GetRole(ctx context.Context, roleId string) (*types.Role, error) {
+	if uuid.Validate(roleId) != nil {
+		return nil, ErrRoleNotFound
+	}
+
+	role, err := r.rbacrepo.GetRoleByID(ctx, roleId)
+	if err != nil {
+		logger.Errorf("failed to get role: %s", err)
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+func (r *RBAC) ListRoles(ctx context.Context) ([]types.Role, error) {
+	roles, err := r.rbacrepo.ListRoles(ctx)
+	if err != nil {
+		logger.Errorf("failed to list roles: %s", err)
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *RBAC) UpdateRole(ctx context.Context, input types.RoleDTO) (*types.Role, error) {
+	role, err := r.GetRole(ctx, input.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err = validatePermissions(input.Permissions); err != nil {
+		return nil, err
+	}
+
+	role.Name = input.Name
+	role.Description = input.Description
+	role.Permissions = nonNil(input.Permissions)
+
+	if err = r.rbacrepo.UpdateRole(ctx, *role); err != nil {
+		logger.Errorf("failed to update role: %s", err)
+		if errors.Is(err, postgres.ErrUniqueContraintFailed) {
+			return nil, ErrRoleAlreadyExists
+		}
+		return nil, err
+	}
+
+	return r.GetRole(ctx, role.Id)
+}
+
+func (r *RBAC) DeleteRole(ctx context.Context, roleId string) error {
+	if uuid.Validate(roleId) != nil {
+		return ErrRoleNotFound
+	}
+
+	deleted, err := r.rbacrepo.DeleteRole(ctx, roleId)
+	if err != nil {
+		logger.Errorf("failed to delete role: %s", err)
+		return err
+	}
+	if !deleted {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+func (r *RBAC) UserRoles(ctx context.Context, userId string) ([]types.UserRole, error) {
+	if uuid.Validate(userId) != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := r.userrepo.GetUserByID(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get user: %s", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	roles, err := r.rbacrepo.ListUserRoles(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list user roles: %s", err)
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Assign gives the role to the user. Assigning a role twice is not an error.
+func (r *RBAC) Assign(ctx context.Context, userId string, roleId string) error {
+	if _, err := r.UserRoles(ctx, userId); err != nil {
+		return err
+	}
+	if _, err := r.GetRole(ctx, roleId); err != nil {
+		return err
+	}
+
+	if err := r.rbacrepo.AssignRole(ctx, types.UserRole{
+		UserId: userId,
+		RoleId: roleId,
+		Source: types.RoleSourceLocal,
+	}); err != nil {
+		logger.Errorf("failed to assign role: %s", err)
+		return err
+	}
+	return nil
+}
+
+// Unassign takes away a role assigned through the admin API. Roles granted by a
+// directory are managed by the directory.
+func (r *RBAC) Unassign(ctx context.Context, userId string, roleId string) error {
+	if uuid.Validate(userId) != nil || uuid.Validate(roleId) != nil {
+		return ErrRoleAssignmentMissing
+	}
+
+	deleted, err := r.rbacrepo.UnassignRole(ctx, types.UserRole{
+		UserId: userId,
+		RoleId: roleId,
+		Source: types.RoleSourceLocal,
+	})
+	if err != nil {
+		logger.Errorf("failed to unassign role: %s", err)
+		return err
+	}
+	if !deleted {
+		return ErrRoleAssignmentMissing
+	}
+	return nil
+}
+
+// RoleNames returns the names of all roles of the user, whatever granted them.
+func (r *RBAC) RoleNames(ctx context.Context, userId string) ([]string, error) {
+	userRoles, err := r.rbacrepo.ListUserRoles(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list user roles: %s", err)
+		return nil, err
+	}
+
+	names := make([]string, 0, len(userRoles))
+	for _, userRole := range userRoles {
+		if !slices.Contains(names, userRole.RoleName) {
+			names = append(names, userRole.RoleName)
+		}
+	}
+	return names, nil
+}
+
+func (r *RBAC) SyncDirectoryRoles(ctx context.Context, userId string, directory string, roles []string) error {
+	if err := r.rbacrepo.ReplaceSourceRoles(ctx, userId, directory, nonNil(roles)); err != nil {
+		logger.Errorf("failed to replace directory roles: %s", err)
+		return err
+	}
+	return nil
+}
+
+// CheckPermissions returns ErrPermissionDenied unless the roles together have every one of permissions.
+func (r *RBAC) CheckPermissions(ctx context.Context, roles []string, permissions ...string) error {
+	if len(roles) == 0 {
+		return ErrPermissionDenied
+	}
+
+	granted, err := r.rbacrepo.GetRolePermissions(ctx, roles)
+	if err != nil {
+		logger.Errorf("failed to get role permissions: %s", err)
+		return err
+	}
+
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			return ErrPermissionDenied
+		}
+	}
+	return nil
+}
+
+func validatePermissions(permissions []string) error {
+	known := types.PermissionNames()
+	for _, permission := range permissions {
+		if !slices.Contains(known, permission) {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, permission)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"slices"
+	"testing"
+)
+
+func newTestRBAC() (*RBAC, *fakeRBACRepo) {
+	rbacrepo := &fakeRBACRepo{}
+	return &RBAC{
+		rbacrepo: rbacrepo,
+		userrepo: newFakeUserRepo(
+			types.User{UserUUID: testUserId, Email: "alice@example.com", Status: types.UserStatusActive},
+			types.User{UserUUID: otherUserId, Email: "bob@example.com", Status: types.UserStatusActive},
+		),
+	}, rbacrepo
+}
+
+func TestRBACBootstrap(t *testing.T) {
+	rbac, rbacrepo := newTestRBAC()
+	ctx := context.Background()
+
+	if err := rbac.Bootstrap(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	if len(rbacrepo.permissions) != len(types.Permissions) {
+		t.Errorf("registered permissions = %d, want %d", len(rbacrepo.permissions), len(types.Permissions))
+	}
+	roles, err := rbac.RoleNames(ctx, testUserId)
+	if err != nil || !slices.Equal(roles, []string{types.RoleAdmin}) {
+		t.Fatalf("RoleNames() = %v, %v, want the admin role", roles, err)
+	}
+
+	// A permission added since the admin role was created is granted on the next start.
+	rbacrepo.roles[0].Permissions = rbacrepo.roles[0].Permissions[1:]
+	if err = rbac.Bootstrap(ctx, "unknown@example.com"); err != nil {
+		t.Fatalf("second Bootstrap() error = %v", err)
+	}
+	if len(rbacrepo.roles) != 1 || len(rbacrepo.roles[0].Permissions) != len(types.Permissions) {
+		t.Errorf("roles after the second bootstrap = %+v, want the admin role with every permission", rbacrepo.roles)
+	}
+	if err = rbac.CheckPermissions(ctx, roles, types.PermissionNames()...); err != nil {
+		t.Errorf("CheckPermissions() of the admin error = %v", err)
+	}
+}
+
+func TestRBACRoles(t *testing.T) {
+	rbac, _ := newTestRBAC()
+	ctx := context.Background()
+
+	role, err := rbac.CreateRole(ctx, types.RoleDTO{Name: "support", Permissions: []string{types.PermissionUsersRead}})
+	if err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+	if _, err = rbac.CreateRole(ctx, types.RoleDTO{Name: "support"}); !errors.Is(err, ErrRoleAlreadyExists) {
+		t.Errorf("CreateRole() of a taken name error = %v, want %v", err, ErrRoleAlreadyExists)
+	}
+	if _, err = rbac.CreateRole(ctx, types.RoleDTO{Name: "root", Permissions: []string{"everything"}}); !errors.Is(err, ErrUnknownPermission) {
+		t.Errorf("CreateRole() with an unknown permission error = %v, want %v", err, ErrUnknownPermission)
+	}
+
+	updated, err := rbac.UpdateRole(ctx, types.RoleDTO{Id: role.Id, Name: "support", Permissions: []string{types.PermissionUsersRead, types.PermissionUsersWrite}})
+	if err != nil {
+		t.Fatalf("UpdateRole() error = %v", err)
+	}
+	if len(updated.Permissions) != 2 {
+		t.Errorf("updated permissions = %v, want 2", updated.Permissions)
+	}
+	if _, err = rbac.UpdateRole(ctx, types.RoleDTO{Id: role.Id, Name: "support", Permissions: []string{"everything"}}); !errors.Is(err, ErrUnknownPermission) {
+		t.Errorf("UpdateRole() with an unknown permission error = %v, want %v", err, ErrUnknownPermission)
+	}
+
+	if err = rbac.DeleteRole(ctx, role.Id); err != nil {
+		t.Fatalf("DeleteRole() error = %v", err)
+	}
+	for _, roleId := range []string{role.Id, "not-a-uuid"} {
+		if _, err = rbac.GetRole(ctx, roleId); !errors.Is(err, ErrRoleNotFound) {
+			t.Errorf("GetRole(%q) error = %v, want %v", roleId, err, ErrRoleNotFound)
+		}
+		if err = rbac.DeleteRole(ctx, roleId); !errors.Is(err, ErrRoleNotFound) {
+			t.Errorf("DeleteRole(%q) error = %v, want %v", roleId, err, ErrRoleNotFound)
+		}
+	}
+}
+
+func TestRBACAssign(t *testing.T) {
+	rbac, rbacrepo := newTestRBAC()
+	ctx := context.Background()
+
+	role, err := rbac.CreateRole(ctx, types.RoleDTO{Name: "support", Permissions: []string{types.PermissionUsersRead}})
+	if err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+
+	if err = rbac.Assign(ctx, "9c3e4f5a-6b7c-4d8e-9f0a-3b4c5d6e7f8a", role.Id); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Assign() to an unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+	if err = rbac.Assign(ctx, testUserId, "0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9"); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("Assign() of an unknown role error = %v, want %v", err, ErrRoleNotFound)
+	}
+
+	// Assigning twice is not an error and does not duplicate the assignment.
+	for range 2 {
+		if err = rbac.Assign(ctx, testUserId, role.Id); err != nil {
+			t.Fatalf("Assign() error = %v", err)
+		}
+	}
+	// The same role granted by a directory is reported once.
+	if err = rbac.SyncDirectoryRoles(ctx, testUserId, "ldap", []string{"support", "auditor"}); err != nil {
+		t.Fatalf("SyncDirectoryRoles() error = %v", err)
+	}
+	names, err := rbac.RoleNames(ctx, testUserId)
+	if err != nil || !slices.Equal(names, []string{"support", "auditor"}) {
+		t.Errorf("RoleNames() = %v, %v, want support and auditor", names, err)
+	}
+	if other, _ := rbac.RoleNames(ctx, otherUserId); len(other) != 0 {
+		t.Errorf("RoleNames() of another user = %v, want none", other)
+	}
+
+	if err = rbac.Unassign(ctx, testUserId, role.Id); err != nil {
+		t.Fatalf("Unassign() error = %v", err)
+	}
+	if err = rbac.Unassign(ctx, testUserId, role.Id); !errors.Is(err, ErrRoleAssignmentMissing) {
+		t.Errorf("second Unassign() error = %v, want %v", err, ErrRoleAssignmentMissing)
+	}
+	// Unassign takes away only the local assignment, the directory keeps granting the role.
+	if names, _ = rbac.RoleNames(ctx, testUserId); !slices.Equal(names, []string{"support", "auditor"}) {
+		t.Errorf("RoleNames() after Unassign() = %v, want the directory roles", names)
+	}
+	if len(rbacrepo.userRoles) != 0 {
+		t.Errorf("local assignments = %+v, want none", rbacrepo.userRoles)
+	}
+}
+
+func TestRBACCheckPermissions(t *testing.T) {
+	rbac, rbacrepo := newTestRBAC()
+	rbacrepo.roles = []types.Role{
+		{Id: "r1", Name: "support", Permissions: []string{types.PermissionUsersRead}},
+		{Id: "r2", Name: "auditor", Permissions: []string{types.PermissionAuditRead}},
+	}
+
+	tests := []struct {
+		name        string
+		roles       []string
+		permissions []string
+		wantErr     error
+	}{
+		{name: "granted permission", roles: []string{"support"}, permissions: []string{types.PermissionUsersRead}},
+		{name: "permissions of several roles", roles: []string{"support", "auditor"}, permissions: []string{types.PermissionUsersRead, types.PermissionAuditRead}},
+		{name: "one permission missing", roles: []string{"support"}, permissions: []string{types.PermissionUsersRead, types.PermissionUsersWrite}, wantErr: ErrPermissionDenied},
+		{name: "unknown role", roles: []string{"root"}, permissions: []string{types.PermissionUsersRead}, wantErr: ErrPermissionDenied},
+		{name: "no roles", permissions: []string{types.PermissionUsersRead}, wantErr: ErrPermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := rbac.CheckPermissions(context.Background(), tt.roles, tt.permissions...); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckPermissions() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/geoip"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/risk"
+	"medods-test/pkg/useragent"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// riskHistorySize is how many recent login attempts of the user are compared with a new sign-in.
+const riskHistorySize = 100
+
+// travelSlackKm allows for the inaccuracy of IP geolocation in the impossible travel check.
+const travelSlackKm = 100
+
+var (
+	ErrSignInBlocked  = errors.New("sign-in is blocked as suspicious")
+	ErrRefreshBlocked = errors.New("token refresh is blocked as suspicious, sign in again")
+)
+
+// IPList is a set of IP addresses, such as Tor exit nodes.
+type IPList interface {
+	Contains(ip string) bool
+}
+
+type RiskConfig struct {
+	Policy *risk.Policy
+	// TorExits and BadIPs are optional.
+	TorExits IPList
+	BadIPs   IPList
+	// FailedAttempts failed sign-ins within FailedAttemptsWindow raise the failed_attempts signal.
+	FailedAttempts       int
+	FailedAttemptsWindow time.Duration
+	// MaxTravelSpeed in km/h between two sign-ins raises the impossible_travel signal.
+	MaxTravelSpeed float64
+}
+
+// RiskEngine scores sign-ins and token refreshes by signals of account takeover.
+type RiskEngine struct {
+	loginrepo LoginHistoryRepo
+	locator   GeoLocator
+	audit     *Audit
+	config    RiskConfig
+}
+
+// AssessSignIn scores a sign-in of the user from the request of ctx against the previous
+// login attempts of the user. device is the known device of the request, nil for a new one;
+// requests without a device id are matched to previous attempts by the user agent.
+func (r *RiskEngine) AssessSignIn(ctx context.Context, userId string, device *types.KnownDevice) risk.Assessment {
+	info := types.RequestInfoFrom(ctx)
+	location, _ := r.locator.Locate(info.IP)
+	signals := r.ipSignals(info.IP)
+
+	history, err := r.loginrepo.ListLoginAttempts(ctx, userId, riskHistorySize, time.Time{})
+	if err != nil {
+		logger.Errorf("failed to list login attempts: %s", err)
+	}
+
+	var (
+		failed      int
+		known       []types.LoginAttempt
+		failedAt    = time.Now().Add(-r.config.FailedAttemptsWindow)
+		userAgent   = useragent.Describe(info.UserAgent)
+		countries   = make(map[string]bool)
+		networks    = make(map[int64]bool)
+		knownDevice bool
+	)
+	for _, attempt := range history {
+		if !attempt.Success {
+			if attempt.FailureReason == loginFailureInvalidCredentials && attempt.CreatedAt.After(failedAt) {
+				failed++
+			}
+			continue
+		}
+		known = append(known, attempt)
+		knownDevice = knownDevice || attempt.Device == userAgent
+		if attempt.Country != "" {
+			countries[attempt.Country] = true
+		}
+		if attempt.ASN != 0 {
+			networks[attempt.ASN] = true
+		}
+	}
+
+	if info.DeviceId != "" {
+		knownDevice = device != nil
+	}
+
+	if r.config.FailedAttempts > 0 && failed >= r.config.FailedAttempts {
+		signals = append(signals, risk.SignalFailedAttempts)
+	}
+	if len(known) > 0 {
+		if !knownDevice {
+			signals = append(signals, risk.SignalNewDevice)
+		}
+		if location.CountryCode != "" && len(countries) > 0 && !countries[location.CountryCode] {
+			signals = append(signals, risk.SignalNewCountry)
+		}
+		if location.ASN != 0 && len(networks) > 0 && !networks[int64(location.ASN)] {
+			signals = append(signals, risk.SignalASNChange)
+		}
+		for _, attempt := range known {
+			previous := risk.Point{Latitude: attempt.Latitude, Longitude: attempt.Longitude}
+			if previous.IsZero() {
+				continue
+			}
+			if r.impossibleTravel(previous, location, time.Since(attempt.CreatedAt)) {
+				signals = append(signals, risk.SignalImpossibleTravel)
+			}
+			break
+		}
+	}
+
+	return r.assess(ctx, userId, "sign_in", signals)
+}
+
+// AssessRefresh scores a refresh of session from newIP against the IP the session was issued to.
+func (r *RiskEngine) AssessRefresh(ctx context.Context, session *types.Session, newIP string) risk.Assessment {
+	signals := r.ipSignals(newIP)
+
+	if newIP != session.IP {
+		location, _ := r.locator.Locate(newIP)
+		previous, _ := r.locator.Locate(session.IP)
+
+		if location.CountryCode != "" && session.Country != "" && location.CountryCode != session.Country {
+			signals = append(signals, risk.SignalNewCountry)
+		}
+		if location.ASN != 0 && session.ASN != 0 && int64(location.ASN) != session.ASN {
+			signals = append(signals, risk.SignalASNChange)
+		}
+		point := risk.Point{Latitude: previous.Latitude, Longitude: previous.Longitude}
+		if r.impossibleTravel(point, location, time.Since(session.CreatedAt)) {
+			signals = append(signals, risk.SignalImpossibleTravel)
+		}
+	}
+
+	return r.assess(ctx, session.UserId, "refresh", signals)
+}
+
+func (r *RiskEngine) ipSignals(ip string) []string {
+	var signals []string
+	if r.config.TorExits != nil && r.config.TorExits.Contains(ip) {
+		signals = append(signals, risk.SignalTor)
+	}
+	if r.config.BadIPs != nil && r.config.BadIPs.Contains(ip) {
+		signals = append(signals, risk.SignalBadIP)
+	}
+	return signals
+}
+
+func (r *RiskEngine) impossibleTravel(previous risk.Point, location geoip.Location, elapsed time.Duration) bool {
+	current := risk.Point{Latitude: location.Latitude, Longitude: location.Longitude}
+	return risk.ImpossibleTravel(previous, current, elapsed, r.config.MaxTravelSpeed, travelSlackKm)
+}
+
+// assess scores signals and records the assessment in the audit log if anything was detected.
+func (r *RiskEngine) assess(ctx context.Context, userId string, event string, signals []string) risk.Assessment {
+	assessment := r.config.Policy.Assess(signals)
+	if len(signals) > 0 {
+		r.audit.Record(ctx, types.AuditRiskAssessed, userId, riskMetadata(event, assessment))
+	}
+	return assessment
+}
+
+func riskMetadata(event string, assessment risk.Assessment) map[string]string {
+	return map[string]string{
+		"event":   event,
+		"score":   strconv.Itoa(assessment.Score),
+		"signals": strings.Join(assessment.Signals, ","),
+		"action":  assessment.Action,
+	}
+}
+
+// riskSignalText describes signals in notification emails.
+var riskSignalText = map[string]string{
+	risk.SignalNewDevice:        "вход с нового устройства",
+	risk.SignalNewCountry:       "вход из новой страны",
+	risk.SignalImpossibleTravel: "слишком быстрое перемещение между местами входа",
+	risk.SignalASNChange:        "вход из сети другого провайдера",
+	risk.SignalFailedAttempts:   "много неудачных попыток входа",
+	risk.SignalTor:              "вход через сеть Tor",
+	risk.SignalBadIP:            "IP-адрес из списка подозрительных",
+}
+
+// checkSignInRisk applies the action for the risk of a sign-in of user and remembers the device
+// of an allowed sign-in. Trusted devices skip the second factor. A sign-in that needs a second
+// factor fails with *signInMFAError.
+func (u *User) checkSignInRisk(ctx context.Context, user *types.User) error {
+	device := u.requestDevice(ctx, user.UserUUID)
+	assessment := u.risk.AssessSignIn(ctx, user.UserUUID, device)
+
+	switch assessment.Action {
+	case risk.ActionBlock:
+		return ErrSignInBlocked
+	case risk.ActionMFA:
+		if device == nil || !device.IsTrusted() {
+			return &signInMFAError{user: user}
+		}
+	}
+
+	u.rememberDevice(ctx, user, device, assessment, false)
+	return nil
+}
+
+// checkRefreshRisk applies the action for the risk of a refresh of session. Refreshes that
+// need a second factor are blocked too: the session is revoked and the user has to sign in again.
+func (u *User) checkRefreshRisk(ctx context.Context, session *types.Session, assessment risk.Assessment) error {
+	if assessment.Action != risk.ActionBlock && assessment.Action != risk.ActionMFA {
+		return nil
+	}
+
+	if _, err := u.sessionrepo.RevokeSession(ctx, session.UserId, session.SessionId); err != nil {
+		logger.Errorf("failed to revoke session: %s", err)
+		return err
+	}
+	return ErrRefreshBlocked
+}
+
+func (u *User) notifyIPChanged(ctx context.Context, userId string, oldIP string, newIP string) {
+	user, err := u.userrepo.GetUserByID(ctx, userId)
+	if err != nil || user == nil {
+		logger.Errorf("failed to get user by id: %v", err)
+		return
+	}
+
+	u.notifications.Notify(ctx, user, types.Notice{
+		Event:   types.NotificationIPChanged,
+		Subject: "Смена IP-адреса",
+		Body: fmt.Sprintf(`<p>Мы заметили, что ваш IP-адрес изменился.</p>
+<p>Новый адрес: %s<br>Прежний адрес: %s</p>
+<p>Если вы не осуществляли вход с этого IP, пожалуйста, свяжитесь с нашей службой поддержки или смените пароль</p>`,
+			u.describeIP(newIP), u.describeIP(oldIP)),
+		Text: fmt.Sprintf("Вход с нового IP-адреса %s. Если это были не вы, смените пароль.", newIP),
+	})
+}
+
+func describeSignals(signals []string) string {
+	texts := make([]string, 0, len(signals))
+	for _, signal := range signals {
+		texts = append(texts, riskSignalText[signal])
+	}
+	return strings.Join(texts, ", ")
+}
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"medods-test/internal/auth/types"
+	"medods-test/internal/config"
 	"medods-test/pkg/auth"
 	"medods-test/pkg/email"
 	"medods-test/pkg/hash"
+	"medods-test/pkg/sms"
+	"strings"
 	"time"
 )
 
@@ -12,28 +16,245 @@ const (
 )
 
 type Repository struct {
-	UserRepo    UserRepo
-	SessionRepo SessionRepo
+	UserRepo         UserRepo
+	SessionRepo      SessionRepo
+	OAuthRepo        OAuthRepo
+	ClientRepo       ClientRepo
+	DeviceRepo       DeviceRepo
+	ExchangeRepo     TokenExchangeRepo
+	IdentityRepo     IdentityRepo
+	RBACRepo         RBACRepo
+	OrgRepo          OrganizationRepo
+	AuditRepo        AuditRepo
+	LoginRepo        LoginHistoryRepo
+	MFARepo          MFARepo
+	KnownDeviceRepo  KnownDeviceRepo
+	NotificationRepo NotificationRepo
+	WebhookRepo      WebhookRepo
+	SignalRepo       SignalRepo
+	PhoneRepo        PhoneRepo
 }
 
 type Service struct {
 	repository *Repository
+	locator    GeoLocator
 }
 
-func New(repository *Repository) *Service {
+// New creates the service factory. locator may be nil if no GeoIP database is configured.
+func New(repository *Repository, locator GeoLocator) *Service {
+	if locator == nil {
+		locator = noGeoLocator{}
+	}
+
 	return &Service{
 		repository: repository,
+		locator:    locator,
 	}
 }
 
-func (s *Service) User(manager auth.TokenManager, smtp email.Sender, accessTokenTTL, refreshTokenTTL time.Duration) *User {
+func (s *Service) Risk(config RiskConfig) *RiskEngine {
+	return &RiskEngine{
+		loginrepo: s.repository.LoginRepo,
+		locator:   s.locator,
+		audit:     s.Audit(),
+		config:    config,
+	}
+}
+
+// User creates the user service. smsSender may be nil if SMS is not configured.
+func (s *Service) User(manager auth.TokenManager, smtp email.Sender, smsSender sms.Sender, verifiers []CredentialVerifier, policy PasswordPolicy, risk *RiskEngine, emailUniqueness string, accessTokenTTL, refreshTokenTTL, emailChangeTTL, deviceCookieTTL, deviceTrustTTL time.Duration, passwordHistorySize int, passwordHistoryRetention, loginHistoryRetention time.Duration) *User {
+	byDomain := make(map[string]CredentialVerifier)
+	for _, verifier := range verifiers {
+		for _, domain := range verifier.Domains() {
+			byDomain[strings.ToLower(domain)] = verifier
+		}
+	}
+
 	return &User{
 		userrepo:        s.repository.UserRepo,
 		sessionrepo:     s.repository.SessionRepo,
+		orgrepo:         s.repository.OrgRepo,
+		loginrepo:       s.repository.LoginRepo,
+		identityrepo:    s.repository.IdentityRepo,
+		mfarepo:         s.repository.MFARepo,
+		devicerepo:      s.repository.KnownDeviceRepo,
+		phonerepo:       s.repository.PhoneRepo,
+		channels:        s.channels(smtp, smsSender),
 		hasher:          hash.NewSHA1Hasher(salt),
+		policy:          policy,
 		tokenManager:    manager,
 		smtp:            smtp,
+		verifiers:       byDomain,
+		roles:           s.RBAC(),
+		audit:           s.Audit(),
+		notifications:   s.Notifications(smtp, smsSender),
+		locator:         s.locator,
+		risk:            risk,
+		emailUniqueness: emailUniqueness,
 		accessTokenTTL:  accessTokenTTL,
 		refreshTokenTTL: refreshTokenTTL,
+		emailChangeTTL:  emailChangeTTL,
+		deviceCookieTTL: deviceCookieTTL,
+		deviceTrustTTL:  deviceTrustTTL,
+
+		passwordHistorySize:      passwordHistorySize,
+		passwordHistoryRetention: passwordHistoryRetention,
+		loginHistoryRetention:    loginHistoryRetention,
+	}
+}
+
+func (s *Service) UserAdmin(smtp email.Sender, passwordResetTTL, purgeDelay, passwordHistoryRetention, loginHistoryRetention, deviceRetention time.Duration) *UserAdmin {
+	return &UserAdmin{
+		userrepo:         s.repository.UserRepo,
+		sessionrepo:      s.repository.SessionRepo,
+		loginrepo:        s.repository.LoginRepo,
+		mfarepo:          s.repository.MFARepo,
+		devicerepo:       s.repository.KnownDeviceRepo,
+		phonerepo:        s.repository.PhoneRepo,
+		audit:            s.Audit(),
+		smtp:             smtp,
+		passwordResetTTL: passwordResetTTL,
+		purgeDelay:       purgeDelay,
+
+		passwordHistoryRetention: passwordHistoryRetention,
+		loginHistoryRetention:    loginHistoryRetention,
+		deviceRetention:          deviceRetention,
+	}
+}
+
+func (s *Service) Privacy(smtp email.Sender, deletionTTL time.Duration) *Privacy {
+	return &Privacy{
+		userrepo:     s.repository.UserRepo,
+		sessionrepo:  s.repository.SessionRepo,
+		identityrepo: s.repository.IdentityRepo,
+		rbacrepo:     s.repository.RBACRepo,
+		orgrepo:      s.repository.OrgRepo,
+		exchangerepo: s.repository.ExchangeRepo,
+		auditrepo:    s.repository.AuditRepo,
+		loginrepo:    s.repository.LoginRepo,
+		devicerepo:   s.repository.KnownDeviceRepo,
+		notifyrepo:   s.repository.NotificationRepo,
+		phonerepo:    s.repository.PhoneRepo,
+		audit:        s.Audit(),
+		smtp:         smtp,
+		deletionTTL:  deletionTTL,
+	}
+}
+
+// Notifications creates the notification service. smsSender may be nil if SMS is not configured.
+func (s *Service) Notifications(smtp email.Sender, smsSender sms.Sender) *Notifications {
+	return &Notifications{
+		notificationrepo: s.repository.NotificationRepo,
+		userrepo:         s.repository.UserRepo,
+		audit:            s.Audit(),
+		channels:         s.channels(smtp, smsSender),
+	}
+}
+
+// ActivitySummaries creates the weekly activity summary scheduler, summaries are sent by email.
+func (s *Service) ActivitySummaries(smtp email.Sender, config ActivitySummaryConfig) *ActivitySummaries {
+	return &ActivitySummaries{
+		notifications:    s.Notifications(smtp, nil),
+		notificationrepo: s.repository.NotificationRepo,
+		userrepo:         s.repository.UserRepo,
+		sessionrepo:      s.repository.SessionRepo,
+		auditrepo:        s.repository.AuditRepo,
+		config:           config,
+	}
+}
+
+// channels returns the channels messages can be sent through by name. SMS is left out if
+// smsSender is nil.
+func (s *Service) channels(smtp email.Sender, smsSender sms.Sender) map[string]Channel {
+	channels := map[string]Channel{
+		types.NotificationChannelEmail: emailChannel{smtp: smtp},
+	}
+	if smsSender != nil {
+		channels[types.NotificationChannelSMS] = smsChannel{
+			sender:    smsSender,
+			phonerepo: s.repository.PhoneRepo,
+		}
+	}
+	return channels
+}
+
+func (s *Service) Audit() *Audit {
+	return &Audit{
+		auditrepo:   s.repository.AuditRepo,
+		webhookrepo: s.repository.WebhookRepo,
+		signalrepo:  s.repository.SignalRepo,
+		locator:     s.locator,
+	}
+}
+
+func (s *Service) Webhooks(sender WebhookSender, config WebhookConfig) *Webhooks {
+	return &Webhooks{
+		webhookrepo: s.repository.WebhookRepo,
+		sender:      sender,
+		config:      config,
+	}
+}
+
+func (s *Service) Signals(manager auth.TokenManager, pusher SecurityEventPusher, config SignalConfig) *Signals {
+	return &Signals{
+		signalrepo:   s.repository.SignalRepo,
+		tokenManager: manager,
+		pusher:       pusher,
+		config:       config,
+	}
+}
+
+func (s *Service) RBAC() *RBAC {
+	return &RBAC{
+		rbacrepo: s.repository.RBACRepo,
+		userrepo: s.repository.UserRepo,
+	}
+}
+
+func (s *Service) Organization(smtp email.Sender, invitationTTL time.Duration) *Organization {
+	return &Organization{
+		orgrepo:       s.repository.OrgRepo,
+		userrepo:      s.repository.UserRepo,
+		rbac:          s.RBAC(),
+		smtp:          smtp,
+		invitationTTL: invitationTTL,
+	}
+}
+
+func (s *Service) OAuth(user *User, cfg config.OAuthConfig) *OAuth {
+	return &OAuth{
+		oauthrepo:            s.repository.OAuthRepo,
+		devicerepo:           s.repository.DeviceRepo,
+		exchangerepo:         s.repository.ExchangeRepo,
+		user:                 user,
+		issuer:               cfg.Issuer,
+		authorizationCodeTTL: cfg.AuthorizationCodeTTL,
+		idTokenTTL:           cfg.IDTokenTTL,
+		clientTokenTTL:       cfg.ClientTokenTTL,
+		deviceCodeTTL:        cfg.DeviceCodeTTL,
+		devicePollInterval:   cfg.DevicePollInterval,
+		exchangeTokenTTL:     cfg.ExchangeTokenTTL,
+	}
+}
+
+func (s *Service) Client(manager auth.TokenManager, secretRotationGrace time.Duration) *Client {
+	return &Client{
+		clientrepo:          s.repository.ClientRepo,
+		tokenManager:        manager,
+		secretRotationGrace: secretRotationGrace,
+	}
+}
+
+func (s *Service) Social(user *User, providers []SocialProvider, stateTTL time.Duration) *Social {
+	byName := make(map[string]SocialProvider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &Social{
+		identityrepo: s.repository.IdentityRepo,
+		user:         user,
+		providers:    byName,
+		stateTTL:     stateTTL,
 	}
 }
@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"github.com/google/uuid"
 	"medods-test/internal/auth/types"
+	"strings"
 )
 
 type SessionRepo interface {
@@ -10,4 +13,37 @@ type SessionRepo interface {
 	GetSessionById(ctx context.Context, sessionId string) (*types.Session, error)
 	SetUsed(ctx context.Context, sessionId string) error
 	CreateAndSetUsed(ctx context.Context, session types.Session, usedSessionId string) error
+	ListActiveSessions(ctx context.Context, userId string) ([]types.Session, error)
+	ListUserSessions(ctx context.Context, userId string) ([]types.Session, error)
+	RevokeSession(ctx context.Context, userId string, sessionId string) (bool, error)
+	RevokeSessionFamily(ctx context.Context, sessionId string) (int64, error)
+	RevokeUserSessions(ctx context.Context, userId string) (int64, error)
+}
+
+// newRefreshToken prefixes secret with the compact session id, so that the session can be
+// found by the refresh token alone. The result fits into the bcrypt 72 bytes limit.
+func newRefreshToken(sessionId string, secret string) (string, error) {
+	id, err := uuid.Parse(sessionId)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(id[:]) + "." + secret, nil
+}
+
+func sessionIdFromRefreshToken(refreshToken string) (string, bool) {
+	prefix, _, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return "", false
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(prefix)
+	if err != nil {
+		return "", false
+	}
+
+	id, err := uuid.FromBytes(b)
+	if err != nil {
+		return "", false
+	}
+	return id.String(), true
 }
@@ -0,0 +1,614 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"medods-test/internal/auth/repo/postgres"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/auth"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/ssf"
+	"medods-test/pkg/webhook"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPollEvents    = 100
+	maxPollEvents        = 1000
+	signalBatchSize      = 20
+	signalPurgeInterval  = time.Hour
+	signalPollPath       = "/ssf/poll"
+	signalStreamPath     = "/ssf/stream"
+	signalStatusPath     = "/ssf/status"
+	signalVerifyPath     = "/ssf/verify"
+	signalJwksPath       = "/.well-known/jwks.json"
+	signalAuthSchemeURN  = "urn:ietf:rfc:6749"
+	signalDefaultSubject = "ALL"
+)
+
+var (
+	ErrSignalStreamNotFound  = errors.New("stream not found")
+	ErrSignalStreamExists    = errors.New("client already has a stream")
+	ErrSignalStreamNotPolled = errors.New("stream is not delivered by poll")
+	ErrInvalidDeliveryMethod = errors.New("delivery method must be push or poll")
+	ErrInvalidSignalEndpoint = errors.New("push endpoint_url must be an absolute http or https url")
+	ErrInvalidSignalStatus   = errors.New("status must be enabled, paused or disabled")
+)
+
+// signalEventsSupported are the event types the transmitter emits.
+var signalEventsSupported = []string{
+	ssf.EventSessionRevoked,
+	ssf.EventCredentialChange,
+	ssf.EventRiskLevelChange,
+	ssf.EventAccountDisabled,
+	ssf.EventAccountEnabled,
+	ssf.EventAccountPurged,
+}
+
+type SignalRepo interface {
+	CreateStream(ctx context.Context, stream types.SignalStream) error
+	GetStream(ctx context.Context, streamId string) (*types.SignalStream, error)
+	GetClientStream(ctx context.Context, clientId string) (*types.SignalStream, error)
+	ListEventStreams(ctx context.Context, eventType string) ([]types.SignalStream, error)
+	UpdateStream(ctx context.Context, stream types.SignalStream) error
+	DeleteStream(ctx context.Context, streamId string) (bool, error)
+	CreateSecurityEvent(ctx context.Context, event types.SecurityEvent) error
+	ClaimPushEvents(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]types.SecurityEvent, error)
+	ListPollEvents(ctx context.Context, streamId string, limit int) ([]types.SecurityEvent, error)
+	SaveSecurityEvent(ctx context.Context, event types.SecurityEvent) error
+	FinishSecurityEvents(ctx context.Context, streamId string, eventIds []string, status string, reason string) error
+	DeleteSecurityEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SecurityEventPusher pushes signed Security Event Tokens to receivers.
+type SecurityEventPusher interface {
+	Push(ctx context.Context, endpoint string, authorization string, set string) error
+}
+
+type SignalConfig struct {
+	// Issuer is the iss of the tokens and the base url of the transmitter endpoints.
+	Issuer string
+	// Timeout of one push, also bounds how long a claimed batch is held by a pusher.
+	Timeout     time.Duration
+	MaxAttempts int
+	// RetryBackoff is the delay after the first failed push, doubled after every next one up to MaxBackoff.
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// Retention is how long events are kept, whether delivered or not.
+	Retention time.Duration
+}
+
+// Signals is the Shared Signals transmitter. It sends security events about users to the
+// streams of relying parties as Security Event Tokens, pushed or polled.
+type Signals struct {
+	signalrepo   SignalRepo
+	tokenManager auth.TokenManager
+	pusher       SecurityEventPusher
+	config       SignalConfig
+}
+
+// Configuration returns the transmitter metadata.
+func (s *Signals) Configuration() ssf.TransmitterMetadata {
+	return ssf.TransmitterMetadata{
+		SpecVersion:              ssf.SpecVersion,
+		Issuer:                   s.config.Issuer,
+		JwksURI:                  s.config.Issuer + signalJwksPath,
+		DeliveryMethodsSupported: []string{ssf.DeliveryMethodPush, ssf.DeliveryMethodPoll},
+		ConfigurationEndpoint:    s.config.Issuer + signalStreamPath,
+		StatusEndpoint:           s.config.Issuer + signalStatusPath,
+		VerificationEndpoint:     s.config.Issuer + signalVerifyPath,
+		AuthorizationSchemes:     []ssf.AuthorizationScheme{{SpecURN: signalAuthSchemeURN}},
+		DefaultSubjects:          signalDefaultSubject,
+	}
+}
+
+// EventsSupported returns the event types the transmitter emits.
+func (s *Signals) EventsSupported() []string {
+	return slices.Clone(signalEventsSupported)
+}
+
+// CreateStream creates the stream of the client, a client has one stream at most. Streams are
+// delivered by poll unless a push method is requested.
+func (s *Signals) CreateStream(ctx context.Context, clientId string, input types.SignalStreamDTO) (*types.SignalStream, error) {
+	stream := types.SignalStream{
+		Id:             uuid.NewString(),
+		ClientId:       clientId,
+		DeliveryMethod: ssf.DeliveryMethodPoll,
+		Status:         ssf.StatusEnabled,
+	}
+	if err := s.applyStreamInput(&stream, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.signalrepo.CreateStream(ctx, stream); err != nil {
+		if errors.Is(err, postgres.ErrUniqueContraintFailed) {
+			return nil, ErrSignalStreamExists
+		}
+		logger.Errorf("failed to create stream: %s", err)
+		return nil, err
+	}
+
+	logger.Infof("client %s created stream %s", clientId, stream.Id)
+	return s.Stream(ctx, clientId, stream.Id)
+}
+
+// Stream returns the stream of the client. streamId may be empty, the client has one stream.
+func (s *Signals) Stream(ctx context.Context, clientId string, streamId string) (*types.SignalStream, error) {
+	stream, err := s.signalrepo.GetClientStream(ctx, clientId)
+	if err != nil {
+		logger.Errorf("failed to get stream: %s", err)
+		return nil, err
+	}
+	if stream == nil || (streamId != "" && stream.Id != streamId) {
+		return nil, ErrSignalStreamNotFound
+	}
+	return stream, nil
+}
+
+// UpdateStream changes the fields of the stream that are set in input.
+func (s *Signals) UpdateStream(ctx context.Context, clientId string, input types.SignalStreamDTO) (*types.SignalStream, error) {
+	stream, err := s.Stream(ctx, clientId, input.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.applyStreamInput(stream, input); err != nil {
+		return nil, err
+	}
+
+	if err = s.signalrepo.UpdateStream(ctx, *stream); err != nil {
+		logger.Errorf("failed to update stream: %s", err)
+		return nil, err
+	}
+
+	return s.Stream(ctx, clientId, stream.Id)
+}
+
+// DeleteStream removes the stream of the client together with its undelivered events.
+func (s *Signals) DeleteStream(ctx context.Context, clientId string, streamId string) error {
+	stream, err := s.Stream(ctx, clientId, streamId)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.signalrepo.DeleteStream(ctx, stream.Id)
+	if err != nil {
+		logger.Errorf("failed to delete stream: %s", err)
+		return err
+	}
+	if !deleted {
+		return ErrSignalStreamNotFound
+	}
+
+	logger.Infof("client %s deleted stream %s", clientId, stream.Id)
+	return nil
+}
+
+// SetStatus enables, pauses or disables the stream. Events are held while the stream is paused
+// and are not queued at all while it is disabled.
+func (s *Signals) SetStatus(ctx context.Context, clientId string, streamId string, status string, reason string) (*types.SignalStream, error) {
+	if status != ssf.StatusEnabled && status != ssf.StatusPaused && status != ssf.StatusDisabled {
+		return nil, ErrInvalidSignalStatus
+	}
+
+	stream, err := s.Stream(ctx, clientId, streamId)
+	if err != nil {
+		return nil, err
+	}
+
+	stream.Status = status
+	stream.StatusReason = reason
+	if err = s.signalrepo.UpdateStream(ctx, *stream); err != nil {
+		logger.Errorf("failed to update stream status: %s", err)
+		return nil, err
+	}
+
+	logger.Infof("stream %s status set to %s", stream.Id, status)
+	return s.Stream(ctx, clientId, stream.Id)
+}
+
+// Verify queues a verification event with the state of the receiver, so that the receiver can
+// check that events of the stream reach it.
+func (s *Signals) Verify(ctx context.Context, clientId string, streamId string, state string) error {
+	stream, err := s.Stream(ctx, clientId, streamId)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]any{}
+	if state != "" {
+		payload["state"] = state
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		logger.Errorf("failed to encode verification event: %s", err)
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	event := types.SecurityEvent{
+		Id:            uuid.NewString(),
+		StreamId:      stream.Id,
+		Type:          ssf.EventVerification,
+		Payload:       encoded,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if err = s.signalrepo.CreateSecurityEvent(ctx, event); err != nil {
+		logger.Errorf("failed to queue verification event: %s", err)
+		return err
+	}
+	return nil
+}
+
+// Poll acknowledges the tokens the receiver processed or failed to process and returns the
+// next pending tokens of its stream, oldest first. Tokens that are not acknowledged are
+// returned again by the next poll. Events are returned immediately, long polling is not supported.
+func (s *Signals) Poll(ctx context.Context, clientId string, request ssf.PollRequest) (ssf.PollResponse, error) {
+	response := ssf.PollResponse{Sets: make(map[string]string)}
+
+	stream, err := s.Stream(ctx, clientId, "")
+	if err != nil {
+		return response, err
+	}
+	if stream.DeliveryMethod != ssf.DeliveryMethodPoll {
+		return response, ErrSignalStreamNotPolled
+	}
+
+	if len(request.Ack) > 0 {
+		if err = s.signalrepo.FinishSecurityEvents(ctx, stream.Id, request.Ack, types.SecurityEventDelivered, ""); err != nil {
+			logger.Errorf("failed to acknowledge security events: %s", err)
+			return response, err
+		}
+	}
+	for jti, setErr := range request.SetErrs {
+		reason := setErr.Err
+		if setErr.Description != "" {
+			reason += ": " + setErr.Description
+		}
+		if err = s.signalrepo.FinishSecurityEvents(ctx, stream.Id, []string{jti}, types.SecurityEventFailed, reason); err != nil {
+			logger.Errorf("failed to save security event error: %s", err)
+			return response, err
+		}
+	}
+
+	limit := defaultPollEvents
+	if request.MaxEvents != nil {
+		limit = min(max(*request.MaxEvents, 0), maxPollEvents)
+	}
+	if limit == 0 || stream.Status != ssf.StatusEnabled {
+		return response, nil
+	}
+
+	events, err := s.signalrepo.ListPollEvents(ctx, stream.Id, limit+1)
+	if err != nil {
+		logger.Errorf("failed to list security events: %s", err)
+		return response, err
+	}
+	if len(events) > limit {
+		events = events[:limit]
+		response.MoreAvailable = true
+	}
+
+	for _, event := range events {
+		set, err := s.sign(*stream, event)
+		if err != nil {
+			logger.Errorf("failed to sign security event %s: %s", event.Id, err)
+			return response, err
+		}
+		response.Sets[event.Id] = set
+	}
+	return response, nil
+}
+
+// SendDue pushes the events that are due to push streams and returns how many were attempted.
+func (s *Signals) SendDue(ctx context.Context) (int, error) {
+	// A claimed batch is pushed one by one, so it is held long enough for every push to time out.
+	lease := signalBatchSize*s.config.Timeout + time.Minute
+
+	var sent int
+	for {
+		now := time.Now()
+		events, err := s.signalrepo.ClaimPushEvents(ctx, now, now.Add(lease), signalBatchSize)
+		if err != nil {
+			logger.Errorf("failed to claim security events: %s", err)
+			return sent, err
+		}
+
+		streams := make(map[string]*types.SignalStream)
+		for _, event := range events {
+			stream, ok := streams[event.StreamId]
+			if !ok {
+				if stream, err = s.signalrepo.GetStream(ctx, event.StreamId); err != nil {
+					logger.Errorf("failed to get stream: %s", err)
+					return sent, err
+				}
+				streams[event.StreamId] = stream
+			}
+			// Deleted while the batch was pushed, its events are gone.
+			if stream == nil {
+				continue
+			}
+
+			s.push(ctx, *stream, event)
+			sent++
+		}
+
+		if len(events) < signalBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// PurgeEvents removes events older than the retention period.
+func (s *Signals) PurgeEvents(ctx context.Context) (int64, error) {
+	deleted, err := s.signalrepo.DeleteSecurityEvents(ctx, time.Now().Add(-s.config.Retention))
+	if err != nil {
+		logger.Errorf("failed to purge security events: %s", err)
+		return 0, err
+	}
+	if deleted > 0 {
+		logger.Infof("purged %d security events", deleted)
+	}
+	return deleted, nil
+}
+
+// RunPusher pushes due events every interval and purges old ones until ctx is done.
+func (s *Signals) RunPusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	purgeTicker := time.NewTicker(signalPurgeInterval)
+	defer purgeTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = s.SendDue(ctx)
+		case <-purgeTicker.C:
+			_, _ = s.PurgeEvents(ctx)
+		}
+	}
+}
+
+// EventsDelivered returns the requested event types of the stream that the transmitter emits.
+func (s *Signals) EventsDelivered(stream types.SignalStream) []string {
+	delivered := make([]string, 0, len(stream.EventsRequested))
+	for _, eventType := range stream.EventsRequested {
+		if slices.Contains(signalEventsSupported, eventType) {
+			delivered = append(delivered, eventType)
+		}
+	}
+	return delivered
+}
+
+// applyStreamInput sets the fields of input on the stream. The endpoint of a poll stream is
+// the poll endpoint of the transmitter.
+func (s *Signals) applyStreamInput(stream *types.SignalStream, input types.SignalStreamDTO) error {
+	if input.DeliveryMethod != nil {
+		stream.DeliveryMethod = *input.DeliveryMethod
+	}
+	if input.EndpointURL != nil {
+		stream.EndpointURL = *input.EndpointURL
+	}
+	if input.AuthorizationHeader != nil {
+		stream.AuthorizationHeader = *input.AuthorizationHeader
+	}
+	if input.Description != nil {
+		stream.Description = *input.Description
+	}
+	if input.EventsRequested != nil {
+		stream.EventsRequested = make([]string, 0, len(input.EventsRequested))
+		for _, eventType := range input.EventsRequested {
+			if !slices.Contains(stream.EventsRequested, eventType) {
+				stream.EventsRequested = append(stream.EventsRequested, eventType)
+			}
+		}
+	}
+	if stream.EventsRequested == nil {
+		stream.EventsRequested = []string{}
+	}
+
+	switch stream.DeliveryMethod {
+	case ssf.DeliveryMethodPoll:
+		stream.EndpointURL = s.config.Issuer + signalPollPath
+		stream.AuthorizationHeader = ""
+	case ssf.DeliveryMethodPush:
+		parsed, err := url.Parse(stream.EndpointURL)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return ErrInvalidSignalEndpoint
+		}
+	default:
+		return ErrInvalidDeliveryMethod
+	}
+	return nil
+}
+
+// push makes one attempt to push the event. A rejected event is not pushed again, any other
+// failure is retried with backoff until the event runs out of attempts.
+func (s *Signals) push(ctx context.Context, stream types.SignalStream, event types.SecurityEvent) {
+	set, err := s.sign(stream, event)
+	if err == nil {
+		err = s.pusher.Push(ctx, stream.EndpointURL, stream.AuthorizationHeader, set)
+	}
+
+	now := time.Now()
+	event.Attempts++
+	event.Error = ""
+	switch {
+	case err == nil:
+		event.Status = types.SecurityEventDelivered
+	case errors.Is(err, ssf.ErrRejected) || event.Attempts >= s.config.MaxAttempts:
+		event.Status = types.SecurityEventFailed
+		event.Error = err.Error()
+		logger.Errorf("security event %s to %s failed after %d attempts: %s", event.Id, stream.EndpointURL, event.Attempts, err)
+	default:
+		event.Status = types.SecurityEventPending
+		event.Error = err.Error()
+		event.NextAttemptAt = now.Add(webhook.Backoff(event.Attempts, s.config.RetryBackoff, s.config.MaxBackoff))
+	}
+
+	if err = s.signalrepo.SaveSecurityEvent(ctx, event); err != nil {
+		logger.Errorf("failed to save security event %s: %s", event.Id, err)
+	}
+}
+
+// sign makes the Security Event Token of the event for the receiver of the stream.
+func (s *Signals) sign(stream types.SignalStream, event types.SecurityEvent) (string, error) {
+	var subject ssf.Subject
+	switch {
+	case event.UserId == "":
+		subject = ssf.OpaqueSubject(stream.Id)
+	case event.SessionId == "":
+		subject = ssf.IssSubSubject(s.config.Issuer, event.UserId)
+	default:
+		subject = ssf.SessionSubject(ssf.IssSubSubject(s.config.Issuer, event.UserId), event.SessionId)
+	}
+	subId, err := json.Marshal(subject)
+	if err != nil {
+		return "", err
+	}
+
+	return s.tokenManager.NewSecurityEventToken(auth.SecurityEventClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       event.Id,
+			Issuer:   s.config.Issuer,
+			Audience: jwt.ClaimStrings{stream.ClientId},
+			IssuedAt: jwt.NewNumericDate(event.CreatedAt),
+		},
+		SubId:  subId,
+		Txn:    event.Txn,
+		Events: map[string]json.RawMessage{event.Type: event.Payload},
+	})
+}
+
+// signal is a security event made of an audit event.
+type signal struct {
+	eventType string
+	sessionId string
+	payload   map[string]any
+}
+
+// queueSecurityEvents queues the security events the audit event maps to for every stream
+// that requested them. A failure is logged and does not fail the caller.
+func (a *Audit) queueSecurityEvents(ctx context.Context, event types.AuditEvent) {
+	for _, signal := range auditSignals(event) {
+		streams, err := a.signalrepo.ListEventStreams(ctx, signal.eventType)
+		if err != nil {
+			logger.Errorf("failed to list streams for audit event %d: %s", event.Id, err)
+			return
+		}
+		if len(streams) == 0 {
+			continue
+		}
+
+		signal.payload["event_timestamp"] = event.CreatedAt.Unix()
+		payload, err := json.Marshal(signal.payload)
+		if err != nil {
+			logger.Errorf("failed to encode security event: %s", err)
+			return
+		}
+
+		for _, stream := range streams {
+			securityEvent := types.SecurityEvent{
+				Id:            uuid.NewString(),
+				StreamId:      stream.Id,
+				Type:          signal.eventType,
+				UserId:        event.UserId,
+				SessionId:     signal.sessionId,
+				Txn:           strconv.FormatInt(event.Id, 10),
+				Payload:       payload,
+				NextAttemptAt: time.Now(),
+				CreatedAt:     event.CreatedAt,
+			}
+			if err = a.signalrepo.CreateSecurityEvent(ctx, securityEvent); err != nil {
+				logger.Errorf("failed to queue security event (event: %d, stream: %s): %s", event.Id, stream.Id, err)
+			}
+		}
+	}
+}
+
+// auditSignals maps an audit event to security events. Reused refresh tokens revoke nothing
+// by themselves and are reported as a raised risk of the user.
+func auditSignals(event types.AuditEvent) []signal {
+	if event.UserId == "" {
+		return nil
+	}
+
+	initiator := ssf.InitiatorSystem
+	switch event.ActorId {
+	case "":
+	case event.UserId:
+		initiator = ssf.InitiatorUser
+	default:
+		initiator = ssf.InitiatorAdmin
+	}
+
+	switch event.Type {
+	case types.AuditUserSignedOut:
+		return []signal{{
+			eventType: ssf.EventSessionRevoked,
+			sessionId: event.Metadata["session_id"],
+			payload:   map[string]any{"initiating_entity": ssf.InitiatorUser},
+		}}
+	case types.AuditSessionRevoked:
+		return []signal{{
+			eventType: ssf.EventSessionRevoked,
+			sessionId: event.Metadata["session_id"],
+			payload:   map[string]any{"initiating_entity": initiator},
+		}}
+	case types.AuditTokenReuseDetected:
+		return []signal{{
+			eventType: ssf.EventRiskLevelChange,
+			payload: map[string]any{
+				"principal":         "USER",
+				"current_level":     "HIGH",
+				"risk_reason":       "refresh_token_reuse",
+				"initiating_entity": ssf.InitiatorPolicy,
+			},
+		}}
+	case types.AuditPasswordChanged, types.AuditPasswordReset:
+		return []signal{{
+			eventType: ssf.EventCredentialChange,
+			payload: map[string]any{
+				"credential_type":   "password",
+				"change_type":       "update",
+				"initiating_entity": ssf.InitiatorUser,
+			},
+		}}
+	case types.AuditPasswordResetForced:
+		// Forcing a reset also revokes every session of the user.
+		return []signal{
+			{
+				eventType: ssf.EventCredentialChange,
+				payload: map[string]any{
+					"credential_type":   "password",
+					"change_type":       "revoke",
+					"initiating_entity": initiator,
+				},
+			},
+			{
+				eventType: ssf.EventSessionRevoked,
+				payload:   map[string]any{"initiating_entity": initiator},
+			},
+		}
+	case types.AuditUserStatusChanged:
+		eventType := ssf.EventAccountDisabled
+		if event.Metadata["status"] == types.UserStatusActive {
+			eventType = ssf.EventAccountEnabled
+		}
+		return []signal{{eventType: eventType, payload: map[string]any{}}}
+	case types.AuditAccountErased, types.AuditAccountPurged:
+		return []signal{{
+			eventType: ssf.EventAccountPurged,
+			payload:   map[string]any{},
+		}}
+	}
+	return nil
+}
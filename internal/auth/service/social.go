@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"medods-test/pkg/oidcclient"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	socialLinkTTL      = 10 * time.Minute
+	maxSocialLinkTries = 5
+)
+
+var (
+	ErrProviderNotFound         = errors.New("identity provider not found")
+	ErrInvalidLoginState        = errors.New("invalid or expired login state")
+	ErrExternalLoginFailed      = errors.New("external login failed")
+	ErrEmailNotVerified         = errors.New("identity provider did not return a verified email")
+	ErrLinkConfirmationRequired = errors.New("an account with this email already exists, confirm linking it with its password")
+	ErrInvalidLinkToken         = errors.New("invalid or expired link token")
+)
+
+// LinkConfirmationError is returned by a social login whose external account has the email
+// of an existing user. Token identifies the link request in ConfirmLink.
+type LinkConfirmationError struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+func (e *LinkConfirmationError) Error() string {
+	return ErrLinkConfirmationRequired.Error()
+}
+
+func (e *LinkConfirmationError) Is(target error) bool {
+	return target == ErrLinkConfirmationRequired
+}
+
+type SocialProvider interface {
+	Name() string
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, nonce, verifier string) (*oidcclient.Identity, error)
+}
+
+type IdentityRepo interface {
+	CreateIdentity(ctx context.Context, identity types.ExternalIdentity) error
+	GetIdentity(ctx context.Context, provider string, subject string) (*types.ExternalIdentity, error)
+	UpdateIdentityLogin(ctx context.Context, id string, email string) error
+	ListUserIdentities(ctx context.Context, userId string) ([]types.ExternalIdentity, error)
+	CreateLoginState(ctx context.Context, state types.SocialLoginState) error
+	ConsumeLoginState(ctx context.Context, stateHash string) (*types.SocialLoginState, error)
+	CreateLinkRequest(ctx context.Context, request types.SocialLinkRequest) error
+	GetLinkRequest(ctx context.Context, tokenHash string) (*types.SocialLinkRequest, error)
+	AddLinkRequestAttempt(ctx context.Context, tokenHash string) error
+	DeleteLinkRequest(ctx context.Context, tokenHash string) (bool, error)
+}
+
+// Social signs users in with external OpenID Connect and OAuth 2.0 providers.
+type Social struct {
+	identityrepo IdentityRepo
+	user         *User
+
+	providers map[string]SocialProvider
+	stateTTL  time.Duration
+}
+
+func (s *Social) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoginURL starts a login at the provider and returns the url to redirect the user to along
+// with the state the callback has to come back with.
+func (s *Social) LoginURL(ctx context.Context, providerName string) (types.SocialLogin, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return types.SocialLogin{}, ErrProviderNotFound
+	}
+
+	state, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to create login state: %s", err)
+		return types.SocialLogin{}, err
+	}
+	nonce, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to create login nonce: %s", err)
+		return types.SocialLogin{}, err
+	}
+	verifier, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to create code verifier: %s", err)
+		return types.SocialLogin{}, err
+	}
+
+	loginURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		logger.Errorf("failed to build login url (provider: %s): %s", providerName, err)
+		return types.SocialLogin{}, err
+	}
+
+	expiresAt := time.Now().Add(s.stateTTL)
+	if err = s.identityrepo.CreateLoginState(ctx, types.SocialLoginState{
+		StateHash:    oauth.HashCode(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    expiresAt,
+	}); err != nil {
+		logger.Errorf("failed to save login state: %s", err)
+		return types.SocialLogin{}, err
+	}
+
+	return types.SocialLogin{
+		URL:       loginURL,
+		State:     state,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Callback completes the login at the provider, maps the external account to a local
+// user and creates a session for them.
+func (s *Social) Callback(ctx context.Context, providerName, state, code, IP string) (types.Tokens, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return types.Tokens{}, ErrProviderNotFound
+	}
+
+	loginState, err := s.identityrepo.ConsumeLoginState(ctx, oauth.HashCode(state))
+	if err != nil {
+		logger.Errorf("failed to consume login state: %s", err)
+		return types.Tokens{}, err
+	}
+	if loginState == nil || loginState.IsExpired() || loginState.Provider != providerName {
+		return types.Tokens{}, ErrInvalidLoginState
+	}
+
+	external, err := provider.Exchange(ctx, code, loginState.Nonce, loginState.CodeVerifier)
+	if err != nil {
+		logger.Errorf("failed to complete external login (provider: %s): %s", providerName, err)
+		return types.Tokens{}, ErrExternalLoginFailed
+	}
+
+	userId, err := s.resolveUser(ctx, providerName, external)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	return s.signIn(ctx, userId, providerName, IP)
+}
+
+// ConfirmLink links the external account of a link request to the existing user after the
+// user entered the local password, and signs the user in.
+func (s *Social) ConfirmLink(ctx context.Context, token, password, IP string) (types.Tokens, error) {
+	request, err := s.confirmLink(ctx, token, password)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	return s.signIn(ctx, request.UserId, request.Provider, IP)
+}
+
+func (s *Social) confirmLink(ctx context.Context, token, password string) (*types.SocialLinkRequest, error) {
+	tokenHash := oauth.HashCode(token)
+
+	request, err := s.identityrepo.GetLinkRequest(ctx, tokenHash)
+	if err != nil {
+		logger.Errorf("failed to get link request: %s", err)
+		return nil, err
+	}
+	if request == nil || request.IsExpired() || request.Attempts >= maxSocialLinkTries {
+		return nil, ErrInvalidLinkToken
+	}
+
+	if _, err = s.user.checkPassword(ctx, request.UserId, password); err != nil {
+		if errors.Is(err, ErrInvalidPassword) {
+			if err := s.identityrepo.AddLinkRequestAttempt(ctx, tokenHash); err != nil {
+				logger.Errorf("failed to count link attempt: %s", err)
+			}
+			s.user.recordLoginAttempt(ctx, request.UserId, types.LoginMethodSocial, err)
+			s.user.audit.Record(ctx, types.AuditUserSignInFailed, request.UserId, map[string]string{
+				"method":   types.LoginMethodSocial,
+				"provider": request.Provider,
+				"reason":   err.Error(),
+			})
+		}
+		return nil, err
+	}
+
+	deleted, err := s.identityrepo.DeleteLinkRequest(ctx, tokenHash)
+	if err != nil {
+		logger.Errorf("failed to delete link request: %s", err)
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrInvalidLinkToken
+	}
+
+	if err = s.link(ctx, request.Provider, request.Subject, request.UserId, request.Email); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// signIn creates a session for the user signed in at the provider unless the user is inactive
+// or the sign-in needs a second factor.
+func (s *Social) signIn(ctx context.Context, userId, providerName, IP string) (types.Tokens, error) {
+	user, err := s.user.userrepo.GetUserByID(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get user: %s", err)
+		return types.Tokens{}, err
+	}
+	if user == nil {
+		return types.Tokens{}, ErrUserNotFound
+	}
+	// The external account does not replace the password reset forced on the user, as in
+	// authenticate.
+	if user.PasswordResetRequired {
+		err = ErrPasswordResetRequired
+	} else if err = checkUserActive(user); err == nil {
+		err = s.user.checkSignInRisk(ctx, user)
+	}
+	if err != nil {
+		s.user.recordLoginAttempt(ctx, userId, types.LoginMethodSocial, err)
+		s.user.audit.Record(ctx, types.AuditUserSignInFailed, userId, map[string]string{
+			"method":   types.LoginMethodSocial,
+			"provider": providerName,
+			"reason":   err.Error(),
+		})
+
+		var mfa *signInMFAError
+		if errors.As(err, &mfa) {
+			return types.Tokens{}, s.user.startMFAChallenge(ctx, user, "", types.LoginMethodSocial)
+		}
+		return types.Tokens{}, err
+	}
+
+	s.user.recordLoginAttempt(ctx, userId, types.LoginMethodSocial, nil)
+	s.user.audit.Record(ctx, types.AuditUserSignedIn, userId, map[string]string{"method": types.LoginMethodSocial, "provider": providerName})
+	return s.user.CreateSession(ctx, userId, "", IP)
+}
+
+// resolveUser finds the local user of an external account. Unknown accounts get a new user.
+// An unknown account with the email of an existing user is linked to it only once the user
+// confirms the link with the local password, so the provider cannot take over the account.
+func (s *Social) resolveUser(ctx context.Context, provider string, external *oidcclient.Identity) (string, error) {
+	email := strings.ToLower(external.Email)
+
+	identity, err := s.identityrepo.GetIdentity(ctx, provider, external.Subject)
+	if err != nil {
+		logger.Errorf("failed to get identity: %s", err)
+		return "", err
+	}
+	if identity != nil {
+		if err = s.identityrepo.UpdateIdentityLogin(ctx, identity.Id, email); err != nil {
+			logger.Errorf("failed to update identity: %s", err)
+			return "", err
+		}
+		return identity.UserId, nil
+	}
+
+	if email == "" || !external.EmailVerified {
+		return "", ErrEmailNotVerified
+	}
+
+	user, err := s.user.userrepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		logger.Errorf("failed to get user by email: %s", err)
+		return "", err
+	}
+	if user != nil {
+		return "", s.startLink(ctx, provider, external.Subject, user.UserUUID, email)
+	}
+
+	if user, err = s.user.provisionUser(ctx, email); err != nil {
+		return "", err
+	}
+	if err = s.link(ctx, provider, external.Subject, user.UserUUID, email); err != nil {
+		return "", err
+	}
+	return user.UserUUID, nil
+}
+
+// startLink saves the external account until the existing user confirms linking it and returns
+// the request as *LinkConfirmationError.
+func (s *Social) startLink(ctx context.Context, provider, subject, userId, email string) error {
+	token, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate link token: %s", err)
+		return err
+	}
+
+	request := types.SocialLinkRequest{
+		TokenHash: oauth.HashCode(token),
+		Provider:  provider,
+		Subject:   subject,
+		UserId:    userId,
+		Email:     email,
+		ExpiresAt: time.Now().Add(socialLinkTTL),
+	}
+	if err = s.identityrepo.CreateLinkRequest(ctx, request); err != nil {
+		logger.Errorf("failed to save link request: %s", err)
+		return err
+	}
+
+	return &LinkConfirmationError{
+		Token:     token,
+		ExpiresAt: request.ExpiresAt,
+	}
+}
+
+func (s *Social) link(ctx context.Context, provider, subject, userId, email string) error {
+	if err := s.identityrepo.CreateIdentity(ctx, types.ExternalIdentity{
+		Id:       uuid.NewString(),
+		Provider: provider,
+		Subject:  subject,
+		UserId:   userId,
+		Email:    email,
+	}); err != nil {
+		logger.Errorf("failed to link identity: %s", err)
+		return err
+	}
+
+	s.user.audit.Record(ctx, types.AuditIdentityLinked, userId, map[string]string{"provider": provider})
+	logger.Infof("linked %s identity to user %s", provider, userId)
+	return nil
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/oauth"
+	"medods-test/pkg/oidcclient"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const (
+	testProvider = "fake"
+	testPassword = "correct horse battery staple"
+)
+
+// fakeProvider is an OAuth 2.0 provider that signs in whoever it is told to.
+type fakeProvider struct {
+	server   *httptest.Server
+	userinfo map[string]any
+}
+
+func newFakeProvider(t *testing.T, userinfo map[string]any) *fakeProvider {
+	t.Helper()
+
+	p := &fakeProvider{userinfo: userinfo}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") == "" || r.PostFormValue("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"provider-token","token_type":"Bearer","expires_in":60}`))
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer provider-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(p.userinfo)
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeProvider) provider(t *testing.T) SocialProvider {
+	t.Helper()
+
+	provider, err := oidcclient.NewProvider(oidcclient.Config{
+		Name:        testProvider,
+		ClientId:    "client",
+		AuthURL:     p.server.URL + "/authorize",
+		TokenURL:    p.server.URL + "/token",
+		UserInfoURL: p.server.URL + "/userinfo",
+	}, "https://auth.example.com/auth/social/fake/callback")
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	return provider
+}
+
+// fakeLoginStates adds the login states to fakeIdentityRepo for the callback tests.
+type fakeLoginStates struct {
+	*fakeIdentityRepo
+
+	states map[string]types.SocialLoginState
+}
+
+func (r *fakeLoginStates) CreateLoginState(_ context.Context, state types.SocialLoginState) error {
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *fakeLoginStates) ConsumeLoginState(_ context.Context, stateHash string) (*types.SocialLoginState, error) {
+	state, ok := r.states[stateHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.states, stateHash)
+	return &state, nil
+}
+
+type socialTest struct {
+	social     *Social
+	users      *fakeUserRepo
+	identities *fakeIdentityRepo
+	audit      *fakeAuditRepo
+	local      types.User
+}
+
+func newSocialTest(t *testing.T, userinfo map[string]any) *socialTest {
+	t.Helper()
+
+	test := &socialTest{
+		identities: newFakeIdentityRepo(),
+		audit:      &fakeAuditRepo{},
+	}
+	test.users = newFakeUserRepo()
+	user := newTestUser(test.users, test.audit)
+
+	passwordHash, err := user.hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	test.local = types.User{
+		UserUUID: "7d3c2a4e-36e5-4c8c-9a43-3f3a3f0c7a11",
+		Email:    "alice@example.com",
+		Password: passwordHash,
+		Status:   types.UserStatusActive,
+	}
+	_ = test.users.Create(context.Background(), test.local)
+
+	test.social = &Social{
+		identityrepo: &fakeLoginStates{fakeIdentityRepo: test.identities, states: make(map[string]types.SocialLoginState)},
+		user:         user,
+		providers:    map[string]SocialProvider{testProvider: newFakeProvider(t, userinfo).provider(t)},
+		stateTTL:     time.Minute,
+	}
+	return test
+}
+
+// login starts a login and returns the state the provider would redirect back with.
+func (s *socialTest) login(t *testing.T) string {
+	t.Helper()
+
+	login, err := s.social.LoginURL(context.Background(), testProvider)
+	if err != nil {
+		t.Fatalf("LoginURL() error = %v", err)
+	}
+	loginURL, err := url.Parse(login.URL)
+	if err != nil {
+		t.Fatalf("LoginURL() returned invalid url %q: %v", login.URL, err)
+	}
+	if got := loginURL.Query().Get("state"); got != login.State {
+		t.Fatalf("LoginURL() state in url = %q, want %q", got, login.State)
+	}
+	if loginURL.Query().Get("code_challenge") == "" {
+		t.Fatalf("LoginURL() url %q has no code_challenge", login.URL)
+	}
+	return login.State
+}
+
+func TestSocialCallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		userinfo map[string]any
+		// state is sent to the callback instead of the state of a new login.
+		state string
+		// reuse sends the state of a login that has already been completed.
+		reuse   bool
+		wantErr error
+	}{
+		{
+			name:     "unknown state",
+			userinfo: map[string]any{"sub": "1", "email": "bob@example.com", "email_verified": true},
+			state:    "forged",
+			wantErr:  ErrInvalidLoginState,
+		},
+		{
+			name:     "state used twice",
+			userinfo: map[string]any{"sub": "1", "email": "bob@example.com", "email_verified": false},
+			reuse:    true,
+			wantErr:  ErrInvalidLoginState,
+		},
+		{
+			name:     "unverified email",
+			userinfo: map[string]any{"sub": "1", "email": "bob@example.com", "email_verified": false},
+			wantErr:  ErrEmailNotVerified,
+		},
+		{
+			name:     "email of an existing user",
+			userinfo: map[string]any{"sub": "1", "email": "Alice@example.com", "email_verified": true},
+			wantErr:  ErrLinkConfirmationRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSocialTest(t, tt.userinfo)
+
+			state := tt.state
+			if state == "" {
+				state = s.login(t)
+			}
+			if tt.reuse {
+				_, _ = s.social.Callback(context.Background(), testProvider, state, "code", "203.0.113.1")
+			}
+
+			_, err := s.social.Callback(context.Background(), testProvider, state, "code", "203.0.113.1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Callback() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(s.identities.identities) != 0 {
+				t.Errorf("Callback() linked %d identities, want none", len(s.identities.identities))
+			}
+		})
+	}
+}
+
+func TestSocialLinkExistingUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		attempts  int
+		expired   bool
+		wantErr   error
+		wantLinks int
+	}{
+		{
+			name:      "correct password",
+			password:  testPassword,
+			wantLinks: 1,
+		},
+		{
+			name:     "wrong password",
+			password: "guess",
+			wantErr:  ErrInvalidPassword,
+		},
+		{
+			name:     "too many attempts",
+			password: testPassword,
+			attempts: maxSocialLinkTries,
+			wantErr:  ErrInvalidLinkToken,
+		},
+		{
+			name:     "expired request",
+			password: testPassword,
+			expired:  true,
+			wantErr:  ErrInvalidLinkToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSocialTest(t, map[string]any{"sub": "external-1", "email": "alice@example.com", "email_verified": true})
+
+			_, err := s.social.Callback(context.Background(), testProvider, s.login(t), "code", "203.0.113.1")
+			var linkErr *LinkConfirmationError
+			if !errors.As(err, &linkErr) {
+				t.Fatalf("Callback() error = %v, want *LinkConfirmationError", err)
+			}
+
+			tokenHash := oauth.HashCode(linkErr.Token)
+			request := s.identities.linkRequests[tokenHash]
+			if request.UserId != s.local.UserUUID || request.Subject != "external-1" {
+				t.Fatalf("link request = %+v, want user %s and subject external-1", request, s.local.UserUUID)
+			}
+			request.Attempts = tt.attempts
+			if tt.expired {
+				request.ExpiresAt = time.Now().Add(-time.Second)
+			}
+			s.identities.linkRequests[tokenHash] = request
+
+			linked, err := s.social.confirmLink(context.Background(), linkErr.Token, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("confirmLink() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := len(s.identities.identities); got != tt.wantLinks {
+				t.Fatalf("confirmLink() linked %d identities, want %d", got, tt.wantLinks)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if linked.UserId != s.local.UserUUID {
+				t.Errorf("confirmLink() user = %s, want %s", linked.UserId, s.local.UserUUID)
+			}
+			if _, err = s.social.confirmLink(context.Background(), linkErr.Token, tt.password); !errors.Is(err, ErrInvalidLinkToken) {
+				t.Errorf("second confirmLink() error = %v, want %v", err, ErrInvalidLinkToken)
+			}
+		})
+	}
+}
+
+func TestSocialLinkCountsWrongPasswords(t *testing.T) {
+	s := newSocialTest(t, map[string]any{"sub": "external-1", "email": "alice@example.com", "email_verified": true})
+
+	_, err := s.social.Callback(context.Background(), testProvider, s.login(t), "code", "203.0.113.1")
+	var linkErr *LinkConfirmationError
+	if !errors.As(err, &linkErr) {
+		t.Fatalf("Callback() error = %v, want *LinkConfirmationError", err)
+	}
+
+	for i := 0; i < maxSocialLinkTries; i++ {
+		if _, err = s.social.confirmLink(context.Background(), linkErr.Token, "guess"); !errors.Is(err, ErrInvalidPassword) {
+			t.Fatalf("confirmLink() attempt %d error = %v, want %v", i+1, err, ErrInvalidPassword)
+		}
+	}
+	if _, err = s.social.confirmLink(context.Background(), linkErr.Token, testPassword); !errors.Is(err, ErrInvalidLinkToken) {
+		t.Fatalf("confirmLink() after %d wrong passwords error = %v, want %v", maxSocialLinkTries, err, ErrInvalidLinkToken)
+	}
+	if len(s.identities.identities) != 0 {
+		t.Errorf("confirmLink() linked %d identities, want none", len(s.identities.identities))
+	}
+}
+
+func TestSocialSignInPasswordResetRequired(t *testing.T) {
+	s := newSocialTest(t, map[string]any{"sub": "external-1", "email": "alice@example.com", "email_verified": true})
+	s.local.PasswordResetRequired = true
+	_ = s.users.Create(context.Background(), s.local)
+	s.identities.identities = append(s.identities.identities, types.ExternalIdentity{
+		Id:       "identity-1",
+		Provider: testProvider,
+		Subject:  "external-1",
+		UserId:   s.local.UserUUID,
+		Email:    s.local.Email,
+	})
+
+	_, err := s.social.Callback(context.Background(), testProvider, s.login(t), "code", "203.0.113.1")
+	if !errors.Is(err, ErrPasswordResetRequired) {
+		t.Fatalf("Callback() error = %v, want %v", err, ErrPasswordResetRequired)
+	}
+	if got := s.audit.eventTypes(); len(got) != 1 || got[0] != types.AuditUserSignInFailed {
+		t.Errorf("audit events = %v, want [%s]", got, types.AuditUserSignInFailed)
+	}
+	attempts := s.social.user.loginrepo.(*fakeLoginHistoryRepo).attempts
+	if len(attempts) != 1 || attempts[0].Success {
+		t.Errorf("login attempts = %+v, want one failed", attempts)
+	}
+}
@@ -0,0 +1,11 @@
+package service
+
+import (
+	"embed"
+	"html/template"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var emailTemplates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/auth"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidSubjectToken     = errors.New("invalid subject token")
+	ErrInvalidActorToken       = errors.New("invalid actor token")
+	ErrUnsupportedTokenType    = errors.New("unsupported token type")
+	ErrActorNotAllowed         = errors.New("actor is not allowed to impersonate")
+	ErrAudienceNotAllowed      = errors.New("subject token was not issued to the client")
+	ErrActorAudienceNotAllowed = errors.New("actor token was not issued to the client")
+)
+
+type TokenExchangeRepo interface {
+	CreateTokenExchange(ctx context.Context, exchange types.TokenExchange) error
+	ListUserTokenExchanges(ctx context.Context, userId string) ([]types.TokenExchange, error)
+}
+
+// exchangeToken implements the token exchange grant (RFC 8693). Every attempt is
+// audited, and no token is issued if the audit record cannot be saved.
+func (o *OAuth) exchangeToken(ctx context.Context, client *types.OAuthClient, req types.TokenRequest, IP string) (types.Tokens, error) {
+	exchange := types.TokenExchange{
+		Id:             uuid.NewString(),
+		ClientId:       client.ClientId,
+		RequestedScope: req.Exchange.Scope,
+		IP:             IP,
+	}
+
+	tokens, err := o.issueExchangedToken(ctx, client, req.Exchange, &exchange)
+	exchange.Success = err == nil
+	if err != nil {
+		exchange.Error = err.Error()
+	}
+
+	if auditErr := o.exchangerepo.CreateTokenExchange(ctx, exchange); auditErr != nil {
+		logger.Errorf("failed to audit token exchange: %s", auditErr)
+		return types.Tokens{}, auditErr
+	}
+
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	logger.Infof("token exchange (client: %s, subject: %s, actor: %s, scope: %s)",
+		exchange.ClientId, exchange.Subject, exchange.Actor, exchange.GrantedScope)
+	return tokens, nil
+}
+
+func (o *OAuth) issueExchangedToken(ctx context.Context, client *types.OAuthClient, req types.TokenExchangeRequest, exchange *types.TokenExchange) (types.Tokens, error) {
+	if req.RequestedTokenType != "" && req.RequestedTokenType != oauth.TokenTypeAccessToken {
+		return types.Tokens{}, ErrUnsupportedTokenType
+	}
+
+	subject, err := o.parseExchangedToken(req.SubjectToken, req.SubjectTokenType)
+	if err != nil {
+		return types.Tokens{}, errors.Join(ErrInvalidSubjectToken, err)
+	}
+	exchange.Subject = subject.Subject
+	if err = o.checkExchangedSession(ctx, subject); err != nil {
+		return types.Tokens{}, joinSessionError(ErrInvalidSubjectToken, err)
+	}
+
+	// A token can only be exchanged by the client it was issued to or by a client trusted
+	// with its tokens, so that a token leaked from one client is useless to another.
+	if !exchangeAudienceAllowed(client, subject.ClientId) {
+		return types.Tokens{}, ErrAudienceNotAllowed
+	}
+
+	// Without an actor token the client itself acts on behalf of the subject.
+	act := &auth.Actor{
+		Subject:  client.ClientId,
+		ClientId: client.ClientId,
+		Act:      subject.Act,
+	}
+
+	if req.ActorToken != "" {
+		actor, err := o.parseExchangedToken(req.ActorToken, req.ActorTokenType)
+		if err != nil {
+			return types.Tokens{}, errors.Join(ErrInvalidActorToken, err)
+		}
+		if err = o.checkExchangedSession(ctx, actor); err != nil {
+			exchange.Actor = actor.Subject
+			return types.Tokens{}, joinSessionError(ErrInvalidActorToken, err)
+		}
+		// The actor token is bound the same way, a leaked support token cannot be used by
+		// any client that can exchange tokens.
+		if !exchangeAudienceAllowed(client, actor.ClientId) {
+			exchange.Actor = actor.Subject
+			return types.Tokens{}, ErrActorAudienceNotAllowed
+		}
+
+		if !oauth.HasScope(actor.Scope, oauth.ScopeImpersonation) {
+			exchange.Actor = actor.Subject
+			return types.Tokens{}, ErrActorNotAllowed
+		}
+
+		act = &auth.Actor{
+			Subject:  actor.Subject,
+			ClientId: actor.ClientId,
+			Act:      subject.Act,
+		}
+	}
+	exchange.Actor = act.Subject
+
+	scope := req.Scope
+	if scope == "" {
+		scope = subject.Scope
+	}
+	// Scopes can only be narrowed: a subject token without scopes has none to delegate.
+	if scope == "" || !oauth.ScopeAllowed(oauth.ParseScope(subject.Scope), scope) {
+		return types.Tokens{}, ErrInvalidScope
+	}
+	if !oauth.ScopeAllowed(slices.Concat(client.Scopes, oauth.StandardScopes), scope) {
+		return types.Tokens{}, ErrInvalidScope
+	}
+	exchange.GrantedScope = scope
+
+	ttl := o.exchangeTokenTTL
+	if remaining := time.Until(subject.ExpiresAt.Time); remaining < ttl {
+		ttl = remaining
+	}
+
+	tokenId := uuid.NewString()
+	accessToken, err := o.user.tokenManager.NewJWT(auth.TokenParams{
+		TokenId:   tokenId,
+		SessionId: subject.SessionId,
+		UserId:    subject.Subject,
+		IP:        subject.IP,
+		ClientId:  client.ClientId,
+		Scope:     scope,
+		Act:       act,
+	}, ttl)
+	if err != nil {
+		logger.Errorf("failed to create exchanged access token: %s", err)
+		return types.Tokens{}, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	exchange.TokenId = tokenId
+	exchange.ExpiresAt = &expiresAt
+
+	return types.Tokens{
+		AccessToken: accessToken,
+		TokenType:   oauth.TokenTypeAccessToken,
+		ExpiresIn:   ttl,
+		Scope:       scope,
+	}, nil
+}
+
+// exchangeAudienceAllowed reports whether the client may exchange a token issued to
+// tokenClientId: its own tokens or those of the clients in its ExchangeAudiences.
+func exchangeAudienceAllowed(client *types.OAuthClient, tokenClientId string) bool {
+	if tokenClientId == "" {
+		return false
+	}
+	return tokenClientId == client.ClientId || slices.Contains(client.ExchangeAudiences, tokenClientId)
+}
+
+func (o *OAuth) parseExchangedToken(token, tokenType string) (*auth.TokenClaims, error) {
+	if tokenType != oauth.TokenTypeAccessToken && tokenType != oauth.TokenTypeJWT {
+		return nil, ErrUnsupportedTokenType
+	}
+
+	return o.user.tokenManager.ParseAccessToken(token)
+}
+
+// checkExchangedSession rejects tokens of revoked sessions, as Identify does, so that signing
+// out or revoking a session also stops its tokens from being exchanged.
+func (o *OAuth) checkExchangedSession(ctx context.Context, claims *auth.TokenClaims) error {
+	if claims.SessionId == "" {
+		return nil
+	}
+
+	session, err := o.user.sessionrepo.GetSessionById(ctx, claims.SessionId)
+	if err != nil {
+		logger.Errorf("failed to get session by id: %s", err)
+		return err
+	}
+	if session == nil || session.IsRevoked() {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// joinSessionError marks a revoked session as an invalid token, other errors are returned as is.
+func joinSessionError(tokenErr error, err error) error {
+	if errors.Is(err, ErrSessionRevoked) {
+		return errors.Join(tokenErr, err)
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/auth"
+	"medods-test/pkg/oauth"
+	"testing"
+	"time"
+)
+
+const supportUserId = "9d3e4f5a-6b7c-4d8e-8f9a-0b1c2d3e4f5a"
+
+var reportsClient = types.OAuthClient{
+	ClientId:          "reports",
+	Type:              types.ClientTypeConfidential,
+	Scopes:            []string{"profile"},
+	GrantTypes:        []string{oauth.GrantTypeTokenExchange},
+	ExchangeAudiences: []string{"web"},
+}
+
+func TestExchangeToken(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		subject auth.TokenParams
+		// actor is the actor token, none if nil.
+		actor   *auth.TokenParams
+		revoked []string
+		wantErr []error
+	}{
+		{
+			name:    "token of an audience client",
+			subject: auth.TokenParams{SessionId: "s-user", UserId: testUserId, ClientId: "web", Scope: "profile"},
+		},
+		{
+			name:    "token of a revoked session",
+			subject: auth.TokenParams{SessionId: "s-user", UserId: testUserId, ClientId: "web", Scope: "profile"},
+			revoked: []string{"s-user"},
+			wantErr: []error{ErrInvalidSubjectToken, ErrSessionRevoked},
+		},
+		{
+			name:    "token of an unknown session",
+			subject: auth.TokenParams{SessionId: "s-unknown", UserId: testUserId, ClientId: "web", Scope: "profile"},
+			wantErr: []error{ErrInvalidSubjectToken, ErrSessionRevoked},
+		},
+		{
+			name:    "token of another client",
+			subject: auth.TokenParams{SessionId: "s-user", UserId: testUserId, ClientId: "mobile", Scope: "profile"},
+			wantErr: []error{ErrAudienceNotAllowed},
+		},
+		{
+			name:    "actor of the client",
+			subject: auth.TokenParams{SessionId: "s-user", UserId: testUserId, ClientId: "web", Scope: "profile"},
+			actor:   &auth.TokenParams{SessionId: "s-support", UserId: supportUserId, ClientId: "reports", Scope: oauth.ScopeImpersonation},
+		},
+		{
+			name:    "actor of an audience client",
+			subject: auth.TokenParams{SessionId: "s-user", UserId: testUserId, ClientId: "web", Scope: "profile"},
+			actor:   &auth.TokenParams{SessionId: "s-support", UserId: supportUserId, ClientId: "web", Scope: oauth.ScopeImpersonation},
+		},
+		{
+			name:    "actor of another client",
+			subject: auth.TokenParams{SessionId: "s-user", UserId: testUserId, ClientId: "web", Scope: "profile"},
+			actor:   &auth.TokenParams{SessionId: "s-support", UserId: supportUserId, ClientId: "mobile", Scope: oauth.ScopeImpersonation},
+			wantErr: []error{ErrActorAudienceNotAllowed},
+		},
+		{
+			name:    "actor of a first-party session",
+			subject: auth.TokenParams{SessionId: "s-user", UserId: testUserId, ClientId: "web", Scope: "profile"},
+			actor:   &auth.TokenParams{SessionId: "s-support", UserId: supportUserId, Scope: oauth.ScopeImpersonation},
+			wantErr: []error{ErrActorAudienceNotAllowed},
+		},
+		{
+			name:    "actor of a revoked session",
+			subject: auth.TokenParams{SessionId: "s-user", UserId: testUserId, ClientId: "web", Scope: "profile"},
+			actor:   &auth.TokenParams{SessionId: "s-support", UserId: supportUserId, ClientId: "reports", Scope: oauth.ScopeImpersonation},
+			revoked: []string{"s-support"},
+			wantErr: []error{ErrInvalidActorToken, ErrSessionRevoked},
+		},
+		{
+			name:    "actor without impersonation",
+			subject: auth.TokenParams{SessionId: "s-user", UserId: testUserId, ClientId: "web", Scope: "profile"},
+			actor:   &auth.TokenParams{SessionId: "s-support", UserId: supportUserId, ClientId: "reports", Scope: "profile"},
+			wantErr: []error{ErrActorNotAllowed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, sessions := newTestOAuth(t, newFakeOAuthRepo(reportsClient))
+			exchanges := &fakeTokenExchangeRepo{}
+			o.exchangerepo = exchanges
+			sessions.sessions = []types.Session{
+				{SessionId: "s-user", UserId: testUserId, ClientId: "web"},
+				{SessionId: "s-support", UserId: supportUserId},
+			}
+			for i, session := range sessions.sessions {
+				for _, revoked := range tt.revoked {
+					if session.SessionId == revoked {
+						sessions.sessions[i].RevokedAt = &now
+					}
+				}
+			}
+
+			subjectToken, err := o.user.tokenManager.NewJWT(tt.subject, time.Minute)
+			if err != nil {
+				t.Fatalf("NewJWT() error = %v", err)
+			}
+			req := types.TokenRequest{
+				GrantType: oauth.GrantTypeTokenExchange,
+				ClientId:  reportsClient.ClientId,
+				Exchange: types.TokenExchangeRequest{
+					SubjectToken:     subjectToken,
+					SubjectTokenType: oauth.TokenTypeAccessToken,
+				},
+			}
+			if tt.actor != nil {
+				actorToken, err := o.user.tokenManager.NewJWT(*tt.actor, time.Minute)
+				if err != nil {
+					t.Fatalf("NewJWT() error = %v", err)
+				}
+				req.Exchange.ActorToken = actorToken
+				req.Exchange.ActorTokenType = oauth.TokenTypeAccessToken
+			}
+
+			tokens, err := o.exchangeToken(context.Background(), &reportsClient, req, "203.0.113.1")
+			for _, wantErr := range tt.wantErr {
+				if !errors.Is(err, wantErr) {
+					t.Errorf("exchangeToken() error = %v, want %v", err, wantErr)
+				}
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("exchangeToken() error = %v", err)
+			}
+
+			if len(exchanges.exchanges) != 1 || exchanges.exchanges[0].Success != (err == nil) {
+				t.Fatalf("exchange records = %+v, want one with success %v", exchanges.exchanges, err == nil)
+			}
+			if err != nil {
+				return
+			}
+
+			claims, err := o.user.tokenManager.ParseAccessToken(tokens.AccessToken)
+			if err != nil {
+				t.Fatalf("ParseAccessToken() error = %v", err)
+			}
+			wantActor := reportsClient.ClientId
+			if tt.actor != nil {
+				wantActor = tt.actor.UserId
+			}
+			if claims.Subject != testUserId || claims.ClientId != reportsClient.ClientId || claims.Act == nil || claims.Act.Subject != wantActor {
+				t.Errorf("exchanged token of %s for %s acted by %+v, want %s for %s acted by %s", claims.Subject, claims.ClientId, claims.Act, testUserId, reportsClient.ClientId, wantActor)
+			}
+		})
+	}
+}
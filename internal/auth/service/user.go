@@ -11,48 +11,129 @@ import (
 	"medods-test/pkg/email"
 	"medods-test/pkg/hash"
 	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"medods-test/pkg/passwordpolicy"
 	"time"
 )
 
 var (
+	ErrInvalidAccessToken      = errors.New("invalid access token")
 	ErrUserNotFound            = errors.New("user not found")
 	ErrUserAlreadyExists       = errors.New("user already exists")
 	ErrSessionNotFound         = errors.New("session not found")
 	ErrRefreshTokenExpired     = errors.New("refresh token is expired")
 	ErrInvalidRefreshToken     = errors.New("invalid refresh token")
 	ErrRefreshTokenAlreadyUsed = errors.New("refresh token already used")
+	ErrSessionRevoked          = errors.New("session is revoked")
+	ErrUserDisabled            = errors.New("user is disabled")
+	ErrUserBanned              = errors.New("user is banned")
+	ErrUserPendingDeletion     = errors.New("user is pending deletion")
+	ErrInvalidUserStatus       = errors.New("invalid user status")
+	ErrPasswordResetRequired   = errors.New("password reset is required")
+	ErrInvalidResetToken       = errors.New("invalid or expired password reset token")
 )
 
 type UserRepo interface {
 	Create(ctx context.Context, user types.User) error
-	GetUserByCreds(ctx context.Context, email string, password string) (*types.User, error)
+	GetUserByCreds(ctx context.Context, email string, password string, organizationId string) (*types.User, error)
 	GetUserByID(ctx context.Context, userId string) (*types.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*types.User, error)
+	GetUserByLogin(ctx context.Context, email string, organizationId string) (*types.User, error)
+	ListUsers(ctx context.Context, filter types.UserFilter) ([]types.User, int, error)
+	SetStatus(ctx context.Context, change types.UserStatusChange) (bool, error)
+	PurgeUsers(ctx context.Context, limit int) ([]string, error)
+	RequirePasswordReset(ctx context.Context, token types.PasswordResetToken) error
+	GetUserByResetToken(ctx context.Context, tokenHash string) (*types.User, error)
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (string, error)
+	ChangePassword(ctx context.Context, change types.PasswordChange) error
+	CreateEmailChange(ctx context.Context, token types.EmailChangeToken) error
+	ConfirmEmailChange(ctx context.Context, userId string, tokenHash string) (string, error)
+	ListPasswordHistory(ctx context.Context, userId string, limit int, since time.Time) ([]string, error)
+	AddPasswordHistory(ctx context.Context, userId string, passwordHash string, keep int) error
+	DeletePasswordHistory(ctx context.Context, before time.Time) (int64, error)
+	CreateDeletionRequest(ctx context.Context, token types.AccountDeletionToken) error
+	EraseUser(ctx context.Context, userId string, tokenHash string) (bool, error)
 }
 
-type User struct {
-	userrepo    UserRepo
-	sessionrepo SessionRepo
-
-	hasher       hash.PasswordHasher
-	tokenManager auth.TokenManager
-	smtp         email.Sender
+// PasswordPolicy validates new passwords. Violated rules are reported as *passwordpolicy.PolicyError.
+type PasswordPolicy interface {
+	Check(ctx context.Context, password string, email string) error
+}
 
+type User struct {
+	userrepo     UserRepo
+	sessionrepo  SessionRepo
+	orgrepo      OrganizationRepo
+	loginrepo    LoginHistoryRepo
+	identityrepo IdentityRepo
+
+	hasher        hash.PasswordHasher
+	policy        PasswordPolicy
+	tokenManager  auth.TokenManager
+	smtp          email.Sender
+	verifiers     map[string]CredentialVerifier
+	roles         *RBAC
+	audit         *Audit
+	notifications *Notifications
+	locator       GeoLocator
+	risk          *RiskEngine
+	mfarepo       MFARepo
+	devicerepo    KnownDeviceRepo
+	phonerepo     PhoneRepo
+	// channels are the channels messages can be sent through by name, SMS is missing if not configured.
+	channels map[string]Channel
+
+	emailUniqueness string
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
+	emailChangeTTL  time.Duration
+	deviceCookieTTL time.Duration
+	deviceTrustTTL  time.Duration
+
+	passwordHistorySize      int
+	passwordHistoryRetention time.Duration
+	loginHistoryRetention    time.Duration
+}
+
+// userOrganization returns the organization that scopes the user's email, if emails are unique per organization.
+func (u *User) userOrganization(organizationId string) string {
+	if u.emailUniqueness != types.EmailUniquenessOrganization {
+		return ""
+	}
+	return organizationId
 }
 
 func (u *User) SignUp(ctx context.Context, input types.UserDTO) error {
+	// Users of directory domains are provisioned by their first sign-in.
+	if u.verifierFor(input.Email) != nil {
+		return ErrExternalCredentials
+	}
+	if err := u.checkPasswordPolicy(ctx, input.Password, input.Email); err != nil {
+		return err
+	}
+
 	passwordHash, err := u.hasher.Hash(input.Password)
 	if err != nil {
 		logger.Errorf("failed to hash password: %s", err)
 		return err
 	}
 
-	userUUID := uuid.NewString()
 	user := types.User{
-		UserUUID: userUUID,
-		Email:    input.Email,
-		Password: passwordHash,
+		UserUUID:       uuid.NewString(),
+		Email:          input.Email,
+		Password:       passwordHash,
+		OrganizationId: u.userOrganization(input.OrganizationId),
+	}
+
+	if user.OrganizationId != "" {
+		org, err := u.orgrepo.GetOrganizationByID(ctx, user.OrganizationId)
+		if err != nil {
+			logger.Errorf("failed to get organization: %s", err)
+			return err
+		}
+		if org == nil {
+			return ErrOrganizationNotFound
+		}
 	}
 
 	if err = u.userrepo.Create(ctx, user); err != nil {
@@ -63,101 +144,358 @@ func (u *User) SignUp(ctx context.Context, input types.UserDTO) error {
 		return err
 
 	}
+
+	u.audit.Record(ctx, types.AuditUserSignedUp, user.UserUUID, nil)
 	return nil
 }
 
+// provisionUser creates a user that signs in through an external identity provider.
+// The password is random, so the user cannot sign in with a password until it is reset.
+func (u *User) provisionUser(ctx context.Context, email string) (*types.User, error) {
+	password, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate password: %s", err)
+		return nil, err
+	}
+
+	passwordHash, err := u.hasher.Hash(password)
+	if err != nil {
+		logger.Errorf("failed to hash password: %s", err)
+		return nil, err
+	}
+
+	user := types.User{
+		UserUUID: uuid.NewString(),
+		Email:    email,
+		Password: passwordHash,
+		Status:   types.UserStatusActive,
+	}
+
+	if err = u.userrepo.Create(ctx, user); err != nil {
+		logger.Errorf("failed to create user: %s", err)
+		if errors.Is(err, postgres.ErrUniqueContraintFailed) {
+			return nil, ErrUserAlreadyExists
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (u *User) SingIn(ctx context.Context, input types.UserDTO, IP string) (types.Tokens, error) {
+	user, err := u.Authenticate(ctx, input)
+	if err != nil {
+		var mfa *signInMFAError
+		if errors.As(err, &mfa) {
+			return types.Tokens{}, u.startMFAChallenge(ctx, mfa.user, input.OrganizationId, types.LoginMethodPassword)
+		}
+		return types.Tokens{}, err
+	}
+
+	return u.CreateSession(ctx, user.UserUUID, input.OrganizationId, IP)
+}
+
+// Authenticate checks user credentials and the risk of the sign-in and returns the matching
+// user. A sign-in that needs a second factor fails with ErrMFARequired. Both the successful
+// and the failed attempts are recorded in the audit log and the login history of the user.
+func (u *User) Authenticate(ctx context.Context, input types.UserDTO) (*types.User, error) {
+	user, err := u.authenticate(ctx, input)
+	if err == nil {
+		err = u.checkSignInRisk(ctx, user)
+	}
+	if err != nil {
+		if user == nil {
+			user = u.attemptedUser(ctx, input.Email)
+		}
+
+		metadata := map[string]string{"email": input.Email, "reason": err.Error()}
+		userId := ""
+		if user != nil {
+			userId = user.UserUUID
+			u.recordLoginAttempt(ctx, userId, types.LoginMethodPassword, err)
+		}
+		u.audit.Record(ctx, types.AuditUserSignInFailed, userId, metadata)
+		return nil, err
+	}
+
+	u.recordLoginAttempt(ctx, user.UserUUID, types.LoginMethodPassword, nil)
+	u.audit.Record(ctx, types.AuditUserSignedIn, user.UserUUID, map[string]string{"method": types.LoginMethodPassword})
+	return user, nil
+}
+
+func (u *User) authenticate(ctx context.Context, input types.UserDTO) (*types.User, error) {
+	if verifier := u.verifierFor(input.Email); verifier != nil {
+		return u.authenticateExternal(ctx, verifier, input)
+	}
+
 	password, err := u.hasher.Hash(input.Password)
 	if err != nil {
 		logger.Errorf("failed to hash password: %s", err)
-		return types.Tokens{}, err
+		return nil, err
 	}
 
-	user, err := u.userrepo.GetUserByCreds(ctx, input.Email, password)
+	user, err := u.userrepo.GetUserByCreds(ctx, input.Email, password, u.userOrganization(input.OrganizationId))
 	if err != nil {
 		logger.Errorf("failed to get user: %s", err)
-		return types.Tokens{}, err
+		return nil, err
 	}
 
 	if user == nil {
-		return types.Tokens{}, ErrUserNotFound
+		return nil, ErrUserNotFound
+	}
+	if user.PasswordResetRequired {
+		return user, ErrPasswordResetRequired
 	}
-	return u.CreateSession(ctx, user.UserUUID, IP)
+	return user, checkUserActive(user)
 }
 
-func (u *User) CreateSession(ctx context.Context, userId string, IP string) (types.Tokens, error) {
-	var (
-		tokens types.Tokens
-		err    error
-	)
+func checkUserActive(user *types.User) error {
+	switch user.Status {
+	case types.UserStatusActive:
+		return nil
+	case types.UserStatusBanned:
+		return ErrUserBanned
+	case types.UserStatusPendingDeletion:
+		return ErrUserPendingDeletion
+	default:
+		return ErrUserDisabled
+	}
+}
 
-	sessionId := uuid.NewString()
+// checkUserActiveError reports whether err was returned by checkUserActive.
+func checkUserActiveError(err error) bool {
+	return errors.Is(err, ErrUserDisabled) ||
+		errors.Is(err, ErrUserBanned) ||
+		errors.Is(err, ErrUserPendingDeletion)
+}
+
+// ResetPassword sets a new password with a token from the password reset email.
+func (u *User) ResetPassword(ctx context.Context, token string, password string) error {
+	tokenHash := oauth.HashCode(token)
 
-	tokens.AccessToken, err = u.tokenManager.NewJWT(sessionId, userId, IP, u.accessTokenTTL)
+	user, err := u.userrepo.GetUserByResetToken(ctx, tokenHash)
 	if err != nil {
-		logger.Errorf("failed to create new access token: %s", err)
-		return tokens, err
+		logger.Errorf("failed to get user by reset token: %s", err)
+		return err
+	}
+	if user == nil {
+		return ErrInvalidResetToken
+	}
+
+	if err = u.checkPasswordPolicy(ctx, password, user.Email); err != nil {
+		return err
 	}
 
-	tokens.RefreshToken, err = u.tokenManager.NewRefreshToken()
+	passwordHash, err := u.hasher.Hash(password)
 	if err != nil {
-		logger.Errorf("failed to create new refresh token: %s", err)
-		return tokens, err
+		logger.Errorf("failed to hash password: %s", err)
+		return err
 	}
 
-	hashToken, err := u.tokenManager.HashToken(tokens.RefreshToken)
+	if err = u.checkPasswordReuse(ctx, user, passwordHash); err != nil {
+		return err
+	}
+
+	userId, err := u.userrepo.ResetPassword(ctx, tokenHash, passwordHash)
+	if err != nil {
+		logger.Errorf("failed to reset password: %s", err)
+		return err
+	}
+	if userId == "" {
+		return ErrInvalidResetToken
+	}
+
+	u.rememberPassword(ctx, user)
+	u.audit.Record(ctx, types.AuditPasswordReset, user.UserUUID, nil)
+	return nil
+}
+
+// checkPasswordPolicy returns a *passwordpolicy.PolicyError if password of the user with email
+// violates the password policy.
+func (u *User) checkPasswordPolicy(ctx context.Context, password string, email string) error {
+	err := u.policy.Check(ctx, password, email)
+	if err != nil {
+		var policyErr *passwordpolicy.PolicyError
+		if !errors.As(err, &policyErr) {
+			logger.Errorf("failed to check password policy: %s", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Identify authenticates the caller by a non-expired access token.
+func (u *User) Identify(ctx context.Context, accessToken string) (*types.Identity, error) {
+	claims, err := u.tokenManager.ParseAccessToken(accessToken)
+	if err != nil {
+		logger.Errorf("failed to parse access token: %s", err)
+		return nil, ErrInvalidAccessToken
+	}
+
+	identity := &types.Identity{
+		SessionId: claims.SessionId,
+		UserId:    claims.Subject,
+		IP:        claims.IP,
+		ClientId:  claims.ClientId,
+		Scope:     claims.Scope,
+		Roles:     claims.Roles,
+		OrgId:     claims.OrgId,
+		OrgRoles:  claims.OrgRoles,
+	}
+	if claims.SessionId == "" && claims.Subject == claims.ClientId {
+		identity.UserId = ""
+	}
+	if claims.Act != nil {
+		identity.Actor = claims.Act.Subject
+	}
+
+	if claims.SessionId != "" {
+		session, err := u.sessionrepo.GetSessionById(ctx, claims.SessionId)
+		if err != nil {
+			logger.Errorf("failed to get session by id: %s", err)
+			return nil, err
+		}
+		if session == nil || session.IsRevoked() {
+			return nil, ErrInvalidAccessToken
+		}
+	}
+
+	return identity, nil
+}
+
+// CreateSession issues a first-party session. If orgId is set, the user must be a member
+// of that organization, and the tokens carry it with the user's roles there.
+func (u *User) CreateSession(ctx context.Context, userId string, orgId string, IP string) (types.Tokens, error) {
+	return u.StartSession(ctx, types.SessionParams{
+		UserId:         userId,
+		IP:             IP,
+		OrganizationId: orgId,
+	})
+}
+
+// StartSession issues a new token pair for params and stores its session.
+func (u *User) StartSession(ctx context.Context, params types.SessionParams) (types.Tokens, error) {
+	tokens, session, err := u.newSession(ctx, params)
 	if err != nil {
-		logger.Errorf("failed to hash refresh token: %s", err)
 		return tokens, err
 	}
-	session := types.Session{
-		SessionId:    sessionId,
-		UserId:       userId,
-		RefreshToken: hashToken,
-		ExpiresAt:    time.Now().Add(u.refreshTokenTTL),
+
+	if err = u.sessionrepo.CreateSession(ctx, session); err != nil {
+		logger.Errorf("failed to create session: %s", err)
+		return types.Tokens{}, err
 	}
+	return tokens, nil
+}
 
-	err = u.sessionrepo.CreateSession(ctx, session)
+func (u *User) CreateNewSessionAndSetOldUsed(ctx context.Context, params types.SessionParams, usedSessionId string) (types.Tokens, error) {
+	tokens, session, err := u.newSession(ctx, params)
+	if err != nil {
+		return tokens, err
+	}
 
-	logger.Errorf("failed to create session: %s", err)
-	return tokens, err
+	if err = u.sessionrepo.CreateAndSetUsed(ctx, session, usedSessionId); err != nil {
+		logger.Errorf("failed to rotate session: %s", err)
+		return types.Tokens{}, err
+	}
+	return tokens, nil
 }
 
-func (u *User) CreateNewSessionAndSetOldUsed(ctx context.Context, userId string, IP string, usedSessionId string) (types.Tokens, error) {
+func (u *User) newSession(ctx context.Context, params types.SessionParams) (types.Tokens, types.Session, error) {
 	var (
-		tokens types.Tokens
-		err    error
+		tokens   types.Tokens
+		roles    []string
+		orgRoles []string
+		err      error
 	)
 
-	sessionId := uuid.NewString()
+	// Global roles grant access to the admin API, so only sessions of the service itself carry
+	// them, never the tokens of OAuth clients.
+	if params.UserId != "" && params.ClientId == "" {
+		if roles, err = u.roles.RoleNames(ctx, params.UserId); err != nil {
+			return tokens, types.Session{}, err
+		}
+	}
+
+	if params.OrganizationId != "" {
+		if orgRoles, err = u.organizationRoles(ctx, params.OrganizationId, params.UserId); err != nil {
+			return tokens, types.Session{}, err
+		}
+	}
+
+	sessionId := params.SessionId
+	if sessionId == "" {
+		sessionId = uuid.NewString()
+	}
 
-	tokens.AccessToken, err = u.tokenManager.NewJWT(sessionId, userId, IP, u.accessTokenTTL)
+	tokens.AccessToken, err = u.tokenManager.NewJWT(auth.TokenParams{
+		SessionId: sessionId,
+		UserId:    params.UserId,
+		IP:        params.IP,
+		ClientId:  params.ClientId,
+		Scope:     params.Scope,
+		Roles:     roles,
+		OrgId:     params.OrganizationId,
+		OrgRoles:  orgRoles,
+	}, u.accessTokenTTL)
 	if err != nil {
 		logger.Errorf("failed to create new access token: %s", err)
-		return tokens, err
+		return tokens, types.Session{}, err
 	}
 
-	tokens.RefreshToken, err = u.tokenManager.NewRefreshToken()
+	secret, err := u.tokenManager.NewRefreshToken()
 	if err != nil {
-		logger.Errorf("failed to create refresh token: %s", err)
-		return tokens, err
+		logger.Errorf("failed to create new refresh token: %s", err)
+		return tokens, types.Session{}, err
+	}
+
+	tokens.RefreshToken, err = newRefreshToken(sessionId, secret)
+	if err != nil {
+		logger.Errorf("failed to create new refresh token: %s", err)
+		return tokens, types.Session{}, err
 	}
 
 	hashToken, err := u.tokenManager.HashToken(tokens.RefreshToken)
 	if err != nil {
 		logger.Errorf("failed to hash refresh token: %s", err)
-		return tokens, err
+		return tokens, types.Session{}, err
 	}
+
+	tokens.ExpiresIn = u.accessTokenTTL
+	tokens.Scope = params.Scope
+
 	session := types.Session{
-		SessionId:    sessionId,
-		UserId:       userId,
-		RefreshToken: hashToken,
-		ExpiresAt:    time.Now().Add(u.refreshTokenTTL),
+		SessionId:      sessionId,
+		UserId:         params.UserId,
+		RefreshToken:   hashToken,
+		ExpiresAt:      time.Now().Add(u.refreshTokenTTL),
+		ClientId:       params.ClientId,
+		Scope:          params.Scope,
+		OrganizationId: params.OrganizationId,
+		IP:             params.IP,
+	}
+	if location, ok := u.locator.Locate(params.IP); ok {
+		session.Country = location.CountryCode
+		session.City = location.City
+		session.ASN = int64(location.ASN)
+	}
+
+	return tokens, session, nil
+}
+
+func (u *User) organizationRoles(ctx context.Context, orgId string, userId string) ([]string, error) {
+	if uuid.Validate(orgId) != nil {
+		return nil, ErrNotOrganizationMember
 	}
 
-	err = u.sessionrepo.CreateAndSetUsed(ctx, session, usedSessionId)
-	logger.Error(err)
-	return tokens, err
+	membership, err := u.orgrepo.GetMembership(ctx, orgId, userId)
+	if err != nil {
+		logger.Errorf("failed to get membership: %s", err)
+		return nil, err
+	}
+	if membership == nil {
+		return nil, ErrNotOrganizationMember
+	}
+	return membership.Roles, nil
 }
 
 func (u *User) RefreshTokens(ctx context.Context, newClientIP string, accessToken, refreshToken string) (types.Tokens, error) {
@@ -167,55 +505,139 @@ func (u *User) RefreshTokens(ctx context.Context, newClientIP string, accessToke
 		return types.Tokens{}, err
 	}
 
+	session, err := u.checkRefreshToken(ctx, sessionId, refreshToken)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	assessment := u.risk.AssessRefresh(ctx, session, newClientIP)
+	if err = u.checkRefreshRisk(ctx, session, assessment); err != nil {
+		return types.Tokens{}, err
+	}
+
+	tokens, err := u.CreateNewSessionAndSetOldUsed(ctx, types.SessionParams{
+		UserId:         userId,
+		IP:             newClientIP,
+		ClientId:       session.ClientId,
+		Scope:          session.Scope,
+		OrganizationId: session.OrganizationId,
+	}, session.SessionId)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+	u.audit.Record(ctx, types.AuditTokenRefreshed, userId, map[string]string{"session_id": session.SessionId})
+
+	if oldClientIP != newClientIP {
+		u.audit.Record(ctx, types.AuditSessionIPChanged, userId, map[string]string{
+			"session_id": session.SessionId,
+			"old_ip":     oldClientIP,
+			"new_ip":     newClientIP,
+		})
+		u.notifyIPChanged(ctx, userId, oldClientIP, newClientIP)
+	}
+	return tokens, nil
+}
+
+// RefreshSession rotates the session identified by the refresh token alone, as
+// required by the OAuth refresh_token grant. The session must belong to clientId.
+func (u *User) RefreshSession(ctx context.Context, newClientIP string, clientId string, refreshToken string) (types.Tokens, error) {
+	sessionId, ok := sessionIdFromRefreshToken(refreshToken)
+	if !ok {
+		return types.Tokens{}, ErrInvalidRefreshToken
+	}
+
+	session, err := u.checkRefreshToken(ctx, sessionId, refreshToken)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	if session.ClientId != clientId {
+		return types.Tokens{}, ErrInvalidRefreshToken
+	}
+
+	assessment := u.risk.AssessRefresh(ctx, session, newClientIP)
+	if err = u.checkRefreshRisk(ctx, session, assessment); err != nil {
+		return types.Tokens{}, err
+	}
+
+	tokens, err := u.CreateNewSessionAndSetOldUsed(ctx, types.SessionParams{
+		UserId:         session.UserId,
+		IP:             newClientIP,
+		ClientId:       session.ClientId,
+		Scope:          session.Scope,
+		OrganizationId: session.OrganizationId,
+	}, session.SessionId)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	u.audit.Record(ctx, types.AuditTokenRefreshed, session.UserId, map[string]string{
+		"session_id": session.SessionId,
+		"client_id":  clientId,
+	})
+	if session.IP != newClientIP {
+		u.notifyIPChanged(ctx, session.UserId, session.IP, newClientIP)
+	}
+	return tokens, nil
+}
+
+// SignOut revokes the session of identity.
+func (u *User) SignOut(ctx context.Context, identity types.Identity) error {
+	revoked, err := u.sessionrepo.RevokeSession(ctx, identity.UserId, identity.SessionId)
+	if err != nil {
+		logger.Errorf("failed to revoke session: %s", err)
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	u.audit.Record(ctx, types.AuditUserSignedOut, identity.UserId, map[string]string{"session_id": identity.SessionId})
+	return nil
+}
+
+func (u *User) checkRefreshToken(ctx context.Context, sessionId string, refreshToken string) (*types.Session, error) {
 	session, err := u.sessionrepo.GetSessionById(ctx, sessionId)
 	if err != nil {
 		logger.Errorf("failed to get session by id: %s", err)
-		return types.Tokens{}, err
+		return nil, err
 	}
 	if session == nil {
-		return types.Tokens{}, ErrSessionNotFound
+		return nil, ErrSessionNotFound
 	}
 
 	if err = bcrypt.CompareHashAndPassword([]byte(session.RefreshToken), []byte(refreshToken)); err != nil {
 		logger.Error(err)
-		return types.Tokens{}, ErrInvalidRefreshToken
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if session.IsRevoked() {
+		logger.Error(ErrSessionRevoked)
+		return nil, ErrSessionRevoked
 	}
 
 	if session.Used {
 		logger.Error(ErrRefreshTokenAlreadyUsed)
-		return types.Tokens{}, ErrRefreshTokenAlreadyUsed
+		u.audit.Record(ctx, types.AuditTokenReuseDetected, session.UserId, map[string]string{"session_id": session.SessionId})
+		return nil, ErrRefreshTokenAlreadyUsed
 	}
 
 	if session.IsRefreshTokenExpired() {
 		logger.Error(ErrRefreshTokenExpired)
-		return types.Tokens{}, ErrRefreshTokenExpired
+		return nil, ErrRefreshTokenExpired
 	}
 
-	tokens, err := u.CreateNewSessionAndSetOldUsed(ctx, userId, newClientIP, session.SessionId)
+	user, err := u.userrepo.GetUserByID(ctx, session.UserId)
 	if err != nil {
-		return types.Tokens{}, err
+		logger.Errorf("failed to get user by id: %s", err)
+		return nil, err
 	}
-
-	if oldClientIP != newClientIP {
-		user, err := u.userrepo.GetUserByID(ctx, userId)
-		if err != nil {
-			logger.Errorf("failed to get user by id: %s", err.Error())
-			return types.Tokens{}, err
-		}
-
-		send := email.Send{
-			Recipient: user.Email,
-			Subject:   "Внимание!",
-			Body: `<h1>Смена IP-адреса</h1>
-<p>Мы заметили, что ваш IP-адрес изменился.</p>
-<p>Если вы не осуществляли вход с этого IP, пожалуйста, свяжитесь с нашей службой поддержки или смените пароль</p>
-<p>С уважением,<br>Команда поддержки</p>`,
-		}
-		if err = u.smtp.Send(send); err != nil {
-			logger.Errorf("failed to send email warning: %s", err.Error())
-			return types.Tokens{}, err
-		}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err = checkUserActive(user); err != nil {
+		return nil, err
 	}
 
-	return tokens, nil
+	return session, nil
 }
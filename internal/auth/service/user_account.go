@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html"
+	"medods-test/internal/auth/repo/postgres"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/email"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidPassword         = errors.New("current password is incorrect")
+	ErrExternalCredentials     = errors.New("credentials are managed by an external directory")
+	ErrSameEmail               = errors.New("new email is the same as the current one")
+	ErrInvalidEmailChangeToken = errors.New("invalid or expired email change token")
+)
+
+// ChangePassword sets a new password after checking the current one. With revokeOtherSessions
+// every session of the user except the session of identity is revoked.
+func (u *User) ChangePassword(ctx context.Context, identity types.Identity, currentPassword, newPassword string, revokeOtherSessions bool) error {
+	user, err := u.checkPassword(ctx, identity.UserId, currentPassword)
+	if err != nil {
+		return err
+	}
+
+	if err = u.checkPasswordPolicy(ctx, newPassword, user.Email); err != nil {
+		return err
+	}
+
+	passwordHash, err := u.hasher.Hash(newPassword)
+	if err != nil {
+		logger.Errorf("failed to hash password: %s", err)
+		return err
+	}
+
+	if err = u.checkPasswordReuse(ctx, user, passwordHash); err != nil {
+		return err
+	}
+
+	if err = u.userrepo.ChangePassword(ctx, types.PasswordChange{
+		UserId:         user.UserUUID,
+		PasswordHash:   passwordHash,
+		RevokeSessions: revokeOtherSessions,
+		KeepSessionId:  identity.SessionId,
+	}); err != nil {
+		logger.Errorf("failed to change password: %s", err)
+		return err
+	}
+	u.rememberPassword(ctx, user)
+	u.audit.Record(ctx, types.AuditPasswordChanged, user.UserUUID, map[string]string{
+		"revoke_other_sessions": strconv.FormatBool(revokeOtherSessions),
+	})
+
+	u.notifications.Notify(ctx, user, types.Notice{
+		Event:   types.NotificationPasswordChanged,
+		Subject: "Пароль изменён",
+		Body: `<p>Пароль вашей учётной записи был изменён.</p>
+<p>Если вы этого не делали, пожалуйста, свяжитесь с нашей службой поддержки.</p>`,
+		Text: "Пароль вашей учётной записи изменён. Если это были не вы, свяжитесь с поддержкой.",
+	})
+
+	return nil
+}
+
+// RequestEmailChange emails a confirmation token to the new address and a notice to the
+// current one. The email changes only after ConfirmEmailChange.
+func (u *User) RequestEmailChange(ctx context.Context, identity types.Identity, password, newEmail string) error {
+	user, err := u.checkPassword(ctx, identity.UserId, password)
+	if err != nil {
+		return err
+	}
+	if newEmail == user.Email {
+		return ErrSameEmail
+	}
+	if u.verifierFor(newEmail) != nil {
+		return ErrExternalCredentials
+	}
+
+	if user.OrganizationId == "" {
+		existing, err := u.userrepo.GetUserByEmail(ctx, newEmail)
+		if err != nil {
+			logger.Errorf("failed to get user by email: %s", err)
+			return err
+		}
+		if existing != nil {
+			return ErrUserAlreadyExists
+		}
+	}
+
+	token, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate email change token: %s", err)
+		return err
+	}
+
+	changeToken := types.EmailChangeToken{
+		TokenHash: oauth.HashCode(token),
+		UserId:    user.UserUUID,
+		NewEmail:  newEmail,
+		ExpiresAt: time.Now().Add(u.emailChangeTTL),
+	}
+	if err = u.userrepo.CreateEmailChange(ctx, changeToken); err != nil {
+		logger.Errorf("failed to save email change: %s", err)
+		return err
+	}
+	u.audit.Record(ctx, types.AuditEmailChangeRequested, user.UserUUID, map[string]string{"new_email": newEmail})
+
+	confirmation := email.Send{
+		Recipient: newEmail,
+		Subject:   "Подтверждение email",
+		Body: fmt.Sprintf(`<h1>Подтверждение email</h1>
+<p>Чтобы сделать этот адрес email вашей учётной записи, отправьте код подтверждения:</p>
+<p><b>%s</b></p>
+<p>Код действует до %s.</p>
+<p>С уважением,<br>Команда поддержки</p>`, token, changeToken.ExpiresAt.Format("02.01.2006 15:04")),
+	}
+	if err = u.smtp.Send(confirmation); err != nil {
+		logger.Errorf("failed to send email change confirmation: %s", err.Error())
+		return err
+	}
+
+	u.notifications.Notify(ctx, user, types.Notice{
+		Event:   types.NotificationEmailChangeRequested,
+		Subject: "Смена email",
+		Body: fmt.Sprintf(`<p>Для вашей учётной записи запрошена смена email на %s.</p>
+<p>Если вы этого не делали, смените пароль и свяжитесь с нашей службой поддержки.</p>`, html.EscapeString(newEmail)),
+		Text: "Запрошена смена email вашей учётной записи. Если это были не вы, смените пароль.",
+	})
+
+	return nil
+}
+
+// ConfirmEmailChange completes the email change with the token sent to the new address.
+func (u *User) ConfirmEmailChange(ctx context.Context, identity types.Identity, token string) (string, error) {
+	newEmail, err := u.userrepo.ConfirmEmailChange(ctx, identity.UserId, oauth.HashCode(token))
+	if err != nil {
+		logger.Errorf("failed to confirm email change: %s", err)
+		if errors.Is(err, postgres.ErrUniqueContraintFailed) {
+			return "", ErrUserAlreadyExists
+		}
+		return "", err
+	}
+	if newEmail == "" {
+		return "", ErrInvalidEmailChangeToken
+	}
+
+	logger.Infof("user %s changed email", identity.UserId)
+	u.audit.Record(ctx, types.AuditEmailChanged, identity.UserId, map[string]string{"new_email": newEmail})
+	return newEmail, nil
+}
+
+// checkPassword returns the user if password is the current password of the user.
+func (u *User) checkPassword(ctx context.Context, userId string, password string) (*types.User, error) {
+	user, err := u.userrepo.GetUserByID(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get user by id: %s", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if u.verifierFor(user.Email) != nil {
+		return nil, ErrExternalCredentials
+	}
+
+	passwordHash, err := u.hasher.Hash(password)
+	if err != nil {
+		logger.Errorf("failed to hash password: %s", err)
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(passwordHash), []byte(user.Password)) != 1 {
+		return nil, ErrInvalidPassword
+	}
+
+	return user, nil
+}
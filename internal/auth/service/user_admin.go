@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/email"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultUsersPageSize = 50
+	maxUsersPageSize     = 500
+	purgeBatchSize       = 100
+)
+
+// UserAdmin lets operators manage user accounts.
+type UserAdmin struct {
+	userrepo    UserRepo
+	sessionrepo SessionRepo
+	loginrepo   LoginHistoryRepo
+	mfarepo     MFARepo
+	devicerepo  KnownDeviceRepo
+	phonerepo   PhoneRepo
+	audit       *Audit
+	smtp        email.Sender
+
+	passwordResetTTL time.Duration
+	purgeDelay       time.Duration
+
+	passwordHistoryRetention time.Duration
+	loginHistoryRetention    time.Duration
+	deviceRetention          time.Duration
+}
+
+func (a *UserAdmin) List(ctx context.Context, filter types.UserFilter) ([]types.User, int, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUsersPageSize
+	}
+	filter.Limit = min(filter.Limit, maxUsersPageSize)
+	filter.Offset = max(filter.Offset, 0)
+
+	users, total, err := a.userrepo.ListUsers(ctx, filter)
+	if err != nil {
+		logger.Errorf("failed to list users: %s", err)
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// Get returns the user and the user's active sessions.
+func (a *UserAdmin) Get(ctx context.Context, userId string) (*types.User, []types.Session, error) {
+	user, err := a.getUser(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sessions, err := a.sessionrepo.ListActiveSessions(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to list sessions: %s", err)
+		return nil, nil, err
+	}
+	return user, sessions, nil
+}
+
+// SetStatus changes the status of the user. A user that is not active loses all sessions.
+// Setting pending_deletion schedules the purge of the user after the purge delay, any
+// other status cancels a scheduled purge.
+func (a *UserAdmin) SetStatus(ctx context.Context, userId string, status string, reason string) error {
+	if !types.IsUserStatus(status) {
+		return ErrInvalidUserStatus
+	}
+	if uuid.Validate(userId) != nil {
+		return ErrUserNotFound
+	}
+
+	change := types.UserStatusChange{
+		UserId: userId,
+		Status: status,
+		Reason: reason,
+	}
+	if status == types.UserStatusPendingDeletion {
+		purgeAt := time.Now().Add(a.purgeDelay)
+		change.PurgeAt = &purgeAt
+	}
+
+	updated, err := a.userrepo.SetStatus(ctx, change)
+	if err != nil {
+		logger.Errorf("failed to update user status: %s", err)
+		return err
+	}
+	if !updated {
+		return ErrUserNotFound
+	}
+
+	logger.Infof("user %s status set to %s", userId, status)
+	a.audit.Record(ctx, types.AuditUserStatusChanged, userId, map[string]string{
+		"status": status,
+		"reason": reason,
+	})
+	return nil
+}
+
+// ForcePasswordReset revokes the sessions of the user, blocks the current password and
+// emails a token to set a new one.
+func (a *UserAdmin) ForcePasswordReset(ctx context.Context, userId string) error {
+	user, err := a.getUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	token, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate password reset token: %s", err)
+		return err
+	}
+
+	resetToken := types.PasswordResetToken{
+		TokenHash: oauth.HashCode(token),
+		UserId:    user.UserUUID,
+		ExpiresAt: time.Now().Add(a.passwordResetTTL),
+	}
+	if err = a.userrepo.RequirePasswordReset(ctx, resetToken); err != nil {
+		logger.Errorf("failed to require password reset: %s", err)
+		return err
+	}
+	a.audit.Record(ctx, types.AuditPasswordResetForced, user.UserUUID, nil)
+
+	send := email.Send{
+		Recipient: user.Email,
+		Subject:   "Сброс пароля",
+		Body: fmt.Sprintf(`<h1>Сброс пароля</h1>
+<p>Администратор потребовал сменить пароль вашей учётной записи. Все активные сессии завершены.</p>
+<p>Чтобы задать новый пароль, отправьте этот код вместе с новым паролем:</p>
+<p><b>%s</b></p>
+<p>Код действует до %s.</p>
+<p>С уважением,<br>Команда поддержки</p>`, token, resetToken.ExpiresAt.Format("02.01.2006 15:04")),
+	}
+	if err = a.smtp.Send(send); err != nil {
+		logger.Errorf("failed to send password reset email: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Delete soft deletes the user. The user is purged after the purge delay unless restored
+// with another status before that.
+func (a *UserAdmin) Delete(ctx context.Context, userId string, reason string) error {
+	return a.SetStatus(ctx, userId, types.UserStatusPendingDeletion, reason)
+}
+
+// Purge removes the users whose purge time has come and returns how many were removed.
+func (a *UserAdmin) Purge(ctx context.Context) (int, error) {
+	var purged int
+	for {
+		userIds, err := a.userrepo.PurgeUsers(ctx, purgeBatchSize)
+		if err != nil {
+			logger.Errorf("failed to purge users: %s", err)
+			return purged, err
+		}
+		purged += len(userIds)
+		for _, userId := range userIds {
+			logger.Infof("user %s purged", userId)
+			a.audit.Record(ctx, types.AuditAccountPurged, userId, nil)
+		}
+		if len(userIds) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// PurgePasswordHistory removes password hashes older than the password history retention.
+func (a *UserAdmin) PurgePasswordHistory(ctx context.Context) (int64, error) {
+	deleted, err := a.userrepo.DeletePasswordHistory(ctx, time.Now().Add(-a.passwordHistoryRetention))
+	if err != nil {
+		logger.Errorf("failed to purge password history: %s", err)
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// PurgeLoginHistory removes login attempts older than the login history retention.
+func (a *UserAdmin) PurgeLoginHistory(ctx context.Context) (int64, error) {
+	deleted, err := a.loginrepo.DeleteLoginAttempts(ctx, time.Now().Add(-a.loginHistoryRetention))
+	if err != nil {
+		logger.Errorf("failed to purge login history: %s", err)
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// PurgeMFAChallenges removes expired sign-in challenges.
+func (a *UserAdmin) PurgeMFAChallenges(ctx context.Context) (int64, error) {
+	deleted, err := a.mfarepo.DeleteExpiredChallenges(ctx)
+	if err != nil {
+		logger.Errorf("failed to purge mfa challenges: %s", err)
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// PurgeSMSCodes removes codes sent before the window they are counted in for the SMS limit,
+// they have expired long before.
+func (a *UserAdmin) PurgeSMSCodes(ctx context.Context) (int64, error) {
+	deleted, err := a.phonerepo.DeleteSMSCodes(ctx, time.Now().Add(-smsCodeWindow))
+	if err != nil {
+		logger.Errorf("failed to purge sms codes: %s", err)
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// PurgeKnownDevices removes devices not seen for the device retention. Their cookies have
+// expired, so they would not be recognized anyway.
+func (a *UserAdmin) PurgeKnownDevices(ctx context.Context) (int64, error) {
+	deleted, err := a.devicerepo.DeleteStaleDevices(ctx, time.Now().Add(-a.deviceRetention))
+	if err != nil {
+		logger.Errorf("failed to purge known devices: %s", err)
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// RunPurger purges deleted users, expired password and login history, expired sign-in
+// challenges and SMS codes and stale devices every interval until ctx is done.
+func (a *UserAdmin) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, _ = a.Purge(ctx)
+		_, _ = a.PurgePasswordHistory(ctx)
+		_, _ = a.PurgeLoginHistory(ctx)
+		_, _ = a.PurgeMFAChallenges(ctx)
+		_, _ = a.PurgeSMSCodes(ctx)
+		_, _ = a.PurgeKnownDevices(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RevokeSessions revokes all sessions of the user and returns how many were active.
+func (a *UserAdmin) RevokeSessions(ctx context.Context, userId string) (int64, error) {
+	if _, err := a.getUser(ctx, userId); err != nil {
+		return 0, err
+	}
+
+	revoked, err := a.sessionrepo.RevokeUserSessions(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to revoke sessions: %s", err)
+		return 0, err
+	}
+
+	a.audit.Record(ctx, types.AuditSessionRevoked, userId, map[string]string{"revoked": strconv.FormatInt(revoked, 10)})
+	return revoked, nil
+}
+
+func (a *UserAdmin) RevokeSession(ctx context.Context, userId string, sessionId string) error {
+	if uuid.Validate(userId) != nil || uuid.Validate(sessionId) != nil {
+		return ErrSessionNotFound
+	}
+
+	revoked, err := a.sessionrepo.RevokeSession(ctx, userId, sessionId)
+	if err != nil {
+		logger.Errorf("failed to revoke session: %s", err)
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	a.audit.Record(ctx, types.AuditSessionRevoked, userId, map[string]string{"session_id": sessionId})
+	return nil
+}
+
+func (a *UserAdmin) getUser(ctx context.Context, userId string) (*types.User, error) {
+	if uuid.Validate(userId) != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := a.userrepo.GetUserByID(ctx, userId)
+	if err != nil {
+		logger.Errorf("failed to get user: %s", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
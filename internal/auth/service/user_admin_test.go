@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/oauth"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestUserAdmin returns a UserAdmin for the users of testUserId and otherUserId, each with
+// an active session.
+func newTestUserAdmin() (*UserAdmin, *fakeUserRepo, *fakeSessionRepo, *fakeAuditRepo) {
+	sessions := &fakeSessionRepo{sessions: []types.Session{
+		{SessionId: "5a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", UserId: testUserId, ExpiresAt: time.Now().Add(time.Hour)},
+		{SessionId: "6b2c3d4e-5f6a-4b7c-9d8e-0f1a2b3c4d5e", UserId: otherUserId, ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	users := newFakeUserRepo(
+		types.User{UserUUID: testUserId, Email: "alice@example.com", Status: types.UserStatusActive},
+		types.User{UserUUID: otherUserId, Email: "bob@example.com", Status: types.UserStatusActive},
+	)
+	users.sessions = sessions
+	auditrepo := &fakeAuditRepo{}
+
+	return &UserAdmin{
+		userrepo:         users,
+		sessionrepo:      sessions,
+		audit:            newTestAudit(auditrepo),
+		smtp:             &fakeEmailSender{},
+		passwordResetTTL: time.Hour,
+		purgeDelay:       24 * time.Hour,
+	}, users, sessions, auditrepo
+}
+
+func TestUserAdminList(t *testing.T) {
+	admin, _, _, _ := newTestUserAdmin()
+
+	tests := []struct {
+		name       string
+		filter     types.UserFilter
+		wantEmails []string
+		wantTotal  int
+	}{
+		{name: "default page", filter: types.UserFilter{}, wantEmails: []string{"alice@example.com", "bob@example.com"}, wantTotal: 2},
+		{name: "email query", filter: types.UserFilter{Query: "BOB"}, wantEmails: []string{"bob@example.com"}, wantTotal: 1},
+		{name: "limit and offset", filter: types.UserFilter{Limit: 1, Offset: 1}, wantEmails: []string{"bob@example.com"}, wantTotal: 2},
+		{name: "negative offset", filter: types.UserFilter{Limit: 1, Offset: -5}, wantEmails: []string{"alice@example.com"}, wantTotal: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, total, err := admin.List(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			emails := make([]string, 0, len(users))
+			for _, user := range users {
+				emails = append(emails, user.Email)
+			}
+			if strings.Join(emails, ",") != strings.Join(tt.wantEmails, ",") {
+				t.Errorf("List() = %v, want %v", emails, tt.wantEmails)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("List() total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestUserAdminGet(t *testing.T) {
+	admin, _, sessions, _ := newTestUserAdmin()
+	ctx := context.Background()
+
+	user, active, err := admin.Get(ctx, testUserId)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if user.Email != "alice@example.com" || len(active) != 1 || active[0].UserId != testUserId {
+		t.Errorf("Get() = %+v with sessions %+v, want alice with her session", user, active)
+	}
+
+	// Revoked and expired sessions are not listed.
+	revokedAt := time.Now()
+	sessions.sessions = append(sessions.sessions,
+		types.Session{SessionId: "revoked", UserId: testUserId, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt},
+		types.Session{SessionId: "expired", UserId: testUserId, ExpiresAt: time.Now().Add(-time.Hour)},
+	)
+	if _, active, _ = admin.Get(ctx, testUserId); len(active) != 1 {
+		t.Errorf("Get() sessions = %+v, want the active one only", active)
+	}
+
+	for _, userId := range []string{"not-a-uuid", "9c3e4f5a-6b7c-4d8e-9f0a-3b4c5d6e7f8a"} {
+		if _, _, err = admin.Get(ctx, userId); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Get(%q) error = %v, want %v", userId, err, ErrUserNotFound)
+		}
+	}
+}
+
+func TestUserAdminForcePasswordReset(t *testing.T) {
+	admin, users, sessions, auditrepo := newTestUserAdmin()
+	ctx := context.Background()
+
+	if err := admin.ForcePasswordReset(ctx, testUserId); err != nil {
+		t.Fatalf("ForcePasswordReset() error = %v", err)
+	}
+
+	if !users.users[testUserId].PasswordResetRequired || users.users[otherUserId].PasswordResetRequired {
+		t.Errorf("password reset required only of the user, got %+v", users.users)
+	}
+	if !sessions.sessions[0].IsRevoked() || sessions.sessions[1].IsRevoked() {
+		t.Errorf("sessions = %+v, want only the user's revoked", sessions.sessions)
+	}
+	if got := auditrepo.eventTypes(); len(got) != 1 || got[0] != types.AuditPasswordResetForced {
+		t.Errorf("audit events = %v, want %s", got, types.AuditPasswordResetForced)
+	}
+
+	// The emailed token is the one stored, by its hash.
+	messages := admin.smtp.(*fakeEmailSender).messages("alice@example.com")
+	if len(messages) != 1 {
+		t.Fatalf("reset emails = %d, want 1", len(messages))
+	}
+	body := messages[0].Body
+	token := body[strings.Index(body, "<b>")+len("<b>") : strings.Index(body, "</b>")]
+	resetToken, ok := users.resetTokens[oauth.HashCode(token)]
+	if !ok || resetToken.UserId != testUserId || time.Until(resetToken.ExpiresAt) > time.Hour {
+		t.Errorf("reset tokens = %+v, want the emailed one of the user", users.resetTokens)
+	}
+
+	if err := admin.ForcePasswordReset(ctx, "9c3e4f5a-6b7c-4d8e-9f0a-3b4c5d6e7f8a"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("ForcePasswordReset() of an unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestUserAdminRevokeSessions(t *testing.T) {
+	admin, _, sessions, auditrepo := newTestUserAdmin()
+	ctx := context.Background()
+
+	revoked, err := admin.RevokeSessions(ctx, testUserId)
+	if err != nil || revoked != 1 {
+		t.Fatalf("RevokeSessions() = %d, %v, want 1 revoked", revoked, err)
+	}
+	if sessions.sessions[1].IsRevoked() {
+		t.Errorf("RevokeSessions() revoked a session of another user")
+	}
+	if revoked, _ = admin.RevokeSessions(ctx, testUserId); revoked != 0 {
+		t.Errorf("second RevokeSessions() = %d, want 0", revoked)
+	}
+	if got := auditrepo.eventTypes(); len(got) != 2 || got[0] != types.AuditSessionRevoked {
+		t.Errorf("audit events = %v, want two %s", got, types.AuditSessionRevoked)
+	}
+}
+
+func TestUserAdminRevokeSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		userId    string
+		sessionId string
+		wantErr   error
+	}{
+		{name: "session of the user", userId: testUserId, sessionId: "5a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"},
+		{name: "session of another user", userId: testUserId, sessionId: "6b2c3d4e-5f6a-4b7c-9d8e-0f1a2b3c4d5e", wantErr: ErrSessionNotFound},
+		{name: "invalid session id", userId: testUserId, sessionId: "s1", wantErr: ErrSessionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin, _, sessions, _ := newTestUserAdmin()
+
+			err := admin.RevokeSession(context.Background(), tt.userId, tt.sessionId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RevokeSession() error = %v, want %v", err, tt.wantErr)
+			}
+			revoked := 0
+			for _, session := range sessions.sessions {
+				if session.IsRevoked() {
+					revoked++
+				}
+			}
+			wantRevoked := 0
+			if tt.wantErr == nil {
+				wantRevoked = 1
+			}
+			if revoked != wantRevoked {
+				t.Errorf("revoked sessions = %d, want %d", revoked, wantRevoked)
+			}
+		})
+	}
+}
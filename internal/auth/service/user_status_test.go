@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"medods-test/internal/auth/types"
+	"testing"
+	"time"
+)
+
+func TestUserAdminSetStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		userId      string
+		status      string
+		wantErr     error
+		wantRevoked bool
+		wantPurge   bool
+	}{
+		{name: "disable", userId: testUserId, status: types.UserStatusDisabled, wantRevoked: true},
+		{name: "ban", userId: testUserId, status: types.UserStatusBanned, wantRevoked: true},
+		{name: "soft delete", userId: testUserId, status: types.UserStatusPendingDeletion, wantRevoked: true, wantPurge: true},
+		{name: "activate", userId: testUserId, status: types.UserStatusActive},
+		{name: "unknown status", userId: testUserId, status: "frozen", wantErr: ErrInvalidUserStatus},
+		{name: "invalid user id", userId: "not-a-uuid", status: types.UserStatusDisabled, wantErr: ErrUserNotFound},
+		{name: "unknown user", userId: "9c3e4f5a-6b7c-4d8e-9f0a-3b4c5d6e7f8a", status: types.UserStatusDisabled, wantErr: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin, users, sessions, auditrepo := newTestUserAdmin()
+
+			err := admin.SetStatus(context.Background(), tt.userId, tt.status, "support ticket 42")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetStatus() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(auditrepo.events) != 0 || users.users[testUserId].Status != types.UserStatusActive {
+					t.Errorf("rejected SetStatus() changed the user or was audited")
+				}
+				return
+			}
+
+			user := users.users[testUserId]
+			if user.Status != tt.status || user.StatusReason != "support ticket 42" {
+				t.Errorf("user status %q (%q), want %q", user.Status, user.StatusReason, tt.status)
+			}
+			if (user.PurgeAt != nil) != tt.wantPurge {
+				t.Errorf("purge at = %v, want scheduled %v", user.PurgeAt, tt.wantPurge)
+			}
+			if tt.wantPurge && time.Until(*user.PurgeAt) < 23*time.Hour {
+				t.Errorf("purge at = %v, want after the purge delay", user.PurgeAt)
+			}
+			if sessions.sessions[0].IsRevoked() != tt.wantRevoked || sessions.sessions[1].IsRevoked() {
+				t.Errorf("sessions = %+v, want the user's revoked %v", sessions.sessions, tt.wantRevoked)
+			}
+			if len(auditrepo.events) != 1 || auditrepo.events[0].Type != types.AuditUserStatusChanged || auditrepo.events[0].Metadata["status"] != tt.status {
+				t.Errorf("audit events = %+v, want the status change", auditrepo.events)
+			}
+		})
+	}
+}
+
+func TestUserAdminRestoreCancelsPurge(t *testing.T) {
+	admin, users, _, _ := newTestUserAdmin()
+	admin.purgeDelay = -time.Second
+	ctx := context.Background()
+
+	if err := admin.Delete(ctx, testUserId, "requested by the user"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := admin.SetStatus(ctx, testUserId, types.UserStatusActive, "changed their mind"); err != nil {
+		t.Fatalf("SetStatus() error = %v", err)
+	}
+	if user := users.users[testUserId]; user.PurgeAt != nil {
+		t.Errorf("purge at = %v after restore, want none", user.PurgeAt)
+	}
+
+	if purged, err := admin.Purge(ctx); err != nil || purged != 0 {
+		t.Errorf("Purge() = %d, %v, want the restored user kept", purged, err)
+	}
+}
+
+func TestUserAdminPurge(t *testing.T) {
+	admin, users, _, auditrepo := newTestUserAdmin()
+	ctx := context.Background()
+
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	users.users[testUserId] = types.User{UserUUID: testUserId, Status: types.UserStatusPendingDeletion, PurgeAt: &past}
+	users.users[otherUserId] = types.User{UserUUID: otherUserId, Status: types.UserStatusPendingDeletion, PurgeAt: &future}
+
+	purged, err := admin.Purge(ctx)
+	if err != nil || purged != 1 {
+		t.Fatalf("Purge() = %d, %v, want 1 purged", purged, err)
+	}
+	if _, ok := users.users[testUserId]; ok {
+		t.Errorf("due user is kept")
+	}
+	if _, ok := users.users[otherUserId]; !ok {
+		t.Errorf("user not yet due is purged")
+	}
+	if got := auditrepo.eventTypes(); len(got) != 1 || got[0] != types.AuditAccountPurged || auditrepo.events[0].UserId != testUserId {
+		t.Errorf("audit events = %v, want %s of the purged user", got, types.AuditAccountPurged)
+	}
+}
+
+func TestAuthenticateUserStatus(t *testing.T) {
+	tests := []struct {
+		status  string
+		wantErr error
+	}{
+		{status: types.UserStatusActive},
+		{status: types.UserStatusDisabled, wantErr: ErrUserDisabled},
+		{status: types.UserStatusBanned, wantErr: ErrUserBanned},
+		{status: types.UserStatusPendingDeletion, wantErr: ErrUserPendingDeletion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			users := newFakeUserRepo()
+			auditrepo := &fakeAuditRepo{}
+			u := newTestUser(users, auditrepo)
+			password, err := u.hasher.Hash("correct horse battery staple")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			users.users[testUserId] = types.User{UserUUID: testUserId, Email: "alice@example.com", Password: password, Status: tt.status}
+
+			user, err := u.Authenticate(context.Background(), types.UserDTO{Email: "alice@example.com", Password: "correct horse battery staple"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && user.UserUUID != testUserId {
+				t.Errorf("Authenticate() = %+v, want the user", user)
+			}
+
+			wantEvent := types.AuditUserSignedIn
+			if tt.wantErr != nil {
+				wantEvent = types.AuditUserSignInFailed
+			}
+			if got := auditrepo.eventTypes(); len(got) != 1 || got[0] != wantEvent {
+				t.Errorf("audit events = %v, want %s", got, wantEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/logger"
+	"medods-test/pkg/oauth"
+	"medods-test/pkg/webhook"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWebhookPageSize = 50
+	maxWebhookPageSize     = 500
+	webhookBatchSize       = 20
+	webhookPurgeInterval   = time.Hour
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL       = errors.New("webhook url must be an absolute http or https url of a public host")
+	ErrInvalidWebhookEvents    = errors.New("webhook event types must be known audit event types or *")
+)
+
+type WebhookRepo interface {
+	CreateSubscription(ctx context.Context, subscription types.WebhookSubscription) error
+	GetSubscription(ctx context.Context, subscriptionId string) (*types.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]types.WebhookSubscription, error)
+	ListEventSubscriptions(ctx context.Context, eventType string) ([]types.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, subscription types.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, subscriptionId string) (bool, error)
+	CreateDelivery(ctx context.Context, delivery types.WebhookDelivery) error
+	GetDelivery(ctx context.Context, subscriptionId string, deliveryId string) (*types.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, filter types.WebhookDeliveryFilter) ([]types.WebhookDelivery, int, error)
+	ClaimDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]types.WebhookDelivery, error)
+	SaveAttempt(ctx context.Context, delivery types.WebhookDelivery, attempt types.WebhookAttempt) error
+	ListAttempts(ctx context.Context, deliveryId string) ([]types.WebhookAttempt, error)
+	DeleteDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// WebhookSender posts signed payloads to webhook endpoints.
+type WebhookSender interface {
+	Send(ctx context.Context, url string, secret string, message webhook.Message) (webhook.Result, error)
+}
+
+type WebhookConfig struct {
+	// Timeout of one attempt, also bounds how long a claimed batch is held by a dispatcher.
+	Timeout     time.Duration
+	MaxAttempts int
+	// RetryBackoff is the delay after the first failed attempt, doubled after every next one up to MaxBackoff.
+	RetryBackoff      time.Duration
+	MaxBackoff        time.Duration
+	DeliveryRetention time.Duration
+}
+
+// Webhooks sends audit events to the endpoints subscribed to them.
+type Webhooks struct {
+	webhookrepo WebhookRepo
+	sender      WebhookSender
+	config      WebhookConfig
+}
+
+// Create registers a subscription. Its signing secret is returned in plain text only once.
+func (w *Webhooks) Create(ctx context.Context, input types.WebhookSubscriptionDTO) (*types.WebhookSubscription, string, error) {
+	secret, err := oauth.NewCode()
+	if err != nil {
+		logger.Errorf("failed to generate webhook secret: %s", err)
+		return nil, "", err
+	}
+
+	subscription := types.WebhookSubscription{
+		Id:          uuid.NewString(),
+		URL:         input.URL,
+		Description: input.Description,
+		Secret:      secret,
+		Active:      input.Active == nil || *input.Active,
+	}
+	if subscription.EventTypes, err = webhookEventTypes(input.EventTypes); err != nil {
+		return nil, "", err
+	}
+	if err = validateWebhookURL(subscription.URL); err != nil {
+		return nil, "", err
+	}
+
+	if err = w.webhookrepo.CreateSubscription(ctx, subscription); err != nil {
+		logger.Errorf("failed to create webhook: %s", err)
+		return nil, "", err
+	}
+
+	created, err := w.Get(ctx, subscription.Id)
+	if err != nil {
+		return nil, "", err
+	}
+	return created, secret, nil
+}
+
+func (w *Webhooks) Get(ctx context.Context, subscriptionId string) (*types.WebhookSubscription, error) {
+	if uuid.Validate(subscriptionId) != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	subscription, err := w.webhookrepo.GetSubscription(ctx, subscriptionId)
+	if err != nil {
+		logger.Errorf("failed to get webhook: %s", err)
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return subscription, nil
+}
+
+func (w *Webhooks) List(ctx context.Context) ([]types.WebhookSubscription, error) {
+	subscriptions, err := w.webhookrepo.ListSubscriptions(ctx)
+	if err != nil {
+		logger.Errorf("failed to list webhooks: %s", err)
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Update replaces the url, description and event types of a subscription. The secret cannot be changed.
+func (w *Webhooks) Update(ctx context.Context, input types.WebhookSubscriptionDTO) (*types.WebhookSubscription, error) {
+	subscription, err := w.Get(ctx, input.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.URL = input.URL
+	subscription.Description = input.Description
+	if input.Active != nil {
+		subscription.Active = *input.Active
+	}
+	if subscription.EventTypes, err = webhookEventTypes(input.EventTypes); err != nil {
+		return nil, err
+	}
+	if err = validateWebhookURL(subscription.URL); err != nil {
+		return nil, err
+	}
+
+	if err = w.webhookrepo.UpdateSubscription(ctx, *subscription); err != nil {
+		logger.Errorf("failed to update webhook: %s", err)
+		return nil, err
+	}
+
+	return w.Get(ctx, subscription.Id)
+}
+
+// Delete removes a subscription together with its deliveries.
+func (w *Webhooks) Delete(ctx context.Context, subscriptionId string) error {
+	if uuid.Validate(subscriptionId) != nil {
+		return ErrWebhookNotFound
+	}
+
+	deleted, err := w.webhookrepo.DeleteSubscription(ctx, subscriptionId)
+	if err != nil {
+		logger.Errorf("failed to delete webhook: %s", err)
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Deliveries returns a page of the deliveries of a subscription, newest first, and the number of matching deliveries.
+func (w *Webhooks) Deliveries(ctx context.Context, filter types.WebhookDeliveryFilter) ([]types.WebhookDelivery, int, error) {
+	if _, err := w.Get(ctx, filter.SubscriptionId); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultWebhookPageSize
+	}
+	filter.Limit = min(filter.Limit, maxWebhookPageSize)
+	filter.Offset = max(filter.Offset, 0)
+
+	deliveries, total, err := w.webhookrepo.ListDeliveries(ctx, filter)
+	if err != nil {
+		logger.Errorf("failed to list webhook deliveries: %s", err)
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// Delivery returns a delivery of a subscription and the log of its attempts.
+func (w *Webhooks) Delivery(ctx context.Context, subscriptionId string, deliveryId string) (*types.WebhookDelivery, []types.WebhookAttempt, error) {
+	delivery, err := w.delivery(ctx, subscriptionId, deliveryId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempts, err := w.webhookrepo.ListAttempts(ctx, delivery.Id)
+	if err != nil {
+		logger.Errorf("failed to list webhook attempts: %s", err)
+		return nil, nil, err
+	}
+	return delivery, attempts, nil
+}
+
+// Replay queues the payload of a delivery to be sent again as a new delivery. The event id
+// of the payload is kept, so that endpoints can tell a replay from a new event.
+func (w *Webhooks) Replay(ctx context.Context, subscriptionId string, deliveryId string) (*types.WebhookDelivery, error) {
+	original, err := w.delivery(ctx, subscriptionId, deliveryId)
+	if err != nil {
+		return nil, err
+	}
+
+	replay := types.WebhookDelivery{
+		Id:             uuid.NewString(),
+		SubscriptionId: original.SubscriptionId,
+		EventId:        original.EventId,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		NextAttemptAt:  time.Now(),
+		ReplayOf:       original.Id,
+	}
+	if err = w.webhookrepo.CreateDelivery(ctx, replay); err != nil {
+		logger.Errorf("failed to replay webhook delivery: %s", err)
+		return nil, err
+	}
+
+	return w.delivery(ctx, subscriptionId, replay.Id)
+}
+
+// SendDue sends the deliveries that are due and returns how many were attempted.
+func (w *Webhooks) SendDue(ctx context.Context) (int, error) {
+	// A claimed batch is sent one by one, so it is held long enough for every attempt to time out.
+	lease := webhookBatchSize*w.config.Timeout + time.Minute
+
+	var sent int
+	for {
+		now := time.Now()
+		deliveries, err := w.webhookrepo.ClaimDeliveries(ctx, now, now.Add(lease), webhookBatchSize)
+		if err != nil {
+			logger.Errorf("failed to claim webhook deliveries: %s", err)
+			return sent, err
+		}
+
+		subscriptions := make(map[string]*types.WebhookSubscription)
+		for _, delivery := range deliveries {
+			subscription, ok := subscriptions[delivery.SubscriptionId]
+			if !ok {
+				if subscription, err = w.webhookrepo.GetSubscription(ctx, delivery.SubscriptionId); err != nil {
+					logger.Errorf("failed to get webhook: %s", err)
+					return sent, err
+				}
+				subscriptions[delivery.SubscriptionId] = subscription
+			}
+			// Deleted while the batch was sent, its deliveries are gone.
+			if subscription == nil {
+				continue
+			}
+
+			w.send(ctx, *subscription, delivery)
+			sent++
+		}
+
+		if len(deliveries) < webhookBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// PurgeDeliveries removes finished deliveries older than the retention period.
+func (w *Webhooks) PurgeDeliveries(ctx context.Context) (int64, error) {
+	deleted, err := w.webhookrepo.DeleteDeliveries(ctx, time.Now().Add(-w.config.DeliveryRetention))
+	if err != nil {
+		logger.Errorf("failed to purge webhook deliveries: %s", err)
+		return 0, err
+	}
+	if deleted > 0 {
+		logger.Infof("purged %d webhook deliveries", deleted)
+	}
+	return deleted, nil
+}
+
+// RunDispatcher sends due deliveries every interval and purges old ones until ctx is done.
+func (w *Webhooks) RunDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	purgeTicker := time.NewTicker(webhookPurgeInterval)
+	defer purgeTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = w.SendDue(ctx)
+		case <-purgeTicker.C:
+			_, _ = w.PurgeDeliveries(ctx)
+		}
+	}
+}
+
+func (w *Webhooks) delivery(ctx context.Context, subscriptionId string, deliveryId string) (*types.WebhookDelivery, error) {
+	if uuid.Validate(subscriptionId) != nil || uuid.Validate(deliveryId) != nil {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+
+	delivery, err := w.webhookrepo.GetDelivery(ctx, subscriptionId, deliveryId)
+	if err != nil {
+		logger.Errorf("failed to get webhook delivery: %s", err)
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+// send makes one attempt to send the delivery. A failed delivery is retried with backoff
+// until it runs out of attempts.
+func (w *Webhooks) send(ctx context.Context, subscription types.WebhookSubscription, delivery types.WebhookDelivery) {
+	message := webhook.Message{
+		Id:      delivery.Id,
+		Event:   delivery.EventType,
+		Payload: delivery.Payload,
+	}
+	result, err := w.sender.Send(ctx, subscription.URL, subscription.Secret, message)
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = result.StatusCode
+	delivery.Error = ""
+	switch {
+	case err == nil:
+		delivery.Status = types.WebhookDeliverySucceeded
+	case delivery.Attempts >= w.config.MaxAttempts:
+		delivery.Status = types.WebhookDeliveryFailed
+		delivery.Error = err.Error()
+		logger.Errorf("webhook delivery %s to %s failed after %d attempts: %s", delivery.Id, subscription.URL, delivery.Attempts, err)
+	default:
+		delivery.Status = types.WebhookDeliveryPending
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = now.Add(webhook.Backoff(delivery.Attempts, w.config.RetryBackoff, w.config.MaxBackoff))
+	}
+
+	attempt := types.WebhookAttempt{
+		DeliveryId: delivery.Id,
+		Attempt:    delivery.Attempts,
+		StatusCode: result.StatusCode,
+		Error:      delivery.Error,
+		Duration:   result.Duration,
+		CreatedAt:  now,
+	}
+	if err = w.webhookrepo.SaveAttempt(ctx, delivery, attempt); err != nil {
+		logger.Errorf("failed to save webhook attempt (delivery: %s): %s", delivery.Id, err)
+	}
+}
+
+// enqueueWebhooks queues the event for every active subscription to its type.
+// A failure is logged and does not fail the caller.
+func (a *Audit) enqueueWebhooks(ctx context.Context, event types.AuditEvent) {
+	subscriptions, err := a.webhookrepo.ListEventSubscriptions(ctx, event.Type)
+	if err != nil {
+		logger.Errorf("failed to list webhooks for audit event %d: %s", event.Id, err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(types.WebhookPayload{
+		EventId:   event.Id,
+		Type:      event.Type,
+		ActorId:   event.ActorId,
+		UserId:    event.UserId,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		Metadata:  event.Metadata,
+		CreatedAt: event.CreatedAt,
+	})
+	if err != nil {
+		logger.Errorf("failed to encode webhook payload: %s", err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		delivery := types.WebhookDelivery{
+			Id:             uuid.NewString(),
+			SubscriptionId: subscription.Id,
+			EventId:        event.Id,
+			EventType:      event.Type,
+			Payload:        payload,
+			NextAttemptAt:  time.Now(),
+		}
+		if err = a.webhookrepo.CreateDelivery(ctx, delivery); err != nil {
+			logger.Errorf("failed to queue webhook delivery (event: %d, webhook: %s): %s", event.Id, subscription.Id, err)
+		}
+	}
+}
+
+func webhookEventTypes(eventTypes []string) ([]string, error) {
+	if len(eventTypes) == 0 {
+		return nil, ErrInvalidWebhookEvents
+	}
+
+	unique := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if eventType != types.WebhookEventAll && !slices.Contains(types.AuditEventTypes, eventType) {
+			return nil, ErrInvalidWebhookEvents
+		}
+		if !slices.Contains(unique, eventType) {
+			unique = append(unique, eventType)
+		}
+	}
+	return unique, nil
+}
+
+// validateWebhookURL rejects urls of local hosts and non-public addresses early. Host names
+// are checked again by the sender once resolved, see webhook.NewClient.
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Hostname() == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ErrInvalidWebhookURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrInvalidWebhookURL
+	}
+	if ip := net.ParseIP(host); ip != nil && !webhook.IsPublicIP(ip) {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
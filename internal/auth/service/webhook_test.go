@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"medods-test/internal/auth/types"
+	"medods-test/pkg/webhook"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebhookSender records the sent messages and fails while errs has errors left.
+type fakeWebhookSender struct {
+	mu       sync.Mutex
+	errs     []error
+	messages []webhook.Message
+}
+
+func (s *fakeWebhookSender) Send(_ context.Context, _ string, secret string, message webhook.Message) (webhook.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message)
+
+	// The endpoint checks the signature the way receivers do.
+	timestamp := time.Now().Unix()
+	if err := webhook.Verify(secret, strconv.FormatInt(timestamp, 10), webhook.Sign(secret, timestamp, message.Payload), message.Payload, time.Minute); err != nil {
+		return webhook.Result{}, err
+	}
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return webhook.Result{StatusCode: 500}, err
+	}
+	return webhook.Result{StatusCode: 200}, nil
+}
+
+func newTestWebhooks() (*Webhooks, *fakeWebhookRepo, *fakeWebhookSender) {
+	webhookrepo := &fakeWebhookRepo{}
+	sender := &fakeWebhookSender{}
+	return &Webhooks{
+		webhookrepo: webhookrepo,
+		sender:      sender,
+		config: WebhookConfig{
+			Timeout:      time.Second,
+			MaxAttempts:  3,
+			RetryBackoff: time.Minute,
+			MaxBackoff:   time.Hour,
+		},
+	}, webhookrepo, sender
+}
+
+func TestWebhookURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{url: "https://hooks.example.com/auth"},
+		{url: "http://93.184.216.34:8080/hook"},
+		{url: "ftp://hooks.example.com/auth", wantErr: ErrInvalidWebhookURL},
+		{url: "/relative", wantErr: ErrInvalidWebhookURL},
+		{url: "http://localhost:8080/hook", wantErr: ErrInvalidWebhookURL},
+		{url: "http://api.localhost./hook", wantErr: ErrInvalidWebhookURL},
+		{url: "http://127.0.0.1/hook", wantErr: ErrInvalidWebhookURL},
+		{url: "http://[::1]/hook", wantErr: ErrInvalidWebhookURL},
+		{url: "http://10.1.2.3/hook", wantErr: ErrInvalidWebhookURL},
+		{url: "http://192.168.0.10/hook", wantErr: ErrInvalidWebhookURL},
+		{url: "http://169.254.169.254/latest/meta-data/", wantErr: ErrInvalidWebhookURL},
+		{url: "http://0.0.0.0/hook", wantErr: ErrInvalidWebhookURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			webhooks, _, _ := newTestWebhooks()
+
+			_, _, err := webhooks.Create(context.Background(), types.WebhookSubscriptionDTO{
+				URL:        tt.url,
+				EventTypes: []string{types.WebhookEventAll},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookUpdateRejectsInternalURL(t *testing.T) {
+	webhooks, webhookrepo, _ := newTestWebhooks()
+	ctx := context.Background()
+
+	subscription, _, err := webhooks.Create(ctx, types.WebhookSubscriptionDTO{URL: "https://hooks.example.com/auth", EventTypes: []string{types.WebhookEventAll}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_, err = webhooks.Update(ctx, types.WebhookSubscriptionDTO{Id: subscription.Id, URL: "http://169.254.169.254/", EventTypes: []string{types.WebhookEventAll}})
+	if !errors.Is(err, ErrInvalidWebhookURL) {
+		t.Fatalf("Update() error = %v, want %v", err, ErrInvalidWebhookURL)
+	}
+	if url := webhookrepo.subscriptions[0].URL; url != "https://hooks.example.com/auth" {
+		t.Errorf("url = %q after a rejected update", url)
+	}
+}
+
+func TestWebhookDispatch(t *testing.T) {
+	webhooks, webhookrepo, sender := newTestWebhooks()
+	ctx := context.Background()
+
+	subscription, secret, err := webhooks.Create(ctx, types.WebhookSubscriptionDTO{URL: "https://hooks.example.com/auth", EventTypes: []string{types.AuditUserSignedIn}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if secret == "" || subscription.Secret != secret || !subscription.Active {
+		t.Fatalf("created subscription %+v with secret %q", subscription, secret)
+	}
+
+	audit := newTestAudit(&fakeAuditRepo{})
+	audit.webhookrepo = webhookrepo
+	audit.Record(ctx, types.AuditUserSignedIn, testUserId, nil)
+	audit.Record(ctx, types.AuditEmailChangeRequested, testUserId, nil)
+	if len(webhookrepo.deliveries) != 1 {
+		t.Fatalf("deliveries = %d, want one for the subscribed event", len(webhookrepo.deliveries))
+	}
+
+	// A failed attempt is retried after the backoff.
+	sender.errs = []error{errors.New("unexpected status 500")}
+	if sent, err := webhooks.SendDue(ctx); err != nil || sent != 1 {
+		t.Fatalf("SendDue() = %d, %v, want 1 sent", sent, err)
+	}
+	delivery := webhookrepo.deliveries[0]
+	if delivery.Status != types.WebhookDeliveryPending || delivery.Attempts != 1 || delivery.Error == "" {
+		t.Fatalf("delivery after a failed attempt = %+v, want pending", delivery)
+	}
+	if wait := time.Until(delivery.NextAttemptAt); wait < 59*time.Second || wait > time.Minute {
+		t.Errorf("next attempt in %v, want the retry backoff", wait)
+	}
+	if sent, _ := webhooks.SendDue(ctx); sent != 0 {
+		t.Errorf("SendDue() before the backoff sent %d", sent)
+	}
+
+	webhookrepo.deliveries[0].NextAttemptAt = time.Now()
+	if sent, err := webhooks.SendDue(ctx); err != nil || sent != 1 {
+		t.Fatalf("second SendDue() = %d, %v, want 1 sent", sent, err)
+	}
+	delivery = webhookrepo.deliveries[0]
+	if delivery.Status != types.WebhookDeliverySucceeded || delivery.Attempts != 2 || delivery.ResponseStatus != 200 {
+		t.Errorf("delivery after a successful attempt = %+v, want succeeded", delivery)
+	}
+	if len(webhookrepo.attempts) != 2 {
+		t.Errorf("attempts = %d, want 2", len(webhookrepo.attempts))
+	}
+
+	var payload types.WebhookPayload
+	if err = json.Unmarshal(sender.messages[0].Payload, &payload); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if sender.messages[0].Event != types.AuditUserSignedIn || payload.Type != types.AuditUserSignedIn || payload.UserId != testUserId {
+		t.Errorf("sent %s with payload %+v, want the signed in event", sender.messages[0].Event, payload)
+	}
+}
+
+func TestWebhookDispatchGivesUp(t *testing.T) {
+	webhooks, webhookrepo, sender := newTestWebhooks()
+	ctx := context.Background()
+
+	if _, _, err := webhooks.Create(ctx, types.WebhookSubscriptionDTO{URL: "https://hooks.example.com/auth", EventTypes: []string{types.WebhookEventAll}}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	audit := newTestAudit(&fakeAuditRepo{})
+	audit.webhookrepo = webhookrepo
+	audit.Record(ctx, types.AuditUserSignedIn, testUserId, nil)
+
+	sender.errs = []error{webhook.ErrForbiddenAddress, webhook.ErrForbiddenAddress, webhook.ErrForbiddenAddress}
+	for attempt := 1; attempt <= webhooks.config.MaxAttempts; attempt++ {
+		webhookrepo.deliveries[0].NextAttemptAt = time.Now()
+		if _, err := webhooks.SendDue(ctx); err != nil {
+			t.Fatalf("SendDue() error = %v", err)
+		}
+	}
+
+	delivery := webhookrepo.deliveries[0]
+	if delivery.Status != types.WebhookDeliveryFailed || delivery.Attempts != webhooks.config.MaxAttempts {
+		t.Errorf("delivery = %+v, want failed after %d attempts", delivery, webhooks.config.MaxAttempts)
+	}
+	webhookrepo.deliveries[0].NextAttemptAt = time.Now()
+	if sent, _ := webhooks.SendDue(ctx); sent != 0 {
+		t.Errorf("SendDue() sent a failed delivery %d times", sent)
+	}
+}
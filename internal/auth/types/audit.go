@@ -0,0 +1,193 @@
+package types
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	AuditUserSignedUp         = "user.signed_up"
+	AuditUserSignedIn         = "user.signed_in"
+	AuditUserSignInFailed     = "user.sign_in_failed"
+	AuditUserSignedOut        = "user.signed_out"
+	AuditTokenRefreshed       = "token.refreshed"
+	AuditTokenReuseDetected   = "token.reuse_detected"
+	AuditRiskAssessed         = "risk.assessed"
+	AuditSessionIPChanged     = "session.ip_changed"
+	AuditSessionRevoked       = "session.revoked"
+	AuditDeviceAdded          = "device.added"
+	AuditDeviceTrusted        = "device.trusted"
+	AuditDeviceForgotten      = "device.forgotten"
+	AuditNotificationsChanged = "notifications.changed"
+	AuditPasswordChanged      = "password.changed"
+	AuditPasswordReset        = "password.reset"
+	AuditPasswordResetForced  = "password.reset_forced"
+	AuditPasswordRecovery     = "password.recovery_requested"
+	AuditEmailChangeRequested = "email.change_requested"
+	AuditEmailChanged         = "email.changed"
+	AuditPhoneChanged         = "phone.changed"
+	AuditPhoneVerified        = "phone.verified"
+	AuditPhoneRemoved         = "phone.removed"
+	AuditIdentityLinked       = "identity.linked"
+	AuditUserStatusChanged    = "user.status_changed"
+	AuditAccountErased        = "account.erased"
+	AuditAccountPurged        = "account.purged"
+	AuditAdminAction          = "admin.action"
+)
+
+// AuditEventTypes lists every type of audit event.
+var AuditEventTypes = []string{
+	AuditUserSignedUp,
+	AuditUserSignedIn,
+	AuditUserSignInFailed,
+	AuditUserSignedOut,
+	AuditTokenRefreshed,
+	AuditTokenReuseDetected,
+	AuditRiskAssessed,
+	AuditSessionIPChanged,
+	AuditSessionRevoked,
+	AuditDeviceAdded,
+	AuditDeviceTrusted,
+	AuditDeviceForgotten,
+	AuditNotificationsChanged,
+	AuditPasswordChanged,
+	AuditPasswordReset,
+	AuditPasswordResetForced,
+	AuditPasswordRecovery,
+	AuditEmailChangeRequested,
+	AuditEmailChanged,
+	AuditPhoneChanged,
+	AuditPhoneVerified,
+	AuditPhoneRemoved,
+	AuditIdentityLinked,
+	AuditUserStatusChanged,
+	AuditAccountErased,
+	AuditAccountPurged,
+	AuditAdminAction,
+}
+
+// AuditGenesisHash is the previous hash of the first audit event.
+var AuditGenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// AuditEvent is a record of the append-only security audit log. Every event is chained to
+// the previous one by Hash, so that a changed or removed record breaks the chain.
+//
+// IP, UserAgent and Metadata are personal data kept apart from the chain: it covers them
+// only through DetailsHash, salted with DetailsSalt, so that they can be erased with the user
+// without breaking the chain. Erased events have an empty DetailsSalt.
+type AuditEvent struct {
+	Id   int64
+	Type string
+	// ActorId is who performed the action: a user id, a client id or empty for the service itself.
+	ActorId string
+	// UserId is the user the event is about.
+	UserId      string
+	IP          string
+	UserAgent   string
+	Metadata    map[string]string
+	DetailsSalt string
+	DetailsHash string
+	CreatedAt   time.Time
+	PrevHash    string
+	Hash        string
+}
+
+// HasDetails reports whether the personal data of the event is kept, that is not erased.
+func (e *AuditEvent) HasDetails() bool {
+	return e.DetailsSalt != ""
+}
+
+// ComputeDetailsHash returns the salted hash of the personal data of the event.
+func (e *AuditEvent) ComputeDetailsHash() string {
+	metadata, _ := json.Marshal(e.Metadata)
+
+	digest := sha256.Sum256([]byte(strings.Join([]string{
+		e.DetailsSalt,
+		e.IP,
+		e.UserAgent,
+		string(metadata),
+	}, "\n")))
+	return hex.EncodeToString(digest[:])
+}
+
+// ComputeHash returns the hash of the event chained to PrevHash.
+func (e *AuditEvent) ComputeHash() string {
+	digest := sha256.Sum256([]byte(strings.Join([]string{
+		e.PrevHash,
+		strconv.FormatInt(e.Id, 10),
+		e.Type,
+		e.ActorId,
+		e.UserId,
+		e.DetailsHash,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "\n")))
+	return hex.EncodeToString(digest[:])
+}
+
+type AuditFilter struct {
+	Type    string
+	UserId  string
+	ActorId string
+	From    time.Time
+	To      time.Time
+	Limit   int
+	Offset  int
+}
+
+// AuditVerification is the result of checking the hash chain of the audit log.
+type AuditVerification struct {
+	Checked int64
+	Valid   bool
+	// BrokenAt is the id of the first event that does not match the chain.
+	BrokenAt int64
+}
+
+// RequestInfo describes the request a service call is made for.
+type RequestInfo struct {
+	IP        string
+	UserAgent string
+	ActorId   string
+	// DeviceId identifies the device of sign-in requests, see KnownDevice.
+	DeviceId string
+}
+
+type requestInfoKey struct{}
+
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+func RequestInfoFrom(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
+
+// AuditMark tells whether an audit event was recorded for a request, so that a generic record
+// of the request is not added next to the specific one.
+type AuditMark struct {
+	recorded atomic.Bool
+}
+
+func (m *AuditMark) Recorded() bool {
+	return m.recorded.Load()
+}
+
+type auditMarkKey struct{}
+
+func WithAuditMark(ctx context.Context) (context.Context, *AuditMark) {
+	mark := &AuditMark{}
+	return context.WithValue(ctx, auditMarkKey{}, mark), mark
+}
+
+// MarkAudited sets the audit mark of ctx, if there is one.
+func MarkAudited(ctx context.Context) {
+	if mark, ok := ctx.Value(auditMarkKey{}).(*AuditMark); ok {
+		mark.recorded.Store(true)
+	}
+}
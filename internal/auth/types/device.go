@@ -0,0 +1,39 @@
+package types
+
+import "time"
+
+const (
+	DeviceCodePending  = "pending"
+	DeviceCodeApproved = "approved"
+	DeviceCodeDenied   = "denied"
+	DeviceCodeConsumed = "consumed"
+)
+
+type DeviceCode struct {
+	DeviceCodeHash string
+	UserCode       string
+	ClientId       string
+	Scope          string
+	Status         string
+	UserId         string
+	Interval       time.Duration
+	LastPolledAt   *time.Time
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+
+	// SlowDown is set by polling when the client polled faster than Interval.
+	SlowDown bool
+}
+
+func (d *DeviceCode) IsExpired() bool {
+	return time.Now().After(d.ExpiresAt)
+}
+
+type DeviceAuthorization struct {
+	DeviceCode              string
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresIn               time.Duration
+	Interval                time.Duration
+}
@@ -0,0 +1,51 @@
+package types
+
+import "time"
+
+// ExternalIdentity links a local user to an account at an external identity provider.
+type ExternalIdentity struct {
+	Id          string
+	Provider    string
+	Subject     string
+	UserId      string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+// SocialLoginState keeps the parameters of a login started at an external provider.
+type SocialLoginState struct {
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+func (s *SocialLoginState) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// SocialLogin is a login started at an external provider. State is bound to the browser that
+// started the login and has to come back with the callback from the same browser.
+type SocialLogin struct {
+	URL       string
+	State     string
+	ExpiresAt time.Time
+}
+
+// SocialLinkRequest is an external account waiting to be linked to the existing user with the
+// same email until the user confirms the link with the local password.
+type SocialLinkRequest struct {
+	TokenHash string
+	Provider  string
+	Subject   string
+	UserId    string
+	Email     string
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+func (r *SocialLinkRequest) IsExpired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
@@ -0,0 +1,31 @@
+package types
+
+import "time"
+
+// KnownDevice is a device the user has signed in from. Devices are recognized by a device id
+// kept in a signed cookie or supplied by the client, only a hash of the id is stored.
+type KnownDevice struct {
+	Id         string
+	UserId     string
+	DeviceHash string
+	// Name is a coarse description of the device derived from the user agent.
+	Name     string
+	IP       string
+	Location string
+	// TrustedUntil is set while the device is remembered and signs in without a second factor.
+	TrustedUntil *time.Time
+	CreatedAt    time.Time
+	LastSeenAt   time.Time
+	// Current is set for the device the request comes from.
+	Current bool
+}
+
+func (d *KnownDevice) IsTrusted() bool {
+	return d.TrustedUntil != nil && time.Now().Before(*d.TrustedUntil)
+}
+
+// DeviceCookie is the signed device id stored in the browser.
+type DeviceCookie struct {
+	Value     string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,31 @@
+package types
+
+import "time"
+
+// Sign-in methods of login attempts.
+const (
+	LoginMethodPassword = "password"
+	LoginMethodSocial   = "social"
+)
+
+// LoginAttempt is a sign-in attempt to the account of a user, shown in the login history.
+type LoginAttempt struct {
+	Id      int64
+	UserId  string
+	Method  string
+	Success bool
+	// FailureReason is why the attempt failed, empty for successful attempts.
+	FailureReason string
+	IP            string
+	UserAgent     string
+	// Device is a coarse description of the device derived from UserAgent.
+	Device string
+	// Location is a coarse location of IP, empty if unknown.
+	Location string
+	// Country, ASN and the coordinates locate IP for the risk assessment of later sign-ins.
+	Country   string
+	ASN       int64
+	Latitude  float64
+	Longitude float64
+	CreatedAt time.Time
+}
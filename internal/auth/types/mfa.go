@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// Second factor channels of MFA challenges.
+const (
+	MFAChannelEmail = "email"
+	MFAChannelSMS   = "sms"
+)
+
+// MFAChallenge is a sign-in that waits for a one-time code sent to the user.
+type MFAChallenge struct {
+	TokenHash      string
+	UserId         string
+	OrganizationId string
+	// Method is the sign-in method that passed the first factor.
+	Method    string
+	Channel   string
+	CodeHash  string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func (c *MFAChallenge) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
@@ -0,0 +1,78 @@
+package types
+
+import "time"
+
+// Security notifications sent to users.
+const (
+	NotificationNewDevice            = "new_device"
+	NotificationIPChanged            = "ip_changed"
+	NotificationPasswordChanged      = "password_changed"
+	NotificationEmailChangeRequested = "email_change_requested"
+	NotificationPhoneChanged         = "phone_changed"
+	NotificationWeeklySummary        = "weekly_summary"
+)
+
+// Notification channels.
+const (
+	NotificationChannelEmail = "email"
+	NotificationChannelSMS   = "sms"
+)
+
+// Notification delivery modes.
+const (
+	NotificationDeliveryImmediate = "immediate"
+	NotificationDeliveryDigest    = "digest"
+)
+
+// NotificationPreference is how the user wants to be notified about an event. No channels
+// turn the notification off.
+type NotificationPreference struct {
+	Event    string
+	Channels []string
+	Delivery string
+	// Critical notifications are always sent by email immediately and cannot be changed.
+	Critical bool
+	// Scheduled notifications are sent on their own schedule by email, they can only be
+	// turned on or off.
+	Scheduled bool
+}
+
+// Notice is a notification about an event to be sent to the user.
+type Notice struct {
+	Event   string
+	Subject string
+	// Body is HTML without a greeting and signature, so that notices can be joined in a digest.
+	Body string
+	// Text is a short plain text version for SMS, Subject is sent if it is empty.
+	Text string
+}
+
+// PendingNotification is a notice waiting to be sent in the next digest.
+type PendingNotification struct {
+	Id        int64
+	UserId    string
+	Event     string
+	Channel   string
+	Subject   string
+	Body      string
+	CreatedAt time.Time
+}
+
+// SummarySchedule is when the next activity summary of the user is due. NextAt is nil if the
+// summary has not been scheduled yet or the time zone has changed.
+type SummarySchedule struct {
+	UserId   string
+	TimeZone string
+	NextAt   *time.Time
+}
+
+// ActivitySummary is the account activity of the user over a period.
+type ActivitySummary struct {
+	From time.Time
+	To   time.Time
+	// SignIns are the most recent successful sign-ins, TotalSignIns counts all of them.
+	SignIns        []AuditEvent
+	TotalSignIns   int
+	FailedSignIns  int
+	ActiveSessions []Session
+}
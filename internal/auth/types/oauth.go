@@ -0,0 +1,108 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	ClientTypePublic       = "public"
+	ClientTypeConfidential = "confidential"
+)
+
+type OAuthClient struct {
+	ClientId     string
+	Name         string
+	Type         string
+	RedirectURIs []string
+	Scopes       []string
+	GrantTypes   []string
+	// ExchangeAudiences are the other clients whose tokens the client may exchange.
+	ExchangeAudiences []string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (c *OAuthClient) IsConfidential() bool {
+	return c.Type == ClientTypeConfidential
+}
+
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+type OAuthClientSecret struct {
+	Id         string
+	ClientId   string
+	SecretHash string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+}
+
+func (s *OAuthClientSecret) IsExpired() bool {
+	return s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt)
+}
+
+// OAuthClientDTO is used by administrators to register and update clients.
+type OAuthClientDTO struct {
+	ClientId     string
+	Name         string
+	Type         string
+	RedirectURIs []string
+	Scopes       []string
+	GrantTypes   []string
+	// ExchangeAudiences are the other clients whose tokens the client may exchange.
+	ExchangeAudiences []string
+}
+
+type AuthorizationCode struct {
+	CodeHash            string
+	ClientId            string
+	UserId              string
+	RedirectURI         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	AuthTime            time.Time
+	ExpiresAt           time.Time
+	Used                bool
+	// SessionId is the session the code was exchanged for, empty until it is used.
+	SessionId string
+}
+
+func (c *AuthorizationCode) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientId            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+type TokenRequest struct {
+	GrantType    string
+	ClientId     string
+	ClientSecret string
+	Scope        string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	DeviceCode   string
+	Exchange     TokenExchangeRequest
+}
+
+// UserInfo holds the OpenID Connect claims released for the granted scopes.
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+}
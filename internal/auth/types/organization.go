@@ -0,0 +1,42 @@
+package types
+
+import "time"
+
+const (
+	// EmailUniquenessGlobal allows one user per email across the service.
+	EmailUniquenessGlobal = "global"
+	// EmailUniquenessOrganization allows one user per email in every organization.
+	EmailUniquenessOrganization = "organization"
+)
+
+type Organization struct {
+	Id        string
+	Name      string
+	Slug      string
+	CreatedAt time.Time
+}
+
+type Membership struct {
+	OrganizationId   string
+	OrganizationName string
+	UserId           string
+	Email            string
+	Roles            []string
+	CreatedAt        time.Time
+}
+
+type Invitation struct {
+	Id             string
+	OrganizationId string
+	Email          string
+	RoleIds        []string
+	TokenHash      string
+	InvitedBy      string
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+	AcceptedAt     *time.Time
+}
+
+func (i *Invitation) IsExpired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
@@ -0,0 +1,60 @@
+package types
+
+import "time"
+
+// Purposes of one-time codes sent by SMS.
+const (
+	SMSCodePhoneVerification = "phone_verification"
+	SMSCodePasswordReset     = "password_reset"
+)
+
+// UserPhone is the phone number of a user. Codes and notifications are sent to the phone
+// only after it is verified.
+type UserPhone struct {
+	UserId     string
+	Phone      string
+	VerifiedAt *time.Time
+	// UseForMFA sends sign-in codes by SMS instead of email once the phone is verified.
+	UseForMFA bool
+	// PendingPhone replaces the verified Phone once it is verified itself, until then codes
+	// and notifications still go to Phone. PendingUseForMFA becomes UseForMFA with it.
+	PendingPhone     string
+	PendingUseForMFA bool
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func (p *UserPhone) IsVerified() bool {
+	return p.VerifiedAt != nil
+}
+
+// SMSCode is a one-time code sent to Phone, identified by the hash of a token returned to
+// the client that requested it.
+type SMSCode struct {
+	TokenHash string
+	UserId    string
+	Purpose   string
+	Phone     string
+	CodeHash  string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func (c *SMSCode) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// SMSChallenge is returned when a code is sent: Token and the code complete the action.
+type SMSChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// ChannelMessage is a message to a user. Text is the plain text version for channels
+// without markup such as SMS.
+type ChannelMessage struct {
+	Subject string
+	HTML    string
+	Text    string
+}
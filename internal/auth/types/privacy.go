@@ -0,0 +1,117 @@
+package types
+
+import "time"
+
+// UserDataExport is everything stored about a user, exported on a data subject access request.
+// Secrets such as password hashes and refresh tokens are left out.
+type UserDataExport struct {
+	ExportedAt     time.Time             `json:"exported_at"`
+	Profile        ProfileExport         `json:"profile"`
+	Sessions       []SessionExport       `json:"sessions"`
+	Identities     []IdentityExport      `json:"identities"`
+	Roles          []RoleExport          `json:"roles"`
+	Memberships    []MembershipExport    `json:"memberships"`
+	TokenExchanges []TokenExchangeExport `json:"token_exchanges"`
+	LoginHistory   []LoginAttemptExport  `json:"login_history"`
+	Devices        []KnownDeviceExport   `json:"devices"`
+	Notifications  []NotificationExport  `json:"notification_preferences"`
+	AuditEvents    []AuditEventExport    `json:"audit_events"`
+}
+
+type ProfileExport struct {
+	Id                    string     `json:"id"`
+	Email                 string     `json:"email"`
+	OrganizationId        string     `json:"organization_id,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	Status                string     `json:"status"`
+	StatusReason          string     `json:"status_reason,omitempty"`
+	StatusChangedAt       *time.Time `json:"status_changed_at,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	Phone                 string     `json:"phone,omitempty"`
+	PhoneVerifiedAt       *time.Time `json:"phone_verified_at,omitempty"`
+	PendingPhone          string     `json:"pending_phone,omitempty"`
+	TimeZone              string     `json:"time_zone,omitempty"`
+}
+
+type SessionExport struct {
+	Id             string     `json:"id"`
+	IP             string     `json:"ip"`
+	Country        string     `json:"country,omitempty"`
+	City           string     `json:"city,omitempty"`
+	ASN            int64      `json:"asn,omitempty"`
+	ClientId       string     `json:"client_id,omitempty"`
+	Scope          string     `json:"scope,omitempty"`
+	OrganizationId string     `json:"organization_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	Used           bool       `json:"used"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+}
+
+type IdentityExport struct {
+	Provider    string    `json:"provider"`
+	Subject     string    `json:"subject"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+type RoleExport struct {
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MembershipExport struct {
+	OrganizationId   string    `json:"organization_id"`
+	OrganizationName string    `json:"organization_name"`
+	Roles            []string  `json:"roles"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type TokenExchangeExport struct {
+	ClientId       string    `json:"client_id"`
+	Subject        string    `json:"subject"`
+	Actor          string    `json:"actor,omitempty"`
+	RequestedScope string    `json:"requested_scope,omitempty"`
+	GrantedScope   string    `json:"granted_scope,omitempty"`
+	IP             string    `json:"ip"`
+	Success        bool      `json:"success"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type LoginAttemptExport struct {
+	Method        string    `json:"method"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	IP            string    `json:"ip"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	Device        string    `json:"device"`
+	Location      string    `json:"location,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type KnownDeviceExport struct {
+	Name         string     `json:"name"`
+	IP           string     `json:"ip"`
+	Location     string     `json:"location,omitempty"`
+	TrustedUntil *time.Time `json:"trusted_until,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastSeenAt   time.Time  `json:"last_seen_at"`
+}
+
+type NotificationExport struct {
+	Event    string   `json:"event"`
+	Channels []string `json:"channels"`
+	Delivery string   `json:"delivery"`
+}
+
+type AuditEventExport struct {
+	Type      string            `json:"type"`
+	ActorId   string            `json:"actor_id,omitempty"`
+	UserId    string            `json:"user_id,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	RoleAdmin = "admin"
+
+	// RoleSourceLocal marks roles assigned through the admin API. Roles granted by an
+	// LDAP directory use the directory name as the source.
+	RoleSourceLocal = "local"
+)
+
+const (
+	PermissionClientsRead  = "clients:read"
+	PermissionClientsWrite = "clients:write"
+	PermissionRolesRead    = "roles:read"
+	PermissionRolesWrite   = "roles:write"
+
+	PermissionUsersRead  = "users:read"
+	PermissionUsersWrite = "users:write"
+
+	PermissionOrganizationsRead  = "organizations:read"
+	PermissionOrganizationsWrite = "organizations:write"
+
+	PermissionAuditRead = "audit:read"
+
+	PermissionWebhooksRead  = "webhooks:read"
+	PermissionWebhooksWrite = "webhooks:write"
+
+	// Members permissions are checked against the roles a user has in the current organization.
+	PermissionMembersRead  = "members:read"
+	PermissionMembersWrite = "members:write"
+)
+
+type Permission struct {
+	Name        string
+	Description string
+}
+
+// Permissions lists every permission the service checks.
+var Permissions = []Permission{
+	{Name: PermissionClientsRead, Description: "View OAuth clients"},
+	{Name: PermissionClientsWrite, Description: "Manage OAuth clients and their secrets"},
+	{Name: PermissionRolesRead, Description: "View roles and role assignments"},
+	{Name: PermissionRolesWrite, Description: "Manage roles and assign them to users"},
+	{Name: PermissionUsersRead, Description: "View users and their sessions"},
+	{Name: PermissionUsersWrite, Description: "Disable, delete and reset users, revoke their sessions"},
+	{Name: PermissionOrganizationsRead, Description: "View organizations and their members"},
+	{Name: PermissionOrganizationsWrite, Description: "Manage organizations and their members"},
+	{Name: PermissionMembersRead, Description: "View members of the current organization"},
+	{Name: PermissionMembersWrite, Description: "Invite and manage members of the current organization"},
+	{Name: PermissionAuditRead, Description: "View and verify the security audit log"},
+	{Name: PermissionWebhooksRead, Description: "View webhook subscriptions and their deliveries"},
+	{Name: PermissionWebhooksWrite, Description: "Manage webhook subscriptions and replay deliveries"},
+}
+
+// OrganizationPermissions are the permissions checked against the roles a user has in an
+// organization. Only roles limited to them can be given to members.
+var OrganizationPermissions = []string{PermissionMembersRead, PermissionMembersWrite}
+
+func PermissionNames() []string {
+	names := make([]string, 0, len(Permissions))
+	for _, permission := range Permissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
+
+type Role struct {
+	Id          string
+	Name        string
+	Description string
+	Permissions []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// IsOrganizationRole reports whether the role grants only organization permissions, so
+// giving it to a member of an organization grants nothing outside the organization.
+func (r *Role) IsOrganizationRole() bool {
+	if r.Name == RoleAdmin {
+		return false
+	}
+	for _, permission := range r.Permissions {
+		if !slices.Contains(OrganizationPermissions, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// RoleDTO is used by administrators to create and update roles.
+type RoleDTO struct {
+	Id          string
+	Name        string
+	Description string
+	Permissions []string
+}
+
+type UserRole struct {
+	UserId    string
+	RoleId    string
+	RoleName  string
+	Source    string
+	CreatedAt time.Time
+}
@@ -3,13 +3,38 @@ package types
 import "time"
 
 type Session struct {
-	SessionId    string
-	UserId       string
-	RefreshToken string
-	ExpiresAt    time.Time
-	Used         bool
+	SessionId      string
+	UserId         string
+	RefreshToken   string
+	ExpiresAt      time.Time
+	Used           bool
+	ClientId       string
+	Scope          string
+	OrganizationId string
+	IP             string
+	// Country, City and ASN locate IP, they are empty if the location is unknown.
+	Country   string
+	City      string
+	ASN       int64
+	CreatedAt time.Time
+	RevokedAt *time.Time
 }
 
 func (s *Session) IsRefreshTokenExpired() bool {
 	return time.Now().After(s.ExpiresAt)
 }
+
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// SessionParams describes who a new session is issued to.
+type SessionParams struct {
+	// SessionId is the id of the new session, a random one is used if it is empty.
+	SessionId      string
+	UserId         string
+	IP             string
+	ClientId       string
+	Scope          string
+	OrganizationId string
+}
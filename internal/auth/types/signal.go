@@ -0,0 +1,59 @@
+package types
+
+import "time"
+
+// Security event statuses.
+const (
+	SecurityEventPending   = "pending"
+	SecurityEventDelivered = "delivered"
+	SecurityEventFailed    = "failed"
+)
+
+// SignalStream is the Shared Signals stream of a receiver, an OAuth client that manages the
+// stream with its client credentials. Events of EventsRequested are pushed to EndpointURL
+// or kept for the receiver to poll, depending on DeliveryMethod.
+type SignalStream struct {
+	Id             string
+	ClientId       string
+	DeliveryMethod string
+	EndpointURL    string
+	// AuthorizationHeader is sent with pushed events.
+	AuthorizationHeader string
+	EventsRequested     []string
+	Description         string
+	Status              string
+	StatusReason        string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// SignalStreamDTO is used by receivers to create and update their streams. Nil fields are
+// left unchanged on update.
+type SignalStreamDTO struct {
+	Id                  string
+	DeliveryMethod      *string
+	EndpointURL         *string
+	AuthorizationHeader *string
+	EventsRequested     []string
+	Description         *string
+}
+
+// SecurityEvent is an event queued for a stream, sent to the receiver as a Security Event
+// Token with Id as its jti.
+type SecurityEvent struct {
+	Id       string
+	StreamId string
+	Type     string
+	// UserId and SessionId are the subject of the event, the stream itself if both are empty.
+	UserId    string
+	SessionId string
+	// Txn is the id of the audit event the event was made of, shared by events of one change.
+	Txn string
+	// Payload is the JSON object of the event type in the events claim.
+	Payload       []byte
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	Error         string
+	CreatedAt     time.Time
+}
@@ -1,6 +1,31 @@
 package types
 
+import "time"
+
 type Tokens struct {
 	AccessToken  string
 	RefreshToken string
+	IDToken      string
+	TokenType    string
+	ExpiresIn    time.Duration
+	Scope        string
+}
+
+// Identity is the caller authenticated by an access token. Tokens issued by the
+// client credentials grant identify the client, and UserId is empty.
+type Identity struct {
+	SessionId string
+	UserId    string
+	IP        string
+	ClientId  string
+	Scope     string
+	Roles     []string
+	OrgId     string
+	OrgRoles  []string
+	// Actor is the subject acting on behalf of UserId in exchanged tokens.
+	Actor string
+}
+
+func (i Identity) IsClient() bool {
+	return i.UserId == ""
 }
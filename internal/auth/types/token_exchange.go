@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+type TokenExchangeRequest struct {
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string
+	ActorTokenType     string
+	RequestedTokenType string
+	Scope              string
+}
+
+// TokenExchange is the audit record of a single token exchange attempt.
+type TokenExchange struct {
+	Id             string
+	ClientId       string
+	Subject        string
+	Actor          string
+	RequestedScope string
+	GrantedScope   string
+	IP             string
+	Success        bool
+	Error          string
+	TokenId        string
+	ExpiresAt      *time.Time
+	CreatedAt      time.Time
+}
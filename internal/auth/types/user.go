@@ -1,12 +1,93 @@
 package types
 
+import (
+	"slices"
+	"time"
+)
+
+const (
+	UserStatusActive          = "active"
+	UserStatusDisabled        = "disabled"
+	UserStatusBanned          = "banned"
+	UserStatusPendingDeletion = "pending_deletion"
+)
+
+var UserStatuses = []string{
+	UserStatusActive,
+	UserStatusDisabled,
+	UserStatusBanned,
+	UserStatusPendingDeletion,
+}
+
+func IsUserStatus(status string) bool {
+	return slices.Contains(UserStatuses, status)
+}
+
 type User struct {
-	UserUUID string
-	Email    string
-	Password string
+	UserUUID              string
+	Email                 string
+	Password              string
+	OrganizationId        string
+	CreatedAt             time.Time
+	PasswordResetRequired bool
+	Status                string
+	StatusReason          string
+	StatusChangedAt       *time.Time
+	// PurgeAt is when a user pending deletion is removed for good.
+	PurgeAt *time.Time
+}
+
+func (u *User) IsActive() bool {
+	return u.Status == UserStatusActive
 }
 
 type UserDTO struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email          string `json:"email"`
+	Password       string `json:"password"`
+	OrganizationId string `json:"organization_id"`
+}
+
+// UserFilter selects a page of users for the admin API. Query matches a part of the email.
+type UserFilter struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// UserStatusChange describes a new status of a user set by an operator.
+type UserStatusChange struct {
+	UserId  string
+	Status  string
+	Reason  string
+	PurgeAt *time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	UserId    string
+	ExpiresAt time.Time
+}
+
+// PasswordChange is a new password set by the user. With RevokeSessions every session
+// of the user except KeepSessionId is revoked.
+type PasswordChange struct {
+	UserId         string
+	PasswordHash   string
+	RevokeSessions bool
+	KeepSessionId  string
+}
+
+// AccountDeletionToken confirms that the user wants the account to be erased.
+type AccountDeletionToken struct {
+	TokenHash string
+	UserId    string
+	ExpiresAt time.Time
+}
+
+// EmailChangeToken confirms that the user owns the new email address.
+type EmailChangeToken struct {
+	TokenHash string
+	UserId    string
+	NewEmail  string
+	ExpiresAt time.Time
 }
@@ -0,0 +1,83 @@
+package types
+
+import "time"
+
+// WebhookEventAll subscribes to every audit event type.
+const WebhookEventAll = "*"
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSubscription sends the audit events of EventTypes to URL. Payloads are signed with Secret.
+type WebhookSubscription struct {
+	Id          string
+	URL         string
+	Description string
+	EventTypes  []string
+	Secret      string
+	Active      bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// WebhookSubscriptionDTO is used by administrators to create and update subscriptions.
+type WebhookSubscriptionDTO struct {
+	Id          string
+	URL         string
+	Description string
+	EventTypes  []string
+	// Active defaults to true for new subscriptions and is left unchanged on update when nil.
+	Active *bool
+}
+
+// WebhookDelivery is an audit event queued for a subscription. A pending delivery is sent
+// at NextAttemptAt until it succeeds or runs out of attempts.
+type WebhookDelivery struct {
+	Id             string
+	SubscriptionId string
+	EventId        int64
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastAttemptAt  *time.Time
+	ResponseStatus int
+	Error          string
+	// ReplayOf is the delivery this one replays.
+	ReplayOf  string
+	CreatedAt time.Time
+}
+
+// WebhookAttempt is the log of one attempt to send a delivery.
+type WebhookAttempt struct {
+	DeliveryId string
+	Attempt    int
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionId string
+	Status         string
+	Limit          int
+	Offset         int
+}
+
+// WebhookPayload is the JSON body of a delivery.
+type WebhookPayload struct {
+	EventId   int64             `json:"event_id"`
+	Type      string            `json:"type"`
+	ActorId   string            `json:"actor_id,omitempty"`
+	UserId    string            `json:"user_id,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
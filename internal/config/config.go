@@ -8,10 +8,22 @@ import (
 )
 
 type Config struct {
-	DBConfig     DBConfig
-	ServerConfig ServerConfig
-	AuthConfig   AuthConfig
-	SMTPConfig   SMTPConfig
+	DBConfig       DBConfig
+	ServerConfig   ServerConfig
+	AuthConfig     AuthConfig
+	SMTPConfig     SMTPConfig
+	SMSConfig      SMSConfig
+	OAuthConfig    OAuthConfig
+	AdminConfig    AdminConfig
+	SocialConfig   SocialConfig
+	LDAPConfig     LDAPConfig
+	OrgConfig      OrganizationConfig
+	PasswordConfig PasswordConfig
+	GeoIPConfig    GeoIPConfig
+	RiskConfig     RiskConfig
+	NotifyConfig   NotificationConfig
+	WebhookConfig  WebhookConfig
+	SignalConfig   SignalConfig
 }
 
 type DBConfig struct {
@@ -28,9 +40,17 @@ type ServerConfig struct {
 }
 
 type AuthConfig struct {
-	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL"`
-	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL"`
-	SigningKey      string        `env:"SIGNING_KEY"`
+	AccessTokenTTL        time.Duration `env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL       time.Duration `env:"REFRESH_TOKEN_TTL"`
+	SigningKey            string        `env:"SIGNING_KEY"`
+	PasswordResetTTL      time.Duration `env:"PASSWORD_RESET_TTL" envDefault:"1h"`
+	EmailChangeTTL        time.Duration `env:"EMAIL_CHANGE_TTL" envDefault:"24h"`
+	AccountDeletionTTL    time.Duration `env:"ACCOUNT_DELETION_TTL" envDefault:"1h"`
+	UserPurgeDelay        time.Duration `env:"USER_PURGE_DELAY" envDefault:"720h"`
+	UserPurgeInterval     time.Duration `env:"USER_PURGE_INTERVAL" envDefault:"1h"`
+	LoginHistoryRetention time.Duration `env:"LOGIN_HISTORY_RETENTION" envDefault:"2160h"`
+	DeviceCookieTTL       time.Duration `env:"DEVICE_COOKIE_TTL" envDefault:"8760h"`
+	DeviceTrustTTL        time.Duration `env:"DEVICE_TRUST_TTL" envDefault:"720h"`
 }
 
 type SMTPConfig struct {
@@ -40,6 +60,99 @@ type SMTPConfig struct {
 	From string `env:"SMTP_FROM"`
 }
 
+type SMSConfig struct {
+	Provider string `env:"SMS_PROVIDER"`
+	FakeFile string `env:"SMS_FAKE_FILE"`
+}
+
+type OAuthConfig struct {
+	AuthorizationCodeTTL time.Duration `env:"AUTHORIZATION_CODE_TTL" envDefault:"1m"`
+	Issuer               string        `env:"OIDC_ISSUER" envDefault:"http://localhost:8082"`
+	IDTokenTTL           time.Duration `env:"OIDC_ID_TOKEN_TTL" envDefault:"15m"`
+	PrivateKeyFile       string        `env:"OIDC_PRIVATE_KEY_FILE"`
+	ClientTokenTTL       time.Duration `env:"CLIENT_TOKEN_TTL" envDefault:"15m"`
+	SecretRotationGrace  time.Duration `env:"CLIENT_SECRET_ROTATION_GRACE" envDefault:"168h"`
+	DeviceCodeTTL        time.Duration `env:"DEVICE_CODE_TTL" envDefault:"10m"`
+	DevicePollInterval   time.Duration `env:"DEVICE_POLL_INTERVAL" envDefault:"5s"`
+	ExchangeTokenTTL     time.Duration `env:"TOKEN_EXCHANGE_TTL" envDefault:"5m"`
+}
+
+type SocialConfig struct {
+	ProvidersFile string        `env:"SOCIAL_PROVIDERS_FILE"`
+	StateTTL      time.Duration `env:"SOCIAL_LOGIN_STATE_TTL" envDefault:"10m"`
+}
+
+type LDAPConfig struct {
+	DirectoriesFile string        `env:"LDAP_DIRECTORIES_FILE"`
+	Timeout         time.Duration `env:"LDAP_TIMEOUT" envDefault:"5s"`
+}
+
+type OrganizationConfig struct {
+	EmailUniqueness string        `env:"EMAIL_UNIQUENESS" envDefault:"global"`
+	InvitationTTL   time.Duration `env:"ORG_INVITATION_TTL" envDefault:"168h"`
+}
+
+type PasswordConfig struct {
+	MinLength        int           `env:"PASSWORD_MIN_LENGTH" envDefault:"8"`
+	MaxLength        int           `env:"PASSWORD_MAX_LENGTH" envDefault:"64"`
+	RequireLower     bool          `env:"PASSWORD_REQUIRE_LOWER" envDefault:"false"`
+	RequireUpper     bool          `env:"PASSWORD_REQUIRE_UPPER" envDefault:"false"`
+	RequireDigit     bool          `env:"PASSWORD_REQUIRE_DIGIT" envDefault:"false"`
+	RequireSymbol    bool          `env:"PASSWORD_REQUIRE_SYMBOL" envDefault:"false"`
+	MinStrength      int           `env:"PASSWORD_MIN_STRENGTH" envDefault:"2"`
+	BannedWords      []string      `env:"PASSWORD_BANNED_WORDS" envSeparator:","`
+	BreachedDir      string        `env:"HIBP_DATASET_DIR"`
+	HistorySize      int           `env:"PASSWORD_HISTORY_SIZE" envDefault:"5"`
+	HistoryRetention time.Duration `env:"PASSWORD_HISTORY_RETENTION" envDefault:"8760h"`
+}
+
+type GeoIPConfig struct {
+	CityDB         string        `env:"GEOIP_CITY_DB"`
+	ASNDB          string        `env:"GEOIP_ASN_DB"`
+	Language       string        `env:"GEOIP_LANGUAGE" envDefault:"en"`
+	ReloadInterval time.Duration `env:"GEOIP_RELOAD_INTERVAL" envDefault:"1m"`
+}
+
+type RiskConfig struct {
+	Weights              string        `env:"RISK_WEIGHTS" envDefault:"new_device:20,new_country:30,impossible_travel:60,asn_change:10,failed_attempts:30,tor:40,bad_ip:80"`
+	Bands                string        `env:"RISK_BANDS" envDefault:"0:allow,30:notify,60:mfa,90:block"`
+	TorExitList          string        `env:"RISK_TOR_EXIT_LIST"`
+	BadIPList            string        `env:"RISK_BAD_IP_LIST"`
+	ListReloadInterval   time.Duration `env:"RISK_LIST_RELOAD_INTERVAL" envDefault:"1m"`
+	FailedAttempts       int           `env:"RISK_FAILED_ATTEMPTS" envDefault:"5"`
+	FailedAttemptsWindow time.Duration `env:"RISK_FAILED_ATTEMPTS_WINDOW" envDefault:"15m"`
+	MaxTravelSpeed       float64       `env:"RISK_MAX_TRAVEL_SPEED" envDefault:"1000"`
+}
+
+type NotificationConfig struct {
+	DigestInterval  time.Duration `env:"NOTIFICATION_DIGEST_INTERVAL" envDefault:"24h"`
+	SummaryWeekday  string        `env:"ACTIVITY_SUMMARY_WEEKDAY" envDefault:"monday"`
+	SummaryHour     int           `env:"ACTIVITY_SUMMARY_HOUR" envDefault:"9"`
+	SummaryInterval time.Duration `env:"ACTIVITY_SUMMARY_INTERVAL" envDefault:"15m"`
+}
+
+type WebhookConfig struct {
+	Timeout           time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	MaxAttempts       int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"8"`
+	RetryBackoff      time.Duration `env:"WEBHOOK_RETRY_BACKOFF" envDefault:"30s"`
+	MaxBackoff        time.Duration `env:"WEBHOOK_MAX_BACKOFF" envDefault:"6h"`
+	PollInterval      time.Duration `env:"WEBHOOK_POLL_INTERVAL" envDefault:"5s"`
+	DeliveryRetention time.Duration `env:"WEBHOOK_DELIVERY_RETENTION" envDefault:"720h"`
+}
+
+type SignalConfig struct {
+	Timeout        time.Duration `env:"SSF_TIMEOUT" envDefault:"10s"`
+	MaxAttempts    int           `env:"SSF_MAX_ATTEMPTS" envDefault:"8"`
+	RetryBackoff   time.Duration `env:"SSF_RETRY_BACKOFF" envDefault:"30s"`
+	MaxBackoff     time.Duration `env:"SSF_MAX_BACKOFF" envDefault:"6h"`
+	PollInterval   time.Duration `env:"SSF_POLL_INTERVAL" envDefault:"5s"`
+	EventRetention time.Duration `env:"SSF_EVENT_RETENTION" envDefault:"720h"`
+}
+
+type AdminConfig struct {
+	BootstrapEmail string `env:"BOOTSTRAP_ADMIN_EMAIL"`
+}
+
 func (s *ServerConfig) Address() string {
 	return fmt.Sprintf("localhost:%s", s.HTTPPort)
 }
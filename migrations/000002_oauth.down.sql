@@ -0,0 +1,7 @@
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS scope,
+    DROP COLUMN IF EXISTS client_id;
+
+DROP TABLE IF EXISTS oauth_authorization_codes;
+
+DROP TABLE IF EXISTS oauth_clients;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE oauth_authorization_codes
+    DROP COLUMN IF EXISTS auth_time,
+    DROP COLUMN IF EXISTS nonce;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE oauth_authorization_codes
+    ADD COLUMN nonce TEXT NOT NULL DEFAULT '',
+    ADD COLUMN auth_time TIMESTAMP NOT NULL DEFAULT now();
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS oauth_client_secrets;
+
+ALTER TABLE oauth_clients
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS grant_types,
+    DROP COLUMN IF EXISTS client_type;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE oauth_clients
+    ADD COLUMN client_type VARCHAR(20) NOT NULL DEFAULT 'public',
+    ADD COLUMN grant_types TEXT[] NOT NULL DEFAULT '{authorization_code,refresh_token}',
+    ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT now();
+
+CREATE TABLE oauth_client_secrets
+(
+    id UUID NOT NULL UNIQUE,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    secret_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX oauth_client_secrets_client_id_idx ON oauth_client_secrets (client_id);
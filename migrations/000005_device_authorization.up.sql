@@ -0,0 +1,13 @@
+CREATE TABLE oauth_device_codes
+(
+    device_code_hash TEXT NOT NULL UNIQUE,
+    user_code VARCHAR(16) NOT NULL UNIQUE,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    scope TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    user_uuid UUID,
+    poll_interval INTEGER NOT NULL,
+    last_polled_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS token_exchanges;
\ No newline at end of file
@@ -0,0 +1,17 @@
+CREATE TABLE token_exchanges
+(
+    id UUID NOT NULL UNIQUE,
+    client_id VARCHAR(64) NOT NULL,
+    subject VARCHAR(64) NOT NULL DEFAULT '',
+    actor VARCHAR(64) NOT NULL DEFAULT '',
+    requested_scope TEXT NOT NULL DEFAULT '',
+    granted_scope TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    token_id UUID,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX token_exchanges_subject_idx ON token_exchanges (subject, created_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS social_login_states;
+
+DROP TABLE IF EXISTS identities;
\ No newline at end of file
@@ -0,0 +1,22 @@
+CREATE TABLE identities
+(
+    id UUID NOT NULL UNIQUE,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_login_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX identities_user_uuid_idx ON identities (user_uuid);
+
+CREATE TABLE social_login_states
+(
+    state_hash TEXT NOT NULL UNIQUE,
+    provider VARCHAR(64) NOT NULL,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
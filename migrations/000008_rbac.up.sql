@@ -0,0 +1,30 @@
+CREATE TABLE roles
+(
+    id UUID NOT NULL UNIQUE,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE permissions
+(
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions
+(
+    role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+    UNIQUE (role_id, permission)
+);
+
+CREATE TABLE user_roles
+(
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    source VARCHAR(64) NOT NULL DEFAULT 'local',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (user_uuid, role_id, source)
+);
@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS users_organization_email_idx;
+DROP INDEX IF EXISTS users_email_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS organization_id,
+    ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS organization_id;
+
+DROP TABLE IF EXISTS organization_invitations;
+
+DROP TABLE IF EXISTS membership_roles;
+
+DROP TABLE IF EXISTS memberships;
+
+DROP TABLE IF EXISTS organizations;
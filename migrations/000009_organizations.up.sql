@@ -0,0 +1,51 @@
+CREATE TABLE organizations
+(
+    id UUID NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE memberships
+(
+    organization_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (organization_id, user_uuid)
+);
+
+CREATE INDEX memberships_user_uuid_idx ON memberships (user_uuid);
+
+CREATE TABLE membership_roles
+(
+    organization_id UUID NOT NULL,
+    user_uuid UUID NOT NULL,
+    role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    UNIQUE (organization_id, user_uuid, role_id),
+    FOREIGN KEY (organization_id, user_uuid) REFERENCES memberships (organization_id, user_uuid) ON DELETE CASCADE
+);
+
+CREATE TABLE organization_invitations
+(
+    id UUID NOT NULL UNIQUE,
+    organization_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL,
+    role_ids UUID[] NOT NULL DEFAULT '{}',
+    token_hash TEXT NOT NULL UNIQUE,
+    invited_by UUID REFERENCES users (user_uuid) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP
+);
+
+ALTER TABLE sessions
+    ADD COLUMN organization_id UUID;
+
+-- With EMAIL_UNIQUENESS=organization the same email may be registered once
+-- per organization and once without an organization.
+ALTER TABLE users
+    ADD COLUMN organization_id UUID REFERENCES organizations (id),
+    DROP CONSTRAINT users_email_key;
+
+CREATE UNIQUE INDEX users_email_idx ON users (email) WHERE organization_id IS NULL;
+CREATE UNIQUE INDEX users_organization_email_idx ON users (organization_id, email) WHERE organization_id IS NOT NULL;
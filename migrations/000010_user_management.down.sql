@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS password_reset_tokens;
+
+DROP INDEX IF EXISTS sessions_user_uuid_idx;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS ip;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS password_reset_required,
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,20 @@
+ALTER TABLE users
+    ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT now(),
+    ADD COLUMN disabled_at TIMESTAMP,
+    ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE sessions
+    ADD COLUMN ip VARCHAR(45) NOT NULL DEFAULT '',
+    ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT now(),
+    ADD COLUMN revoked_at TIMESTAMP;
+
+CREATE INDEX sessions_user_uuid_idx ON sessions (user_uuid);
+
+CREATE TABLE password_reset_tokens
+(
+    token_hash TEXT NOT NULL UNIQUE,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
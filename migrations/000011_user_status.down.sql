@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS users_purge_at_idx;
+
+ALTER TABLE users
+    ADD COLUMN disabled_at TIMESTAMP;
+
+UPDATE users
+SET disabled_at = COALESCE(status_changed_at, now())
+WHERE status <> 'active';
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS purge_at,
+    DROP COLUMN IF EXISTS status_changed_at,
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,14 @@
+ALTER TABLE users
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN status_reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN status_changed_at TIMESTAMP,
+    ADD COLUMN purge_at TIMESTAMP;
+
+UPDATE users
+SET status = 'disabled', status_changed_at = disabled_at
+WHERE disabled_at IS NOT NULL;
+
+ALTER TABLE users
+    DROP COLUMN disabled_at;
+
+CREATE INDEX users_purge_at_idx ON users (purge_at) WHERE status = 'pending_deletion';
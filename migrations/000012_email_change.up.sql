@@ -0,0 +1,11 @@
+CREATE TABLE email_change_tokens
+(
+    token_hash TEXT NOT NULL UNIQUE,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    new_email VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX email_change_tokens_user_uuid_idx ON email_change_tokens (user_uuid);
@@ -0,0 +1,9 @@
+CREATE TABLE password_history
+(
+    id BIGSERIAL PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX password_history_user_uuid_idx ON password_history (user_uuid, created_at DESC);
@@ -0,0 +1,8 @@
+CREATE TABLE account_deletion_tokens
+(
+    token_hash TEXT NOT NULL UNIQUE,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
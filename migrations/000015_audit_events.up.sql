@@ -0,0 +1,43 @@
+CREATE TABLE audit_events
+(
+    id BIGINT PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    actor_id VARCHAR(64) NOT NULL DEFAULT '',
+    user_id VARCHAR(64) NOT NULL DEFAULT '',
+    details_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL
+);
+
+-- Personal data of the events is kept out of the hash chain, so that it can be erased
+-- together with the user. The chain covers only the salted details_hash of it.
+CREATE TABLE audit_event_details
+(
+    event_id BIGINT PRIMARY KEY REFERENCES audit_events (id),
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    metadata JSONB NOT NULL DEFAULT '{}',
+    salt VARCHAR(64) NOT NULL
+);
+
+CREATE INDEX audit_events_user_id_idx ON audit_events (user_id, id);
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, id);
+CREATE INDEX audit_events_type_idx ON audit_events (type, id);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- The audit log is append-only.
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,16 @@
+CREATE TABLE login_attempts
+(
+    id BIGSERIAL PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    method VARCHAR(32) NOT NULL,
+    success BOOLEAN NOT NULL,
+    failure_reason VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    device VARCHAR(255) NOT NULL DEFAULT '',
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX login_attempts_user_uuid_idx ON login_attempts (user_uuid, created_at DESC);
+CREATE INDEX login_attempts_created_at_idx ON login_attempts (created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS country,
+    DROP COLUMN IF EXISTS city,
+    DROP COLUMN IF EXISTS asn;
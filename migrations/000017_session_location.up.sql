@@ -0,0 +1,4 @@
+ALTER TABLE sessions
+    ADD COLUMN country VARCHAR(2) NOT NULL DEFAULT '',
+    ADD COLUMN city VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN asn BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE login_attempts
+    DROP COLUMN IF EXISTS country,
+    DROP COLUMN IF EXISTS asn,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS longitude;
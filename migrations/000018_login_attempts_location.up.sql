@@ -0,0 +1,5 @@
+ALTER TABLE login_attempts
+    ADD COLUMN country VARCHAR(2) NOT NULL DEFAULT '',
+    ADD COLUMN asn BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN latitude DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN longitude DOUBLE PRECISION NOT NULL DEFAULT 0;
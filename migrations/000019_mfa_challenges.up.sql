@@ -0,0 +1,14 @@
+CREATE TABLE mfa_challenges
+(
+    token_hash CHAR(64) PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    organization_id UUID REFERENCES organizations (id) ON DELETE CASCADE,
+    method VARCHAR(32) NOT NULL,
+    channel VARCHAR(32) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX mfa_challenges_expires_at_idx ON mfa_challenges (expires_at);
@@ -0,0 +1,15 @@
+CREATE TABLE known_devices
+(
+    id UUID PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    device_hash CHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    trusted_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (user_uuid, device_hash)
+);
+
+CREATE INDEX known_devices_last_seen_at_idx ON known_devices (last_seen_at);
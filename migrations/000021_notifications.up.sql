@@ -0,0 +1,22 @@
+CREATE TABLE notification_preferences
+(
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    channels TEXT[] NOT NULL DEFAULT '{}',
+    delivery VARCHAR(32) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_uuid, event)
+);
+
+CREATE TABLE pending_notifications
+(
+    id BIGSERIAL PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    channel VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX pending_notifications_user_uuid_idx ON pending_notifications (user_uuid, id);
@@ -0,0 +1,34 @@
+CREATE TABLE signal_streams
+(
+    id UUID PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL UNIQUE REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    delivery_method VARCHAR(64) NOT NULL,
+    endpoint_url TEXT NOT NULL DEFAULT '',
+    authorization_header TEXT NOT NULL DEFAULT '',
+    events_requested TEXT[] NOT NULL DEFAULT '{}',
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'enabled',
+    status_reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE security_events
+(
+    id UUID PRIMARY KEY,
+    stream_id UUID NOT NULL REFERENCES signal_streams (id) ON DELETE CASCADE,
+    event_type VARCHAR(255) NOT NULL,
+    user_id VARCHAR(64) NOT NULL DEFAULT '',
+    session_id VARCHAR(64) NOT NULL DEFAULT '',
+    txn VARCHAR(64) NOT NULL DEFAULT '',
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX security_events_stream_id_idx ON security_events (stream_id, created_at) WHERE status = 'pending';
+CREATE INDEX security_events_next_attempt_at_idx ON security_events (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX security_events_created_at_idx ON security_events (created_at);
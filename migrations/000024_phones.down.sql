@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sms_codes;
+DROP TABLE IF EXISTS user_phones;
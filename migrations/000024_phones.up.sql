@@ -0,0 +1,24 @@
+CREATE TABLE user_phones
+(
+    user_uuid UUID PRIMARY KEY REFERENCES users (user_uuid) ON DELETE CASCADE,
+    phone VARCHAR(16) NOT NULL,
+    verified_at TIMESTAMP,
+    use_for_mfa BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE sms_codes
+(
+    token_hash CHAR(64) PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    phone VARCHAR(16) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX sms_codes_user_uuid_idx ON sms_codes (user_uuid, purpose, created_at);
+CREATE INDEX sms_codes_expires_at_idx ON sms_codes (expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE activity_summaries
+(
+    user_uuid UUID PRIMARY KEY REFERENCES users (user_uuid) ON DELETE CASCADE,
+    time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    next_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX activity_summaries_next_at_idx ON activity_summaries (next_at);
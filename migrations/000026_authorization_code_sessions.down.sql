@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS sessions_family_id_idx;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS family_id;
+
+ALTER TABLE oauth_authorization_codes
+    DROP COLUMN IF EXISTS session_id;
@@ -0,0 +1,7 @@
+ALTER TABLE oauth_authorization_codes
+    ADD COLUMN session_id UUID;
+
+ALTER TABLE sessions
+    ADD COLUMN family_id UUID;
+
+CREATE INDEX sessions_family_id_idx ON sessions (family_id);
@@ -0,0 +1,2 @@
+ALTER TABLE oauth_clients
+    ADD COLUMN exchange_audiences TEXT[] NOT NULL DEFAULT '{}';
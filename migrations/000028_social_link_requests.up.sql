@@ -0,0 +1,10 @@
+CREATE TABLE social_link_requests
+(
+    token_hash TEXT NOT NULL UNIQUE,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL DEFAULT '',
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE user_phones
+    DROP COLUMN IF EXISTS pending_use_for_mfa,
+    DROP COLUMN IF EXISTS pending_phone;
@@ -0,0 +1,3 @@
+ALTER TABLE user_phones
+    ADD COLUMN pending_phone VARCHAR(16),
+    ADD COLUMN pending_use_for_mfa BOOLEAN NOT NULL DEFAULT true;
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+var (
+	ErrNoIDTokenKey = errors.New("id token signing key is not configured")
+)
+
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string           `json:"nonce,omitempty"`
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"`
+	AtHash            string           `json:"at_hash,omitempty"`
+	Email             string           `json:"email,omitempty"`
+	EmailVerified     *bool            `json:"email_verified,omitempty"`
+	PreferredUsername string           `json:"preferred_username,omitempty"`
+}
+
+// NewIDToken signs an OpenID Connect ID token with the RSA key of the manager.
+func (m *Manager) NewIDToken(claims IDTokenClaims, ttl time.Duration) (string, error) {
+	if m.idTokenKey == nil {
+		return "", ErrNoIDTokenKey
+	}
+
+	now := time.Now()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.idTokenKeyId
+
+	return token.SignedString(m.idTokenKey)
+}
+
+// JWKS returns the public keys used to verify tokens signed by the manager.
+func (m *Manager) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	if m.idTokenKey != nil {
+		set.Keys = append(set.Keys, publicJWK(&m.idTokenKey.PublicKey, jwt.SigningMethodRS256.Alg()))
+	}
+	return set
+}
+
+// AccessTokenHash computes the at_hash claim for an RS256 signed ID token.
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
@@ -1,12 +1,16 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
-	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -18,49 +22,95 @@ var validationDate = time.Date(2023, time.December, 7, 0, 0, 0, 0, time.UTC)
 
 type TokenClaims struct {
 	jwt.RegisteredClaims
-	IP        string `json:"ip"`
-	SessionId string `json:"session_id"`
+	IP        string   `json:"ip"`
+	SessionId string   `json:"session_id"`
+	ClientId  string   `json:"client_id,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	OrgId     string   `json:"org_id,omitempty"`
+	OrgRoles  []string `json:"org_roles,omitempty"`
+	Act       *Actor   `json:"act,omitempty"`
+}
+
+// Actor is the act claim of delegated tokens (RFC 8693, section 4.1). Nested
+// actors describe a chain of delegation, the outermost being the current actor.
+type Actor struct {
+	Subject  string `json:"sub"`
+	ClientId string `json:"client_id,omitempty"`
+	Act      *Actor `json:"act,omitempty"`
+}
+
+// TokenParams describes the subject of a new access token.
+type TokenParams struct {
+	TokenId   string
+	SessionId string
+	UserId    string
+	IP        string
+	ClientId  string
+	Scope     string
+	Roles     []string
+	OrgId     string
+	OrgRoles  []string
+	Act       *Actor
 }
 
 type TokenManager interface {
-	NewJWT(sessionId, userId, userIP string, ttl time.Duration) (string, error)
+	NewJWT(params TokenParams, ttl time.Duration) (string, error)
 	ParseToken(accessToken string) (string, string, string, error)
+	ParseAccessToken(accessToken string) (*TokenClaims, error)
 	NewRefreshToken() (string, error)
 	HashToken(refreshToken string) (string, error)
+	NewIDToken(claims IDTokenClaims, ttl time.Duration) (string, error)
+	NewSecurityEventToken(claims SecurityEventClaims) (string, error)
+	SignValue(value string) string
+	VerifyValue(signed string) (string, bool)
 }
 
 type Manager struct {
 	signingKey string
+
+	idTokenKey   *rsa.PrivateKey
+	idTokenKeyId string
 }
 
-func NewManager(signingKey string) (*Manager, error) {
+func NewManager(signingKey string, idTokenKey *rsa.PrivateKey) (*Manager, error) {
 	if signingKey == "" {
 		return nil, ErrEmptySigningKey
 	}
-	return &Manager{signingKey: signingKey}, nil
+
+	m := &Manager{
+		signingKey: signingKey,
+		idTokenKey: idTokenKey,
+	}
+	if idTokenKey != nil {
+		m.idTokenKeyId = publicJWK(&idTokenKey.PublicKey, jwt.SigningMethodRS256.Alg()).Kid
+	}
+	return m, nil
 }
 
-func (m *Manager) NewJWT(sessionId, userId, userIP string, ttl time.Duration) (string, error) {
+func (m *Manager) NewJWT(params TokenParams, ttl time.Duration) (string, error) {
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS512, TokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   userId,
+			Subject:   params.UserId,
+			ID:        params.TokenId,
 		},
-		IP:        userIP,
-		SessionId: sessionId,
+		IP:        params.IP,
+		SessionId: params.SessionId,
+		ClientId:  params.ClientId,
+		Scope:     params.Scope,
+		Roles:     params.Roles,
+		OrgId:     params.OrgId,
+		OrgRoles:  params.OrgRoles,
+		Act:       params.Act,
 	})
 
 	return jwtToken.SignedString([]byte(m.signingKey))
 }
 
 func (m *Manager) ParseToken(accessToken string) (string, string, string, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(m.signingKey), nil
-	}, jwt.WithTimeFunc(func() time.Time {
+	token, err := jwt.ParseWithClaims(accessToken, &TokenClaims{}, m.keyFunc, jwt.WithTimeFunc(func() time.Time {
 		return validationDate
 	}))
 
@@ -76,13 +126,32 @@ func (m *Manager) ParseToken(accessToken string) (string, string, string, error)
 	return claims.SessionId, claims.Subject, claims.IP, err
 }
 
+// ParseAccessToken parses the access token and, unlike ParseToken, checks that it is not expired.
+func (m *Manager) ParseAccessToken(accessToken string) (*TokenClaims, error) {
+	token, err := jwt.ParseWithClaims(accessToken, &TokenClaims{}, m.keyFunc, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*TokenClaims)
+	if !ok {
+		return nil, fmt.Errorf("error get token claims")
+	}
+
+	return claims, nil
+}
+
+func (m *Manager) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return []byte(m.signingKey), nil
+}
+
 func (m *Manager) NewRefreshToken() (string, error) {
 	b := make([]byte, 32)
 
-	s := rand.NewSource(time.Now().Unix())
-	r := rand.New(s)
-
-	if _, err := r.Read(b); err != nil {
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
 
@@ -97,3 +166,32 @@ func (m *Manager) HashToken(refreshToken string) (string, error) {
 	}
 	return string(hash), nil
 }
+
+// SignValue appends an HMAC-SHA256 signature to value, so that it can be handed to clients,
+// e.g. in a cookie, and verified when it comes back.
+func (m *Manager) SignValue(value string) string {
+	return value + "." + m.valueSignature(value)
+}
+
+// VerifyValue returns the value signed by SignValue and whether the signature is valid.
+func (m *Manager) VerifyValue(signed string) (string, bool) {
+	value, signature, ok := cutLast(signed, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(m.valueSignature(value))) {
+		return "", false
+	}
+	return value, true
+}
+
+func (m *Manager) valueSignature(value string) string {
+	mac := hmac.New(sha256.New, []byte(m.signingKey))
+	mac.Write([]byte("value:" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T, signingKey string) *Manager {
+	t.Helper()
+
+	manager, err := NewManager(signingKey, nil)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	return manager
+}
+
+func TestParseAccessToken(t *testing.T) {
+	manager := newTestManager(t, "signing-key")
+	params := TokenParams{
+		SessionId: "session-1",
+		UserId:    "user-1",
+		IP:        "203.0.113.1",
+		ClientId:  "crm",
+		Scope:     "openid profile",
+		Roles:     []string{"support"},
+		Act:       &Actor{Subject: "admin-1"},
+	}
+
+	signed := func(t *testing.T, manager *Manager, ttl time.Duration) string {
+		t.Helper()
+		token, err := manager.NewJWT(params, ttl)
+		if err != nil {
+			t.Fatalf("NewJWT() error = %v", err)
+		}
+		return token
+	}
+
+	rs256, err := jwt.NewWithClaims(jwt.SigningMethodRS256, TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   params.UserId,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}).SignedString(newTestKey(t))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	noExpiry, err := jwt.NewWithClaims(jwt.SigningMethodHS512, TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: params.UserId},
+	}).SignedString([]byte("signing-key"))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   params.UserId,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "valid token",
+			token: signed(t, manager, time.Minute),
+		},
+		{
+			name:    "expired token",
+			token:   signed(t, manager, -time.Minute),
+			wantErr: true,
+		},
+		{
+			name:    "signed with another key",
+			token:   signed(t, newTestManager(t, "other-key"), time.Minute),
+			wantErr: true,
+		},
+		{
+			name:    "signed with rsa",
+			token:   rs256,
+			wantErr: true,
+		},
+		{
+			name:    "alg none",
+			token:   unsigned,
+			wantErr: true,
+		},
+		{
+			name:    "no expiration",
+			token:   noExpiry,
+			wantErr: true,
+		},
+		{
+			name:    "not a token",
+			token:   "not-a-token",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := manager.ParseAccessToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAccessToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if claims.Subject != params.UserId || claims.SessionId != params.SessionId || claims.IP != params.IP ||
+				claims.ClientId != params.ClientId || claims.Scope != params.Scope {
+				t.Errorf("ParseAccessToken() claims = %+v, want the claims of %+v", claims, params)
+			}
+			if !slices.Equal(claims.Roles, params.Roles) || claims.Act == nil || claims.Act.Subject != params.Act.Subject {
+				t.Errorf("ParseAccessToken() roles = %v, act = %+v", claims.Roles, claims.Act)
+			}
+		})
+	}
+}
+
+func TestVerifyValue(t *testing.T) {
+	manager := newTestManager(t, "signing-key")
+	signed := manager.SignValue("0123456789abcdef")
+
+	tests := []struct {
+		name      string
+		signed    string
+		wantValue string
+		wantOk    bool
+	}{
+		{
+			name:      "signed value",
+			signed:    signed,
+			wantValue: "0123456789abcdef",
+			wantOk:    true,
+		},
+		{
+			name:      "value with dots",
+			signed:    manager.SignValue("a.b.c"),
+			wantValue: "a.b.c",
+			wantOk:    true,
+		},
+		{
+			name:   "changed value",
+			signed: "0123456789abcdeg" + signed[len("0123456789abcdef"):],
+		},
+		{
+			name:   "changed signature",
+			signed: signed[:len(signed)-1] + "A",
+		},
+		{
+			name:   "signed with another key",
+			signed: newTestManager(t, "other-key").SignValue("0123456789abcdef"),
+		},
+		{
+			name:   "no signature",
+			signed: "0123456789abcdef",
+		},
+		{
+			name:   "empty signature",
+			signed: "0123456789abcdef.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := manager.VerifyValue(tt.signed)
+			if value != tt.wantValue || ok != tt.wantOk {
+				t.Errorf("VerifyValue(%q) = %q, %v, want %q, %v", tt.signed, value, ok, tt.wantValue, tt.wantOk)
+			}
+		})
+	}
+}
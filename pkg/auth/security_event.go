@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// securityEventTokenType is the typ header of Security Event Tokens (RFC 8417, section 2.3).
+const securityEventTokenType = "secevent+jwt"
+
+// SecurityEventClaims are the claims of a Security Event Token (RFC 8417). The events claim
+// maps event type URIs to their payloads, sub_id identifies the subject as defined by the
+// Shared Signals Framework. SETs have no expiration, IssuedAt is the time of the event.
+type SecurityEventClaims struct {
+	jwt.RegisteredClaims
+	SubId  json.RawMessage            `json:"sub_id,omitempty"`
+	Txn    string                     `json:"txn,omitempty"`
+	Events map[string]json.RawMessage `json:"events"`
+}
+
+// NewSecurityEventToken signs a Security Event Token with the RSA key of the manager, the
+// key published in the JWKS.
+func (m *Manager) NewSecurityEventToken(claims SecurityEventClaims) (string, error) {
+	if m.idTokenKey == nil {
+		return "", ErrNoIDTokenKey
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.idTokenKeyId
+	token.Header["typ"] = securityEventTokenType
+
+	return token.SignedString(m.idTokenKey)
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+func TestNewSecurityEventToken(t *testing.T) {
+	key := newTestKey(t)
+	const sessionRevoked = "https://schemas.openid.net/secevent/caep/event-type/session-revoked"
+
+	tests := []struct {
+		name    string
+		key     *rsa.PrivateKey
+		wantErr error
+	}{
+		{
+			name: "signed with the id token key",
+			key:  key,
+		},
+		{
+			name:    "no id token key",
+			wantErr: ErrNoIDTokenKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, err := NewManager("signing-key", tt.key)
+			if err != nil {
+				t.Fatalf("NewManager() error = %v", err)
+			}
+
+			issuedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+			signed, err := manager.NewSecurityEventToken(SecurityEventClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					Issuer:   "https://auth.example.com",
+					Audience: jwt.ClaimStrings{"crm"},
+					ID:       "event-1",
+					IssuedAt: jwt.NewNumericDate(issuedAt),
+				},
+				SubId:  json.RawMessage(`{"format":"opaque","id":"user-1"}`),
+				Events: map[string]json.RawMessage{sessionRevoked: json.RawMessage(`{"event_timestamp":1700000000}`)},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewSecurityEventToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var claims SecurityEventClaims
+			token, err := jwt.ParseWithClaims(signed, &claims, func(token *jwt.Token) (interface{}, error) {
+				return &tt.key.PublicKey, nil
+			}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+			if err != nil {
+				t.Fatalf("failed to verify the token: %v", err)
+			}
+
+			if typ := token.Header["typ"]; typ != securityEventTokenType {
+				t.Errorf("typ = %v, want %s", typ, securityEventTokenType)
+			}
+			if kid := token.Header["kid"]; kid != manager.JWKS().Keys[0].Kid {
+				t.Errorf("kid = %v, want the kid published in the JWKS", kid)
+			}
+			if claims.ExpiresAt != nil {
+				t.Errorf("exp = %v, want none", claims.ExpiresAt)
+			}
+			if !claims.IssuedAt.Time.Equal(issuedAt) || claims.ID != "event-1" {
+				t.Errorf("iat = %v, jti = %q, want %v and event-1", claims.IssuedAt, claims.ID, issuedAt)
+			}
+			if _, ok := claims.Events[sessionRevoked]; !ok || len(claims.Events) != 1 {
+				t.Errorf("events = %v, want only %s", claims.Events, sessionRevoked)
+			}
+			if string(claims.SubId) != `{"format":"opaque","id":"user-1"}` {
+				t.Errorf("sub_id = %s", claims.SubId)
+			}
+		})
+	}
+}
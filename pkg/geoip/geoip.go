@@ -0,0 +1,198 @@
+package geoip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/oschwald/maxminddb-golang"
+	"medods-test/pkg/logger"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const defaultLanguage = "en"
+
+// Location is the coarse location of an IP address.
+type Location struct {
+	CountryCode string
+	Country     string
+	City        string
+	// Latitude and Longitude are approximate, both are zero if unknown.
+	Latitude       float64
+	Longitude      float64
+	ASN            uint32
+	ASOrganization string
+}
+
+// String returns the city and the country of the location, such as "Moscow, Russia".
+func (l Location) String() string {
+	switch {
+	case l.City != "" && l.Country != "":
+		return l.City + ", " + l.Country
+	case l.Country != "":
+		return l.Country
+	default:
+		return l.City
+	}
+}
+
+type Config struct {
+	// CityDB is the path to a GeoIP2/GeoLite2 City or Country database.
+	CityDB string
+	// ASNDB is the path to a GeoIP2/GeoLite2 ASN database.
+	ASNDB string
+	// Language of the place names, English names are used if there is no translation.
+	Language string
+}
+
+type cityRecord struct {
+	Country struct {
+		IsoCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+type asnRecord struct {
+	Number       uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// database is a loaded MaxMind database file.
+type database struct {
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+}
+
+// DB looks up IP addresses in local MaxMind databases. The files are read into memory, so
+// they can be replaced while the service runs; Reload picks up the new files.
+type DB struct {
+	config Config
+	city   atomic.Pointer[database]
+	asn    atomic.Pointer[database]
+}
+
+// Open loads the databases of cfg. At least one of CityDB and ASNDB must be set.
+func Open(cfg Config) (*DB, error) {
+	if cfg.CityDB == "" && cfg.ASNDB == "" {
+		return nil, errors.New("geoip: no database file is set")
+	}
+	if cfg.Language == "" {
+		cfg.Language = defaultLanguage
+	}
+
+	db := &DB{config: cfg}
+	if err := db.Reload(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Locate returns the location of ip. ok is false if ip is invalid or not found in any database.
+func (db *DB) Locate(ip string) (location Location, ok bool) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return Location{}, false
+	}
+
+	if city := db.city.Load(); city != nil {
+		var record cityRecord
+		if _, found, err := city.reader.LookupNetwork(addr, &record); err != nil {
+			logger.Errorf("failed to look up %s in %s: %s", ip, db.config.CityDB, err)
+		} else if found {
+			location.CountryCode = record.Country.IsoCode
+			location.Country = db.name(record.Country.Names)
+			location.City = db.name(record.City.Names)
+			location.Latitude = record.Location.Latitude
+			location.Longitude = record.Location.Longitude
+			ok = true
+		}
+	}
+
+	if asn := db.asn.Load(); asn != nil {
+		var record asnRecord
+		if _, found, err := asn.reader.LookupNetwork(addr, &record); err != nil {
+			logger.Errorf("failed to look up %s in %s: %s", ip, db.config.ASNDB, err)
+		} else if found {
+			location.ASN = record.Number
+			location.ASOrganization = record.Organization
+			ok = true
+		}
+	}
+
+	return location, ok
+}
+
+// Reload loads the database files that changed since they were loaded last time.
+// The previous databases stay in use if a file cannot be loaded.
+func (db *DB) Reload() error {
+	if err := reload(&db.city, db.config.CityDB); err != nil {
+		return err
+	}
+	return reload(&db.asn, db.config.ASNDB)
+}
+
+// Watch reloads changed database files every interval until ctx is done.
+func (db *DB) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := db.Reload(); err != nil {
+				logger.Errorf("failed to reload geoip database: %s", err)
+			}
+		}
+	}
+}
+
+func (db *DB) name(names map[string]string) string {
+	if name, ok := names[db.config.Language]; ok {
+		return name
+	}
+	return names[defaultLanguage]
+}
+
+func reload(current *atomic.Pointer[database], path string) error {
+	if path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("geoip: %w", err)
+	}
+	if loaded := current.Load(); loaded != nil && loaded.modTime.Equal(info.ModTime()) && loaded.size == info.Size() {
+		return nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("geoip: %w", err)
+	}
+	reader, err := maxminddb.FromBytes(b)
+	if err != nil {
+		return fmt.Errorf("geoip: failed to load %s: %w", path, err)
+	}
+
+	current.Store(&database{
+		reader:  reader,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	})
+	logger.Infof("geoip database %s loaded (%s, built %s)", path, reader.Metadata.DatabaseType,
+		time.Unix(int64(reader.Metadata.BuildEpoch), 0).UTC().Format(time.DateOnly))
+	return nil
+}
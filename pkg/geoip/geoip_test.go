@@ -0,0 +1,243 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeTestDB writes an IPv4 MaxMind database to path in which network holds record.
+func writeTestDB(t *testing.T, path string, network string, record map[string]any) {
+	t.Helper()
+
+	_, ipnet, err := net.ParseCIDR(network)
+	if err != nil {
+		t.Fatalf("ParseCIDR() error = %v", err)
+	}
+	ip := ipnet.IP.To4()
+	prefix, _ := ipnet.Mask.Size()
+
+	// A chain of one node per bit of the prefix, the other branch of every node is empty.
+	nodeCount := uint32(prefix)
+	var tree []byte
+	for i := 0; i < prefix; i++ {
+		next := uint32(i + 1)
+		if i == prefix-1 {
+			next = nodeCount + 16 // the record at the start of the data section
+		}
+		records := [2]uint32{nodeCount, nodeCount}
+		records[ip[i/8]>>(7-i%8)&1] = next
+		for _, r := range records {
+			tree = append(tree, byte(r>>16), byte(r>>8), byte(r))
+		}
+	}
+
+	b := append(tree, make([]byte, 16)...)
+	b = append(b, encodeTestValue(record)...)
+	b = append(b, "\xAB\xCD\xEFMaxMind.com"...)
+	b = append(b, encodeTestValue(map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               "Test",
+		"ip_version":                  uint16(4),
+		"languages":                   []any{"en", "ru"},
+		"node_count":                  nodeCount,
+		"record_size":                 uint16(24),
+	})...)
+
+	if err = os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+// encodeTestValue encodes v in the MaxMind DB data format.
+func encodeTestValue(v any) []byte {
+	control := func(typ int, size int) []byte {
+		var b []byte
+		if typ > 7 {
+			b = []byte{byte(size), byte(typ - 7)}
+		} else {
+			b = []byte{byte(typ<<5 | size)}
+		}
+		if size >= 29 {
+			b[0] = b[0]&0xE0 | 29
+			b = append(b, byte(size-29))
+		}
+		return b
+	}
+	unsigned := func(typ int, n uint64) []byte {
+		var b []byte
+		for ; n > 0; n >>= 8 {
+			b = append([]byte{byte(n)}, b...)
+		}
+		return append(control(typ, len(b)), b...)
+	}
+
+	switch v := v.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case float64:
+		return binary.BigEndian.AppendUint64(control(3, 8), math.Float64bits(v))
+	case uint16:
+		return unsigned(5, uint64(v))
+	case uint32:
+		return unsigned(6, uint64(v))
+	case uint64:
+		return unsigned(9, v)
+	case []any:
+		b := control(11, len(v))
+		for _, item := range v {
+			b = append(b, encodeTestValue(item)...)
+		}
+		return b
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		b := control(7, len(v))
+		for _, key := range keys {
+			b = append(b, encodeTestValue(key)...)
+			b = append(b, encodeTestValue(v[key])...)
+		}
+		return b
+	default:
+		panic("unsupported value")
+	}
+}
+
+func cityRecordOf(city map[string]any) map[string]any {
+	return map[string]any{
+		"country":  map[string]any{"iso_code": "RU", "names": map[string]any{"en": "Russia", "ru": "Россия"}},
+		"city":     map[string]any{"names": city},
+		"location": map[string]any{"latitude": 55.75, "longitude": 37.62},
+	}
+}
+
+func TestLocationString(t *testing.T) {
+	tests := []struct {
+		location Location
+		want     string
+	}{
+		{location: Location{City: "Moscow", Country: "Russia"}, want: "Moscow, Russia"},
+		{location: Location{Country: "Russia"}, want: "Russia"},
+		{location: Location{City: "Moscow"}, want: "Moscow"},
+		{location: Location{ASN: 12389}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.location.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.location, got, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.mmdb")
+	if err := os.WriteFile(invalid, []byte("not a database"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	for name, cfg := range map[string]Config{
+		"no files":     {},
+		"missing file": {CityDB: filepath.Join(dir, "missing.mmdb")},
+		"invalid file": {ASNDB: invalid},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Open(cfg); err == nil {
+				t.Errorf("Open() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLocate(t *testing.T) {
+	dir := t.TempDir()
+	cityDB, asnDB := filepath.Join(dir, "city.mmdb"), filepath.Join(dir, "asn.mmdb")
+	writeTestDB(t, cityDB, "203.0.113.0/24", cityRecordOf(map[string]any{"en": "Moscow", "ru": "Москва"}))
+	writeTestDB(t, asnDB, "203.0.113.0/24", map[string]any{
+		"autonomous_system_number":       uint32(12389),
+		"autonomous_system_organization": "Rostelecom",
+	})
+
+	moscow := Location{CountryCode: "RU", Country: "Russia", City: "Moscow", Latitude: 55.75, Longitude: 37.62, ASN: 12389, ASOrganization: "Rostelecom"}
+	tests := []struct {
+		name   string
+		config Config
+		ip     string
+		want   Location
+		wantOk bool
+	}{
+		{name: "both databases", config: Config{CityDB: cityDB, ASNDB: asnDB}, ip: "203.0.113.7", want: moscow, wantOk: true},
+		{
+			name:   "translated names",
+			config: Config{CityDB: cityDB, Language: "ru"},
+			ip:     "203.0.113.7",
+			want:   Location{CountryCode: "RU", Country: "Россия", City: "Москва", Latitude: 55.75, Longitude: 37.62},
+			wantOk: true,
+		},
+		{
+			name:   "no translation",
+			config: Config{CityDB: cityDB, Language: "de"},
+			ip:     "203.0.113.7",
+			want:   Location{CountryCode: "RU", Country: "Russia", City: "Moscow", Latitude: 55.75, Longitude: 37.62},
+			wantOk: true,
+		},
+		{name: "asn database only", config: Config{ASNDB: asnDB}, ip: "203.0.113.7", want: Location{ASN: 12389, ASOrganization: "Rostelecom"}, wantOk: true},
+		{name: "unknown address", config: Config{CityDB: cityDB, ASNDB: asnDB}, ip: "198.51.100.1"},
+		{name: "invalid address", config: Config{CityDB: cityDB, ASNDB: asnDB}, ip: "not-an-ip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := Open(tt.config)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+
+			location, ok := db.Locate(tt.ip)
+			if ok != tt.wantOk || location != tt.want {
+				t.Errorf("Locate(%q) = %+v, %v, want %+v, %v", tt.ip, location, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeTestDB(t, path, "203.0.113.0/24", cityRecordOf(map[string]any{"en": "Moscow"}))
+
+	db, err := Open(Config{CityDB: path})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	// A replaced file is picked up.
+	writeTestDB(t, path, "203.0.113.0/24", cityRecordOf(map[string]any{"en": "Saint Petersburg"}))
+	if err = db.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if location, _ := db.Locate("203.0.113.7"); location.City != "Saint Petersburg" {
+		t.Errorf("city after reload = %q, want the new one", location.City)
+	}
+
+	// The loaded database stays in use if the new file is broken.
+	if err = os.WriteFile(path, []byte("not a database"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err = db.Reload(); err == nil {
+		t.Errorf("Reload() of a broken file error = nil, want an error")
+	}
+	if location, _ := db.Locate("203.0.113.7"); location.City != "Saint Petersburg" {
+		t.Errorf("city after a failed reload = %q, want the previous one", location.City)
+	}
+}
@@ -0,0 +1,170 @@
+package ldapauth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Config describes an LDAP or Active Directory server used to check passwords of
+// users from the listed email domains.
+type Config struct {
+	Name               string              `json:"name"`
+	URL                string              `json:"url"`
+	StartTLS           bool                `json:"start_tls"`
+	InsecureSkipVerify bool                `json:"insecure_skip_verify"`
+	BindDN             string              `json:"bind_dn"`
+	BindPassword       string              `json:"bind_password"`
+	BaseDN             string              `json:"base_dn"`
+	UserFilter         string              `json:"user_filter"`
+	MailAttribute      string              `json:"mail_attribute"`
+	GroupAttribute     string              `json:"group_attribute"`
+	GroupRoles         map[string][]string `json:"group_roles"`
+	Domains            []string            `json:"domains"`
+	Timeout            time.Duration       `json:"-"`
+}
+
+// Principal is a user found and authenticated in the directory.
+type Principal struct {
+	DN     string
+	Email  string
+	Groups []string
+	Roles  []string
+}
+
+type Authenticator struct {
+	cfg Config
+}
+
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, fmt.Errorf("ldap %s: url and base_dn are required", cfg.Name)
+	}
+
+	if cfg.MailAttribute == "" {
+		cfg.MailAttribute = "mail"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(objectClass=person)"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &Authenticator{cfg: cfg}, nil
+}
+
+func (a *Authenticator) Name() string {
+	return a.cfg.Name
+}
+
+func (a *Authenticator) Domains() []string {
+	return a.cfg.Domains
+}
+
+// Authenticate finds the user by the mail attribute and binds as that user to check the password.
+func (a *Authenticator) Authenticate(ctx context.Context, email, password string) (*Principal, error) {
+	// An empty password would make an unauthenticated bind, which always succeeds.
+	if email == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.cfg.BindDN != "" {
+		if err = conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap %s: service bind: %w", a.cfg.Name, err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		int(a.cfg.Timeout.Seconds()),
+		false,
+		fmt.Sprintf("(&%s(%s=%s))", a.cfg.UserFilter, a.cfg.MailAttribute, ldap.EscapeFilter(email)),
+		[]string{a.cfg.MailAttribute, a.cfg.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("ldap %s: search: %w", a.cfg.Name, err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	entry := result.Entries[0]
+	if err = conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap %s: user bind: %w", a.cfg.Name, err)
+	}
+
+	principal := &Principal{
+		DN:     entry.DN,
+		Email:  entry.GetAttributeValue(a.cfg.MailAttribute),
+		Groups: entry.GetAttributeValues(a.cfg.GroupAttribute),
+	}
+	principal.Roles = a.mapRoles(principal.Groups)
+
+	return principal, nil
+}
+
+func (a *Authenticator) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.cfg.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: a.cfg.Timeout}
+
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithTLSConfig(tlsConfig), ldap.DialWithDialer(dialer))
+	if err != nil {
+		return nil, fmt.Errorf("ldap %s: dial: %w", a.cfg.Name, err)
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap %s: start tls: %w", a.cfg.Name, err)
+		}
+	}
+
+	return conn, nil
+}
+
+// mapRoles converts directory groups to local role names. Group DNs are compared case-insensitively.
+func (a *Authenticator) mapRoles(groups []string) []string {
+	roles := make([]string, 0)
+	for group, groupRoles := range a.cfg.GroupRoles {
+		if !slices.ContainsFunc(groups, func(g string) bool { return strings.EqualFold(g, group) }) {
+			continue
+		}
+		for _, role := range groupRoles {
+			if !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	slices.Sort(roles)
+	return roles
+}
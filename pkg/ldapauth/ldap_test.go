@@ -0,0 +1,312 @@
+package ldapauth
+
+import (
+	"context"
+	"errors"
+	"github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	serviceDN       = "cn=service,dc=example,dc=com"
+	servicePassword = "service-secret"
+)
+
+type stubEntry struct {
+	dn       string
+	mail     string
+	password string
+	groups   []string
+}
+
+// stubDirectory is an in-process LDAP server that answers simple binds and searches by the
+// equality and presence filters the authenticator sends.
+type stubDirectory struct {
+	listener net.Listener
+	entries  []stubEntry
+
+	mu    sync.Mutex
+	binds []string
+}
+
+func newStubDirectory(t *testing.T, entries ...stubEntry) *stubDirectory {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	d := &stubDirectory{listener: listener, entries: entries}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	return d
+}
+
+func (d *stubDirectory) url() string {
+	return "ldap://" + d.listener.Addr().String()
+}
+
+func (d *stubDirectory) bindDNs() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.binds)
+}
+
+func (d *stubDirectory) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageId := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			d.mu.Lock()
+			d.binds = append(d.binds, dn)
+			d.mu.Unlock()
+
+			code := uint16(ldap.LDAPResultInvalidCredentials)
+			if d.checkPassword(dn, password) {
+				code = ldap.LDAPResultSuccess
+			}
+			_, _ = conn.Write(response(messageId, ldap.ApplicationBindResponse, code).Bytes())
+		case ldap.ApplicationSearchRequest:
+			for _, entry := range d.entries {
+				if matches(op.Children[6], entry) {
+					_, _ = conn.Write(searchEntry(messageId, entry).Bytes())
+				}
+			}
+			_, _ = conn.Write(response(messageId, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess).Bytes())
+		default:
+			return
+		}
+	}
+}
+
+func (d *stubDirectory) checkPassword(dn, password string) bool {
+	if dn == serviceDN {
+		return password == servicePassword
+	}
+	for _, entry := range d.entries {
+		if strings.EqualFold(entry.dn, dn) {
+			return password != "" && password == entry.password
+		}
+	}
+	return false
+}
+
+func matches(filter *ber.Packet, entry stubEntry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterEqualityMatch:
+		attribute := filter.Children[0].Value.(string)
+		value := filter.Children[1].Value.(string)
+		switch strings.ToLower(attribute) {
+		case "objectclass":
+			return strings.EqualFold(value, "person")
+		case "mail":
+			return strings.EqualFold(value, entry.mail)
+		}
+		return false
+	case ldap.FilterPresent:
+		return true
+	}
+	return false
+}
+
+func response(messageId int64, tag ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, uint64(code), "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return message(messageId, op)
+}
+
+func searchEntry(messageId int64, entry stubEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	attributes.AppendChild(attribute("mail", entry.mail))
+	attributes.AppendChild(attribute("memberOf", entry.groups...))
+	op.AppendChild(attributes)
+	return message(messageId, op)
+}
+
+func attribute(name string, values ...string) *ber.Packet {
+	attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+	attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+	set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+	for _, value := range values {
+		set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+	}
+	attr.AppendChild(set)
+	return attr
+}
+
+func message(messageId int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Message")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageId, "messageID"))
+	packet.AppendChild(op)
+	return packet
+}
+
+func TestAuthenticate(t *testing.T) {
+	alice := stubEntry{
+		dn:       "uid=alice,ou=people,dc=example,dc=com",
+		mail:     "alice@example.com",
+		password: "alice-secret",
+		groups:   []string{"CN=Admins,OU=Groups,DC=example,DC=com", "cn=staff,ou=groups,dc=example,dc=com"},
+	}
+
+	tests := []struct {
+		name            string
+		entries         []stubEntry
+		bindPassword    string
+		email           string
+		password        string
+		wantErr         error
+		wantAnyErr      bool
+		wantDN          string
+		wantRoles       []string
+		wantNoUserBinds bool
+	}{
+		{
+			name:      "valid credentials",
+			entries:   []stubEntry{alice},
+			email:     "alice@example.com",
+			password:  "alice-secret",
+			wantDN:    alice.dn,
+			wantRoles: []string{"admin", "support"},
+		},
+		{
+			name:     "wrong password",
+			entries:  []stubEntry{alice},
+			email:    "alice@example.com",
+			password: "guess",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:            "unknown email",
+			entries:         []stubEntry{alice},
+			email:           "bob@example.com",
+			password:        "alice-secret",
+			wantErr:         ErrInvalidCredentials,
+			wantNoUserBinds: true,
+		},
+		{
+			name:            "empty password",
+			entries:         []stubEntry{alice},
+			email:           "alice@example.com",
+			wantErr:         ErrInvalidCredentials,
+			wantNoUserBinds: true,
+		},
+		{
+			name:            "wildcard email",
+			entries:         []stubEntry{alice},
+			email:           "*",
+			password:        "alice-secret",
+			wantErr:         ErrInvalidCredentials,
+			wantNoUserBinds: true,
+		},
+		{
+			name:            "ambiguous email",
+			entries:         []stubEntry{alice, {dn: "uid=alice2,ou=people,dc=example,dc=com", mail: alice.mail, password: "other"}},
+			email:           "alice@example.com",
+			password:        "alice-secret",
+			wantErr:         ErrInvalidCredentials,
+			wantNoUserBinds: true,
+		},
+		{
+			name:            "service bind rejected",
+			entries:         []stubEntry{alice},
+			bindPassword:    "wrong",
+			email:           "alice@example.com",
+			password:        "alice-secret",
+			wantAnyErr:      true,
+			wantNoUserBinds: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := newStubDirectory(t, tt.entries...)
+
+			bindPassword := tt.bindPassword
+			if bindPassword == "" {
+				bindPassword = servicePassword
+			}
+			authenticator, err := NewAuthenticator(Config{
+				Name:         "corp",
+				URL:          directory.url(),
+				BindDN:       serviceDN,
+				BindPassword: bindPassword,
+				BaseDN:       "dc=example,dc=com",
+				GroupRoles: map[string][]string{
+					"cn=admins,ou=groups,dc=example,dc=com":  {"admin", "support"},
+					"cn=staff,ou=groups,dc=example,dc=com":   {"support"},
+					"cn=finance,ou=groups,dc=example,dc=com": {"billing"},
+				},
+				Timeout: time.Second,
+			})
+			if err != nil {
+				t.Fatalf("NewAuthenticator() error = %v", err)
+			}
+
+			principal, err := authenticator.Authenticate(context.Background(), tt.email, tt.password)
+			switch {
+			case tt.wantAnyErr:
+				if err == nil || errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Authenticate() error = %v, want a directory error", err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantNoUserBinds {
+				for _, dn := range directory.bindDNs() {
+					if dn != serviceDN {
+						t.Errorf("Authenticate() bound as %q, want only the service account", dn)
+					}
+				}
+			}
+			if err != nil {
+				return
+			}
+
+			if principal.DN != tt.wantDN || principal.Email != tt.email {
+				t.Errorf("Authenticate() principal = %+v, want DN %q and email %q", principal, tt.wantDN, tt.email)
+			}
+			if !slices.Equal(principal.Roles, tt.wantRoles) {
+				t.Errorf("Authenticate() roles = %v, want %v", principal.Roles, tt.wantRoles)
+			}
+		})
+	}
+}
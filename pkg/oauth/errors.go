@@ -0,0 +1,44 @@
+package oauth
+
+// Error codes from RFC 6749, section 4.1.2.1 and 5.2.
+const (
+	ErrCodeInvalidRequest          = "invalid_request"
+	ErrCodeUnauthorizedClient      = "unauthorized_client"
+	ErrCodeAccessDenied            = "access_denied"
+	ErrCodeUnsupportedResponseType = "unsupported_response_type"
+	ErrCodeInvalidScope            = "invalid_scope"
+	ErrCodeServerError             = "server_error"
+	ErrCodeInvalidClient           = "invalid_client"
+	ErrCodeInvalidGrant            = "invalid_grant"
+	ErrCodeUnsupportedGrantType    = "unsupported_grant_type"
+)
+
+// Error codes of the device authorization grant from RFC 8628, section 3.5.
+const (
+	ErrCodeAuthorizationPending = "authorization_pending"
+	ErrCodeSlowDown             = "slow_down"
+	ErrCodeExpiredToken         = "expired_token"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// Token type identifiers from RFC 8693, section 3.
+const (
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+const (
+	ResponseTypeCode = "code"
+)
+
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,32 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+const (
+	CodeChallengeMethodS256 = "S256"
+)
+
+// verifierRegex matches code verifiers allowed by RFC 7636, section 4.1.
+var verifierRegex = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+func IsCodeVerifierValid(verifier string) bool {
+	return verifierRegex.MatchString(verifier)
+}
+
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func VerifyCodeChallenge(challenge, method, verifier string) bool {
+	if method != CodeChallengeMethodS256 || !IsCodeVerifierValid(verifier) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(CodeChallenge(verifier)), []byte(challenge)) == 1
+}
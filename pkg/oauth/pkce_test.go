@@ -0,0 +1,84 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+)
+
+// The verifier and challenge of RFC 7636, appendix B.
+const (
+	rfcVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestCodeChallenge(t *testing.T) {
+	if got := CodeChallenge(rfcVerifier); got != rfcChallenge {
+		t.Errorf("CodeChallenge() = %s, want %s", got, rfcChallenge)
+	}
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		method    string
+		verifier  string
+		want      bool
+	}{
+		{
+			name:      "matching verifier",
+			challenge: rfcChallenge,
+			method:    CodeChallengeMethodS256,
+			verifier:  rfcVerifier,
+			want:      true,
+		},
+		{
+			name:      "another verifier",
+			challenge: rfcChallenge,
+			method:    CodeChallengeMethodS256,
+			verifier:  strings.Repeat("a", 43),
+		},
+		{
+			name:      "plain method",
+			challenge: rfcVerifier,
+			method:    "plain",
+			verifier:  rfcVerifier,
+		},
+		{
+			name:      "no method",
+			challenge: rfcChallenge,
+			verifier:  rfcVerifier,
+		},
+		{
+			name:      "verifier too short",
+			challenge: CodeChallenge(strings.Repeat("a", 42)),
+			method:    CodeChallengeMethodS256,
+			verifier:  strings.Repeat("a", 42),
+		},
+		{
+			name:      "verifier too long",
+			challenge: CodeChallenge(strings.Repeat("a", 129)),
+			method:    CodeChallengeMethodS256,
+			verifier:  strings.Repeat("a", 129),
+		},
+		{
+			name:      "verifier with invalid characters",
+			challenge: CodeChallenge(strings.Repeat("a", 42) + "+"),
+			method:    CodeChallengeMethodS256,
+			verifier:  strings.Repeat("a", 42) + "+",
+		},
+		{
+			name:     "empty challenge",
+			method:   CodeChallengeMethodS256,
+			verifier: rfcVerifier,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyCodeChallenge(tt.challenge, tt.method, tt.verifier); got != tt.want {
+				t.Errorf("VerifyCodeChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package oauth
+
+import (
+	"net"
+	"net/url"
+)
+
+// MatchRedirectURI reports whether requested exactly matches one of the registered
+// redirect URIs. For loopback redirect URIs of native apps the port is ignored
+// (RFC 8252, section 7.3).
+func MatchRedirectURI(registered []string, requested string) bool {
+	req, err := url.Parse(requested)
+	if err != nil || req.Fragment != "" || !req.IsAbs() {
+		return false
+	}
+
+	for _, uri := range registered {
+		if uri == requested {
+			return true
+		}
+
+		reg, err := url.Parse(uri)
+		if err != nil {
+			continue
+		}
+
+		if isLoopback(reg) && isLoopback(req) &&
+			reg.Scheme == req.Scheme &&
+			reg.Hostname() == req.Hostname() &&
+			reg.Path == req.Path &&
+			reg.RawQuery == req.RawQuery {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isLoopback(u *url.URL) bool {
+	if u.Scheme != "http" {
+		return false
+	}
+
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+// AppendQuery returns redirectURI with params added to its query string.
+func AppendQuery(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	q := u.Query()
+	for k, vs := range params {
+		for _, v := range vs {
+			if v != "" {
+				q.Add(k, v)
+			}
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
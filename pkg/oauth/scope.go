@@ -0,0 +1,31 @@
+package oauth
+
+import (
+	"slices"
+	"strings"
+)
+
+// ScopeImpersonation must be granted to an actor token to act on behalf of other users.
+const ScopeImpersonation = "impersonation"
+
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+func JoinScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// ScopeAllowed reports whether every requested scope is present in allowed.
+func ScopeAllowed(allowed []string, requested string) bool {
+	for _, s := range ParseScope(requested) {
+		if !slices.Contains(allowed, s) {
+			return false
+		}
+	}
+	return true
+}
+
+func HasScope(scope string, want string) bool {
+	return slices.Contains(ParseScope(scope), want)
+}
@@ -0,0 +1,48 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+// userCodeAlphabet has no vowels and no ambiguous characters (RFC 8628, section 6.1).
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+const userCodeLength = 8
+
+// NewUserCode returns a random user code formatted as XXXX-XXXX.
+func NewUserCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+
+	for i := 0; i < userCodeLength; i++ {
+		if i == userCodeLength/2 {
+			sb.WriteByte('-')
+		}
+
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(userCodeAlphabet[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+// NormalizeUserCode converts user input into the stored user code format.
+func NormalizeUserCode(input string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(input) {
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			sb.WriteRune(r)
+		}
+	}
+
+	code := sb.String()
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
@@ -0,0 +1,22 @@
+package oidcclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadConfigs reads the list of providers from a JSON file.
+func LoadConfigs(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read providers file: %w", err)
+	}
+
+	var configs []Config
+	if err = json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse providers file: %w", err)
+	}
+
+	return configs, nil
+}
@@ -0,0 +1,243 @@
+package oidcclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"io"
+	"net/http"
+	"sync"
+)
+
+var (
+	ErrMissingIDToken  = errors.New("id token is missing in token response")
+	ErrNonceMismatch   = errors.New("id token nonce does not match")
+	ErrMissingSubject  = errors.New("provider did not return a subject")
+	ErrInvalidProvider = errors.New("provider needs either an issuer or auth, token and userinfo urls")
+)
+
+// Config describes an external identity provider. Providers with an issuer are
+// configured by OpenID Connect discovery; plain OAuth 2.0 providers need explicit
+// endpoints and the names of the userinfo fields.
+type Config struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientId     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+
+	AuthURL     string `json:"auth_url"`
+	TokenURL    string `json:"token_url"`
+	UserInfoURL string `json:"userinfo_url"`
+
+	SubjectField       string `json:"subject_field"`
+	EmailField         string `json:"email_field"`
+	EmailVerifiedField string `json:"email_verified_field"`
+}
+
+// Identity is the user as seen by the external provider.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type Provider struct {
+	cfg         Config
+	redirectURL string
+
+	mu       sync.Mutex
+	oidc     *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewProvider(cfg Config, redirectURL string) (*Provider, error) {
+	if cfg.Issuer == "" && (cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "") {
+		return nil, fmt.Errorf("%s: %w", cfg.Name, ErrInvalidProvider)
+	}
+
+	if cfg.SubjectField == "" {
+		cfg.SubjectField = "sub"
+	}
+	if cfg.EmailField == "" {
+		cfg.EmailField = "email"
+	}
+	if cfg.EmailVerifiedField == "" {
+		cfg.EmailVerifiedField = "email_verified"
+	}
+
+	return &Provider{cfg: cfg, redirectURL: redirectURL}, nil
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) IsOIDC() bool {
+	return p.cfg.Issuer != ""
+}
+
+// AuthCodeURL returns the url of the provider login page. The nonce is only sent to OIDC providers.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	config, err := p.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if p.IsOIDC() {
+		opts = append(opts, oidc.Nonce(nonce))
+	}
+
+	return config.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange redeems the authorization code and returns the external identity.
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	config, err := p.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	if p.IsOIDC() {
+		return p.identityFromIDToken(ctx, token, nonce)
+	}
+	return p.identityFromUserInfo(ctx, config.Client(ctx, token))
+}
+
+func (p *Provider) identityFromIDToken(ctx context.Context, token *oauth2.Token, nonce string) (*Identity, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err = idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode id token claims: %w", err)
+	}
+
+	identity := &Identity{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+	}
+
+	// Some providers put the email only into the userinfo response.
+	if identity.Email == "" && p.oidc.UserInfoEndpoint() != "" {
+		info, err := p.oidc.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get userinfo: %w", err)
+		}
+		if info.Subject == identity.Subject {
+			identity.Email = info.Email
+			identity.EmailVerified = info.EmailVerified
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *Provider) identityFromUserInfo(ctx context.Context, client *http.Client) (*Identity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get userinfo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read userinfo: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get userinfo: %s: %s", resp.Status, body)
+	}
+
+	var fields map[string]any
+	if err = json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode userinfo: %w", err)
+	}
+
+	identity := &Identity{
+		Subject: stringField(fields[p.cfg.SubjectField]),
+		Email:   stringField(fields[p.cfg.EmailField]),
+	}
+	identity.EmailVerified, _ = fields[p.cfg.EmailVerifiedField].(bool)
+
+	if identity.Subject == "" {
+		return nil, ErrMissingSubject
+	}
+	return identity, nil
+}
+
+// oauth2Config lazily runs discovery, so an unavailable provider does not prevent start up.
+func (p *Provider) oauth2Config(ctx context.Context) (*oauth2.Config, error) {
+	config := &oauth2.Config{
+		ClientID:     p.cfg.ClientId,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.redirectURL,
+		Scopes:       p.cfg.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.cfg.AuthURL,
+			TokenURL: p.cfg.TokenURL,
+		},
+	}
+
+	if !p.IsOIDC() {
+		return config, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oidc == nil {
+		provider, err := oidc.NewProvider(ctx, p.cfg.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover provider %s: %w", p.cfg.Name, err)
+		}
+		p.oidc = provider
+		p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientId})
+	}
+
+	config.Endpoint = p.oidc.Endpoint()
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{oidc.ScopeOpenID, "email"}
+	}
+
+	return config, nil
+}
+
+// stringField converts string and numeric ids of userinfo responses to strings.
+func stringField(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
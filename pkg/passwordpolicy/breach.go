@@ -0,0 +1,71 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const hashPrefixLen = 5
+
+// BreachedPasswords looks passwords up in a local copy of the Have I Been Pwned password
+// dataset split by SHA-1 prefix, as produced by the HIBP range API and downloader: the
+// directory holds a file per 5 hex character prefix named "<PREFIX>" or "<PREFIX>.txt",
+// each line of which is "<SUFFIX>:<COUNT>".
+type BreachedPasswords struct {
+	dir string
+}
+
+func NewBreachedPasswords(dir string) (*BreachedPasswords, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached passwords dataset: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached passwords dataset %s is not a directory", dir)
+	}
+	return &BreachedPasswords{dir: dir}, nil
+}
+
+func (b *BreachedPasswords) IsBreached(_ context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:hashPrefixLen], digest[hashPrefixLen:]
+
+	file, err := b.open(prefix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(lineSuffix, suffix) {
+			return count != "0", nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached passwords range %s: %w", prefix, err)
+	}
+
+	return false, nil
+}
+
+func (b *BreachedPasswords) open(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.Open(filepath.Join(b.dir, prefix))
+	}
+	return file, err
+}
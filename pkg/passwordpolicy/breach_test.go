@@ -0,0 +1,66 @@
+package passwordpolicy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBreachedPasswords(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+	dir := t.TempDir()
+	ranges := map[string]string{
+		"5BAA6.txt": "003D68EB55068C33ACE09247EE4C639306B:3\r\n1e4c9b93f3f0682250b6cf8331b7ee68fd8:9659365\r\n",
+		// "123456" is padded with a zero count, as in padded HIBP downloads.
+		"7C4A8": "D09CA3762AF61E59520943DC26494F8941B:0\n",
+		// The range of "passwore" without its suffix C406539B6E55106CA1917673CD49D13683605B7E.
+		"C4065": "39B6E55106CA1917673CD49D13683605B7F:12\n",
+	}
+	for name, content := range ranges {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write range %s: %v", name, err)
+		}
+	}
+
+	breaches, err := NewBreachedPasswords(dir)
+	if err != nil {
+		t.Fatalf("NewBreachedPasswords() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{name: "breached password", password: "password", want: true},
+		{name: "padding entry", password: "123456", want: false},
+		{name: "range without the suffix", password: "passwore", want: false},
+		{name: "no range file", password: "Vq7#zmT!4rLp", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := breaches.IsBreached(context.Background(), tt.password)
+			if err != nil {
+				t.Fatalf("IsBreached() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsBreached(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBreachedPasswordsNotADirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ranges")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	for _, path := range []string{file, filepath.Join(t.TempDir(), "missing")} {
+		if _, err := NewBreachedPasswords(path); err == nil {
+			t.Errorf("NewBreachedPasswords(%q) error = nil, want an error", path)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package passwordpolicy
+
+import (
+	"context"
+	"fmt"
+	"github.com/nbutton23/zxcvbn-go"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules reported in violations.
+const (
+	RuleMinLength  = "min_length"
+	RuleMaxLength  = "max_length"
+	RuleLowercase  = "lowercase"
+	RuleUppercase  = "uppercase"
+	RuleDigit      = "digit"
+	RuleSymbol     = "symbol"
+	RuleStrength   = "strength"
+	RuleBannedWord = "banned_word"
+	RuleEmail      = "email"
+	RuleBreached   = "breached"
+)
+
+// minEmailPartLen is the shortest email local part that passwords must not contain.
+const minEmailPartLen = 3
+
+type Config struct {
+	MinLength     int
+	MaxLength     int
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// MinStrength is the minimum zxcvbn score from 0 to 4, 0 disables the check.
+	MinStrength int
+	BannedWords []string
+}
+
+// BreachChecker reports whether a password is known from data breaches.
+type BreachChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists the rules a password does not satisfy.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	return "password does not satisfy the policy: " + strings.Join(rules, ", ")
+}
+
+type Policy struct {
+	cfg      Config
+	breaches BreachChecker
+}
+
+// NewPolicy creates a policy. breaches may be nil to skip the breached password check.
+func NewPolicy(cfg Config, breaches BreachChecker) *Policy {
+	banned := make([]string, 0, len(cfg.BannedWords))
+	for _, word := range cfg.BannedWords {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			banned = append(banned, word)
+		}
+	}
+	cfg.BannedWords = banned
+
+	return &Policy{cfg: cfg, breaches: breaches}
+}
+
+// Check validates password of the user with email. It returns a *PolicyError if any rule
+// is not satisfied.
+func (p *Policy) Check(ctx context.Context, password string, email string) error {
+	var violations []Violation
+	violate := func(rule, message string) {
+		violations = append(violations, Violation{Rule: rule, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.cfg.MinLength > 0 && length < p.cfg.MinLength {
+		violate(RuleMinLength, fmt.Sprintf("must be at least %d characters long", p.cfg.MinLength))
+	}
+	if p.cfg.MaxLength > 0 && length > p.cfg.MaxLength {
+		violate(RuleMaxLength, fmt.Sprintf("must be at most %d characters long", p.cfg.MaxLength))
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.cfg.RequireLower && !lower {
+		violate(RuleLowercase, "must contain a lowercase letter")
+	}
+	if p.cfg.RequireUpper && !upper {
+		violate(RuleUppercase, "must contain an uppercase letter")
+	}
+	if p.cfg.RequireDigit && !digit {
+		violate(RuleDigit, "must contain a digit")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		violate(RuleSymbol, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, word := range p.cfg.BannedWords {
+		if strings.Contains(lowered, word) {
+			violate(RuleBannedWord, "must not contain commonly used words")
+			break
+		}
+	}
+
+	localPart, _, _ := strings.Cut(strings.ToLower(email), "@")
+	if len(localPart) >= minEmailPartLen && strings.Contains(lowered, localPart) {
+		violate(RuleEmail, "must not contain the email address")
+	}
+
+	if p.cfg.MinStrength > 0 && password != "" {
+		userInputs := append([]string{localPart}, p.cfg.BannedWords...)
+		if zxcvbn.PasswordStrength(password, userInputs).Score < p.cfg.MinStrength {
+			violate(RuleStrength, "is too easy to guess")
+		}
+	}
+
+	if p.breaches != nil {
+		breached, err := p.breaches.IsBreached(ctx, password)
+		if err != nil {
+			return fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+		if breached {
+			violate(RuleBreached, "has appeared in a data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
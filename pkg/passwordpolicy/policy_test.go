@@ -0,0 +1,138 @@
+package passwordpolicy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeBreaches reports the passwords it holds as breached, or fails with err.
+type fakeBreaches struct {
+	passwords []string
+	err       error
+}
+
+func (b fakeBreaches) IsBreached(_ context.Context, password string) (bool, error) {
+	return slices.Contains(b.passwords, password), b.err
+}
+
+func TestPolicyCheck(t *testing.T) {
+	cfg := Config{
+		MinLength:     10,
+		MaxLength:     64,
+		RequireLower:  true,
+		RequireUpper:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		MinStrength:   3,
+		BannedWords:   []string{" Medods ", ""},
+	}
+
+	tests := []struct {
+		name      string
+		password  string
+		email     string
+		breaches  BreachChecker
+		wantRules []string
+		wantErr   bool
+	}{
+		{
+			name:     "strong password",
+			password: "Vq7#zmT!4rLp",
+			email:    "alice@example.com",
+		},
+		{
+			name:      "short password",
+			password:  "Aa1!",
+			email:     "alice@example.com",
+			wantRules: []string{RuleMinLength, RuleStrength},
+		},
+		{
+			name:      "too long",
+			password:  "Vq7#zmT!4rLp" + strings.Repeat("x", 60),
+			email:     "alice@example.com",
+			wantRules: []string{RuleMaxLength},
+		},
+		{
+			name:      "missing character classes",
+			password:  "vqzmtxrlpwkd",
+			email:     "alice@example.com",
+			wantRules: []string{RuleUppercase, RuleDigit, RuleSymbol},
+		},
+		{
+			name:     "unicode letters",
+			password: "Пароль#Вq7зм!",
+			email:    "alice@example.com",
+		},
+		{
+			name:      "banned word in any case",
+			password:  "Vq7#MEDODS!4rLp",
+			email:     "alice@example.com",
+			wantRules: []string{RuleBannedWord},
+		},
+		{
+			name:      "contains the email",
+			password:  "Vq7#Alice!4rLp",
+			email:     "alice@example.com",
+			wantRules: []string{RuleEmail},
+		},
+		{
+			name:     "short email local part is allowed",
+			password: "Vq7#zmT!4rLpab",
+			email:    "ab@example.com",
+		},
+		{
+			name:      "easy to guess",
+			password:  "Password123!",
+			email:     "alice@example.com",
+			wantRules: []string{RuleStrength},
+		},
+		{
+			name:      "breached",
+			password:  "Vq7#zmT!4rLp",
+			email:     "alice@example.com",
+			breaches:  fakeBreaches{passwords: []string{"Vq7#zmT!4rLp"}},
+			wantRules: []string{RuleBreached},
+		},
+		{
+			name:     "breach check fails",
+			password: "Vq7#zmT!4rLp",
+			email:    "alice@example.com",
+			breaches: fakeBreaches{err: errors.New("disk error")},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewPolicy(cfg, tt.breaches).Check(context.Background(), tt.password, tt.email)
+
+			var policyErr *PolicyError
+			if tt.wantErr {
+				if err == nil || errors.As(err, &policyErr) {
+					t.Fatalf("Check() error = %v, want a breach check error", err)
+				}
+				return
+			}
+			if len(tt.wantRules) == 0 {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Check() error = %v, want *PolicyError", err)
+			}
+
+			rules := make([]string, 0, len(policyErr.Violations))
+			for _, violation := range policyErr.Violations {
+				rules = append(rules, violation.Rule)
+			}
+			if !slices.Equal(rules, tt.wantRules) {
+				t.Errorf("Check() violated %v, want %v", rules, tt.wantRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package risk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"medods-test/pkg/logger"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// IPList is a set of IP addresses and networks read from a file with one address or CIDR per
+// line, such as a Tor exit node list. Empty lines and lines starting with # are skipped.
+type IPList struct {
+	path    string
+	entries atomic.Pointer[ipEntries]
+}
+
+type ipEntries struct {
+	addrs    map[netip.Addr]struct{}
+	prefixes []netip.Prefix
+	modTime  time.Time
+	size     int64
+}
+
+func OpenIPList(path string) (*IPList, error) {
+	list := &IPList{path: path}
+	if err := list.Reload(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Contains reports whether ip is in the list.
+func (l *IPList) Contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	entries := l.entries.Load()
+	if _, ok := entries.addrs[addr]; ok {
+		return true
+	}
+	for _, prefix := range entries.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload reads the file again if it changed since the last load.
+func (l *IPList) Reload() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return fmt.Errorf("risk: %w", err)
+	}
+	if loaded := l.entries.Load(); loaded != nil && loaded.modTime.Equal(info.ModTime()) && loaded.size == info.Size() {
+		return nil
+	}
+
+	b, err := os.ReadFile(l.path)
+	if err != nil {
+		return fmt.Errorf("risk: %w", err)
+	}
+
+	entries := &ipEntries{
+		addrs:   make(map[netip.Addr]struct{}),
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.Contains(text, "/") {
+			prefix, err := netip.ParsePrefix(text)
+			if err != nil {
+				return fmt.Errorf("risk: %s:%d: %w", l.path, line, err)
+			}
+			entries.prefixes = append(entries.prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(text)
+		if err != nil {
+			return fmt.Errorf("risk: %s:%d: %w", l.path, line, err)
+		}
+		entries.addrs[addr.Unmap()] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("risk: %w", err)
+	}
+
+	l.entries.Store(entries)
+	logger.Infof("ip list %s loaded (%d addresses, %d networks)", l.path, len(entries.addrs), len(entries.prefixes))
+	return nil
+}
+
+// Watch reloads the list every interval until ctx is done.
+func (l *IPList) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Reload(); err != nil {
+				logger.Errorf("failed to reload ip list: %s", err)
+			}
+		}
+	}
+}
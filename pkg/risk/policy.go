@@ -0,0 +1,147 @@
+package risk
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Signals that raise the risk of a sign-in or a token refresh.
+const (
+	SignalNewDevice        = "new_device"
+	SignalNewCountry       = "new_country"
+	SignalImpossibleTravel = "impossible_travel"
+	SignalASNChange        = "asn_change"
+	SignalFailedAttempts   = "failed_attempts"
+	SignalTor              = "tor"
+	SignalBadIP            = "bad_ip"
+)
+
+var Signals = []string{
+	SignalNewDevice,
+	SignalNewCountry,
+	SignalImpossibleTravel,
+	SignalASNChange,
+	SignalFailedAttempts,
+	SignalTor,
+	SignalBadIP,
+}
+
+// Actions taken for a score band, from the mildest to the strictest.
+const (
+	ActionAllow  = "allow"
+	ActionNotify = "notify"
+	ActionMFA    = "mfa"
+	ActionBlock  = "block"
+)
+
+var Actions = []string{ActionAllow, ActionNotify, ActionMFA, ActionBlock}
+
+// Band applies Action to scores from MinScore up to the MinScore of the next band.
+type Band struct {
+	MinScore int
+	Action   string
+}
+
+// Assessment is the risk of an event.
+type Assessment struct {
+	Score   int
+	Signals []string
+	Action  string
+}
+
+// Policy scores signals with weights and maps the score to an action.
+type Policy struct {
+	weights map[string]int
+	bands   []Band
+}
+
+func NewPolicy(weights map[string]int, bands []Band) (*Policy, error) {
+	for signal, weight := range weights {
+		if !slices.Contains(Signals, signal) {
+			return nil, fmt.Errorf("risk: unknown signal %q", signal)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("risk: negative weight of %s", signal)
+		}
+	}
+	for _, band := range bands {
+		if !slices.Contains(Actions, band.Action) {
+			return nil, fmt.Errorf("risk: unknown action %q", band.Action)
+		}
+	}
+
+	bands = slices.Clone(bands)
+	sort.Slice(bands, func(i, j int) bool {
+		return bands[i].MinScore < bands[j].MinScore
+	})
+
+	return &Policy{
+		weights: weights,
+		bands:   bands,
+	}, nil
+}
+
+// Assess sums the weights of signals and returns the action of the band the score falls in.
+// Scores below every band are allowed.
+func (p *Policy) Assess(signals []string) Assessment {
+	assessment := Assessment{
+		Signals: signals,
+		Action:  ActionAllow,
+	}
+	for _, signal := range signals {
+		assessment.Score += p.weights[signal]
+	}
+	for _, band := range p.bands {
+		if assessment.Score >= band.MinScore {
+			assessment.Action = band.Action
+		}
+	}
+	return assessment
+}
+
+// ParseWeights parses weights in the form "new_device:20,tor:40".
+func ParseWeights(s string) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, pair := range splitList(s) {
+		signal, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("risk: invalid weight %q", pair)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("risk: invalid weight %q: %w", pair, err)
+		}
+		weights[strings.TrimSpace(signal)] = weight
+	}
+	return weights, nil
+}
+
+// ParseBands parses bands in the form "0:allow,30:notify,60:mfa,80:block".
+func ParseBands(s string) ([]Band, error) {
+	var bands []Band
+	for _, pair := range splitList(s) {
+		value, action, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("risk: invalid band %q", pair)
+		}
+		score, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("risk: invalid band %q: %w", pair, err)
+		}
+		bands = append(bands, Band{MinScore: score, Action: strings.TrimSpace(action)})
+	}
+	return bands, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,151 @@
+package risk
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPolicyAssess(t *testing.T) {
+	policy, err := NewPolicy(map[string]int{
+		SignalNewDevice:        20,
+		SignalNewCountry:       20,
+		SignalImpossibleTravel: 50,
+		SignalTor:              80,
+	}, []Band{
+		{MinScore: 80, Action: ActionBlock},
+		{MinScore: 0, Action: ActionAllow},
+		{MinScore: 60, Action: ActionMFA},
+		{MinScore: 30, Action: ActionNotify},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		signals    []string
+		wantScore  int
+		wantAction string
+	}{
+		{
+			name:       "no signals",
+			wantAction: ActionAllow,
+		},
+		{
+			name:       "below the notify band",
+			signals:    []string{SignalNewDevice},
+			wantScore:  20,
+			wantAction: ActionAllow,
+		},
+		{
+			name:       "notify band",
+			signals:    []string{SignalNewDevice, SignalNewCountry},
+			wantScore:  40,
+			wantAction: ActionNotify,
+		},
+		{
+			name:       "mfa band",
+			signals:    []string{SignalNewDevice, SignalImpossibleTravel},
+			wantScore:  70,
+			wantAction: ActionMFA,
+		},
+		{
+			name:       "score at the block band",
+			signals:    []string{SignalTor},
+			wantScore:  80,
+			wantAction: ActionBlock,
+		},
+		{
+			name:       "signal without weight",
+			signals:    []string{SignalASNChange},
+			wantAction: ActionAllow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assessment := policy.Assess(tt.signals)
+			if assessment.Score != tt.wantScore || assessment.Action != tt.wantAction {
+				t.Errorf("Assess() = %d %s, want %d %s", assessment.Score, assessment.Action, tt.wantScore, tt.wantAction)
+			}
+			if !slices.Equal(assessment.Signals, tt.signals) {
+				t.Errorf("Assess() signals = %v, want %v", assessment.Signals, tt.signals)
+			}
+		})
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+		bands   []Band
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			weights: map[string]int{SignalTor: 40},
+			bands:   []Band{{MinScore: 0, Action: ActionAllow}},
+		},
+		{
+			name:    "unknown signal",
+			weights: map[string]int{"weather": 10},
+			wantErr: true,
+		},
+		{
+			name:    "negative weight",
+			weights: map[string]int{SignalTor: -1},
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			bands:   []Band{{MinScore: 50, Action: "captcha"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPolicy(tt.weights, tt.bands); (err != nil) != tt.wantErr {
+				t.Errorf("NewPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBands(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []Band
+		wantErr bool
+	}{
+		{
+			name: "bands",
+			s:    "0:allow, 30:notify,60:mfa,80:block,",
+			want: []Band{{0, ActionAllow}, {30, ActionNotify}, {60, ActionMFA}, {80, ActionBlock}},
+		},
+		{
+			name:    "missing action",
+			s:       "30",
+			wantErr: true,
+		},
+		{
+			name:    "score is not a number",
+			s:       "high:block",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBands(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBands() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseBands() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
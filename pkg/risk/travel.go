@@ -0,0 +1,49 @@
+package risk
+
+import (
+	"math"
+	"time"
+)
+
+const earthRadiusKm = 6371
+
+// Point is a geographic location.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// IsZero reports whether the location is unknown.
+func (p Point) IsZero() bool {
+	return p.Latitude == 0 && p.Longitude == 0
+}
+
+// Distance returns the great-circle distance between a and b in kilometers.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(b.Longitude - a.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// ImpossibleTravel reports whether getting from a to b within elapsed requires moving faster
+// than maxSpeed km/h. slack km are subtracted from the distance to allow for the inaccuracy
+// of IP geolocation.
+func ImpossibleTravel(a, b Point, elapsed time.Duration, maxSpeed float64, slack float64) bool {
+	if a.IsZero() || b.IsZero() {
+		return false
+	}
+
+	distance := Distance(a, b) - slack
+	if distance <= 0 {
+		return false
+	}
+	hours := max(elapsed.Hours(), 1.0/60)
+	return distance/hours > maxSpeed
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
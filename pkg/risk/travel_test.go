@@ -0,0 +1,105 @@
+package risk
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	moscow     = Point{Latitude: 55.7558, Longitude: 37.6173}
+	petersburg = Point{Latitude: 59.9343, Longitude: 30.3351}
+	newYork    = Point{Latitude: 40.7128, Longitude: -74.0060}
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Point
+		want float64
+	}{
+		{name: "same point", a: moscow, b: moscow, want: 0},
+		{name: "moscow to petersburg", a: moscow, b: petersburg, want: 634},
+		{name: "moscow to new york", a: moscow, b: newYork, want: 7510},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Distance(tt.a, tt.b); math.Abs(got-tt.want) > 10 {
+				t.Errorf("Distance() = %.0f km, want about %.0f km", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImpossibleTravel(t *testing.T) {
+	const (
+		maxSpeed = 900
+		slack    = 100
+	)
+
+	tests := []struct {
+		name    string
+		a, b    Point
+		elapsed time.Duration
+		want    bool
+	}{
+		{
+			name:    "flight to petersburg in time",
+			a:       moscow,
+			b:       petersburg,
+			elapsed: time.Hour,
+			want:    false,
+		},
+		{
+			name:    "petersburg ten minutes later",
+			a:       moscow,
+			b:       petersburg,
+			elapsed: 10 * time.Minute,
+			want:    true,
+		},
+		{
+			name:    "new york two hours later",
+			a:       moscow,
+			b:       newYork,
+			elapsed: 2 * time.Hour,
+			want:    true,
+		},
+		{
+			name:    "new york next day",
+			a:       moscow,
+			b:       newYork,
+			elapsed: 24 * time.Hour,
+			want:    false,
+		},
+		{
+			name:    "within the slack",
+			a:       moscow,
+			b:       Point{Latitude: 56.3, Longitude: 37.6},
+			elapsed: 0,
+			want:    false,
+		},
+		{
+			name:    "simultaneous sign-ins far apart",
+			a:       moscow,
+			b:       petersburg,
+			elapsed: 0,
+			want:    true,
+		},
+		{
+			name:    "unknown location",
+			a:       Point{},
+			b:       newYork,
+			elapsed: time.Minute,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ImpossibleTravel(tt.a, tt.b, tt.elapsed, maxSpeed, slack); got != tt.want {
+				t.Errorf("ImpossibleTravel() = %v, want %v (distance %.0f km)", got, tt.want, Distance(tt.a, tt.b))
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package fake
+
+import (
+	"encoding/json"
+	"medods-test/pkg/sms"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is a message sent through the fake provider.
+type Message struct {
+	Recipient string    `json:"to"`
+	Body      string    `json:"body"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// FakeSender is an SMS provider for development and tests: it delivers nothing, keeps sent
+// messages in an in-memory inbox and, if a file is set, appends them to it as JSON lines.
+type FakeSender struct {
+	path string
+
+	mu    sync.Mutex
+	inbox []Message
+}
+
+// NewFakeSender creates a fake provider. With an empty path messages are only kept in memory.
+func NewFakeSender(path string) *FakeSender {
+	return &FakeSender{path: path}
+}
+
+func (s *FakeSender) Send(input sms.Send) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	message := Message{
+		Recipient: input.Recipient,
+		Body:      input.Body,
+		SentAt:    time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path != "" {
+		if err := s.write(message); err != nil {
+			return err
+		}
+	}
+	s.inbox = append(s.inbox, message)
+	return nil
+}
+
+// Messages returns the messages sent to recipient, oldest first, or every message if recipient is empty.
+func (s *FakeSender) Messages(recipient string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]Message, 0, len(s.inbox))
+	for _, message := range s.inbox {
+		if recipient == "" || message.Recipient == recipient {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// Last returns the last message sent to recipient.
+func (s *FakeSender) Last(recipient string) (Message, bool) {
+	messages := s.Messages(recipient)
+	if len(messages) == 0 {
+		return Message{}, false
+	}
+	return messages[len(messages)-1], true
+}
+
+// Reset empties the in-memory inbox, the file is left as is.
+func (s *FakeSender) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inbox = nil
+}
+
+func (s *FakeSender) write(message Message) error {
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,34 @@
+package sms
+
+import (
+	"errors"
+)
+
+type Send struct {
+	Recipient string
+	Body      string
+}
+
+type Sender interface {
+	Send(input Send) error
+}
+
+func (s *Send) Validate() error {
+	if s.Recipient == "" {
+		return errors.New("empty to")
+	}
+
+	if s.Body == "" {
+		return errors.New("empty body")
+	}
+
+	if len([]rune(s.Body)) > MaxBodyLen {
+		return errors.New("body is too long")
+	}
+
+	if !IsPhoneValid(s.Recipient) {
+		return errors.New("invalid to phone")
+	}
+
+	return nil
+}
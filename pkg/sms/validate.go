@@ -0,0 +1,28 @@
+package sms
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxBodyLen is the length in characters of a message split into at most three parts in UCS-2.
+const MaxBodyLen = 201
+
+// phoneRegex matches phone numbers in the E.164 format: a plus, the country code and up to
+// 15 digits in total.
+var phoneRegex = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+func IsPhoneValid(phone string) bool {
+	return phoneRegex.MatchString(phone)
+}
+
+// NormalizePhone removes spaces, dashes, dots and parentheses people put into phone numbers.
+func NormalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+}
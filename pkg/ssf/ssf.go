@@ -0,0 +1,180 @@
+package ssf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SpecVersion is the version of the Shared Signals Framework implemented by the transmitter.
+const SpecVersion = "1_0"
+
+// Delivery methods: push (RFC 8935) and poll (RFC 8936).
+const (
+	DeliveryMethodPush = "urn:ietf:rfc:8935"
+	DeliveryMethodPoll = "urn:ietf:rfc:8936"
+)
+
+// ContentTypeSET is the media type of a pushed Security Event Token.
+const ContentTypeSET = "application/secevent+jwt"
+
+// Event types of the Continuous Access Evaluation Profile (CAEP), the Risk Incident Sharing
+// and Coordination profile (RISC) and the framework itself.
+const (
+	EventSessionRevoked   = "https://schemas.openid.net/secevent/caep/event-type/session-revoked"
+	EventCredentialChange = "https://schemas.openid.net/secevent/caep/event-type/credential-change"
+	EventRiskLevelChange  = "https://schemas.openid.net/secevent/caep/event-type/risk-level-change"
+	EventAccountDisabled  = "https://schemas.openid.net/secevent/risc/event-type/account-disabled"
+	EventAccountEnabled   = "https://schemas.openid.net/secevent/risc/event-type/account-enabled"
+	EventAccountPurged    = "https://schemas.openid.net/secevent/risc/event-type/account-purged"
+	EventVerification     = "https://schemas.openid.net/secevent/ssf/event-type/verification"
+)
+
+// Initiating entities of CAEP events.
+const (
+	InitiatorUser   = "user"
+	InitiatorAdmin  = "admin"
+	InitiatorPolicy = "policy"
+	InitiatorSystem = "system"
+)
+
+// Stream statuses.
+const (
+	StatusEnabled  = "enabled"
+	StatusPaused   = "paused"
+	StatusDisabled = "disabled"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	// maxErrorBody is how much of an unsuccessful response is read.
+	maxErrorBody = 512
+)
+
+// ErrRejected is returned by Push when the receiver rejected the token as invalid. Such
+// tokens must not be pushed again (RFC 8935, section 2.3).
+var ErrRejected = errors.New("security event token rejected")
+
+// Subject identifies the subject of an event (RFC 9493).
+type Subject struct {
+	Format  string   `json:"format"`
+	Iss     string   `json:"iss,omitempty"`
+	Sub     string   `json:"sub,omitempty"`
+	Id      string   `json:"id,omitempty"`
+	User    *Subject `json:"user,omitempty"`
+	Session *Subject `json:"session,omitempty"`
+}
+
+// IssSubSubject identifies a user by the issuer and the subject of its tokens.
+func IssSubSubject(iss string, sub string) Subject {
+	return Subject{Format: "iss_sub", Iss: iss, Sub: sub}
+}
+
+// OpaqueSubject identifies a subject by an id only the transmitter and receiver understand.
+func OpaqueSubject(id string) Subject {
+	return Subject{Format: "opaque", Id: id}
+}
+
+// SessionSubject identifies a session of a user.
+func SessionSubject(user Subject, sessionId string) Subject {
+	session := OpaqueSubject(sessionId)
+	return Subject{Format: "complex", User: &user, Session: &session}
+}
+
+// AuthorizationScheme is a way receivers authorize to the transmitter.
+type AuthorizationScheme struct {
+	SpecURN string `json:"spec_urn"`
+}
+
+// TransmitterMetadata is the transmitter configuration published at
+// /.well-known/ssf-configuration (SSF 1.0, section 7.1).
+type TransmitterMetadata struct {
+	SpecVersion              string                `json:"spec_version"`
+	Issuer                   string                `json:"issuer"`
+	JwksURI                  string                `json:"jwks_uri"`
+	DeliveryMethodsSupported []string              `json:"delivery_methods_supported"`
+	ConfigurationEndpoint    string                `json:"configuration_endpoint"`
+	StatusEndpoint           string                `json:"status_endpoint"`
+	VerificationEndpoint     string                `json:"verification_endpoint"`
+	AuthorizationSchemes     []AuthorizationScheme `json:"authorization_schemes"`
+	DefaultSubjects          string                `json:"default_subjects"`
+}
+
+// PollRequest is the body of a poll request (RFC 8936, section 2.1).
+type PollRequest struct {
+	MaxEvents         *int                `json:"maxEvents,omitempty"`
+	ReturnImmediately bool                `json:"returnImmediately,omitempty"`
+	Ack               []string            `json:"ack,omitempty"`
+	SetErrs           map[string]SetError `json:"setErrs,omitempty"`
+}
+
+// SetError is why a receiver could not process a token.
+type SetError struct {
+	Err         string `json:"err"`
+	Description string `json:"description,omitempty"`
+}
+
+// PollResponse returns tokens by their jti (RFC 8936, section 2.2).
+type PollResponse struct {
+	Sets          map[string]string `json:"sets"`
+	MoreAvailable bool              `json:"moreAvailable,omitempty"`
+}
+
+// Pusher pushes Security Event Tokens to receivers.
+type Pusher struct {
+	http *http.Client
+}
+
+func NewPusher(timeout time.Duration) *Pusher {
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Pusher{
+		http: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Push posts the token to the endpoint of the receiver. authorization is sent as the
+// Authorization header when set. A rejected token is reported with ErrRejected, any other
+// unsuccessful response may be retried.
+func (p *Pusher) Push(ctx context.Context, endpoint string, authorization string, set string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader([]byte(set)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentTypeSET)
+	req.Header.Set("Accept", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode == http.StatusBadRequest {
+		var setErr SetError
+		if json.Unmarshal(body, &setErr) == nil && setErr.Err != "" {
+			return fmt.Errorf("%w: %s: %s", ErrRejected, setErr.Err, setErr.Description)
+		}
+	}
+	return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+}
@@ -0,0 +1,64 @@
+package useragent
+
+import "strings"
+
+// token maps a substring of the User-Agent header to a readable name.
+type token struct {
+	match string
+	name  string
+}
+
+// browsers are checked in order, since most browsers also mention the engines they are based on.
+var browsers = []token{
+	{"YaBrowser/", "Yandex Browser"},
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"okhttp/", "OkHttp"},
+	{"PostmanRuntime/", "Postman"},
+}
+
+var systems = []token{
+	{"Windows", "Windows"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// Describe returns a coarse description of the device from a User-Agent header,
+// such as "Chrome on Windows". Unknown parts are left out.
+func Describe(userAgent string) string {
+	browser := find(browsers, userAgent)
+	system := find(systems, userAgent)
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+func find(tokens []token, userAgent string) string {
+	for _, t := range tokens {
+		if strings.Contains(userAgent, t.match) {
+			return t.name
+		}
+	}
+	return ""
+}
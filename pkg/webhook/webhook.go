@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Headers of a delivery. Receivers check the signature over the timestamp and the body
+// and reject deliveries with old timestamps, so that a captured request cannot be replayed.
+const (
+	HeaderId        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	signaturePrefix = "sha256="
+	defaultTimeout  = 10 * time.Second
+	// maxErrorBody is how much of an unsuccessful response is kept in the error.
+	maxErrorBody = 512
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredTimestamp = errors.New("webhook timestamp is too old")
+	ErrForbiddenAddress = errors.New("webhook endpoint resolves to a non-public address")
+)
+
+// reservedNetworks are the special-purpose ranges not covered by the net.IP predicates.
+var reservedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+	mustParseCIDR("64:ff9b::/96"),
+}
+
+// Message is one delivery of an event to an endpoint.
+type Message struct {
+	Id      string
+	Event   string
+	Payload []byte
+}
+
+// Result describes the response of the endpoint.
+type Result struct {
+	StatusCode int
+	Duration   time.Duration
+}
+
+// Sign returns the signature header value of a payload sent at timestamp (unix seconds):
+// the hex HMAC-SHA256 of "timestamp.payload" keyed with the secret of the subscription.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers of a received delivery. Timestamps
+// further than tolerance from now are rejected.
+func Verify(secret string, timestamp string, signature string, payload []byte, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(Sign(secret, unix, payload)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	age := time.Since(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrExpiredTimestamp
+	}
+	return nil
+}
+
+// Client posts signed messages to webhook endpoints.
+type Client struct {
+	http *http.Client
+}
+
+func NewClient(timeout time.Duration) *Client {
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl,
+	}
+	return &Client{
+		http: &http.Client{
+			Timeout: timeout,
+			// No proxy is used, the address is checked by the dialer after the host is resolved.
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				ForceAttemptHTTP2:   true,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// A redirect is reported as an unsuccessful delivery instead of resending the payload elsewhere.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send posts the message to url signed with secret. Responses other than 2xx are errors,
+// the result is filled in whenever the endpoint responded.
+func (c *Client) Send(ctx context.Context, url string, secret string, message Message) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(message.Payload))
+	if err != nil {
+		return Result{}, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderId, message.Id)
+	req.Header.Set(HeaderEvent, message.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, message.Payload))
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	result := Result{Duration: time.Since(start)}
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return result, nil
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed ones:
+// initial after the first one, doubled after every next one up to limit.
+func Backoff(attempts int, initial time.Duration, limit time.Duration) time.Duration {
+	delay := initial
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// IsPublicIP reports whether ip is a globally routable unicast address. Loopback, private,
+// link-local (including the 169.254.169.254 metadata endpoint of cloud providers) and other
+// special-purpose addresses are not.
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialControl runs after the host is resolved, right before connecting, so that a host name
+// resolving to an internal address (or rebinding to one after the subscription was checked)
+// cannot be used to reach internal services.
+func dialControl(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicIP(net.ParseIP(host)) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
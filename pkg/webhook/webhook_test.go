@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"type":"user.signed_in"}`)
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		payload   []byte
+		wantErr   error
+	}{
+		{
+			name:      "valid signature",
+			secret:    "secret",
+			timestamp: strconv.FormatInt(now, 10),
+			signature: Sign("secret", now, payload),
+			payload:   payload,
+		},
+		{
+			name:      "wrong secret",
+			secret:    "other",
+			timestamp: strconv.FormatInt(now, 10),
+			signature: Sign("secret", now, payload),
+			payload:   payload,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "tampered payload",
+			secret:    "secret",
+			timestamp: strconv.FormatInt(now, 10),
+			signature: Sign("secret", now, payload),
+			payload:   []byte(`{"type":"user.deleted"}`),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "changed timestamp",
+			secret:    "secret",
+			timestamp: strconv.FormatInt(now+1, 10),
+			signature: Sign("secret", now, payload),
+			payload:   payload,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "malformed timestamp",
+			secret:    "secret",
+			timestamp: "yesterday",
+			signature: Sign("secret", now, payload),
+			payload:   payload,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "old timestamp",
+			secret:    "secret",
+			timestamp: strconv.FormatInt(now-600, 10),
+			signature: Sign("secret", now-600, payload),
+			payload:   payload,
+			wantErr:   ErrExpiredTimestamp,
+		},
+		{
+			name:      "future timestamp",
+			secret:    "secret",
+			timestamp: strconv.FormatInt(now+600, 10),
+			signature: Sign("secret", now+600, payload),
+			payload:   payload,
+			wantErr:   ErrExpiredTimestamp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.signature, tt.payload, 5*time.Minute)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Minute},
+		{attempts: 2, want: 2 * time.Minute},
+		{attempts: 4, want: 8 * time.Minute},
+		{attempts: 6, want: 30 * time.Minute},
+		{attempts: 100, want: 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts, time.Minute, 30*time.Minute); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "10.0.0.5"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "fe80::1"},
+		{ip: "fd00::1"},
+		{ip: "0.0.0.0"},
+		{ip: "100.64.0.1"},
+		{ip: "224.0.0.1"},
+		{ip: "255.255.255.255"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "::ffff:169.254.169.254"},
+	}
+
+	for _, tt := range tests {
+		if got := IsPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestSendRejectsInternalAddress(t *testing.T) {
+	received := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer server.Close()
+
+	// The url of the test server is a loopback address, as a host name resolving to one would be.
+	_, err := NewClient(time.Second).Send(context.Background(), server.URL, "secret", Message{Id: "1", Event: "user.signed_in", Payload: []byte(`{}`)})
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Send() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+	if received {
+		t.Errorf("Send() reached the internal endpoint")
+	}
+}